@@ -10,7 +10,10 @@ import (
 	"ChatServer/config"
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/deviceactive"
+	"ChatServer/pkg/grpcx"
+	"ChatServer/pkg/kafka"
 	"ChatServer/pkg/logger"
+	"ChatServer/pkg/metrics"
 	pkgredis "ChatServer/pkg/redis"
 	"context"
 	"net/http"
@@ -38,6 +41,7 @@ func main() {
 	defer func() {
 		_ = l.Sync()
 	}()
+	metrics.Init("connect", config.DefaultMetricsConfig().Version)
 
 	// 2) 初始化 Redis。
 	// 说明：
@@ -66,9 +70,23 @@ func main() {
 	}
 	var userDeviceClient userpb.DeviceServiceClient
 	var userGRPCConn *googlegrpc.ClientConn
+	// user-service 不可用时熔断快速失败，避免连接建立/断开与活跃同步逐个等待超时。
+	breakerCfg := config.DefaultGRPCBreakerConfig()
+	userBreaker := grpcx.NewCircuitBreaker("user-service", grpcx.BreakerConfig{
+		MaxRequests:  uint32(breakerCfg.MaxRequests),
+		Interval:     breakerCfg.Interval,
+		Timeout:      breakerCfg.Timeout,
+		MinRequests:  uint32(breakerCfg.MinRequests),
+		FailureRatio: float64(breakerCfg.FailurePercent) / 100,
+	})
 	userGRPCConn, err = googlegrpc.NewClient(
 		userGRPCAddr,
 		googlegrpc.WithTransportCredentials(insecure.NewCredentials()),
+		googlegrpc.WithChainUnaryInterceptor(
+			// 透传 trace_id 等链路字段，使 user-service 日志可与连接日志关联。
+			grpcx.MetadataUnaryClientInterceptor(),
+			grpcx.CircuitBreakerUnaryClientInterceptor(userBreaker),
+		),
 	)
 	if err != nil {
 		logger.Warn(ctx, "user-service gRPC 连接创建失败，降级为无设备状态同步模式",
@@ -153,10 +171,93 @@ func main() {
 	// - svc:     connect 业务逻辑（鉴权、心跳、活跃时间、设备状态）。
 	// - handler: Gin /ws 入口，承接协议层逻辑。
 	connManager := manager.NewConnectionManager()
+	redeliveryCfg := config.DefaultConnectRedeliveryConfig()
+	connManager.SetRedeliveryPolicy(manager.RedeliveryPolicy{
+		Interval:    redeliveryCfg.Interval,
+		MaxAttempts: redeliveryCfg.MaxAttempts,
+		MaxPending:  redeliveryCfg.MaxPending,
+	})
 	connectSvc := svc.NewConnectService(redisClient, userDeviceClient, activeSyncer)
+	heartbeatCfg := config.DefaultConnectHeartbeatConfig()
+	connectSvc.SetHeartbeatPolicy(svc.HeartbeatPolicy{
+		Min:     heartbeatCfg.MinInterval,
+		Max:     heartbeatCfg.MaxInterval,
+		Default: heartbeatCfg.DefaultInterval,
+	})
+	logger.Info(ctx, "Connect 心跳策略已加载",
+		logger.Duration("min_interval", heartbeatCfg.MinInterval),
+		logger.Duration("max_interval", heartbeatCfg.MaxInterval),
+		logger.Duration("default_interval", heartbeatCfg.DefaultInterval),
+	)
+	// 在线状态变更事件：按用户整体在线状态翻转投递到 Kafka，防抖合并快速上下线。
+	var presenceProducer *kafka.Producer
+	presenceCfg := config.DefaultConnectPresenceConfig()
+	connectSvc.SetPresenceSubscriptionLimit(presenceCfg.MaxSubscriptions)
+	connectSvc.SetPresenceIdleThreshold(presenceCfg.IdleThreshold)
+	if presenceCfg.Enabled {
+		kafkaCfg := config.DefaultKafkaConfig()
+		presenceProducer = kafka.NewProducer(kafkaCfg.Brokers, kafkaCfg.PresenceTopic)
+		presenceDebouncer := svc.NewPresenceDebouncer(presenceCfg.DebounceWindow, svc.NewKafkaPresencePublisher(presenceProducer))
+		presenceDebouncer.Start()
+		connectSvc.SetPresenceDebouncer(presenceDebouncer)
+		logger.Info(ctx, "Connect 在线状态事件投递已启用",
+			logger.String("topic", kafkaCfg.PresenceTopic),
+			logger.Duration("debounce_window", presenceCfg.DebounceWindow),
+			logger.Duration("idle_threshold", presenceCfg.IdleThreshold),
+		)
+	}
+	// resume 补发依赖 msg 服务按 seq 拉取消息（svc.MessageReplaySource）；msg 服务接入前不设置数据源，
+	// resume 帧回 error 帧（code=17004），客户端重连后直接走 HTTP 拉取。
+	// 连接归属登记：滚动发布时排空节点断开的连接若已在其他节点重连，不再上报离线。
+	drainCfg := config.DefaultConnectDrainConfig()
+	// 停机时先下发 server_shutdown 帧与 CloseGoingAway 关闭帧，等待写出后再强制断开，客户端据此退避重连。
+	shutdownFrame, err := connectSvc.ServerShutdownFrame(drainCfg.RetryAfter)
+	if err != nil {
+		// 构造失败时仅发送关闭帧
+		logger.Warn(ctx, "构造停机通知帧失败",
+			logger.ErrorField("error", err),
+		)
+	}
+	connManager.SetShutdownDrain(shutdownFrame, drainCfg.CloseGrace)
+	if redisClient != nil {
+		connectSvc.SetConnectionRegistry(svc.NewRedisConnectionRegistry(redisClient), drainCfg.NodeID)
+		logger.Info(ctx, "Connect 连接归属登记已启用",
+			logger.String("node_id", drainCfg.NodeID),
+			logger.Duration("drain_grace", drainCfg.Grace),
+		)
+		if presenceCfg.Enabled {
+			// 节点状态有效期取 3 个空闲扫描周期：扫描会刷新本节点在线用户，异常退出的节点状态随后过期。
+			connectSvc.SetPresenceAggregator(svc.NewRedisPresenceAggregator(redisClient, 3*connectSvc.PresenceSweepInterval()))
+		}
+	}
+	// 在线状态推送：每个节点不加入消费组、从最新 offset 直接读取全部分区，向本节点在线的好友推送 type=presence 帧。
+	// 不按节点 ID 建消费组：节点 ID 默认取主机名，每次发布都会遗留无人使用的消费组。
+	var presenceConsumer *kafka.BroadcastConsumer
+	presenceCtx, stopPresence := context.WithCancel(context.Background())
+	defer stopPresence()
+	// 活跃 → 空闲检测：周期扫描本节点连接，用户全部连接超过活跃阈值无上行帧时上报 idle。
+	if presenceCfg.Enabled {
+		go connectSvc.RunPresenceIdleSweep(presenceCtx, connManager.ActivitySnapshot)
+	}
+	if presenceCfg.FanoutEnabled && redisClient != nil {
+		kafkaCfg := config.DefaultKafkaConfig()
+		presenceConsumer = kafka.NewBroadcastConsumer(kafkaCfg.Brokers, kafkaCfg.PresenceTopic)
+		fanout := svc.NewPresenceFanout(svc.NewRedisPresenceAudienceSource(redisClient), connManager.SendToUser)
+		fanout.SetSubscriptions(connectSvc.PresenceSubscriptions(), connManager)
+		go func() {
+			if err := presenceConsumer.Start(presenceCtx, fanout.Consume); err != nil && err != context.Canceled {
+				logger.Error(ctx, "在线状态事件消费异常退出",
+					logger.ErrorField("error", err),
+				)
+			}
+		}()
+		logger.Info(ctx, "Connect 在线状态推送已启用",
+			logger.String("topic", kafkaCfg.PresenceTopic),
+		)
+	}
 	wsHandler := handler.NewWSHandler(connManager, connectSvc)
 
-	// 5) 构建 HTTP 服务（包含 /health、/metrics 与 /ws）。
+	// 5) 构建 HTTP 服务（公网 /health、/ws；内部监听 /metrics）。
 	srvCfg := server.DefaultConfig()
 	srv := server.New(srvCfg, wsHandler, connManager)
 
@@ -168,6 +269,8 @@ func main() {
 		grpcAddr = ":9091"
 	}
 	grpcSrv := grpc.NewServer(grpcAddr, connManager)
+	broadcastCfg := config.DefaultConnectBroadcastConfig()
+	grpcSrv.SetBroadcastConcurrency(broadcastCfg.Concurrency)
 
 	// 7) 后台启动 HTTP 监听。
 	// ListenAndServe 的正常退出会返回 http.ErrServerClosed，这种情况不视为启动失败。
@@ -200,8 +303,9 @@ func main() {
 	<-quit
 
 	// 10) 优雅关闭流程：
-	// - 先停 gRPC（不再接受新的 RPC 调用）。
-	// - 再关闭连接管理器，主动断开所有 WebSocket 连接，避免悬挂连接。
+	// - 先停 gRPC（不再接受新的 RPC 调用）与在线状态推送消费。
+	// - 导出本节点连接进入排空状态，再关闭连接管理器，主动断开所有 WebSocket 连接。
+	// - 等待宽限期让客户端重连到其他节点，对未被接管的连接上报离线。
 	// - 关闭 user-service gRPC 连接。
 	// - 最后关闭 HTTP 服务，等待进行中的请求在超时时间内结束。
 	logger.Info(ctx, "Connect 服务开始优雅停机")
@@ -209,8 +313,31 @@ func main() {
 	defer cancel()
 
 	grpcSrv.Stop()
+	stopPresence()
+	if presenceConsumer != nil {
+		if closeErr := presenceConsumer.Close(); closeErr != nil {
+			logger.Warn(ctx, "关闭在线状态 Kafka Consumer 失败",
+				logger.ErrorField("error", closeErr),
+			)
+		}
+	}
+	connectSvc.BeginDrain(shutdownCtx, connManager.Snapshot(), drainCfg.Grace)
 	connManager.Shutdown()
+	if redisClient != nil {
+		time.Sleep(drainCfg.Grace)
+	}
+	offline := connectSvc.FinishDrain(shutdownCtx)
+	logger.Info(ctx, "Connect 排空完成",
+		logger.Int("offline_count", offline),
+	)
 	connectSvc.ShutdownStatusWorkers()
+	if presenceProducer != nil {
+		if closeErr := presenceProducer.Close(); closeErr != nil {
+			logger.Warn(ctx, "关闭在线状态 Kafka Producer 失败",
+				logger.ErrorField("error", closeErr),
+			)
+		}
+	}
 	if userGRPCConn != nil {
 		if closeErr := userGRPCConn.Close(); closeErr != nil {
 			logger.Warn(ctx, "关闭 user-service gRPC 连接失败",
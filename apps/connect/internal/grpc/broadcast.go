@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultBroadcastConcurrency BroadcastToUsers 默认并发 worker 数。
+const defaultBroadcastConcurrency = 32
+
+// broadcastOutcome 单个用户的推送结果。
+type broadcastOutcome int
+
+const (
+	broadcastDelivered broadcastOutcome = iota // 至少一个设备入队成功
+	broadcastOffline                           // 用户无在线设备
+	broadcastFailed                            // 在线但全部入队失败，或请求取消未及推送
+)
+
+// broadcastResult 批量推送的聚合结果。
+// Offline/Failed 保持与请求中用户顺序一致，便于调用方对照重试。
+type broadcastResult struct {
+	SuccessCount   int32
+	TotalDelivered int32
+	Offline        []string
+	Failed         []string
+}
+
+// fanOutBroadcast 使用有界 worker 池并发向多个用户推送同一条消息。
+// - 重复的 user_uuid 只推送一次；
+// - 单个用户入队失败不影响其他用户；
+// - ctx 取消后不再派发新任务，未派发的用户计入 Failed。
+func fanOutBroadcast(ctx context.Context, conns ConnManager, userUUIDs []string, data []byte, concurrency int) broadcastResult {
+	users := dedupeUserUUIDs(userUUIDs)
+	if concurrency <= 0 {
+		concurrency = defaultBroadcastConcurrency
+	}
+	if concurrency > len(users) {
+		concurrency = len(users)
+	}
+
+	outcomes := make([]broadcastOutcome, len(users))
+	delivered := make([]int, len(users))
+	dispatched := make([]bool, len(users))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				outcomes[idx], delivered[idx] = pushOne(conns, users[idx], data)
+			}
+		}()
+	}
+
+dispatch:
+	for idx := range users {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- idx:
+			dispatched[idx] = true
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var result broadcastResult
+	for idx, userUUID := range users {
+		if !dispatched[idx] {
+			result.Failed = append(result.Failed, userUUID)
+			continue
+		}
+		switch outcomes[idx] {
+		case broadcastDelivered:
+			result.SuccessCount++
+			result.TotalDelivered += int32(delivered[idx])
+		case broadcastOffline:
+			result.Offline = append(result.Offline, userUUID)
+		default:
+			result.Failed = append(result.Failed, userUUID)
+		}
+	}
+	return result
+}
+
+// pushOne 向单个用户推送，并区分离线与入队失败。
+func pushOne(conns ConnManager, userUUID string, data []byte) (broadcastOutcome, int) {
+	if count := conns.SendToUser(userUUID, data); count > 0 {
+		return broadcastDelivered, count
+	}
+	// 入队数为 0 时再确认是否在线：在线说明写队列已满或连接正在关闭。
+	if len(conns.GetOnlineDevices(userUUID)) == 0 {
+		return broadcastOffline, 0
+	}
+	return broadcastFailed, 0
+}
+
+// dedupeUserUUIDs 去重并保持原始顺序，忽略空字符串。
+func dedupeUserUUIDs(userUUIDs []string) []string {
+	seen := make(map[string]struct{}, len(userUUIDs))
+	users := make([]string, 0, len(userUUIDs))
+	for _, userUUID := range userUUIDs {
+		if userUUID == "" {
+			continue
+		}
+		if _, ok := seen[userUUID]; ok {
+			continue
+		}
+		seen[userUUID] = struct{}{}
+		users = append(users, userUUID)
+	}
+	return users
+}
@@ -0,0 +1,209 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeConnManager struct {
+	sendToUserFn         func(userUUID string, msg []byte) int
+	sendReliableToUserFn func(userUUID string, build manager.FrameBuilder) int
+	getOnlineDevicesFn   func(userUUID string) []string
+}
+
+func (f *fakeConnManager) SendToDevice(string, string, []byte) bool { return false }
+
+func (f *fakeConnManager) SendToUser(userUUID string, msg []byte) int {
+	if f.sendToUserFn == nil {
+		return 0
+	}
+	return f.sendToUserFn(userUUID, msg)
+}
+
+func (f *fakeConnManager) SendReliableToDevice(string, string, manager.FrameBuilder) bool {
+	return false
+}
+
+func (f *fakeConnManager) SendReliableToUser(userUUID string, build manager.FrameBuilder) int {
+	if f.sendReliableToUserFn == nil {
+		return 0
+	}
+	return f.sendReliableToUserFn(userUUID, build)
+}
+
+func (f *fakeConnManager) KickDevice(string, string) bool { return false }
+
+func (f *fakeConnManager) GetOnlineDevices(userUUID string) []string {
+	if f.getOnlineDevicesFn == nil {
+		return nil
+	}
+	return f.getOnlineDevicesFn(userUUID)
+}
+
+func TestBroadcastToUsers_ReportsOfflineAndFailed(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	// u1/u2 在线且投递成功（u2 两个设备），u3 离线，u4 在线但写队列已满。
+	conns := &fakeConnManager{
+		sendToUserFn: func(userUUID string, _ []byte) int {
+			switch userUUID {
+			case "u1":
+				return 1
+			case "u2":
+				return 2
+			default:
+				return 0
+			}
+		},
+		getOnlineDevicesFn: func(userUUID string) []string {
+			if userUUID == "u4" {
+				return []string{"d1"}
+			}
+			return nil
+		},
+	}
+	s := &Server{connManager: conns}
+	s.SetBroadcastConcurrency(4)
+
+	resp, err := s.BroadcastToUsers(context.Background(), &pb.BroadcastToUsersRequest{
+		UserUuids: []string{"u1", "u3", "u2", "u4", "u1"},
+		Message:   &pb.MessageEnvelope{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.SuccessCount)
+	assert.Equal(t, int32(3), resp.TotalDelivered)
+	assert.Equal(t, []string{"u3"}, resp.OfflineUserUuids)
+	assert.Equal(t, []string{"u4"}, resp.FailedUserUuids)
+}
+
+func TestFanOutBroadcast_DeliversInParallel(t *testing.T) {
+	const users = 8
+	var arrived sync.WaitGroup
+	arrived.Add(users)
+	release := make(chan struct{})
+
+	conns := &fakeConnManager{
+		sendToUserFn: func(string, []byte) int {
+			// 所有用户必须同时处于推送中才能放行，串行实现会在此超时。
+			arrived.Done()
+			<-release
+			return 1
+		},
+	}
+
+	go func() {
+		arrived.Wait()
+		close(release)
+	}()
+
+	uuids := make([]string, users)
+	for i := range uuids {
+		uuids[i] = string(rune('a' + i))
+	}
+
+	done := make(chan broadcastResult, 1)
+	go func() {
+		done <- fanOutBroadcast(context.Background(), conns, uuids, []byte("m"), users)
+	}()
+
+	select {
+	case result := <-done:
+		assert.Equal(t, int32(users), result.SuccessCount)
+		assert.Empty(t, result.Offline)
+		assert.Empty(t, result.Failed)
+	case <-time.After(2 * time.Second):
+		close(release)
+		t.Fatal("推送未并发执行")
+	}
+}
+
+func TestFanOutBroadcast_BoundsConcurrency(t *testing.T) {
+	const limit = 3
+	var inFlight, maxInFlight atomic.Int32
+
+	conns := &fakeConnManager{
+		sendToUserFn: func(string, []byte) int {
+			cur := inFlight.Add(1)
+			for {
+				prev := maxInFlight.Load()
+				if cur <= prev || maxInFlight.CompareAndSwap(prev, cur) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			return 1
+		},
+	}
+
+	uuids := make([]string, 30)
+	for i := range uuids {
+		uuids[i] = "u" + string(rune('A'+i))
+	}
+
+	result := fanOutBroadcast(context.Background(), conns, uuids, []byte("m"), limit)
+	assert.Equal(t, int32(len(uuids)), result.SuccessCount)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(limit))
+	assert.Greater(t, maxInFlight.Load(), int32(1))
+}
+
+func TestFanOutBroadcast_CanceledContextMarksUndispatchedFailed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	conns := &fakeConnManager{
+		sendToUserFn: func(string, []byte) int { return 1 },
+	}
+
+	result := fanOutBroadcast(ctx, conns, []string{"u1", "u2"}, []byte("m"), 1)
+	// 取消后可能仍有少量任务被派发，但所有用户都必须被归类。
+	assert.Equal(t, 2, int(result.SuccessCount)+len(result.Failed))
+}
+
+func TestPushToUser_AckRequiredAssignsPushID(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	var frames [][]byte
+	conns := &fakeConnManager{
+		sendToUserFn: func(string, []byte) int {
+			t.Fatal("ack_required 消息不应走普通投递")
+			return 0
+		},
+		sendReliableToUserFn: func(_ string, build manager.FrameBuilder) int {
+			// 模拟两个设备，各自分配独立 push_id。
+			for _, pushID := range []uint64{1, 7} {
+				frame, err := build(pushID)
+				require.NoError(t, err)
+				frames = append(frames, frame)
+			}
+			return 2
+		},
+	}
+	s := &Server{connManager: conns}
+
+	msg := &pb.MessageEnvelope{Type: "message", Seq: 3, AckRequired: true}
+	resp, err := s.PushToUser(context.Background(), &pb.PushToUserRequest{UserUuid: "u1", Message: msg})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.DeliveredCount)
+	assert.Zero(t, msg.PushId, "不应修改请求中的 envelope")
+
+	require.Len(t, frames, 2)
+	for i, want := range []uint64{1, 7} {
+		var got pb.MessageEnvelope
+		require.NoError(t, proto.Unmarshal(frames[i], &got))
+		assert.Equal(t, want, got.PushId)
+		assert.Equal(t, int64(3), got.Seq)
+	}
+}
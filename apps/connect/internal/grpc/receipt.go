@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/logger"
+)
+
+// DeliveryReceiptData 送达回执 data：消息已写入接收方连接的发送队列。
+type DeliveryReceiptData struct {
+	MsgID string `json:"msg_id"`
+	Seq   int64  `json:"seq"`
+}
+
+// deliveryReceiptFrameType 送达回执下行帧类型。
+// 与上行 type=ack（客户端按 push_id 确认下行帧）区分，客户端按 type 即可分派，无需检查 data 字段。
+const deliveryReceiptFrameType = "delivery_ack"
+
+// deliveryReceiptFrame 送达回执下行帧：{"type":"delivery_ack","data":{"msg_id":"...","seq":N}}。
+type deliveryReceiptFrame struct {
+	Type string              `json:"type"`
+	Data DeliveryReceiptData `json:"data"`
+}
+
+// sendDeliveryReceipt 在消息成功入队接收方连接后，向发送者在线设备下发送达回执。
+// 仅当 envelope 携带 msg_id 与 sender_uuid 时生效；发给自己（多端同步）不回执。
+// 回执为尽力投递：发送者离线或队列已满时直接丢弃，发送者可通过拉取消息状态兜底。
+func (s *Server) sendDeliveryReceipt(ctx context.Context, recipientUUID string, msg *pb.MessageEnvelope) {
+	if msg.GetMsgId() == "" || msg.GetSenderUuid() == "" || msg.GetSenderUuid() == recipientUUID {
+		return
+	}
+
+	frame, err := json.Marshal(deliveryReceiptFrame{
+		Type: deliveryReceiptFrameType,
+		Data: DeliveryReceiptData{MsgID: msg.GetMsgId(), Seq: msg.GetSeq()},
+	})
+	if err != nil {
+		logger.Warn(ctx, "送达回执序列化失败",
+			logger.String("msg_id", msg.GetMsgId()),
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	s.connManager.SendToUser(msg.GetSenderUuid(), frame)
+}
@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPushToUser_SendsDeliveryReceiptToSender(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	receipts := make(map[string][]byte)
+	conns := &fakeConnManager{
+		sendToUserFn: func(userUUID string, msg []byte) int {
+			if userUUID == "receiver" {
+				return 1
+			}
+			receipts[userUUID] = msg
+			return 1
+		},
+	}
+	s := &Server{connManager: conns}
+
+	resp, err := s.PushToUser(context.Background(), &pb.PushToUserRequest{
+		UserUuid: "receiver",
+		Message:  &pb.MessageEnvelope{Type: "message", Seq: 7, MsgId: "m1", SenderUuid: "sender"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), resp.DeliveredCount)
+
+	require.Contains(t, receipts, "sender")
+	var frame deliveryReceiptFrame
+	require.NoError(t, json.Unmarshal(receipts["sender"], &frame))
+	assert.Equal(t, "delivery_ack", frame.Type)
+	assert.Equal(t, DeliveryReceiptData{MsgID: "m1", Seq: 7}, frame.Data)
+}
+
+func TestPushToUser_SkipsDeliveryReceipt(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	tests := []struct {
+		name      string
+		delivered int
+		msg       *pb.MessageEnvelope
+	}{
+		{"未投递", 0, &pb.MessageEnvelope{MsgId: "m1", SenderUuid: "sender"}},
+		{"无 msg_id", 1, &pb.MessageEnvelope{SenderUuid: "sender"}},
+		{"无发送者", 1, &pb.MessageEnvelope{MsgId: "m1"}},
+		{"多端同步给自己", 1, &pb.MessageEnvelope{MsgId: "m1", SenderUuid: "receiver"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			conns := &fakeConnManager{
+				sendToUserFn: func(userUUID string, _ []byte) int {
+					calls = append(calls, userUUID)
+					return tt.delivered
+				},
+			}
+			s := &Server{connManager: conns}
+
+			_, err := s.PushToUser(context.Background(), &pb.PushToUserRequest{UserUuid: "receiver", Message: tt.msg})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"receiver"}, calls)
+		})
+	}
+}
@@ -15,20 +15,34 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// ConnManager gRPC 层依赖的连接管理能力（由 *manager.ConnectionManager 实现）。
+type ConnManager interface {
+	SendToDevice(userUUID, deviceID string, msg []byte) bool
+	SendToUser(userUUID string, msg []byte) int
+	SendReliableToDevice(userUUID, deviceID string, build manager.FrameBuilder) bool
+	SendReliableToUser(userUUID string, build manager.FrameBuilder) int
+	KickDevice(userUUID, deviceID string) bool
+	GetOnlineDevices(userUUID string) []string
+}
+
+var _ ConnManager = (*manager.ConnectionManager)(nil)
+
 // Server 封装 connect gRPC 服务的启动与停机。
 type Server struct {
 	pb.UnimplementedConnectServiceServer
-	grpcServer  *grpc.Server
-	connManager *manager.ConnectionManager
-	addr        string
+	grpcServer           *grpc.Server
+	connManager          ConnManager
+	addr                 string
+	broadcastConcurrency int
 }
 
 // NewServer 创建 connect gRPC Server。
 // addr 示例：":9091"。
-func NewServer(addr string, connManager *manager.ConnectionManager) *Server {
+func NewServer(addr string, connManager ConnManager) *Server {
 	s := &Server{
-		connManager: connManager,
-		addr:        addr,
+		connManager:          connManager,
+		addr:                 addr,
+		broadcastConcurrency: defaultBroadcastConcurrency,
 	}
 
 	// 构建拦截器链：Recovery → Metadata → RateLimit → Metrics → Logging
@@ -79,10 +93,27 @@ func (s *Server) Stop() {
 	s.grpcServer.GracefulStop()
 }
 
+// SetBroadcastConcurrency 设置 BroadcastToUsers 并发 worker 数。
+// n <= 0 时回退到默认值。
+func (s *Server) SetBroadcastConcurrency(n int) {
+	if n <= 0 {
+		n = defaultBroadcastConcurrency
+	}
+	s.broadcastConcurrency = n
+}
+
 // ==================== RPC 实现 ====================
 
 // PushToDevice 向指定用户的指定设备投递消息。
 func (s *Server) PushToDevice(ctx context.Context, req *pb.PushToDeviceRequest) (*pb.PushToDeviceResponse, error) {
+	if req.Message.GetAckRequired() {
+		delivered := s.connManager.SendReliableToDevice(req.UserUuid, req.DeviceId, envelopeFrameBuilder(req.Message))
+		if delivered {
+			s.sendDeliveryReceipt(ctx, req.UserUuid, req.Message)
+		}
+		return &pb.PushToDeviceResponse{Delivered: delivered}, nil
+	}
+
 	data, err := proto.Marshal(req.Message)
 	if err != nil {
 		logger.Warn(ctx, "PushToDevice: 序列化 MessageEnvelope 失败",
@@ -92,11 +123,22 @@ func (s *Server) PushToDevice(ctx context.Context, req *pb.PushToDeviceRequest)
 	}
 
 	delivered := s.connManager.SendToDevice(req.UserUuid, req.DeviceId, data)
+	if delivered {
+		s.sendDeliveryReceipt(ctx, req.UserUuid, req.Message)
+	}
 	return &pb.PushToDeviceResponse{Delivered: delivered}, nil
 }
 
 // PushToUser 向用户所有在线设备广播。
 func (s *Server) PushToUser(ctx context.Context, req *pb.PushToUserRequest) (*pb.PushToUserResponse, error) {
+	if req.Message.GetAckRequired() {
+		count := s.connManager.SendReliableToUser(req.UserUuid, envelopeFrameBuilder(req.Message))
+		if count > 0 {
+			s.sendDeliveryReceipt(ctx, req.UserUuid, req.Message)
+		}
+		return &pb.PushToUserResponse{DeliveredCount: int32(count)}, nil
+	}
+
 	data, err := proto.Marshal(req.Message)
 	if err != nil {
 		logger.Warn(ctx, "PushToUser: 序列化 MessageEnvelope 失败",
@@ -106,10 +148,24 @@ func (s *Server) PushToUser(ctx context.Context, req *pb.PushToUserRequest) (*pb
 	}
 
 	count := s.connManager.SendToUser(req.UserUuid, data)
+	if count > 0 {
+		s.sendDeliveryReceipt(ctx, req.UserUuid, req.Message)
+	}
 	return &pb.PushToUserResponse{DeliveredCount: int32(count)}, nil
 }
 
+// envelopeFrameBuilder 为需要回执的消息构造帧：每个连接分配独立 push_id，
+// 因此需复制原始 envelope 后再填充并序列化，避免并发修改请求对象。
+func envelopeFrameBuilder(msg *pb.MessageEnvelope) manager.FrameBuilder {
+	return func(pushID uint64) ([]byte, error) {
+		envelope := proto.Clone(msg).(*pb.MessageEnvelope)
+		envelope.PushId = pushID
+		return proto.Marshal(envelope)
+	}
+}
+
 // BroadcastToUsers 批量向多个用户广播相同的消息。
+// 使用有界 worker 池并发推送，返回成功统计以及离线/失败用户列表。
 func (s *Server) BroadcastToUsers(ctx context.Context, req *pb.BroadcastToUsersRequest) (*pb.BroadcastToUsersResponse, error) {
 	data, err := proto.Marshal(req.Message)
 	if err != nil {
@@ -119,18 +175,20 @@ func (s *Server) BroadcastToUsers(ctx context.Context, req *pb.BroadcastToUsersR
 		return &pb.BroadcastToUsersResponse{}, nil
 	}
 
-	var successCount, totalDelivered int32
-	for _, userUUID := range req.UserUuids {
-		count := s.connManager.SendToUser(userUUID, data)
-		if count > 0 {
-			successCount++
-			totalDelivered += int32(count)
-		}
+	result := fanOutBroadcast(ctx, s.connManager, req.UserUuids, data, s.broadcastConcurrency)
+	if len(result.Failed) > 0 {
+		logger.Warn(ctx, "BroadcastToUsers: 部分用户推送失败",
+			logger.Int("total", len(req.UserUuids)),
+			logger.Int("failed", len(result.Failed)),
+			logger.Int("offline", len(result.Offline)),
+		)
 	}
 
 	return &pb.BroadcastToUsersResponse{
-		SuccessCount:   successCount,
-		TotalDelivered: totalDelivered,
+		SuccessCount:     result.SuccessCount,
+		TotalDelivered:   result.TotalDelivered,
+		OfflineUserUuids: result.Offline,
+		FailedUserUuids:  result.Failed,
 	}, nil
 }
 
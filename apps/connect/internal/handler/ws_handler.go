@@ -45,12 +45,18 @@ func NewWSHandler(connManager *manager.ConnectionManager, connectSvc *svc.Connec
 }
 
 // ServeWS 处理 WebSocket 握手与接入。
+// 停机排空期间直接返回 503，客户端按退避策略重连到其他节点。
 // 执行流程：
-// 1. 从 query 中读取 token/device_id，并获取 client_ip。
-// 2. 调用 connectSvc.Authenticate 做鉴权。
+// 1. 从 query 中读取 token/device_id/heartbeat_interval/platform，并获取 client_ip。
+// 2. 调用 connectSvc.Authenticate 做鉴权，并协商心跳间隔。
 // 3. 构建连接级 context（注入 trace/user/device/ip）。
 // 4. 完成协议升级并进入连接处理主循环。
 func (h *WSHandler) ServeWS(c *gin.Context) {
+	if h.connManager.ShuttingDown() {
+		h.writeHTTPError(c, http.StatusServiceUnavailable, consts.CodeServiceUnavailable)
+		return
+	}
+
 	token := c.Query("token")
 	deviceID := c.Query("device_id")
 	clientIP := ctxmeta.ClientIPFromGin(c)
@@ -63,6 +69,9 @@ func (h *WSHandler) ServeWS(c *gin.Context) {
 		h.writeAuthError(c, err)
 		return
 	}
+	// heartbeat_interval（秒）为客户端期望的心跳间隔，服务端会限制在允许范围内。
+	session.HeartbeatInterval = h.connectSvc.ResolveHeartbeatInterval(c.Query("heartbeat_interval"))
+	session.Platform = c.Query("platform")
 
 	connCtx := context.Background()
 	if traceID := ctxmeta.TraceIDFromGin(c); traceID != "" {
@@ -86,28 +95,43 @@ func (h *WSHandler) ServeWS(c *gin.Context) {
 // handleConnection 承载单个连接的完整生命周期。
 // 关键语义：
 // - 同设备重复连接时，用新连接替换旧连接；
-// - 连接建立/断开分别触发 OnConnect/OnDisconnect；
+// - 连接建立/断开分别触发 OnConnect/OnDisconnect，并上报用户整体在线状态；
+// - 连接建立后首帧下发 type=ready，供客户端发起增量同步；
 // - 日志里保留 user_uuid/device_id 便于排障。
 func (h *WSHandler) handleConnection(ctx context.Context, conn *websocket.Conn, session *svc.Session) {
 	client := manager.NewClient(conn, session.UserUUID, session.DeviceID)
+	client.SetHeartbeatInterval(session.HeartbeatInterval)
+	client.SetPlatform(session.Platform)
+	client.SetIdleThreshold(h.connectSvc.PresenceIdleThreshold())
+	client.EnableRedelivery(h.connManager.RedeliveryPolicy())
 	replaced := h.connManager.Register(client)
 	if replaced != nil {
 		replaced.Close()
 	}
+	// 升级期间进入停机：注册已被拒绝（或连接已被停机流程收走），以 GoingAway 关闭，不上报上线。
+	if h.connManager.ShuttingDown() {
+		client.CloseGracefully()
+		return
+	}
 
 	h.connectSvc.OnConnect(ctx, session)
+	h.observePresence(session.UserUUID)
 	logger.Info(ctx, "WebSocket 连接已建立",
 		logger.String("user_uuid", session.UserUUID),
 		logger.String("device_id", session.DeviceID),
 		logger.String("client_ip", session.ClientIP),
+		logger.Duration("heartbeat_interval", session.HeartbeatInterval),
 		logger.Int("online_count", h.connManager.Count()),
 	)
+	h.sendReadyFrame(ctx, client, session)
 
 	client.Run(ctx, func(raw []byte) {
 		h.handleMessage(ctx, client, session, raw)
 	}, func() {
 		h.connManager.Unregister(client)
+		h.connectSvc.UnsubscribePresence(session)
 		h.connectSvc.OnDisconnect(ctx, session)
+		h.observePresence(session.UserUUID)
 		logger.Info(ctx, "WebSocket 连接已断开",
 			logger.String("user_uuid", session.UserUUID),
 			logger.String("device_id", session.DeviceID),
@@ -116,43 +140,276 @@ func (h *WSHandler) handleConnection(ctx context.Context, conn *websocket.Conn,
 	})
 }
 
+// observePresence 按本节点该用户的连接上报在线状态（活跃/空闲/离线），由 connectSvc 与其他节点汇总后防抖投递变更事件。
+// 跨节点汇总在 connectSvc 的异步协程中执行，读协程不等待 Redis。
+func (h *WSHandler) observePresence(userUUID string) {
+	h.connectSvc.RefreshPresence(userUUID, h.connManager.UserActivity)
+}
+
 // handleMessage 处理客户端上行帧。
+// 任意合法上行帧都会刷新连接的活跃时间；空闲连接恢复活跃时立即上报在线状态。
 // 当前支持：
-// - heartbeat: 更新活跃时间并返回 heartbeat_ack；
-// - message: 预留消息链路（当前仅回 message_ack 占位）。
+// - heartbeat: 更新活跃时间并返回 heartbeat_ack（携带协商后的心跳间隔）；
+// - message: 预留消息链路（当前仅回 message_ack 占位）；
+// - typing: 输入状态透传给会话其他参与者的在线设备（单聊对端/群成员，不持久化，离线直接丢弃）；
+// - ack: 确认 ack_required 下行帧（按 push_id 幂等，不回包）。
+// - resume: 断线重连后按会话 last_seq 补发消息（缺口过大时回 resume_truncated；未配置补发数据源时回 error 帧）；
+// - read: 上报会话已读位置（只前进不后退，不回包）；
+// - subscribe_presence: 覆盖本连接的在线状态订阅列表（回 subscribe_presence_ack）。
 func (h *WSHandler) handleMessage(ctx context.Context, client *manager.Client, session *svc.Session, raw []byte) {
 	envelope, err := h.connectSvc.ParseEnvelope(raw)
 	if err != nil {
+		manager.RecordInboundMessage(inboundTypeInvalid)
 		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
 		return
 	}
+	manager.RecordInboundMessage(inboundMetricType(envelope.Type))
+	if client.MarkActive(time.Now()) {
+		h.observePresence(session.UserUUID)
+	}
 
 	switch envelope.Type {
 	case "heartbeat":
 		h.connectSvc.OnHeartbeat(ctx, session)
-		ack, marshalErr := h.connectSvc.MarshalEnvelope("heartbeat_ack", nil)
+		ack, marshalErr := h.connectSvc.MarshalEnvelope("heartbeat_ack", svc.HeartbeatAckData{
+			Interval: int(session.HeartbeatInterval / time.Second),
+		})
 		if marshalErr != nil {
 			logger.Warn(ctx, "心跳应答序列化失败",
 				logger.ErrorField("error", marshalErr),
 			)
 			return
 		}
-		if !client.Enqueue(ack) {
-			client.Close()
-		}
+		// 入队失败时连接已关闭或已作为慢连接被驱逐（见 Client.Enqueue），无需额外处理。
+		client.Enqueue(ack)
 	case "message":
 		// TODO: 接入 msg 服务进行消息路由与持久化，并返回投递结果回执。
 		ack, marshalErr := h.connectSvc.MarshalEnvelope("message_ack", nil)
-		if marshalErr == nil && !client.Enqueue(ack) {
-			client.Close()
+		if marshalErr == nil {
+			client.Enqueue(ack)
+		}
+	case "typing":
+		h.handleTyping(ctx, client, session, envelope)
+	case "ack":
+		pushID, err := h.connectSvc.ParseAck(envelope.Data)
+		if err != nil {
+			h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+			return
 		}
+		// 重复/过期的 ack 直接忽略：客户端可能在重投后对同一 push_id 多次确认。
+		client.Ack(pushID)
+	case "resume":
+		h.handleResume(ctx, client, session, envelope)
+	case "read":
+		h.handleRead(ctx, client, session, envelope)
+	case "subscribe_presence":
+		h.handleSubscribePresence(ctx, client, session, envelope)
 	default:
 		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageTypeNotSupport)
 	}
 }
 
+// 上行帧指标的 type 标签：无法解析的帧记为 invalid，未支持的类型统一记为 unknown，限制标签基数。
+const (
+	inboundTypeInvalid = "invalid"
+	inboundTypeUnknown = "unknown"
+)
+
+// inboundFrameTypes 已支持的上行帧类型，与 handleMessage 的分支保持一致。
+var inboundFrameTypes = map[string]struct{}{
+	"heartbeat":          {},
+	"message":            {},
+	"typing":             {},
+	"ack":                {},
+	"resume":             {},
+	"read":               {},
+	"subscribe_presence": {},
+}
+
+func inboundMetricType(frameType string) string {
+	if _, ok := inboundFrameTypes[frameType]; ok {
+		return frameType
+	}
+	return inboundTypeUnknown
+}
+
+// handleTyping 处理输入状态帧。
+// 校验失败回 error 帧；被限流、群成员查询失败或接收方不在线时静默丢弃（typing 为瞬时状态，无需补发）。
+// 群聊扇出到各成员的在线连接；不落库、不占用会话 seq。
+func (h *WSHandler) handleTyping(ctx context.Context, client *manager.Client, session *svc.Session, envelope *svc.Envelope) {
+	data, recipients, err := h.connectSvc.ParseTyping(ctx, envelope.Data, session.UserUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, svc.ErrTypingNotMember):
+			h.sendErrorFrame(ctx, client, consts.CodeConnectNotConvMember)
+		case errors.Is(err, svc.ErrTypingConvUnsupported):
+			h.sendErrorFrame(ctx, client, consts.CodeConnectMessageTypeNotSupport)
+		case errors.Is(err, svc.ErrTypingInvalid):
+			h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+		default:
+			logger.Warn(ctx, "typing 帧接收方查询失败",
+				logger.ErrorField("error", err),
+			)
+		}
+		return
+	}
+	if !h.connectSvc.AllowTyping(session, data, time.Now()) {
+		return
+	}
+
+	frame, err := h.connectSvc.MarshalEnvelope("typing", data)
+	if err != nil {
+		logger.Warn(ctx, "typing 帧序列化失败",
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	forwarded := 0
+	for _, recipient := range recipients {
+		forwarded += h.connManager.SendToUser(recipient, frame)
+	}
+	h.connectSvc.RecordTypingForwarded(forwarded)
+}
+
+// TypingForwardedTotal 返回累计转发的 typing 帧数，connectSvc 未配置时为 0。
+func (h *WSHandler) TypingForwardedTotal() int64 {
+	if h.connectSvc == nil {
+		return 0
+	}
+	return h.connectSvc.TypingForwardedTotal()
+}
+
+// handleResume 处理断线续传帧。
+// 下发顺序：补发消息（type=message）→ resume_truncated（如有）→ resume_done。
+// 客户端收到 resume_done 后即可认为已追平，对截断的会话改走 HTTP 拉取。
+// 拉取补发消息最多耗时 2s，在独立协程中执行，不阻塞读协程处理心跳与 ack；
+// 同一连接同时只处理一个 resume，进行中收到的重复 resume 帧直接忽略（进行中的补发结束后同样回 resume_done）。
+func (h *WSHandler) handleResume(ctx context.Context, client *manager.Client, session *svc.Session, envelope *svc.Envelope) {
+	if !h.connectSvc.ResumeEnabled() {
+		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageTypeNotSupport)
+		return
+	}
+	data, err := h.connectSvc.ParseResume(envelope.Data)
+	if err != nil {
+		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+		return
+	}
+	if !client.TryBeginResume() {
+		return
+	}
+
+	go func() {
+		defer client.EndResume()
+		// 连接关闭后取消拉取
+		resumeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-client.Done():
+				cancel()
+			case <-resumeCtx.Done():
+			}
+		}()
+		h.replay(resumeCtx, client, session, data)
+	}()
+}
+
+// replay 拉取并按序下发补发消息、截断会话与结束标记。
+// 补发条数可能远超写队列容量，逐帧以 EnqueueWait 流控入队，避免正常连接被当作慢连接驱逐。
+func (h *WSHandler) replay(ctx context.Context, client *manager.Client, session *svc.Session, data *svc.ResumeData) {
+	messages, truncated := h.connectSvc.Resume(ctx, session, data)
+	for _, msg := range messages {
+		if !h.enqueueReplayFrame(ctx, client, "message", msg) {
+			return
+		}
+	}
+	if len(truncated) > 0 {
+		if !h.enqueueReplayFrame(ctx, client, "resume_truncated", svc.ResumeTruncatedData{ConvIDs: truncated}) {
+			return
+		}
+	}
+	h.enqueueReplayFrame(ctx, client, "resume_done", svc.ResumeDoneData{Replayed: len(messages)})
+}
+
+// enqueueReplayFrame 序列化并流控入队补发帧；连接关闭、ctx 取消或等待超时被驱逐时返回 false。
+func (h *WSHandler) enqueueReplayFrame(ctx context.Context, client *manager.Client, msgType string, data any) bool {
+	payload, err := h.connectSvc.MarshalEnvelope(msgType, data)
+	if err != nil {
+		logger.Warn(ctx, "下行帧序列化失败",
+			logger.String("type", msgType),
+			logger.ErrorField("error", err),
+		)
+		return true
+	}
+	return client.EnqueueWait(ctx, payload)
+}
+
+// handleRead 处理已读上报帧。
+// 校验失败回 error 帧；存储失败仅 log Warn（客户端下次上报更大的 seq 时会覆盖）。
+func (h *WSHandler) handleRead(ctx context.Context, client *manager.Client, session *svc.Session, envelope *svc.Envelope) {
+	data, err := h.connectSvc.ParseRead(envelope.Data, session.UserUUID)
+	if err != nil {
+		if errors.Is(err, svc.ErrReadNotMember) {
+			h.sendErrorFrame(ctx, client, consts.CodeConnectNotConvMember)
+		} else {
+			h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+		}
+		return
+	}
+	if err := h.connectSvc.MarkRead(ctx, session, data); err != nil {
+		logger.Warn(ctx, "已读位置写入失败",
+			logger.String("user_uuid", session.UserUUID),
+			logger.String("conv_id", data.ConvID),
+			logger.Int64("seq", data.Seq),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
+// handleSubscribePresence 处理在线状态订阅帧。
+// 订阅列表整体覆盖；超过上限回 error 帧且保留原订阅。订阅只影响之后的变化推送，当前状态由客户端主动拉取。
+func (h *WSHandler) handleSubscribePresence(ctx context.Context, client *manager.Client, session *svc.Session, envelope *svc.Envelope) {
+	data, err := h.connectSvc.ParseSubscribePresence(envelope.Data)
+	if err != nil {
+		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+		return
+	}
+	count, err := h.connectSvc.SubscribePresence(session, data)
+	if err != nil {
+		h.sendErrorFrame(ctx, client, consts.CodeConnectPresenceSubscribeTooMany)
+		return
+	}
+	h.enqueueFrame(ctx, client, "subscribe_presence_ack", svc.SubscribePresenceAckData{Count: count})
+}
+
+// enqueueFrame 序列化并入队下行帧；发送队列已满时连接被驱逐（见 Client.Enqueue）并返回 false。
+func (h *WSHandler) enqueueFrame(ctx context.Context, client *manager.Client, msgType string, data any) bool {
+	payload, err := h.connectSvc.MarshalEnvelope(msgType, data)
+	if err != nil {
+		logger.Warn(ctx, "下行帧序列化失败",
+			logger.String("type", msgType),
+			logger.ErrorField("error", err),
+		)
+		return true
+	}
+	return client.Enqueue(payload)
+}
+
+// sendReadyFrame 下发 ready 首帧（服务端时间、心跳间隔、未读数、同步水位）。
+// 首帧入队先于读写循环启动，保证客户端收到的第一帧一定是 ready。
+func (h *WSHandler) sendReadyFrame(ctx context.Context, client *manager.Client, session *svc.Session) {
+	payload, err := h.connectSvc.MarshalEnvelope("ready", h.connectSvc.BuildReadyData(ctx, session))
+	if err != nil {
+		logger.Warn(ctx, "ready 帧序列化失败",
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	client.Enqueue(payload)
+}
+
 // sendErrorFrame 发送 ws 协议层错误帧。
-// 发送失败通常表示连接不可写，此时主动关闭连接避免资源泄漏。
+// 写队列已满时连接由 Client.Enqueue 按慢连接驱逐，避免资源泄漏。
 func (h *WSHandler) sendErrorFrame(ctx context.Context, client *manager.Client, code int) {
 	payload, err := h.connectSvc.MarshalEnvelope("error", svc.ErrorData{
 		Code:    code,
@@ -165,22 +422,25 @@ func (h *WSHandler) sendErrorFrame(ctx context.Context, client *manager.Client,
 		)
 		return
 	}
-	if !client.Enqueue(payload) {
-		client.Close()
-	}
+	client.Enqueue(payload)
 }
 
 // writeAuthError 将鉴权错误映射为 HTTP 握手阶段错误响应。
 // 说明：握手前还未升级为 WebSocket，因此用 HTTP JSON 返回更直观。
+// 同时按失败原因计入 connect_ws_auth_failures_total。
 func (h *WSHandler) writeAuthError(c *gin.Context, err error) {
 	switch {
 	case errors.Is(err, svc.ErrTokenRequired):
+		manager.RecordAuthFailure("token_required")
 		h.writeHTTPError(c, http.StatusBadRequest, consts.CodeConnectTokenRequired)
 	case errors.Is(err, svc.ErrDeviceIDRequired):
+		manager.RecordAuthFailure("device_id_required")
 		h.writeHTTPError(c, http.StatusBadRequest, consts.CodeConnectDeviceIDRequired)
 	case errors.Is(err, svc.ErrTokenInvalid):
+		manager.RecordAuthFailure("token_invalid")
 		h.writeHTTPError(c, http.StatusUnauthorized, consts.CodeInvalidToken)
 	default:
+		manager.RecordAuthFailure("internal")
 		h.writeHTTPError(c, http.StatusInternalServerError, consts.CodeInternalError)
 	}
 }
@@ -0,0 +1,530 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var wsHandlerLoggerOnce sync.Once
+
+func initWSHandlerTestLogger() {
+	wsHandlerLoggerOnce.Do(func() {
+		logger.ReplaceGlobal(zap.NewNop())
+	})
+}
+
+type fakeReadyStateSource struct {
+	state svc.ReadyState
+	err   error
+	calls []string
+}
+
+func (f *fakeReadyStateSource) LoadReadyState(_ context.Context, userUUID string) (svc.ReadyState, error) {
+	f.calls = append(f.calls, userUUID)
+	return f.state, f.err
+}
+
+type readyFrame struct {
+	Type string        `json:"type"`
+	Data svc.ReadyData `json:"data"`
+}
+
+// newTestWSServer 启动挂载 /ws 的测试服务，返回 ws:// 地址。
+func newTestWSServer(t *testing.T, source svc.ReadyStateSource) string {
+	t.Helper()
+	initWSHandlerTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	// redisClient=nil：鉴权降级为仅 JWT 校验，无需真实 Redis。
+	connectSvc := svc.NewConnectService(nil, nil, nil)
+	connectSvc.SetReadyStateSource(source)
+	h := NewWSHandler(manager.NewConnectionManager(), connectSvc)
+
+	r := gin.New()
+	r.GET("/ws", h.ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+// dialTestWS 以指定用户/设备完成一次真实的 WebSocket 握手。
+func dialTestWS(t *testing.T, wsURL, userUUID, deviceID string) *websocket.Conn {
+	t.Helper()
+	token, err := util.GenerateToken(userUUID, deviceID)
+	require.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+token+"&device_id="+deviceID+"&heartbeat_interval=45", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// readTestFrame 在超时时间内读取一帧并解析。
+func readTestFrame(t *testing.T, conn *websocket.Conn, timeout time.Duration, v any) error {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+	_, raw, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	require.NoError(t, json.Unmarshal(raw, v))
+	return nil
+}
+
+// dialReadyFrame 完成一次真实的 WebSocket 握手并读取服务端首帧。
+func dialReadyFrame(t *testing.T, source svc.ReadyStateSource) readyFrame {
+	t.Helper()
+	conn := dialTestWS(t, newTestWSServer(t, source), "u1", "d1")
+
+	var frame readyFrame
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	return frame
+}
+
+func TestServeWS_SendsReadyFrameAfterHandshake(t *testing.T) {
+	activity := time.Unix(1760000000, 0)
+	source := &fakeReadyStateSource{state: svc.ReadyState{
+		FriendApplyUnread: 3,
+		LastInboxActivity: activity,
+	}}
+
+	before := time.Now().UnixMilli()
+	frame := dialReadyFrame(t, source)
+
+	assert.Equal(t, "ready", frame.Type)
+	assert.GreaterOrEqual(t, frame.Data.ServerTime, before)
+	assert.LessOrEqual(t, frame.Data.ServerTime, time.Now().UnixMilli())
+	assert.Equal(t, 45, frame.Data.HeartbeatInterval)
+	assert.Equal(t, int64(3), frame.Data.TotalUnread)
+	assert.Equal(t, int64(3), frame.Data.FriendApplyUnread)
+	assert.Equal(t, activity.UnixMilli(), frame.Data.SyncWatermark)
+	assert.False(t, frame.Data.Degraded)
+	assert.Equal(t, []string{"u1"}, source.calls)
+}
+
+func TestServeWS_ReadyFrameDegradedOnCacheError(t *testing.T) {
+	frame := dialReadyFrame(t, &fakeReadyStateSource{err: errors.New("redis down")})
+
+	assert.Equal(t, "ready", frame.Type)
+	assert.True(t, frame.Data.Degraded)
+	assert.Zero(t, frame.Data.TotalUnread)
+	assert.Zero(t, frame.Data.SyncWatermark)
+	assert.NotZero(t, frame.Data.ServerTime)
+}
+
+type typingFrame struct {
+	Type string         `json:"type"`
+	Data svc.TypingData `json:"data"`
+}
+
+type errorFrame struct {
+	Type string        `json:"type"`
+	Data svc.ErrorData `json:"data"`
+}
+
+// dialReadyTestWS 建立连接并消费掉 ready 首帧。
+func dialReadyTestWS(t *testing.T, wsURL, userUUID, deviceID string) *websocket.Conn {
+	t.Helper()
+	conn := dialTestWS(t, wsURL, userUUID, deviceID)
+	var ready readyFrame
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &ready))
+	require.Equal(t, "ready", ready.Type)
+	return conn
+}
+
+func TestServeWS_TypingForwardedToOnlinePeerDevices(t *testing.T) {
+	wsURL := newTestWSServer(t, nil)
+	sender := dialReadyTestWS(t, wsURL, "1001", "d1")
+	peerPhone := dialReadyTestWS(t, wsURL, "1002", "phone")
+	peerPC := dialReadyTestWS(t, wsURL, "1002", "pc")
+	bystander := dialReadyTestWS(t, wsURL, "1003", "d1")
+
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"typing","data":{"conv_id":"p2p-1001-1002","is_typing":true}}`)))
+
+	for _, conn := range []*websocket.Conn{peerPhone, peerPC} {
+		var frame typingFrame
+		require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+		assert.Equal(t, "typing", frame.Type)
+		assert.Equal(t, "p2p-1001-1002", frame.Data.ConvID)
+		assert.Equal(t, "1001", frame.Data.FromUUID)
+		assert.True(t, frame.Data.IsTyping)
+	}
+
+	// 非会话参与者与发送者自身都不会收到 typing 帧
+	var frame typingFrame
+	assert.Error(t, readTestFrame(t, bystander, 200*time.Millisecond, &frame))
+	assert.Error(t, readTestFrame(t, sender, 200*time.Millisecond, &frame))
+}
+
+func TestServeWS_TypingToOfflinePeerDropped(t *testing.T) {
+	wsURL := newTestWSServer(t, nil)
+	sender := dialReadyTestWS(t, wsURL, "1001", "d1")
+
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"typing","data":{"conv_id":"p2p-1001-1009","is_typing":true}}`)))
+
+	// 对端离线：静默丢弃，不回 error 帧
+	var frame errorFrame
+	assert.Error(t, readTestFrame(t, sender, 200*time.Millisecond, &frame))
+
+	// 对端之后上线也不会收到之前的 typing（不持久化）
+	peer := dialReadyTestWS(t, wsURL, "1009", "d1")
+	var typing typingFrame
+	assert.Error(t, readTestFrame(t, peer, 200*time.Millisecond, &typing))
+}
+
+func TestServeWS_TypingNotMemberRejected(t *testing.T) {
+	wsURL := newTestWSServer(t, nil)
+	sender := dialReadyTestWS(t, wsURL, "1001", "d1")
+	peer := dialReadyTestWS(t, wsURL, "1002", "d1")
+
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"typing","data":{"conv_id":"p2p-1002-1003","is_typing":true}}`)))
+
+	var frame errorFrame
+	require.NoError(t, readTestFrame(t, sender, 3*time.Second, &frame))
+	assert.Equal(t, "error", frame.Type)
+	assert.Equal(t, consts.CodeConnectNotConvMember, frame.Data.Code)
+
+	var typing typingFrame
+	assert.Error(t, readTestFrame(t, peer, 200*time.Millisecond, &typing))
+}
+
+type fakeGroupMemberSource struct {
+	members map[string][]string
+}
+
+func (f *fakeGroupMemberSource) GroupMembers(_ context.Context, groupUUID string) ([]string, error) {
+	return f.members[groupUUID], nil
+}
+
+func TestServeWS_GroupTypingFannedOutToOnlineMembers(t *testing.T) {
+	initWSHandlerTestLogger()
+	gin.SetMode(gin.TestMode)
+	connectSvc := svc.NewConnectService(nil, nil, nil)
+	connectSvc.SetGroupMemberSource(&fakeGroupMemberSource{members: map[string][]string{
+		"g-2001": {"1001", "1002", "1003", "1004"},
+	}})
+	h := NewWSHandler(manager.NewConnectionManager(), connectSvc)
+	r := gin.New()
+	r.GET("/ws", h.ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	sender := dialReadyTestWS(t, wsURL, "1001", "d1")
+	member := dialReadyTestWS(t, wsURL, "1002", "d1")
+	outsider := dialReadyTestWS(t, wsURL, "1005", "d1")
+	// 1003 在线，1004 离线（直接丢弃，不计入转发数）
+	other := dialReadyTestWS(t, wsURL, "1003", "d1")
+
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"typing","data":{"conv_id":"g-2001","is_typing":true}}`)))
+
+	for _, conn := range []*websocket.Conn{member, other} {
+		var frame typingFrame
+		require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+		assert.Equal(t, "g-2001", frame.Data.ConvID)
+		assert.Equal(t, "1001", frame.Data.FromUUID)
+	}
+	var frame typingFrame
+	assert.Error(t, readTestFrame(t, outsider, 200*time.Millisecond, &frame))
+	assert.Error(t, readTestFrame(t, sender, 200*time.Millisecond, &frame))
+	assert.Eventually(t, func() bool { return h.TypingForwardedTotal() == 2 }, time.Second, 10*time.Millisecond)
+
+	// 3s 内重复的“正在输入”被连接级限流，不再转发
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"typing","data":{"conv_id":"g-2001","is_typing":true}}`)))
+	assert.Error(t, readTestFrame(t, member, 200*time.Millisecond, &frame))
+	assert.Equal(t, int64(2), h.TypingForwardedTotal())
+}
+
+func TestServeWS_AckIdempotentAndValidated(t *testing.T) {
+	wsURL := newTestWSServer(t, nil)
+	conn := dialReadyTestWS(t, wsURL, "1001", "d1")
+
+	// 未知/重复 push_id 静默忽略，不回包。
+	for i := 0; i < 2; i++ {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+			[]byte(`{"type":"ack","data":{"push_id":42}}`)))
+	}
+	var frame errorFrame
+	assert.Error(t, readTestFrame(t, conn, 200*time.Millisecond, &frame))
+
+	// 缺少 push_id：返回格式错误。
+	conn = dialReadyTestWS(t, wsURL, "1001", "d2")
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ack","data":{}}`)))
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	assert.Equal(t, "error", frame.Type)
+	assert.Equal(t, consts.CodeConnectMessageFormatError, frame.Data.Code)
+}
+
+func TestServeWS_ResumeDisabledWithoutReplaySource(t *testing.T) {
+	wsURL := newTestWSServer(t, nil)
+	conn := dialReadyTestWS(t, wsURL, "1001", "d1")
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"resume","data":{"conv_seqs":{"p2p-1001-1002":7}}}`)))
+	var frame errorFrame
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	assert.Equal(t, "error", frame.Type)
+	assert.Equal(t, consts.CodeConnectMessageTypeNotSupport, frame.Data.Code)
+}
+
+// blockingReplaySource 在 release 关闭前阻塞拉取，用于验证补发不阻塞读协程。
+type blockingReplaySource struct {
+	release chan struct{}
+}
+
+func (b *blockingReplaySource) LoadMessagesAfter(ctx context.Context, _, convID string, afterSeq int64, _ int) ([]svc.ReplayMessage, error) {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if convID == "g-1" {
+		return nil, errors.New("msg unavailable")
+	}
+	return []svc.ReplayMessage{{ConvID: convID, Seq: afterSeq + 1}}, nil
+}
+
+func TestServeWS_ResumeReplaysOffReadLoop(t *testing.T) {
+	initWSHandlerTestLogger()
+	gin.SetMode(gin.TestMode)
+	source := &blockingReplaySource{release: make(chan struct{})}
+	connectSvc := svc.NewConnectService(nil, nil, nil)
+	connectSvc.SetReplaySource(source, 10)
+	h := NewWSHandler(manager.NewConnectionManager(), connectSvc)
+	r := gin.New()
+	r.GET("/ws", h.ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	conn := dialReadyTestWS(t, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", "1001", "d1")
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"resume","data":{"conv_seqs":{"p2p-1001-1002":7,"g-1":3}}}`)))
+	// 补发拉取阻塞期间心跳照常应答。
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"heartbeat"}`)))
+	var frame struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	assert.Equal(t, "heartbeat_ack", frame.Type)
+
+	// conv_seqs 为空：返回格式错误。
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"resume","data":{}}`)))
+	var errFrame errorFrame
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &errFrame))
+	assert.Equal(t, consts.CodeConnectMessageFormatError, errFrame.Data.Code)
+
+	// 进行中重复发送的 resume 被忽略，只收到一轮补发。
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"resume","data":{"conv_seqs":{"p2p-1001-1002":7}}}`)))
+	// 读协程按序处理：收到心跳应答说明重复的 resume 已在补发进行中被处理。
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"heartbeat"}`)))
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	require.Equal(t, "heartbeat_ack", frame.Type)
+	close(source.release)
+
+	var types []string
+	for {
+		require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+		types = append(types, frame.Type)
+		if frame.Type == "resume_done" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"message", "resume_truncated", "resume_done"}, types)
+	var done svc.ResumeDoneData
+	require.NoError(t, json.Unmarshal(frame.Data, &done))
+	assert.Equal(t, 1, done.Replayed)
+	assert.Error(t, readTestFrame(t, conn, 200*time.Millisecond, &frame), "重复的 resume 不应触发第二轮补发")
+}
+
+// fullReplaySource 每个会话都返回 limit 条连续消息，用于构造大批量补发。
+type fullReplaySource struct{}
+
+func (fullReplaySource) LoadMessagesAfter(_ context.Context, _, convID string, afterSeq int64, limit int) ([]svc.ReplayMessage, error) {
+	// Resume 按单会话上限 +1 拉取以判断截断，返回 limit-1 条即恰好达到上限、不被截断。
+	messages := make([]svc.ReplayMessage, 0, limit-1)
+	for i := int64(1); i < int64(limit); i++ {
+		messages = append(messages, svc.ReplayMessage{ConvID: convID, Seq: afterSeq + i})
+	}
+	return messages, nil
+}
+
+func TestServeWS_ResumeLargeReplayDoesNotEvict(t *testing.T) {
+	initWSHandlerTestLogger()
+	gin.SetMode(gin.TestMode)
+	connectSvc := svc.NewConnectService(nil, nil, nil)
+	connectSvc.SetReplaySource(fullReplaySource{}, 100)
+	h := NewWSHandler(manager.NewConnectionManager(), connectSvc)
+	r := gin.New()
+	r.GET("/ws", h.ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	conn := dialReadyTestWS(t, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", "1001", "d1")
+
+	// 3 个会话共 300 条，远超 64 条写队列容量。
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"resume","data":{"conv_seqs":{"g-1":0,"g-2":0,"g-3":0}}}`)))
+	var frame struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	messages := 0
+	for {
+		require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame), "补发过程中连接不应被驱逐")
+		if frame.Type != "message" {
+			break
+		}
+		messages++
+	}
+	require.Equal(t, "resume_done", frame.Type)
+	var done svc.ResumeDoneData
+	require.NoError(t, json.Unmarshal(frame.Data, &done))
+	assert.Equal(t, 300, done.Replayed)
+	assert.Equal(t, 300, messages)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"heartbeat"}`)))
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	assert.Equal(t, "heartbeat_ack", frame.Type)
+}
+
+func TestServeWS_ReadValidated(t *testing.T) {
+	wsURL := newTestWSServer(t, nil)
+	conn := dialReadyTestWS(t, wsURL, "1001", "d1")
+
+	// 合法上报不回包（测试环境无 Redis，已读存储为空直接忽略）。
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"read","data":{"conv_id":"p2p-1001-1002","seq":9}}`)))
+	var frame errorFrame
+	assert.Error(t, readTestFrame(t, conn, 200*time.Millisecond, &frame))
+
+	conn = dialReadyTestWS(t, wsURL, "1001", "d2")
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"read","data":{"conv_id":"p2p-1002-1003","seq":9}}`)))
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	assert.Equal(t, consts.CodeConnectNotConvMember, frame.Data.Code)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"read","data":{"conv_id":"p2p-1001-1002","seq":0}}`)))
+	require.NoError(t, readTestFrame(t, conn, 3*time.Second, &frame))
+	assert.Equal(t, consts.CodeConnectMessageFormatError, frame.Data.Code)
+}
+
+type presenceAudienceFunc func(ctx context.Context, userUUID string) ([]string, error)
+
+func (f presenceAudienceFunc) PresenceAudience(ctx context.Context, userUUID string) ([]string, error) {
+	return f(ctx, userUUID)
+}
+
+type subscribePresenceAckFrame struct {
+	Type string                       `json:"type"`
+	Data svc.SubscribePresenceAckData `json:"data"`
+}
+
+type presenceFrame struct {
+	Type string           `json:"type"`
+	Data svc.PresenceData `json:"data"`
+}
+
+func TestServeWS_SubscribePresence(t *testing.T) {
+	initWSHandlerTestLogger()
+	gin.SetMode(gin.TestMode)
+	connManager := manager.NewConnectionManager()
+	connectSvc := svc.NewConnectService(nil, nil, nil)
+	connectSvc.SetPresenceSubscriptionLimit(2)
+	fanout := svc.NewPresenceFanout(presenceAudienceFunc(func(context.Context, string) ([]string, error) {
+		return []string{"1002", "1003"}, nil
+	}), connManager.SendToUser)
+	fanout.SetSubscriptions(connectSvc.PresenceSubscriptions(), connManager)
+	h := NewWSHandler(connManager, connectSvc)
+	r := gin.New()
+	r.GET("/ws", h.ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	subscribed := dialReadyTestWS(t, wsURL, "1002", "d1")
+	unsubscribed := dialReadyTestWS(t, wsURL, "1003", "d1")
+
+	require.NoError(t, subscribed.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"subscribe_presence","data":{"uuids":["1001","1001"]}}`)))
+	var ack subscribePresenceAckFrame
+	require.NoError(t, readTestFrame(t, subscribed, 3*time.Second, &ack))
+	assert.Equal(t, "subscribe_presence_ack", ack.Type)
+	assert.Equal(t, 1, ack.Data.Count)
+
+	require.NoError(t, unsubscribed.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"subscribe_presence","data":{"uuids":["1009"]}}`)))
+	require.NoError(t, readTestFrame(t, unsubscribed, 3*time.Second, &ack))
+
+	delivered, err := fanout.HandleMessage(context.Background(), []byte(`{"user_uuid":"1001","online":true,"at":1760000000000}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	var frame presenceFrame
+	require.NoError(t, readTestFrame(t, subscribed, 3*time.Second, &frame))
+	assert.Equal(t, "presence", frame.Type)
+	assert.Equal(t, svc.PresenceData{UUID: "1001", Online: true, At: 1760000000000}, frame.Data)
+	assert.Error(t, readTestFrame(t, unsubscribed, 200*time.Millisecond, &frame), "未订阅 1001 的连接不推送")
+
+	// 超过上限与格式错误
+	var errFrame errorFrame
+	require.NoError(t, subscribed.WriteMessage(websocket.TextMessage,
+		[]byte(`{"type":"subscribe_presence","data":{"uuids":["1001","1003","1004"]}}`)))
+	require.NoError(t, readTestFrame(t, subscribed, 3*time.Second, &errFrame))
+	assert.Equal(t, consts.CodeConnectPresenceSubscribeTooMany, errFrame.Data.Code)
+
+	require.NoError(t, subscribed.WriteMessage(websocket.TextMessage, []byte(`{"type":"subscribe_presence"}`)))
+	require.NoError(t, readTestFrame(t, subscribed, 3*time.Second, &errFrame))
+	assert.Equal(t, consts.CodeConnectMessageFormatError, errFrame.Data.Code)
+}
+
+func TestInboundMetricType(t *testing.T) {
+	assert.Equal(t, "heartbeat", inboundMetricType("heartbeat"))
+	assert.Equal(t, "subscribe_presence", inboundMetricType("subscribe_presence"))
+	assert.Equal(t, inboundTypeUnknown, inboundMetricType("whatever"), "未支持的类型不作为标签原样输出")
+}
+
+func TestServeWS_RejectsHandshakeDuringShutdown(t *testing.T) {
+	initWSHandlerTestLogger()
+	gin.SetMode(gin.TestMode)
+	connManager := manager.NewConnectionManager()
+	h := NewWSHandler(connManager, svc.NewConnectService(nil, nil, nil))
+	r := gin.New()
+	r.GET("/ws", h.ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	connManager.Shutdown()
+	token, err := util.GenerateToken("u1", "d1")
+	require.NoError(t, err)
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?token="+token+"&device_id=d1", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Zero(t, connManager.Count())
+}
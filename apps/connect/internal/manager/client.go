@@ -3,6 +3,7 @@ package manager
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -12,17 +13,25 @@ const (
 	defaultSendQueueSize = 64
 	// wsWriteTimeout 单次写操作超时，避免慢连接长期阻塞写协程。
 	wsWriteTimeout = 5 * time.Second
-	// wsPongWait 读取超时窗口：若该时间内未收到任何数据或 Pong，判定连接失活。
+	// wsPongWait 默认读取超时窗口：若该时间内未收到任何数据或 Pong，判定连接失活。
 	wsPongWait = 60 * time.Second
-	// wsPingPeriod 主动 Ping 周期，通常小于 pongWait，确保超时窗口持续被刷新。
-	wsPingPeriod = wsPongWait * 9 / 10
+	// wsIdleTimeoutFactor 空闲超时 = 心跳间隔 * 该系数，容忍单次心跳丢失。
+	wsIdleTimeoutFactor = 2
 	// wsMaxMessageSize 限制单条上行消息大小，防止超大包导致内存风险。
 	wsMaxMessageSize = 1 << 20 // 1MB
+	// defaultIdleThreshold 默认活跃阈值：超过该时间未收到上行帧的连接视为空闲。
+	defaultIdleThreshold = 2 * time.Minute
 	// wsBatchDrainLimit 单次唤醒最多额外清空的排队消息数。
 	// 目的：在高峰期减少 goroutine 调度与锁竞争开销。
 	wsBatchDrainLimit = 16
+	// slowConsumerCloseTimeout 驱逐慢连接时写关闭帧的最长等待，写协程卡在网络写上时不再继续等待。
+	slowConsumerCloseTimeout = time.Second
 )
 
+// CloseSlowConsumer 慢连接驱逐的 WebSocket 关闭码（应用自定义区间 4000~4999），
+// 客户端收到后应按退避策略重连并走增量同步补齐消息。
+const CloseSlowConsumer = 4008
+
 // MessageHandler 定义上行消息回调。
 // 参数 raw 为客户端原始二进制载荷（通常是 JSON 编码后的字节）。
 type MessageHandler func(raw []byte)
@@ -40,22 +49,126 @@ type Client struct {
 	conn     *websocket.Conn
 	userUUID string
 	deviceID string
-	send     chan []byte
+	// platform 客户端平台（已规整，见 NormalizePlatform），用于连接数指标。
+	platform string
+	send     chan outboundFrame
 	done     chan struct{}
 	once     sync.Once
+	// pongWait 连接级读取超时窗口（空闲超时），由心跳间隔决定。
+	pongWait time.Duration
+	// pingPeriod 连接级主动 Ping 周期，始终小于 pongWait。
+	pingPeriod time.Duration
+	// redelivery 需要回执帧的重投缓冲区，nil 表示未启用。
+	redelivery *redeliveryBuffer
+	// draining 停机排空信号：写协程收到后写出积压消息、停机通知帧与 CloseGoingAway 帧。
+	draining   chan struct{}
+	drainOnce  sync.Once
+	drainFrame []byte
+	// lastActive 最近一次收到上行帧（业务消息/心跳）的时间（Unix 纳秒）。
+	// 协议层 Pong 只用于保活，不计入活跃：客户端退到后台只靠 Ping/Pong 维持连接时判定为空闲。
+	lastActive atomic.Int64
+	// idleThreshold 活跃阈值，超过该时间无上行帧视为空闲。
+	idleThreshold time.Duration
+	// evictOnce 保证慢连接驱逐只执行一次（计数与关闭帧不重复）。
+	evictOnce sync.Once
+	// resuming 是否有进行中的断线续传补发（同一连接同时只处理一个）。
+	resuming atomic.Bool
+}
+
+// outboundFrame 写队列中的下行帧，记录入队时间与投递方式用于推送指标。
+type outboundFrame struct {
+	data       []byte
+	kind       string
+	enqueuedAt time.Time
 }
 
 // NewClient 创建连接包装对象。
 func NewClient(conn *websocket.Conn, userUUID, deviceID string) *Client {
-	return &Client{
-		conn:     conn,
-		userUUID: userUUID,
-		deviceID: deviceID,
-		send:     make(chan []byte, defaultSendQueueSize),
-		done:     make(chan struct{}),
+	c := &Client{
+		conn:          conn,
+		userUUID:      userUUID,
+		deviceID:      deviceID,
+		platform:      PlatformUnknown,
+		send:          make(chan outboundFrame, defaultSendQueueSize),
+		done:          make(chan struct{}),
+		draining:      make(chan struct{}),
+		pongWait:      wsPongWait,
+		pingPeriod:    pingPeriodFor(wsPongWait),
+		idleThreshold: defaultIdleThreshold,
+	}
+	// 刚建立的连接视为活跃。
+	c.lastActive.Store(time.Now().UnixNano())
+	return c
+}
+
+// SetPlatform 设置客户端平台（按 NormalizePlatform 规整），必须在 Register 之前调用。
+func (c *Client) SetPlatform(platform string) {
+	c.platform = NormalizePlatform(platform)
+}
+
+// Platform 返回客户端平台。
+func (c *Client) Platform() string {
+	return c.platform
+}
+
+// SetHeartbeatInterval 按协商得到的心跳间隔调整连接的空闲超时与 Ping 周期。
+// 必须在 Run 之前调用；interval<=0 时保持默认值。
+func (c *Client) SetHeartbeatInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	c.pongWait = interval * wsIdleTimeoutFactor
+	c.pingPeriod = pingPeriodFor(c.pongWait)
+}
+
+// SetIdleThreshold 设置活跃阈值。必须在 Run 之前调用；threshold<=0 时保持默认值。
+func (c *Client) SetIdleThreshold(threshold time.Duration) {
+	if threshold > 0 {
+		c.idleThreshold = threshold
 	}
 }
 
+// MarkActive 记录一次上行活动，返回记录前连接是否处于空闲状态（用于及时上报空闲 → 活跃）。
+func (c *Client) MarkActive(now time.Time) (wasIdle bool) {
+	prev := c.lastActive.Swap(now.UnixNano())
+	return now.Sub(time.Unix(0, prev)) > c.effectiveIdleThreshold()
+}
+
+// LastActive 返回最近一次上行活动时间。
+func (c *Client) LastActive() time.Time {
+	return time.Unix(0, c.lastActive.Load())
+}
+
+// IsIdle 判断连接在 now 时刻是否空闲（超过活跃阈值未收到上行帧）。
+func (c *Client) IsIdle(now time.Time) bool {
+	return now.Sub(c.LastActive()) > c.effectiveIdleThreshold()
+}
+
+// effectiveIdleThreshold 实际生效的活跃阈值：不小于空闲超时（心跳间隔 * 2），
+// 避免协商了较长心跳间隔的客户端在两次心跳之间被误判为空闲。
+func (c *Client) effectiveIdleThreshold() time.Duration {
+	return max(c.idleThreshold, c.pongWait)
+}
+
+// EnableRedelivery 为连接启用需要回执帧的重投缓冲区。
+// 必须在 Run 之前调用；策略未启用（任一参数<=0）时保持关闭。
+func (c *Client) EnableRedelivery(policy RedeliveryPolicy) {
+	if !policy.Enabled() {
+		return
+	}
+	c.redelivery = newRedeliveryBuffer(policy)
+}
+
+// IdleTimeout 返回连接的空闲超时时间。
+func (c *Client) IdleTimeout() time.Duration {
+	return c.pongWait
+}
+
+// pingPeriodFor 计算主动 Ping 周期：取超时窗口的 9/10，确保超时窗口持续被刷新。
+func pingPeriodFor(pongWait time.Duration) time.Duration {
+	return pongWait * 9 / 10
+}
+
 func (c *Client) UserUUID() string {
 	return c.userUUID
 }
@@ -70,25 +183,124 @@ func (c *Client) Done() <-chan struct{} {
 	return c.done
 }
 
-// Enqueue 将待发送消息投递到写队列。
+// TryBeginResume 标记开始断线续传补发，已有进行中的补发时返回 false。
+func (c *Client) TryBeginResume() bool {
+	return c.resuming.CompareAndSwap(false, true)
+}
+
+// EndResume 标记断线续传补发结束。
+func (c *Client) EndResume() {
+	c.resuming.Store(false)
+}
+
+// Enqueue 将待发送消息投递到写队列，不阻塞调用方。
+// 写队列已满说明客户端长时间未读取（慢连接），此时以 CloseSlowConsumer 驱逐该连接，
+// 避免推送方阻塞或积压无界增长。
 // 返回值语义：
 // - true：已成功入队；
-// - false：连接已关闭或队列已满（调用方可选择断开连接或丢弃消息）。
+// - false：连接已关闭或因队列已满被驱逐，消息被丢弃。
 func (c *Client) Enqueue(msg []byte) bool {
+	return c.enqueue(msg, frameKindNormal)
+}
+
+func (c *Client) enqueue(msg []byte, kind string) bool {
 	if len(msg) == 0 {
 		return true
 	}
-	cloned := append([]byte(nil), msg...)
+	frame := outboundFrame{data: append([]byte(nil), msg...), kind: kind, enqueuedAt: time.Now()}
 	select {
 	case <-c.done:
 		return false
-	case c.send <- cloned:
+	case c.send <- frame:
 		return true
 	default:
+		c.evictSlowConsumer()
 		return false
 	}
 }
 
+// EnqueueWait 将消息投递到写队列，队列已满时等待写协程腾出空间，用于断线续传等批量下发。
+// 批量下发会在短时间内写满队列，按 Enqueue 的语义会误驱逐正常读取的连接；
+// 这里改为流控：最多等待 wsWriteTimeout，仍无空间才视为慢连接驱逐。
+// ctx 取消或连接关闭时返回 false，不驱逐。
+func (c *Client) EnqueueWait(ctx context.Context, msg []byte) bool {
+	if len(msg) == 0 {
+		return true
+	}
+	frame := outboundFrame{data: append([]byte(nil), msg...), kind: frameKindNormal, enqueuedAt: time.Now()}
+	select {
+	case <-c.done:
+		return false
+	case c.send <- frame:
+		return true
+	default:
+	}
+	timer := time.NewTimer(wsWriteTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.done:
+		return false
+	case <-ctx.Done():
+		return false
+	case c.send <- frame:
+		return true
+	case <-timer.C:
+		c.evictSlowConsumer()
+		return false
+	}
+}
+
+// evictSlowConsumer 驱逐写队列已满的慢连接：异步写出 CloseSlowConsumer 关闭帧后关闭连接。
+// 关闭帧写入可能因写协程卡在网络写上而等待，放到独立 goroutine 避免阻塞推送方。
+// 连接关闭后读写循环退出，由 Run 的关闭回调完成注销。
+func (c *Client) evictSlowConsumer() {
+	c.evictOnce.Do(func() {
+		slowConsumerEvictionsTotal.Inc()
+		go func() {
+			msg := websocket.FormatCloseMessage(CloseSlowConsumer, "slow_consumer")
+			_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(slowConsumerCloseTimeout))
+			c.Close()
+		}()
+	})
+}
+
+// EnqueueReliable 投递需要客户端回执的下行帧。
+// 为帧分配连接内单调递增的 push_id 并登记到重投缓冲区，直到 Ack 或达到最大发送次数。
+// 未启用重投时 push_id 为 0，退化为普通 Enqueue。
+// 返回值语义同 Enqueue；写队列已满时连接被驱逐，缓冲区随连接一起释放。
+func (c *Client) EnqueueReliable(build FrameBuilder) (uint64, bool) {
+	if c.redelivery == nil {
+		frame, err := build(0)
+		if err != nil {
+			return 0, false
+		}
+		return 0, c.enqueue(frame, frameKindReliable)
+	}
+
+	pushID, frame, _, err := c.redelivery.add(build, time.Now())
+	if err != nil {
+		return 0, false
+	}
+	return pushID, c.enqueue(frame, frameKindReliable)
+}
+
+// Ack 确认客户端已收到 push_id 对应的帧，从重投缓冲区移除。
+// 重复确认或未知 push_id 返回 false，不视为错误。
+func (c *Client) Ack(pushID uint64) bool {
+	if c.redelivery == nil {
+		return false
+	}
+	return c.redelivery.ack(pushID)
+}
+
+// PendingAcks 返回等待客户端确认的帧数。
+func (c *Client) PendingAcks() int {
+	if c.redelivery == nil {
+		return 0
+	}
+	return c.redelivery.size()
+}
+
 // Run 启动读写循环并阻塞等待 readLoop 结束。
 // 行为说明：
 // - writeLoop 在独立 goroutine 中运行；
@@ -103,9 +315,9 @@ func (c *Client) Run(ctx context.Context, onMessage MessageHandler, onClose Clos
 	}()
 
 	c.conn.SetReadLimit(wsMaxMessageSize)
-	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 	c.conn.SetPongHandler(func(string) error {
-		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 	})
 
 	go c.writeLoop(ctx)
@@ -133,6 +345,16 @@ func (c *Client) CloseGracefully() {
 	c.Close()
 }
 
+// Drain 通知写协程进入停机排空：先写出队列中积压的消息，再写出 frame（为空时跳过）
+// 与 CloseGoingAway 关闭帧，之后不再写入。连接在客户端回复关闭帧后由读协程关闭；
+// 调用方需在宽限期后调用 Close 兜底。幂等，只有首次调用的 frame 生效。
+func (c *Client) Drain(frame []byte) {
+	c.drainOnce.Do(func() {
+		c.drainFrame = frame
+		close(c.draining)
+	})
+}
+
 // readLoop 持续读取客户端上行帧并交由 onMessage 处理。
 // 注意：ReadMessage 是阻塞调用，不使用 select 轮询 ctx/done。
 // 退出依赖连接关闭（Close）或网络读错误。
@@ -142,6 +364,8 @@ func (c *Client) readLoop(onMessage MessageHandler) {
 		if err != nil {
 			return
 		}
+		// 任意上行帧（含业务心跳）都视为连接活跃，刷新空闲超时窗口。
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 
 		if onMessage != nil {
 			onMessage(raw)
@@ -150,11 +374,19 @@ func (c *Client) readLoop(onMessage MessageHandler) {
 }
 
 // writeLoop 持续从 send 队列取消息写入客户端。
-// 同时按固定周期发送 Ping 保活，收到 Pong 后由读协程刷新读超时。
+// 同时按固定周期发送 Ping 保活，收到 Pong 后由读协程刷新读超时；
+// 启用重投时按重投间隔补发未确认的帧。
 func (c *Client) writeLoop(ctx context.Context) {
-	ticker := time.NewTicker(wsPingPeriod)
+	ticker := time.NewTicker(c.pingPeriod)
 	defer ticker.Stop()
 
+	var redeliverC <-chan time.Time
+	if c.redelivery != nil {
+		redeliverTicker := time.NewTicker(c.redelivery.policy.Interval)
+		defer redeliverTicker.Stop()
+		redeliverC = redeliverTicker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -163,6 +395,11 @@ func (c *Client) writeLoop(ctx context.Context) {
 			return
 		case <-c.done:
 			return
+		case <-c.draining:
+			if err := c.writeDrain(); err != nil {
+				c.Close()
+			}
+			return
 		case msg := <-c.send:
 			if err := c.writeBatch(msg); err != nil {
 				c.Close()
@@ -173,21 +410,30 @@ func (c *Client) writeLoop(ctx context.Context) {
 				c.Close()
 				return
 			}
+		case now := <-redeliverC:
+			frames, _ := c.redelivery.due(now)
+			for _, frame := range frames {
+				if err := c.writeFrame(frame); err != nil {
+					c.Close()
+					return
+				}
+				recordOutbound(frameKindRedelivery, time.Time{})
+			}
 		}
 	}
 }
 
 // writeBatch 先发送当前消息，再尽量清空队列中已积压的消息。
 // 说明：每条业务消息仍保持独立 WebSocket 帧语义，避免破坏上层协议解析。
-func (c *Client) writeBatch(first []byte) error {
-	if err := c.writeFrame(first); err != nil {
+func (c *Client) writeBatch(first outboundFrame) error {
+	if err := c.writeOutbound(first); err != nil {
 		return err
 	}
 
 	for i := 0; i < wsBatchDrainLimit; i++ {
 		select {
 		case msg := <-c.send:
-			if err := c.writeFrame(msg); err != nil {
+			if err := c.writeOutbound(msg); err != nil {
 				return err
 			}
 		default:
@@ -197,6 +443,38 @@ func (c *Client) writeBatch(first []byte) error {
 	return nil
 }
 
+// writeDrain 写出队列中积压的消息、停机通知帧与 CloseGoingAway 关闭帧。
+func (c *Client) writeDrain() error {
+flush:
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.writeOutbound(msg); err != nil {
+				return err
+			}
+		default:
+			break flush
+		}
+	}
+	if len(c.drainFrame) > 0 {
+		if err := c.writeFrame(c.drainFrame); err != nil {
+			return err
+		}
+	}
+	deadline := time.Now().Add(wsWriteTimeout)
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	return c.conn.WriteControl(websocket.CloseMessage, msg, deadline)
+}
+
+// writeOutbound 写出队列中的下行帧，并记录帧数与入队到写出完成的耗时。
+func (c *Client) writeOutbound(frame outboundFrame) error {
+	if err := c.writeFrame(frame.data); err != nil {
+		return err
+	}
+	recordOutbound(frame.kind, frame.enqueuedAt)
+	return nil
+}
+
 // writeFrame 使用 NextWriter 发送单条文本帧。
 // 与直接 WriteMessage 相比，可为后续更细粒度写优化保留扩展点。
 func (c *Client) writeFrame(msg []byte) error {
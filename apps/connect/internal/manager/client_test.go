@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientSetHeartbeatInterval(t *testing.T) {
+	c := NewClient(nil, "u1", "d1")
+	assert.Equal(t, wsPongWait, c.IdleTimeout())
+
+	c.SetHeartbeatInterval(90 * time.Second)
+	assert.Equal(t, 180*time.Second, c.IdleTimeout())
+	assert.Equal(t, 162*time.Second, c.pingPeriod)
+	assert.Less(t, c.pingPeriod, c.IdleTimeout())
+}
+
+func TestClientSetHeartbeatInterval_NonPositiveKeepsDefault(t *testing.T) {
+	c := NewClient(nil, "u1", "d1")
+	c.SetHeartbeatInterval(0)
+	assert.Equal(t, wsPongWait, c.IdleTimeout())
+	assert.Equal(t, pingPeriodFor(wsPongWait), c.pingPeriod)
+}
+
+func TestClientIdleTracking(t *testing.T) {
+	c := NewClient(nil, "u1", "d1")
+	c.SetIdleThreshold(90 * time.Second)
+	now := time.Now()
+	c.MarkActive(now)
+
+	assert.False(t, c.IsIdle(now.Add(90*time.Second)))
+	assert.True(t, c.IsIdle(now.Add(91*time.Second)), "超过活跃阈值无上行帧视为空闲")
+
+	assert.True(t, c.MarkActive(now.Add(2*time.Minute)), "空闲连接收到上行帧返回 wasIdle")
+	assert.False(t, c.IsIdle(now.Add(2*time.Minute)))
+	assert.False(t, c.MarkActive(now.Add(3*time.Minute)))
+}
+
+func TestClientIdleThreshold_NotShorterThanIdleTimeout(t *testing.T) {
+	c := NewClient(nil, "u1", "d1")
+	c.SetIdleThreshold(time.Minute)
+	c.SetHeartbeatInterval(5 * time.Minute)
+	now := time.Now()
+	c.MarkActive(now)
+
+	// 心跳间隔 5 分钟：两次心跳之间不应判定为空闲。
+	assert.False(t, c.IsIdle(now.Add(5*time.Minute)))
+	assert.True(t, c.IsIdle(now.Add(11*time.Minute)))
+}
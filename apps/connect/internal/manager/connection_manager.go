@@ -11,6 +11,8 @@ const (
 	// defaultConnectionBuckets 连接管理默认分桶数量。
 	// 默认 32 桶可在多数场景下显著降低单把大锁竞争。
 	defaultConnectionBuckets = 32
+	// defaultShutdownGrace 停机时等待连接写出剩余消息并完成关闭握手的默认时长。
+	defaultShutdownGrace = time.Second
 )
 
 type userBucket struct {
@@ -24,6 +26,12 @@ type userBucket struct {
 type ConnectionManager struct {
 	userBuckets []userBucket
 	shutdown    atomic.Bool
+	// redelivery 新连接使用的重投策略（零值表示不启用）。
+	redelivery RedeliveryPolicy
+	// shutdownFrame 停机时下发给每个连接的通知帧（如 type=server_shutdown），nil 表示不发送。
+	shutdownFrame []byte
+	// shutdownGrace 停机时等待连接排空的最长时间。
+	shutdownGrace time.Duration
 }
 
 // NewConnectionManager 创建连接管理器实例。
@@ -40,7 +48,8 @@ func NewConnectionManagerWithBuckets(bucketCount int) *ConnectionManager {
 	}
 
 	m := &ConnectionManager{
-		userBuckets: make([]userBucket, bucketCount),
+		userBuckets:   make([]userBucket, bucketCount),
+		shutdownGrace: defaultShutdownGrace,
 	}
 
 	for i := 0; i < bucketCount; i++ {
@@ -52,6 +61,28 @@ func NewConnectionManagerWithBuckets(bucketCount int) *ConnectionManager {
 	return m
 }
 
+// SetRedeliveryPolicy 设置新连接的重投策略。
+// 应在服务启动阶段调用（接收连接之前），运行期不支持并发修改。
+func (m *ConnectionManager) SetRedeliveryPolicy(policy RedeliveryPolicy) {
+	m.redelivery = policy
+}
+
+// SetShutdownDrain 设置停机排空行为：frame 为下发给每个连接的通知帧（nil 表示只发送关闭帧），
+// grace 为等待连接写出剩余消息并完成关闭握手的最长时间（<=0 时使用默认 1s）。
+// 应在服务启动阶段调用，运行期不支持并发修改。
+func (m *ConnectionManager) SetShutdownDrain(frame []byte, grace time.Duration) {
+	if grace <= 0 {
+		grace = defaultShutdownGrace
+	}
+	m.shutdownFrame = frame
+	m.shutdownGrace = grace
+}
+
+// RedeliveryPolicy 返回新连接的重投策略。
+func (m *ConnectionManager) RedeliveryPolicy() RedeliveryPolicy {
+	return m.redelivery
+}
+
 // Register 注册一个设备连接。
 // 返回值 replaced 表示被新连接替换掉的旧连接（如果存在）。
 // 调用方通常应主动关闭 replaced，确保同设备最多一个活跃连接。
@@ -78,10 +109,16 @@ func (m *ConnectionManager) Register(client *Client) (replaced *Client) {
 		userConns = make(map[string]*Client)
 		userBucket.byUser[userUUID] = userConns
 	}
-	if old, ok := userConns[deviceID]; ok && old != client {
+	old, existed := userConns[deviceID]
+	if existed && old == client {
+		return nil
+	}
+	if existed {
 		replaced = old
+		wsConnections.WithLabelValues(old.Platform()).Dec()
 	}
 	userConns[deviceID] = client
+	wsConnections.WithLabelValues(client.Platform()).Inc()
 
 	return replaced
 }
@@ -105,6 +142,7 @@ func (m *ConnectionManager) Unregister(client *Client) {
 		// 防御并发替换：仅当指针一致时才删除，避免误删新连接。
 		if existed, ok := userConns[deviceID]; ok && existed == client {
 			delete(userConns, deviceID)
+			wsConnections.WithLabelValues(client.Platform()).Dec()
 		}
 		if len(userConns) == 0 {
 			delete(userBucket.byUser, userUUID)
@@ -113,7 +151,7 @@ func (m *ConnectionManager) Unregister(client *Client) {
 }
 
 // SendToDevice 向指定用户的指定设备发送消息。
-// 返回 false 表示目标连接不存在或写队列不可用。
+// 返回 false 表示目标连接不存在、已关闭，或写队列已满（该连接随即按慢连接驱逐）。
 func (m *ConnectionManager) SendToDevice(userUUID, deviceID string, msg []byte) bool {
 	userBucket := m.userBucketFor(userUUID)
 
@@ -129,30 +167,63 @@ func (m *ConnectionManager) SendToDevice(userUUID, deviceID string, msg []byte)
 	return client.Enqueue(msg)
 }
 
+// SendReliableToDevice 向指定设备发送需要回执的消息（按连接分配 push_id）。
+// 返回值语义同 SendToDevice。
+func (m *ConnectionManager) SendReliableToDevice(userUUID, deviceID string, build FrameBuilder) bool {
+	userBucket := m.userBucketFor(userUUID)
+
+	userBucket.mu.RLock()
+	var client *Client
+	if userConns, ok := userBucket.byUser[userUUID]; ok {
+		client = userConns[deviceID]
+	}
+	userBucket.mu.RUnlock()
+	if client == nil {
+		return false
+	}
+	_, ok := client.EnqueueReliable(build)
+	return ok
+}
+
+// SendReliableToUser 向用户所有在线设备发送需要回执的消息。
+// 返回成功入队的设备数量。
+func (m *ConnectionManager) SendReliableToUser(userUUID string, build FrameBuilder) int {
+	sent := 0
+	for _, client := range m.userClients(userUUID) {
+		if _, ok := client.EnqueueReliable(build); ok {
+			sent++
+		}
+	}
+	return sent
+}
+
 // SendToUser 向用户的所有在线设备广播消息。
 // 返回成功入队的设备数量，可用于统计下行投递率。
 func (m *ConnectionManager) SendToUser(userUUID string, msg []byte) int {
+	sent := 0
+	for _, client := range m.userClients(userUUID) {
+		if client.Enqueue(msg) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// userClients 复制用户当前的在线连接列表（读锁内只做拷贝，发送在锁外进行）。
+func (m *ConnectionManager) userClients(userUUID string) []*Client {
 	userBucket := m.userBucketFor(userUUID)
 
 	userBucket.mu.RLock()
+	defer userBucket.mu.RUnlock()
 	userConns, ok := userBucket.byUser[userUUID]
 	if !ok || len(userConns) == 0 {
-		userBucket.mu.RUnlock()
-		return 0
+		return nil
 	}
 	clients := make([]*Client, 0, len(userConns))
 	for _, client := range userConns {
 		clients = append(clients, client)
 	}
-	userBucket.mu.RUnlock()
-
-	sent := 0
-	for _, client := range clients {
-		if client.Enqueue(msg) {
-			sent++
-		}
-	}
-	return sent
+	return clients
 }
 
 // Count 返回当前在线连接数（按 user_uuid+device_id 去重后）。
@@ -189,6 +260,39 @@ func (m *ConnectionManager) GetOnlineDevices(userUUID string) []string {
 	return devices
 }
 
+// UserActivity 返回用户在本节点的在线与活跃状态：
+// online 表示至少有一个连接，active 表示至少有一个连接在 now 时刻未空闲。
+func (m *ConnectionManager) UserActivity(userUUID string, now time.Time) (online, active bool) {
+	clients := m.userClients(userUUID)
+	for _, client := range clients {
+		if !client.IsIdle(now) {
+			return true, true
+		}
+	}
+	return len(clients) > 0, false
+}
+
+// ActivitySnapshot 返回本节点全部在线用户的活跃状态（user_uuid -> 任一连接未空闲），用于周期性检测活跃 → 空闲。
+func (m *ConnectionManager) ActivitySnapshot(now time.Time) map[string]bool {
+	snapshot := make(map[string]bool)
+	for i := range m.userBuckets {
+		b := &m.userBuckets[i]
+		b.mu.RLock()
+		for userUUID, userConns := range b.byUser {
+			active := false
+			for _, client := range userConns {
+				if !client.IsIdle(now) {
+					active = true
+					break
+				}
+			}
+			snapshot[userUUID] = active
+		}
+		b.mu.RUnlock()
+	}
+	return snapshot
+}
+
 // KickDevice 强制断开指定用户的指定设备连接。
 // 返回 true 表示连接存在且已被关闭；false 表示目标不在线。
 func (m *ConnectionManager) KickDevice(userUUID, deviceID string) bool {
@@ -206,6 +310,7 @@ func (m *ConnectionManager) KickDevice(userUUID, deviceID string) bool {
 		return false
 	}
 	delete(userConns, deviceID)
+	wsConnections.WithLabelValues(client.Platform()).Dec()
 	if len(userConns) == 0 {
 		delete(userBucket.byUser, userUUID)
 	}
@@ -215,12 +320,36 @@ func (m *ConnectionManager) KickDevice(userUUID, deviceID string) bool {
 	return true
 }
 
+// Snapshot 返回当前在线连接快照（user_uuid -> device_ids），用于滚动发布时导出本节点连接。
+func (m *ConnectionManager) Snapshot() map[string][]string {
+	snapshot := make(map[string][]string)
+	for i := range m.userBuckets {
+		b := &m.userBuckets[i]
+		b.mu.RLock()
+		for userUUID, userConns := range b.byUser {
+			deviceIDs := make([]string, 0, len(userConns))
+			for deviceID := range userConns {
+				deviceIDs = append(deviceIDs, deviceID)
+			}
+			snapshot[userUUID] = deviceIDs
+		}
+		b.mu.RUnlock()
+	}
+	return snapshot
+}
+
+// ShuttingDown 返回是否已进入停机流程（Shutdown 已调用），此后新连接不再被注册。
+func (m *ConnectionManager) ShuttingDown() bool {
+	return m.shutdown.Load()
+}
+
 // Shutdown 关闭全部连接并阻止后续注册。
 // 关闭流程：
 // 1. 标记 shutdown 状态，阻止新连接注册；
 // 2. 收集所有在线连接并从索引中移除；
-// 3. 向所有连接发送 CloseGoingAway 帧，通知客户端服务端正在维护；
-// 4. 等待 1 秒让客户端处理关闭帧；
+// 3. 通知所有连接排空：写出积压消息、停机通知帧（见 SetShutdownDrain）与 CloseGoingAway 帧，
+//    客户端据此以退避策略重连到其他节点；
+// 4. 等待连接完成关闭握手，最长等待宽限期（默认 1 秒）；
 // 5. 强制关闭仍未断开的连接。
 func (m *ConnectionManager) Shutdown() {
 	if !m.shutdown.CompareAndSwap(false, true) {
@@ -234,19 +363,29 @@ func (m *ConnectionManager) Shutdown() {
 		for _, userConns := range b.byUser {
 			for _, client := range userConns {
 				clients = append(clients, client)
+				wsConnections.WithLabelValues(client.Platform()).Dec()
 			}
 		}
 		b.byUser = make(map[string]map[string]*Client)
 		b.mu.Unlock()
 	}
 
-	// 先发送 CloseGoingAway 帧，让客户端感知到优雅关闭。
+	// 由各连接的写协程发送停机通知帧与 CloseGoingAway 帧，避免与正在进行的写操作交错。
 	for _, client := range clients {
-		client.CloseGracefully()
+		client.Drain(m.shutdownFrame)
 	}
 
-	// 等待 1 秒让客户端完成关闭握手，再强制断开残余连接。
-	time.Sleep(1 * time.Second)
+	// 等待客户端完成关闭握手（连接关闭），宽限期到达后强制断开残余连接。
+	timer := time.NewTimer(m.shutdownGrace)
+	defer timer.Stop()
+wait:
+	for _, client := range clients {
+		select {
+		case <-client.Done():
+		case <-timer.C:
+			break wait
+		}
+	}
 
 	for _, client := range clients {
 		client.Close()
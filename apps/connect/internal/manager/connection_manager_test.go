@@ -0,0 +1,219 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialManagedClient 启动 WebSocket 服务端，将连接注册到 m 并运行读写循环，返回客户端连接与服务端 Client。
+func dialManagedClient(t *testing.T, m *ConnectionManager, userUUID, deviceID string) (*websocket.Conn, *Client) {
+	t.Helper()
+	registered := make(chan *Client, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClient(conn, userUUID, deviceID)
+		m.Register(client)
+		registered <- client
+		client.Run(context.Background(), nil, nil)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	select {
+	case client := <-registered:
+		return conn, client
+	case <-time.After(3 * time.Second):
+		t.Fatal("connection not registered")
+		return nil, nil
+	}
+}
+
+func TestConnectionManagerShutdown_WritesShutdownFrameAndCloses(t *testing.T) {
+	m := NewConnectionManager()
+	m.SetShutdownDrain([]byte(`{"type":"server_shutdown","data":{"retry_after_ms":1000}}`), 3*time.Second)
+	conn, client := dialManagedClient(t, m, "u1", "d1")
+
+	// 停机前已入队的消息先于停机通知帧写出
+	require.True(t, client.Enqueue([]byte(`{"type":"message"}`)))
+
+	var frames []string
+	var closeErr *websocket.CloseError
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				closeErr, _ = err.(*websocket.CloseError)
+				return
+			}
+			frames = append(frames, string(raw))
+		}
+	}()
+
+	start := time.Now()
+	m.Shutdown()
+	elapsed := time.Since(start)
+	<-readDone
+
+	assert.Equal(t, []string{`{"type":"message"}`, `{"type":"server_shutdown","data":{"retry_after_ms":1000}}`}, frames)
+	require.NotNil(t, closeErr)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+	assert.Less(t, elapsed, time.Second, "客户端回复关闭帧后无需等满宽限期")
+	assert.Zero(t, m.Count())
+	assert.Nil(t, m.Register(NewClient(nil, "u2", "d1")), "停机后拒绝注册")
+}
+
+func TestConnectionManagerShutdown_ForceClosesAfterGrace(t *testing.T) {
+	m := NewConnectionManager()
+	grace := 200 * time.Millisecond
+	m.SetShutdownDrain(nil, grace)
+	// 客户端不读取，也就不会回复关闭帧
+	_, client := dialManagedClient(t, m, "u1", "d1")
+
+	start := time.Now()
+	m.Shutdown()
+	elapsed := time.Since(start)
+
+	assert.GreaterOrEqual(t, elapsed, grace)
+	assert.Less(t, elapsed, 2*time.Second)
+	select {
+	case <-client.Done():
+	default:
+		t.Fatal("connection should be force-closed after grace period")
+	}
+}
+
+func TestConnectionManagerSetShutdownDrain_DefaultGrace(t *testing.T) {
+	m := NewConnectionManager()
+	assert.Equal(t, defaultShutdownGrace, m.shutdownGrace)
+
+	m.SetShutdownDrain(nil, 0)
+	assert.Equal(t, defaultShutdownGrace, m.shutdownGrace)
+}
+
+func TestConnectionManagerUserActivity(t *testing.T) {
+	m := NewConnectionManager()
+	now := time.Now()
+	idle := NewClient(nil, "u1", "d1")
+	idle.MarkActive(now.Add(-10 * time.Minute))
+	m.Register(idle)
+
+	online, active := m.UserActivity("u1", now)
+	assert.True(t, online)
+	assert.False(t, active, "唯一连接长时间无上行帧")
+	assert.Equal(t, map[string]bool{"u1": false}, m.ActivitySnapshot(now))
+
+	m.Register(NewClient(nil, "u1", "d2"))
+	online, active = m.UserActivity("u1", now)
+	assert.True(t, online)
+	assert.True(t, active, "任一连接活跃即视为活跃")
+	assert.Equal(t, map[string]bool{"u1": true}, m.ActivitySnapshot(now))
+
+	online, active = m.UserActivity("u2", now)
+	assert.False(t, online)
+	assert.False(t, active)
+}
+
+func TestClientEnqueue_EvictsSlowConsumerWhenQueueFull(t *testing.T) {
+	m := NewConnectionManager()
+	registered := make(chan *Client, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		// 不启动 Run：写协程不消费队列，模拟客户端长时间不读取导致的积压。
+		client := NewClient(conn, "slow-u1", "d1")
+		m.Register(client)
+		registered <- client
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var client *Client
+	select {
+	case client = <-registered:
+	case <-time.After(3 * time.Second):
+		t.Fatal("connection not registered")
+	}
+
+	base := testutil.ToFloat64(slowConsumerEvictionsTotal)
+	for i := 0; i < defaultSendQueueSize; i++ {
+		require.True(t, m.SendToDevice("slow-u1", "d1", []byte(`{"type":"message"}`)))
+	}
+	assert.False(t, m.SendToDevice("slow-u1", "d1", []byte(`{"type":"message"}`)), "队列已满时不阻塞推送方")
+	assert.False(t, client.Enqueue([]byte(`{"type":"message"}`)))
+	assert.Equal(t, base+1, testutil.ToFloat64(slowConsumerEvictionsTotal), "同一连接只驱逐一次")
+
+	select {
+	case <-client.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("slow consumer not closed")
+	}
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseSlowConsumer, closeErr.Code)
+	assert.Equal(t, "slow_consumer", closeErr.Text)
+}
+
+func TestClientEnqueueWait_WaitsForQueueSpaceInsteadOfEvicting(t *testing.T) {
+	// 不启动 Run：由测试直接消费写队列，模拟写协程逐步腾出空间。
+	client := NewClient(nil, "u1", "d1")
+	base := testutil.ToFloat64(slowConsumerEvictionsTotal)
+	for i := 0; i < defaultSendQueueSize; i++ {
+		require.True(t, client.Enqueue([]byte(`{"type":"message"}`)))
+	}
+
+	result := make(chan bool, 1)
+	go func() {
+		result <- client.EnqueueWait(context.Background(), []byte(`{"type":"message"}`))
+	}()
+	select {
+	case <-result:
+		t.Fatal("队列已满时应等待而不是立即返回")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-client.send
+	select {
+	case ok := <-result:
+		assert.True(t, ok)
+	case <-time.After(3 * time.Second):
+		t.Fatal("腾出空间后应完成入队")
+	}
+
+	// ctx 取消：放弃入队但不驱逐连接。
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, client.EnqueueWait(ctx, []byte(`{"type":"message"}`)))
+	assert.Equal(t, base, testutil.ToFloat64(slowConsumerEvictionsTotal))
+	select {
+	case <-client.Done():
+		t.Fatal("流控入队不应驱逐连接")
+	default:
+	}
+}
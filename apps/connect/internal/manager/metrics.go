@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// WebSocket 指标定义（注册到默认 Registry，由 connect 内部监听的 /metrics 暴露）
+
+// wsConnections 仪表：本节点当前 WebSocket 连接数
+// 标签：
+//   - platform: 客户端平台（握手 query 参数 platform，见 NormalizePlatform）
+var wsConnections = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "connect_ws_connections",
+		Help: "Current number of WebSocket connections by platform",
+	},
+	[]string{"platform"},
+)
+
+// wsMessagesTotal 计数器：WebSocket 帧数
+// 标签：
+//   - direction: inbound（客户端上行）/ outbound（服务端下行，实际写出后计数）
+//   - type: 上行为帧 type（未知类型为 unknown，无法解析为 invalid）；
+//     下行为投递方式 normal（普通推送）/ reliable（ack_required）/ redelivery（未确认重投）
+var wsMessagesTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "connect_ws_messages_total",
+		Help: "Total number of WebSocket frames by direction and type",
+	},
+	[]string{"direction", "type"},
+)
+
+// wsAuthFailuresTotal 计数器：WebSocket 握手鉴权失败次数
+// 标签：
+//   - reason: token_required / device_id_required / token_invalid / internal
+var wsAuthFailuresTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "connect_ws_auth_failures_total",
+		Help: "Total number of WebSocket handshake authentication failures by reason",
+	},
+	[]string{"reason"},
+)
+
+// slowConsumerEvictionsTotal 计数器：写队列已满被驱逐的慢连接数
+var slowConsumerEvictionsTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "connect_ws_slow_consumer_evictions_total",
+		Help: "Total number of WebSocket connections evicted because their send queue was full",
+	},
+)
+
+// wsPushLatency 直方图：下行帧从入队到写出完成的耗时（含排队等待），反映写队列积压
+var wsPushLatency = promauto.NewHistogram(
+	prometheus.HistogramOpts{
+		Name:    "connect_ws_push_latency_seconds",
+		Help:    "Latency from enqueue to completed write for outbound WebSocket frames in seconds",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	},
+)
+
+// 下行帧投递方式（connect_ws_messages_total 的 type 标签）
+const (
+	frameKindNormal     = "normal"
+	frameKindReliable   = "reliable"
+	frameKindRedelivery = "redelivery"
+)
+
+// PlatformUnknown 未携带或无法识别的平台。
+const PlatformUnknown = "unknown"
+
+// knownPlatforms 允许作为指标标签的平台，限制标签基数。
+var knownPlatforms = map[string]struct{}{
+	"ios":     {},
+	"android": {},
+	"web":     {},
+	"windows": {},
+	"mac":     {},
+	"linux":   {},
+}
+
+// NormalizePlatform 规整客户端上报的平台：转小写，未知取值归为 unknown。
+func NormalizePlatform(platform string) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if _, ok := knownPlatforms[platform]; ok {
+		return platform
+	}
+	return PlatformUnknown
+}
+
+// RecordInboundMessage 记录一条上行帧，frameType 应为已支持的帧类型、unknown 或 invalid。
+func RecordInboundMessage(frameType string) {
+	wsMessagesTotal.WithLabelValues("inbound", frameType).Inc()
+}
+
+// RecordAuthFailure 记录一次握手鉴权失败。
+func RecordAuthFailure(reason string) {
+	wsAuthFailuresTotal.WithLabelValues(reason).Inc()
+}
+
+func recordOutbound(kind string, enqueuedAt time.Time) {
+	wsMessagesTotal.WithLabelValues("outbound", kind).Inc()
+	if !enqueuedAt.IsZero() {
+		wsPushLatency.Observe(time.Since(enqueuedAt).Seconds())
+	}
+}
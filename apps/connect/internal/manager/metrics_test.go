@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePlatform(t *testing.T) {
+	assert.Equal(t, "ios", NormalizePlatform(" iOS "))
+	assert.Equal(t, "android", NormalizePlatform("android"))
+	assert.Equal(t, PlatformUnknown, NormalizePlatform(""))
+	assert.Equal(t, PlatformUnknown, NormalizePlatform("symbian"), "未知平台归为 unknown，限制标签基数")
+}
+
+func TestConnectionManager_ConnectionGaugeByPlatform(t *testing.T) {
+	m := NewConnectionManager()
+	gauge := func(platform string) float64 {
+		return testutil.ToFloat64(wsConnections.WithLabelValues(platform))
+	}
+	baseIOS, baseWeb := gauge("ios"), gauge("web")
+
+	c1 := NewClient(nil, "metrics-u1", "d1")
+	c1.SetPlatform("ios")
+	m.Register(c1)
+	m.Register(c1) // 重复注册同一连接不重复计数
+	assert.Equal(t, baseIOS+1, gauge("ios"))
+
+	// 同设备新连接替换旧连接：旧平台减一、新平台加一；旧连接随后注销不再重复扣减。
+	c2 := NewClient(nil, "metrics-u1", "d1")
+	c2.SetPlatform("web")
+	require.Same(t, c1, m.Register(c2))
+	m.Unregister(c1)
+	assert.Equal(t, baseIOS, gauge("ios"))
+	assert.Equal(t, baseWeb+1, gauge("web"))
+
+	m.Unregister(c2)
+	assert.Equal(t, baseWeb, gauge("web"))
+
+	// 踢下线与停机清空索引时同样扣减（未设置平台的连接计入 unknown）。
+	baseUnknown := gauge(PlatformUnknown)
+	dialManagedClient(t, m, "metrics-u1", "d1")
+	dialManagedClient(t, m, "metrics-u2", "d1")
+	assert.Equal(t, baseUnknown+2, gauge(PlatformUnknown))
+	assert.True(t, m.KickDevice("metrics-u1", "d1"))
+	assert.Equal(t, baseUnknown+1, gauge(PlatformUnknown))
+	m.SetShutdownDrain(nil, 100*time.Millisecond)
+	m.Shutdown()
+	assert.Equal(t, baseUnknown, gauge(PlatformUnknown))
+}
+
+func TestClient_OutboundFramesRecorded(t *testing.T) {
+	m := NewConnectionManager()
+	conn, client := dialManagedClient(t, m, "metrics-u3", "d1")
+	counter := func(kind string) float64 {
+		return testutil.ToFloat64(wsMessagesTotal.WithLabelValues("outbound", kind))
+	}
+	base := counter(frameKindNormal)
+
+	require.True(t, client.Enqueue([]byte(`{"type":"ping"}`)))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	_, _, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return counter(frameKindNormal) == base+1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestRecordAuthFailure(t *testing.T) {
+	base := testutil.ToFloat64(wsAuthFailuresTotal.WithLabelValues("token_invalid"))
+	RecordAuthFailure("token_invalid")
+	assert.Equal(t, base+1, testutil.ToFloat64(wsAuthFailuresTotal.WithLabelValues("token_invalid")))
+}
@@ -0,0 +1,131 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// FrameBuilder 根据连接内分配的 push_id 构造下行帧。
+// 同一条消息推送到不同连接时 push_id 不同，因此需要按连接分别构造。
+type FrameBuilder func(pushID uint64) ([]byte, error)
+
+// RedeliveryPolicy 连接级重投策略。
+// 需要回执的下行帧在收到客户端 ack 前保留在连接的重投缓冲区中，
+// 每隔 Interval 重投一次，累计发送 MaxAttempts 次仍未确认则丢弃。
+type RedeliveryPolicy struct {
+	// Interval 未确认帧的重投间隔，<=0 表示关闭重投。
+	Interval time.Duration
+	// MaxAttempts 单帧最多发送次数（含首次发送）。
+	MaxAttempts int
+	// MaxPending 单连接最多保留的未确认帧数，超出时淘汰最早的帧。
+	MaxPending int
+}
+
+// Enabled 返回策略是否启用重投。
+func (p RedeliveryPolicy) Enabled() bool {
+	return p.Interval > 0 && p.MaxAttempts > 0 && p.MaxPending > 0
+}
+
+// pendingFrame 等待客户端确认的下行帧。
+type pendingFrame struct {
+	frame    []byte
+	attempts int
+	lastSent time.Time
+}
+
+// redeliveryBuffer 单连接的重投缓冲区。
+// pending 保存未确认帧，order 按 push_id 递增记录插入顺序（用于容量淘汰与按序重投）。
+// 已确认/已丢弃的 push_id 在 order 中惰性清理。
+type redeliveryBuffer struct {
+	mu      sync.Mutex
+	policy  RedeliveryPolicy
+	nextID  uint64
+	pending map[uint64]*pendingFrame
+	order   []uint64
+}
+
+func newRedeliveryBuffer(policy RedeliveryPolicy) *redeliveryBuffer {
+	return &redeliveryBuffer{
+		policy:  policy,
+		pending: make(map[uint64]*pendingFrame),
+	}
+}
+
+// add 分配单调递增的 push_id 并登记待确认帧，返回 push_id、帧内容与被淘汰的帧数。
+func (b *redeliveryBuffer) add(build FrameBuilder, now time.Time) (uint64, []byte, int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pushID := b.nextID + 1
+	frame, err := build(pushID)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	b.nextID = pushID
+
+	evicted := 0
+	for len(b.pending) >= b.policy.MaxPending && len(b.order) > 0 {
+		oldest := b.order[0]
+		b.order = b.order[1:]
+		if _, ok := b.pending[oldest]; ok {
+			delete(b.pending, oldest)
+			evicted++
+		}
+	}
+
+	b.pending[pushID] = &pendingFrame{frame: frame, attempts: 1, lastSent: now}
+	b.order = append(b.order, pushID)
+	return pushID, frame, evicted, nil
+}
+
+// ack 确认并移除指定 push_id；重复确认或未知 push_id 返回 false（幂等）。
+func (b *redeliveryBuffer) ack(pushID uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.pending[pushID]; !ok {
+		return false
+	}
+	delete(b.pending, pushID)
+	if len(b.pending) == 0 {
+		b.order = b.order[:0]
+	}
+	return true
+}
+
+// due 返回到期需要重投的帧（按 push_id 顺序），并丢弃已达最大发送次数的帧。
+// 返回 dropped 为本次因次数耗尽被丢弃的帧数。
+func (b *redeliveryBuffer) due(now time.Time) (frames [][]byte, dropped int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	live := b.order[:0]
+	for _, pushID := range b.order {
+		p, ok := b.pending[pushID]
+		if !ok {
+			continue
+		}
+		if now.Sub(p.lastSent) < b.policy.Interval {
+			live = append(live, pushID)
+			continue
+		}
+		if p.attempts >= b.policy.MaxAttempts {
+			delete(b.pending, pushID)
+			dropped++
+			continue
+		}
+		p.attempts++
+		p.lastSent = now
+		frames = append(frames, p.frame)
+		live = append(live, pushID)
+	}
+	b.order = live
+	return frames, dropped
+}
+
+// size 返回未确认帧数量。
+func (b *redeliveryBuffer) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFrameBuilder(pushID uint64) ([]byte, error) {
+	return []byte(strconv.FormatUint(pushID, 10)), nil
+}
+
+func testRedeliveryPolicy() RedeliveryPolicy {
+	return RedeliveryPolicy{Interval: time.Second, MaxAttempts: 3, MaxPending: 4}
+}
+
+func TestRedeliveryBuffer_AckIsIdempotent(t *testing.T) {
+	b := newRedeliveryBuffer(testRedeliveryPolicy())
+	now := time.Now()
+
+	id1, frame, _, err := b.add(testFrameBuilder, now)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), id1)
+	assert.Equal(t, []byte("1"), frame)
+	id2, _, _, err := b.add(testFrameBuilder, now)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), id2)
+
+	assert.True(t, b.ack(id1))
+	assert.False(t, b.ack(id1), "重复 ack 应被忽略")
+	assert.False(t, b.ack(99))
+	assert.Equal(t, 1, b.size())
+
+	// 已确认的帧不再重投。
+	frames, dropped := b.due(now.Add(time.Second))
+	assert.Equal(t, [][]byte{[]byte("2")}, frames)
+	assert.Zero(t, dropped)
+}
+
+func TestRedeliveryBuffer_RedeliversUntilMaxAttempts(t *testing.T) {
+	b := newRedeliveryBuffer(testRedeliveryPolicy())
+	now := time.Now()
+	_, _, _, err := b.add(testFrameBuilder, now)
+	require.NoError(t, err)
+
+	// 未到重投间隔不补发。
+	frames, _ := b.due(now.Add(500 * time.Millisecond))
+	assert.Empty(t, frames)
+
+	// 第 2、3 次发送。
+	frames, _ = b.due(now.Add(time.Second))
+	assert.Len(t, frames, 1)
+	frames, _ = b.due(now.Add(2 * time.Second))
+	assert.Len(t, frames, 1)
+
+	// 已发送 3 次仍未确认：丢弃。
+	frames, dropped := b.due(now.Add(3 * time.Second))
+	assert.Empty(t, frames)
+	assert.Equal(t, 1, dropped)
+	assert.Zero(t, b.size())
+}
+
+func TestRedeliveryBuffer_EvictsOldestWhenFull(t *testing.T) {
+	b := newRedeliveryBuffer(testRedeliveryPolicy())
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		_, _, evicted, err := b.add(testFrameBuilder, now)
+		require.NoError(t, err)
+		assert.Zero(t, evicted)
+	}
+
+	_, _, evicted, err := b.add(testFrameBuilder, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	assert.Equal(t, 4, b.size())
+	assert.False(t, b.ack(1), "最早的帧应被淘汰")
+	assert.True(t, b.ack(5))
+}
+
+func TestClientEnqueueReliable_DisabledUsesZeroPushID(t *testing.T) {
+	c := NewClient(nil, "u1", "d1")
+	c.EnableRedelivery(RedeliveryPolicy{})
+
+	pushID, ok := c.EnqueueReliable(testFrameBuilder)
+	assert.True(t, ok)
+	assert.Zero(t, pushID)
+	assert.Zero(t, c.PendingAcks())
+	assert.False(t, c.Ack(0))
+}
+
+func TestClientEnqueueReliable_TracksUntilAck(t *testing.T) {
+	c := NewClient(nil, "u1", "d1")
+	c.EnableRedelivery(testRedeliveryPolicy())
+
+	pushID, ok := c.EnqueueReliable(testFrameBuilder)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), pushID)
+	assert.Equal(t, []byte("1"), (<-c.send).data)
+	assert.Equal(t, 1, c.PendingAcks())
+
+	assert.True(t, c.Ack(pushID))
+	assert.False(t, c.Ack(pushID))
+	assert.Zero(t, c.PendingAcks())
+}
@@ -5,6 +5,7 @@ import (
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/result"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
@@ -36,6 +37,14 @@ type WSHandshakeRateLimitConfig struct {
 	CleanupInterval time.Duration
 	// MaxEntries 表示可保留的 IP 桶上限，防止内存无限增长。
 	MaxEntries int
+	// GlobalRate 表示全节点每秒允许的握手请求数（<=0 表示不启用全局限流）。
+	// 用于发布后客户端集中重连（reconnect storm）时削峰。
+	GlobalRate float64
+	// GlobalBurst 表示全局令牌桶突发容量。
+	GlobalBurst int
+	// GlobalMaxWait 表示全局令牌不足时允许排队等待的最长时间。
+	// 等待时间在该阈值内的请求会被延迟放行（平滑），超过则直接拒绝并返回 Retry-After。
+	GlobalMaxWait time.Duration
 }
 
 // DefaultWSHandshakeRateLimitConfig 返回默认握手限流参数。
@@ -43,6 +52,9 @@ type WSHandshakeRateLimitConfig struct {
 // - CONNECT_WS_HANDSHAKE_RATE: 每秒握手数（默认 5）
 // - CONNECT_WS_HANDSHAKE_BURST: 突发容量（默认 20）
 // - CONNECT_WS_HANDSHAKE_MAX_ENTRIES: 最大 IP 桶数量（默认 50000）
+// - CONNECT_WS_HANDSHAKE_GLOBAL_RATE: 全节点每秒握手数（默认 500）
+// - CONNECT_WS_HANDSHAKE_GLOBAL_BURST: 全局突发容量（默认 1000）
+// - CONNECT_WS_HANDSHAKE_GLOBAL_MAX_WAIT_MS: 全局排队最长等待毫秒数（默认 2000）
 func DefaultWSHandshakeRateLimitConfig() WSHandshakeRateLimitConfig {
 	return WSHandshakeRateLimitConfig{
 		Rate:            parseFloatEnv("CONNECT_WS_HANDSHAKE_RATE", 5),
@@ -50,6 +62,9 @@ func DefaultWSHandshakeRateLimitConfig() WSHandshakeRateLimitConfig {
 		BucketTTL:       10 * time.Minute,
 		CleanupInterval: 1 * time.Minute,
 		MaxEntries:      parseIntEnv("CONNECT_WS_HANDSHAKE_MAX_ENTRIES", wsHandshakeMaxEntriesDefault),
+		GlobalRate:      parseFloatEnv("CONNECT_WS_HANDSHAKE_GLOBAL_RATE", 500),
+		GlobalBurst:     parseIntEnv("CONNECT_WS_HANDSHAKE_GLOBAL_BURST", 1000),
+		GlobalMaxWait:   time.Duration(parseIntEnv("CONNECT_WS_HANDSHAKE_GLOBAL_MAX_WAIT_MS", 2000)) * time.Millisecond,
 	}
 }
 
@@ -74,16 +89,62 @@ func newHandshakeLimiter(cfg WSHandshakeRateLimitConfig) *handshakeLimiter {
 	return l
 }
 
-func (l *handshakeLimiter) allow(ip string, now time.Time) bool {
+// allow 检查单个 IP 是否允许握手。
+// 被拒绝时返回该 IP 下一个令牌可用前需要等待的时间（用于 Retry-After）。
+func (l *handshakeLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
 	entry := l.getOrCreateEntry(ip, now)
 	if entry == nil {
 		// 容量保护触发时采用降级放行，避免误伤合法握手请求。
-		return true
+		return true, 0
 	}
 	entry.lastSeenUnixNano.Store(now.UnixNano())
 
 	// 只在 entry 级别执行令牌检查，不再持有全局 map 锁。
-	return entry.limiter.AllowN(now, 1)
+	reservation := entry.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// globalHandshakeLimiter 全节点握手令牌桶。
+// 与 per-IP 限流叠加：per-IP 防单点刷连接，全局桶平滑发布后的集中重连。
+type globalHandshakeLimiter struct {
+	limiter *rate.Limiter
+	maxWait time.Duration
+}
+
+func newGlobalHandshakeLimiter(cfg WSHandshakeRateLimitConfig) *globalHandshakeLimiter {
+	if cfg.GlobalRate <= 0 || cfg.GlobalBurst <= 0 {
+		return nil
+	}
+	return &globalHandshakeLimiter{
+		limiter: rate.NewLimiter(rate.Limit(cfg.GlobalRate), cfg.GlobalBurst),
+		maxWait: cfg.GlobalMaxWait,
+	}
+}
+
+// reserve 预占一个全局令牌。
+// 返回值语义：
+// - reservation!=nil：允许握手，调用方需先等待 wait 再继续（wait 可能为 0）；
+// - reservation==nil：超出排队阈值，retryAfter 为建议客户端重试的等待时间。
+// 等待期间放弃握手时须调用 reservation.Cancel() 归还令牌，避免已断开的请求占用后续请求的配额。
+func (g *globalHandshakeLimiter) reserve(now time.Time) (reservation *rate.Reservation, wait time.Duration, retryAfter time.Duration) {
+	reservation = g.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return nil, 0, time.Second
+	}
+	delay := reservation.DelayFrom(now)
+	if delay > g.maxWait {
+		reservation.CancelAt(now)
+		return nil, 0, delay
+	}
+	return reservation, delay, 0
 }
 
 func (l *handshakeLimiter) getOrCreateEntry(ip string, now time.Time) *ipLimiterEntry {
@@ -156,10 +217,13 @@ func (l *handshakeLimiter) evictCount() int {
 	return n
 }
 
-// WSHandshakeRateLimitMiddleware 仅用于 /ws 握手请求限流。
+// WSHandshakeRateLimitMiddleware 仅用于 /ws 握手请求限流（per-IP + 全局两级令牌桶）。
 // 注意：它只限制“建连频率”，不干预 WebSocket 长连接内的消息收发。
+// 被拒绝的请求返回 429 与 Retry-After 头，客户端应按该值（建议叠加随机抖动）退避重连。
 func WSHandshakeRateLimitMiddleware(cfg WSHandshakeRateLimitConfig) gin.HandlerFunc {
-	if cfg.Rate <= 0 || cfg.Burst <= 0 {
+	perIPEnabled := cfg.Rate > 0 && cfg.Burst > 0
+	global := newGlobalHandshakeLimiter(cfg)
+	if !perIPEnabled && global == nil {
 		return func(c *gin.Context) { c.Next() }
 	}
 	if cfg.BucketTTL <= 0 {
@@ -172,40 +236,81 @@ func WSHandshakeRateLimitMiddleware(cfg WSHandshakeRateLimitConfig) gin.HandlerF
 		cfg.MaxEntries = wsHandshakeMaxEntriesDefault
 	}
 
-	limiter := newHandshakeLimiter(cfg)
+	var limiter *handshakeLimiter
+	if perIPEnabled {
+		limiter = newHandshakeLimiter(cfg)
+	}
 	return func(c *gin.Context) {
 		ip := ctxmeta.ClientIPFromGin(c)
 		if ip == "" {
 			ip = c.ClientIP()
 		}
-		if ip == "" {
-			c.Next()
-			return
-		}
 
 		now := time.Now()
-		if limiter.allow(ip, now) {
-			c.Next()
-			return
+		// 1. per-IP 限流：先拦截单个来源的高频握手，避免其消耗全局令牌。
+		if limiter != nil && ip != "" {
+			if ok, retryAfter := limiter.allow(ip, now); !ok {
+				rejectHandshake(c, ip, "ip", retryAfter, now)
+				return
+			}
+		}
+
+		// 2. 全局限流：令牌不足时在阈值内排队等待，超出阈值则拒绝。
+		if global != nil {
+			reservation, wait, retryAfter := global.reserve(now)
+			if reservation == nil {
+				rejectHandshake(c, ip, "global", retryAfter, now)
+				return
+			}
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-c.Request.Context().Done():
+					// 客户端在排队期间断开：归还预占的令牌并放弃本次握手。
+					timer.Stop()
+					reservation.Cancel()
+					c.Abort()
+					return
+				}
+			}
 		}
 
-		logCtx := ctxmeta.BuildContextFromGin(c)
-		logger.Warn(logCtx, "WebSocket 握手请求被限流",
-			logger.String("ip", ip),
-			logger.String("path", c.Request.URL.Path),
-			logger.String("method", c.Request.Method),
-		)
-
-		c.Set("business_code", consts.CodeTooManyRequests)
-		c.JSON(http.StatusTooManyRequests, result.Response{
-			Code:      consts.CodeTooManyRequests,
-			Message:   consts.GetMessage(consts.CodeTooManyRequests),
-			Data:      nil,
-			TraceId:   ctxmeta.TraceIDFromGin(c),
-			Timestamp: now.Unix(),
-		})
-		c.Abort()
+		c.Next()
+	}
+}
+
+// rejectHandshake 输出 429 响应并设置 Retry-After（秒，向上取整，至少 1 秒）。
+func rejectHandshake(c *gin.Context, ip, scope string, retryAfter time.Duration, now time.Time) {
+	retryAfterSeconds := retryAfterSeconds(retryAfter)
+
+	logCtx := ctxmeta.BuildContextFromGin(c)
+	logger.Warn(logCtx, "WebSocket 握手请求被限流",
+		logger.String("ip", ip),
+		logger.String("scope", scope),
+		logger.Int("retry_after", retryAfterSeconds),
+		logger.String("path", c.Request.URL.Path),
+		logger.String("method", c.Request.Method),
+	)
+
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+	c.Set("business_code", consts.CodeTooManyRequests)
+	c.JSON(http.StatusTooManyRequests, result.Response{
+		Code:      consts.CodeTooManyRequests,
+		Message:   consts.GetMessage(consts.CodeTooManyRequests),
+		Data:      nil,
+		TraceId:   ctxmeta.TraceIDFromGin(c),
+		Timestamp: now.Unix(),
+	})
+	c.Abort()
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
 	}
+	return seconds
 }
 
 func parseFloatEnv(key string, fallback float64) float64 {
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var rateLimitTestOnce sync.Once
+
+func newHandshakeTestRouter(cfg WSHandshakeRateLimitConfig) *gin.Engine {
+	rateLimitTestOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		logger.ReplaceGlobal(zap.NewNop())
+	})
+	r := gin.New()
+	r.GET("/ws", WSHandshakeRateLimitMiddleware(cfg), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func doHandshake(r *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func requireRetryAfter(t *testing.T, w *httptest.ResponseRecorder) int {
+	t.Helper()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, retryAfter, 1)
+	return retryAfter
+}
+
+func TestWSHandshakeRateLimit_PerIPRejectsWithRetryAfter(t *testing.T) {
+	r := newHandshakeTestRouter(WSHandshakeRateLimitConfig{
+		Rate:  0.5,
+		Burst: 1,
+	})
+
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.0.1").Code)
+
+	w := doHandshake(r, "10.0.0.1")
+	retryAfter := requireRetryAfter(t, w)
+	assert.Equal(t, 2, retryAfter)
+
+	// 其他 IP 不受影响
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.0.2").Code)
+}
+
+func TestWSHandshakeRateLimit_GlobalRejectsBeyondRate(t *testing.T) {
+	r := newHandshakeTestRouter(WSHandshakeRateLimitConfig{
+		GlobalRate:  1,
+		GlobalBurst: 2,
+	})
+
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.1.1").Code)
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.1.2").Code)
+
+	// 全局令牌耗尽后，不同 IP 的握手同样被拒绝
+	w := doHandshake(r, "10.0.1.3")
+	requireRetryAfter(t, w)
+}
+
+func TestWSHandshakeRateLimit_GlobalDelaysWithinMaxWait(t *testing.T) {
+	r := newHandshakeTestRouter(WSHandshakeRateLimitConfig{
+		GlobalRate:    20,
+		GlobalBurst:   1,
+		GlobalMaxWait: time.Second,
+	})
+
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.2.1").Code)
+
+	// 令牌不足但等待时间在阈值内：延迟放行而非拒绝
+	start := time.Now()
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.2.2").Code)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestWSHandshakeRateLimit_GlobalReturnsTokenOnDisconnect(t *testing.T) {
+	r := newHandshakeTestRouter(WSHandshakeRateLimitConfig{
+		GlobalRate:    10,
+		GlobalBurst:   1,
+		GlobalMaxWait: time.Second,
+	})
+
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.3.1").Code)
+
+	// 排队中的客户端断开：预占的令牌需归还，否则每个断开的请求都会让后续请求多等 100ms
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil).WithContext(ctx)
+		req.RemoteAddr = "10.0.3.2:12345"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+	}
+
+	start := time.Now()
+	assert.Equal(t, http.StatusOK, doHandshake(r, "10.0.3.3").Code)
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, retryAfterSeconds(0))
+	assert.Equal(t, 1, retryAfterSeconds(200*time.Millisecond))
+	assert.Equal(t, 2, retryAfterSeconds(1500*time.Millisecond))
+}
@@ -4,20 +4,25 @@ import (
 	"ChatServer/apps/connect/internal/handler"
 	"ChatServer/apps/connect/internal/manager"
 	"ChatServer/apps/connect/internal/middleware"
+	"ChatServer/config"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/metrics"
 	"ChatServer/pkg/util"
 	"context"
-	"fmt"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Config 定义 connect HTTP 服务的运行参数。
 // 这些超时用于限制异常连接占用资源，避免慢连接拖垮服务。
 type Config struct {
 	Addr              string
+	MetricsAddr       string // 内部监控监听地址，暴露 /metrics（及 /admin/loglevel）；为空时不启动
+	AdminToken        string // 内部监控端口管理接口的访问口令；为空时不挂载 /admin/loglevel
 	ReadHeaderTimeout time.Duration
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
@@ -26,13 +31,21 @@ type Config struct {
 
 // DefaultConfig 返回 connect 服务的默认配置。
 // 端口优先读取 CONNECT_ADDR，未设置时默认监听 :8081。
+// 监控端口读取 CONNECT_METRICS_ADDR，未设置时默认仅绑定本机 127.0.0.1:9092，
+// 避免运维指标随公网 WS 端口对外暴露；容器部署时可设为内网地址供 Prometheus 抓取。
 func DefaultConfig() Config {
 	addr := os.Getenv("CONNECT_ADDR")
 	if addr == "" {
 		addr = ":8081"
 	}
+	metricsAddr, ok := os.LookupEnv("CONNECT_METRICS_ADDR")
+	if !ok {
+		metricsAddr = "127.0.0.1:9092"
+	}
 	return Config{
 		Addr:              addr,
+		MetricsAddr:       metricsAddr,
+		AdminToken:        config.DefaultMetricsConfig().AdminToken,
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       30 * time.Second,
 		WriteTimeout:      30 * time.Second,
@@ -43,14 +56,17 @@ func DefaultConfig() Config {
 // Server 对 http.Server 的轻量封装。
 // 这里集中管理启动和优雅关闭，避免调用方直接操作底层对象。
 type Server struct {
-	httpServer *http.Server
+	httpServer    *http.Server
+	metricsServer *http.Server // 内部监控服务，MetricsAddr 为空时为 nil
 }
 
 // New 构建 Gin 路由并包装成 HTTP Server。
-// 路由职责：
+// 公网监听（Addr）路由职责：
 // - GET /health:   健康检查，返回在线连接数，供容器/探针调用。
-// - GET /metrics:  暴露 Prometheus 文本格式指标（online_connections gauge）。
 // - GET /ws:       WebSocket 接入入口。
+// 内部监听（MetricsAddr）路由职责：
+// - GET /metrics:  暴露 Prometheus 指标（online_connections、typing_forwarded_total 及默认 Registry 中的 connect_ws_* 指标）。
+// - GET/PUT /admin/loglevel: 运行期查看/修改日志级别（需 AdminToken，未配置时不挂载）。
 func New(cfg Config, wsHandler *handler.WSHandler, connManager *manager.ConnectionManager) *Server {
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
@@ -74,17 +90,9 @@ func New(cfg Config, wsHandler *handler.WSHandler, connManager *manager.Connecti
 		})
 	})
 
-	r.GET("/metrics", func(c *gin.Context) {
-		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		c.String(http.StatusOK,
-			fmt.Sprintf("# HELP connect_online_connections Current number of active WebSocket connections.\n"+
-				"# TYPE connect_online_connections gauge\n"+
-				"connect_online_connections %d\n", connManager.Count()))
-	})
-
 	r.GET("/ws", middleware.WSHandshakeRateLimitMiddleware(wsRateLimitCfg), wsHandler.ServeWS)
 
-	return &Server{
+	srv := &Server{
 		httpServer: &http.Server{
 			Addr:              cfg.Addr,
 			Handler:           r,
@@ -94,16 +102,72 @@ func New(cfg Config, wsHandler *handler.WSHandler, connManager *manager.Connecti
 			IdleTimeout:       cfg.IdleTimeout,
 		},
 	}
+	if cfg.MetricsAddr != "" {
+		srv.metricsServer = &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           newMetricsHandler(connManager, wsHandler, cfg.AdminToken),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		}
+	}
+	return srv
+}
+
+// newMetricsHandler 构建内部监控路由，挂载 /metrics，adminToken 非空时挂载 /admin/loglevel。
+// 与公网路由分离，不经过 CORS/握手限流等面向客户端的中间件。
+func newMetricsHandler(connManager *manager.ConnectionManager, wsHandler *handler.WSHandler, adminToken string) http.Handler {
+	mux := http.NewServeMux()
+	if adminToken != "" {
+		mux.Handle("/admin/loglevel", logger.LevelHandler(adminToken))
+	}
+	// 连接数与 typing 转发数在抓取时从实例读取，注册到独立 Registry，
+	// 与默认 Registry（connect_ws_* 指标，见 manager/metrics.go）合并输出，统一附加 service / version 标签。
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "connect_online_connections",
+			Help: "Current number of active WebSocket connections.",
+		}, func() float64 { return float64(connManager.Count()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "connect_typing_forwarded_total",
+			Help: "Total typing frames forwarded to online connections.",
+		}, func() float64 { return float64(wsHandler.TypingForwardedTotal()) }),
+	)
+	mux.Handle("/metrics", metrics.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, reg}))
+	return mux
 }
 
 // Start 启动 HTTP 监听。
+// 内部监控服务在后台启动，失败只记录日志，不影响公网 WS 服务。
 // 正常优雅关闭时会返回 http.ErrServerClosed，调用方应将其视为正常退出。
 func (s *Server) Start() error {
+	if s.metricsServer != nil {
+		go func() {
+			ctx := context.Background()
+			logger.Info(ctx, "Connect Metrics 服务启动中",
+				logger.String("addr", s.metricsServer.Addr),
+			)
+			if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error(ctx, "Connect Metrics 服务启动失败",
+					logger.ErrorField("error", err),
+				)
+			}
+		}()
+	}
 	return s.httpServer.ListenAndServe()
 }
 
 // Shutdown 执行优雅停机。
 // 调用方需要传入带超时的 ctx，以防止无限等待。
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			logger.Warn(ctx, "Connect Metrics 服务关闭失败",
+				logger.ErrorField("error", err),
+			)
+		}
+	}
 	return s.httpServer.Shutdown(ctx)
 }
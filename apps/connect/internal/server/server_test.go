@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ChatServer/apps/connect/internal/handler"
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Setenv("GIN_MODE", "test")
+	connManager := manager.NewConnectionManager()
+	return New(cfg, handler.NewWSHandler(connManager, nil), connManager)
+}
+
+func TestNew_PublicListenerDoesNotServeMetrics(t *testing.T) {
+	srv := newTestServer(t, Config{Addr: ":0", MetricsAddr: "127.0.0.1:0"})
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestNew_MetricsServedOnInternalListener(t *testing.T) {
+	srv := newTestServer(t, Config{Addr: ":0", MetricsAddr: "127.0.0.1:0"})
+	require.NotNil(t, srv.metricsServer)
+
+	rec := httptest.NewRecorder()
+	srv.metricsServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "connect_online_connections 0"))
+	assert.True(t, strings.Contains(rec.Body.String(), "connect_typing_forwarded_total 0"))
+	assert.Contains(t, rec.Body.String(), "# TYPE connect_ws_push_latency_seconds histogram")
+
+	// 内部监听只暴露 /metrics，不承载 WS 入口。
+	rec = httptest.NewRecorder()
+	srv.metricsServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNew_EmptyMetricsAddrDisablesInternalListener(t *testing.T) {
+	srv := newTestServer(t, Config{Addr: ":0"})
+	assert.Nil(t, srv.metricsServer)
+}
+
+func TestNew_AdminLogLevelOnlyWithToken(t *testing.T) {
+	srv := newTestServer(t, Config{Addr: ":0", MetricsAddr: "127.0.0.1:0"})
+	rec := httptest.NewRecorder()
+	srv.metricsServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "未配置口令时不挂载")
+
+	srv = newTestServer(t, Config{Addr: ":0", MetricsAddr: "127.0.0.1:0", AdminToken: "secret"})
+	rec = httptest.NewRecorder()
+	srv.metricsServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	srv.metricsServer.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"level"`)
+}
@@ -0,0 +1,24 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrAckInvalid 表示 ack 帧 data 格式非法（缺少 push_id 或为 0）。
+var ErrAckInvalid = errors.New("ack data is invalid")
+
+// AckData 定义 type=ack 时的 data 结构。
+// push_id 取自下行 MessageEnvelope.push_id，客户端收到 ack_required 的帧后回传。
+type AckData struct {
+	PushID uint64 `json:"push_id"`
+}
+
+// ParseAck 解析 ack 帧，返回待确认的 push_id。
+func (s *ConnectService) ParseAck(raw json.RawMessage) (uint64, error) {
+	var data AckData
+	if len(raw) == 0 || json.Unmarshal(raw, &data) != nil || data.PushID == 0 {
+		return 0, ErrAckInvalid
+	}
+	return data.PushID, nil
+}
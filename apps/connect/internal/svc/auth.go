@@ -28,11 +28,14 @@ var (
 // 1. 校验 token/device_id 是否为空；
 // 2. 解析 JWT，校验 claims 基本字段；
 // 3. 强校验 claims.DeviceID 与 query.device_id 一致；
-// 4. 若 Redis 可用，校验 auth:at:{user_uuid}:{device_id} 中存储的 token md5。
+// 4. 若 Redis 可用，校验 auth:at:{user_uuid}:{device_id} 中存储的 token md5；
+// 4. 校验 claims.Epoch 不低于设备当前纪元 auth:epoch:{user_uuid}:{device_id}（踢出设备时递增），claims.TokenVersion 不低于用户当前版本 auth:token_version:{user_uuid}（修改密码/重置密码/注销时递增）；
+// 5. 若 Redis 可用，校验 auth:at:{user_uuid}:{device_id} 中存储的 token md5；
+// 6. 若第 5 步未能完成校验，查询吊销列表，拒绝已被刷新/踢出的旧 token。
 //
 // 降级策略（Fail-Open）：
-// - 当 Redis 异常不可用时，不直接拒绝连接，而是退化为仅 JWT 校验；
-// - 这样可提升可用性，但会降低"被踢立即失效"的严格性。
+// - 当 Redis 异常不可用时，不直接拒绝连接，而是退化为 JWT + 纪元/版本 + 吊销列表校验；
+// - 纪元/版本/吊销列表也不可达时仅做 JWT 校验，这样可提升可用性，但会降低"被踢立即失效"的严格性。
 func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clientIP string) (*Session, error) {
 	token = strings.TrimSpace(token)
 	deviceID = strings.TrimSpace(deviceID)
@@ -53,8 +56,39 @@ func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clie
 		return nil, ErrTokenInvalid
 	}
 
+	// 设备纪元：被踢出/改密后递增，签发早于当前纪元的 token 一律拒绝。
+	if s.epochSource != nil {
+		currentEpoch, epochErr := s.epochSource.CurrentEpoch(ctx, claims.UserUUID, claims.DeviceID)
+		switch {
+		case epochErr != nil:
+			logger.Warn(ctx, "连接鉴权读取设备令牌纪元失败，跳过纪元校验",
+				logger.String("user_uuid", claims.UserUUID),
+				logger.String("device_id", claims.DeviceID),
+				logger.ErrorField("error", epochErr),
+			)
+		case util.CheckTokenEpoch(claims, currentEpoch) != nil:
+			return nil, ErrTokenInvalid
+		}
+	}
+
+	// 用户令牌版本：修改密码/重置密码/注销后递增，该用户所有设备签发早于当前版本的 token 一律拒绝。
+	if s.versionSource != nil {
+		currentVersion, versionErr := s.versionSource.CurrentVersion(ctx, claims.UserUUID)
+		switch {
+		case versionErr != nil:
+			logger.Warn(ctx, "连接鉴权读取用户令牌版本失败，跳过版本校验",
+				logger.String("user_uuid", claims.UserUUID),
+				logger.String("device_id", claims.DeviceID),
+				logger.ErrorField("error", versionErr),
+			)
+		case util.CheckTokenVersion(claims, currentVersion) != nil:
+			return nil, ErrTokenInvalid
+		}
+	}
+
 	// 与 user/auth 存储规则保持一致：
 	// auth:at:{user_uuid}:{device_id} = md5(access_token)
+	verified := false
 	if s.redisClient != nil {
 		key := rediskey.AccessTokenKey(claims.UserUUID, claims.DeviceID)
 		storedHash, getErr := s.redisClient.Get(ctx, key).Result()
@@ -72,6 +106,22 @@ func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clie
 			if storedHash != md5Hex(token) {
 				return nil, ErrTokenInvalid
 			}
+			verified = true
+		}
+	}
+
+	// per-device 哈希未能校验时，吊销列表兜底拒绝已知失效的 token。
+	if !verified && s.revocation != nil {
+		revoked, revokeErr := s.revocation.IsRevoked(ctx, md5Hex(token))
+		switch {
+		case revokeErr != nil:
+			logger.Warn(ctx, "连接鉴权查询吊销列表失败，降级为仅 JWT 校验",
+				logger.String("user_uuid", claims.UserUUID),
+				logger.String("device_id", claims.DeviceID),
+				logger.ErrorField("error", revokeErr),
+			)
+		case revoked:
+			return nil, ErrTokenInvalid
 		}
 	}
 
@@ -82,9 +132,101 @@ func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clie
 	}, nil
 }
 
+// TokenRevocationChecker 查询 access token 是否已被吊销（刷新/重新登录/踢出后的旧 token）。
+// tokenHash 为 access token 的 md5 十六进制摘要。
+type TokenRevocationChecker interface {
+	IsRevoked(ctx context.Context, tokenHash string) (bool, error)
+}
+
+// redisTokenRevocationChecker 基于 user 服务维护的 auth:revoked:{token_md5} 查询吊销状态。
+type redisTokenRevocationChecker struct {
+	redisClient *redis.Client
+}
+
+// NewRedisTokenRevocationChecker 创建基于 Redis 的吊销列表查询器。
+func NewRedisTokenRevocationChecker(redisClient *redis.Client) TokenRevocationChecker {
+	return &redisTokenRevocationChecker{redisClient: redisClient}
+}
+
+// IsRevoked 判断 token 哈希是否在吊销列表中。
+func (c *redisTokenRevocationChecker) IsRevoked(ctx context.Context, tokenHash string) (bool, error) {
+	n, err := c.redisClient.Exists(ctx, rediskey.RevokedAccessTokenKey(tokenHash)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetTokenRevocationChecker 设置吊销列表查询器。
+// 应在服务启动阶段调用（接收连接之前）；吊销列表与设备 Token 分库部署时可传入独立的实现，
+// 传 nil 表示降级时仅做 JWT 校验。
+func (s *ConnectService) SetTokenRevocationChecker(checker TokenRevocationChecker) {
+	s.revocation = checker
+}
+
+// TokenEpochSource 查询设备当前令牌纪元（由 user 服务在踢出设备/修改密码时递增）。
+type TokenEpochSource interface {
+	CurrentEpoch(ctx context.Context, userUUID, deviceID string) (int64, error)
+}
+
+// redisTokenEpochSource 基于 auth:epoch:{user_uuid}:{device_id} 查询设备令牌纪元。
+type redisTokenEpochSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisTokenEpochSource 创建基于 Redis 的设备令牌纪元查询器。
+func NewRedisTokenEpochSource(redisClient *redis.Client) TokenEpochSource {
+	return &redisTokenEpochSource{redisClient: redisClient}
+}
+
+// CurrentEpoch 读取设备当前纪元，key 不存在时为 0（从未被批量失效）。
+func (e *redisTokenEpochSource) CurrentEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	epoch, err := e.redisClient.Get(ctx, rediskey.TokenEpochKey(userUUID, deviceID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// SetTokenEpochSource 设置设备令牌纪元查询器。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 表示不做纪元校验。
+func (s *ConnectService) SetTokenEpochSource(source TokenEpochSource) {
+	s.epochSource = source
+}
+
 // md5Hex 返回字符串的 MD5 十六进制摘要。
 // 用于与 auth 服务中存储的 access_token 哈希值进行比较。
 func md5Hex(value string) string {
 	sum := md5.Sum([]byte(value))
 	return hex.EncodeToString(sum[:])
 }
+
+// TokenVersionSource 查询用户当前令牌版本（由 user 服务在修改密码/重置密码/注销账号时递增）。
+type TokenVersionSource interface {
+	CurrentVersion(ctx context.Context, userUUID string) (int64, error)
+}
+
+// redisTokenVersionSource 基于 auth:token_version:{user_uuid} 查询用户令牌版本。
+type redisTokenVersionSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisTokenVersionSource 创建基于 Redis 的用户令牌版本查询器。
+func NewRedisTokenVersionSource(redisClient *redis.Client) TokenVersionSource {
+	return &redisTokenVersionSource{redisClient: redisClient}
+}
+
+// CurrentVersion 读取用户当前令牌版本，key 不存在时为 0（从未全局失效）。
+func (v *redisTokenVersionSource) CurrentVersion(ctx context.Context, userUUID string) (int64, error) {
+	version, err := v.redisClient.Get(ctx, rediskey.TokenVersionKey(userUUID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+// SetTokenVersionSource 设置用户令牌版本查询器。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 表示不做版本校验。
+func (s *ConnectService) SetTokenVersionSource(source TokenVersionSource) {
+	s.versionSource = source
+}
@@ -0,0 +1,149 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeTokenRevocationChecker struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (f *fakeTokenRevocationChecker) IsRevoked(_ context.Context, tokenHash string) (bool, error) {
+	return f.revoked[tokenHash], f.err
+}
+
+type fakeTokenEpochSource struct {
+	epochs map[string]int64
+	err    error
+}
+
+func (f *fakeTokenEpochSource) CurrentEpoch(_ context.Context, userUUID, deviceID string) (int64, error) {
+	return f.epochs[userUUID+":"+deviceID], f.err
+}
+
+type fakeTokenVersionSource struct {
+	versions map[string]int64
+}
+
+func (f *fakeTokenVersionSource) CurrentVersion(_ context.Context, userUUID string) (int64, error) {
+	return f.versions[userUUID], nil
+}
+
+// newFailOpenConnectService 返回 Redis 不可达（触发 fail-open）的服务实例。
+func newFailOpenConnectService(t *testing.T) *ConnectService {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	return &ConnectService{redisClient: client}
+}
+
+func TestAuthenticate_FailOpenRejectsRevokedToken(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	revokedToken, err := util.GenerateToken("1001", "d1")
+	require.NoError(t, err)
+	validToken, err := util.GenerateToken("1001", "d2")
+	require.NoError(t, err)
+
+	s := newFailOpenConnectService(t)
+	s.SetTokenRevocationChecker(&fakeTokenRevocationChecker{
+		revoked: map[string]bool{md5Hex(revokedToken): true},
+	})
+
+	// 已吊销但 JWT 未过期：即使 per-device 哈希不可达也拒绝。
+	_, err = s.Authenticate(context.Background(), revokedToken, "d1", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	session, err := s.Authenticate(context.Background(), validToken, "d2", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1001", session.UserUUID)
+}
+
+func TestAuthenticate_FailOpenWhenRevocationUnavailable(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	token, err := util.GenerateToken("1001", "d1")
+	require.NoError(t, err)
+
+	s := newFailOpenConnectService(t)
+	s.SetTokenRevocationChecker(&fakeTokenRevocationChecker{err: errors.New("redis down")})
+
+	// 吊销列表也不可达：降级为仅 JWT 校验。
+	session, err := s.Authenticate(context.Background(), token, "d1", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "d1", session.DeviceID)
+}
+
+func TestAuthenticate_RejectsTokenFromStaleEpoch(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	staleToken, err := util.GenerateTokenWithEpoch("1001", "d1", 1)
+	require.NoError(t, err)
+	currentToken, err := util.GenerateTokenWithEpoch("1001", "d1", 2)
+	require.NoError(t, err)
+
+	s := newFailOpenConnectService(t)
+	s.SetTokenEpochSource(&fakeTokenEpochSource{epochs: map[string]int64{"1001:d1": 2}})
+
+	// 设备被踢出后纪元递增：旧 token 在 fail-open 下同样被拒绝。
+	_, err = s.Authenticate(context.Background(), staleToken, "d1", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	session, err := s.Authenticate(context.Background(), currentToken, "d1", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1001", session.UserUUID)
+}
+
+func TestAuthenticate_RejectsTokenFromStaleVersion(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	staleToken, err := util.GenerateTokenWithVersion("1001", "d1", 0, 1)
+	require.NoError(t, err)
+	otherDeviceToken, err := util.GenerateTokenWithVersion("1001", "d2", 0, 1)
+	require.NoError(t, err)
+	currentToken, err := util.GenerateTokenWithVersion("1001", "d1", 0, 2)
+	require.NoError(t, err)
+
+	s := newFailOpenConnectService(t)
+	s.SetTokenVersionSource(&fakeTokenVersionSource{versions: map[string]int64{"1001": 2}})
+
+	// 重置密码后用户令牌版本递增：该用户所有设备的旧 token 均被拒绝。
+	_, err = s.Authenticate(context.Background(), staleToken, "d1", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+	_, err = s.Authenticate(context.Background(), otherDeviceToken, "d2", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	session, err := s.Authenticate(context.Background(), currentToken, "d1", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1001", session.UserUUID)
+}
+
+func TestAuthenticate_FailOpenWhenEpochUnavailable(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	token, err := util.GenerateToken("1001", "d1")
+	require.NoError(t, err)
+
+	s := newFailOpenConnectService(t)
+	s.SetTokenEpochSource(&fakeTokenEpochSource{err: errors.New("redis down")})
+
+	session, err := s.Authenticate(context.Background(), token, "d1", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "d1", session.DeviceID)
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -17,6 +19,13 @@ type Session struct {
 	UserUUID string
 	DeviceID string
 	ClientIP string
+	// HeartbeatInterval 握手阶段协商得到的心跳间隔，决定连接的空闲超时。
+	HeartbeatInterval time.Duration
+	// Platform 客户端在握手 query 中上报的平台（ios/android/web 等），仅用于监控指标。
+	Platform string
+
+	// typing 连接级 typing 限流状态（conv_id -> 最近一次转发），仅由读协程访问。
+	typing map[string]typingMark
 }
 
 // Envelope 定义 WebSocket 通用消息包格式。
@@ -39,8 +48,31 @@ type ConnectService struct {
 	redisClient      *redis.Client
 	userDeviceClient userpb.DeviceServiceClient // 可为 nil，降级时跳过 RPC
 	activeSyncer     *deviceactive.Syncer
-	statusQueue      chan deviceStatusTask // 设备状态 RPC 任务队列
-	statusWg         sync.WaitGroup        // 等待工作协程退出
+	statusQueue      chan deviceStatusTask  // 设备状态 RPC 任务队列
+	statusWg         sync.WaitGroup         // 等待工作协程退出
+	heartbeatPolicy  HeartbeatPolicy        // 心跳间隔协商策略
+	readySource      ReadyStateSource       // ready 帧状态数据源（可为 nil）
+	replaySource     MessageReplaySource    // resume 补发数据源（可为 nil）
+	resumeMaxPerConv int                    // resume 单会话最多补发条数
+	presence         *PresenceDebouncer     // 在线状态变更防抖投递（可为 nil）
+	presenceIdle     time.Duration          // 连接活跃阈值，超过该时间无上行帧视为空闲
+	presenceAgg      PresenceAggregator     // 跨节点在线状态汇总（可为 nil）
+	presenceQueue    chan presenceRefresh   // 异步汇总队列（启用跨节点汇总时创建）
+	presenceStop     chan struct{}          // 停止异步汇总协程
+	presenceStopOnce sync.Once              // 保证只停止一次
+	presenceWg       sync.WaitGroup         // 等待异步汇总协程退出
+	readStore        ReadPositionStore      // 会话已读位置存储（可为 nil）
+	revocation       TokenRevocationChecker // access token 吊销列表（可为 nil）
+	epochSource      TokenEpochSource       // 设备令牌纪元（可为 nil）
+	versionSource    TokenVersionSource     // 用户令牌版本（可为 nil）
+	groupMembers     GroupMemberSource      // 群成员查询（可为 nil，群聊不转发 typing）
+	typingForwarded  atomic.Int64           // 累计转发的 typing 帧数
+	presenceSubs     *PresenceSubscriptions // 连接级在线状态订阅
+	registry         ConnectionRegistry     // 连接归属登记（可为 nil）
+	nodeID           string                 // 本节点 ID（连接归属登记使用）
+	draining         atomic.Bool            // 是否处于排空状态（滚动发布停机中）
+	drainMu          sync.Mutex
+	drainEntries     []ConnEntry // 排空时导出的本节点连接
 }
 
 // NewConnectService 创建业务服务实例。
@@ -50,6 +82,18 @@ func NewConnectService(redisClient *redis.Client, userDeviceClient userpb.Device
 		redisClient:      redisClient,
 		userDeviceClient: userDeviceClient,
 		activeSyncer:     activeSyncer,
+		heartbeatPolicy:  DefaultHeartbeatPolicy(),
+		resumeMaxPerConv: defaultResumeMaxPerConv,
+		presenceIdle:     defaultPresenceIdleThreshold,
+		presenceSubs:     NewPresenceSubscriptions(defaultPresenceMaxSubscriptions),
+	}
+	if redisClient != nil {
+		s.readySource = NewRedisReadyStateSource(redisClient)
+		s.readStore = NewRedisReadPositionStore(redisClient)
+		s.revocation = NewRedisTokenRevocationChecker(redisClient)
+		s.epochSource = NewRedisTokenEpochSource(redisClient)
+		s.versionSource = NewRedisTokenVersionSource(redisClient)
+		s.groupMembers = NewRedisGroupMemberSource(redisClient)
 	}
 
 	// 仅在 userDeviceClient 可用时启动工作协程。
@@ -73,6 +117,10 @@ func (s *ConnectService) ShutdownStatusWorkers() {
 	if s.activeSyncer != nil {
 		s.activeSyncer.Stop()
 	}
+	s.stopPresenceRefresh()
+	if s.presence != nil {
+		s.presence.Stop()
+	}
 }
 
 // ParseEnvelope 解析客户端上行帧。
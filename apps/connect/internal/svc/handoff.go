@@ -0,0 +1,214 @@
+package svc
+
+import (
+	"context"
+	"time"
+
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/model"
+	"ChatServer/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// handoffBatchSize 单次 Lua 调用处理的连接数，避免大节点排空时脚本阻塞 Redis 过久。
+const handoffBatchSize = 500
+
+// ServerShutdownData 定义 type=server_shutdown 时的 data 结构。
+// 节点停机前下发：客户端应在收到关闭帧后以退避策略重连（由负载均衡路由到其他节点），而不是当作异常断线立即重试。
+type ServerShutdownData struct {
+	RetryAfterMs int64 `json:"retry_after_ms"` // 建议的首次重连延迟，客户端应叠加随机抖动
+}
+
+// ServerShutdownFrame 构造停机通知帧（type=server_shutdown），供 ConnectionManager.SetShutdownDrain 使用。
+func (s *ConnectService) ServerShutdownFrame(retryAfter time.Duration) ([]byte, error) {
+	return s.MarshalEnvelope("server_shutdown", ServerShutdownData{RetryAfterMs: retryAfter.Milliseconds()})
+}
+
+// ConnEntry 表示一条 (user, device) 连接。
+type ConnEntry struct {
+	UserUUID string
+	DeviceID string
+}
+
+// ConnectionRegistry 连接归属登记：记录每个 (user, device) 当前由哪个 connect 节点持有。
+// 用于滚动发布：排空节点断开的连接若已在其他节点重连，则不再上报离线，避免覆盖新节点的在线状态。
+type ConnectionRegistry interface {
+	// Claim 登记连接归属本节点（覆盖旧节点的登记）。
+	Claim(ctx context.Context, entry ConnEntry, nodeID string) error
+	// Release 注销本节点的登记；归属已被其他节点接管时不删除并返回 superseded=true。
+	Release(ctx context.Context, entry ConnEntry, nodeID string) (superseded bool, err error)
+	// Handoff 排空前导出本节点连接：仍归属本节点的登记改为 ttl 后过期，未被重连接管的条目到期自动清除。
+	Handoff(ctx context.Context, nodeID string, entries []ConnEntry, ttl time.Duration) error
+}
+
+// luaReleaseConnOwner 仅当归属仍为本节点时删除；返回 1 表示已被其他节点接管。
+// KEYS[1]: connect:conn:{user_uuid}:{device_id}；ARGV[1]: node_id。
+const luaReleaseConnOwner = `
+local owner = redis.call('GET', KEYS[1])
+if not owner then
+	return 0
+end
+if owner == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+return 1
+`
+
+// luaHandoffConnOwner 将仍归属本节点的登记改为 ttl 毫秒后过期。
+// KEYS: connect:conn:{user_uuid}:{device_id}...；ARGV[1]: node_id；ARGV[2]: ttl 毫秒。
+const luaHandoffConnOwner = `
+for i = 1, #KEYS do
+	if redis.call('GET', KEYS[i]) == ARGV[1] then
+		redis.call('PEXPIRE', KEYS[i], ARGV[2])
+	end
+end
+return 1
+`
+
+var (
+	releaseConnOwnerScript = redis.NewScript(luaReleaseConnOwner)
+	handoffConnOwnerScript = redis.NewScript(luaHandoffConnOwner)
+)
+
+// redisConnectionRegistry 基于 Redis 的连接归属登记（connect:conn:{user_uuid}:{device_id} = node_id）。
+type redisConnectionRegistry struct {
+	redisClient *redis.Client
+}
+
+// NewRedisConnectionRegistry 创建基于 Redis 的连接归属登记。
+func NewRedisConnectionRegistry(redisClient *redis.Client) ConnectionRegistry {
+	return &redisConnectionRegistry{redisClient: redisClient}
+}
+
+// Claim 写入归属并重置 TTL。
+func (r *redisConnectionRegistry) Claim(ctx context.Context, entry ConnEntry, nodeID string) error {
+	key := rediskey.ConnectConnOwnerKey(entry.UserUUID, entry.DeviceID)
+	return r.redisClient.Set(ctx, key, nodeID, rediskey.ConnectConnOwnerTTL).Err()
+}
+
+// Release 原子比较并删除归属。
+func (r *redisConnectionRegistry) Release(ctx context.Context, entry ConnEntry, nodeID string) (bool, error) {
+	key := rediskey.ConnectConnOwnerKey(entry.UserUUID, entry.DeviceID)
+	superseded, err := releaseConnOwnerScript.Run(ctx, r.redisClient, []string{key}, nodeID).Int()
+	if err != nil {
+		return false, err
+	}
+	return superseded == 1, nil
+}
+
+// Handoff 分批为本节点仍持有的登记设置过期时间。
+func (r *redisConnectionRegistry) Handoff(ctx context.Context, nodeID string, entries []ConnEntry, ttl time.Duration) error {
+	for start := 0; start < len(entries); start += handoffBatchSize {
+		end := min(start+handoffBatchSize, len(entries))
+		keys := make([]string, 0, end-start)
+		for _, entry := range entries[start:end] {
+			keys = append(keys, rediskey.ConnectConnOwnerKey(entry.UserUUID, entry.DeviceID))
+		}
+		if err := handoffConnOwnerScript.Run(ctx, r.redisClient, keys, nodeID, ttl.Milliseconds()).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetConnectionRegistry 设置连接归属登记与本节点 ID。
+// 应在服务启动阶段调用（接收连接之前）；registry 为 nil 或 nodeID 为空时不做归属登记，
+// 断开连接总是上报离线（单节点部署的行为）。
+func (s *ConnectService) SetConnectionRegistry(registry ConnectionRegistry, nodeID string) {
+	if nodeID == "" {
+		registry = nil
+	}
+	s.registry = registry
+	s.nodeID = nodeID
+}
+
+// claimConnection 连接建立时登记归属本节点，失败仅 log Warn（不影响连接）。
+func (s *ConnectService) claimConnection(ctx context.Context, session *Session) {
+	if s.registry == nil {
+		return
+	}
+	entry := ConnEntry{UserUUID: session.UserUUID, DeviceID: session.DeviceID}
+	if err := s.registry.Claim(ctx, entry, s.nodeID); err != nil {
+		logger.Warn(ctx, "连接归属登记失败",
+			logger.String("user_uuid", session.UserUUID),
+			logger.String("device_id", session.DeviceID),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
+// releaseConnection 连接断开时注销归属，返回连接是否已被其他节点接管。
+// Redis 异常时按未接管处理（照常上报离线，与未启用登记时一致）。
+func (s *ConnectService) releaseConnection(ctx context.Context, entry ConnEntry) bool {
+	if s.registry == nil {
+		return false
+	}
+	superseded, err := s.registry.Release(ctx, entry, s.nodeID)
+	if err != nil {
+		logger.Warn(ctx, "连接归属注销失败，按未接管处理",
+			logger.String("user_uuid", entry.UserUUID),
+			logger.String("device_id", entry.DeviceID),
+			logger.ErrorField("error", err),
+		)
+		return false
+	}
+	return superseded
+}
+
+// BeginDrain 进入排空状态并导出本节点连接（滚动发布停机前调用，先于断开连接）。
+// 排空期间断开的连接不立即上报离线：客户端通常会在 grace 内重连到其他节点并接管归属；
+// 未被接管的连接由 FinishDrain 统一上报离线，节点异常退出时登记也会在 grace 后过期。
+func (s *ConnectService) BeginDrain(ctx context.Context, devices map[string][]string, grace time.Duration) {
+	entries := make([]ConnEntry, 0, len(devices))
+	for userUUID, deviceIDs := range devices {
+		for _, deviceID := range deviceIDs {
+			entries = append(entries, ConnEntry{UserUUID: userUUID, DeviceID: deviceID})
+		}
+	}
+
+	s.drainMu.Lock()
+	s.drainEntries = entries
+	s.drainMu.Unlock()
+	s.draining.Store(true)
+
+	if s.registry == nil {
+		return
+	}
+	if err := s.registry.Handoff(ctx, s.nodeID, entries, grace); err != nil {
+		logger.Warn(ctx, "导出排空节点连接失败",
+			logger.String("node_id", s.nodeID),
+			logger.Int("entry_count", len(entries)),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
+// FinishDrain 结束排空：对未被其他节点接管的连接上报离线（需在 ShutdownStatusWorkers 之前调用）。
+// 在线状态按用户汇总：本节点的状态全部撤销，仅当其他节点也没有该用户的连接时才投递离线事件。
+// 返回上报离线的连接数。
+func (s *ConnectService) FinishDrain(ctx context.Context) int {
+	s.drainMu.Lock()
+	entries := s.drainEntries
+	s.drainEntries = nil
+	s.drainMu.Unlock()
+
+	offline := 0
+	users := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		users[entry.UserUUID] = struct{}{}
+		if s.releaseConnection(ctx, entry) {
+			continue
+		}
+		session := &Session{UserUUID: entry.UserUUID, DeviceID: entry.DeviceID}
+		s.updateDeviceStatusAsync(ctx, session, model.DeviceStatusOffline)
+		offline++
+	}
+	if s.presence != nil {
+		for userUUID := range users {
+			s.observeAggregatedPresence(userUUID, PresenceOffline)
+		}
+	}
+	return offline
+}
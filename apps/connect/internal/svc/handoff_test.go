@@ -0,0 +1,200 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	userpb "ChatServer/apps/user/pb"
+	"ChatServer/model"
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// fakeConnectionRegistry 内存版连接归属登记，模拟多节点共享的 Redis。
+type fakeConnectionRegistry struct {
+	mu     sync.Mutex
+	owners map[ConnEntry]string
+	ttls   map[ConnEntry]time.Duration
+}
+
+func newFakeConnectionRegistry() *fakeConnectionRegistry {
+	return &fakeConnectionRegistry{
+		owners: make(map[ConnEntry]string),
+		ttls:   make(map[ConnEntry]time.Duration),
+	}
+}
+
+func (f *fakeConnectionRegistry) Claim(_ context.Context, entry ConnEntry, nodeID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.owners[entry] = nodeID
+	delete(f.ttls, entry)
+	return nil
+}
+
+func (f *fakeConnectionRegistry) Release(_ context.Context, entry ConnEntry, nodeID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	owner, ok := f.owners[entry]
+	if !ok {
+		return false, nil
+	}
+	if owner != nodeID {
+		return true, nil
+	}
+	delete(f.owners, entry)
+	return false, nil
+}
+
+func (f *fakeConnectionRegistry) Handoff(_ context.Context, nodeID string, entries []ConnEntry, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, entry := range entries {
+		if f.owners[entry] == nodeID {
+			f.ttls[entry] = ttl
+		}
+	}
+	return nil
+}
+
+func (f *fakeConnectionRegistry) owner(entry ConnEntry) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.owners[entry]
+}
+
+// fakeStatusDeviceClient 记录 UpdateDeviceStatus 调用。
+type fakeStatusDeviceClient struct {
+	userpb.DeviceServiceClient
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeStatusDeviceClient) UpdateDeviceStatus(_ context.Context, in *userpb.UpdateDeviceStatusRequest, _ ...grpc.CallOption) (*userpb.UpdateDeviceStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state := "online"
+	if in.Status == int32(model.DeviceStatusOffline) {
+		state = "offline"
+	}
+	f.calls = append(f.calls, in.DeviceId+":"+state)
+	return &userpb.UpdateDeviceStatusResponse{}, nil
+}
+
+func newHandoffTestNode(registry ConnectionRegistry, nodeID string) (*ConnectService, *fakeStatusDeviceClient) {
+	client := &fakeStatusDeviceClient{}
+	s := NewConnectService(nil, client, nil)
+	s.SetConnectionRegistry(registry, nodeID)
+	return s, client
+}
+
+func TestDrain_ReconnectOnPeerSupersedesDrainedNode(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	ctx := context.Background()
+	registry := newFakeConnectionRegistry()
+	nodeA, clientA := newHandoffTestNode(registry, "node-a")
+	nodeB, clientB := newHandoffTestNode(registry, "node-b")
+
+	d1 := &Session{UserUUID: "1001", DeviceID: "d1"}
+	d2 := &Session{UserUUID: "1001", DeviceID: "d2"}
+	nodeA.OnConnect(ctx, d1)
+	nodeA.OnConnect(ctx, d2)
+
+	// node-a 排空：导出连接后断开，排空期间不上报离线。
+	nodeA.BeginDrain(ctx, map[string][]string{"1001": {"d1", "d2"}}, 5*time.Second)
+	assert.Equal(t, 5*time.Second, registry.ttls[ConnEntry{UserUUID: "1001", DeviceID: "d1"}])
+	nodeA.OnDisconnect(ctx, d1)
+	nodeA.OnDisconnect(ctx, d2)
+
+	// d1 在 node-b 重连，接管归属并清除过期时间；d2 未重连。
+	nodeB.OnConnect(ctx, d1)
+	assert.Equal(t, "node-b", registry.owner(ConnEntry{UserUUID: "1001", DeviceID: "d1"}))
+	assert.NotContains(t, registry.ttls, ConnEntry{UserUUID: "1001", DeviceID: "d1"})
+
+	// 宽限期结束：仅未被接管的 d2 上报离线。
+	assert.Equal(t, 1, nodeA.FinishDrain(ctx))
+	nodeA.ShutdownStatusWorkers()
+	nodeB.ShutdownStatusWorkers()
+
+	assert.ElementsMatch(t, []string{"d1:online", "d2:online", "d2:offline"}, clientA.calls)
+	assert.Equal(t, []string{"d1:online"}, clientB.calls)
+	assert.Equal(t, "node-b", registry.owner(ConnEntry{UserUUID: "1001", DeviceID: "d1"}))
+	assert.Empty(t, registry.owner(ConnEntry{UserUUID: "1001", DeviceID: "d2"}))
+}
+
+func TestOnDisconnect_SkipsOfflineWhenSuperseded(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	ctx := context.Background()
+	registry := newFakeConnectionRegistry()
+	nodeA, clientA := newHandoffTestNode(registry, "node-a")
+	nodeB, _ := newHandoffTestNode(registry, "node-b")
+
+	session := &Session{UserUUID: "1001", DeviceID: "d1"}
+	nodeA.OnConnect(ctx, session)
+	// 客户端先在 node-b 建立新连接，node-a 的旧连接随后才断开。
+	nodeB.OnConnect(ctx, session)
+	nodeA.OnDisconnect(ctx, session)
+	nodeA.ShutdownStatusWorkers()
+	nodeB.ShutdownStatusWorkers()
+
+	require.Equal(t, []string{"d1:online"}, clientA.calls)
+	assert.Equal(t, "node-b", registry.owner(ConnEntry{UserUUID: "1001", DeviceID: "d1"}))
+}
+
+func TestOnDisconnect_ReportsOfflineWithoutRegistry(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	ctx := context.Background()
+	s, client := newHandoffTestNode(newFakeConnectionRegistry(), "")
+
+	session := &Session{UserUUID: "1001", DeviceID: "d1"}
+	s.OnConnect(ctx, session)
+	s.OnDisconnect(ctx, session)
+	s.ShutdownStatusWorkers()
+
+	assert.ElementsMatch(t, []string{"d1:online", "d1:offline"}, client.calls)
+}
+
+func TestFinishDrain_SkipsPresenceOfflineWhenOnlineOnPeer(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	ctx := context.Background()
+	registry := newFakeConnectionRegistry()
+	aggregator := newFakePresenceAggregator()
+	nodeA, _ := newHandoffTestNode(registry, "node-a")
+	nodeB, _ := newHandoffTestNode(registry, "node-b")
+	// 窗口足够长，后台协程不会在测试期间投递，由测试手动取出到期事件。
+	debouncerA := NewPresenceDebouncer(time.Minute, &fakePresencePublisher{})
+	debouncerA.Start()
+	nodeA.SetPresenceDebouncer(debouncerA)
+	nodeA.SetPresenceAggregator(aggregator)
+	debouncerB := NewPresenceDebouncer(time.Minute, &fakePresencePublisher{})
+	debouncerB.Start()
+	nodeB.SetPresenceDebouncer(debouncerB)
+	nodeB.SetPresenceAggregator(aggregator)
+
+	// u1 的 d1 连 node-a、d2 连 node-b；u2 仅连 node-a。
+	nodeA.OnConnect(ctx, &Session{UserUUID: "u1", DeviceID: "d1"})
+	nodeA.ObservePresence("u1", PresenceActive)
+	nodeA.OnConnect(ctx, &Session{UserUUID: "u2", DeviceID: "d1"})
+	nodeA.ObservePresence("u2", PresenceActive)
+	nodeB.OnConnect(ctx, &Session{UserUUID: "u1", DeviceID: "d2"})
+	nodeB.ObservePresence("u1", PresenceActive)
+	debouncerA.due(time.Now().Add(time.Second), true)
+
+	nodeA.BeginDrain(ctx, map[string][]string{"u1": {"d1"}, "u2": {"d1"}}, 5*time.Second)
+	assert.Equal(t, 2, nodeA.FinishDrain(ctx))
+
+	// 仅 u2 投递离线；u1 仍在 node-b 在线。
+	events := debouncerA.due(time.Now().Add(time.Second), true)
+	nodeA.ShutdownStatusWorkers()
+	nodeB.ShutdownStatusWorkers()
+	require.Len(t, events, 1)
+	assert.Equal(t, "u2", events[0].UserUUID)
+	assert.Equal(t, PresenceOffline, events[0].State)
+}
@@ -0,0 +1,61 @@
+package svc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeartbeatPolicy 定义服务端允许的心跳间隔范围。
+// 移动端在弱网/低电量场景下可申请更长的心跳间隔，服务端将其限制在 [Min, Max] 内。
+type HeartbeatPolicy struct {
+	Min     time.Duration
+	Max     time.Duration
+	Default time.Duration
+}
+
+// DefaultHeartbeatPolicy 返回默认心跳策略（30s，允许 10s ~ 5min）。
+func DefaultHeartbeatPolicy() HeartbeatPolicy {
+	return HeartbeatPolicy{
+		Min:     10 * time.Second,
+		Max:     5 * time.Minute,
+		Default: 30 * time.Second,
+	}
+}
+
+// HeartbeatAckData 定义 type=heartbeat_ack 时的 data 结构。
+// Interval 为服务端最终采纳的心跳间隔（秒），客户端应按该值发送心跳。
+type HeartbeatAckData struct {
+	Interval int `json:"interval"`
+}
+
+// SetHeartbeatPolicy 设置心跳间隔协商策略。
+// 应在服务启动阶段调用（接收连接之前），运行期不支持并发修改。
+func (s *ConnectService) SetHeartbeatPolicy(policy HeartbeatPolicy) {
+	s.heartbeatPolicy = policy
+}
+
+// ResolveHeartbeatInterval 根据客户端申请值（秒）计算最终心跳间隔。
+// 规则：
+// - 未申请或参数非法（非整数/非正数）时使用默认值；
+// - 小于最小值时提升到最小值，大于最大值时降到最大值。
+func (s *ConnectService) ResolveHeartbeatInterval(requested string) time.Duration {
+	policy := s.heartbeatPolicy
+	requested = strings.TrimSpace(requested)
+	if requested == "" {
+		return policy.Default
+	}
+	seconds, err := strconv.Atoi(requested)
+	if err != nil || seconds <= 0 {
+		return policy.Default
+	}
+
+	interval := time.Duration(seconds) * time.Second
+	if interval < policy.Min {
+		return policy.Min
+	}
+	if interval > policy.Max {
+		return policy.Max
+	}
+	return interval
+}
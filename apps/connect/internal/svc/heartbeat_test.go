@@ -0,0 +1,41 @@
+package svc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHeartbeatTestService() *ConnectService {
+	s := &ConnectService{}
+	s.SetHeartbeatPolicy(HeartbeatPolicy{
+		Min:     10 * time.Second,
+		Max:     120 * time.Second,
+		Default: 30 * time.Second,
+	})
+	return s
+}
+
+func TestResolveHeartbeatInterval_WithinBoundsHonored(t *testing.T) {
+	s := newHeartbeatTestService()
+
+	assert.Equal(t, 45*time.Second, s.ResolveHeartbeatInterval("45"))
+	assert.Equal(t, 10*time.Second, s.ResolveHeartbeatInterval("10"))
+	assert.Equal(t, 120*time.Second, s.ResolveHeartbeatInterval(" 120 "))
+}
+
+func TestResolveHeartbeatInterval_OutOfBoundsClamped(t *testing.T) {
+	s := newHeartbeatTestService()
+
+	assert.Equal(t, 10*time.Second, s.ResolveHeartbeatInterval("3"))
+	assert.Equal(t, 120*time.Second, s.ResolveHeartbeatInterval("3600"))
+}
+
+func TestResolveHeartbeatInterval_InvalidFallsBackToDefault(t *testing.T) {
+	s := newHeartbeatTestService()
+
+	for _, raw := range []string{"", "abc", "0", "-5", "1.5"} {
+		assert.Equal(t, 30*time.Second, s.ResolveHeartbeatInterval(raw), "raw=%q", raw)
+	}
+}
@@ -30,13 +30,15 @@ type deviceStatusTask struct {
 // OnConnect 在连接建立后触发。
 // 行为：
 // 1. 立即触发活跃时间同步（不受节流限制）；
-// 2. 异步调用 user-service RPC 将 DeviceSession.status 置为在线。
+// 2. 登记连接归属本节点（接管排空节点上的同一设备）；
+// 3. 异步调用 user-service RPC 将 DeviceSession.status 置为在线。
 func (s *ConnectService) OnConnect(ctx context.Context, session *Session) {
 	if s.activeSyncer != nil {
 		// 连接建立时强制刷新：先删除节流记录再 touch，确保本次会入缓冲 map。
 		s.activeSyncer.Delete(session.UserUUID, session.DeviceID)
 		_ = s.activeSyncer.Touch(session.UserUUID, session.DeviceID, time.Now())
 	}
+	s.claimConnection(ctx, session)
 	s.updateDeviceStatusAsync(ctx, session, model.DeviceStatusOnline)
 }
 
@@ -52,11 +54,19 @@ func (s *ConnectService) OnHeartbeat(ctx context.Context, session *Session) {
 // OnDisconnect 在连接断开后触发。
 // 行为：
 // 1. 清理本地节流缓存，避免内存泄漏；
-// 2. 异步调用 user-service RPC 将 DeviceSession.status 置为离线。
+// 2. 排空期间不上报离线（由 FinishDrain 统一处理未被接管的连接）；
+// 3. 注销连接归属，已被其他节点接管时不上报离线；
+// 4. 异步调用 user-service RPC 将 DeviceSession.status 置为离线。
 func (s *ConnectService) OnDisconnect(ctx context.Context, session *Session) {
 	if s.activeSyncer != nil {
 		s.activeSyncer.Delete(session.UserUUID, session.DeviceID)
 	}
+	if s.draining.Load() {
+		return
+	}
+	if s.releaseConnection(ctx, ConnEntry{UserUUID: session.UserUUID, DeviceID: session.DeviceID}) {
+		return
+	}
 	s.updateDeviceStatusAsync(ctx, session, model.DeviceStatusOffline)
 }
 
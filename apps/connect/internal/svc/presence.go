@@ -0,0 +1,290 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"ChatServer/pkg/kafka"
+	"ChatServer/pkg/logger"
+)
+
+const (
+	// presencePublishTimeout 单个在线状态事件的投递超时。
+	presencePublishTimeout = 3 * time.Second
+	// defaultPresenceIdleThreshold 默认活跃阈值：超过该时间未收到上行帧（业务消息/心跳）的连接视为空闲。
+	defaultPresenceIdleThreshold = 2 * time.Minute
+	// minPresenceIdleSweepInterval 空闲检测的最小扫描周期。
+	minPresenceIdleSweepInterval = time.Second
+)
+
+// PresenceState 用户整体在线状态。
+type PresenceState string
+
+const (
+	// PresenceActive 在线且活跃：任一连接在活跃阈值内收到过上行帧。
+	PresenceActive PresenceState = "active"
+	// PresenceIdle 在线但空闲：全部连接仅靠协议层 Ping/Pong 保活（如客户端退到后台）。
+	PresenceIdle PresenceState = "idle"
+	// PresenceOffline 离线：所有节点都没有该用户的连接。
+	PresenceOffline PresenceState = "offline"
+)
+
+// PresenceStateOf 按在线与活跃状态计算整体状态。
+func PresenceStateOf(online, active bool) PresenceState {
+	switch {
+	case !online:
+		return PresenceOffline
+	case active:
+		return PresenceActive
+	default:
+		return PresenceIdle
+	}
+}
+
+// PresenceEvent 用户整体在线状态变更事件（任一设备在线即视为在线，任一设备活跃即视为活跃）。
+// 由 connect 投递到 Kafka，下游（gateway/msg）据此向好友推送在线状态。
+// Online 保留给只关心在线/离线的旧消费者，State 区分活跃与空闲。
+type PresenceEvent struct {
+	UserUUID string        `json:"user_uuid"`
+	Online   bool          `json:"online"`
+	State    PresenceState `json:"state,omitempty"`
+	At       int64         `json:"at"` // 状态稳定时间（毫秒）
+}
+
+// PresencePublisher 在线状态事件投递接口。
+type PresencePublisher interface {
+	PublishPresence(ctx context.Context, event PresenceEvent) error
+}
+
+// kafkaPresencePublisher 基于 Kafka 的在线状态事件投递实现（JSON 编码）。
+type kafkaPresencePublisher struct {
+	producer *kafka.Producer
+}
+
+// NewKafkaPresencePublisher 创建基于 Kafka 的在线状态事件投递器。
+func NewKafkaPresencePublisher(producer *kafka.Producer) PresencePublisher {
+	return &kafkaPresencePublisher{producer: producer}
+}
+
+// PublishPresence 序列化并投递在线状态事件。
+func (p *kafkaPresencePublisher) PublishPresence(ctx context.Context, event PresenceEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.producer.Send(ctx, data)
+}
+
+// presencePending 防抖窗口内尚未确认的在线状态。
+type presencePending struct {
+	state    PresenceState
+	deadline time.Time
+}
+
+// PresenceDebouncer 对用户在线状态变更做防抖后投递。
+// 规则：
+// - 每次观测到状态都会把该用户的确认时间推迟到 now+window；
+// - 窗口内无新观测后，仅当最终状态与上次投递的状态不同才投递事件；
+// - 因此窗口内的快速上下线抖动会被合并，抖动后状态不变则不投递；
+// - 活跃/空闲切换与上下线同样防抖，每次变化都会投递一次事件。
+// 仅记录已投递为在线（活跃或空闲）的用户，内存占用与在线用户数同量级。
+type PresenceDebouncer struct {
+	window    time.Duration
+	publisher PresencePublisher
+
+	mu        sync.Mutex
+	pending   map[string]*presencePending
+	published map[string]PresenceState // 最近一次投递为在线的用户及其状态
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewPresenceDebouncer 创建在线状态防抖器，window<=0 时按 1s 处理。
+func NewPresenceDebouncer(window time.Duration, publisher PresencePublisher) *PresenceDebouncer {
+	if window <= 0 {
+		window = time.Second
+	}
+	return &PresenceDebouncer{
+		window:    window,
+		publisher: publisher,
+		pending:   make(map[string]*presencePending),
+		published: make(map[string]PresenceState),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Observe 记录一次用户整体在线状态观测。
+// 没有待确认状态且与已投递状态相同时忽略，周期性空闲检测不会为状态未变的用户产生待确认记录。
+func (d *PresenceDebouncer) Observe(userUUID string, state PresenceState, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if p, ok := d.pending[userUUID]; ok {
+		p.state = state
+		p.deadline = now.Add(d.window)
+		return
+	}
+	if d.publishedStateLocked(userUUID) == state {
+		return
+	}
+	d.pending[userUUID] = &presencePending{state: state, deadline: now.Add(d.window)}
+}
+
+// Forget 丢弃用户的待确认状态与投递记录（不投递事件）。
+// 用于本节点已无该用户连接、但其他节点仍在线的情况：后续状态变化由持有连接的节点上报。
+func (d *PresenceDebouncer) Forget(userUUID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, userUUID)
+	delete(d.published, userUUID)
+}
+
+// publishedStateLocked 返回用户最近一次投递的状态，未投递过在线视为离线。
+func (d *PresenceDebouncer) publishedStateLocked(userUUID string) PresenceState {
+	if state, ok := d.published[userUUID]; ok {
+		return state
+	}
+	return PresenceOffline
+}
+
+// due 取出到期且与已投递状态不同的事件；force=true 时忽略截止时间（停机时使用）。
+func (d *PresenceDebouncer) due(now time.Time, force bool) []PresenceEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var events []PresenceEvent
+	for userUUID, p := range d.pending {
+		if !force && now.Before(p.deadline) {
+			continue
+		}
+		delete(d.pending, userUUID)
+
+		if d.publishedStateLocked(userUUID) == p.state {
+			continue
+		}
+		if p.state == PresenceOffline {
+			delete(d.published, userUUID)
+		} else {
+			d.published[userUUID] = p.state
+		}
+		events = append(events, PresenceEvent{
+			UserUUID: userUUID,
+			Online:   p.state != PresenceOffline,
+			State:    p.state,
+			At:       now.UnixMilli(),
+		})
+	}
+	return events
+}
+
+// Start 启动后台协程，按 window/2 周期投递到期事件。
+func (d *PresenceDebouncer) Start() {
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.window / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				d.publish(d.due(now, false))
+			case <-d.stop:
+				// 停机前投递所有待确认状态，避免下游残留在线状态。
+				d.publish(d.due(time.Now(), true))
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台协程并等待剩余事件投递完成。
+func (d *PresenceDebouncer) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		<-d.done
+	})
+}
+
+// publish 逐条投递事件，失败仅 log Warn（在线状态为尽力通知，客户端仍可主动拉取）。
+func (d *PresenceDebouncer) publish(events []PresenceEvent) {
+	for _, event := range events {
+		ctx, cancel := context.WithTimeout(context.Background(), presencePublishTimeout)
+		if err := d.publisher.PublishPresence(ctx, event); err != nil {
+			logger.Warn(ctx, "在线状态事件投递失败",
+				logger.String("user_uuid", event.UserUUID),
+				logger.String("state", string(event.State)),
+				logger.ErrorField("error", err),
+			)
+		}
+		cancel()
+	}
+}
+
+// SetPresenceDebouncer 设置在线状态防抖器（需已调用 Start）。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 表示不投递在线状态事件。
+func (s *ConnectService) SetPresenceDebouncer(debouncer *PresenceDebouncer) {
+	s.presence = debouncer
+}
+
+// SetPresenceIdleThreshold 设置连接的活跃阈值（超过该时间未收到上行帧视为空闲）。
+// 应在服务启动阶段调用（接收连接之前）；threshold<=0 时使用默认值 2 分钟。
+func (s *ConnectService) SetPresenceIdleThreshold(threshold time.Duration) {
+	if threshold <= 0 {
+		threshold = defaultPresenceIdleThreshold
+	}
+	s.presenceIdle = threshold
+}
+
+// PresenceIdleThreshold 返回连接的活跃阈值。
+func (s *ConnectService) PresenceIdleThreshold() time.Duration {
+	return s.presenceIdle
+}
+
+// ObservePresence 同步记录用户在本节点的在线状态，
+// 设置了跨节点汇总时按所有节点汇总后的整体状态防抖投递（handler 使用 RefreshPresence，不在读协程中访问 Redis）。
+// 排空期间忽略：断开的连接多数会在其他节点重连，未被接管的由 FinishDrain 上报离线。
+func (s *ConnectService) ObservePresence(userUUID string, state PresenceState) {
+	if s.presence == nil || s.draining.Load() {
+		return
+	}
+	s.observeAggregatedPresence(userUUID, state)
+}
+
+// RunPresenceIdleSweep 周期性检测本节点连接的活跃/空闲状态并上报变化，阻塞直到 ctx 取消。
+// snapshot 返回 user_uuid -> 是否有活跃连接（如 ConnectionManager.ActivitySnapshot）。
+// 扫描周期为活跃阈值的一半，空闲判定最多延迟半个阈值；空闲恢复为活跃由 handler 在收到上行帧时通过 RefreshPresence 上报。
+func (s *ConnectService) RunPresenceIdleSweep(ctx context.Context, snapshot func(now time.Time) map[string]bool) {
+	ticker := time.NewTicker(s.PresenceSweepInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sweepPresenceIdle(snapshot(now))
+		}
+	}
+}
+
+// sweepPresenceIdle 按活跃状态快照上报每个在线用户的状态（同时刷新跨节点汇总中本节点的状态），
+// 跨节点汇总按 presenceSweepBatchSize 分批走 pipeline，状态未变的用户由防抖器忽略。
+func (s *ConnectService) sweepPresenceIdle(activity map[string]bool) {
+	if s.presence == nil || s.draining.Load() {
+		return
+	}
+	batch := make(map[string]PresenceState, min(len(activity), presenceSweepBatchSize))
+	for userUUID, active := range activity {
+		batch[userUUID] = PresenceStateOf(true, active)
+		if len(batch) >= presenceSweepBatchSize {
+			s.observeAggregatedPresenceBatch(batch)
+			batch = make(map[string]PresenceState, presenceSweepBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		s.observeAggregatedPresenceBatch(batch)
+	}
+}
@@ -0,0 +1,252 @@
+package svc
+
+import (
+	"context"
+	"time"
+
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// presenceAggregateTimeout 单次跨节点在线状态汇总的超时，超时按本节点状态处理。
+	presenceAggregateTimeout = 100 * time.Millisecond
+	// presenceSweepBatchSize 空闲扫描时单个 pipeline 汇总的用户数。
+	presenceSweepBatchSize = 500
+	// presenceSweepBatchTimeout 空闲扫描单批汇总的超时，超时的用户按本节点状态处理。
+	presenceSweepBatchTimeout = time.Second
+	// presenceRefreshQueueSize 异步汇总队列容量，队列满时由调用方同步汇总（不丢弃，避免离线上报丢失）。
+	presenceRefreshQueueSize = 1024
+)
+
+// PresenceAggregator 跨节点在线状态汇总：同一用户的多台设备可能连接在不同 connect 节点上，
+// 仅凭本节点的连接会把"本节点已无连接"误判为离线、把"本节点空闲"误判为整体空闲。
+type PresenceAggregator interface {
+	// Report 记录本节点该用户的状态（offline 表示本节点已无连接），返回所有节点汇总后的整体状态。
+	Report(ctx context.Context, userUUID, nodeID string, local PresenceState) (PresenceState, error)
+	// ReportBatch 批量记录本节点多个用户的状态，返回每个用户汇总后的整体状态。
+	// 部分失败时返回已成功的结果与错误，调用方对缺失的用户按本节点状态处理。
+	ReportBatch(ctx context.Context, nodeID string, local map[string]PresenceState) (map[string]PresenceState, error)
+}
+
+// luaReportPresence 写入本节点状态并汇总所有节点的未过期状态（active > idle > offline）。
+// 每个节点的状态附带 Redis 服务端时间，超过 stale 毫秒未刷新的节点（异常退出未清理）视为离线并删除。
+// KEYS[1]: connect:presence:{user_uuid}；ARGV[1]: node_id；ARGV[2]: 本节点状态；ARGV[3]: stale 毫秒。
+const luaReportPresence = `
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local stale = tonumber(ARGV[3])
+if ARGV[2] == 'offline' then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+else
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2] .. ':' .. now)
+	redis.call('PEXPIRE', KEYS[1], stale)
+end
+local best = 'offline'
+local fields = redis.call('HGETALL', KEYS[1])
+for i = 1, #fields, 2 do
+	local state, at = string.match(fields[i + 1], '^(%a+):(%d+)$')
+	if state and now - tonumber(at) <= stale then
+		if state == 'active' then
+			best = 'active'
+		elseif state == 'idle' and best == 'offline' then
+			best = 'idle'
+		end
+	else
+		redis.call('HDEL', KEYS[1], fields[i])
+	end
+end
+return best
+`
+
+var reportPresenceScript = redis.NewScript(luaReportPresence)
+
+// redisPresenceAggregator 基于 Redis 的跨节点在线状态汇总（connect:presence:{user_uuid} Hash，field 为节点 ID）。
+type redisPresenceAggregator struct {
+	redisClient *redis.Client
+	staleAfter  time.Duration
+}
+
+// NewRedisPresenceAggregator 创建基于 Redis 的跨节点在线状态汇总。
+// staleAfter 为节点状态的有效期，应不短于空闲检测扫描周期的数倍（扫描会刷新本节点在线用户的状态）。
+func NewRedisPresenceAggregator(redisClient *redis.Client, staleAfter time.Duration) PresenceAggregator {
+	return &redisPresenceAggregator{redisClient: redisClient, staleAfter: staleAfter}
+}
+
+// Report 原子写入本节点状态并返回汇总状态。
+func (a *redisPresenceAggregator) Report(ctx context.Context, userUUID, nodeID string, local PresenceState) (PresenceState, error) {
+	key := rediskey.ConnectPresenceKey(userUUID)
+	state, err := reportPresenceScript.Run(ctx, a.redisClient, []string{key}, nodeID, string(local), a.staleAfter.Milliseconds()).Text()
+	if err != nil {
+		return local, err
+	}
+	return PresenceState(state), nil
+}
+
+// ReportBatch 以单个 pipeline 批量执行汇总脚本（EVALSHA，脚本未缓存时加载后重试一次）。
+func (a *redisPresenceAggregator) ReportBatch(ctx context.Context, nodeID string, local map[string]PresenceState) (map[string]PresenceState, error) {
+	result, err := a.reportBatch(ctx, nodeID, local)
+	if err != nil && redis.HasErrorPrefix(err, "NOSCRIPT") {
+		if loadErr := reportPresenceScript.Load(ctx, a.redisClient).Err(); loadErr != nil {
+			return result, loadErr
+		}
+		result, err = a.reportBatch(ctx, nodeID, local)
+	}
+	return result, err
+}
+
+func (a *redisPresenceAggregator) reportBatch(ctx context.Context, nodeID string, local map[string]PresenceState) (map[string]PresenceState, error) {
+	pipe := a.redisClient.Pipeline()
+	cmds := make(map[string]*redis.Cmd, len(local))
+	for userUUID, state := range local {
+		key := rediskey.ConnectPresenceKey(userUUID)
+		cmds[userUUID] = reportPresenceScript.EvalSha(ctx, pipe, []string{key}, nodeID, string(state), a.staleAfter.Milliseconds())
+	}
+	_, err := pipe.Exec(ctx)
+
+	result := make(map[string]PresenceState, len(cmds))
+	for userUUID, cmd := range cmds {
+		if state, cmdErr := cmd.Text(); cmdErr == nil {
+			result[userUUID] = PresenceState(state)
+		}
+	}
+	return result, err
+}
+
+// presenceRefresh 待异步汇总的用户，处理时按 activity 重新计算本节点状态。
+type presenceRefresh struct {
+	userUUID string
+	activity PresenceActivityFunc
+}
+
+// PresenceActivityFunc 返回本节点该用户的连接是否在线、是否活跃（如 ConnectionManager.UserActivity）。
+type PresenceActivityFunc func(userUUID string, now time.Time) (online, active bool)
+
+// SetPresenceAggregator 设置跨节点在线状态汇总（需先通过 SetConnectionRegistry 设置本节点 ID）。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 或本节点 ID 为空时仅按本节点连接计算（单节点部署的行为）。
+// 启用汇总时同时启动异步汇总协程，RefreshPresence 不在调用方协程中访问 Redis。
+func (s *ConnectService) SetPresenceAggregator(aggregator PresenceAggregator) {
+	if s.nodeID == "" {
+		aggregator = nil
+	}
+	s.presenceAgg = aggregator
+	if aggregator != nil && s.presenceQueue == nil {
+		s.presenceQueue = make(chan presenceRefresh, presenceRefreshQueueSize)
+		s.presenceStop = make(chan struct{})
+		s.presenceWg.Add(1)
+		go s.presenceRefreshWorker()
+	}
+}
+
+// RefreshPresence 按本节点该用户的连接重新计算并上报在线状态（由 handler 在连接注册/注销、空闲连接恢复活跃后调用）。
+// 启用跨节点汇总时投递到异步汇总协程：状态在处理时才计算，且单协程按投递顺序处理，
+// 同一用户最后一次投递总能反映最终状态；队列满时在调用方同步汇总。未启用汇总时直接交给防抖器。
+func (s *ConnectService) RefreshPresence(userUUID string, activity PresenceActivityFunc) {
+	if s.presence == nil || s.draining.Load() {
+		return
+	}
+	if s.presenceQueue != nil {
+		select {
+		case <-s.presenceStop:
+			return
+		case s.presenceQueue <- presenceRefresh{userUUID: userUUID, activity: activity}:
+			return
+		default:
+		}
+	}
+	s.ObservePresence(userUUID, PresenceStateOf(activity(userUUID, time.Now())))
+}
+
+// presenceRefreshWorker 逐条处理异步汇总，停止时处理完队列中剩余的任务后退出。
+func (s *ConnectService) presenceRefreshWorker() {
+	defer s.presenceWg.Done()
+	handle := func(r presenceRefresh) {
+		s.ObservePresence(r.userUUID, PresenceStateOf(r.activity(r.userUUID, time.Now())))
+	}
+	for {
+		select {
+		case r := <-s.presenceQueue:
+			handle(r)
+		case <-s.presenceStop:
+			for {
+				select {
+				case r := <-s.presenceQueue:
+					handle(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// stopPresenceRefresh 停止异步汇总协程并等待剩余任务处理完成。
+func (s *ConnectService) stopPresenceRefresh() {
+	if s.presenceStop == nil {
+		return
+	}
+	s.presenceStopOnce.Do(func() {
+		close(s.presenceStop)
+	})
+	s.presenceWg.Wait()
+}
+
+// PresenceSweepInterval 返回空闲检测扫描周期（活跃阈值的一半，不低于 1s）。
+func (s *ConnectService) PresenceSweepInterval() time.Duration {
+	return max(s.presenceIdle/2, minPresenceIdleSweepInterval)
+}
+
+// observeAggregatedPresence 汇总其他节点的状态后交给防抖器。
+// 本节点已无连接而其他节点仍在线时不上报离线，并清除本节点的投递记录，由仍持有连接的节点负责后续上报；
+// 汇总失败时按本节点状态处理（退化为单节点行为）。
+func (s *ConnectService) observeAggregatedPresence(userUUID string, local PresenceState) {
+	state := local
+	if s.presenceAgg != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), presenceAggregateTimeout)
+		aggregated, err := s.presenceAgg.Report(ctx, userUUID, s.nodeID, local)
+		cancel()
+		if err != nil {
+			logger.Warn(ctx, "跨节点在线状态汇总失败，按本节点状态上报",
+				logger.String("user_uuid", userUUID),
+				logger.String("state", string(local)),
+				logger.ErrorField("error", err),
+			)
+		} else {
+			state = aggregated
+		}
+	}
+	if local == PresenceOffline && state != PresenceOffline {
+		s.presence.Forget(userUUID)
+		return
+	}
+	s.presence.Observe(userUUID, state, time.Now())
+}
+
+// observeAggregatedPresenceBatch 批量汇总本节点在线用户的状态后交给防抖器（空闲扫描使用，本节点状态均为在线）。
+// 汇总失败的用户按本节点状态处理。
+func (s *ConnectService) observeAggregatedPresenceBatch(local map[string]PresenceState) {
+	var aggregated map[string]PresenceState
+	if s.presenceAgg != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), presenceSweepBatchTimeout)
+		result, err := s.presenceAgg.ReportBatch(ctx, s.nodeID, local)
+		cancel()
+		if err != nil {
+			logger.Warn(ctx, "跨节点在线状态批量汇总失败，失败的用户按本节点状态上报",
+				logger.Int("users", len(local)),
+				logger.Int("aggregated", len(result)),
+				logger.ErrorField("error", err),
+			)
+		}
+		aggregated = result
+	}
+	now := time.Now()
+	for userUUID, state := range local {
+		if agg, ok := aggregated[userUUID]; ok {
+			state = agg
+		}
+		s.presence.Observe(userUUID, state, now)
+	}
+}
@@ -0,0 +1,165 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// friendCacheEmptyField 好友关系缓存中的空值占位字段（与 user 服务保持一致）。
+const friendCacheEmptyField = "__EMPTY__"
+
+// ErrPresenceEventInvalid 表示在线状态事件格式非法。
+var ErrPresenceEventInvalid = errors.New("presence event is invalid")
+
+// PresenceData 定义 type=presence 时的 data 结构（下行给好友）。
+type PresenceData struct {
+	UUID   string        `json:"uuid"`
+	Online bool          `json:"online"`
+	State  PresenceState `json:"state,omitempty"` // active / idle / offline，旧版本事件为空
+	At     int64         `json:"at"`              // 状态稳定时间（毫秒），客户端据此丢弃乱序的旧状态
+}
+
+// PresenceAudienceSource 查询用户在线状态的推送对象。
+// 用户隐藏在线状态时返回空列表。
+type PresenceAudienceSource interface {
+	PresenceAudience(ctx context.Context, userUUID string) ([]string, error)
+}
+
+// redisPresenceAudienceSource 基于 Redis 查询推送对象：
+// - user:presence:hidden          隐藏在线状态的用户集合（user 服务维护）；
+// - user:relation:friend:{uuid}   好友关系缓存（Hash，field 为好友 UUID）。
+// 好友缓存未命中时不推送（在线状态为尽力通知，客户端可主动拉取）。
+type redisPresenceAudienceSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisPresenceAudienceSource 创建基于 Redis 的在线状态推送对象查询器。
+func NewRedisPresenceAudienceSource(redisClient *redis.Client) PresenceAudienceSource {
+	return &redisPresenceAudienceSource{redisClient: redisClient}
+}
+
+// PresenceAudience 单次 Pipeline 读取隐私设置与好友列表。
+func (p *redisPresenceAudienceSource) PresenceAudience(ctx context.Context, userUUID string) ([]string, error) {
+	pipe := p.redisClient.Pipeline()
+	hiddenCmd := pipe.SIsMember(ctx, rediskey.PresenceHiddenKey(), userUUID)
+	friendsCmd := pipe.HKeys(ctx, rediskey.FriendRelationKey(userUUID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if hiddenCmd.Val() {
+		return nil, nil
+	}
+
+	friends := friendsCmd.Val()
+	audience := make([]string, 0, len(friends))
+	for _, friendUUID := range friends {
+		if friendUUID != friendCacheEmptyField {
+			audience = append(audience, friendUUID)
+		}
+	}
+	return audience, nil
+}
+
+// PresenceDeviceSender 本节点按设备投递（ConnectionManager 实现）。
+type PresenceDeviceSender interface {
+	GetOnlineDevices(userUUID string) []string
+	SendToDevice(userUUID, deviceID string, msg []byte) bool
+}
+
+// PresenceFanout 消费在线状态事件，向本节点在线的好友推送 type=presence 帧。
+// 每个 connect 节点独立消费全部事件（消费组按节点区分），只投递本节点上的连接。
+// 配置订阅表后，发送过 subscribe_presence 的连接只接收其订阅用户的变化（订阅对象仍限于推送对象范围内）。
+type PresenceFanout struct {
+	audience PresenceAudienceSource
+	send     func(userUUID string, frame []byte) int
+	subs     *PresenceSubscriptions // 连接级订阅（可为 nil，全部按好友推送）
+	devices  PresenceDeviceSender
+}
+
+// NewPresenceFanout 创建在线状态推送器；send 为本节点按用户投递的函数（如 ConnectionManager.SendToUser）。
+func NewPresenceFanout(audience PresenceAudienceSource, send func(userUUID string, frame []byte) int) *PresenceFanout {
+	return &PresenceFanout{audience: audience, send: send}
+}
+
+// SetSubscriptions 启用连接级订阅过滤；应在开始消费之前调用。
+func (f *PresenceFanout) SetSubscriptions(subs *PresenceSubscriptions, devices PresenceDeviceSender) {
+	f.subs = subs
+	f.devices = devices
+}
+
+// HandleMessage 处理一条 Kafka 消息（PresenceEvent JSON），返回实际投递的连接数。
+// 格式非法返回 ErrPresenceEventInvalid；推送对象查询失败原样返回，由调用方记录。
+func (f *PresenceFanout) HandleMessage(ctx context.Context, message []byte) (int, error) {
+	var event PresenceEvent
+	if err := json.Unmarshal(message, &event); err != nil || event.UserUUID == "" {
+		return 0, ErrPresenceEventInvalid
+	}
+
+	audience, err := f.audience.PresenceAudience(ctx, event.UserUUID)
+	if err != nil {
+		return 0, err
+	}
+	if len(audience) == 0 {
+		return 0, nil
+	}
+
+	frame, err := json.Marshal(map[string]any{
+		"type": "presence",
+		"data": PresenceData{UUID: event.UserUUID, Online: event.Online, State: event.State, At: event.At},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if f.subs == nil {
+		delivered := 0
+		for _, friendUUID := range audience {
+			delivered += f.send(friendUUID, frame)
+		}
+		return delivered, nil
+	}
+	return f.deliverWithSubscriptions(event.UserUUID, audience, frame), nil
+}
+
+// deliverWithSubscriptions 按订阅模式投递：
+// - 未订阅的连接保持默认行为，接收全部好友的在线状态；
+// - 订阅模式的连接只在订阅了 target 时接收（隐藏在线状态、非好友的订阅不会推送）。
+func (f *PresenceFanout) deliverWithSubscriptions(target string, audience []string, frame []byte) int {
+	delivered := 0
+	allowed := make(map[string]struct{}, len(audience))
+	for _, friendUUID := range audience {
+		allowed[friendUUID] = struct{}{}
+		if !f.subs.HasSubscribed(friendUUID) {
+			delivered += f.send(friendUUID, frame)
+			continue
+		}
+		for _, deviceID := range f.devices.GetOnlineDevices(friendUUID) {
+			if !f.subs.IsSubscribed(friendUUID, deviceID) && f.devices.SendToDevice(friendUUID, deviceID, frame) {
+				delivered++
+			}
+		}
+	}
+	for _, sub := range f.subs.Subscribers(target) {
+		if _, ok := allowed[sub.UserUUID]; ok && f.devices.SendToDevice(sub.UserUUID, sub.DeviceID, frame) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Consume 作为 kafka.MessageHandler 使用：失败仅 log Warn（在线状态为尽力通知，不重试）。
+func (f *PresenceFanout) Consume(ctx context.Context, message []byte) error {
+	if _, err := f.HandleMessage(ctx, message); err != nil {
+		logger.Warn(ctx, "在线状态推送失败",
+			logger.ErrorField("error", err),
+		)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePresenceAudience 内存版推送对象（hidden 中的用户不推送）。
+type fakePresenceAudience struct {
+	friends map[string][]string
+	hidden  map[string]bool
+	err     error
+}
+
+func (f *fakePresenceAudience) PresenceAudience(_ context.Context, userUUID string) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.hidden[userUUID] {
+		return nil, nil
+	}
+	return f.friends[userUUID], nil
+}
+
+type presenceTestFrame struct {
+	Type string       `json:"type"`
+	Data PresenceData `json:"data"`
+}
+
+func TestPresenceFanout_PushesToOnlineFriends(t *testing.T) {
+	sent := map[string][]presenceTestFrame{}
+	online := map[string]int{"1002": 2, "1003": 0}
+	fanout := NewPresenceFanout(&fakePresenceAudience{
+		friends: map[string][]string{"1001": {"1002", "1003"}},
+	}, func(userUUID string, frame []byte) int {
+		var f presenceTestFrame
+		require.NoError(t, json.Unmarshal(frame, &f))
+		sent[userUUID] = append(sent[userUUID], f)
+		return online[userUUID]
+	})
+
+	delivered, err := fanout.HandleMessage(context.Background(), []byte(`{"user_uuid":"1001","online":true,"at":1760000000000}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered, "offline friends are skipped")
+	require.Len(t, sent["1002"], 1)
+	assert.Equal(t, presenceTestFrame{Type: "presence", Data: PresenceData{UUID: "1001", Online: true, At: 1760000000000}}, sent["1002"][0])
+}
+
+func TestPresenceFanout_RespectsHiddenPresence(t *testing.T) {
+	calls := 0
+	fanout := NewPresenceFanout(&fakePresenceAudience{
+		friends: map[string][]string{"1001": {"1002"}},
+		hidden:  map[string]bool{"1001": true},
+	}, func(string, []byte) int {
+		calls++
+		return 1
+	})
+
+	delivered, err := fanout.HandleMessage(context.Background(), []byte(`{"user_uuid":"1001","online":false}`))
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+	assert.Zero(t, calls)
+}
+
+func TestPresenceFanout_Errors(t *testing.T) {
+	fanout := NewPresenceFanout(&fakePresenceAudience{}, func(string, []byte) int { return 0 })
+	_, err := fanout.HandleMessage(context.Background(), []byte(`{"online":true}`))
+	assert.ErrorIs(t, err, ErrPresenceEventInvalid)
+	_, err = fanout.HandleMessage(context.Background(), []byte(`not-json`))
+	assert.ErrorIs(t, err, ErrPresenceEventInvalid)
+
+	lookupErr := errors.New("redis down")
+	fanout = NewPresenceFanout(&fakePresenceAudience{err: lookupErr}, func(string, []byte) int { return 0 })
+	_, err = fanout.HandleMessage(context.Background(), []byte(`{"user_uuid":"1001","online":true}`))
+	assert.ErrorIs(t, err, lookupErr)
+}
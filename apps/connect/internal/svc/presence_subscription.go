@@ -0,0 +1,185 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// defaultPresenceMaxSubscriptions 单连接默认最多订阅的用户数。
+const defaultPresenceMaxSubscriptions = 200
+
+var (
+	// ErrPresenceSubscribeInvalid 表示 subscribe_presence 帧 data 格式非法。
+	ErrPresenceSubscribeInvalid = errors.New("subscribe_presence data is invalid")
+	// ErrPresenceSubscribeTooMany 表示单连接订阅的用户数超过上限。
+	ErrPresenceSubscribeTooMany = errors.New("too many presence subscriptions")
+)
+
+// SubscribePresenceData 定义 type=subscribe_presence 时的 data 结构。
+// uuids 为全量订阅列表（覆盖上一次订阅），空列表表示不再接收任何在线状态推送。
+type SubscribePresenceData struct {
+	UUIDs []string `json:"uuids"`
+}
+
+// SubscribePresenceAckData 定义 type=subscribe_presence_ack 时的 data 结构。
+type SubscribePresenceAckData struct {
+	Count int `json:"count"` // 去重后实际生效的订阅数
+}
+
+// PresenceSubscriber 订阅在线状态的连接（用户 + 设备）。
+type PresenceSubscriber struct {
+	UserUUID string
+	DeviceID string
+}
+
+// PresenceSubscriptions 本节点连接级在线状态订阅表。
+// 连接发送过 subscribe_presence 后进入“订阅模式”：只接收订阅列表中用户的在线状态变化；
+// 未订阅的连接保持默认行为（接收全部好友的在线状态变化）。
+// 以 *Session 区分连接：同设备重连产生新 Session，旧连接断开时只清理自己的订阅。
+type PresenceSubscriptions struct {
+	max int
+
+	mu        sync.RWMutex
+	bySession map[*Session]map[string]struct{} // 连接 -> 订阅的用户
+	byTarget  map[string]map[*Session]struct{} // 被订阅用户 -> 订阅连接
+	active    map[string]map[string]*Session   // user_uuid -> device_id -> 当前处于订阅模式的连接
+}
+
+// NewPresenceSubscriptions 创建订阅表，limit<=0 时使用默认上限。
+func NewPresenceSubscriptions(limit int) *PresenceSubscriptions {
+	if limit <= 0 {
+		limit = defaultPresenceMaxSubscriptions
+	}
+	return &PresenceSubscriptions{
+		max:       limit,
+		bySession: make(map[*Session]map[string]struct{}),
+		byTarget:  make(map[string]map[*Session]struct{}),
+		active:    make(map[string]map[string]*Session),
+	}
+}
+
+// Replace 以 uuids 覆盖连接的订阅列表，返回去重后的订阅数。
+// 空白与重复的 uuid 会被忽略，订阅自己无意义也会被忽略；超过上限返回 ErrPresenceSubscribeTooMany 且不修改原订阅。
+// 去重集合一旦超过上限立即拒绝，单帧携带大量 uuid 时不会按帧大小分配内存。
+func (p *PresenceSubscriptions) Replace(session *Session, uuids []string) (int, error) {
+	targets := make(map[string]struct{}, min(len(uuids), p.max))
+	for _, uuid := range uuids {
+		uuid = strings.TrimSpace(uuid)
+		if uuid == "" || uuid == session.UserUUID {
+			continue
+		}
+		targets[uuid] = struct{}{}
+		if len(targets) > p.max {
+			return 0, ErrPresenceSubscribeTooMany
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(session)
+	p.bySession[session] = targets
+	for target := range targets {
+		subs := p.byTarget[target]
+		if subs == nil {
+			subs = make(map[*Session]struct{})
+			p.byTarget[target] = subs
+		}
+		subs[session] = struct{}{}
+	}
+	devices := p.active[session.UserUUID]
+	if devices == nil {
+		devices = make(map[string]*Session)
+		p.active[session.UserUUID] = devices
+	}
+	devices[session.DeviceID] = session
+	return len(targets), nil
+}
+
+// Remove 清理连接的订阅（连接断开时调用），未订阅过时为空操作。
+func (p *PresenceSubscriptions) Remove(session *Session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeLocked(session)
+}
+
+func (p *PresenceSubscriptions) removeLocked(session *Session) {
+	targets, ok := p.bySession[session]
+	if !ok {
+		return
+	}
+	delete(p.bySession, session)
+	for target := range targets {
+		if subs := p.byTarget[target]; subs != nil {
+			delete(subs, session)
+			if len(subs) == 0 {
+				delete(p.byTarget, target)
+			}
+		}
+	}
+	// 仅当登记的仍是本连接时才删除，避免同设备新连接的订阅被旧连接断开误删
+	if devices := p.active[session.UserUUID]; devices != nil && devices[session.DeviceID] == session {
+		delete(devices, session.DeviceID)
+		if len(devices) == 0 {
+			delete(p.active, session.UserUUID)
+		}
+	}
+}
+
+// Subscribers 返回订阅了 target 的连接。
+func (p *PresenceSubscriptions) Subscribers(target string) []PresenceSubscriber {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	subs := p.byTarget[target]
+	out := make([]PresenceSubscriber, 0, len(subs))
+	for session := range subs {
+		out = append(out, PresenceSubscriber{UserUUID: session.UserUUID, DeviceID: session.DeviceID})
+	}
+	return out
+}
+
+// HasSubscribed 判断用户在本节点是否有处于订阅模式的连接。
+func (p *PresenceSubscriptions) HasSubscribed(userUUID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.active[userUUID]) > 0
+}
+
+// IsSubscribed 判断用户的指定设备连接是否处于订阅模式。
+func (p *PresenceSubscriptions) IsSubscribed(userUUID, deviceID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.active[userUUID][deviceID]
+	return ok
+}
+
+// ParseSubscribePresence 解析 subscribe_presence 帧，data 缺失或 uuids 字段格式错误时返回 ErrPresenceSubscribeInvalid。
+func (s *ConnectService) ParseSubscribePresence(raw json.RawMessage) (*SubscribePresenceData, error) {
+	var data SubscribePresenceData
+	if len(raw) == 0 || json.Unmarshal(raw, &data) != nil {
+		return nil, ErrPresenceSubscribeInvalid
+	}
+	return &data, nil
+}
+
+// SubscribePresence 覆盖连接的在线状态订阅，返回生效的订阅数。
+func (s *ConnectService) SubscribePresence(session *Session, data *SubscribePresenceData) (int, error) {
+	return s.presenceSubs.Replace(session, data.UUIDs)
+}
+
+// UnsubscribePresence 清理连接的在线状态订阅（连接断开时调用）。
+func (s *ConnectService) UnsubscribePresence(session *Session) {
+	s.presenceSubs.Remove(session)
+}
+
+// PresenceSubscriptions 返回本节点的订阅表，供在线状态推送（PresenceFanout）使用。
+func (s *ConnectService) PresenceSubscriptions() *PresenceSubscriptions {
+	return s.presenceSubs
+}
+
+// SetPresenceSubscriptionLimit 设置单连接最多订阅的用户数。
+// 应在服务启动阶段调用（接收连接之前）；limit<=0 时使用默认上限。
+func (s *ConnectService) SetPresenceSubscriptionLimit(limit int) {
+	s.presenceSubs = NewPresenceSubscriptions(limit)
+}
@@ -0,0 +1,162 @@
+package svc
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePresenceDevices 内存版本节点连接表（user_uuid -> 在线设备），记录按设备投递的帧。
+type fakePresenceDevices struct {
+	online map[string][]string
+	sent   map[PresenceSubscriber]int
+}
+
+func (f *fakePresenceDevices) GetOnlineDevices(userUUID string) []string {
+	return f.online[userUUID]
+}
+
+func (f *fakePresenceDevices) SendToDevice(userUUID, deviceID string, _ []byte) bool {
+	for _, d := range f.online[userUUID] {
+		if d == deviceID {
+			f.sent[PresenceSubscriber{UserUUID: userUUID, DeviceID: deviceID}]++
+			return true
+		}
+	}
+	return false
+}
+
+// sendToUser 模拟 ConnectionManager.SendToUser：投递到用户全部在线设备。
+func (f *fakePresenceDevices) sendToUser(userUUID string, frame []byte) int {
+	n := 0
+	for _, deviceID := range f.online[userUUID] {
+		if f.SendToDevice(userUUID, deviceID, frame) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPresenceSubscriptions_Replace(t *testing.T) {
+	subs := NewPresenceSubscriptions(2)
+	session := &Session{UserUUID: "1001", DeviceID: "d1"}
+
+	count, err := subs.Replace(session, []string{"1002", " 1002 ", "", "1001", "1003"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "去重并忽略空白与自己")
+	assert.True(t, subs.IsSubscribed("1001", "d1"))
+	assert.Len(t, subs.Subscribers("1002"), 1)
+
+	// 超过上限：返回错误且保留原订阅
+	_, err = subs.Replace(session, []string{"1002", "1003", "1004"})
+	require.ErrorIs(t, err, ErrPresenceSubscribeTooMany)
+	assert.Len(t, subs.Subscribers("1003"), 1)
+
+	// 覆盖订阅：旧订阅对象不再关联该连接
+	count, err = subs.Replace(session, []string{"1004"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Empty(t, subs.Subscribers("1002"))
+	assert.Equal(t, []PresenceSubscriber{{UserUUID: "1001", DeviceID: "d1"}}, subs.Subscribers("1004"))
+}
+
+func TestPresenceSubscriptions_ReplaceBeyondLimit(t *testing.T) {
+	subs := NewPresenceSubscriptions(3)
+	session := &Session{UserUUID: "1001", DeviceID: "d1"}
+
+	// 恰好达到上限（含重复项）仍可订阅
+	count, err := subs.Replace(session, []string{"2001", "2002", "2003", "2001", "2003"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// 单帧大量 uuid：拒绝且保留原订阅
+	huge := make([]string, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		huge = append(huge, strconv.Itoa(3000+i))
+	}
+	_, err = subs.Replace(session, huge)
+	require.ErrorIs(t, err, ErrPresenceSubscribeTooMany)
+	assert.Len(t, subs.Subscribers("2002"), 1)
+	assert.Empty(t, subs.Subscribers("3000"))
+
+	// 其他连接不受影响，各自独立计数
+	other := &Session{UserUUID: "1002", DeviceID: "d1"}
+	count, err = subs.Replace(other, []string{"2001", "2002", "2003"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Len(t, subs.Subscribers("2001"), 2)
+}
+
+func TestPresenceSubscriptions_RemoveKeepsReconnectedSession(t *testing.T) {
+	subs := NewPresenceSubscriptions(0)
+	oldSession := &Session{UserUUID: "1001", DeviceID: "d1"}
+	newSession := &Session{UserUUID: "1001", DeviceID: "d1"}
+
+	_, err := subs.Replace(oldSession, []string{"1002"})
+	require.NoError(t, err)
+	_, err = subs.Replace(newSession, []string{"1003"})
+	require.NoError(t, err)
+
+	// 旧连接晚于新连接订阅后断开：只清理旧连接自己的订阅
+	subs.Remove(oldSession)
+	assert.Empty(t, subs.Subscribers("1002"))
+	assert.Len(t, subs.Subscribers("1003"), 1)
+	assert.True(t, subs.IsSubscribed("1001", "d1"))
+
+	subs.Remove(newSession)
+	assert.False(t, subs.HasSubscribed("1001"))
+	assert.Empty(t, subs.Subscribers("1003"))
+}
+
+func TestPresenceFanout_Subscriptions(t *testing.T) {
+	// 1001 上线；好友 1002（两台设备）、1003、1004；1005 不是好友
+	devices := &fakePresenceDevices{
+		online: map[string][]string{
+			"1002": {"phone", "pc"},
+			"1003": {"d1"},
+			"1004": {"d1"},
+			"1005": {"d1"},
+		},
+		sent: map[PresenceSubscriber]int{},
+	}
+	subs := NewPresenceSubscriptions(0)
+	fanout := NewPresenceFanout(&fakePresenceAudience{
+		friends: map[string][]string{"1001": {"1002", "1003", "1004"}},
+	}, devices.sendToUser)
+	fanout.SetSubscriptions(subs, devices)
+
+	subscribe := func(userUUID, deviceID string, uuids ...string) {
+		_, err := subs.Replace(&Session{UserUUID: userUUID, DeviceID: deviceID}, uuids)
+		require.NoError(t, err)
+	}
+	subscribe("1002", "phone", "1001") // 订阅了 1001
+	subscribe("1003", "d1", "1009")    // 订阅了其他用户
+	subscribe("1005", "d1", "1001")    // 非好友订阅 1001
+	// 1002/pc 与 1004 未订阅：保持默认的好友推送
+
+	delivered, err := fanout.HandleMessage(context.Background(), []byte(`{"user_uuid":"1001","online":true,"at":1}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[PresenceSubscriber]int{
+		{UserUUID: "1002", DeviceID: "phone"}: 1,
+		{UserUUID: "1002", DeviceID: "pc"}:    1,
+		{UserUUID: "1004", DeviceID: "d1"}:    1,
+	}, devices.sent)
+	assert.Equal(t, 3, delivered)
+}
+
+func TestConnectService_ParseSubscribePresence(t *testing.T) {
+	s := NewConnectService(nil, nil, nil)
+
+	data, err := s.ParseSubscribePresence([]byte(`{"uuids":["1002"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1002"}, data.UUIDs)
+
+	_, err = s.ParseSubscribePresence(nil)
+	assert.ErrorIs(t, err, ErrPresenceSubscribeInvalid)
+	_, err = s.ParseSubscribePresence([]byte(`{"uuids":"1002"}`))
+	assert.ErrorIs(t, err, ErrPresenceSubscribeInvalid)
+}
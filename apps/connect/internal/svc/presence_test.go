@@ -0,0 +1,309 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakePresencePublisher struct {
+	mu     sync.Mutex
+	events []PresenceEvent
+}
+
+func (f *fakePresencePublisher) PublishPresence(_ context.Context, event PresenceEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakePresencePublisher) snapshot() []PresenceEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]PresenceEvent(nil), f.events...)
+}
+
+func TestPresenceDebouncer_OfflineToOnlineEmitsOnce(t *testing.T) {
+	d := NewPresenceDebouncer(time.Second, &fakePresencePublisher{})
+	now := time.Now()
+
+	d.Observe("u1", PresenceActive, now)
+	assert.Empty(t, d.due(now.Add(500*time.Millisecond), false), "窗口内不投递")
+
+	events := d.due(now.Add(time.Second), false)
+	require.Len(t, events, 1)
+	assert.Equal(t, "u1", events[0].UserUUID)
+	assert.True(t, events[0].Online)
+
+	// 第二台设备上线：整体状态仍为在线，不重复投递。
+	d.Observe("u1", PresenceActive, now.Add(2*time.Second))
+	assert.Empty(t, d.due(now.Add(3*time.Second), false))
+}
+
+func TestPresenceDebouncer_RapidFlapsDebounced(t *testing.T) {
+	d := NewPresenceDebouncer(time.Second, &fakePresencePublisher{})
+	now := time.Now()
+
+	// 离线 → 快速上下线抖动，最终在线：只投递一次 online。
+	d.Observe("u1", PresenceActive, now)
+	d.Observe("u1", PresenceOffline, now.Add(100*time.Millisecond))
+	d.Observe("u1", PresenceActive, now.Add(200*time.Millisecond))
+	assert.Empty(t, d.due(now.Add(time.Second), false), "每次观测都会推迟确认时间")
+
+	events := d.due(now.Add(1200*time.Millisecond), false)
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Online)
+
+	// 在线期间短暂断线重连（弱网切换）：最终状态不变，不投递。
+	d.Observe("u1", PresenceOffline, now.Add(5*time.Second))
+	d.Observe("u1", PresenceActive, now.Add(5200*time.Millisecond))
+	assert.Empty(t, d.due(now.Add(10*time.Second), false))
+
+	// 真正下线：投递 offline。
+	d.Observe("u1", PresenceOffline, now.Add(20*time.Second))
+	events = d.due(now.Add(21*time.Second), false)
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Online)
+}
+
+func TestPresenceDebouncer_StopFlushesPending(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	publisher := &fakePresencePublisher{}
+	d := NewPresenceDebouncer(time.Hour, publisher)
+	d.Start()
+
+	d.Observe("u1", PresenceActive, time.Now())
+	d.Stop()
+	d.Stop()
+
+	events := publisher.snapshot()
+	require.Len(t, events, 1)
+	assert.Equal(t, "u1", events[0].UserUUID)
+}
+
+func TestPresenceDebouncer_ActiveIdleTransitions(t *testing.T) {
+	d := NewPresenceDebouncer(time.Second, &fakePresencePublisher{})
+	now := time.Now()
+
+	d.Observe("u1", PresenceActive, now)
+	require.Len(t, d.due(now.Add(time.Second), false), 1)
+
+	d.Observe("u1", PresenceIdle, now.Add(2*time.Second))
+	events := d.due(now.Add(3*time.Second), false)
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Online, "空闲仍为在线")
+	assert.Equal(t, PresenceIdle, events[0].State)
+
+	// 空闲期间重复上报 idle 不产生待确认记录
+	d.Observe("u1", PresenceIdle, now.Add(4*time.Second))
+	assert.Empty(t, d.pending)
+
+	d.Observe("u1", PresenceActive, now.Add(5*time.Second))
+	events = d.due(now.Add(6*time.Second), false)
+	require.Len(t, events, 1)
+	assert.Equal(t, PresenceActive, events[0].State)
+
+	d.Observe("u1", PresenceOffline, now.Add(7*time.Second))
+	events = d.due(now.Add(8*time.Second), false)
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Online)
+	assert.Equal(t, PresenceOffline, events[0].State)
+}
+
+func TestConnectService_SweepReportsIdleConnection(t *testing.T) {
+	publisher := &fakePresencePublisher{}
+	d := NewPresenceDebouncer(time.Second, publisher)
+	s := &ConnectService{presence: d, presenceIdle: time.Minute}
+
+	connManager := manager.NewConnectionManager()
+	now := time.Now()
+	stale := manager.NewClient(nil, "u1", "d1")
+	stale.SetIdleThreshold(s.PresenceIdleThreshold())
+	stale.MarkActive(now.Add(-2 * time.Minute))
+	connManager.Register(stale)
+	connManager.Register(manager.NewClient(nil, "u2", "d1"))
+
+	s.sweepPresenceIdle(connManager.ActivitySnapshot(now))
+	events := d.due(time.Now().Add(time.Second), true)
+	require.Len(t, events, 2)
+	states := map[string]PresenceState{}
+	for _, event := range events {
+		assert.True(t, event.Online)
+		states[event.UserUUID] = event.State
+	}
+	assert.Equal(t, map[string]PresenceState{"u1": PresenceIdle, "u2": PresenceActive}, states)
+}
+
+// fakePresenceAggregator 内存版跨节点在线状态汇总，模拟多节点共享的 Redis。
+type fakePresenceAggregator struct {
+	mu          sync.Mutex
+	states      map[string]map[string]PresenceState // user_uuid -> node_id -> state
+	reportCalls int
+	batchCalls  int
+	// release 非 nil 时 Report 阻塞到其关闭，模拟 Redis 慢响应
+	release chan struct{}
+}
+
+func newFakePresenceAggregator() *fakePresenceAggregator {
+	return &fakePresenceAggregator{states: make(map[string]map[string]PresenceState)}
+}
+
+func (f *fakePresenceAggregator) Report(_ context.Context, userUUID, nodeID string, local PresenceState) (PresenceState, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reportCalls++
+	return f.reportLocked(userUUID, nodeID, local), nil
+}
+
+func (f *fakePresenceAggregator) ReportBatch(_ context.Context, nodeID string, local map[string]PresenceState) (map[string]PresenceState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchCalls++
+	result := make(map[string]PresenceState, len(local))
+	for userUUID, state := range local {
+		result[userUUID] = f.reportLocked(userUUID, nodeID, state)
+	}
+	return result, nil
+}
+
+func (f *fakePresenceAggregator) reportLocked(userUUID, nodeID string, local PresenceState) PresenceState {
+	nodes := f.states[userUUID]
+	if nodes == nil {
+		nodes = make(map[string]PresenceState)
+		f.states[userUUID] = nodes
+	}
+	if local == PresenceOffline {
+		delete(nodes, nodeID)
+	} else {
+		nodes[nodeID] = local
+	}
+	best := PresenceOffline
+	for _, state := range nodes {
+		if state == PresenceActive {
+			return PresenceActive
+		}
+		best = PresenceIdle
+	}
+	return best
+}
+
+func newAggregatedPresenceNode(aggregator PresenceAggregator, nodeID string) (*ConnectService, *PresenceDebouncer) {
+	d := NewPresenceDebouncer(time.Second, &fakePresencePublisher{})
+	s := &ConnectService{presence: d, presenceIdle: time.Minute}
+	s.SetConnectionRegistry(newFakeConnectionRegistry(), nodeID)
+	s.SetPresenceAggregator(aggregator)
+	return s, d
+}
+
+func TestConnectService_PresenceAggregatedAcrossNodes(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	aggregator := newFakePresenceAggregator()
+	nodeA, debouncerA := newAggregatedPresenceNode(aggregator, "node-a")
+	nodeB, debouncerB := newAggregatedPresenceNode(aggregator, "node-b")
+	flush := func(d *PresenceDebouncer) []PresenceEvent {
+		return d.due(time.Now().Add(time.Second), true)
+	}
+
+	// 手机连 node-a、电脑连 node-b。
+	nodeA.ObservePresence("u1", PresenceActive)
+	nodeB.ObservePresence("u1", PresenceActive)
+	require.Len(t, flush(debouncerA), 1)
+	flush(debouncerB)
+
+	// node-a 上的连接空闲而 node-b 仍活跃：整体仍为活跃，不投递 idle。
+	nodeA.ObservePresence("u1", PresenceIdle)
+	assert.Empty(t, flush(debouncerA))
+
+	// node-a 上的连接断开而 node-b 仍在线：不投递离线。
+	nodeA.ObservePresence("u1", PresenceOffline)
+	assert.Empty(t, flush(debouncerA))
+
+	// node-b 上的连接也断开：由 node-b 投递离线。
+	nodeB.ObservePresence("u1", PresenceOffline)
+	events := flush(debouncerB)
+	require.Len(t, events, 1)
+	assert.Equal(t, PresenceOffline, events[0].State)
+	assert.False(t, events[0].Online)
+}
+
+func TestConnectService_PresenceWithoutNodeIDIgnoresAggregator(t *testing.T) {
+	s := &ConnectService{presenceIdle: time.Minute}
+	s.SetPresenceAggregator(newFakePresenceAggregator())
+	assert.Nil(t, s.presenceAgg, "未设置本节点 ID 时不启用跨节点汇总")
+}
+
+func TestConnectService_SweepAggregatesInBatches(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	aggregator := newFakePresenceAggregator()
+	nodeA, debouncerA := newAggregatedPresenceNode(aggregator, "node-a")
+	nodeB, _ := newAggregatedPresenceNode(aggregator, "node-b")
+	t.Cleanup(nodeA.stopPresenceRefresh)
+	t.Cleanup(nodeB.stopPresenceRefresh)
+
+	// u1 在 node-b 仍活跃：node-a 扫描到空闲后汇总为活跃。
+	nodeB.ObservePresence("u1", PresenceActive)
+	activity := make(map[string]bool, presenceSweepBatchSize+1)
+	for i := 0; i < presenceSweepBatchSize; i++ {
+		activity[fmt.Sprintf("bulk-%d", i)] = true
+	}
+	activity["u1"] = false
+
+	nodeA.sweepPresenceIdle(activity)
+	assert.Equal(t, 2, aggregator.batchCalls, "按批走 pipeline")
+	assert.Equal(t, 1, aggregator.reportCalls, "扫描不逐用户调用 Report")
+
+	events := debouncerA.due(time.Now().Add(time.Second), true)
+	require.Len(t, events, presenceSweepBatchSize+1)
+	for _, event := range events {
+		assert.Equal(t, PresenceActive, event.State)
+	}
+}
+
+func TestConnectService_RefreshPresenceDoesNotWaitForAggregator(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	aggregator := newFakePresenceAggregator()
+	aggregator.release = make(chan struct{})
+	s, d := newAggregatedPresenceNode(aggregator, "node-a")
+
+	var mu sync.Mutex
+	online, active := true, true
+	activity := func(string, time.Time) (bool, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return online, active
+	}
+
+	returned := make(chan struct{})
+	go func() {
+		s.RefreshPresence("u1", activity)
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("汇总阻塞时 RefreshPresence 不应等待")
+	}
+
+	// 汇总未完成前连接断开：再次投递的刷新按处理时的状态（离线）上报。
+	mu.Lock()
+	online, active = false, false
+	mu.Unlock()
+	s.RefreshPresence("u1", activity)
+	close(aggregator.release)
+	s.stopPresenceRefresh()
+
+	assert.Empty(t, d.due(time.Now().Add(time.Second), true), "上线后立即离线，防抖后不投递")
+}
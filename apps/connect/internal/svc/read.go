@@ -0,0 +1,104 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	rediskey "ChatServer/consts/redisKey"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// readConvIDMaxLen conv_id 最大长度，超出视为非法帧。
+const readConvIDMaxLen = 64
+
+var (
+	// ErrReadInvalid 表示 read 帧 data 格式非法（缺少 conv_id、seq 非正等）。
+	ErrReadInvalid = errors.New("read data is invalid")
+	// ErrReadNotMember 表示上报者不是该单聊会话的参与者。
+	ErrReadNotMember = errors.New("reader is not a conversation member")
+)
+
+// ReadData 定义 type=read 时的 data 结构：客户端已读到该会话的 seq（含）。
+type ReadData struct {
+	ConvID string `json:"conv_id"`
+	Seq    int64  `json:"seq"`
+}
+
+// ReadPositionStore 会话已读位置存储。
+// 已读位置只前进不后退：上报的 seq 小于等于已存储值时忽略。
+type ReadPositionStore interface {
+	MarkRead(ctx context.Context, userUUID, convID string, seq int64) error
+}
+
+// luaMarkRead 仅在新 seq 更大时写入已读位置，并续期 TTL。
+// KEYS[1]: msg:read:{user_uuid}；ARGV[1]: conv_id；ARGV[2]: seq；ARGV[3]: TTL 秒数。
+const luaMarkRead = `
+local cur = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if tonumber(ARGV[2]) > cur then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`
+
+var markReadScript = redis.NewScript(luaMarkRead)
+
+// redisReadPositionStore 基于 Redis Hash 的已读位置存储（msg:read:{user_uuid}，field 为 conv_id）。
+// 未读数 = 会话最大 seq - 已读 seq，由 msg 服务读取该 Hash 计算。
+type redisReadPositionStore struct {
+	redisClient *redis.Client
+}
+
+// NewRedisReadPositionStore 创建基于 Redis 的已读位置存储。
+func NewRedisReadPositionStore(redisClient *redis.Client) ReadPositionStore {
+	return &redisReadPositionStore{redisClient: redisClient}
+}
+
+// MarkRead 原子推进已读位置。
+func (r *redisReadPositionStore) MarkRead(ctx context.Context, userUUID, convID string, seq int64) error {
+	ttl := int64(rediskey.ConvReadPositionTTL.Seconds())
+	return markReadScript.Run(ctx, r.redisClient, []string{rediskey.ConvReadPositionKey(userUUID)}, convID, seq, ttl).Err()
+}
+
+// SetReadPositionStore 设置已读位置存储。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 表示丢弃 read 帧。
+func (s *ConnectService) SetReadPositionStore(store ReadPositionStore) {
+	s.readStore = store
+}
+
+// ParseRead 解析并校验 read 帧。
+// 校验规则：
+// - conv_id 必填且不超过 64 字符，seq 必须大于 0；
+// - 单聊会话要求上报者是参与者之一；群聊成员关系由 msg/group 服务维护，此处不校验。
+func (s *ConnectService) ParseRead(raw json.RawMessage, userUUID string) (*ReadData, error) {
+	var data ReadData
+	if len(raw) == 0 || json.Unmarshal(raw, &data) != nil {
+		return nil, ErrReadInvalid
+	}
+	data.ConvID = strings.TrimSpace(data.ConvID)
+	if data.ConvID == "" || len(data.ConvID) > readConvIDMaxLen || data.Seq <= 0 {
+		return nil, ErrReadInvalid
+	}
+
+	if strings.HasPrefix(data.ConvID, p2pConvPrefix) {
+		userA, userB, ok := parseP2PConvID(data.ConvID)
+		if !ok {
+			return nil, ErrReadInvalid
+		}
+		if userUUID != userA && userUUID != userB {
+			return nil, ErrReadNotMember
+		}
+	}
+	return &data, nil
+}
+
+// MarkRead 记录会话已读位置；未配置存储时直接忽略。
+func (s *ConnectService) MarkRead(ctx context.Context, session *Session, data *ReadData) error {
+	if s.readStore == nil {
+		return nil
+	}
+	return s.readStore.MarkRead(ctx, session.UserUUID, data.ConvID, data.Seq)
+}
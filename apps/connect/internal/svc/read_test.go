@@ -0,0 +1,73 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReadPositionStore struct {
+	calls []ReadData
+	err   error
+}
+
+func (f *fakeReadPositionStore) MarkRead(_ context.Context, _, convID string, seq int64) error {
+	f.calls = append(f.calls, ReadData{ConvID: convID, Seq: seq})
+	return f.err
+}
+
+func TestParseRead_Valid(t *testing.T) {
+	s := &ConnectService{}
+
+	data, err := s.ParseRead(json.RawMessage(`{"conv_id":" p2p-1001-1002 ","seq":12}`), "1002")
+	require.NoError(t, err)
+	assert.Equal(t, &ReadData{ConvID: "p2p-1001-1002", Seq: 12}, data)
+
+	// 群聊会话成员关系由 msg/group 服务维护，此处不校验。
+	data, err = s.ParseRead(json.RawMessage(`{"conv_id":"g-2001","seq":3}`), "1001")
+	require.NoError(t, err)
+	assert.Equal(t, "g-2001", data.ConvID)
+}
+
+func TestParseRead_Invalid(t *testing.T) {
+	s := &ConnectService{}
+
+	cases := []struct {
+		name string
+		raw  string
+		want error
+	}{
+		{"empty_data", ``, ErrReadInvalid},
+		{"bad_json", `{"conv_id":`, ErrReadInvalid},
+		{"missing_conv_id", `{"seq":1}`, ErrReadInvalid},
+		{"zero_seq", `{"conv_id":"g-2001","seq":0}`, ErrReadInvalid},
+		{"negative_seq", `{"conv_id":"g-2001","seq":-1}`, ErrReadInvalid},
+		{"too_long", `{"conv_id":"g-0123456789012345678901234567890123456789012345678901234567890123","seq":1}`, ErrReadInvalid},
+		{"malformed_p2p", `{"conv_id":"p2p-1001","seq":1}`, ErrReadInvalid},
+		{"not_member", `{"conv_id":"p2p-1002-1003","seq":1}`, ErrReadNotMember},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.ParseRead(json.RawMessage(tc.raw), "1001")
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestMarkRead_DelegatesToStore(t *testing.T) {
+	s := &ConnectService{}
+	session := &Session{UserUUID: "1001"}
+	data := &ReadData{ConvID: "p2p-1001-1002", Seq: 5}
+
+	// 未配置存储：直接忽略。
+	assert.NoError(t, s.MarkRead(context.Background(), session, data))
+
+	store := &fakeReadPositionStore{err: errors.New("redis down")}
+	s.SetReadPositionStore(store)
+	assert.EqualError(t, s.MarkRead(context.Background(), session, data), "redis down")
+	assert.Equal(t, []ReadData{*data}, store.calls)
+}
@@ -0,0 +1,123 @@
+package svc
+
+import (
+	"context"
+	"time"
+
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// readyStateTimeout 加载 ready 帧状态的超时时间。
+	// 仅读缓存，超时即降级，避免拖慢连接建立。
+	readyStateTimeout = 200 * time.Millisecond
+
+	// applyPendingPlaceholder 好友申请待处理 ZSet 的空值占位成员（与 user 服务保持一致）。
+	applyPendingPlaceholder = "__EMPTY__"
+)
+
+// ReadyData 定义 type=ready 时的 data 结构。
+// 连接建立后服务端下发的首帧，客户端据此一次性发起增量同步，而不是多次冷查询。
+type ReadyData struct {
+	// ServerTime 服务端当前时间（毫秒），用于客户端校准时钟。
+	ServerTime int64 `json:"server_time"`
+	// HeartbeatInterval 协商后的心跳间隔（秒）。
+	HeartbeatInterval int `json:"heartbeat_interval"`
+	// TotalUnread 当前总未读数（目前仅包含好友申请未读，消息未读待 msg 服务接入后累加）。
+	TotalUnread int64 `json:"total_unread"`
+	// FriendApplyUnread 好友申请未读数。
+	FriendApplyUnread int64 `json:"friend_apply_unread"`
+	// SyncWatermark 最近一次收件箱活动时间（毫秒），0 表示缓存中无活动记录。
+	SyncWatermark int64 `json:"sync_watermark"`
+	// Degraded 为 true 表示缓存读取失败，以上计数不可信，客户端应执行全量同步。
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// ReadyState 为构造 ready 帧所需的缓存状态。
+type ReadyState struct {
+	FriendApplyUnread int64
+	LastInboxActivity time.Time
+}
+
+// ReadyStateSource 加载 ready 帧状态的数据源。
+// 实现只允许读缓存，不允许回源 DB。
+type ReadyStateSource interface {
+	LoadReadyState(ctx context.Context, userUUID string) (ReadyState, error)
+}
+
+// SetReadyStateSource 设置 ready 帧状态数据源。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 表示不加载缓存状态。
+func (s *ConnectService) SetReadyStateSource(source ReadyStateSource) {
+	s.readySource = source
+}
+
+// BuildReadyData 构造连接建立后的 ready 帧数据。
+// 状态加载失败时不影响连接，仅标记 Degraded 由客户端兜底全量同步。
+func (s *ConnectService) BuildReadyData(ctx context.Context, session *Session) ReadyData {
+	data := ReadyData{
+		ServerTime:        time.Now().UnixMilli(),
+		HeartbeatInterval: int(session.HeartbeatInterval / time.Second),
+	}
+	if s.readySource == nil {
+		return data
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, readyStateTimeout)
+	defer cancel()
+
+	state, err := s.readySource.LoadReadyState(loadCtx, session.UserUUID)
+	if err != nil {
+		logger.Warn(ctx, "加载 ready 帧状态失败，降级为全量同步",
+			logger.String("user_uuid", session.UserUUID),
+			logger.ErrorField("error", err),
+		)
+		data.Degraded = true
+		return data
+	}
+
+	data.FriendApplyUnread = state.FriendApplyUnread
+	data.TotalUnread = state.FriendApplyUnread
+	if !state.LastInboxActivity.IsZero() {
+		data.SyncWatermark = state.LastInboxActivity.UnixMilli()
+	}
+	return data
+}
+
+// redisReadyStateSource 基于 user 服务维护的 Redis 缓存加载 ready 帧状态。
+// 单次 Pipeline 读取：
+// - user:notify:friend_apply:unread:{uuid}  好友申请未读计数；
+// - user:apply:pending:{uuid}               待处理申请 ZSet（score 为申请时间戳），取最大 score 作为水位。
+type redisReadyStateSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisReadyStateSource 创建基于 Redis 的 ready 帧状态数据源。
+func NewRedisReadyStateSource(redisClient *redis.Client) ReadyStateSource {
+	return &redisReadyStateSource{redisClient: redisClient}
+}
+
+// LoadReadyState 读取好友申请未读数与最近收件箱活动时间。
+func (r *redisReadyStateSource) LoadReadyState(ctx context.Context, userUUID string) (ReadyState, error) {
+	pipe := r.redisClient.Pipeline()
+	unreadCmd := pipe.Get(ctx, rediskey.ApplyUnreadNotifyKey(userUUID))
+	latestCmd := pipe.ZRevRangeWithScores(ctx, rediskey.ApplyPendingKey(userUUID), 0, 1)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return ReadyState{}, err
+	}
+
+	var state ReadyState
+	if unread, err := unreadCmd.Int64(); err == nil && unread > 0 {
+		state.FriendApplyUnread = unread
+	}
+	for _, z := range latestCmd.Val() {
+		if member, _ := z.Member.(string); member == applyPendingPlaceholder || z.Score <= 0 {
+			continue
+		}
+		state.LastInboxActivity = time.Unix(int64(z.Score), 0)
+		break
+	}
+	return state, nil
+}
@@ -0,0 +1,131 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"ChatServer/pkg/logger"
+)
+
+const (
+	// resumeMaxConvs 单次 resume 最多携带的会话数，超出视为非法帧（客户端应改走 HTTP 拉取）。
+	resumeMaxConvs = 200
+	// resumeLoadTimeout 单次 resume 拉取补发消息的总超时，超时的会话按截断处理。
+	resumeLoadTimeout = 2 * time.Second
+	// defaultResumeMaxPerConv 单会话默认最多补发条数。
+	defaultResumeMaxPerConv = 100
+	// resumeMaxTotal 单次 resume 最多补发的消息总数，放不下的会话整体列入 resume_truncated。
+	resumeMaxTotal = 1000
+)
+
+// ErrResumeInvalid 表示 resume 帧 data 格式非法（conv_seqs 为空/超限/含非法项）。
+var ErrResumeInvalid = errors.New("resume data is invalid")
+
+// ResumeData 定义 type=resume 时的 data 结构。
+// conv_seqs 为客户端本地各会话已收到的最大 seq（conv_id -> last_seq）。
+type ResumeData struct {
+	ConvSeqs map[string]int64 `json:"conv_seqs"`
+}
+
+// ReplayMessage 为 resume 补发的单条消息，以 type=message 帧下发。
+// Data 为 msg 服务序列化后的消息体，connect 不做解析。
+type ReplayMessage struct {
+	ConvID string          `json:"conv_id"`
+	Seq    int64           `json:"seq"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// ResumeTruncatedData 定义 type=resume_truncated 时的 data 结构。
+// 列出的会话缺口过大（或补发失败）未做补发，客户端需对这些会话走 HTTP 拉取。
+type ResumeTruncatedData struct {
+	ConvIDs []string `json:"conv_ids"`
+}
+
+// ResumeDoneData 定义 type=resume_done 时的 data 结构，标记补发结束。
+type ResumeDoneData struct {
+	Replayed int `json:"replayed"`
+}
+
+// MessageReplaySource 拉取会话内 seq 大于 afterSeq 的消息（按 seq 升序，最多 limit 条）。
+// 由 msg 服务提供；实现需校验 userUUID 为会话成员，非成员返回空列表。
+type MessageReplaySource interface {
+	LoadMessagesAfter(ctx context.Context, userUUID, convID string, afterSeq int64, limit int) ([]ReplayMessage, error)
+}
+
+// SetReplaySource 设置 resume 补发数据源及单会话补发上限。
+// 应在服务启动阶段调用（接收连接之前）；source 为 nil 时不支持 resume（见 ResumeEnabled），
+// maxPerConv <= 0 时使用默认值 100。
+func (s *ConnectService) SetReplaySource(source MessageReplaySource, maxPerConv int) {
+	if maxPerConv <= 0 {
+		maxPerConv = defaultResumeMaxPerConv
+	}
+	s.replaySource = source
+	s.resumeMaxPerConv = maxPerConv
+}
+
+// ResumeEnabled 是否支持断线续传：未设置补发数据源时 resume 帧按不支持的类型处理。
+func (s *ConnectService) ResumeEnabled() bool {
+	return s.replaySource != nil
+}
+
+// ParseResume 解析并校验 resume 帧。
+// 校验规则：conv_seqs 非空且不超过 200 项；conv_id 非空；last_seq 不小于 0。
+func (s *ConnectService) ParseResume(raw json.RawMessage) (*ResumeData, error) {
+	var data ResumeData
+	if len(raw) == 0 || json.Unmarshal(raw, &data) != nil {
+		return nil, ErrResumeInvalid
+	}
+	if len(data.ConvSeqs) == 0 || len(data.ConvSeqs) > resumeMaxConvs {
+		return nil, ErrResumeInvalid
+	}
+	for convID, seq := range data.ConvSeqs {
+		if strings.TrimSpace(convID) == "" || seq < 0 {
+			return nil, ErrResumeInvalid
+		}
+	}
+	return &data, nil
+}
+
+// Resume 按会话拉取断线期间的消息，返回待补发消息与需回退到 HTTP 拉取的会话。
+// 单会话缺口超过上限、或补发总数将超过 resumeMaxTotal 时不做部分补发，整体标记为截断，避免客户端拿到不连续的 seq。
+// 会话按 conv_id 排序处理，保证补发顺序稳定。
+func (s *ConnectService) Resume(ctx context.Context, session *Session, data *ResumeData) ([]ReplayMessage, []string) {
+	convIDs := make([]string, 0, len(data.ConvSeqs))
+	for convID := range data.ConvSeqs {
+		convIDs = append(convIDs, convID)
+	}
+	sort.Strings(convIDs)
+
+	if s.replaySource == nil {
+		return nil, convIDs
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, resumeLoadTimeout)
+	defer cancel()
+
+	limit := s.resumeMaxPerConv
+	var messages []ReplayMessage
+	var truncated []string
+	for _, convID := range convIDs {
+		// 多取一条用于判断缺口是否超过上限。
+		batch, err := s.replaySource.LoadMessagesAfter(loadCtx, session.UserUUID, convID, data.ConvSeqs[convID], limit+1)
+		if err != nil {
+			logger.Warn(ctx, "resume 拉取补发消息失败，回退为 HTTP 拉取",
+				logger.String("conv_id", convID),
+				logger.ErrorField("error", err),
+			)
+			truncated = append(truncated, convID)
+			continue
+		}
+		if len(batch) > limit || len(messages)+len(batch) > resumeMaxTotal {
+			truncated = append(truncated, convID)
+			continue
+		}
+		messages = append(messages, batch...)
+	}
+	return messages, truncated
+}
@@ -0,0 +1,109 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeReplaySource 按会话返回 (afterSeq, maxSeq] 区间的消息。
+type fakeReplaySource struct {
+	maxSeq map[string]int64
+	errs   map[string]error
+	calls  []string
+}
+
+func (f *fakeReplaySource) LoadMessagesAfter(_ context.Context, userUUID, convID string, afterSeq int64, limit int) ([]ReplayMessage, error) {
+	f.calls = append(f.calls, fmt.Sprintf("%s:%s:%d:%d", userUUID, convID, afterSeq, limit))
+	if err := f.errs[convID]; err != nil {
+		return nil, err
+	}
+	var messages []ReplayMessage
+	for seq := afterSeq + 1; seq <= f.maxSeq[convID] && len(messages) < limit; seq++ {
+		messages = append(messages, ReplayMessage{ConvID: convID, Seq: seq})
+	}
+	return messages, nil
+}
+
+func TestParseResume(t *testing.T) {
+	s := &ConnectService{}
+
+	data, err := s.ParseResume(json.RawMessage(`{"conv_seqs":{"c1":10,"c2":0}}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"c1": 10, "c2": 0}, data.ConvSeqs)
+
+	tooMany := make(map[string]int64, resumeMaxConvs+1)
+	for i := 0; i <= resumeMaxConvs; i++ {
+		tooMany[fmt.Sprintf("c%d", i)] = 1
+	}
+	tooManyRaw, err := json.Marshal(ResumeData{ConvSeqs: tooMany})
+	require.NoError(t, err)
+
+	for _, raw := range []string{``, `{}`, `{"conv_seqs":{}}`, `{"conv_seqs":{" ":1}}`, `{"conv_seqs":{"c1":-1}}`, `[]`, string(tooManyRaw)} {
+		_, err := s.ParseResume(json.RawMessage(raw))
+		assert.ErrorIs(t, err, ErrResumeInvalid, raw)
+	}
+}
+
+func TestResume_ReplaysWithinLimitAndTruncatesLargeGaps(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	source := &fakeReplaySource{
+		maxSeq: map[string]int64{"c1": 13, "c2": 100, "c3": 5},
+		errs:   map[string]error{"c4": errors.New("msg unavailable")},
+	}
+	s := &ConnectService{}
+	s.SetReplaySource(source, 5)
+
+	messages, truncated := s.Resume(context.Background(), &Session{UserUUID: "u1"}, &ResumeData{
+		ConvSeqs: map[string]int64{"c1": 10, "c2": 10, "c3": 5, "c4": 1},
+	})
+
+	// c1 补发 11~13；c2 缺口 90 条超过上限被截断；c3 已追平；c4 拉取失败回退 HTTP。
+	seqs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		seqs = append(seqs, fmt.Sprintf("%s#%d", msg.ConvID, msg.Seq))
+	}
+	assert.Equal(t, []string{"c1#11", "c1#12", "c1#13"}, seqs)
+	assert.Equal(t, []string{"c2", "c4"}, truncated)
+	assert.Equal(t, "u1:c1:10:6", source.calls[0], "应多取一条判断是否超限")
+}
+
+func TestResume_TruncatesBeyondTotalLimit(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	// 每个会话 100 条缺口（等于单会话上限），总数超过 resumeMaxTotal 后的会话整体截断。
+	convSeqs := make(map[string]int64)
+	maxSeq := make(map[string]int64)
+	convCount := resumeMaxTotal/defaultResumeMaxPerConv + 2
+	for i := 0; i < convCount; i++ {
+		convID := fmt.Sprintf("c%02d", i)
+		convSeqs[convID] = 0
+		maxSeq[convID] = defaultResumeMaxPerConv
+	}
+	s := &ConnectService{}
+	s.SetReplaySource(&fakeReplaySource{maxSeq: maxSeq}, 0)
+
+	messages, truncated := s.Resume(context.Background(), &Session{UserUUID: "u1"}, &ResumeData{ConvSeqs: convSeqs})
+	assert.Len(t, messages, resumeMaxTotal)
+	assert.Equal(t, []string{fmt.Sprintf("c%02d", convCount-2), fmt.Sprintf("c%02d", convCount-1)}, truncated)
+}
+
+func TestResume_NoSourceTruncatesAll(t *testing.T) {
+	s := &ConnectService{}
+	s.SetReplaySource(nil, 0)
+	assert.Equal(t, defaultResumeMaxPerConv, s.resumeMaxPerConv)
+
+	messages, truncated := s.Resume(context.Background(), &Session{UserUUID: "u1"}, &ResumeData{
+		ConvSeqs: map[string]int64{"b": 1, "a": 2},
+	})
+	assert.Empty(t, messages)
+	assert.Equal(t, "a,b", strings.Join(truncated, ","))
+}
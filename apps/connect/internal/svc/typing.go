@@ -0,0 +1,189 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	rediskey "ChatServer/consts/redisKey"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// p2pConvPrefix 单聊会话 ID 前缀，格式：p2p-<较小 uuid>-<较大 uuid>。
+	p2pConvPrefix = "p2p-"
+
+	// typingMinInterval 同一会话相同状态的 typing 帧最小转发间隔。
+	// 输入状态变化（开始/停止）立即转发，重复的“正在输入”按该间隔限流。
+	typingMinInterval = 3 * time.Second
+	// typingMaxConvs 单连接最多跟踪的会话数，超出后清空重新计数，防止内存增长。
+	typingMaxConvs = 64
+	// typingMaxGroupMembers 群聊 typing 扇出的成员数上限，超出的大群不转发输入状态。
+	typingMaxGroupMembers = 500
+)
+
+var (
+	// ErrTypingInvalid 表示 typing 帧 data 格式非法（缺少 conv_id 等）。
+	ErrTypingInvalid = errors.New("typing data is invalid")
+	// ErrTypingConvUnsupported 表示会话类型暂不支持 typing（未配置群成员数据源时的群聊、超大群）。
+	ErrTypingConvUnsupported = errors.New("typing conversation is unsupported")
+	// ErrTypingNotMember 表示发送者不是该会话的参与者。
+	ErrTypingNotMember = errors.New("sender is not a conversation member")
+)
+
+// TypingData 定义 type=typing 时的 data 结构。
+// 上行：客户端只需携带 conv_id/is_typing；下行：服务端补充 from_uuid 后转发给对端。
+type TypingData struct {
+	ConvID   string `json:"conv_id"`
+	IsTyping bool   `json:"is_typing"`
+	FromUUID string `json:"from_uuid,omitempty"`
+}
+
+// typingMark 记录某会话最近一次转发的输入状态。
+type typingMark struct {
+	isTyping bool
+	at       time.Time
+}
+
+// ParseTyping 解析并校验 typing 帧，返回下行数据与接收方 UUID 列表（不含发送者）。
+// 校验规则：
+//   - conv_id 必填；
+//   - 单聊（p2p-<uuid>-<uuid>）：发送者必须是参与者之一，且不能是自己与自己的会话；
+//   - 其余 conv_id 视为群聊（conv_id 即 group_uuid）：需配置群成员数据源，发送者必须是群成员，
+//     成员数超过 typingMaxGroupMembers 的大群不转发。
+func (s *ConnectService) ParseTyping(ctx context.Context, raw json.RawMessage, senderUUID string) (*TypingData, []string, error) {
+	var data TypingData
+	if len(raw) == 0 || json.Unmarshal(raw, &data) != nil {
+		return nil, nil, ErrTypingInvalid
+	}
+	data.ConvID = strings.TrimSpace(data.ConvID)
+	if data.ConvID == "" {
+		return nil, nil, ErrTypingInvalid
+	}
+
+	var (
+		recipients []string
+		err        error
+	)
+	if strings.HasPrefix(data.ConvID, p2pConvPrefix) {
+		recipients, err = p2pTypingRecipients(data.ConvID, senderUUID)
+	} else {
+		recipients, err = s.groupTypingRecipients(ctx, data.ConvID, senderUUID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data.FromUUID = senderUUID
+	return &data, recipients, nil
+}
+
+// p2pTypingRecipients 返回单聊会话中发送者的对端。
+func p2pTypingRecipients(convID, senderUUID string) ([]string, error) {
+	userA, userB, ok := parseP2PConvID(convID)
+	if !ok {
+		return nil, ErrTypingConvUnsupported
+	}
+	switch senderUUID {
+	case userA:
+		return []string{userB}, nil
+	case userB:
+		return []string{userA}, nil
+	default:
+		return nil, ErrTypingNotMember
+	}
+}
+
+// groupTypingRecipients 返回群聊中除发送者外的全部成员（是否在线由投递时决定）。
+func (s *ConnectService) groupTypingRecipients(ctx context.Context, groupUUID, senderUUID string) ([]string, error) {
+	if s.groupMembers == nil {
+		return nil, ErrTypingConvUnsupported
+	}
+	members, err := s.groupMembers.GroupMembers(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) > typingMaxGroupMembers {
+		return nil, ErrTypingConvUnsupported
+	}
+	if !slices.Contains(members, senderUUID) {
+		return nil, ErrTypingNotMember
+	}
+	recipients := make([]string, 0, len(members)-1)
+	for _, member := range members {
+		if member != senderUUID {
+			recipients = append(recipients, member)
+		}
+	}
+	return recipients, nil
+}
+
+// AllowTyping 判断本次 typing 帧是否需要转发（连接级限流）。
+// 仅在单连接的读协程中调用，无需加锁。
+func (s *ConnectService) AllowTyping(session *Session, data *TypingData, now time.Time) bool {
+	if session.typing == nil || len(session.typing) >= typingMaxConvs {
+		session.typing = make(map[string]typingMark)
+	}
+
+	last, ok := session.typing[data.ConvID]
+	if ok && last.isTyping == data.IsTyping && now.Sub(last.at) < typingMinInterval {
+		return false
+	}
+	session.typing[data.ConvID] = typingMark{isTyping: data.IsTyping, at: now}
+	return true
+}
+
+// parseP2PConvID 解析单聊会话 ID，返回两个参与者 UUID。
+func parseP2PConvID(convID string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(convID, p2pConvPrefix)
+	if !ok {
+		return "", "", false
+	}
+	userA, userB, ok := strings.Cut(rest, "-")
+	if !ok || userA == "" || userB == "" || userA == userB || strings.Contains(userB, "-") {
+		return "", "", false
+	}
+	return userA, userB, true
+}
+
+// RecordTypingForwarded 累加已转发的 typing 帧数（按实际投递的连接数计）。
+func (s *ConnectService) RecordTypingForwarded(n int) {
+	if n > 0 {
+		s.typingForwarded.Add(int64(n))
+	}
+}
+
+// TypingForwardedTotal 返回累计转发的 typing 帧数（/metrics 暴露）。
+func (s *ConnectService) TypingForwardedTotal() int64 {
+	return s.typingForwarded.Load()
+}
+
+// GroupMemberSource 查询群成员 UUID 列表（由群组服务维护）。
+type GroupMemberSource interface {
+	GroupMembers(ctx context.Context, groupUUID string) ([]string, error)
+}
+
+// redisGroupMemberSource 基于 group:members:{group_uuid} 查询群成员。
+type redisGroupMemberSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisGroupMemberSource 创建基于 Redis 的群成员查询器。
+func NewRedisGroupMemberSource(redisClient *redis.Client) GroupMemberSource {
+	return &redisGroupMemberSource{redisClient: redisClient}
+}
+
+// GroupMembers 读取群成员集合，key 不存在时返回空列表（视为非群成员）。
+func (g *redisGroupMemberSource) GroupMembers(ctx context.Context, groupUUID string) ([]string, error) {
+	return g.redisClient.SMembers(ctx, rediskey.GroupMembersKey(groupUUID)).Result()
+}
+
+// SetGroupMemberSource 设置群成员查询器。
+// 应在服务启动阶段调用（接收连接之前）；传 nil 表示群聊不转发 typing。
+func (s *ConnectService) SetGroupMemberSource(source GroupMemberSource) {
+	s.groupMembers = source
+}
@@ -0,0 +1,123 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTyping_Valid(t *testing.T) {
+	s := &ConnectService{}
+
+	data, peers, err := s.ParseTyping(context.Background(), json.RawMessage(`{"conv_id":" p2p-1001-1002 ","is_typing":true}`), "1001")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1002"}, peers)
+	assert.Equal(t, "p2p-1001-1002", data.ConvID)
+	assert.True(t, data.IsTyping)
+	assert.Equal(t, "1001", data.FromUUID)
+
+	_, peers, err = s.ParseTyping(context.Background(), json.RawMessage(`{"conv_id":"p2p-1001-1002","is_typing":false}`), "1002")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1001"}, peers)
+}
+
+func TestParseTyping_Invalid(t *testing.T) {
+	s := &ConnectService{}
+
+	cases := []struct {
+		name string
+		raw  string
+		want error
+	}{
+		{"empty_data", ``, ErrTypingInvalid},
+		{"bad_json", `{"conv_id":`, ErrTypingInvalid},
+		{"missing_conv_id", `{"is_typing":true}`, ErrTypingInvalid},
+		{"group_conv_without_member_source", `{"conv_id":"g-2001","is_typing":true}`, ErrTypingConvUnsupported},
+		{"malformed_p2p", `{"conv_id":"p2p-1001","is_typing":true}`, ErrTypingConvUnsupported},
+		{"self_conv", `{"conv_id":"p2p-1001-1001","is_typing":true}`, ErrTypingConvUnsupported},
+		{"too_many_parts", `{"conv_id":"p2p-1001-1002-1003","is_typing":true}`, ErrTypingConvUnsupported},
+		{"not_member", `{"conv_id":"p2p-1002-1003","is_typing":true}`, ErrTypingNotMember},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := s.ParseTyping(context.Background(), json.RawMessage(tc.raw), "1001")
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+// fakeGroupMemberSource 内存版群成员数据源。
+type fakeGroupMemberSource struct {
+	members map[string][]string
+	err     error
+}
+
+func (f *fakeGroupMemberSource) GroupMembers(_ context.Context, groupUUID string) ([]string, error) {
+	return f.members[groupUUID], f.err
+}
+
+func TestParseTyping_GroupFanOutExcludesSender(t *testing.T) {
+	s := &ConnectService{}
+	s.SetGroupMemberSource(&fakeGroupMemberSource{members: map[string][]string{
+		"g-2001": {"1001", "1002", "1003"},
+	}})
+
+	data, recipients, err := s.ParseTyping(context.Background(), json.RawMessage(`{"conv_id":"g-2001","is_typing":true}`), "1002")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1001", "1003"}, recipients)
+	assert.Equal(t, "1002", data.FromUUID)
+
+	_, _, err = s.ParseTyping(context.Background(), json.RawMessage(`{"conv_id":"g-2001","is_typing":true}`), "1009")
+	assert.ErrorIs(t, err, ErrTypingNotMember)
+}
+
+func TestParseTyping_GroupLimits(t *testing.T) {
+	large := make([]string, typingMaxGroupMembers+1)
+	for i := range large {
+		large[i] = strconv.Itoa(1001 + i)
+	}
+
+	s := &ConnectService{}
+	s.SetGroupMemberSource(&fakeGroupMemberSource{members: map[string][]string{"g-big": large}})
+	_, _, err := s.ParseTyping(context.Background(), json.RawMessage(`{"conv_id":"g-big","is_typing":true}`), "1001")
+	assert.ErrorIs(t, err, ErrTypingConvUnsupported, "large groups do not forward typing")
+
+	lookupErr := errors.New("redis down")
+	s.SetGroupMemberSource(&fakeGroupMemberSource{err: lookupErr})
+	_, _, err = s.ParseTyping(context.Background(), json.RawMessage(`{"conv_id":"g-2001","is_typing":true}`), "1001")
+	assert.ErrorIs(t, err, lookupErr)
+}
+
+func TestAllowTyping_RateLimitsRepeatedState(t *testing.T) {
+	s := &ConnectService{}
+	session := &Session{UserUUID: "1001"}
+	now := time.Now()
+	typing := &TypingData{ConvID: "p2p-1001-1002", IsTyping: true}
+	stopped := &TypingData{ConvID: "p2p-1001-1002", IsTyping: false}
+
+	assert.True(t, s.AllowTyping(session, typing, now))
+	assert.False(t, s.AllowTyping(session, typing, now.Add(time.Second)), "repeated state within interval is dropped")
+	assert.True(t, s.AllowTyping(session, stopped, now.Add(2*time.Second)), "state change is forwarded immediately")
+	assert.True(t, s.AllowTyping(session, typing, now.Add(2*time.Second)))
+	assert.True(t, s.AllowTyping(session, typing, now.Add(2*time.Second+typingMinInterval)))
+
+	other := &TypingData{ConvID: "p2p-1001-1003", IsTyping: true}
+	assert.True(t, s.AllowTyping(session, other, now), "conversations are limited independently")
+}
+
+func TestAllowTyping_BoundedState(t *testing.T) {
+	s := &ConnectService{}
+	session := &Session{UserUUID: "1001"}
+	now := time.Now()
+
+	for i := 0; i < typingMaxConvs*2; i++ {
+		s.AllowTyping(session, &TypingData{ConvID: "p2p-1001-" + string(rune('a'+i%26)) + string(rune('a'+i/26)), IsTyping: true}, now)
+		assert.LessOrEqual(t, len(session.typing), typingMaxConvs)
+	}
+}
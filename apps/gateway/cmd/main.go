@@ -12,9 +12,12 @@ import (
 	"ChatServer/pkg/async"
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/deviceactive"
+	"ChatServer/pkg/grpcx"
 	"ChatServer/pkg/logger"
+	"ChatServer/pkg/metrics"
 	pkgminio "ChatServer/pkg/minio"
 	pkgredis "ChatServer/pkg/redis"
+	"ChatServer/pkg/result"
 	"context"
 	"fmt"
 	"net/http"
@@ -45,6 +48,9 @@ func main() {
 		_ = l.Sync()
 	}()
 
+	// 1.1 指标公共标签（/metrics 中所有指标携带 service / version）
+	metrics.Init("gateway", config.DefaultMetricsConfig().Version)
+
 	// 2. 初始化 Redis
 	redisCfg := config.DefaultRedisConfig()
 	redisClient, err := pkgredis.Build(redisCfg)
@@ -56,6 +62,9 @@ func main() {
 		redisClient = nil
 	} else {
 		pkgredis.ReplaceGlobal(redisClient)
+		// 修改密码/重置密码/注销后拒绝该用户此前签发的 Token；踢出设备后拒绝该设备此前签发的 Token
+		middleware.SetTokenVersionSource(middleware.NewRedisTokenVersionSource(redisClient))
+		middleware.SetTokenEpochSource(middleware.NewRedisTokenEpochSource(redisClient))
 		logger.Info(ctx, "Redis 初始化成功",
 			logger.String("addr", redisCfg.Addr),
 		)
@@ -123,6 +132,39 @@ func main() {
 		logger.Int("queue_size", deviceActiveCfg.QueueSize),
 	)
 
+	// 4.6 响应格式协商（Accept: application/x-protobuf）
+	responseCfg := config.DefaultGatewayResponseConfig()
+	result.SetProtobufEnabled(responseCfg.ProtobufEnabled)
+	logger.Info(ctx, "响应格式配置已加载",
+		logger.Bool("protobuf_enabled", responseCfg.ProtobufEnabled),
+	)
+
+	// 4.7 降级模式探测（Redis 不可用时标记降级，响应附带 X-Service-Mode: degraded）
+	serviceModeCfg := config.DefaultGatewayServiceModeConfig()
+	middleware.SetServiceModeHeaderEnabled(serviceModeCfg.HeaderEnabled)
+	probeCtx, stopProbe := context.WithCancel(context.Background())
+	defer stopProbe()
+	middleware.StartRedisHealthProbe(probeCtx, redisClient, serviceModeCfg.RedisProbeInterval)
+	logger.Info(ctx, "降级模式探测已启动",
+		logger.Bool("header_enabled", serviceModeCfg.HeaderEnabled),
+		logger.Duration("probe_interval", serviceModeCfg.RedisProbeInterval),
+		logger.Bool("degraded", middleware.IsDegraded()),
+	)
+
+	// 4.8 认证类接口审计日志（独立输出，默认关闭）
+	auditCfg := config.DefaultGatewayAuditConfig()
+	if auditCfg.Enabled {
+		auditLogger := logger.BuildAudit(auditCfg.OutputPaths)
+		middleware.SetAuditLogger(auditLogger)
+		defer func() {
+			_ = auditLogger.Sync()
+		}()
+	}
+	logger.Info(ctx, "审计日志配置已加载",
+		logger.Bool("enabled", auditCfg.Enabled),
+		logger.Any("output_paths", auditCfg.OutputPaths),
+	)
+
 	// 5. 初始化 gRPC 客户端（依赖注入）
 	userServiceAddr := os.Getenv("USER_SERVICE_ADDR")
 	if userServiceAddr == "" {
@@ -130,11 +172,23 @@ func main() {
 	}
 
 	// 5.1 创建熔断器
-	userServiceBreaker := pb.CreateCircuitBreaker("user-service")
-	logger.Info(ctx, "熔断器创建成功", logger.String("name", "user-service"))
+	breakerCfg := config.DefaultGRPCBreakerConfig()
+	userServiceBreaker := pb.CreateCircuitBreaker("user-service", grpcx.BreakerConfig{
+		MaxRequests:  uint32(breakerCfg.MaxRequests),
+		Interval:     breakerCfg.Interval,
+		Timeout:      breakerCfg.Timeout,
+		MinRequests:  uint32(breakerCfg.MinRequests),
+		FailureRatio: float64(breakerCfg.FailurePercent) / 100,
+	})
+	logger.Info(ctx, "熔断器创建成功",
+		logger.String("name", "user-service"),
+		logger.Int("min_requests", breakerCfg.MinRequests),
+		logger.Int("failure_percent", breakerCfg.FailurePercent),
+		logger.Duration("open_timeout", breakerCfg.Timeout),
+	)
 
 	// 5.2 创建 gRPC 连接
-	userServiceConn, err := pb.CreateUserServiceConnection(userServiceAddr, userServiceBreaker)
+	userServiceConn, err := pb.CreateUserServiceConnection(userServiceAddr)
 	if err != nil {
 		logger.Error(ctx, "创建用户服务 gRPC 连接失败", logger.ErrorField("error", err))
 		os.Exit(1)
@@ -146,6 +200,13 @@ func main() {
 	}()
 	logger.Info(ctx, "用户服务 gRPC 连接创建成功", logger.String("address", userServiceAddr))
 
+	// 5.2.0 就绪检查（/readyz）：用户服务 gRPC 连接 + Redis
+	readinessCfg := config.DefaultGatewayReadinessConfig()
+	middleware.SetReadinessChecks(readinessCfg.Timeout,
+		middleware.GRPCReadinessCheck("user-service", userServiceConn),
+		middleware.RedisReadinessCheck(readinessCfg.RedisRequired),
+	)
+
 	// 5.2.1 初始化设备活跃时间同步器（分片节流 map + 缓冲 map 批量消费）
 	deviceRPCClient := userpb.NewDeviceServiceClient(userServiceConn)
 	if err := middleware.InitDeviceActiveSyncer(deviceActiveCfg, func(_ context.Context, items []deviceactive.BatchItem) error {
@@ -236,6 +297,12 @@ func main() {
 	}
 	gin.SetMode(ginMode)
 	r := router.InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler)
+	// 运行期调整日志级别（与 /metrics 同端口），未配置 METRICS_ADMIN_TOKEN 时不挂载。
+	if adminToken := config.DefaultMetricsConfig().AdminToken; adminToken != "" {
+		levelHandler := gin.WrapH(logger.LevelHandler(adminToken))
+		r.GET("/admin/loglevel", levelHandler)
+		r.PUT("/admin/loglevel", levelHandler)
+	}
 	logger.Info(ctx, "路由初始化完成")
 
 	// 9. 配置服务器
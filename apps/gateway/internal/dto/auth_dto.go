@@ -8,11 +8,11 @@ import (
 
 // RegisterRequest 注册请求 DTO
 type RegisterRequest struct {
-	Email      string `json:"email" binding:"required,email"`            // 邮箱（必填）
-	Password   string `json:"password" binding:"required,min=6,max=20"`  // 密码（必填）
-	VerifyCode string `json:"verifyCode" binding:"required,len=6"`       // 验证码（必填）
-	Nickname   string `json:"nickname" binding:"omitempty,min=2,max=20"` // 昵称（可选）
-	Telephone  string `json:"telephone" binding:"omitempty,len=11"`      // 手机号（可选）
+	Email      string `json:"email" binding:"required,email"`             // 邮箱（必填）
+	Password   string `json:"password" binding:"required,min=6,max=20"`   // 密码（必填）
+	VerifyCode string `json:"verifyCode" binding:"required,min=4,max=12"` // 验证码（必填）
+	Nickname   string `json:"nickname" binding:"omitempty,min=2,max=20"`  // 昵称（可选）
+	Telephone  string `json:"telephone" binding:"omitempty,len=11"`       // 手机号（可选）
 }
 
 // RegisterResponse 注册响应 DTO
@@ -37,13 +37,14 @@ type LoginResponse struct {
 	TokenType    string    `json:"tokenType"`    // 令牌类型
 	ExpiresIn    int64     `json:"expiresIn"`    // 过期时间(秒)
 	UserInfo     *UserInfo `json:"userInfo"`     // 用户信息
+	DeviceID     string    `json:"deviceId"`     // 本次登录使用的设备ID（未携带 X-Device-ID 时为服务端派生值，客户端应持久化）
 }
 
 // LoginByCodeRequest 验证码登录请求 DTO
 type LoginByCodeRequest struct {
-	Email      string      `json:"email" binding:"required,email"`      // 邮箱
-	VerifyCode string      `json:"verifyCode" binding:"required,len=6"` // 验证码
-	DeviceInfo *DeviceInfo `json:"deviceInfo"`                          // 设备信息
+	Email      string      `json:"email" binding:"required,email"`             // 邮箱
+	VerifyCode string      `json:"verifyCode" binding:"required,min=4,max=12"` // 验证码
+	DeviceInfo *DeviceInfo `json:"deviceInfo"`                                 // 设备信息
 }
 
 // LoginByCodeResponse 验证码登录响应 DTO（同LoginResponse）
@@ -53,12 +54,13 @@ type LoginByCodeResponse struct {
 	TokenType    string    `json:"tokenType"`    // 令牌类型
 	ExpiresIn    int64     `json:"expiresIn"`    // 过期时间(秒)
 	UserInfo     *UserInfo `json:"userInfo"`     // 用户信息
+	DeviceID     string    `json:"deviceId"`     // 本次登录使用的设备ID（未携带 X-Device-ID 时为服务端派生值，客户端应持久化）
 }
 
 // SendVerifyCodeRequest 发送验证码请求 DTO
 type SendVerifyCodeRequest struct {
-	Email string `json:"email" binding:"required,email"`        // 邮箱
-	Type  int32  `json:"type" binding:"required,oneof=1 2 3 4"` // 1:注册 2:登录 3:重置密码 4:换绑邮箱
+	Email string `json:"email" binding:"required,email"`            // 邮箱
+	Type  int32  `json:"type" binding:"required,oneof=1 2 3 4 5 6"` // 1:注册 2:登录 3:重置密码 4:换绑邮箱 5:换绑手机 6:注销账号
 }
 
 // SendVerifyCodeResponse 发送验证码响应 DTO
@@ -68,9 +70,9 @@ type SendVerifyCodeResponse struct {
 
 // VerifyCodeRequest 校验验证码请求 DTO
 type VerifyCodeRequest struct {
-	Email      string `json:"email" binding:"required,email"`        // 邮箱
-	VerifyCode string `json:"verifyCode" binding:"required,len=6"`   // 验证码
-	Type       int32  `json:"type" binding:"required,oneof=1 2 3 4"` // 1:注册 2:登录 3:重置密码 4:换绑邮箱
+	Email      string `json:"email" binding:"required,email"`             // 邮箱
+	VerifyCode string `json:"verifyCode" binding:"required,min=4,max=12"` // 验证码
+	Type       int32  `json:"type" binding:"required,oneof=1 2 3 4 5 6"`  // 1:注册 2:登录 3:重置密码 4:换绑邮箱 5:换绑手机 6:注销账号
 }
 
 // VerifyCodeResponse 校验验证码响应 DTO
@@ -103,7 +105,7 @@ type LogoutResponse struct{}
 // ResetPasswordRequest 重置密码请求 DTO
 type ResetPasswordRequest struct {
 	Email       string `json:"email" binding:"required,email"`              // 邮箱
-	VerifyCode  string `json:"verifyCode" binding:"required,len=6"`         // 验证码
+	VerifyCode  string `json:"verifyCode" binding:"required,min=4,max=12"`  // 验证码
 	NewPassword string `json:"newPassword" binding:"required,min=6,max=20"` // 新密码
 }
 
@@ -104,6 +104,19 @@ func ConvertDeviceInfoFromProto(pb *userpb.DeviceInfo) *DeviceInfo {
 	}
 }
 
+// ConvertPaginationInfoToProto 将分页信息 DTO 转换为 Protobuf
+func ConvertPaginationInfoToProto(dto *PaginationInfo) *userpb.PaginationInfo {
+	if dto == nil {
+		return nil
+	}
+	return &userpb.PaginationInfo{
+		Page:       dto.Page,
+		PageSize:   dto.PageSize,
+		Total:      dto.Total,
+		TotalPages: dto.TotalPages,
+	}
+}
+
 // ConvertPaginationInfoFromProto 将 Protobuf 分页信息转换为 DTO
 func ConvertPaginationInfoFromProto(pb *userpb.PaginationInfo) *PaginationInfo {
 	if pb == nil {
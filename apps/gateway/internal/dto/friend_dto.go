@@ -2,15 +2,19 @@ package dto
 
 import (
 	userpb "ChatServer/apps/user/pb"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // ==================== 好友服务相关 DTO ====================
 
 // SearchUserRequest 搜索用户请求 DTO
 type SearchUserRequest struct {
-	Keyword  string `form:"keyword" json:"keyword" binding:"required,min=1,max=20"`     // 搜索关键字
+	Keyword  string `form:"keyword" json:"keyword" binding:"required,min=2,max=100"`    // 搜索关键字（2~100 个字符，需容纳邮箱）
 	Page     int32  `form:"page" json:"page" binding:"omitempty,min=1"`                 // 页码
 	PageSize int32  `form:"pageSize" json:"pageSize" binding:"omitempty,min=1,max=100"` // 每页大小
+	// SearchType 搜索模式：uuid（UUID 精确）/ email（邮箱精确）/ nickname（昵称模糊），为空时自动判断
+	SearchType string `form:"searchType" json:"searchType" binding:"omitempty,oneof=uuid email nickname"`
 }
 
 // SearchUserResponse 搜索用户响应 DTO
@@ -42,9 +46,10 @@ type SendFriendApplyResponse struct {
 
 // GetFriendApplyListRequest 获取好友申请列表请求 DTO
 type GetFriendApplyListRequest struct {
-	Status   int32 `json:"status" binding:"omitempty,oneof=-1 0 1 2"`  // 状态(-1:全部 0:待处理 1:已同意 2:已拒绝)
-	Page     int32 `json:"page" binding:"omitempty,min=1"`             // 页码
-	PageSize int32 `json:"pageSize" binding:"omitempty,min=1,max=100"` // 每页大小
+	Status   int32  `json:"status" binding:"omitempty,oneof=-1 0 1 2 3"`      // 状态(-1:全部 0:待处理 1:已同意 2:已拒绝 3:已过期)
+	Page     int32  `json:"page" binding:"omitempty,min=1"`                   // 页码
+	PageSize int32  `json:"pageSize" binding:"omitempty,min=1,max=100"`       // 每页大小
+	Cursor   string `form:"cursor" json:"cursor" binding:"omitempty,max=128"` // 游标（上一页的 nextCursor，非空时忽略 page）
 }
 
 // FriendApplyItem 好友申请信息 DTO
@@ -64,19 +69,22 @@ type FriendApplyItem struct {
 type GetFriendApplyListResponse struct {
 	Items      []*FriendApplyItem `json:"items"`      // 好友申请列表
 	Pagination *PaginationInfo    `json:"pagination"` // 分页信息
+	NextCursor string             `json:"nextCursor"` // 下一页游标，为空表示没有更多
 }
 
 // GetSentApplyListRequest 获取发出的申请列表请求 DTO
 type GetSentApplyListRequest struct {
-	Status   int32 `json:"status" binding:"omitempty,oneof=-1 0 1 2"`  // 状态(-1:全部 0:待处理 1:已同意 2:已拒绝)
-	Page     int32 `json:"page" binding:"omitempty,min=1"`             // 页码
-	PageSize int32 `json:"pageSize" binding:"omitempty,min=1,max=100"` // 每页大小
+	Status   int32  `json:"status" binding:"omitempty,oneof=-1 0 1 2 3"`      // 状态(-1:全部 0:待处理 1:已同意 2:已拒绝 3:已过期)
+	Page     int32  `json:"page" binding:"omitempty,min=1"`                   // 页码
+	PageSize int32  `json:"pageSize" binding:"omitempty,min=1,max=100"`       // 每页大小
+	Cursor   string `form:"cursor" json:"cursor" binding:"omitempty,max=128"` // 游标（上一页的 nextCursor，非空时忽略 page）
 }
 
 // GetSentApplyListResponse 获取发出的申请列表响应 DTO
 type GetSentApplyListResponse struct {
 	Items      []*SentApplyItem `json:"items"`      // 发出的申请列表
 	Pagination *PaginationInfo  `json:"pagination"` // 分页信息
+	NextCursor string           `json:"nextCursor"` // 下一页游标，为空表示没有更多
 }
 
 // SentApplyItem 发出的申请项 DTO
@@ -111,17 +119,21 @@ type GetUnreadApplyCountResponse struct {
 
 // MarkApplyAsReadRequest 标记申请已读请求 DTO
 type MarkApplyAsReadRequest struct {
-	ApplyIDs []int64 `json:"applyIds" binding:"required"` // 申请ID列表
+	ApplyIDs []int64 `json:"applyIds" binding:"required_unless=MarkAll true"` // 申请ID列表（markAll=true 时可省略）
+	MarkAll  bool    `json:"markAll"`                                         // 是否标记全部未读申请
 }
 
 // MarkApplyAsReadResponse 标记申请已读响应 DTO
-type MarkApplyAsReadResponse struct{}
+type MarkApplyAsReadResponse struct {
+	UnreadCount int32 `json:"unreadCount"` // 剩余未读数量
+}
 
 // GetFriendListRequest 获取好友列表请求 DTO
 type GetFriendListRequest struct {
-	GroupTag string `json:"groupTag" binding:"omitempty"`               // 标签
-	Page     int32  `json:"page" binding:"omitempty,min=1"`             // 页码
-	PageSize int32  `json:"pageSize" binding:"omitempty,min=1,max=100"` // 每页大小
+	GroupTag string `json:"groupTag" binding:"omitempty"`                     // 标签
+	Page     int32  `json:"page" binding:"omitempty,min=1"`                   // 页码
+	PageSize int32  `json:"pageSize" binding:"omitempty,min=1,max=100"`       // 每页大小
+	Cursor   string `form:"cursor" json:"cursor" binding:"omitempty,max=128"` // 游标（上一页的 nextCursor，非空时忽略 page）
 }
 
 // FriendItem 好友信息 DTO
@@ -142,6 +154,7 @@ type GetFriendListResponse struct {
 	Items      []*FriendItem   `json:"items"`      // 好友列表
 	Pagination *PaginationInfo `json:"pagination"` // 分页信息
 	Version    int64           `json:"version"`    // 版本号
+	NextCursor string          `json:"nextCursor"` // 下一页游标，为空表示没有更多
 }
 
 // SyncFriendListRequest 增量同步请求 DTO
@@ -162,6 +175,7 @@ type FriendChange struct {
 	Source     string `json:"source"`     // 来源
 	ChangeType string `json:"changeType"` // 变更类型(add/update/delete)
 	ChangedAt  int64  `json:"changedAt"`  // 变更时间（毫秒时间戳）
+	Version    int64  `json:"version"`    // 关系版本（毫秒时间戳）
 }
 
 // SyncFriendListResponse 增量同步响应 DTO
@@ -230,11 +244,12 @@ type GetRelationStatusRequest struct {
 
 // GetRelationStatusResponse 获取关系状态响应 DTO
 type GetRelationStatusResponse struct {
-	Relation    string `json:"relation"`    // 关系(none/friend/blacklist/deleted)
-	IsFriend    bool   `json:"isFriend"`    // 是否好友
-	IsBlacklist bool   `json:"isBlacklist"` // 是否拉黑
-	Remark      string `json:"remark"`      // 备注名
-	GroupTag    string `json:"groupTag"`    // 标签
+	Relation      string `json:"relation"`      // 关系(none/friend/blacklist/deleted)
+	RelationState string `json:"relationState"` // 关系状态(friend/pending_outgoing/pending_incoming/blacklisted_by_me/blacklisted_by_peer/stranger)
+	IsFriend      bool   `json:"isFriend"`      // 是否好友
+	IsBlacklist   bool   `json:"isBlacklist"`   // 是否拉黑
+	Remark        string `json:"remark"`        // 备注名
+	GroupTag      string `json:"groupTag"`      // 标签
 }
 
 // ==================== 好友服务 DTO 转换函数 ====================
@@ -245,12 +260,20 @@ func ConvertToProtoSearchUserRequest(dto *SearchUserRequest) *userpb.SearchUserR
 		return nil
 	}
 	return &userpb.SearchUserRequest{
-		Keyword:  dto.Keyword,
-		Page:     dto.Page,
-		PageSize: dto.PageSize,
+		Keyword:    dto.Keyword,
+		Page:       dto.Page,
+		PageSize:   dto.PageSize,
+		SearchType: searchUserTypes[dto.SearchType],
 	}
 }
 
+// searchUserTypes 搜索模式到 Protobuf 枚举的映射，空值（未指定）映射为 UNSPECIFIED
+var searchUserTypes = map[string]userpb.SearchUserType{
+	"uuid":     userpb.SearchUserType_SEARCH_USER_TYPE_UUID,
+	"email":    userpb.SearchUserType_SEARCH_USER_TYPE_EMAIL,
+	"nickname": userpb.SearchUserType_SEARCH_USER_TYPE_NICKNAME,
+}
+
 // ConvertToProtoSendFriendApplyRequest 将 DTO 转换为 Protobuf 请求
 func ConvertToProtoSendFriendApplyRequest(dto *SendFriendApplyRequest) *userpb.SendFriendApplyRequest {
 	if dto == nil {
@@ -282,6 +305,7 @@ func ConvertToProtoMarkApplyAsReadRequest(dto *MarkApplyAsReadRequest) *userpb.M
 	}
 	return &userpb.MarkApplyAsReadRequest{
 		ApplyIds: dto.ApplyIDs,
+		MarkAll:  dto.MarkAll,
 	}
 }
 
@@ -420,6 +444,7 @@ func ConvertGetFriendApplyListResponseFromProto(pb *userpb.GetFriendApplyListRes
 	return &GetFriendApplyListResponse{
 		Items:      items,
 		Pagination: ConvertPaginationInfoFromProto(pb.Pagination),
+		NextCursor: pb.NextCursor,
 	}
 }
 
@@ -454,6 +479,7 @@ func ConvertGetSentApplyListResponseFromProto(pb *userpb.GetSentApplyListRespons
 	return &GetSentApplyListResponse{
 		Items:      items,
 		Pagination: ConvertPaginationInfoFromProto(pb.Pagination),
+		NextCursor: pb.NextCursor,
 	}
 }
 
@@ -480,7 +506,9 @@ func ConvertMarkApplyAsReadResponseFromProto(pb *userpb.MarkApplyAsReadResponse)
 	if pb == nil {
 		return nil
 	}
-	return &MarkApplyAsReadResponse{}
+	return &MarkApplyAsReadResponse{
+		UnreadCount: pb.UnreadCount,
+	}
 }
 
 // ConvertFriendItemFromProto 将 Protobuf 好友项转换为 DTO
@@ -516,6 +544,7 @@ func ConvertGetFriendListResponseFromProto(pb *userpb.GetFriendListResponse) *Ge
 		Items:      items,
 		Pagination: ConvertPaginationInfoFromProto(pb.Pagination),
 		Version:    pb.Version,
+		NextCursor: pb.NextCursor,
 	}
 }
 
@@ -535,6 +564,7 @@ func ConvertFriendChangeFromProto(pb *userpb.FriendChange) *FriendChange {
 		Source:     pb.Source,
 		ChangeType: pb.ChangeType,
 		ChangedAt:  pb.ChangedAt,
+		Version:    pb.Version,
 	}
 }
 
@@ -556,6 +586,67 @@ func ConvertSyncFriendListResponseFromProto(pb *userpb.SyncFriendListResponse) *
 	}
 }
 
+// ToProto 将好友列表响应转换为 Protobuf（客户端 Accept: application/x-protobuf 时使用）
+func (r *GetFriendListResponse) ToProto() proto.Message {
+	if r == nil {
+		return nil
+	}
+	items := make([]*userpb.FriendItem, 0, len(r.Items))
+	for _, item := range r.Items {
+		if item == nil {
+			continue
+		}
+		items = append(items, &userpb.FriendItem{
+			Uuid:      item.UUID,
+			Nickname:  item.Nickname,
+			Avatar:    item.Avatar,
+			Gender:    item.Gender,
+			Signature: item.Signature,
+			Remark:    item.Remark,
+			GroupTag:  item.GroupTag,
+			Source:    item.Source,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	return &userpb.GetFriendListResponse{
+		Items:      items,
+		Pagination: ConvertPaginationInfoToProto(r.Pagination),
+		Version:    r.Version,
+		NextCursor: r.NextCursor,
+	}
+}
+
+// ToProto 将增量同步响应转换为 Protobuf（客户端 Accept: application/x-protobuf 时使用）
+func (r *SyncFriendListResponse) ToProto() proto.Message {
+	if r == nil {
+		return nil
+	}
+	changes := make([]*userpb.FriendChange, 0, len(r.Changes))
+	for _, change := range r.Changes {
+		if change == nil {
+			continue
+		}
+		changes = append(changes, &userpb.FriendChange{
+			Uuid:       change.UUID,
+			Nickname:   change.Nickname,
+			Avatar:     change.Avatar,
+			Gender:     change.Gender,
+			Signature:  change.Signature,
+			Remark:     change.Remark,
+			GroupTag:   change.GroupTag,
+			Source:     change.Source,
+			ChangeType: change.ChangeType,
+			ChangedAt:  change.ChangedAt,
+			Version:    change.Version,
+		})
+	}
+	return &userpb.SyncFriendListResponse{
+		Changes:       changes,
+		HasMore:       r.HasMore,
+		LatestVersion: r.LatestVersion,
+	}
+}
+
 // ConvertDeleteFriendResponseFromProto 将 Protobuf 删除好友响应转换为 DTO
 func ConvertDeleteFriendResponseFromProto(pb *userpb.DeleteFriendResponse) *DeleteFriendResponse {
 	if pb == nil {
@@ -623,10 +714,11 @@ func ConvertGetRelationStatusResponseFromProto(pb *userpb.GetRelationStatusRespo
 		return nil
 	}
 	return &GetRelationStatusResponse{
-		Relation:    pb.Relation,
-		IsFriend:    pb.IsFriend,
-		IsBlacklist: pb.IsBlacklist,
-		Remark:      pb.Remark,
-		GroupTag:    pb.GroupTag,
+		Relation:      pb.Relation,
+		RelationState: pb.RelationState,
+		IsFriend:      pb.IsFriend,
+		IsBlacklist:   pb.IsBlacklist,
+		Remark:        pb.Remark,
+		GroupTag:      pb.GroupTag,
 	}
 }
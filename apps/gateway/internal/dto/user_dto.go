@@ -11,7 +11,8 @@ type GetProfileRequest struct{}
 
 // GetProfileResponse 获取个人信息响应 DTO
 type GetProfileResponse struct {
-	UserInfo *UserInfo `json:"userInfo"` // 用户信息
+	UserInfo *UserInfo        `json:"userInfo"`          // 用户信息
+	Privacy  *PrivacySettings `json:"privacy,omitempty"` // 隐私设置（仅本人可见）
 }
 
 // GetOtherProfileRequest 获取他人信息请求 DTO
@@ -21,8 +22,9 @@ type GetOtherProfileRequest struct {
 
 // GetOtherProfileResponse 获取他人信息响应 DTO
 type GetOtherProfileResponse struct {
-	UserInfo *UserInfo `json:"userInfo"` // 用户信息
+	UserInfo *UserInfo `json:"userInfo"` // 用户信息（已按关系脱敏）
 	IsFriend bool      `json:"isFriend"` // 是否好友
+	Relation string    `json:"relation"` // 关系层级：stranger/pending/friend
 }
 
 // UpdateProfileRequest 更新基本信息请求 DTO
@@ -55,12 +57,17 @@ type ChangePasswordRequest struct {
 }
 
 // ChangePasswordResponse 修改密码响应 DTO
-type ChangePasswordResponse struct{}
+// 旧 Access Token 已失效，当前设备改用新 Token；AccessToken 为空时需重新登录
+type ChangePasswordResponse struct {
+	AccessToken string `json:"accessToken,omitempty"` // 新访问令牌
+	TokenType   string `json:"tokenType,omitempty"`   // 令牌类型
+	ExpiresIn   int64  `json:"expiresIn,omitempty"`   // 过期时间(秒)
+}
 
 // ChangeEmailRequest 换绑邮箱请求 DTO
 type ChangeEmailRequest struct {
-	NewEmail   string `json:"newEmail" binding:"required,email"`   // 新邮箱
-	VerifyCode string `json:"verifyCode" binding:"required,len=6"` // 验证码
+	NewEmail   string `json:"newEmail" binding:"required,email"`          // 新邮箱
+	VerifyCode string `json:"verifyCode" binding:"required,min=4,max=12"` // 验证码
 }
 
 // ChangeEmailResponse 换绑邮箱响应 DTO
@@ -70,8 +77,8 @@ type ChangeEmailResponse struct {
 
 // ChangeTelephoneRequest 换绑手机请求 DTO
 type ChangeTelephoneRequest struct {
-	NewTelephone string `json:"newTelephone" binding:"required,len=11"` // 新手机号
-	VerifyCode   string `json:"verifyCode" binding:"required,len=6"`    // 验证码
+	NewTelephone string `json:"newTelephone" binding:"required,len=11"`     // 新手机号
+	VerifyCode   string `json:"verifyCode" binding:"required,min=4,max=12"` // 验证码
 }
 
 // ChangeTelephoneResponse 换绑手机响应 DTO
@@ -95,13 +102,47 @@ type ParseQRCodeRequest struct {
 
 // ParseQRCodeResponse 解析二维码响应 DTO
 type ParseQRCodeResponse struct {
-	UserUUID string `json:"uuid"` // 用户UUID
+	UserUUID string          `json:"uuid"`     // 用户UUID
+	UserInfo *SimpleUserInfo `json:"userInfo"` // 二维码所属用户信息
+	Relation string          `json:"relation"` // 关系层级：stranger/pending/friend
+	IsFriend bool            `json:"isFriend"` // 是否好友
+}
+
+// SetPresenceVisibilityRequest 设置在线状态可见性请求 DTO
+// Hidden 使用指针区分“未传”与 false。
+type SetPresenceVisibilityRequest struct {
+	Hidden *bool `json:"hidden" binding:"required"` // true: 不向好友推送在线状态
+}
+
+// SetPresenceVisibilityResponse 设置在线状态可见性响应 DTO
+type SetPresenceVisibilityResponse struct {
+	Hidden bool `json:"hidden"` // 当前是否隐藏在线状态
+}
+
+// PrivacySettings 隐私设置 DTO
+type PrivacySettings struct {
+	HidePresence      bool  `json:"hidePresence"`      // 是否隐藏在线状态与最近活跃时间
+	PresenceHideScope int32 `json:"presenceHideScope"` // 隐藏范围：0=所有人 1=仅非好友
+}
+
+// UpdatePrivacyRequest 更新隐私设置请求 DTO
+// HidePresence 使用指针区分“未传”与 false。
+type UpdatePrivacyRequest struct {
+	HidePresence      *bool `json:"hidePresence" binding:"required"`       // 是否隐藏在线状态与最近活跃时间
+	PresenceHideScope int32 `json:"presenceHideScope" binding:"oneof=0 1"` // 隐藏范围：0=所有人 1=仅非好友
+}
+
+// UpdatePrivacyResponse 更新隐私设置响应 DTO
+type UpdatePrivacyResponse struct {
+	Privacy *PrivacySettings `json:"privacy"` // 更新后的隐私设置
 }
 
 // DeleteAccountRequest 注销账号请求 DTO
+// Password 与 VerifyCode 至少提供一个（二次确认）
 type DeleteAccountRequest struct {
-	Password string `json:"password" binding:"required,min=6,max=20"` // 密码
-	Reason   string `json:"reason" binding:"omitempty,max=255"`       // 注销原因
+	Password   string `json:"password" binding:"required_without=VerifyCode,omitempty,min=6,max=20"` // 密码
+	VerifyCode string `json:"verifyCode" binding:"required_without=Password,omitempty,min=4,max=12"` // 邮箱验证码（type=6）
+	Reason     string `json:"reason" binding:"omitempty,max=255"`                                    // 注销原因
 }
 
 // DeleteAccountResponse 注销账号响应 DTO
@@ -188,14 +229,38 @@ func ConvertToProtoParseQRCodeRequest(dto *ParseQRCodeRequest) *userpb.ParseQRCo
 	}
 }
 
+// ConvertToProtoSetPresenceVisibilityRequest 将 DTO 转换为 Protobuf 请求
+func ConvertToProtoSetPresenceVisibilityRequest(dto *SetPresenceVisibilityRequest) *userpb.SetPresenceVisibilityRequest {
+	if dto == nil || dto.Hidden == nil {
+		return nil
+	}
+	return &userpb.SetPresenceVisibilityRequest{
+		Hidden: *dto.Hidden,
+	}
+}
+
+// ConvertToProtoUpdatePrivacyRequest 将 DTO 转换为 Protobuf 请求
+func ConvertToProtoUpdatePrivacyRequest(dto *UpdatePrivacyRequest) *userpb.UpdatePrivacyRequest {
+	if dto == nil || dto.HidePresence == nil {
+		return nil
+	}
+	return &userpb.UpdatePrivacyRequest{
+		Privacy: &userpb.PrivacySettings{
+			HidePresence:      *dto.HidePresence,
+			PresenceHideScope: dto.PresenceHideScope,
+		},
+	}
+}
+
 // ConvertToProtoDeleteAccountRequest 将 DTO 转换为 Protobuf 请求
 func ConvertToProtoDeleteAccountRequest(dto *DeleteAccountRequest) *userpb.DeleteAccountRequest {
 	if dto == nil {
 		return nil
 	}
 	return &userpb.DeleteAccountRequest{
-		Password: dto.Password,
-		Reason:   dto.Reason,
+		Password:   dto.Password,
+		VerifyCode: dto.VerifyCode,
+		Reason:     dto.Reason,
 	}
 }
 
@@ -218,17 +283,19 @@ func ConvertGetProfileResponseFromProto(pb *userpb.GetProfileResponse) *GetProfi
 	}
 	return &GetProfileResponse{
 		UserInfo: ConvertUserInfoFromProto(pb.UserInfo),
+		Privacy:  ConvertPrivacySettingsFromProto(pb.Privacy),
 	}
 }
 
 // ConvertGetOtherProfileResponseFromProto 将 Protobuf 获取他人信息响应转换为 DTO
-func ConvertGetOtherProfileResponseFromProto(pb *userpb.GetOtherProfileResponse, isFriend bool) *GetOtherProfileResponse {
+func ConvertGetOtherProfileResponseFromProto(pb *userpb.GetOtherProfileResponse) *GetOtherProfileResponse {
 	if pb == nil {
 		return nil
 	}
 	return &GetOtherProfileResponse{
 		UserInfo: ConvertUserInfoFromProto(pb.UserInfo),
-		IsFriend: isFriend,
+		IsFriend: pb.IsFriend,
+		Relation: pb.Relation,
 	}
 }
 
@@ -257,7 +324,11 @@ func ConvertChangePasswordResponseFromProto(pb *userpb.ChangePasswordResponse) *
 	if pb == nil {
 		return nil
 	}
-	return &ChangePasswordResponse{}
+	return &ChangePasswordResponse{
+		AccessToken: pb.AccessToken,
+		TokenType:   pb.TokenType,
+		ExpiresIn:   pb.ExpiresIn,
+	}
 }
 
 // ConvertChangeEmailResponseFromProto 将 Protobuf 换绑邮箱响应转换为 DTO
@@ -298,6 +369,9 @@ func ConvertParseQRCodeResponseFromProto(pb *userpb.ParseQRCodeResponse) *ParseQ
 	}
 	return &ParseQRCodeResponse{
 		UserUUID: pb.UserUuid,
+		UserInfo: ConvertSimpleUserInfoFromProto(pb.UserInfo),
+		Relation: pb.Relation,
+		IsFriend: pb.IsFriend,
 	}
 }
 
@@ -321,3 +395,34 @@ func ConvertBatchGetProfileResponseFromProto(pb *userpb.BatchGetProfileResponse)
 		Users: ConvertSimpleUserItemsFromProto(pb.Users),
 	}
 }
+
+// ConvertSetPresenceVisibilityResponseFromProto 将 Protobuf 在线状态可见性响应转换为 DTO
+func ConvertSetPresenceVisibilityResponseFromProto(pb *userpb.SetPresenceVisibilityResponse) *SetPresenceVisibilityResponse {
+	if pb == nil {
+		return nil
+	}
+	return &SetPresenceVisibilityResponse{
+		Hidden: pb.Hidden,
+	}
+}
+
+// ConvertPrivacySettingsFromProto 将 Protobuf 隐私设置转换为 DTO
+func ConvertPrivacySettingsFromProto(pb *userpb.PrivacySettings) *PrivacySettings {
+	if pb == nil {
+		return nil
+	}
+	return &PrivacySettings{
+		HidePresence:      pb.HidePresence,
+		PresenceHideScope: pb.PresenceHideScope,
+	}
+}
+
+// ConvertUpdatePrivacyResponseFromProto 将 Protobuf 更新隐私设置响应转换为 DTO
+func ConvertUpdatePrivacyResponseFromProto(pb *userpb.UpdatePrivacyResponse) *UpdatePrivacyResponse {
+	if pb == nil {
+		return nil
+	}
+	return &UpdatePrivacyResponse{
+		Privacy: ConvertPrivacySettingsFromProto(pb.Privacy),
+	}
+}
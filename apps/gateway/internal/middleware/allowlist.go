@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+
+	"ChatServer/consts"
+	"ChatServer/pkg/result"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// defaultContentTypes 写请求默认允许的请求体类型。
+var defaultContentTypes = []string{binding.MIMEJSON}
+
+// contentTypeOverrides 需要非 JSON 请求体的路由（路由模板 -> 允许的类型），未列出的路由使用 defaultContentTypes。
+var contentTypeOverrides = map[string][]string{
+	"/api/v1/auth/user/avatar": {binding.MIMEMultipartPOSTForm},
+}
+
+// MethodNotAllowedHandler 路由存在但请求方法未注册时的处理器，配合 engine.HandleMethodNotAllowed 使用，
+// 返回 CodeMethodNotAllowed，避免未声明的方法落到 404 或被其他路由意外处理。
+func MethodNotAllowedHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result.Fail(c, nil, consts.CodeMethodNotAllowed)
+		c.Abort()
+	}
+}
+
+// ContentTypeMiddleware 写请求（POST/PUT/PATCH/DELETE）请求体类型白名单中间件。
+// 携带请求体时 Content-Type 必须在该路由的白名单内（默认仅 JSON），否则返回 CodeBodyError；
+// 无请求体的写请求（如登出）与读请求直接放行。
+func ContentTypeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasWriteBody(c.Request) {
+			c.Next()
+			return
+		}
+		allowed, ok := contentTypeOverrides[c.FullPath()]
+		if !ok {
+			allowed = defaultContentTypes
+		}
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || !slices.Contains(allowed, mediaType) {
+			result.Fail(c, nil, consts.CodeBodyError)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasWriteBody 判断是否为携带请求体的写请求。
+func hasWriteBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	return r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody
+}
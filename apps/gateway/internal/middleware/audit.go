@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"ChatServer/apps/gateway/internal/utils"
+	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/util"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// auditMaxBodyBytes 审计时最多读取的请求体字节数，认证类请求体很小，超出部分不解析。
+const auditMaxBodyBytes = 64 << 10
+
+// auditActions 需要审计的认证类接口（路由模板 -> 操作名），未列出的路由直接放行。
+var auditActions = map[string]string{
+	"/api/v1/public/user/login":          "login",
+	"/api/v1/public/user/login-by-code":  "login_by_code",
+	"/api/v1/public/user/register":       "register",
+	"/api/v1/public/user/reset-password": "reset_password",
+	"/api/v1/auth/user/change-password":  "change_password",
+	"/api/v1/auth/user/logout":           "logout",
+}
+
+// auditFields 审计日志记录的请求字段（JSON 字段名 -> 脱敏函数），其余字段不记录。
+var auditFields = map[string]func(string) string{
+	"account":     maskAccount,
+	"email":       util.MaskEmail,
+	"telephone":   util.MaskTelephone,
+	"password":    utils.MaskPassword,
+	"oldPassword": utils.MaskPassword,
+	"newPassword": utils.MaskPassword,
+	"verifyCode":  utils.MaskPassword,
+	"deviceId":    func(s string) string { return s },
+}
+
+// auditLogger 审计日志输出，为 nil 时不记录（默认关闭）。
+var auditLogger *zap.Logger
+
+// SetAuditLogger 设置审计日志 logger（见 logger.BuildAudit），需在 InitRouter 之前调用；传 nil 关闭审计。
+func SetAuditLogger(l *zap.Logger) {
+	auditLogger = l
+}
+
+// AuditMiddleware 认证类接口审计中间件，挂在认证相关路由组上。
+// 对 auditActions 中的接口记录一条审计日志：操作名、trace_id、IP、User-Agent、脱敏后的请求字段、
+// HTTP 状态与业务状态码。请求体读取后原样放回，不影响后续绑定。
+func AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		action, ok := auditActions[c.FullPath()]
+		l := auditLogger
+		if !ok || l == nil {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		input := readAuditInput(c)
+
+		c.Next()
+
+		bizCode := -1
+		if code, exists := c.Get("business_code"); exists {
+			if v, ok := code.(int); ok {
+				bizCode = v
+			}
+		}
+		clientIP := ctxmeta.ClientIPFromGin(c)
+		if clientIP == "" {
+			clientIP = c.ClientIP()
+		}
+		l.Info(action,
+			zap.String("trace_id", ctxmeta.TraceIDFromGin(c)),
+			zap.String("user_uuid", ctxmeta.UserUUIDFromGin(c)),
+			zap.String("ip", clientIP),
+			zap.String("user_agent", c.Request.UserAgent()),
+			zap.String("path", c.FullPath()),
+			zap.Any("input", input),
+			zap.Int("status", c.Writer.Status()),
+			zap.Int("code", bizCode),
+			zap.Duration("cost", time.Since(start)),
+		)
+	}
+}
+
+// readAuditInput 读取 JSON 请求体中需要审计的字段并脱敏，随后把请求体放回供 handler 绑定。
+func readAuditInput(c *gin.Context) map[string]string {
+	if c.Request.Body == nil {
+		return nil
+	}
+	original := c.Request.Body
+	head, _ := io.ReadAll(io.LimitReader(original, auditMaxBodyBytes))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), original), original}
+
+	var body map[string]any
+	if err := json.Unmarshal(head, &body); err != nil {
+		return nil
+	}
+	input := make(map[string]string)
+	for key, mask := range auditFields {
+		if value, ok := body[key].(string); ok && value != "" {
+			input[key] = mask(value)
+		}
+	}
+	return input
+}
+
+// maskAccount 账号可能是邮箱或手机号，按格式选择脱敏方式。
+func maskAccount(account string) string {
+	if strings.Contains(account, "@") {
+		return util.MaskEmail(account)
+	}
+	return util.MaskTelephone(account)
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ChatServer/pkg/result"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newAuditRouter(t *testing.T) (*gin.Engine, *observer.ObservedLogs) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	SetAuditLogger(zap.New(core))
+	t.Cleanup(func() { SetAuditLogger(nil) })
+
+	r := gin.New()
+	user := r.Group("/api/v1/public/user")
+	user.Use(AuditMiddleware())
+	user.POST("/login", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		assert.Contains(t, string(body), "secret123", "handler 仍能读取完整请求体")
+		result.Fail(c, nil, 11003)
+	})
+	user.POST("/send-verify-code", func(c *gin.Context) { result.Success(c, nil) })
+	return r, logs
+}
+
+func TestAuditMiddleware_RecordsMaskedInput(t *testing.T) {
+	r, logs := newAuditRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/public/user/login",
+		strings.NewReader(`{"account":"13812345678","password":"secret123","deviceInfo":{"platform":"ios"}}`))
+	req.Header.Set("User-Agent", "test-agent")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "login", entries[0].Message)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "test-agent", fields["user_agent"])
+	assert.EqualValues(t, 11003, fields["code"])
+	assert.EqualValues(t, http.StatusOK, fields["status"])
+
+	input, ok := fields["input"].(map[string]string)
+	require.True(t, ok)
+	assert.Equal(t, "138****5678", input["account"])
+	assert.NotContains(t, input["password"], "secret")
+	assert.NotContains(t, input, "deviceInfo", "只记录白名单字段")
+}
+
+func TestAuditMiddleware_SkipsUnlistedRoutesAndDisabled(t *testing.T) {
+	r, logs := newAuditRouter(t)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/public/user/send-verify-code", strings.NewReader(`{}`)))
+	assert.Zero(t, logs.Len())
+
+	SetAuditLogger(nil)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/public/user/login", strings.NewReader(`{"password":"secret123"}`)))
+	assert.Zero(t, logs.Len())
+}
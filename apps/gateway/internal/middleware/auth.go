@@ -1,16 +1,146 @@
 package middleware
 
 import (
+	"ChatServer/consts/redisKey"
 	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/logger"
 	"ChatServer/pkg/util"
+	"context"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
+// tokenVersionTimeout 单次读取用户令牌版本/设备令牌纪元的超时，超时后跳过对应校验，避免拖慢所有认证请求
+const tokenVersionTimeout = 100 * time.Millisecond
+
+// TokenVersionSource 查询用户当前令牌版本（由 user 服务在修改密码/重置密码/注销账号时递增）
+type TokenVersionSource interface {
+	CurrentVersion(ctx context.Context, userUUID string) (int64, error)
+}
+
+// TokenEpochSource 查询设备当前令牌纪元（由 user 服务在踢出设备/修改密码时递增），与 connect 共用 auth:epoch Key
+type TokenEpochSource interface {
+	CurrentEpoch(ctx context.Context, userUUID, deviceID string) (int64, error)
+}
+
+var (
+	tokenSourceMu      sync.RWMutex
+	tokenVersionSource TokenVersionSource
+	tokenEpochSource   TokenEpochSource
+)
+
+// SetTokenVersionSource 设置用户令牌版本查询器，传 nil 表示不做版本校验
+func SetTokenVersionSource(source TokenVersionSource) {
+	tokenSourceMu.Lock()
+	defer tokenSourceMu.Unlock()
+	tokenVersionSource = source
+}
+
+func currentTokenVersionSource() TokenVersionSource {
+	tokenSourceMu.RLock()
+	defer tokenSourceMu.RUnlock()
+	return tokenVersionSource
+}
+
+// SetTokenEpochSource 设置设备令牌纪元查询器，传 nil 表示不做纪元校验
+func SetTokenEpochSource(source TokenEpochSource) {
+	tokenSourceMu.Lock()
+	defer tokenSourceMu.Unlock()
+	tokenEpochSource = source
+}
+
+func currentTokenEpochSource() TokenEpochSource {
+	tokenSourceMu.RLock()
+	defer tokenSourceMu.RUnlock()
+	return tokenEpochSource
+}
+
+// redisTokenVersionSource 基于 auth:token_version:{user_uuid} 查询用户令牌版本
+type redisTokenVersionSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisTokenVersionSource 创建基于 Redis 的用户令牌版本查询器
+func NewRedisTokenVersionSource(redisClient *redis.Client) TokenVersionSource {
+	return &redisTokenVersionSource{redisClient: redisClient}
+}
+
+// CurrentVersion 读取用户当前令牌版本，key 不存在时为 0（从未全局失效）
+func (v *redisTokenVersionSource) CurrentVersion(ctx context.Context, userUUID string) (int64, error) {
+	version, err := v.redisClient.Get(ctx, rediskey.TokenVersionKey(userUUID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+// tokenVersionStale 判断 Token 是否因用户令牌版本递增而失效
+// 读取失败时 fail-open（仅记录日志），与 connect 鉴权降级策略一致
+func tokenVersionStale(ctx context.Context, claims *util.CustomClaims) bool {
+	source := currentTokenVersionSource()
+	if source == nil {
+		return false
+	}
+	versionCtx, cancel := context.WithTimeout(ctx, tokenVersionTimeout)
+	defer cancel()
+	currentVersion, err := source.CurrentVersion(versionCtx, claims.UserUUID)
+	if err != nil {
+		logger.Warn(ctx, "读取用户令牌版本失败，跳过版本校验",
+			logger.String("user_uuid", claims.UserUUID),
+			logger.ErrorField("error", err),
+		)
+		return false
+	}
+	return util.CheckTokenVersion(claims, currentVersion) != nil
+}
+
+// redisTokenEpochSource 基于 auth:epoch:{user_uuid}:{device_id} 查询设备令牌纪元
+type redisTokenEpochSource struct {
+	redisClient *redis.Client
+}
+
+// NewRedisTokenEpochSource 创建基于 Redis 的设备令牌纪元查询器
+func NewRedisTokenEpochSource(redisClient *redis.Client) TokenEpochSource {
+	return &redisTokenEpochSource{redisClient: redisClient}
+}
+
+// CurrentEpoch 读取设备当前纪元，key 不存在时为 0（从未被踢出）
+func (e *redisTokenEpochSource) CurrentEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	epoch, err := e.redisClient.Get(ctx, rediskey.TokenEpochKey(userUUID, deviceID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// tokenEpochStale 判断 Token 是否因设备被踢出（纪元递增）而失效
+// 读取失败时 fail-open（仅记录日志），与版本校验一致
+func tokenEpochStale(ctx context.Context, claims *util.CustomClaims) bool {
+	source := currentTokenEpochSource()
+	if source == nil {
+		return false
+	}
+	epochCtx, cancel := context.WithTimeout(ctx, tokenVersionTimeout)
+	defer cancel()
+	currentEpoch, err := source.CurrentEpoch(epochCtx, claims.UserUUID, claims.DeviceID)
+	if err != nil {
+		logger.Warn(ctx, "读取设备令牌纪元失败，跳过纪元校验",
+			logger.String("user_uuid", claims.UserUUID),
+			logger.String("device_id", claims.DeviceID),
+			logger.ErrorField("error", err),
+		)
+		return false
+	}
+	return util.CheckTokenEpoch(claims, currentEpoch) != nil
+}
+
 // JWTAuthMiddleware JWT 认证中间件
-// 从请求头中提取 Token 并验证，验证通过后将用户信息存入 Context
+// 从请求头中提取 Token 并验证（签名、有效期、用户令牌版本与设备令牌纪元），验证通过后将用户信息存入 Context
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. 从 Header 中获取 Authorization
@@ -41,6 +171,14 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 
 		// 3. 解析并验证 Token
 		claims, err := util.ParseToken(tokenString)
+		if err == nil && tokenVersionStale(c.Request.Context(), claims) {
+			// 修改密码/重置密码/注销后该用户旧 Token 全局失效，按 Token 无效处理
+			err = util.ErrTokenVersionStale
+		}
+		if err == nil && tokenEpochStale(c.Request.Context(), claims) {
+			// 设备被踢出后该设备旧 Token 失效，按 Token 无效处理
+			err = util.ErrTokenEpochStale
+		}
 		if err != nil {
 			// Token 无效或过期,属于正常业务流程,不记录日志
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeTokenVersionSource struct {
+	version int64
+	err     error
+}
+
+func (f *fakeTokenVersionSource) CurrentVersion(_ context.Context, _ string) (int64, error) {
+	return f.version, f.err
+}
+
+type fakeTokenEpochSource struct {
+	epochs map[string]int64
+	err    error
+}
+
+func (f *fakeTokenEpochSource) CurrentEpoch(_ context.Context, _, deviceID string) (int64, error) {
+	return f.epochs[deviceID], f.err
+}
+
+func serveWithJWTAuth(t *testing.T, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(JWTAuthMiddleware())
+	r.GET("/me", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"code": 0}) })
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTAuthMiddleware_TokenVersion(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Cleanup(func() { SetTokenVersionSource(nil) })
+
+	staleToken, err := util.GenerateTokenWithVersion("u1", "d1", 0, 1)
+	require.NoError(t, err)
+	currentToken, err := util.GenerateTokenWithVersion("u1", "d1", 0, 2)
+	require.NoError(t, err)
+
+	SetTokenVersionSource(&fakeTokenVersionSource{version: 2})
+	assert.Equal(t, http.StatusUnauthorized, serveWithJWTAuth(t, staleToken).Code, "版本递增前签发的 Token 被拒绝")
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, currentToken).Code)
+
+	// 版本读取失败时 fail-open，仅做 JWT 校验。
+	SetTokenVersionSource(&fakeTokenVersionSource{err: errors.New("redis down")})
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, staleToken).Code)
+
+	SetTokenVersionSource(nil)
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, staleToken).Code)
+}
+
+func TestJWTAuthMiddleware_RejectsTokenIssuedBeforePasswordChange(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Cleanup(func() { SetTokenVersionSource(nil) })
+
+	source := &fakeTokenVersionSource{version: 0}
+	SetTokenVersionSource(source)
+
+	beforeChange, err := util.GenerateTokenWithVersion("u1", "d1", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, beforeChange).Code)
+
+	// 修改密码递增 token_version，并为当前设备按新版本重新签发 Token。
+	source.version++
+	reissued, err := util.GenerateTokenWithVersion("u1", "d1", 0, source.version)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, serveWithJWTAuth(t, beforeChange).Code, "修改密码前签发的 Token 被拒绝")
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, reissued).Code)
+}
+
+func TestJWTAuthMiddleware_TokenEpoch(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Cleanup(func() { SetTokenEpochSource(nil) })
+
+	kickedToken, err := util.GenerateTokenWithEpoch("u1", "d1", 0)
+	require.NoError(t, err)
+	otherDeviceToken, err := util.GenerateTokenWithEpoch("u1", "d2", 0)
+	require.NoError(t, err)
+
+	// 踢出 d1：auth:epoch:u1:d1 递增后，d1 此前签发的 Token 被拒绝，其他设备不受影响。
+	SetTokenEpochSource(&fakeTokenEpochSource{epochs: map[string]int64{"d1": 1}})
+	assert.Equal(t, http.StatusUnauthorized, serveWithJWTAuth(t, kickedToken).Code)
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, otherDeviceToken).Code)
+
+	reissued, err := util.GenerateTokenWithEpoch("u1", "d1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, reissued).Code)
+
+	// 纪元读取失败时 fail-open。
+	SetTokenEpochSource(&fakeTokenEpochSource{err: errors.New("redis down")})
+	assert.Equal(t, http.StatusOK, serveWithJWTAuth(t, kickedToken).Code)
+}
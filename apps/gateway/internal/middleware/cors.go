@@ -22,6 +22,7 @@ func CorsMiddleware() gin.HandlerFunc {
 		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Device-ID, X-Requested-With")
 		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", HeaderServiceMode) // 允许前端读取降级模式标识
 		c.Header("Vary", "Origin") // 重要：告诉浏览器 Origin 值会变化
 
 		// 处理 OPTIONS 预检请求
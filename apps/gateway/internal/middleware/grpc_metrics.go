@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// GRPCMetricsInterceptor 创建一个 gRPC 客户端一元拦截器，自动记录每次调用的耗时与结果
+// service/method 由 FullMethod（如 /user.AuthService/Login）解析，无需在各业务方法中手动调用 RecordGRPCRequest
+func GRPCMetricsInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		service, name := splitGRPCMethod(method)
+		RecordGRPCRequest(service, name, time.Since(start).Seconds(), err)
+		return err
+	}
+}
+
+// splitGRPCMethod 将 /package.Service/Method 拆分为 (package.Service, Method)
+// 格式不合法时 service 记为 unknown，method 保留原始字符串
+func splitGRPCMethod(fullMethod string) (string, string) {
+	trimmed := strings.TrimPrefix(fullMethod, "/")
+	if idx := strings.LastIndex(trimmed, "/"); idx > 0 && idx < len(trimmed)-1 {
+		return trimmed[:idx], trimmed[idx+1:]
+	}
+	return "unknown", fullMethod
+}
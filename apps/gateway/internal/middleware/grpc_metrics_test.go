@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestSplitGRPCMethod(t *testing.T) {
+	service, method := splitGRPCMethod("/user.AuthService/Login")
+	assert.Equal(t, "user.AuthService", service)
+	assert.Equal(t, "Login", method)
+
+	service, method = splitGRPCMethod("bad")
+	assert.Equal(t, "unknown", service)
+	assert.Equal(t, "bad", method)
+}
+
+func TestGRPCMetricsInterceptor_RecordsStatus(t *testing.T) {
+	interceptor := GRPCMetricsInterceptor()
+	okCounter := gRPCRequestsTotal.WithLabelValues("user.FriendService", "GetFriendList", "ok")
+	errCounter := gRPCRequestsTotal.WithLabelValues("user.FriendService", "GetFriendList", "error")
+	okBefore, errBefore := testutil.ToFloat64(okCounter), testutil.ToFloat64(errCounter)
+
+	okInvoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+	errInvoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return errors.New("boom")
+	}
+
+	assert.NoError(t, interceptor(context.Background(), "/user.FriendService/GetFriendList", nil, nil, nil, okInvoker))
+	assert.Error(t, interceptor(context.Background(), "/user.FriendService/GetFriendList", nil, nil, nil, errInvoker))
+
+	assert.Equal(t, okBefore+1, testutil.ToFloat64(okCounter))
+	assert.Equal(t, errBefore+1, testutil.ToFloat64(errCounter))
+}
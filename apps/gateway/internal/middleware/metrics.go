@@ -84,7 +84,7 @@ var httpRequestsInProgress = promauto.NewGaugeVec(
 
 // gRPCRequestsTotal gRPC 请求计数器
 // 标签：
-//   - service: 服务名 (user.UserService)
+//   - grpc_service: 下游服务名 (user.UserService)；不使用 service，避免与公共标签 service=gateway 冲突
 //   - method: 方法名 (Login)
 //   - status: 状态 (ok, error)
 var gRPCRequestsTotal = promauto.NewCounterVec(
@@ -92,7 +92,7 @@ var gRPCRequestsTotal = promauto.NewCounterVec(
 		Name: "gateway_grpc_requests_total",
 		Help: "Total number of gRPC requests",
 	},
-	[]string{"service", "method", "status"},
+	[]string{"grpc_service", "method", "status"},
 )
 
 // gRPCRequestDuration gRPC 请求耗时
@@ -102,7 +102,20 @@ var gRPCRequestDuration = promauto.NewHistogramVec(
 		Help:    "gRPC request latency distributions in seconds",
 		Buckets: prometheus.DefBuckets,
 	},
-	[]string{"service", "method"},
+	[]string{"grpc_service", "method"},
+)
+
+// gRPCBreakerRejectedTotal 熔断器快速失败计数器（未发起 RPC，不计入 gateway_grpc_requests_total）
+// 标签：
+//   - breaker: 熔断器名称 (user-service)
+//   - method: 方法名 (Login)
+//   - reason: 拒绝原因 (open: 熔断打开, too_many_requests: 半开探测名额已满)
+var gRPCBreakerRejectedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gateway_grpc_breaker_rejected_total",
+		Help: "Total number of gRPC requests rejected by the circuit breaker",
+	},
+	[]string{"breaker", "method", "reason"},
 )
 
 // PrometheusMiddleware Prometheus 监控中间件
@@ -175,6 +188,12 @@ func RecordGRPCRequest(service, method string, duration float64, err error) {
 	gRPCRequestDuration.WithLabelValues(service, method).Observe(duration)
 }
 
+// RecordGRPCBreakerRejected 记录熔断器快速失败
+// reason: open（熔断打开）或 too_many_requests（半开探测名额已满）
+func RecordGRPCBreakerRejected(breaker, method, reason string) {
+	gRPCBreakerRejectedTotal.WithLabelValues(breaker, method, reason).Inc()
+}
+
 // GetHTTPRequestsTotal 获取 HTTP 请求总数指标（可用于监控面板）
 func GetHTTPRequestsTotal() *prometheus.CounterVec {
 	return httpRequestsTotal
@@ -199,3 +218,8 @@ func GetGRPCRequestsTotal() *prometheus.CounterVec {
 func GetGRPCRequestDuration() *prometheus.HistogramVec {
 	return gRPCRequestDuration
 }
+
+// GetGRPCBreakerRejectedTotal 获取熔断器快速失败计数指标
+func GetGRPCBreakerRejectedTotal() *prometheus.CounterVec {
+	return gRPCBreakerRejectedTotal
+}
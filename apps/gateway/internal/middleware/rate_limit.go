@@ -86,7 +86,7 @@ return allowed
 
 // RedisRateLimiter 基于 Redis 的 IP 级别限流器
 type RedisRateLimiter struct {
-	redisClient *redis.Client
+	redisClient redis.Scripter
 	rate        float64 // 每秒产生的令牌数
 	burst       int     // 令牌桶容量
 	mu          *sync.RWMutex
@@ -110,6 +110,10 @@ func NewRedisRateLimiter(rate float64, burst int) *RedisRateLimiter {
 func (r *RedisRateLimiter) RedisSetClient(redisClient *redis.Client) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if redisClient == nil {
+		r.redisClient = nil
+		return
+	}
 	r.redisClient = redisClient
 }
 
@@ -336,8 +340,13 @@ func IPRateLimitMiddleware(blacklistKey string, rate float64, burst int) gin.Han
 
 // ==================== 用户限流中间件 ====================
 
+// UserRateLimiterGlobal 全局用户限流器名称（UserRateLimitMiddleware 使用）
+const UserRateLimiterGlobal = "global"
+
 // UserRateLimitMiddleware 基于用户 UUID 的限流中间件
 // 用于对已认证用户进行限流，需要在 JWT 认证中间件之后使用
+// 令牌桶状态完全保存在 Redis（gateway:rate:limit:user:global:{user_uuid}），多副本部署时共享同一配额；
+// 使用独立的限流器实例，rate/burst 不受全局 IP 限流参数影响。
 // 参数：
 //   - rate: 每秒产生的令牌数
 //   - burst: 令牌桶容量
@@ -348,79 +357,28 @@ func IPRateLimitMiddleware(blacklistKey string, rate float64, burst int) gin.Han
 //	api.Use(JWTAuthMiddleware())
 //	api.Use(UserRateLimitMiddleware(100, 200))
 func UserRateLimitMiddleware(rate float64, burst int) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := c
-
-		// 1. 获取用户 UUID
-		userUUID, exists := GetUserUUID(c)
-		if !exists || userUUID == "" {
-			// 无法获取用户 UUID，可能是未认证请求，放行
-			// 注意：这个中间件应该在 JWTAuthMiddleware 之后使用
-			logger.Warn(ctx, "无法获取用户 UUID，跳过用户限流检查",
-				logger.String("path", c.Request.URL.Path),
-			)
-			c.Next()
-			return
-		}
-
-		// 2. 检查全局限流器是否初始化
-		if globalRedisLimiter == nil {
-			// 限流器未初始化，放行请求
-			logger.Warn(ctx, "Redis 限流器未初始化，跳过用户限流检查",
-				logger.String("user_uuid", userUUID),
-				logger.String("path", c.Request.URL.Path),
-			)
-			c.Next()
-			return
-		}
-
-		// 3. 构造用户限流 key: gateway:rate:limit:user:{user_uuid}
-		rateLimitKey := rediskey.GatewayUserRateLimitKey(userUUID)
-
-		// 4. 检查是否允许通过
-		allowed, err := globalRedisLimiter.Allow(ctx, rateLimitKey)
-		if err != nil {
-			// Redis 错误，已经降级放行了（返回 true）
-			logger.Warn(ctx, "Redis 用户限流检查异常，降级放行",
-				logger.String("user_uuid", userUUID),
-				logger.String("path", c.Request.URL.Path),
-				logger.ErrorField("error", err),
-			)
-		} else if !allowed {
-			// 用户被限流
-			logger.Warn(ctx, "用户请求被限流",
-				logger.String("user_uuid", userUUID),
-				logger.String("path", c.Request.URL.Path),
-				logger.String("method", c.Request.Method),
-			)
-
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"code":    10005,
-				"message": "请求过于频繁，请稍后再试",
-			})
-			c.Abort()
-			return
-		}
-
-		// 5. 通过检查，继续处理请求
-		c.Next()
-	}
+	return UserRateLimitMiddlewareWithConfig(UserRateLimiterGlobal, rate, burst)
 }
 
 // UserRateLimitMiddlewareWithConfig 可配置的用户限流中间件
 // 允许为不同的路由组设置不同的限流参数
 // 参数：
+//   - name: 限流器名称，写入 Redis key（gateway:rate:limit:user:{name}:{user_uuid}），
+//     不同 rate/burst 的限流器必须使用不同名称，否则会共用并互相覆盖同一个令牌桶
 //   - rate: 每秒产生的令牌数
 //   - burst: 令牌桶容量
 //
 // 使用示例：
 //
 //	// 为敏感接口设置更严格的限流
-//	api.POST("/sensitive", UserRateLimitMiddlewareWithConfig(10, 20), handler)
-func UserRateLimitMiddlewareWithConfig(rate float64, burst int) gin.HandlerFunc {
+//	api.POST("/sensitive", UserRateLimitMiddlewareWithConfig("sensitive", 10, 20), handler)
+func UserRateLimitMiddlewareWithConfig(name string, rate float64, burst int) gin.HandlerFunc {
 	// 创建独立的限流器实例
-	limiter := NewRedisRateLimiter(rate, burst)
+	return userRateLimitHandler(name, NewRedisRateLimiter(rate, burst))
+}
 
+// userRateLimitHandler 使用指定限流器构造用户限流中间件
+func userRateLimitHandler(name string, limiter *RedisRateLimiter) gin.HandlerFunc {
 	// 使用 sync.Once 懒加载 Redis Client（只执行一次，避免每次请求都加锁）
 	var once sync.Once
 
@@ -445,14 +403,15 @@ func UserRateLimitMiddlewareWithConfig(rate float64, burst int) gin.HandlerFunc
 			return
 		}
 
-		// 2. 构造用户限流 key: gateway:rate:limit:user:{user_uuid}
-		rateLimitKey := rediskey.GatewayUserRateLimitKey(userUUID)
+		// 2. 构造用户限流 key: gateway:rate:limit:user:{name}:{user_uuid}
+		rateLimitKey := rediskey.GatewayUserRateLimitKey(name, userUUID)
 
 		// 3. 检查是否允许通过
 		allowed, err := limiter.Allow(ctx, rateLimitKey)
 		if err != nil {
 			// Redis 错误，已经降级放行了（返回 true）
 			logger.Warn(ctx, "Redis 用户限流检查异常，降级放行",
+				logger.String("limiter", name),
 				logger.String("user_uuid", userUUID),
 				logger.String("path", c.Request.URL.Path),
 				logger.ErrorField("error", err),
@@ -460,6 +419,7 @@ func UserRateLimitMiddlewareWithConfig(rate float64, burst int) gin.HandlerFunc
 		} else if !allowed {
 			// 用户被限流
 			logger.Warn(ctx, "用户请求被限流",
+				logger.String("limiter", name),
 				logger.String("user_uuid", userUUID),
 				logger.String("path", c.Request.URL.Path),
 				logger.String("method", c.Request.Method),
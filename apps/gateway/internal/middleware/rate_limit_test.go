@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeTokenBucketRedis 内存版 Redis，按 luaTokenBucketRedis 的语义执行令牌桶脚本，
+// 供多个网关实例的限流器共享，模拟多副本部署。
+type fakeTokenBucketRedis struct {
+	redis.Scripter
+
+	mu      sync.Mutex
+	buckets map[string]*fakeBucket
+}
+
+type fakeBucket struct {
+	tokens   float64
+	lastTime int64
+}
+
+func newFakeTokenBucketRedis() *fakeTokenBucketRedis {
+	return &fakeTokenBucketRedis{buckets: make(map[string]*fakeBucket)}
+}
+
+func (f *fakeTokenBucketRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	cmd := redis.NewCmd(ctx)
+	if script != luaTokenBucketRedis || len(keys) != 1 || len(args) != 4 {
+		cmd.SetErr(fmt.Errorf("unexpected script call"))
+		return cmd
+	}
+	now := args[0].(int64)
+	capacity := float64(args[1].(int))
+	rate := args[2].(float64)
+	requested := float64(args[3].(int))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket, ok := f.buckets[keys[0]]
+	if !ok {
+		bucket = &fakeBucket{tokens: capacity, lastTime: now}
+		f.buckets[keys[0]] = bucket
+	}
+	if newTokens := math.Floor(float64(max(0, now-bucket.lastTime)) * rate / 1000); newTokens > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+newTokens)
+		bucket.lastTime = now
+	}
+	var allowed int64
+	if bucket.tokens >= requested {
+		bucket.tokens -= requested
+		allowed = 1
+	}
+	cmd.SetVal(allowed)
+	return cmd
+}
+
+func (f *fakeTokenBucketRedis) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.buckets))
+	for key := range f.buckets {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// newRateLimitTestInstance 构造一个网关实例：全局用户限流 100/200，修改密码额外限流 2/5。
+func newRateLimitTestInstance(shared redis.Scripter) *gin.Engine {
+	newLimiter := func(rate float64, burst int) *RedisRateLimiter {
+		limiter := NewRedisRateLimiter(rate, burst)
+		limiter.redisClient = shared
+		return limiter
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctxmeta.SetUserUUID(c, c.GetHeader("X-User"))
+		c.Next()
+	})
+	r.Use(userRateLimitHandler(UserRateLimiterGlobal, newLimiter(100, 200)))
+	r.GET("/profile", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/change-password",
+		userRateLimitHandler("change_password", newLimiter(2, 5)),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestUserRateLimit_SharedAcrossInstances(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.ReplaceGlobal(zap.NewNop())
+	shared := newFakeTokenBucketRedis()
+	instances := []*gin.Engine{
+		newRateLimitTestInstance(shared),
+		newRateLimitTestInstance(shared),
+		newRateLimitTestInstance(shared),
+	}
+	do := func(instance *gin.Engine, method, path, user string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		instance.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 请求轮询分发到各实例：敏感接口配额由所有实例共享，总共只放行 burst=5 次。
+	allowed := 0
+	for i := 0; i < 12; i++ {
+		if do(instances[i%len(instances)], http.MethodPost, "/change-password", "u1") == http.StatusOK {
+			allowed++
+		}
+	}
+	assert.Equal(t, 5, allowed)
+
+	// 敏感接口耗尽配额不影响全局限流器：两者使用不同的令牌桶。
+	for _, instance := range instances {
+		require.Equal(t, http.StatusOK, do(instance, http.MethodGet, "/profile", "u1"))
+	}
+	// 其他用户不受影响。
+	assert.Equal(t, http.StatusOK, do(instances[0], http.MethodPost, "/change-password", "u2"))
+
+	assert.ElementsMatch(t, []string{
+		"gateway:rate:limit:user:global:u1",
+		"gateway:rate:limit:user:change_password:u1",
+		"gateway:rate:limit:user:global:u2",
+		"gateway:rate:limit:user:change_password:u2",
+	}, shared.keys())
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/connectivity"
+)
+
+// defaultReadinessTimeout 就绪检查默认超时（所有依赖并发检查，共用该超时）。
+const defaultReadinessTimeout = time.Second
+
+// errRedisDegraded Redis 探活失败（见 StartRedisHealthProbe）。
+var errRedisDegraded = errors.New("redis unavailable")
+
+// GRPCConnState 抽象 gRPC 客户端连接状态（*grpc.ClientConn 实现），便于测试注入。
+type GRPCConnState interface {
+	GetState() connectivity.State
+	Connect()
+	WaitForStateChange(ctx context.Context, sourceState connectivity.State) bool
+}
+
+// ReadinessCheck 单个依赖的就绪检查。
+type ReadinessCheck struct {
+	// Name 依赖名称，出现在 /readyz 响应中。
+	Name string
+	// Required 为 true 时该依赖不可达则整体返回 503；为 false 时只在响应中报告状态。
+	Required bool
+	// Check 在 ctx 超时前返回 nil 表示依赖可达。
+	Check func(ctx context.Context) error
+}
+
+// ReadinessStatus /readyz 响应中单个依赖的状态。
+type ReadinessStatus struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // up / down
+	Required bool   `json:"required"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReadinessResponse /readyz 响应体。
+type ReadinessResponse struct {
+	Status       string            `json:"status"` // ready / not_ready
+	Dependencies []ReadinessStatus `json:"dependencies"`
+}
+
+var (
+	readinessMu      sync.RWMutex
+	readinessTimeout = defaultReadinessTimeout
+	readinessChecks  []ReadinessCheck
+)
+
+// SetReadinessChecks 设置 /readyz 检查的依赖与超时，timeout<=0 时使用默认 1s。
+func SetReadinessChecks(timeout time.Duration, checks ...ReadinessCheck) {
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	readinessMu.Lock()
+	defer readinessMu.Unlock()
+	readinessTimeout = timeout
+	readinessChecks = checks
+}
+
+// GRPCReadinessCheck 基于 gRPC 连接状态的检查：Ready 即可达；
+// Idle 时主动触发连接，Connecting/TransientFailure 时在超时内等待变为 Ready。
+func GRPCReadinessCheck(name string, conn GRPCConnState) ReadinessCheck {
+	return ReadinessCheck{
+		Name:     name,
+		Required: true,
+		Check: func(ctx context.Context) error {
+			for {
+				state := conn.GetState()
+				switch state {
+				case connectivity.Ready:
+					return nil
+				case connectivity.Shutdown:
+					return fmt.Errorf("grpc connection %s", state)
+				case connectivity.Idle:
+					conn.Connect()
+				}
+				if !conn.WaitForStateChange(ctx, state) {
+					return fmt.Errorf("grpc connection %s", state)
+				}
+			}
+		},
+	}
+}
+
+// RedisReadinessCheck 基于降级探测结果的 Redis 检查，不额外发起 PING。
+// 网关在 Redis 不可用时可降级运行，required 决定 Redis 故障时是否摘除流量。
+func RedisReadinessCheck(required bool) ReadinessCheck {
+	return ReadinessCheck{
+		Name:     "redis",
+		Required: required,
+		Check: func(context.Context) error {
+			if IsDegraded() {
+				return errRedisDegraded
+			}
+			return nil
+		},
+	}
+}
+
+// ReadinessHandler 返回 /readyz 处理器：并发检查各依赖，
+// 全部必需依赖可达时返回 200，否则返回 503；响应体列出每个依赖的状态。
+func ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		readinessMu.RLock()
+		timeout, checks := readinessTimeout, readinessChecks
+		readinessMu.RUnlock()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		resp := ReadinessResponse{Status: "ready", Dependencies: make([]ReadinessStatus, len(checks))}
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status := ReadinessStatus{Name: check.Name, Status: "up", Required: check.Required}
+				if err := check.Check(ctx); err != nil {
+					status.Status = "down"
+					status.Error = err.Error()
+				}
+				resp.Dependencies[i] = status
+			}()
+		}
+		wg.Wait()
+
+		httpStatus := http.StatusOK
+		for _, dep := range resp.Dependencies {
+			if dep.Required && dep.Status != "up" {
+				resp.Status = "not_ready"
+				httpStatus = http.StatusServiceUnavailable
+			}
+		}
+		c.JSON(httpStatus, resp)
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/connectivity"
+)
+
+// fakeConn 模拟 gRPC 连接状态：Connect 后切换到 afterConnect（未设置时保持不变）。
+type fakeConn struct {
+	mu           sync.Mutex
+	state        connectivity.State
+	afterConnect connectivity.State
+	connected    bool
+	changed      chan struct{}
+}
+
+func newFakeConn(state connectivity.State) *fakeConn {
+	return &fakeConn{state: state, afterConnect: state, changed: make(chan struct{}, 1)}
+}
+
+func (f *fakeConn) GetState() connectivity.State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func (f *fakeConn) Connect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = true
+	if f.afterConnect != f.state {
+		f.state = f.afterConnect
+		f.changed <- struct{}{}
+	}
+}
+
+func (f *fakeConn) WaitForStateChange(ctx context.Context, _ connectivity.State) bool {
+	select {
+	case <-f.changed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func serveReadiness(t *testing.T, timeout time.Duration, checks ...ReadinessCheck) (*httptest.ResponseRecorder, ReadinessResponse) {
+	t.Helper()
+	SetReadinessChecks(timeout, checks...)
+	t.Cleanup(func() { SetReadinessChecks(0) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/readyz", ReadinessHandler())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var resp ReadinessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w, resp
+}
+
+func TestReadinessHandler_GRPCStates(t *testing.T) {
+	t.Run("ready", func(t *testing.T) {
+		w, resp := serveReadiness(t, time.Second, GRPCReadinessCheck("user-service", newFakeConn(connectivity.Ready)))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ready", resp.Status)
+		assert.Equal(t, []ReadinessStatus{{Name: "user-service", Status: "up", Required: true}}, resp.Dependencies)
+	})
+
+	t.Run("idle_connects", func(t *testing.T) {
+		conn := newFakeConn(connectivity.Idle)
+		conn.afterConnect = connectivity.Ready
+		w, resp := serveReadiness(t, time.Second, GRPCReadinessCheck("user-service", conn))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ready", resp.Status)
+		assert.True(t, conn.connected, "idle connection should be kicked")
+	})
+
+	t.Run("transient_failure_times_out", func(t *testing.T) {
+		start := time.Now()
+		w, resp := serveReadiness(t, 50*time.Millisecond, GRPCReadinessCheck("user-service", newFakeConn(connectivity.TransientFailure)))
+
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "not_ready", resp.Status)
+		require.Len(t, resp.Dependencies, 1)
+		assert.Equal(t, "down", resp.Dependencies[0].Status)
+		assert.Contains(t, resp.Dependencies[0].Error, "TRANSIENT_FAILURE")
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		w, resp := serveReadiness(t, time.Second, GRPCReadinessCheck("user-service", newFakeConn(connectivity.Shutdown)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "down", resp.Dependencies[0].Status)
+	})
+}
+
+func TestReadinessHandler_Redis(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	SetDegraded(true)
+	t.Cleanup(func() { SetDegraded(false) })
+	user := GRPCReadinessCheck("user-service", newFakeConn(connectivity.Ready))
+
+	// 默认 Redis 非必需：降级时仍就绪，但响应中报告 Redis 不可用
+	w, resp := serveReadiness(t, time.Second, user, RedisReadinessCheck(false))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, ReadinessStatus{Name: "redis", Status: "down", Required: false, Error: "redis unavailable"}, resp.Dependencies[1])
+
+	w, resp = serveReadiness(t, time.Second, user, RedisReadinessCheck(true))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "not_ready", resp.Status)
+
+	SetDegraded(false)
+	w, _ = serveReadiness(t, time.Second, user, RedisReadinessCheck(true))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"ChatServer/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// HeaderServiceMode 服务运行模式响应头，降级时取值 ServiceModeDegraded。
+	HeaderServiceMode = "X-Service-Mode"
+	// ServiceModeDegraded Redis 不可用、仅依赖 MySQL 运行时的模式标识。
+	ServiceModeDegraded = "degraded"
+
+	// redisProbeTimeout 单次 Redis 探活超时。
+	redisProbeTimeout = time.Second
+)
+
+// degradedMode 仪表：当前是否处于降级模式（1=Redis 不可用，在线状态/限流等功能降级）
+var degradedMode = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "gateway_degraded_mode",
+		Help: "Whether the gateway is running in degraded mode (1 = Redis unavailable)",
+	},
+)
+
+var (
+	degraded            atomic.Bool
+	serviceModeHeaderOn atomic.Bool
+)
+
+func init() {
+	serviceModeHeaderOn.Store(true)
+}
+
+// SetServiceModeHeaderEnabled 设置降级时是否返回 X-Service-Mode 响应头（指标不受影响）。
+func SetServiceModeHeaderEnabled(enabled bool) {
+	serviceModeHeaderOn.Store(enabled)
+}
+
+// SetDegraded 设置降级状态，同步更新 gateway_degraded_mode 指标。
+func SetDegraded(on bool) {
+	if degraded.Swap(on) == on {
+		return
+	}
+	if on {
+		degradedMode.Set(1)
+		logger.Warn(context.Background(), "Redis 不可用，进入降级模式")
+		return
+	}
+	degradedMode.Set(0)
+	logger.Info(context.Background(), "Redis 已恢复，退出降级模式")
+}
+
+// IsDegraded 返回当前是否处于降级模式。
+func IsDegraded() bool {
+	return degraded.Load()
+}
+
+// ServiceModeMiddleware 降级时为所有响应添加 X-Service-Mode: degraded。
+// 在 c.Next() 之前写入响应头，保证 handler 输出 body 前已生效；正常模式不添加该头。
+func ServiceModeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if serviceModeHeaderOn.Load() && IsDegraded() {
+			c.Header(HeaderServiceMode, ServiceModeDegraded)
+		}
+		c.Next()
+	}
+}
+
+// StartRedisHealthProbe 周期性 PING Redis 并更新降级状态，ctx 取消后退出。
+// redisClient 为 nil（启动时 Redis 初始化失败）时直接进入降级模式且不再探测。
+func StartRedisHealthProbe(ctx context.Context, redisClient *redis.Client, interval time.Duration) {
+	if redisClient == nil {
+		SetDegraded(true)
+		return
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	probe := func() {
+		probeCtx, cancel := context.WithTimeout(ctx, redisProbeTimeout)
+		defer cancel()
+		err := redisClient.Ping(probeCtx).Err()
+		if ctx.Err() != nil {
+			return // 停机中，不据此切换状态
+		}
+		SetDegraded(err != nil)
+	}
+
+	probe()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				probe()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
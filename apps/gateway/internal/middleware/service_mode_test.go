@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ChatServer/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func serveWithServiceMode(t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ServiceModeMiddleware())
+	r.GET("/ping", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"code": 0}) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	return w
+}
+
+func TestServiceModeMiddleware_SetsHeaderWhenDegraded(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Cleanup(func() { SetDegraded(false) })
+
+	SetDegraded(true)
+	w := serveWithServiceMode(t)
+	assert.Equal(t, ServiceModeDegraded, w.Header().Get(HeaderServiceMode))
+	assert.Equal(t, float64(1), testutil.ToFloat64(degradedMode))
+
+	SetDegraded(false)
+	w = serveWithServiceMode(t)
+	assert.Empty(t, w.Header().Get(HeaderServiceMode))
+	assert.Equal(t, float64(0), testutil.ToFloat64(degradedMode))
+}
+
+func TestServiceModeMiddleware_HeaderDisabled(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Cleanup(func() {
+		SetDegraded(false)
+		SetServiceModeHeaderEnabled(true)
+	})
+
+	SetDegraded(true)
+	SetServiceModeHeaderEnabled(false)
+	w := serveWithServiceMode(t)
+	assert.Empty(t, w.Header().Get(HeaderServiceMode))
+	assert.Equal(t, float64(1), testutil.ToFloat64(degradedMode), "metric is kept when the header is disabled")
+}
+
+func TestStartRedisHealthProbe_NilClientDegrades(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	t.Cleanup(func() { SetDegraded(false) })
+
+	StartRedisHealthProbe(context.Background(), nil, 0)
+	assert.True(t, IsDegraded())
+}
@@ -3,10 +3,10 @@ package pb
 import (
 	userpb "ChatServer/apps/user/pb"
 	"context"
-	"time"
+	"errors"
 
 	"ChatServer/apps/gateway/internal/middleware"
-	"ChatServer/pkg/logger"
+	"ChatServer/pkg/grpcx"
 
 	"github.com/sony/gobreaker"
 	"google.golang.org/grpc"
@@ -178,6 +178,20 @@ func (c *userServiceClientImpl) BatchGetProfile(ctx context.Context, req *userpb
 	})
 }
 
+// SetPresenceVisibility 设置在线状态可见性
+func (c *userServiceClientImpl) SetPresenceVisibility(ctx context.Context, req *userpb.SetPresenceVisibilityRequest) (*userpb.SetPresenceVisibilityResponse, error) {
+	return ExecuteWithBreaker(c.breaker, "SetPresenceVisibility", func() (*userpb.SetPresenceVisibilityResponse, error) {
+		return c.userClient.SetPresenceVisibility(ctx, req)
+	})
+}
+
+// UpdatePrivacy 更新隐私设置
+func (c *userServiceClientImpl) UpdatePrivacy(ctx context.Context, req *userpb.UpdatePrivacyRequest) (*userpb.UpdatePrivacyResponse, error) {
+	return ExecuteWithBreaker(c.breaker, "UpdatePrivacy", func() (*userpb.UpdatePrivacyResponse, error) {
+		return c.userClient.UpdatePrivacy(ctx, req)
+	})
+}
+
 // ==================== 好友服务方法实现 ====================
 
 // SearchUser 搜索用户
@@ -356,9 +370,8 @@ func (c *userServiceClientImpl) BatchGetOnlineStatus(ctx context.Context, req *u
 // CreateConnection 通用的 gRPC 连接创建函数
 // addr: 服务地址，格式为 "host:port"
 // serviceName: 服务名称（用于重试策略配置）
-// breaker: 熔断器实例
 // 返回: gRPC 连接和错误
-func CreateConnection(addr string, serviceName string, breaker *gobreaker.CircuitBreaker) (*grpc.ClientConn, error) {
+func CreateConnection(addr string, serviceName string) (*grpc.ClientConn, error) {
 	conn, err := grpc.NewClient(
 		addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -366,11 +379,12 @@ func CreateConnection(addr string, serviceName string, breaker *gobreaker.Circui
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(4*1024*1024), // 4MB接收大小
 		),
-		// 注入熔断拦截器
+		// 熔断由 ExecuteWithBreaker 按调用执行（覆盖 gRPC 内置重试），此处不再重复挂载熔断拦截器，
+		// 避免同一次调用被熔断器统计两次。
 		grpc.WithChainUnaryInterceptor(
 			middleware.GRPCMetadataInterceptor(), // 透传 trace/user/device/ip
 			middleware.GRPCLoggerInterceptor(),// 记录请求日志
-			middleware.CircuitBreakerInterceptor(breaker),// 熔断器拦截器
+			middleware.GRPCMetricsInterceptor(), // 按 RPC 记录耗时/错误指标
 		),
 	)
 	if err != nil {
@@ -382,26 +396,10 @@ func CreateConnection(addr string, serviceName string, breaker *gobreaker.Circui
 
 // CreateCircuitBreaker 创建熔断器实例
 // name: 熔断器名称
-// 返回: 熔断器实例
-func CreateCircuitBreaker(name string) *gobreaker.CircuitBreaker {
-	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
-		Name:        name,
-		MaxRequests: 3,                // 半开状态下最多允许 3 个请求尝试
-		Interval:    15 * time.Second, // 清除计数的时间间隔
-		Timeout:     45 * time.Second, // 熔断器开启后多久尝试进入半开状态
-		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			// 失败率超过 50% 且连续失败次数超过 5 次时触发熔断
-			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-			return counts.Requests >= 5 && failureRatio >= 0.5
-		},
-		OnStateChange: func(name string, from, to gobreaker.State) {
-			logger.Info(context.Background(), "熔断器状态变化",
-				logger.String("name", name),
-				logger.String("from", from.String()),
-				logger.String("to", to.String()),
-			)
-		},
-	})
+// cfg: 熔断阈值（半开探测数、统计周期、打开时长、最小请求数、失败率）
+// 返回: 熔断器实例（仅下游不可用类错误计入失败，业务错误不触发熔断）
+func CreateCircuitBreaker(name string, cfg grpcx.BreakerConfig) *gobreaker.CircuitBreaker {
+	return grpcx.NewCircuitBreaker(name, cfg)
 }
 
 
@@ -410,7 +408,6 @@ func CreateCircuitBreaker(name string) *gobreaker.CircuitBreaker {
 // method: 方法名
 // fn: 具体的业务逻辑闭包
 func ExecuteWithBreaker[T any](breaker *gobreaker.CircuitBreaker, method string, fn func() (T, error)) (T, error) {
-    start := time.Now()
     var resp T
     var err error
 
@@ -423,13 +420,14 @@ func ExecuteWithBreaker[T any](breaker *gobreaker.CircuitBreaker, method string,
     })
 
     if breakerErr != nil {
-        err = breakerErr
+        // 熔断打开时快速失败，返回 CodeServiceUnavailable（不再进入 gRPC 内置重试）
+        // 快速失败不会经过 GRPCMetricsInterceptor，在此单独记录
+        if grpcx.IsBreakerRejected(breakerErr) {
+            middleware.RecordGRPCBreakerRejected(breaker.Name(), method, breakerRejectReason(breakerErr))
+        }
+        err = grpcx.BreakerError(breakerErr)
     }
 
-    duration := time.Since(start).Seconds()
-    // 假设 middleware 是一个全局包
-    middleware.RecordGRPCRequest("user.Service", method, duration, err)
-
     if err != nil {
         var zero T // 高效返回零值
         return zero, err
@@ -438,6 +436,14 @@ func ExecuteWithBreaker[T any](breaker *gobreaker.CircuitBreaker, method string,
     return resp, nil
 }
 
+// breakerRejectReason 返回熔断拒绝原因的指标标签值
+func breakerRejectReason(err error) string {
+    if errors.Is(err, gobreaker.ErrTooManyRequests) {
+        return "too_many_requests"
+    }
+    return "open"
+}
+
 // ==================== gRPC 连接和熔断器初始化工具函数 ====================
 
 // gRPC 服务配置，定义重试策略
@@ -458,40 +464,35 @@ const retryPolicy = `{
 
 // CreateAuthServiceConnection 创建认证服务 gRPC 连接
 // addr: 用户服务地址，格式为 "host:port"
-// breaker: 熔断器实例
 // 返回: gRPC 连接和错误
-func CreateAuthServiceConnection(addr string, breaker *gobreaker.CircuitBreaker) (*grpc.ClientConn, error) {
-	return CreateConnection(addr, "user.AuthService", breaker)
+func CreateAuthServiceConnection(addr string) (*grpc.ClientConn, error) {
+	return CreateConnection(addr, "user.AuthService")
 }
 
 // CreateUserServiceConnection 创建用户服务 gRPC 连接
 // addr: 用户服务地址，格式为 "host:port"
-// breaker: 熔断器实例
 // 返回: gRPC 连接和错误
-func CreateUserServiceConnection(addr string, breaker *gobreaker.CircuitBreaker) (*grpc.ClientConn, error) {
-	return CreateConnection(addr, "user.UserService", breaker)
+func CreateUserServiceConnection(addr string) (*grpc.ClientConn, error) {
+	return CreateConnection(addr, "user.UserService")
 }
 
 // CreateFriendServiceConnection 创建好友服务 gRPC 连接
 // addr: 用户服务地址，格式为 "host:port"
-// breaker: 熔断器实例
 // 返回: gRPC 连接和错误
-func CreateFriendServiceConnection(addr string, breaker *gobreaker.CircuitBreaker) (*grpc.ClientConn, error) {
-	return CreateConnection(addr, "user.FriendService", breaker)
+func CreateFriendServiceConnection(addr string) (*grpc.ClientConn, error) {
+	return CreateConnection(addr, "user.FriendService")
 }
 
 // CreateBlacklistServiceConnection 创建黑名单服务 gRPC 连接
 // addr: 用户服务地址，格式为 "host:port"
-// breaker: 熔断器实例
 // 返回: gRPC 连接和错误
-func CreateBlacklistServiceConnection(addr string, breaker *gobreaker.CircuitBreaker) (*grpc.ClientConn, error) {
-	return CreateConnection(addr, "user.BlacklistService", breaker)
+func CreateBlacklistServiceConnection(addr string) (*grpc.ClientConn, error) {
+	return CreateConnection(addr, "user.BlacklistService")
 }
 
 // CreateDeviceServiceConnection 创建设备服务 gRPC 连接
 // addr: 用户服务地址，格式为 "host:port"
-// breaker: 熔断器实例
 // 返回: gRPC 连接和错误
-func CreateDeviceServiceConnection(addr string, breaker *gobreaker.CircuitBreaker) (*grpc.ClientConn, error) {
-	return CreateConnection(addr, "user.DeviceService", breaker)
+func CreateDeviceServiceConnection(addr string) (*grpc.ClientConn, error) {
+	return CreateConnection(addr, "user.DeviceService")
 }
@@ -0,0 +1,55 @@
+package pb
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"ChatServer/apps/gateway/internal/middleware"
+	"ChatServer/consts"
+	"ChatServer/pkg/grpcx"
+	"ChatServer/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExecuteWithBreaker_RecordsFastFail(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+	breaker := CreateCircuitBreaker("test-execute", grpcx.BreakerConfig{
+		MaxRequests:  1,
+		Interval:     time.Minute,
+		Timeout:      time.Minute,
+		MinRequests:  1,
+		FailureRatio: 0.5,
+	})
+	rejected := middleware.GetGRPCBreakerRejectedTotal().WithLabelValues("test-execute", "GetProfile", "open")
+	before := testutil.ToFloat64(rejected)
+
+	// 下游不可用一次即打开熔断
+	unavailable := status.Error(codes.Unavailable, "down")
+	_, err := ExecuteWithBreaker(breaker, "GetProfile", func() (int, error) { return 0, unavailable })
+	require.ErrorIs(t, err, unavailable)
+	assert.Equal(t, before, testutil.ToFloat64(rejected), "实际发起的调用不计入快速失败")
+
+	invoked := false
+	_, err = ExecuteWithBreaker(breaker, "GetProfile", func() (int, error) {
+		invoked = true
+		return 1, nil
+	})
+	assert.False(t, invoked)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Equal(t, strconv.Itoa(consts.CodeServiceUnavailable), st.Message())
+	assert.Equal(t, before+1, testutil.ToFloat64(rejected))
+}
+
+func TestBreakerRejectReason(t *testing.T) {
+	assert.Equal(t, "open", breakerRejectReason(gobreaker.ErrOpenState))
+	assert.Equal(t, "too_many_requests", breakerRejectReason(gobreaker.ErrTooManyRequests))
+}
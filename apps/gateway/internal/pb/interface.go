@@ -70,6 +70,12 @@ type UserServiceClient interface {
 	// BatchGetProfile 批量获取用户信息
 	BatchGetProfile(ctx context.Context, req *userpb.BatchGetProfileRequest) (*userpb.BatchGetProfileResponse, error)
 
+	// SetPresenceVisibility 设置在线状态可见性
+	SetPresenceVisibility(ctx context.Context, req *userpb.SetPresenceVisibilityRequest) (*userpb.SetPresenceVisibilityResponse, error)
+
+	// UpdatePrivacy 更新隐私设置
+	UpdatePrivacy(ctx context.Context, req *userpb.UpdatePrivacyRequest) (*userpb.UpdatePrivacyResponse, error)
+
 	// ==================== 好友服务 ====================
 	// SendFriendApply 发送好友申请
 	SendFriendApply(ctx context.Context, req *userpb.SendFriendApplyRequest) (*userpb.SendFriendApplyResponse, error)
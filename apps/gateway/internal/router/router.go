@@ -4,10 +4,10 @@ import (
 	"ChatServer/apps/gateway/internal/middleware"
 	v1 "ChatServer/apps/gateway/internal/router/v1"
 	"ChatServer/consts/redisKey"
+	"ChatServer/pkg/metrics"
 	"ChatServer/pkg/util"
 
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // InitRouter 初始化路由
@@ -19,6 +19,9 @@ import (
 func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friendHandler *v1.FriendHandler, blacklistHandler *v1.BlacklistHandler, deviceHandler *v1.DeviceHandler) *gin.Engine {
 	r := gin.New()
 
+	// 路由存在但方法未注册时返回 CodeMethodNotAllowed（而不是 404）
+	r.HandleMethodNotAllowed = true
+
 	// 恢复中间件
 	r.Use(middleware.GinRecovery(true))
 
@@ -37,6 +40,9 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 	// 跨域中间件
 	r.Use(middleware.CorsMiddleware())
 
+	// 降级模式响应头（Redis 不可用时添加 X-Service-Mode: degraded）
+	r.Use(middleware.ServiceModeMiddleware())
+
 	// ==================== 全局 IP 限流中间件 ====================
 	// 参数说明：
 	//   - blacklistKey: gateway:blacklist:ips (黑名单 Redis Set 的 key)
@@ -48,6 +54,8 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 	//   3. Redis 不可用时降级放行（Fail-Open），不影响服务可用性
 	r.Use(middleware.IPRateLimitMiddleware(rediskey.GatewayIPBlacklistKey(), 10.0, 20))
 
+	r.NoMethod(middleware.MethodNotAllowedHandler())
+
 	// 健康检查（无需认证）
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -55,16 +63,22 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 		})
 	})
 
+	// 就绪检查（无需认证）：下游依赖不可达时返回 503，供编排系统摘除流量
+	r.GET("/readyz", middleware.ReadinessHandler())
+
 	// Prometheus 指标暴露接口
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	// API 路由组
 	api := r.Group("/api/v1")
+	// 写请求体类型白名单（默认仅 JSON，头像上传为 multipart）
+	api.Use(middleware.ContentTypeMiddleware())
 	{
 		// 公开接口（不需要认证）
 		public := api.Group("/public")
 		{
 			user := public.Group("/user")
+			user.Use(middleware.AuditMiddleware()) // 认证类接口审计（登录/注册/重置密码）
 			{
 				user.POST("/login", authHandler.Login)
 				user.POST("/login-by-code", authHandler.LoginByCode)
@@ -89,6 +103,7 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 		auth.Use(middleware.UserRateLimitMiddleware(100.0, 200))
 		{
 			user := auth.Group("/user")
+			user.Use(middleware.AuditMiddleware()) // 认证类接口审计（修改密码/登出）
 			{
 				user.GET("/profile", userHandler.GetProfile)
 				user.PUT("/profile", userHandler.UpdateProfile)
@@ -101,16 +116,21 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 				user.DELETE("/devices/:deviceId", deviceHandler.KickDevice)
 				user.GET("/online-status/:userUuid", deviceHandler.GetOnlineStatus)
 				user.POST("/batch-online-status", deviceHandler.BatchGetOnlineStatus)
+				user.PUT("/presence-visibility", userHandler.SetPresenceVisibility)
+				user.PUT("/privacy", userHandler.UpdatePrivacy)
 
 				// 敏感操作使用更严格的限流
 				user.POST("/change-password",
-					middleware.UserRateLimitMiddlewareWithConfig(2.0, 5),
+					middleware.UserRateLimitMiddlewareWithConfig("change_password", 2.0, 5),
 					userHandler.ChangePassword)
 				user.POST("/change-email",
-					middleware.UserRateLimitMiddlewareWithConfig(2.0, 5),
+					middleware.UserRateLimitMiddlewareWithConfig("change_email", 2.0, 5),
 					userHandler.ChangeEmail)
+				user.POST("/change-telephone",
+					middleware.UserRateLimitMiddlewareWithConfig("change_telephone", 2.0, 5),
+					userHandler.ChangeTelephone)
 				user.POST("/delete-account",
-					middleware.UserRateLimitMiddlewareWithConfig(2.0, 5),
+					middleware.UserRateLimitMiddlewareWithConfig("delete_account", 2.0, 5),
 					userHandler.DeleteAccount)
 
 				user.POST("/logout", authHandler.Logout)
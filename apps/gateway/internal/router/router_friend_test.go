@@ -7,14 +7,17 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"testing"
 
 	"ChatServer/apps/gateway/internal/dto"
 	v1 "ChatServer/apps/gateway/internal/router/v1"
 	"ChatServer/apps/gateway/internal/service"
+	userpb "ChatServer/apps/user/pb"
 	"ChatServer/consts"
 	"ChatServer/pkg/logger"
+	"ChatServer/pkg/result"
 	"ChatServer/pkg/util"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +26,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type fakeRouterFriendService struct {
@@ -530,3 +534,75 @@ func TestRouterFriendErrorMapping(t *testing.T) {
 		assert.Equal(t, consts.CodeInternalError, decodeRouterFriendCode(t, w2))
 	})
 }
+
+func TestRouterFriendListContentNegotiation(t *testing.T) {
+	initRouterFriendTestLogger()
+	svc := &fakeRouterFriendService{
+		friendListFn: func(_ context.Context, _ *dto.GetFriendListRequest) (*dto.GetFriendListResponse, error) {
+			return &dto.GetFriendListResponse{
+				Items:      []*dto.FriendItem{{UUID: "u2", Nickname: "bob", Remark: "b", CreatedAt: 1700000000000}},
+				Pagination: &dto.PaginationInfo{Page: 1, PageSize: 20, Total: 1, TotalPages: 1},
+				Version:    42,
+			}, nil
+		},
+	}
+	r := buildFriendTestRouter(svc)
+
+	t.Run("default_json", func(t *testing.T) {
+		req := newAuthedRouterFriendRequest(t, http.MethodGet, "/api/v1/auth/friend/list?page=1&pageSize=20", "")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		assert.Equal(t, consts.CodeSuccess, decodeRouterFriendCode(t, w))
+	})
+
+	t.Run("protobuf_by_accept", func(t *testing.T) {
+		req := newAuthedRouterFriendRequest(t, http.MethodGet, "/api/v1/auth/friend/list?page=1&pageSize=20", "")
+		req.Header.Set("Accept", result.MIMEProtobuf)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, result.MIMEProtobuf, w.Header().Get("Content-Type"))
+		assert.Equal(t, strconv.Itoa(consts.CodeSuccess), w.Header().Get(result.HeaderBizCode))
+
+		var resp userpb.GetFriendListResponse
+		require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Items, 1)
+		assert.Equal(t, "u2", resp.Items[0].Uuid)
+		assert.Equal(t, "b", resp.Items[0].Remark)
+		assert.Equal(t, int64(1), resp.Pagination.Total)
+		assert.Equal(t, int64(42), resp.Version)
+	})
+
+	t.Run("protobuf_disabled_falls_back_to_json", func(t *testing.T) {
+		result.SetProtobufEnabled(false)
+		t.Cleanup(func() { result.SetProtobufEnabled(true) })
+
+		req := newAuthedRouterFriendRequest(t, http.MethodGet, "/api/v1/auth/friend/list?page=1&pageSize=20", "")
+		req.Header.Set("Accept", result.MIMEProtobuf)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Equal(t, consts.CodeSuccess, decodeRouterFriendCode(t, w))
+	})
+
+	t.Run("error_stays_json", func(t *testing.T) {
+		errSvc := &fakeRouterFriendService{
+			friendListFn: func(_ context.Context, _ *dto.GetFriendListRequest) (*dto.GetFriendListResponse, error) {
+				return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+			},
+		}
+		req := newAuthedRouterFriendRequest(t, http.MethodGet, "/api/v1/auth/friend/list", "")
+		req.Header.Set("Accept", result.MIMEProtobuf)
+		w := httptest.NewRecorder()
+		buildFriendTestRouter(errSvc).ServeHTTP(w, req)
+
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Equal(t, consts.CodeUserNotFound, decodeRouterFriendCode(t, w))
+	})
+}
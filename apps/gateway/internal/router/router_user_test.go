@@ -32,13 +32,16 @@ type fakeRouterUserService struct {
 	getOtherProfileFn func(context.Context, *dto.GetOtherProfileRequest) (*dto.GetOtherProfileResponse, error)
 	searchUserFn      func(context.Context, *dto.SearchUserRequest) (*dto.SearchUserResponse, error)
 	updateProfileFn   func(context.Context, *dto.UpdateProfileRequest) (*dto.UpdateProfileResponse, error)
-	changePasswordFn  func(context.Context, *dto.ChangePasswordRequest) error
+	changePasswordFn  func(context.Context, *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error)
 	changeEmailFn     func(context.Context, *dto.ChangeEmailRequest) (*dto.ChangeEmailResponse, error)
+	changeTelFn       func(context.Context, *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error)
 	uploadAvatarFn    func(context.Context, string) (string, error)
 	getQRCodeFn       func(context.Context) (*dto.GetQRCodeResponse, error)
 	parseQRCodeFn     func(context.Context, *dto.ParseQRCodeRequest) (*dto.ParseQRCodeResponse, error)
 	batchGetProfileFn func(context.Context, *dto.BatchGetProfileRequest) (*dto.BatchGetProfileResponse, error)
 	deleteAccountFn   func(context.Context, *dto.DeleteAccountRequest) (*dto.DeleteAccountResponse, error)
+	setPresenceFn     func(context.Context, *dto.SetPresenceVisibilityRequest) (*dto.SetPresenceVisibilityResponse, error)
+	updatePrivacyFn   func(context.Context, *dto.UpdatePrivacyRequest) (*dto.UpdatePrivacyResponse, error)
 }
 
 var _ service.UserService = (*fakeRouterUserService)(nil)
@@ -71,9 +74,9 @@ func (f *fakeRouterUserService) UpdateProfile(ctx context.Context, req *dto.Upda
 	return f.updateProfileFn(ctx, req)
 }
 
-func (f *fakeRouterUserService) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) error {
+func (f *fakeRouterUserService) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error) {
 	if f.changePasswordFn == nil {
-		return nil
+		return &dto.ChangePasswordResponse{}, nil
 	}
 	return f.changePasswordFn(ctx, req)
 }
@@ -85,6 +88,13 @@ func (f *fakeRouterUserService) ChangeEmail(ctx context.Context, req *dto.Change
 	return f.changeEmailFn(ctx, req)
 }
 
+func (f *fakeRouterUserService) ChangeTelephone(ctx context.Context, req *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error) {
+	if f.changeTelFn == nil {
+		return &dto.ChangeTelephoneResponse{}, nil
+	}
+	return f.changeTelFn(ctx, req)
+}
+
 func (f *fakeRouterUserService) UploadAvatar(ctx context.Context, avatarURL string) (string, error) {
 	if f.uploadAvatarFn == nil {
 		return avatarURL, nil
@@ -120,6 +130,20 @@ func (f *fakeRouterUserService) DeleteAccount(ctx context.Context, req *dto.Dele
 	return f.deleteAccountFn(ctx, req)
 }
 
+func (f *fakeRouterUserService) SetPresenceVisibility(ctx context.Context, req *dto.SetPresenceVisibilityRequest) (*dto.SetPresenceVisibilityResponse, error) {
+	if f.setPresenceFn == nil {
+		return &dto.SetPresenceVisibilityResponse{}, nil
+	}
+	return f.setPresenceFn(ctx, req)
+}
+
+func (f *fakeRouterUserService) UpdatePrivacy(ctx context.Context, req *dto.UpdatePrivacyRequest) (*dto.UpdatePrivacyResponse, error) {
+	if f.updatePrivacyFn == nil {
+		return &dto.UpdatePrivacyResponse{}, nil
+	}
+	return f.updatePrivacyFn(ctx, req)
+}
+
 type routerUserResultBody struct {
 	Code int `json:"code"`
 }
@@ -283,10 +307,10 @@ func TestRouterUserAuthRoutesSuccess(t *testing.T) {
 			target: "/api/v1/auth/user/change-password",
 			body:   `{"oldPassword":"oldpass123","newPassword":"newpass123"}`,
 			setup: func(s *fakeRouterUserService, called *bool) {
-				s.changePasswordFn = func(_ context.Context, req *dto.ChangePasswordRequest) error {
+				s.changePasswordFn = func(_ context.Context, req *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error) {
 					*called = true
 					require.Equal(t, "oldpass123", req.OldPassword)
-					return nil
+					return &dto.ChangePasswordResponse{}, nil
 				}
 			},
 		},
@@ -303,6 +327,19 @@ func TestRouterUserAuthRoutesSuccess(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "change_telephone",
+			method: http.MethodPost,
+			target: "/api/v1/auth/user/change-telephone",
+			body:   `{"newTelephone":"13900139000","verifyCode":"123456"}`,
+			setup: func(s *fakeRouterUserService, called *bool) {
+				s.changeTelFn = func(_ context.Context, req *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error) {
+					*called = true
+					require.Equal(t, "13900139000", req.NewTelephone)
+					return &dto.ChangeTelephoneResponse{Telephone: req.NewTelephone}, nil
+				}
+			},
+		},
 		{
 			name:   "get_qrcode",
 			method: http.MethodGet,
@@ -340,6 +377,35 @@ func TestRouterUserAuthRoutesSuccess(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "set_presence_visibility",
+			method: http.MethodPut,
+			target: "/api/v1/auth/user/presence-visibility",
+			body:   `{"hidden":false}`,
+			setup: func(s *fakeRouterUserService, called *bool) {
+				s.setPresenceFn = func(_ context.Context, req *dto.SetPresenceVisibilityRequest) (*dto.SetPresenceVisibilityResponse, error) {
+					*called = true
+					require.NotNil(t, req.Hidden)
+					require.False(t, *req.Hidden)
+					return &dto.SetPresenceVisibilityResponse{}, nil
+				}
+			},
+		},
+		{
+			name:   "update_privacy",
+			method: http.MethodPut,
+			target: "/api/v1/auth/user/privacy",
+			body:   `{"hidePresence":true,"presenceHideScope":1}`,
+			setup: func(s *fakeRouterUserService, called *bool) {
+				s.updatePrivacyFn = func(_ context.Context, req *dto.UpdatePrivacyRequest) (*dto.UpdatePrivacyResponse, error) {
+					*called = true
+					require.NotNil(t, req.HidePresence)
+					require.True(t, *req.HidePresence)
+					require.Equal(t, int32(1), req.PresenceHideScope)
+					return &dto.UpdatePrivacyResponse{}, nil
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -402,17 +468,32 @@ func TestRouterUserParamErrors(t *testing.T) {
 			target: "/api/v1/auth/user/batch-profile",
 			body:   `{"userUuids":[]}`,
 		},
+		{
+			name:   "presence_visibility_missing_hidden",
+			method: http.MethodPut,
+			target: "/api/v1/auth/user/presence-visibility",
+			body:   `{}`,
+		},
+		{
+			name:   "privacy_invalid_scope",
+			method: http.MethodPut,
+			target: "/api/v1/auth/user/privacy",
+			body:   `{"hidePresence":true,"presenceHideScope":2}`,
+		},
 		{
 			name:   "avatar_missing_file",
 			method: http.MethodPost,
 			target: "/api/v1/auth/user/avatar",
-			body:   `{}`,
+			body:   "--x--\r\n",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := newRouterUserAuthedRequest(t, tt.method, tt.target, tt.body)
+			if tt.target == "/api/v1/auth/user/avatar" {
+				req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+			}
 			w := httptest.NewRecorder()
 			r.ServeHTTP(w, req)
 
@@ -422,6 +503,52 @@ func TestRouterUserParamErrors(t *testing.T) {
 	}
 }
 
+func TestRouterUserMethodAndContentTypeAllowlist(t *testing.T) {
+	initRouterUserTestLogger()
+	r := buildRouterUserTestRouter(&fakeRouterUserService{})
+
+	t.Run("wrong_method", func(t *testing.T) {
+		req := newRouterUserAuthedRequest(t, http.MethodDelete, "/api/v1/auth/user/profile", "")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeMethodNotAllowed, decodeRouterUserCode(t, w))
+	})
+
+	t.Run("wrong_content_type", func(t *testing.T) {
+		req := newRouterUserAuthedRequest(t, http.MethodPut, "/api/v1/auth/user/profile", `nickname=alice`)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeBodyError, decodeRouterUserCode(t, w))
+	})
+
+	t.Run("json_to_upload_route", func(t *testing.T) {
+		req := newRouterUserAuthedRequest(t, http.MethodPost, "/api/v1/auth/user/avatar", `{}`)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, consts.CodeBodyError, decodeRouterUserCode(t, w))
+	})
+
+	t.Run("json_with_charset_and_empty_body_pass", func(t *testing.T) {
+		req := newRouterUserAuthedRequest(t, http.MethodPut, "/api/v1/auth/user/profile", `{"nickname":"alice"}`)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.NotEqual(t, consts.CodeBodyError, decodeRouterUserCode(t, w))
+
+		req = newRouterUserAuthedRequest(t, http.MethodPost, "/api/v1/auth/user/logout", "")
+		req.Header.Del("Content-Type")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.NotEqual(t, consts.CodeBodyError, decodeRouterUserCode(t, w))
+	})
+}
+
 func TestRouterUserErrorMapping(t *testing.T) {
 	initRouterUserTestLogger()
 
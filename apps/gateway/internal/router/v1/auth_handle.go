@@ -9,6 +9,8 @@ import (
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/result"
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -27,6 +29,28 @@ func resolveDeviceID(c *gin.Context) string {
 	return strings.TrimSpace(c.GetHeader(ctxmeta.HeaderDeviceID))
 }
 
+// fallbackDeviceIDPrefix 服务端派生设备ID的前缀，便于在设备列表中区分。
+const fallbackDeviceIDPrefix = "fp-"
+
+// fallbackDeviceID 客户端未携带设备ID时，按 User-Agent + 平台派生稳定的设备ID。
+// 取舍：同一客户端重复登录复用同一设备会话，不会在设备列表中堆积随机设备；
+// 代价是 UA 与平台完全相同的多台设备会被视为同一设备（互相顶替登录）。
+// 因此登录响应会返回该设备ID，客户端应持久化后通过 X-Device-ID 携带。
+// UA 与平台均为空时无法区分客户端，返回空串（按参数错误处理）。
+func fallbackDeviceID(c *gin.Context, info *dto.DeviceInfo) string {
+	userAgent := strings.TrimSpace(c.GetHeader("User-Agent"))
+	platform := ""
+	if info != nil {
+		platform = strings.ToLower(strings.TrimSpace(info.Platform))
+	}
+	if userAgent == "" && platform == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(userAgent + "|" + platform))
+	return fallbackDeviceIDPrefix + hex.EncodeToString(sum[:16])
+}
+
 // NewAuthHandler 创建认证处理器
 // authService: 认证服务
 func NewAuthHandler(authService service.AuthService) *AuthHandler {
@@ -53,8 +77,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 2. 设备 ID 优先取 context，其次取 X-Device-ID；空值视为参数错误
+	// 2. 设备 ID 优先取 context，其次取 X-Device-ID，均为空时按客户端特征派生
 	deviceID := resolveDeviceID(c)
+	if deviceID == "" {
+		deviceID = fallbackDeviceID(c, req.DeviceInfo)
+	}
 	if deviceID == "" {
 		ctx := middleware.NewContextWithGin(c)
 		logger.Warn(ctx, "请求头中无设备ID")
@@ -80,11 +107,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		logger.Error(ctx, "登录服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
-	// 6. 返回成功响应
+	// 6. 返回成功响应（附带设备ID，供未携带 X-Device-ID 的客户端持久化）
+	if loginResp != nil {
+		loginResp.DeviceID = deviceID
+	}
 	result.Success(c, loginResp)
 }
 
@@ -122,7 +152,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		logger.Error(ctx, "注册服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -164,7 +194,7 @@ func (h *AuthHandler) SendVerifyCode(c *gin.Context) {
 		logger.Error(ctx, "发送验证码服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -190,8 +220,11 @@ func (h *AuthHandler) LoginByCode(c *gin.Context) {
 		return
 	}
 
-	// 2. 设备 ID 优先取 context，其次取 X-Device-ID；空值视为参数错误
+	// 2. 设备 ID 优先取 context，其次取 X-Device-ID，均为空时按客户端特征派生
 	deviceID := resolveDeviceID(c)
+	if deviceID == "" {
+		deviceID = fallbackDeviceID(c, req.DeviceInfo)
+	}
 	if deviceID == "" {
 		ctx := middleware.NewContextWithGin(c)
 		logger.Warn(ctx, "请求头中无设备ID")
@@ -217,11 +250,14 @@ func (h *AuthHandler) LoginByCode(c *gin.Context) {
 		logger.Error(ctx, "验证码登录服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
-	// 5. 返回成功响应
+	// 5. 返回成功响应（附带设备ID，供未携带 X-Device-ID 的客户端持久化）
+	if loginResp != nil {
+		loginResp.DeviceID = deviceID
+	}
 	result.Success(c, loginResp)
 }
 
@@ -259,7 +295,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		logger.Error(ctx, "登出服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -301,7 +337,7 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		logger.Error(ctx, "重置密码服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -367,7 +403,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		logger.Error(ctx, "刷新Token服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -409,7 +445,7 @@ func (h *AuthHandler) VerifyCode(c *gin.Context) {
 		logger.Error(ctx, "校验验证码服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -258,6 +258,48 @@ func TestAuthHandlerLogin(t *testing.T) {
 	}
 }
 
+func TestAuthHandlerLoginFallbackDeviceIDIsStable(t *testing.T) {
+	initGatewayAuthHandlerLogger()
+
+	var gotIDs []string
+	svc := &fakeAuthHTTPService{
+		loginFn: func(_ context.Context, _ *dto.LoginRequest, deviceID string) (*dto.LoginResponse, error) {
+			gotIDs = append(gotIDs, deviceID)
+			return &dto.LoginResponse{AccessToken: "at"}, nil
+		},
+	}
+	h := NewAuthHandler(svc)
+
+	login := func(userAgent, platform string) string {
+		w := httptest.NewRecorder()
+		body := `{"account":"a","password":"pass123","deviceInfo":{"deviceName":"n","platform":"` + platform + `"}}`
+		req := newJSONRequest(t, http.MethodPost, "/api/v1/public/user/login", body)
+		req.Header.Set("User-Agent", userAgent)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		h.Login(c)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Code int               `json:"code"`
+			Data dto.LoginResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, consts.CodeSuccess, resp.Code)
+		return resp.Data.DeviceID
+	}
+
+	first := login("LCchat/1.0 (iPhone)", "iOS")
+	second := login("LCchat/1.0 (iPhone)", "iOS")
+	other := login("LCchat/1.0 (Pixel)", "Android")
+
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+	assert.Equal(t, []string{first, second, other}, gotIDs)
+}
+
 func TestAuthHandlerLoginByCode(t *testing.T) {
 	initGatewayAuthHandlerLogger()
 
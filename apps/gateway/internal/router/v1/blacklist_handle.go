@@ -52,7 +52,7 @@ func (h *BlacklistHandler) AddBlacklist(c *gin.Context) {
 		logger.Error(ctx, "拉黑用户服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -91,7 +91,7 @@ func (h *BlacklistHandler) RemoveBlacklist(c *gin.Context) {
 		logger.Error(ctx, "取消拉黑服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -134,7 +134,7 @@ func (h *BlacklistHandler) GetBlacklistList(c *gin.Context) {
 		logger.Error(ctx, "获取黑名单列表服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -169,7 +169,7 @@ func (h *BlacklistHandler) CheckIsBlacklist(c *gin.Context) {
 		logger.Error(ctx, "判断是否拉黑服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -43,7 +43,7 @@ func (h *DeviceHandler) GetDeviceList(c *gin.Context) {
 		logger.Error(ctx, "获取设备列表服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -76,7 +76,7 @@ func (h *DeviceHandler) KickDevice(c *gin.Context) {
 		logger.Error(ctx, "踢出设备服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -109,7 +109,7 @@ func (h *DeviceHandler) GetOnlineStatus(c *gin.Context) {
 		logger.Error(ctx, "获取在线状态服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -151,7 +151,7 @@ func (h *DeviceHandler) BatchGetOnlineStatus(c *gin.Context) {
 		logger.Error(ctx, "批量获取在线状态服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
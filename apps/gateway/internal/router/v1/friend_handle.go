@@ -58,7 +58,7 @@ func (h *FriendHandler) SendFriendApply(c *gin.Context) {
 		logger.Error(ctx, "发送好友申请服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -110,7 +110,7 @@ func (h *FriendHandler) GetFriendApplyList(c *gin.Context) {
 		logger.Error(ctx, "获取好友申请列表服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -162,7 +162,7 @@ func (h *FriendHandler) GetSentApplyList(c *gin.Context) {
 		logger.Error(ctx, "获取发出的申请列表服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -204,7 +204,7 @@ func (h *FriendHandler) HandleFriendApply(c *gin.Context) {
 		logger.Error(ctx, "处理好友申请服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -237,7 +237,7 @@ func (h *FriendHandler) GetUnreadApplyCount(c *gin.Context) {
 		logger.Error(ctx, "获取未读申请数量服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -247,7 +247,7 @@ func (h *FriendHandler) GetUnreadApplyCount(c *gin.Context) {
 
 // MarkApplyAsRead 标记申请已读接口
 // @Summary 标记申请已读
-// @Description 批量标记好友申请为已读
+// @Description 批量标记好友申请为已读；markAll=true 时标记全部未读申请并清零红点
 // @Tags 好友接口
 // @Accept json
 // @Produce json
@@ -265,14 +265,14 @@ func (h *FriendHandler) MarkApplyAsRead(c *gin.Context) {
 		return
 	}
 
-	// 2. 验证申请ID列表
-	if len(req.ApplyIDs) == 0 {
+	// 2. 验证申请ID列表（markAll=true 时标记全部，无需传 ID）
+	if !req.MarkAll && len(req.ApplyIDs) == 0 {
 		result.Fail(c, nil, consts.CodeParamError)
 		return
 	}
 
 	// 3. 调用服务层处理业务逻辑（依赖注入）
-	_, err := h.friendService.MarkApplyAsRead(ctx, &req)
+	resp, err := h.friendService.MarkApplyAsRead(ctx, &req)
 	if err != nil {
 		// 检查是否为业务错误
 		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
@@ -285,20 +285,20 @@ func (h *FriendHandler) MarkApplyAsRead(c *gin.Context) {
 		logger.Error(ctx, "标记申请已读服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
 	// 4. 返回成功响应
-	result.Success(c, nil)
+	result.Success(c, resp)
 }
 
 // GetFriendList 获取好友列表接口
 // @Summary 获取好友列表
-// @Description 获取当前用户的好友列表
+// @Description 获取当前用户的好友列表（Accept: application/x-protobuf 时返回 userpb.GetFriendListResponse 编码）
 // @Tags 好友接口
 // @Accept json
-// @Produce json
+// @Produce json,application/x-protobuf
 // @Param groupTag query string false "标签"
 // @Param page query int false "页码(默认1)"
 // @Param pageSize query int false "每页数量(默认20)"
@@ -337,7 +337,7 @@ func (h *FriendHandler) GetFriendList(c *gin.Context) {
 		logger.Error(ctx, "获取好友列表服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -347,10 +347,10 @@ func (h *FriendHandler) GetFriendList(c *gin.Context) {
 
 // SyncFriendList 好友增量同步接口
 // @Summary 好友增量同步
-// @Description 增量同步好友列表
+// @Description 增量同步好友列表（Accept: application/x-protobuf 时返回 userpb.SyncFriendListResponse 编码）
 // @Tags 好友接口
 // @Accept json
-// @Produce json
+// @Produce json,application/x-protobuf
 // @Param request body dto.SyncFriendListRequest true "增量同步请求"
 // @Success 200 {object} dto.SyncFriendListResponse
 // @Router /api/v1/user/friend/sync [post]
@@ -384,7 +384,7 @@ func (h *FriendHandler) SyncFriendList(c *gin.Context) {
 		logger.Error(ctx, "好友增量同步服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -427,7 +427,7 @@ func (h *FriendHandler) DeleteFriend(c *gin.Context) {
 			logger.String("user_uuid", req.UserUUID),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -470,7 +470,7 @@ func (h *FriendHandler) SetFriendRemark(c *gin.Context) {
 			logger.String("user_uuid", req.UserUUID),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -513,7 +513,7 @@ func (h *FriendHandler) SetFriendTag(c *gin.Context) {
 			logger.String("user_uuid", req.UserUUID),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -546,7 +546,7 @@ func (h *FriendHandler) GetTagList(c *gin.Context) {
 		logger.Error(ctx, "获取标签列表服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -590,7 +590,7 @@ func (h *FriendHandler) CheckIsFriend(c *gin.Context) {
 			logger.String("peer_uuid", req.PeerUUID),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -634,7 +634,7 @@ func (h *FriendHandler) GetRelationStatus(c *gin.Context) {
 			logger.String("peer_uuid", req.PeerUUID),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
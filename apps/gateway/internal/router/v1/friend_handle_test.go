@@ -420,6 +420,41 @@ func TestFriendHandlerHandleAndMarkApply(t *testing.T) {
 		assert.False(t, called)
 	})
 
+	t.Run("mark_apply_as_read_mark_all_without_ids", func(t *testing.T) {
+		called := false
+		h := NewFriendHandler(&fakeFriendHTTPService{
+			markReadFn: func(_ context.Context, req *dto.MarkApplyAsReadRequest) (*dto.MarkApplyAsReadResponse, error) {
+				called = true
+				assert.True(t, req.MarkAll)
+				assert.Empty(t, req.ApplyIDs)
+				return &dto.MarkApplyAsReadResponse{}, nil
+			},
+		})
+		w := httptest.NewRecorder()
+		req := newFriendJSONRequest(t, http.MethodPost, "/api/v1/auth/friend/apply/read", `{"markAll":true}`)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		h.MarkApplyAsRead(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeSuccess, decodeFriendHandlerCode(t, w))
+		assert.True(t, called)
+	})
+
+	t.Run("mark_apply_as_read_missing_ids", func(t *testing.T) {
+		h := NewFriendHandler(&fakeFriendHTTPService{})
+		w := httptest.NewRecorder()
+		req := newFriendJSONRequest(t, http.MethodPost, "/api/v1/auth/friend/apply/read", `{"markAll":false}`)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		h.MarkApplyAsRead(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeParamError, decodeFriendHandlerCode(t, w))
+	})
+
 	t.Run("mark_apply_as_read_internal_error", func(t *testing.T) {
 		h := NewFriendHandler(&fakeFriendHTTPService{
 			markReadFn: func(_ context.Context, req *dto.MarkApplyAsReadRequest) (*dto.MarkApplyAsReadResponse, error) {
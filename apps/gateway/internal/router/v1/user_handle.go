@@ -55,7 +55,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		logger.Error(ctx, "获取个人信息服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -101,7 +101,7 @@ func (h *UserHandler) GetOtherProfile(c *gin.Context) {
 		logger.Error(ctx, "获取他人信息服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -164,7 +164,7 @@ func (h *UserHandler) SearchUser(c *gin.Context) {
 		logger.Error(ctx, "搜索用户服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -193,7 +193,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// 2. 调用服务层处理业务逻辑（依赖注入）
-	err := h.userService.ChangePassword(ctx, &req)
+	passwordResp, err := h.userService.ChangePassword(ctx, &req)
 	if err != nil {
 		// 检查是否为业务错误
 		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
@@ -206,12 +206,12 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		logger.Error(ctx, "修改密码服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
-	// 3. 返回成功响应
-	result.Success(c, nil)
+	// 3. 返回成功响应（携带当前设备的新 Access Token）
+	result.Success(c, passwordResp)
 }
 
 // UpdateProfile 更新基本信息接口
@@ -254,7 +254,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		logger.Error(ctx, "更新基本信息服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -296,7 +296,7 @@ func (h *UserHandler) ChangeEmail(c *gin.Context) {
 		logger.Error(ctx, "换绑邮箱服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -304,6 +304,48 @@ func (h *UserHandler) ChangeEmail(c *gin.Context) {
 	result.Success(c, emailResp)
 }
 
+// ChangeTelephone 换绑手机接口
+// @Summary 换绑手机
+// @Description 更换绑定手机号（需新手机号验证码）
+// @Tags 用户信息接口
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangeTelephoneRequest true "换绑手机请求"
+// @Success 200 {object} dto.ChangeTelephoneResponse
+// @Router /api/v1/user/change-telephone [post]
+func (h *UserHandler) ChangeTelephone(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	// 1. 绑定请求数据
+	var req dto.ChangeTelephoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// 参数错误由客户端输入导致,属于正常业务流程,不记录日志
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	// 2. 调用服务层处理业务逻辑（依赖注入）
+	telResp, err := h.userService.ChangeTelephone(ctx, &req)
+	if err != nil {
+		// 检查是否为业务错误
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			// 业务逻辑失败（如手机号已被使用、验证码错误等）
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+
+		// 其他内部错误
+		logger.Error(ctx, "换绑手机服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	result.Success(c, telResp)
+}
+
 // UploadAvatar 上传头像接口
 // @Summary 上传并更新用户头像
 // @Description 上传图片文件到对象存储并更新用户头像
@@ -417,7 +459,7 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 			logger.String("avatar_url", uploadResult.URL),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -447,13 +489,13 @@ func (h *UserHandler) BatchGetProfile(c *gin.Context) {
 		return
 	}
 
-	// 2. 验证用户UUID列表数量（最多100个）
+	// 2. 验证用户UUID列表数量（最多 consts.BatchGetProfileMaxSize 个）
 	if len(req.UserUUIDs) == 0 {
 		result.Fail(c, nil, consts.CodeParamError)
 		return
 	}
 
-	if len(req.UserUUIDs) > 100 {
+	if len(req.UserUUIDs) > consts.BatchGetProfileMaxSize {
 		logger.Warn(ctx, "批量获取用户信息超过最大限制",
 			logger.Int("count", len(req.UserUUIDs)),
 		)
@@ -476,7 +518,7 @@ func (h *UserHandler) BatchGetProfile(c *gin.Context) {
 			logger.Int("count", len(req.UserUUIDs)),
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -509,7 +551,7 @@ func (h *UserHandler) GetQRCode(c *gin.Context) {
 		logger.Error(ctx, "获取用户二维码服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -551,7 +593,7 @@ func (h *UserHandler) ParseQRCode(c *gin.Context) {
 		logger.Error(ctx, "解析二维码服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
@@ -593,10 +635,92 @@ func (h *UserHandler) DeleteAccount(c *gin.Context) {
 		logger.Error(ctx, "注销账号服务内部错误",
 			logger.ErrorField("error", err),
 		)
-		result.Fail(c, nil, consts.CodeInternalError)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
 		return
 	}
 
 	// 3. 返回成功响应
 	result.Success(c, deleteResp)
 }
+
+// SetPresenceVisibility 设置在线状态可见性接口
+// @Summary 设置在线状态可见性
+// @Description 设置是否向好友隐藏在线状态（隐藏后好友不再收到上下线推送）
+// @Tags 用户信息接口
+// @Accept json
+// @Produce json
+// @Param request body dto.SetPresenceVisibilityRequest true "设置在线状态可见性请求"
+// @Success 200 {object} dto.SetPresenceVisibilityResponse
+// @Router /api/v1/auth/user/presence-visibility [put]
+func (h *UserHandler) SetPresenceVisibility(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	// 1. 绑定请求数据
+	var req dto.SetPresenceVisibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// 参数错误由客户端输入导致,属于正常业务流程,不记录日志
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	// 2. 调用服务层处理业务逻辑（依赖注入）
+	visibilityResp, err := h.userService.SetPresenceVisibility(ctx, &req)
+	if err != nil {
+		// 检查是否为业务错误
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+
+		// 其他内部错误
+		logger.Error(ctx, "设置在线状态可见性服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	result.Success(c, visibilityResp)
+}
+
+// UpdatePrivacy 更新隐私设置接口
+// @Summary 更新隐私设置
+// @Description 设置是否隐藏在线状态与最近活跃时间及隐藏范围（0=所有人 1=仅非好友）
+// @Tags 用户信息接口
+// @Accept json
+// @Produce json
+// @Param request body dto.UpdatePrivacyRequest true "更新隐私设置请求"
+// @Success 200 {object} dto.UpdatePrivacyResponse
+// @Router /api/v1/auth/user/privacy [put]
+func (h *UserHandler) UpdatePrivacy(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	// 1. 绑定请求数据
+	var req dto.UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// 参数错误由客户端输入导致,属于正常业务流程,不记录日志
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	// 2. 调用服务层处理业务逻辑（依赖注入）
+	privacyResp, err := h.userService.UpdatePrivacy(ctx, &req)
+	if err != nil {
+		// 检查是否为业务错误
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+
+		// 其他内部错误
+		logger.Error(ctx, "更新隐私设置服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, utils.ServerErrorCode(err))
+		return
+	}
+
+	// 3. 返回成功响应
+	result.Success(c, privacyResp)
+}
@@ -8,6 +8,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -34,8 +35,9 @@ type fakeUserHTTPService struct {
 	searchUserFn      func(context.Context, *dto.SearchUserRequest) (*dto.SearchUserResponse, error)
 	updateProfileFn   func(context.Context, *dto.UpdateProfileRequest) (*dto.UpdateProfileResponse, error)
 	uploadAvatarFn    func(context.Context, string) (string, error)
-	changePasswordFn  func(context.Context, *dto.ChangePasswordRequest) error
+	changePasswordFn  func(context.Context, *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error)
 	changeEmailFn     func(context.Context, *dto.ChangeEmailRequest) (*dto.ChangeEmailResponse, error)
+	changeTelFn       func(context.Context, *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error)
 	getQRCodeFn       func(context.Context) (*dto.GetQRCodeResponse, error)
 	parseQRCodeFn     func(context.Context, *dto.ParseQRCodeRequest) (*dto.ParseQRCodeResponse, error)
 	batchGetProfileFn func(context.Context, *dto.BatchGetProfileRequest) (*dto.BatchGetProfileResponse, error)
@@ -77,9 +79,9 @@ func (f *fakeUserHTTPService) UploadAvatar(ctx context.Context, avatarURL string
 	return f.uploadAvatarFn(ctx, avatarURL)
 }
 
-func (f *fakeUserHTTPService) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) error {
+func (f *fakeUserHTTPService) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error) {
 	if f.changePasswordFn == nil {
-		return nil
+		return &dto.ChangePasswordResponse{}, nil
 	}
 	return f.changePasswordFn(ctx, req)
 }
@@ -91,6 +93,13 @@ func (f *fakeUserHTTPService) ChangeEmail(ctx context.Context, req *dto.ChangeEm
 	return f.changeEmailFn(ctx, req)
 }
 
+func (f *fakeUserHTTPService) ChangeTelephone(ctx context.Context, req *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error) {
+	if f.changeTelFn == nil {
+		return &dto.ChangeTelephoneResponse{}, nil
+	}
+	return f.changeTelFn(ctx, req)
+}
+
 func (f *fakeUserHTTPService) GetQRCode(ctx context.Context) (*dto.GetQRCodeResponse, error) {
 	if f.getQRCodeFn == nil {
 		return &dto.GetQRCodeResponse{}, nil
@@ -333,6 +342,19 @@ func TestUserHandlerSearchUser(t *testing.T) {
 		assert.Equal(t, consts.CodeSuccess, decodeUserHandlerCode(t, w))
 	})
 
+	t.Run("invalid_search_type_failed", func(t *testing.T) {
+		h := NewUserHandler(&fakeUserHTTPService{})
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/api/v1/auth/user/search?keyword=alice&searchType=phone", nil)
+		require.NoError(t, err)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		h.SearchUser(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeParamError, decodeUserHandlerCode(t, w))
+	})
+
 	t.Run("page_zero_failed", func(t *testing.T) {
 		h := NewUserHandler(&fakeUserHTTPService{})
 		w := httptest.NewRecorder()
@@ -390,8 +412,8 @@ func TestUserHandlerChangePasswordAndUpdateProfile(t *testing.T) {
 
 	t.Run("change_password_business_error", func(t *testing.T) {
 		h := NewUserHandler(&fakeUserHTTPService{
-			changePasswordFn: func(_ context.Context, _ *dto.ChangePasswordRequest) error {
-				return status.Error(codes.Code(consts.CodePasswordError), "biz")
+			changePasswordFn: func(_ context.Context, _ *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error) {
+				return nil, status.Error(codes.Code(consts.CodePasswordError), "biz")
 			},
 		})
 		w := httptest.NewRecorder()
@@ -453,6 +475,21 @@ func TestUserHandlerChangeEmailBatchGetProfileAndQRCode(t *testing.T) {
 		assert.Equal(t, consts.CodeInternalError, decodeUserHandlerCode(t, w))
 	})
 
+	t.Run("change_telephone_business_error", func(t *testing.T) {
+		h := NewUserHandler(&fakeUserHTTPService{
+			changeTelFn: func(_ context.Context, _ *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error) {
+				return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeTelephoneSameAsOld))
+			},
+		})
+		w := httptest.NewRecorder()
+		req := newUserJSONRequest(t, http.MethodPost, "/api/v1/auth/user/change-telephone", `{"newTelephone":"13800138000","verifyCode":"123456"}`)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		h.ChangeTelephone(c)
+		assert.Equal(t, consts.CodeTelephoneSameAsOld, decodeUserHandlerCode(t, w))
+	})
+
 	t.Run("batch_get_profile_param_error", func(t *testing.T) {
 		h := NewUserHandler(&fakeUserHTTPService{})
 		w := httptest.NewRecorder()
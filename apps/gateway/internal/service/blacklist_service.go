@@ -127,7 +127,8 @@ func (s *BlacklistServiceImpl) GetBlacklistList(ctx context.Context, req *dto.Ge
 // batchGetSimpleUserInfo 批量获取用户信息（含去重与分片）
 // 失败时返回错误，由调用方决定是否降级
 func (s *BlacklistServiceImpl) batchGetSimpleUserInfo(ctx context.Context, uuids []string) (map[string]*dto.SimpleUserInfo, error) {
-	const batchSize = 100
+	// 分片大小与 user 服务 BatchGetProfile 上限对齐，避免单片被拒绝
+	const batchSize = consts.BatchGetProfileMaxSize
 	result := make(map[string]*dto.SimpleUserInfo)
 	if len(uuids) == 0 {
 		return result, nil
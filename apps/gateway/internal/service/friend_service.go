@@ -62,6 +62,7 @@ func (s *FriendServiceImpl) GetFriendApplyList(ctx context.Context, req *dto.Get
 		Status:   req.Status,
 		Page:     req.Page,
 		PageSize: req.PageSize,
+		Cursor:   req.Cursor,
 	}
 
 	// 2. 调用用户服务获取好友申请列表(gRPC)
@@ -127,6 +128,7 @@ func (s *FriendServiceImpl) GetSentApplyList(ctx context.Context, req *dto.GetSe
 		Status:   req.Status,
 		Page:     req.Page,
 		PageSize: req.PageSize,
+		Cursor:   req.Cursor,
 	}
 
 	// 2. 调用用户服务获取发出的申请列表(gRPC)
@@ -251,7 +253,7 @@ func (s *FriendServiceImpl) MarkApplyAsRead(ctx context.Context, req *dto.MarkAp
 	grpcReq := dto.ConvertToProtoMarkApplyAsReadRequest(req)
 
 	// 2. 调用用户服务标记申请已读(gRPC)
-	_, err := s.userClient.MarkApplyAsRead(ctx, grpcReq)
+	grpcResp, err := s.userClient.MarkApplyAsRead(ctx, grpcReq)
 	if err != nil {
 		// gRPC 调用失败，提取业务错误码
 		code := utils.ExtractErrorCode(err)
@@ -269,7 +271,7 @@ func (s *FriendServiceImpl) MarkApplyAsRead(ctx context.Context, req *dto.MarkAp
 	}
 
 	// 3. gRPC 调用成功，返回结果
-	return dto.ConvertMarkApplyAsReadResponseFromProto(nil), nil
+	return dto.ConvertMarkApplyAsReadResponseFromProto(grpcResp), nil
 }
 
 // GetFriendList 获取好友列表
@@ -281,6 +283,7 @@ func (s *FriendServiceImpl) GetFriendList(ctx context.Context, req *dto.GetFrien
 		GroupTag: req.GroupTag,
 		Page:     req.Page,
 		PageSize: req.PageSize,
+		Cursor:   req.Cursor,
 	}
 
 	// 2. 调用用户服务获取好友列表(gRPC)
@@ -580,7 +583,8 @@ func (s *FriendServiceImpl) GetRelationStatus(ctx context.Context, req *dto.GetR
 // batchGetSimpleUserInfo 批量获取用户信息（含去重与分片）
 // 失败时返回错误，由调用方决定是否降级
 func (s *FriendServiceImpl) batchGetSimpleUserInfo(ctx context.Context, uuids []string) (map[string]*dto.SimpleUserInfo, error) {
-	const batchSize = 100
+	// 分片大小与 user 服务 BatchGetProfile 上限对齐，避免单片被拒绝
+	const batchSize = consts.BatchGetProfileMaxSize
 	result := make(map[string]*dto.SimpleUserInfo)
 	if len(uuids) == 0 {
 		return result, nil
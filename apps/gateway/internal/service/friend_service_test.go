@@ -328,12 +328,14 @@ func TestGatewayFriendServiceGetFriendListAndSync(t *testing.T) {
 
 	t.Run("get_friend_list_enrich", func(t *testing.T) {
 		svc := NewFriendService(&fakeGatewayFriendClient{
-			getFriendListFn: func(_ context.Context, _ *userpb.GetFriendListRequest) (*userpb.GetFriendListResponse, error) {
+			getFriendListFn: func(_ context.Context, req *userpb.GetFriendListRequest) (*userpb.GetFriendListResponse, error) {
+				assert.Equal(t, "c1", req.Cursor)
 				return &userpb.GetFriendListResponse{
 					Items: []*userpb.FriendItem{
 						{Uuid: "u2"},
 						{Uuid: "u3"},
 					},
+					NextCursor: "c2",
 				}, nil
 			},
 			batchGetProfileFn: func(_ context.Context, req *userpb.BatchGetProfileRequest) (*userpb.BatchGetProfileResponse, error) {
@@ -346,10 +348,11 @@ func TestGatewayFriendServiceGetFriendListAndSync(t *testing.T) {
 				}, nil
 			},
 		})
-		resp, err := svc.GetFriendList(context.Background(), &dto.GetFriendListRequest{Page: 1, PageSize: 20})
+		resp, err := svc.GetFriendList(context.Background(), &dto.GetFriendListRequest{Page: 1, PageSize: 20, Cursor: "c1"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
 		require.Len(t, resp.Items, 2)
+		assert.Equal(t, "c2", resp.NextCursor)
 		assert.Equal(t, "n2", resp.Items[0].Nickname)
 		assert.Equal(t, "a3", resp.Items[1].Avatar)
 	})
@@ -415,10 +418,13 @@ func TestGatewayFriendServiceSimpleMethods(t *testing.T) {
 				return &userpb.GetUnreadApplyCountResponse{UnreadCount: 3}, nil
 			},
 			markApplyAsReadFn: func(_ context.Context, req *userpb.MarkApplyAsReadRequest) (*userpb.MarkApplyAsReadResponse, error) {
-				if len(req.ApplyIds) == 0 {
+				if len(req.ApplyIds) == 0 && !req.MarkAll {
 					return nil, wantErr
 				}
-				return &userpb.MarkApplyAsReadResponse{}, nil
+				if req.MarkAll {
+					return &userpb.MarkApplyAsReadResponse{}, nil
+				}
+				return &userpb.MarkApplyAsReadResponse{UnreadCount: 2}, nil
 			},
 			deleteFriendFn: func(_ context.Context, req *userpb.DeleteFriendRequest) (*userpb.DeleteFriendResponse, error) {
 				if req.UserUuid == "bad" {
@@ -462,9 +468,12 @@ func TestGatewayFriendServiceSimpleMethods(t *testing.T) {
 
 		markResp, markErr := svc.MarkApplyAsRead(context.Background(), &dto.MarkApplyAsReadRequest{ApplyIDs: []int64{1}})
 		require.NoError(t, markErr)
-		require.Nil(t, markResp)
+		require.NotNil(t, markResp)
+		assert.Equal(t, int32(2), markResp.UnreadCount)
 		_, markErrBad := svc.MarkApplyAsRead(context.Background(), &dto.MarkApplyAsReadRequest{})
 		require.ErrorIs(t, markErrBad, wantErr)
+		_, markAllErr := svc.MarkApplyAsRead(context.Background(), &dto.MarkApplyAsReadRequest{MarkAll: true})
+		require.NoError(t, markAllErr)
 
 		delResp, delErr := svc.DeleteFriend(context.Background(), &dto.DeleteFriendRequest{UserUUID: "u2"})
 		require.NoError(t, delErr)
@@ -153,9 +153,11 @@ type UserService interface {
 	// UploadAvatar 上传头像
 	UploadAvatar(ctx context.Context, avatarURL string) (string, error)
 	// ChangePassword 修改密码
-	ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) error
+	ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error)
 	// ChangeEmail 绑定/换绑邮箱
 	ChangeEmail(ctx context.Context, req *dto.ChangeEmailRequest) (*dto.ChangeEmailResponse, error)
+	// ChangeTelephone 绑定/换绑手机
+	ChangeTelephone(ctx context.Context, req *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error)
 	// GetQRCode 获取用户二维码
 	GetQRCode(ctx context.Context) (*dto.GetQRCodeResponse, error)
 	// ParseQRCode 解析二维码
@@ -164,4 +166,9 @@ type UserService interface {
 	BatchGetProfile(ctx context.Context, req *dto.BatchGetProfileRequest) (*dto.BatchGetProfileResponse, error)
 	// DeleteAccount 注销账号
 	DeleteAccount(ctx context.Context, req *dto.DeleteAccountRequest) (*dto.DeleteAccountResponse, error)
+	// SetPresenceVisibility 设置在线状态可见性
+	SetPresenceVisibility(ctx context.Context, req *dto.SetPresenceVisibilityRequest) (*dto.SetPresenceVisibilityResponse, error)
+
+	// UpdatePrivacy 更新隐私设置
+	UpdatePrivacy(ctx context.Context, req *dto.UpdatePrivacyRequest) (*dto.UpdatePrivacyResponse, error)
 }
@@ -6,7 +6,6 @@ import (
 	"ChatServer/apps/gateway/internal/utils"
 	userpb "ChatServer/apps/user/pb"
 	"ChatServer/consts"
-	"ChatServer/pkg/async"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/util"
 	"context"
@@ -80,87 +79,33 @@ func (s *UserServiceImpl) GetOtherProfile(ctx context.Context, req *dto.GetOther
 	// 2. 转换 DTO 为 Protobuf 请求
 	grpcReq := dto.ConvertToProtoGetOtherProfileRequest(req)
 
-	// 3. 并发调用用户服务和好友服务获取信息
-	// 使用goroutine并发调用两个服务
-	type userResult struct {
-		resp *userpb.GetOtherProfileResponse
-		err  error
-	}
-	type friendResult struct {
-		resp *userpb.CheckIsFriendResponse
-		err  error
-	}
-
-	userChan := make(chan userResult, 1)
-	friendChan := make(chan friendResult, 1)
-
-	// 并发调用用户服务（使用协程池，使用 RunSafe 确保 Context 不会被父请求取消）
-	async.RunSafe(ctx, func(asyncCtx context.Context) {
-		grpcResp, err := s.userClient.GetOtherProfile(asyncCtx, grpcReq)
-		userChan <- userResult{resp: grpcResp, err: err}
-	}, 5*time.Second)
-
-	// 并发调用好友服务判断是否为好友（使用协程池）
-	async.RunSafe(ctx, func(asyncCtx context.Context) {
-		friendReq := &userpb.CheckIsFriendRequest{
-			UserUuid: currentUserUUID,
-			PeerUuid: req.UserUUID,
-		}
-		friendResp, err := s.userClient.CheckIsFriend(asyncCtx, friendReq)
-		friendChan <- friendResult{resp: friendResp, err: err}
-	}, 5*time.Second)
-
-	// 等待两个服务调用完成
-	userRes := <-userChan
-	friendRes := <-friendChan
-
-	// 4. 检查用户服务调用结果
-	if userRes.err != nil {
+	// 3. 调用用户服务获取信息（关系判定与字段脱敏由 user 服务统一完成）
+	grpcResp, err := s.userClient.GetOtherProfile(ctx, grpcReq)
+	if err != nil {
 		// gRPC 调用失败，提取业务错误码
-		code := utils.ExtractErrorCode(userRes.err)
+		code := utils.ExtractErrorCode(err)
 		// 记录错误日志
 		if code >= 30000 {
 			logger.Error(ctx, "调用用户服务 gRPC 失败",
-				logger.ErrorField("error", userRes.err),
+				logger.ErrorField("error", err),
 				logger.Int("business_code", code),
 				logger.String("business_message", consts.GetMessage(code)),
 				logger.Duration("duration", time.Since(startTime)),
 			)
 		}
 		// 返回业务错误（作为 Go error 返回，由 Handler 层处理）
-		return nil, userRes.err
+		return nil, err
 	}
 
-	// 5. gRPC 调用成功，检查响应数据
-	if userRes.resp.UserInfo == nil {
+	// 4. gRPC 调用成功，检查响应数据
+	if grpcResp.UserInfo == nil {
 		// 成功返回但 UserInfo 为空，属于非预期的异常情况
 		logger.Error(ctx, "gRPC 成功响应但用户信息为空")
 		return nil, errors.New(strconv.Itoa(consts.CodeInternalError))
 	}
 
-	// 6. 检查好友服务调用结果（非关键错误，只记录日志）
-	isFriend := false
-	if friendRes.err != nil {
-		logger.Warn(ctx, "调用好友服务失败",
-			logger.ErrorField("error", friendRes.err),
-		)
-	} else if friendRes.resp != nil {
-		isFriend = friendRes.resp.IsFriend
-	}
-
-	// 7. 非好友时脱敏邮箱和手机号
-	userInfo := userRes.resp.UserInfo
-	if !isFriend && userInfo.Email != "" {
-		// 脱敏邮箱：只显示前3位和@domain部分
-		userInfo.Email = utils.MaskEmail(userInfo.Email)
-	}
-	if !isFriend && userInfo.Telephone != "" {
-		// 脱敏手机号：只显示前3位和后4位
-		userInfo.Telephone = utils.MaskTelephone(userInfo.Telephone)
-	}
-
-	// 8. 返回用户信息
-	return dto.ConvertGetOtherProfileResponseFromProto(userRes.resp, isFriend), nil
+	// 5. 返回用户信息（已按关系脱敏，此处不再重复脱敏）
+	return dto.ConvertGetOtherProfileResponseFromProto(grpcResp), nil
 }
 
 // SearchUser 搜索用户
@@ -291,15 +236,15 @@ func (s *UserServiceImpl) UpdateProfile(ctx context.Context, req *dto.UpdateProf
 // ChangePassword 修改密码
 // ctx: 请求上下文
 // req: 修改密码请求
-// 返回: 错误
-func (s *UserServiceImpl) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) error {
+// 返回: 修改密码响应（当前设备的新 Access Token）
+func (s *UserServiceImpl) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) (*dto.ChangePasswordResponse, error) {
 	startTime := time.Now()
 
 	// 1. 转换 DTO 为 Protobuf 请求
 	grpcReq := dto.ConvertToProtoChangePasswordRequest(req)
 
 	// 2. 调用用户服务修改密码(gRPC)
-	_, err := s.userClient.ChangePassword(ctx, grpcReq)
+	grpcResp, err := s.userClient.ChangePassword(ctx, grpcReq)
 	if err != nil {
 		// gRPC 调用失败，提取业务错误码
 		code := utils.ExtractErrorCode(err)
@@ -313,10 +258,10 @@ func (s *UserServiceImpl) ChangePassword(ctx context.Context, req *dto.ChangePas
 			)
 		}
 		// 返回业务错误（作为 Go error 返回，由 Handler 层处理）
-		return err
+		return nil, err
 	}
 
-	return nil
+	return dto.ConvertChangePasswordResponseFromProto(grpcResp), nil
 }
 
 // ChangeEmail 绑定/换绑邮箱
@@ -350,6 +295,37 @@ func (s *UserServiceImpl) ChangeEmail(ctx context.Context, req *dto.ChangeEmailR
 	return dto.ConvertChangeEmailResponseFromProto(grpcResp), nil
 }
 
+// ChangeTelephone 绑定/换绑手机
+// ctx: 请求上下文
+// req: 换绑手机请求
+// 返回: 换绑手机响应
+func (s *UserServiceImpl) ChangeTelephone(ctx context.Context, req *dto.ChangeTelephoneRequest) (*dto.ChangeTelephoneResponse, error) {
+	startTime := time.Now()
+
+	// 1. 转换 DTO 为 Protobuf 请求
+	grpcReq := dto.ConvertToProtoChangeTelephoneRequest(req)
+
+	// 2. 调用用户服务换绑手机(gRPC)
+	grpcResp, err := s.userClient.ChangeTelephone(ctx, grpcReq)
+	if err != nil {
+		// gRPC 调用失败，提取业务错误码
+		code := utils.ExtractErrorCode(err)
+		// 记录错误日志
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		// 返回业务错误（作为 Go error 返回，由 Handler 层处理）
+		return nil, err
+	}
+
+	return dto.ConvertChangeTelephoneResponseFromProto(grpcResp), nil
+}
+
 // UploadAvatar 上传头像
 // ctx: 请求上下文
 // avatarURL: 头像URL（已上传到MinIO）
@@ -513,3 +489,65 @@ func (s *UserServiceImpl) DeleteAccount(ctx context.Context, req *dto.DeleteAcco
 
 	return dto.ConvertDeleteAccountResponseFromProto(grpcResp), nil
 }
+
+// SetPresenceVisibility 设置在线状态可见性
+// ctx: 请求上下文
+// req: 设置在线状态可见性请求
+// 返回: 当前可见性设置
+func (s *UserServiceImpl) SetPresenceVisibility(ctx context.Context, req *dto.SetPresenceVisibilityRequest) (*dto.SetPresenceVisibilityResponse, error) {
+	startTime := time.Now()
+
+	// 1. 转换 DTO 为 Protobuf 请求
+	grpcReq := dto.ConvertToProtoSetPresenceVisibilityRequest(req)
+
+	// 2. 调用用户服务设置在线状态可见性(gRPC)
+	grpcResp, err := s.userClient.SetPresenceVisibility(ctx, grpcReq)
+	if err != nil {
+		// gRPC 调用失败，提取业务错误码
+		code := utils.ExtractErrorCode(err)
+		// 记录错误日志
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		// 返回业务错误（作为 Go error 返回，由 Handler 层处理）
+		return nil, err
+	}
+
+	return dto.ConvertSetPresenceVisibilityResponseFromProto(grpcResp), nil
+}
+
+// UpdatePrivacy 更新隐私设置
+// ctx: 请求上下文
+// req: 更新隐私设置请求
+// 返回: 更新后的隐私设置
+func (s *UserServiceImpl) UpdatePrivacy(ctx context.Context, req *dto.UpdatePrivacyRequest) (*dto.UpdatePrivacyResponse, error) {
+	startTime := time.Now()
+
+	// 1. 转换 DTO 为 Protobuf 请求
+	grpcReq := dto.ConvertToProtoUpdatePrivacyRequest(req)
+
+	// 2. 调用用户服务更新隐私设置(gRPC)
+	grpcResp, err := s.userClient.UpdatePrivacy(ctx, grpcReq)
+	if err != nil {
+		// gRPC 调用失败，提取业务错误码
+		code := utils.ExtractErrorCode(err)
+		// 记录错误日志
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		// 返回业务错误（作为 Go error 返回，由 Handler 层处理）
+		return nil, err
+	}
+
+	return dto.ConvertUpdatePrivacyResponseFromProto(grpcResp), nil
+}
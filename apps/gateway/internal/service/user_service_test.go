@@ -9,7 +9,6 @@ import (
 
 	"ChatServer/apps/gateway/internal/dto"
 	gatewaypb "ChatServer/apps/gateway/internal/pb"
-	"ChatServer/apps/gateway/internal/utils"
 	userpb "ChatServer/apps/user/pb"
 	"ChatServer/config"
 	"ChatServer/consts"
@@ -41,6 +40,7 @@ type fakeGatewayUserServiceClient struct {
 	updateProfileFn    func(context.Context, *userpb.UpdateProfileRequest) (*userpb.UpdateProfileResponse, error)
 	changePasswordFn   func(context.Context, *userpb.ChangePasswordRequest) (*userpb.ChangePasswordResponse, error)
 	changeEmailFn      func(context.Context, *userpb.ChangeEmailRequest) (*userpb.ChangeEmailResponse, error)
+	changeTelFn        func(context.Context, *userpb.ChangeTelephoneRequest) (*userpb.ChangeTelephoneResponse, error)
 	uploadAvatarFn     func(context.Context, *userpb.UploadAvatarRequest) (*userpb.UploadAvatarResponse, error)
 	batchGetProfileFn  func(context.Context, *userpb.BatchGetProfileRequest) (*userpb.BatchGetProfileResponse, error)
 	getQRCodeFn        func(context.Context, *userpb.GetQRCodeRequest) (*userpb.GetQRCodeResponse, error)
@@ -104,6 +104,13 @@ func (f *fakeGatewayUserServiceClient) ChangeEmail(ctx context.Context, req *use
 	return f.changeEmailFn(ctx, req)
 }
 
+func (f *fakeGatewayUserServiceClient) ChangeTelephone(ctx context.Context, req *userpb.ChangeTelephoneRequest) (*userpb.ChangeTelephoneResponse, error) {
+	if f.changeTelFn == nil {
+		return nil, errors.New("unexpected ChangeTelephone call")
+	}
+	return f.changeTelFn(ctx, req)
+}
+
 func (f *fakeGatewayUserServiceClient) UploadAvatar(ctx context.Context, req *userpb.UploadAvatarRequest) (*userpb.UploadAvatarResponse, error) {
 	if f.uploadAvatarFn == nil {
 		return nil, errors.New("unexpected UploadAvatar call")
@@ -206,11 +213,6 @@ func TestGatewayUserServiceGetOtherProfile(t *testing.T) {
 				require.Equal(t, "u2", req.UserUuid)
 				return nil, wantErr
 			},
-			checkIsFriendFn: func(_ context.Context, req *userpb.CheckIsFriendRequest) (*userpb.CheckIsFriendResponse, error) {
-				require.Equal(t, "u1", req.UserUuid)
-				require.Equal(t, "u2", req.PeerUuid)
-				return &userpb.CheckIsFriendResponse{IsFriend: false}, nil
-			},
 		})
 
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
@@ -224,9 +226,6 @@ func TestGatewayUserServiceGetOtherProfile(t *testing.T) {
 			getOtherProfileFn: func(_ context.Context, _ *userpb.GetOtherProfileRequest) (*userpb.GetOtherProfileResponse, error) {
 				return &userpb.GetOtherProfileResponse{}, nil
 			},
-			checkIsFriendFn: func(_ context.Context, _ *userpb.CheckIsFriendRequest) (*userpb.CheckIsFriendResponse, error) {
-				return &userpb.CheckIsFriendResponse{IsFriend: true}, nil
-			},
 		})
 
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
@@ -235,20 +234,18 @@ func TestGatewayUserServiceGetOtherProfile(t *testing.T) {
 		require.EqualError(t, err, strconv.Itoa(consts.CodeInternalError))
 	})
 
-	t.Run("friend_service_error_downgrade_and_mask", func(t *testing.T) {
+	t.Run("stranger_fields_passthrough_without_double_mask", func(t *testing.T) {
 		svc := NewUserService(&fakeGatewayUserServiceClient{
 			getOtherProfileFn: func(_ context.Context, _ *userpb.GetOtherProfileRequest) (*userpb.GetOtherProfileResponse, error) {
 				return &userpb.GetOtherProfileResponse{
 					UserInfo: &userpb.UserInfo{
 						Uuid:      "u2",
-						Email:     "alice@example.com",
-						Telephone: "13800138000",
+						Email:     "a***@example.com",
+						Telephone: "138****8000",
 					},
+					Relation: "stranger",
 				}, nil
 			},
-			checkIsFriendFn: func(_ context.Context, _ *userpb.CheckIsFriendRequest) (*userpb.CheckIsFriendResponse, error) {
-				return nil, errors.New("friend service failed")
-			},
 		})
 
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
@@ -257,11 +254,12 @@ func TestGatewayUserServiceGetOtherProfile(t *testing.T) {
 		require.NotNil(t, resp)
 		require.NotNil(t, resp.UserInfo)
 		assert.False(t, resp.IsFriend)
-		assert.Equal(t, utils.MaskEmail("alice@example.com"), resp.UserInfo.Email)
-		assert.Equal(t, utils.MaskTelephone("13800138000"), resp.UserInfo.Telephone)
+		assert.Equal(t, "stranger", resp.Relation)
+		assert.Equal(t, "a***@example.com", resp.UserInfo.Email)
+		assert.Equal(t, "138****8000", resp.UserInfo.Telephone)
 	})
 
-	t.Run("friend_true_no_mask", func(t *testing.T) {
+	t.Run("friend_relation_passthrough", func(t *testing.T) {
 		svc := NewUserService(&fakeGatewayUserServiceClient{
 			getOtherProfileFn: func(_ context.Context, _ *userpb.GetOtherProfileRequest) (*userpb.GetOtherProfileResponse, error) {
 				return &userpb.GetOtherProfileResponse{
@@ -270,11 +268,10 @@ func TestGatewayUserServiceGetOtherProfile(t *testing.T) {
 						Email:     "alice@example.com",
 						Telephone: "13800138000",
 					},
+					Relation: "friend",
+					IsFriend: true,
 				}, nil
 			},
-			checkIsFriendFn: func(_ context.Context, _ *userpb.CheckIsFriendRequest) (*userpb.CheckIsFriendResponse, error) {
-				return &userpb.CheckIsFriendResponse{IsFriend: true}, nil
-			},
 		})
 
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
@@ -283,6 +280,7 @@ func TestGatewayUserServiceGetOtherProfile(t *testing.T) {
 		require.NotNil(t, resp)
 		require.NotNil(t, resp.UserInfo)
 		assert.True(t, resp.IsFriend)
+		assert.Equal(t, "friend", resp.Relation)
 		assert.Equal(t, "alice@example.com", resp.UserInfo.Email)
 		assert.Equal(t, "13800138000", resp.UserInfo.Telephone)
 	})
@@ -333,6 +331,18 @@ func TestGatewayUserServiceSearchUser(t *testing.T) {
 		assert.False(t, called)
 	})
 
+	t.Run("search_type_forwarded", func(t *testing.T) {
+		svc := NewUserService(&fakeGatewayUserServiceClient{
+			searchUserFn: func(_ context.Context, req *userpb.SearchUserRequest) (*userpb.SearchUserResponse, error) {
+				require.Equal(t, userpb.SearchUserType_SEARCH_USER_TYPE_EMAIL, req.SearchType)
+				return &userpb.SearchUserResponse{}, nil
+			},
+		})
+
+		_, err := svc.SearchUser(context.Background(), &dto.SearchUserRequest{Keyword: "alice@example.com", SearchType: "email"})
+		require.NoError(t, err)
+	})
+
 	t.Run("batch_friend_check_with_dedup", func(t *testing.T) {
 		svc := NewUserService(&fakeGatewayUserServiceClient{
 			searchUserFn: func(_ context.Context, _ *userpb.SearchUserRequest) (*userpb.SearchUserResponse, error) {
@@ -429,18 +439,23 @@ func TestGatewayUserServiceOtherMethods(t *testing.T) {
 				if req.OldPassword == "bad" {
 					return nil, wantErr
 				}
-				return &userpb.ChangePasswordResponse{}, nil
+				return &userpb.ChangePasswordResponse{AccessToken: "new-token", TokenType: "Bearer", ExpiresIn: 7200}, nil
 			},
 		})
 
-		require.NoError(t, svc.ChangePassword(context.Background(), &dto.ChangePasswordRequest{
+		resp, err := svc.ChangePassword(context.Background(), &dto.ChangePasswordRequest{
 			OldPassword: "old",
 			NewPassword: "new",
-		}))
-		require.ErrorIs(t, svc.ChangePassword(context.Background(), &dto.ChangePasswordRequest{
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &dto.ChangePasswordResponse{AccessToken: "new-token", TokenType: "Bearer", ExpiresIn: 7200}, resp)
+
+		resp, err = svc.ChangePassword(context.Background(), &dto.ChangePasswordRequest{
 			OldPassword: "bad",
 			NewPassword: "new",
-		}), wantErr)
+		})
+		require.ErrorIs(t, err, wantErr)
+		assert.Nil(t, resp)
 	})
 
 	t.Run("change_email_success_and_error", func(t *testing.T) {
@@ -464,6 +479,27 @@ func TestGatewayUserServiceOtherMethods(t *testing.T) {
 		require.ErrorIs(t, err, wantErr)
 	})
 
+	t.Run("change_telephone_success_and_error", func(t *testing.T) {
+		wantErr := errors.New("change telephone failed")
+		svc := NewUserService(&fakeGatewayUserServiceClient{
+			changeTelFn: func(_ context.Context, req *userpb.ChangeTelephoneRequest) (*userpb.ChangeTelephoneResponse, error) {
+				if req.NewTelephone == "13700137000" {
+					return nil, wantErr
+				}
+				return &userpb.ChangeTelephoneResponse{Telephone: req.NewTelephone}, nil
+			},
+		})
+
+		okResp, okErr := svc.ChangeTelephone(context.Background(), &dto.ChangeTelephoneRequest{NewTelephone: "13900139000", VerifyCode: "123456"})
+		require.NoError(t, okErr)
+		require.NotNil(t, okResp)
+		assert.Equal(t, "13900139000", okResp.Telephone)
+
+		errResp, err := svc.ChangeTelephone(context.Background(), &dto.ChangeTelephoneRequest{NewTelephone: "13700137000", VerifyCode: "123456"})
+		require.Nil(t, errResp)
+		require.ErrorIs(t, err, wantErr)
+	})
+
 	t.Run("upload_avatar_success_and_error", func(t *testing.T) {
 		wantErr := errors.New("upload avatar failed")
 		svc := NewUserService(&fakeGatewayUserServiceClient{
@@ -23,3 +23,12 @@ func ExtractErrorCode(err error) int {
 
 	return consts.CodeInternalError
 }
+
+// ServerErrorCode 返回服务端错误对外暴露的错误码。
+// 下游熔断/不可用（CodeServiceUnavailable）原样透出，便于客户端区分“稍后重试”；其余统一为 CodeInternalError。
+func ServerErrorCode(err error) int {
+	if ExtractErrorCode(err) == consts.CodeServiceUnavailable {
+		return consts.CodeServiceUnavailable
+	}
+	return consts.CodeInternalError
+}
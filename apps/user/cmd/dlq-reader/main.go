@@ -0,0 +1,50 @@
+// dlq-reader 死信队列排查工具
+// 从 Redis 重试死信 topic 读取重试耗尽的任务并按 JSON Lines 输出到标准输出，
+// 只读、不提交 offset，不会影响线上消费者，也不会删除死信消息。
+//
+// 用法:
+//
+//	go run ./apps/user/cmd/dlq-reader -limit 20 -source DeviceRepository.DeleteTokens
+//
+// Kafka 地址与死信 topic 取自 KAFKA_BROKERS / KAFKA_RETRY_DLQ_TOPIC。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"ChatServer/apps/user/mq"
+	"ChatServer/config"
+)
+
+func main() {
+	limit := flag.Int("limit", 50, "最多读取的死信消息条数（<=0 表示不限制，直到超时）")
+	source := flag.String("source", "", "只输出指定来源（WithSource）的任务")
+	timeout := flag.Duration("timeout", 10*time.Second, "读取超时时间，超时视为已读到末尾")
+	groupID := flag.String("group", "redis-retry-dlq-inspector", "检查使用的消费者组（不会提交 offset）")
+	flag.Parse()
+
+	kafkaCfg := config.DefaultKafkaConfig()
+	reader := mq.NewDeadLetterReader(kafkaCfg.Brokers, kafkaCfg.RedisRetryDLQTopic, *groupID)
+	defer reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	tasks, skipped, err := reader.List(ctx, *limit, *source)
+	if err != nil {
+		log.Fatalf("读取死信队列失败: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, task := range tasks {
+		if err := enc.Encode(task); err != nil {
+			log.Fatalf("输出死信任务失败: %v", err)
+		}
+	}
+	log.Printf("topic=%s 输出 %d 条死信任务，跳过 %d 条无法解析的消息", kafkaCfg.RedisRetryDLQTopic, len(tasks), skipped)
+}
@@ -11,6 +11,7 @@ import (
 	"ChatServer/apps/user/internal/handler"
 	"ChatServer/apps/user/internal/repository"
 	"ChatServer/apps/user/internal/service"
+	"ChatServer/apps/user/internal/utils"
 	"ChatServer/apps/user/mq"
 	userpb "ChatServer/apps/user/pb"
 	"ChatServer/config"
@@ -20,6 +21,7 @@ import (
 	"ChatServer/pkg/grpcx"
 	"ChatServer/pkg/kafka"
 	"ChatServer/pkg/logger"
+	"ChatServer/pkg/metrics"
 	"ChatServer/pkg/mysql"
 	pkgredis "ChatServer/pkg/redis"
 	"ChatServer/pkg/util"
@@ -41,6 +43,9 @@ func main() {
 	logger.ReplaceGlobal(zl)
 	defer zl.Sync()
 
+	// 1.1 指标公共标签（/metrics 中所有指标携带 service / version）
+	metrics.Init("user", config.DefaultMetricsConfig().Version)
+
 	// 1.2 初始化验证码邮件配置（授权码仅从环境变量读取，避免硬编码密钥）
 	initVerifyEmailConfig(ctx)
 
@@ -90,6 +95,7 @@ func main() {
 
 	// 4. 初始化 Kafka（仅在 Redis 可用时启动）
 	var kafkaProducer *kafka.Producer
+	var dlqProducer *kafka.Producer
 	var redisConsumer *mq.RedisRetryConsumer
 	if redisClient != nil {
 		kafkaCfg := config.DefaultKafkaConfig()
@@ -102,6 +108,12 @@ func main() {
 			logger.String("topic", kafkaCfg.RedisRetryTopic),
 		)
 
+		// 创建死信队列 Producer（重试耗尽的任务投递至此）
+		dlqProducer = kafka.NewProducer(kafkaCfg.Brokers, kafkaCfg.RedisRetryDLQTopic)
+		logger.Info(ctx, "Kafka 死信队列 Producer 初始化成功",
+			logger.String("topic", kafkaCfg.RedisRetryDLQTopic),
+		)
+
 		// 创建 Redis 重试消费者
 		zapLogger := kafka.NewZapLoggerAdapter(logger.L())
 		redisConsumer = mq.NewRedisRetryConsumer(
@@ -110,8 +122,13 @@ func main() {
 			kafkaCfg.ConsumerConfig.GroupID,
 			redisClient,
 			kafkaProducer,
+			dlqProducer,
 			zapLogger,
 		)
+		redisConsumer.SetRetryBackoff(mq.RetryBackoff{
+			Base: kafkaCfg.RedisRetryBackoffBase,
+			Max:  kafkaCfg.RedisRetryBackoffMax,
+		})
 
 		// 启动消费者（在后台 goroutine 中运行）
 		go func() {
@@ -124,16 +141,21 @@ func main() {
 			}
 		}()
 
-		// 确保程序退出时关闭 Kafka 连接
+		// 确保程序退出时关闭 Kafka 连接（先关闭消费者：未到期的延迟任务需经 Producer 写回重试队列）
 		defer func() {
+			if redisConsumer != nil {
+				if err := redisConsumer.Close(); err != nil {
+					logger.Error(ctx, "关闭 Redis 重试消费者失败", logger.ErrorField("error", err))
+				}
+			}
 			if kafkaProducer != nil {
 				if err := kafkaProducer.Close(); err != nil {
 					logger.Error(ctx, "关闭 Kafka Producer 失败", logger.ErrorField("error", err))
 				}
 			}
-			if redisConsumer != nil {
-				if err := redisConsumer.Close(); err != nil {
-					logger.Error(ctx, "关闭 Redis 重试消费者失败", logger.ErrorField("error", err))
+			if dlqProducer != nil {
+				if err := dlqProducer.Close(); err != nil {
+					logger.Error(ctx, "关闭 Kafka 死信队列 Producer 失败", logger.ErrorField("error", err))
 				}
 			}
 		}()
@@ -152,16 +174,49 @@ func main() {
 	authRepo := repository.NewAuthRepository(db, redisClient)
 	userRepo := repository.NewUserRepository(db, redisClient)
 	friendRepo := repository.NewFriendRepository(db, redisClient)
-	applyRepo := repository.NewApplyRepository(db, redisClient)
+	friendApplyCfg := config.DefaultFriendApplyConfig()
+	applyRepo := repository.NewApplyRepository(db, redisClient, friendApplyCfg.ExpireAfter)
 	blacklistRepo := repository.NewBlacklistRepository(db, redisClient)
 	deviceRepo := repository.NewDeviceRepository(db, redisClient)
 
+	// 5.5 初始化二维码签名器（加好友二维码）
+	qrCodeCfg := config.DefaultQRCodeConfig()
+	if err := qrCodeCfg.Validate(); err != nil {
+		log.Fatalf("二维码签名配置无效: %v", err)
+	}
+	qrSigner := utils.NewQRCodeSigner(qrCodeCfg.Secret, qrCodeCfg.TTL)
+
+	// 5.6 验证码格式（长度/字符集，可按验证码类型覆盖）
+	verifyCodeCfg := config.DefaultVerifyCodeConfig()
+	util.SetVerifyCodeSpecs(verifyCodeCfg.Default, verifyCodeCfg.ByType)
+
+	// 5.65 Token 有效期（Access/Refresh）
+	jwtCfg := config.DefaultJWTConfig()
+	util.SetTokenTTL(jwtCfg.AccessTTL, jwtCfg.RefreshTTL)
+
+	// 5.7 用户搜索（关键词最小长度、分页默认值与上限）
+	searchCfg := config.DefaultUserSearchConfig()
+
 	// 6. 组装依赖 - Service 层
 	authService := service.NewAuthService(authRepo, deviceRepo)
-	userService := service.NewUserService(userRepo, authRepo, deviceRepo)
+	accountDeleteCfg := config.DefaultAccountDeleteConfig()
+	userService := service.NewUserService(userRepo, authRepo, deviceRepo, friendRepo, applyRepo, qrSigner, accountDeleteCfg.GracePeriod, searchCfg)
 	friendService := service.NewFriendService(friendRepo, applyRepo, blacklistRepo)
 	blacklistService := service.NewBlacklistService(blacklistRepo)
-	deviceService := service.NewDeviceService(deviceRepo)
+	deviceService := service.NewDeviceService(deviceRepo, userRepo, friendRepo)
+
+	// 6.1 好友申请过期扫描（到期的待处理申请置为过期并移出待处理缓存）
+	service.StartApplyExpireSweeper(ctx, applyRepo, friendApplyCfg.SweepInterval, friendApplyCfg.SweepBatchSize)
+	logger.Info(ctx, "User 好友申请过期配置已加载",
+		logger.Duration("expire_after", friendApplyCfg.ExpireAfter),
+		logger.Duration("sweep_interval", friendApplyCfg.SweepInterval),
+	)
+
+	// 6.2 隐藏在线状态集合校准（以 MySQL 为准恢复 user:presence:hidden）
+	if redisClient != nil {
+		presencePrivacyCfg := config.DefaultPresencePrivacyConfig()
+		service.StartPresenceHiddenRebuilder(ctx, userRepo, presencePrivacyCfg.HiddenRebuildInterval)
+	}
 
 	// 7. 组装依赖 - Handler 层
 	authHandler := handler.NewAuthHandler(authService)
@@ -176,25 +231,33 @@ func main() {
 	// 9. 启动 Metrics HTTP Server（暴露 Prometheus 指标）。
 	// 注意：必须在 grpcx.Start 之前启动，因为 Start 是阻塞调用。
 	metricsMux := http.NewServeMux()
-	metricsMux.Handle("/metrics", grpcx.DefaultHandler())
+	metricsMux.Handle("/metrics", metrics.Handler())
+	// 运行期调整日志级别（GET/PUT /admin/loglevel），未配置 METRICS_ADMIN_TOKEN 时不挂载。
+	if adminToken := config.DefaultMetricsConfig().AdminToken; adminToken != "" {
+		metricsMux.Handle("/admin/loglevel", logger.LevelHandler(adminToken))
+	}
 
 	metricsAddr := os.Getenv("USER_METRICS_ADDR")
 	if metricsAddr == "" {
 		metricsAddr = ":9091"
 	}
-	metricsServer := &http.Server{
-		Addr:    metricsAddr,
-		Handler: metricsMux,
+	// 同步监听：端口占用等错误在启动阶段直接退出，避免服务在无监控的状态下运行。
+	metricsSrv, err := startMetricsServer(ctx, metricsAddr, metricsMux)
+	if err != nil {
+		log.Fatalf("启动 Metrics HTTP Server 失败: %v", err)
 	}
-
-	go func() {
-		logger.Info(ctx, "Metrics HTTP Server 启动中", logger.String("address", metricsAddr))
-		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error(ctx, "Metrics HTTP Server 启动失败", logger.ErrorField("error", err))
+	// gRPC 服务停止后（收到 SIGINT/SIGTERM）关闭 Metrics HTTP Server
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer shutdownCancel()
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Error(ctx, "Metrics HTTP Server 关闭失败", logger.ErrorField("error", err))
+			return
 		}
+		logger.Info(ctx, "Metrics HTTP Server 已关闭")
 	}()
 
-	// 10. 启动 gRPC Server（阻塞直到服务停止）。
+	// 10. 启动 gRPC Server（阻塞直到收到 SIGINT/SIGTERM 并优雅停机）。
 	grpcAddr := os.Getenv("USER_GRPC_ADDR")
 	if grpcAddr == "" {
 		grpcAddr = ":9090"
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"ChatServer/pkg/logger"
+)
+
+// metricsShutdownTimeout 退出时等待 Metrics HTTP Server 关闭的最长时间。
+const metricsShutdownTimeout = 5 * time.Second
+
+// metricsServer 封装 Metrics HTTP Server 的启动与关闭。
+// 启动时同步监听端口，端口占用等错误直接返回给调用方，而不是在后台 goroutine 中只记录日志。
+type metricsServer struct {
+	server   *http.Server
+	listener net.Listener
+	done     chan struct{}
+}
+
+// startMetricsServer 监听 addr 并在后台提供 handler 服务。
+// 监听失败返回错误；监听成功后 Serve 的非 ErrServerClosed 错误只记录日志。
+func startMetricsServer(ctx context.Context, addr string, handler http.Handler) (*metricsServer, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &metricsServer{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		listener: lis,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(m.done)
+		logger.Info(ctx, "Metrics HTTP Server 启动中", logger.String("address", lis.Addr().String()))
+		if err := m.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(ctx, "Metrics HTTP Server 运行错误", logger.ErrorField("error", err))
+		}
+	}()
+	return m, nil
+}
+
+// Addr 返回实际监听地址（addr 端口为 0 时由系统分配）。
+func (m *metricsServer) Addr() string {
+	return m.listener.Addr().String()
+}
+
+// Shutdown 优雅关闭 Metrics HTTP Server，关闭监听并等待 Serve 退出。
+// ctx 超时后强制关闭剩余连接。
+func (m *metricsServer) Shutdown(ctx context.Context) error {
+	err := m.server.Shutdown(ctx)
+	if err != nil {
+		_ = m.server.Close()
+	}
+	<-m.done
+	return err
+}
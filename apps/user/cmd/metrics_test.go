@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMetricsServerShutdownClosesListener(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	m, err := startMetricsServer(context.Background(), "127.0.0.1:0", mux)
+	require.NoError(t, err)
+	addr := m.Addr()
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, m.Shutdown(ctx))
+
+	_, err = net.DialTimeout("tcp", addr, 200*time.Millisecond)
+	assert.Error(t, err, "listener should be closed after shutdown")
+}
+
+func TestStartMetricsServerFailsWhenPortInUse(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	m, err := startMetricsServer(context.Background(), lis.Addr().String(), http.NewServeMux())
+	assert.Error(t, err)
+	assert.Nil(t, m)
+}
@@ -28,6 +28,17 @@ func ModelToProtoUserInfo(user *model.UserInfo) *pb.UserInfo {
 	}
 }
 
+// ModelToProtoPrivacySettings 提取用户隐私设置（仅用于本人资料）
+func ModelToProtoPrivacySettings(user *model.UserInfo) *pb.PrivacySettings {
+	if user == nil {
+		return nil
+	}
+	return &pb.PrivacySettings{
+		HidePresence:      user.HidePresence == 1,
+		PresenceHideScope: int32(user.PresenceHideScope),
+	}
+}
+
 // ModelListToProtoUserInfoList 批量转换 UserInfo
 func ModelListToProtoUserInfoList(users []*model.UserInfo) []*pb.UserInfo {
 	if users == nil {
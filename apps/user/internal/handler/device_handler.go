@@ -30,6 +30,11 @@ func (h *DeviceHandler) KickDevice(ctx context.Context, req *pb.KickDeviceReques
 	return &pb.KickDeviceResponse{}, h.deviceService.KickDevice(ctx, req)
 }
 
+// BatchKickDevices 批量踢出设备
+func (h *DeviceHandler) BatchKickDevices(ctx context.Context, req *pb.BatchKickDevicesRequest) (*pb.BatchKickDevicesResponse, error) {
+	return h.deviceService.BatchKickDevices(ctx, req)
+}
+
 // GetOnlineStatus 获取用户在线状态
 func (h *DeviceHandler) GetOnlineStatus(ctx context.Context, req *pb.GetOnlineStatusRequest) (*pb.GetOnlineStatusResponse, error) {
 	return h.deviceService.GetOnlineStatus(ctx, req)
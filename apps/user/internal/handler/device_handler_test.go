@@ -15,8 +15,10 @@ import (
 type fakeDeviceHandlerService struct {
 	getDeviceListFn        func(context.Context, *pb.GetDeviceListRequest) (*pb.GetDeviceListResponse, error)
 	kickDeviceFn           func(context.Context, *pb.KickDeviceRequest) error
+	batchKickDevicesFn     func(context.Context, *pb.BatchKickDevicesRequest) (*pb.BatchKickDevicesResponse, error)
 	getOnlineStatusFn      func(context.Context, *pb.GetOnlineStatusRequest) (*pb.GetOnlineStatusResponse, error)
 	batchGetOnlineStatusFn func(context.Context, *pb.BatchGetOnlineStatusRequest) (*pb.BatchGetOnlineStatusResponse, error)
+	batchGetChunkedFn      func(context.Context, []string) ([]*pb.OnlineStatusItem, error)
 	updateDeviceActiveFn   func(context.Context, *pb.UpdateDeviceActiveRequest) error
 	updateDeviceStatusFn   func(context.Context, *pb.UpdateDeviceStatusRequest) error
 }
@@ -37,6 +39,13 @@ func (f *fakeDeviceHandlerService) KickDevice(ctx context.Context, req *pb.KickD
 	return f.kickDeviceFn(ctx, req)
 }
 
+func (f *fakeDeviceHandlerService) BatchKickDevices(ctx context.Context, req *pb.BatchKickDevicesRequest) (*pb.BatchKickDevicesResponse, error) {
+	if f.batchKickDevicesFn == nil {
+		return &pb.BatchKickDevicesResponse{}, nil
+	}
+	return f.batchKickDevicesFn(ctx, req)
+}
+
 func (f *fakeDeviceHandlerService) GetOnlineStatus(ctx context.Context, req *pb.GetOnlineStatusRequest) (*pb.GetOnlineStatusResponse, error) {
 	if f.getOnlineStatusFn == nil {
 		return &pb.GetOnlineStatusResponse{}, nil
@@ -51,6 +60,13 @@ func (f *fakeDeviceHandlerService) BatchGetOnlineStatus(ctx context.Context, req
 	return f.batchGetOnlineStatusFn(ctx, req)
 }
 
+func (f *fakeDeviceHandlerService) BatchGetOnlineStatusChunked(ctx context.Context, userUUIDs []string) ([]*pb.OnlineStatusItem, error) {
+	if f.batchGetChunkedFn == nil {
+		return []*pb.OnlineStatusItem{}, nil
+	}
+	return f.batchGetChunkedFn(ctx, userUUIDs)
+}
+
 func (f *fakeDeviceHandlerService) UpdateDeviceStatus(ctx context.Context, req *pb.UpdateDeviceStatusRequest) error {
 	if f.updateDeviceStatusFn == nil {
 		return nil
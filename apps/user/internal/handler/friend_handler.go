@@ -47,7 +47,7 @@ func (h *FriendHandler) GetUnreadApplyCount(ctx context.Context, req *pb.GetUnre
 
 // MarkApplyAsRead 标记申请已读
 func (h *FriendHandler) MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) (*pb.MarkApplyAsReadResponse, error) {
-	return &pb.MarkApplyAsReadResponse{}, h.friendService.MarkApplyAsRead(ctx, req)
+	return h.friendService.MarkApplyAsRead(ctx, req)
 }
 
 // GetFriendList 获取好友列表
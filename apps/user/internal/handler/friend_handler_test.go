@@ -18,7 +18,7 @@ type fakeFriendHandlerService struct {
 	sentApplyListFn  func(context.Context, *pb.GetSentApplyListRequest) (*pb.GetSentApplyListResponse, error)
 	handleApplyFn    func(context.Context, *pb.HandleFriendApplyRequest) error
 	unreadCountFn    func(context.Context, *pb.GetUnreadApplyCountRequest) (*pb.GetUnreadApplyCountResponse, error)
-	markReadFn       func(context.Context, *pb.MarkApplyAsReadRequest) error
+	markReadFn       func(context.Context, *pb.MarkApplyAsReadRequest) (*pb.MarkApplyAsReadResponse, error)
 	friendListFn     func(context.Context, *pb.GetFriendListRequest) (*pb.GetFriendListResponse, error)
 	syncFn           func(context.Context, *pb.SyncFriendListRequest) (*pb.SyncFriendListResponse, error)
 	deleteFn         func(context.Context, *pb.DeleteFriendRequest) error
@@ -67,9 +67,9 @@ func (f *fakeFriendHandlerService) GetUnreadApplyCount(ctx context.Context, req
 	return f.unreadCountFn(ctx, req)
 }
 
-func (f *fakeFriendHandlerService) MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) error {
+func (f *fakeFriendHandlerService) MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) (*pb.MarkApplyAsReadResponse, error) {
 	if f.markReadFn == nil {
-		return nil
+		return &pb.MarkApplyAsReadResponse{}, nil
 	}
 	return f.markReadFn(ctx, req)
 }
@@ -283,7 +283,9 @@ func TestUserFriendHandlerEmptyResponseContracts(t *testing.T) {
 
 	t.Run("mark_read_delete_remark_tag", func(t *testing.T) {
 		h := NewFriendHandler(&fakeFriendHandlerService{
-			markReadFn: func(_ context.Context, _ *pb.MarkApplyAsReadRequest) error { return nil },
+			markReadFn: func(_ context.Context, _ *pb.MarkApplyAsReadRequest) (*pb.MarkApplyAsReadResponse, error) {
+				return &pb.MarkApplyAsReadResponse{UnreadCount: 2}, nil
+			},
 			deleteFn:   func(_ context.Context, _ *pb.DeleteFriendRequest) error { return nil },
 			remarkFn:   func(_ context.Context, _ *pb.SetFriendRemarkRequest) error { return nil },
 			tagFn:      func(_ context.Context, _ *pb.SetFriendTagRequest) error { return nil },
@@ -291,7 +293,7 @@ func TestUserFriendHandlerEmptyResponseContracts(t *testing.T) {
 
 		markResp, markErr := h.MarkApplyAsRead(context.Background(), &pb.MarkApplyAsReadRequest{ApplyIds: []int64{1}})
 		require.NoError(t, markErr)
-		assert.IsType(t, &pb.MarkApplyAsReadResponse{}, markResp)
+		assert.Equal(t, int32(2), markResp.UnreadCount)
 
 		deleteResp, deleteErr := h.DeleteFriend(context.Background(), &pb.DeleteFriendRequest{UserUuid: "u2"})
 		require.NoError(t, deleteErr)
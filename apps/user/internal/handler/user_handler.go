@@ -47,7 +47,7 @@ func (h *UserHandler) UploadAvatar(ctx context.Context, req *pb.UploadAvatarRequ
 
 // ChangePassword 修改密码
 func (h *UserHandler) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
-	return &pb.ChangePasswordResponse{}, h.userService.ChangePassword(ctx, req)
+	return h.userService.ChangePassword(ctx, req)
 }
 
 // ChangeEmail 绑定/换绑邮箱
@@ -79,3 +79,13 @@ func (h *UserHandler) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRe
 func (h *UserHandler) BatchGetProfile(ctx context.Context, req *pb.BatchGetProfileRequest) (*pb.BatchGetProfileResponse, error) {
 	return h.userService.BatchGetProfile(ctx, req)
 }
+
+// SetPresenceVisibility 设置在线状态可见性
+func (h *UserHandler) SetPresenceVisibility(ctx context.Context, req *pb.SetPresenceVisibilityRequest) (*pb.SetPresenceVisibilityResponse, error) {
+	return h.userService.SetPresenceVisibility(ctx, req)
+}
+
+// UpdatePrivacy 更新隐私设置
+func (h *UserHandler) UpdatePrivacy(ctx context.Context, req *pb.UpdatePrivacyRequest) (*pb.UpdatePrivacyResponse, error) {
+	return h.userService.UpdatePrivacy(ctx, req)
+}
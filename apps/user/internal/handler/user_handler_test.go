@@ -20,7 +20,7 @@ type fakeUserHandlerService struct {
 	searchUserFn      func(context.Context, *pb.SearchUserRequest) (*pb.SearchUserResponse, error)
 	updateProfileFn   func(context.Context, *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error)
 	uploadAvatarFn    func(context.Context, *pb.UploadAvatarRequest) (*pb.UploadAvatarResponse, error)
-	changePasswordFn  func(context.Context, *pb.ChangePasswordRequest) error
+	changePasswordFn  func(context.Context, *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error)
 	changeEmailFn     func(context.Context, *pb.ChangeEmailRequest) (*pb.ChangeEmailResponse, error)
 	changeTelFn       func(context.Context, *pb.ChangeTelephoneRequest) (*pb.ChangeTelephoneResponse, error)
 	getQRCodeFn       func(context.Context, *pb.GetQRCodeRequest) (*pb.GetQRCodeResponse, error)
@@ -66,9 +66,9 @@ func (f *fakeUserHandlerService) UploadAvatar(ctx context.Context, req *pb.Uploa
 	return f.uploadAvatarFn(ctx, req)
 }
 
-func (f *fakeUserHandlerService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) error {
+func (f *fakeUserHandlerService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
 	if f.changePasswordFn == nil {
-		return nil
+		return &pb.ChangePasswordResponse{}, nil
 	}
 	return f.changePasswordFn(ctx, req)
 }
@@ -212,9 +212,9 @@ func TestUserHandlerForwardingContracts(t *testing.T) {
 	t.Run("change_password_empty_response_contract", func(t *testing.T) {
 		wantErr := errors.New("change password failed")
 		h := NewUserHandler(&fakeUserHandlerService{
-			changePasswordFn: func(_ context.Context, req *pb.ChangePasswordRequest) error {
+			changePasswordFn: func(_ context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
 				require.Equal(t, "oldpass123", req.OldPassword)
-				return nil
+				return &pb.ChangePasswordResponse{AccessToken: "new-token"}, nil
 			},
 		})
 
@@ -224,11 +224,11 @@ func TestUserHandlerForwardingContracts(t *testing.T) {
 		})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
-		assert.IsType(t, &pb.ChangePasswordResponse{}, resp)
+		assert.Equal(t, "new-token", resp.AccessToken)
 
 		hErr := NewUserHandler(&fakeUserHandlerService{
-			changePasswordFn: func(_ context.Context, _ *pb.ChangePasswordRequest) error {
-				return wantErr
+			changePasswordFn: func(_ context.Context, _ *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+				return nil, wantErr
 			},
 		})
 		respErr, errGot := hErr.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
@@ -236,8 +236,7 @@ func TestUserHandlerForwardingContracts(t *testing.T) {
 			NewPassword: "newpass123",
 		})
 		require.ErrorIs(t, errGot, wantErr)
-		require.NotNil(t, respErr)
-		assert.IsType(t, &pb.ChangePasswordResponse{}, respErr)
+		assert.Nil(t, respErr)
 	})
 
 	t.Run("email_telephone_qrcode_batch_delete_success_and_error", func(t *testing.T) {
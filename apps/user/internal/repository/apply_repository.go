@@ -8,26 +8,75 @@ import (
 	"ChatServer/consts/redisKey"
 	"ChatServer/model"
 	"ChatServer/pkg/async"
+	"ChatServer/pkg/cachejitter"
+	"ChatServer/pkg/cursor"
 	"ChatServer/pkg/logger"
+	pkgmysql "ChatServer/pkg/mysql"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// applyPendingPlaceholder 待处理申请 ZSet 的空值占位成员（防缓存穿透，score=0）
+const applyPendingPlaceholder = "__EMPTY__"
+
+// DefaultApplyExpireAfter 好友申请默认有效期
+const DefaultApplyExpireAfter = 7 * 24 * time.Hour
+
+// applyStatusExpired 申请已过期状态（status=3）
+const applyStatusExpired = 3
+
 // applyRepositoryImpl 好友申请数据访问层实现
 type applyRepositoryImpl struct {
 	db          *gorm.DB
 	redisClient *redis.Client
+	expireAfter time.Duration // 申请有效期，Create 时写入 expired_at
 }
 
-// NewApplyRepository 创建好友申请仓储实例
-func NewApplyRepository(db *gorm.DB, redisClient *redis.Client) IApplyRepository {
-	return &applyRepositoryImpl{db: db, redisClient: redisClient}
+// NewApplyRepository 创建好友申请仓储实例，expireAfter<=0 时使用 DefaultApplyExpireAfter
+func NewApplyRepository(db *gorm.DB, redisClient *redis.Client, expireAfter time.Duration) IApplyRepository {
+	if expireAfter <= 0 {
+		expireAfter = DefaultApplyExpireAfter
+	}
+	return &applyRepositoryImpl{db: db, redisClient: redisClient, expireAfter: expireAfter}
+}
+
+// notExpired 过滤已到期但尚未被置为过期状态的待处理申请（expired_at 为空的历史数据不过期）
+func notExpired(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(expired_at IS NULL OR expired_at > ?)", now)
+	}
+}
+
+// applyPendingKey 生成待处理申请唯一键：apply_type:applicant_uuid:target_uuid。
+// 仅 status=0 的记录写入，处理后置 NULL（MySQL 唯一索引允许多个 NULL）。
+func applyPendingKey(apply *model.ApplyRequest) *string {
+	key := strconv.Itoa(int(apply.ApplyType)) + ":" + apply.ApplicantUuid + ":" + apply.TargetUuid
+	return &key
 }
 
 // Create 创建好友申请
+// 待处理申请写入 pending_key 与 expired_at，同一 (类型, 申请人, 目标) 已有未过期的待处理记录时返回 ErrDuplicateKey；
+// 已到期的旧记录先置为过期并释放 pending_key，允许重新申请。
 func (r *applyRepositoryImpl) Create(ctx context.Context, apply *model.ApplyRequest) (*model.ApplyRequest, error) {
+	if apply.Status == 0 {
+		now := time.Now()
+		if apply.PendingKey == nil {
+			apply.PendingKey = applyPendingKey(apply)
+		}
+		if apply.ExpiredAt == nil {
+			expiredAt := now.Add(r.expireAfter)
+			apply.ExpiredAt = &expiredAt
+		}
+		err := r.db.WithContext(ctx).
+			Model(&model.ApplyRequest{}).
+			Where("pending_key = ? AND status = ? AND expired_at <= ?", *apply.PendingKey, 0, now).
+			Updates(map[string]interface{}{"status": applyStatusExpired, "pending_key": nil}).Error
+		if err != nil {
+			return nil, WrapDBError(err)
+		}
+	}
 	err := r.db.WithContext(ctx).Create(apply).Error
 	if err != nil {
 		return nil, WrapDBError(err)
@@ -41,7 +90,7 @@ func (r *applyRepositoryImpl) Create(ctx context.Context, apply *model.ApplyRequ
 	// 使用 Lua 脚本原子性地：检查 Key 存在 -> 移除占位符 -> 添加新成员 -> 续期
 	luaScript := redis.NewScript(luaAddPendingApplyIfExists)
 
-	expireSeconds := int(getRandomExpireTime(rediskey.ApplyPendingTTL).Seconds())
+	expireSeconds := int(cachejitter.ExpireTime(rediskey.ApplyPendingTTL).Seconds())
 	_, err = luaScript.Run(ctx, r.redisClient,
 		[]string{cacheKey},
 		apply.CreatedAt.Unix(),
@@ -70,7 +119,7 @@ func (r *applyRepositoryImpl) Create(ctx context.Context, apply *model.ApplyRequ
 	return apply, nil
 }
 
-// GetByID 根据ID获取好友申请
+// GetByID 根据ID获取好友申请，不存在时返回 ErrRecordNotFound
 func (r *applyRepositoryImpl) GetByID(ctx context.Context, id int64) (*model.ApplyRequest, error) {
 	var apply model.ApplyRequest
 	err := r.db.WithContext(ctx).
@@ -84,8 +133,8 @@ func (r *applyRepositoryImpl) GetByID(ctx context.Context, id int64) (*model.App
 
 // GetPendingList 获取待处理的好友申请列表
 // 冷热分离策略：
-//   - status=0 (待处理)：高热度数据，优先查 Redis ZSet，未命中回源 MySQL
-//   - status=1/2 (已处理)：历史冷数据，直接查 MySQL
+//   - status=0 (待处理)：高热度数据，优先查 Redis ZSet，未命中回源 MySQL；已到期的申请不返回
+//   - status=1/2/3 (已处理/已过期)：历史冷数据，直接查 MySQL
 //   - status<0 (全部)：合并分页复杂，直接查 MySQL
 func (r *applyRepositoryImpl) GetPendingList(ctx context.Context, targetUUID string, status, page, pageSize int) ([]*model.ApplyRequest, int64, error) {
 	// 兜底分页参数
@@ -108,7 +157,7 @@ func (r *applyRepositoryImpl) GetPendingList(ctx context.Context, targetUUID str
 		}
 	}
 
-	// status=1/2 或 status<0 或缓存失败：直接查 MySQL
+	// status=1/2/3 或 status<0 或缓存失败：直接查 MySQL
 	return r.getPendingListFromDB(ctx, targetUUID, status, page, pageSize)
 }
 
@@ -117,51 +166,48 @@ func (r *applyRepositoryImpl) GetPendingList(ctx context.Context, targetUUID str
 func (r *applyRepositoryImpl) getPendingListFromCache(ctx context.Context, targetUUID string, page, pageSize int) ([]*model.ApplyRequest, int64, error) {
 	cacheKey := rediskey.ApplyPendingKey(targetUUID)
 
-	// 1. Pipeline 查询：总数 + 分页成员
+	// 1. Pipeline 查询：总数 + 分页成员 + 占位符是否存在
 	pipe := r.redisClient.Pipeline()
 	totalCmd := pipe.ZCard(ctx, cacheKey)
 	start := int64((page - 1) * pageSize)
 	stop := start + int64(pageSize) - 1
 	membersCmd := pipe.ZRevRange(ctx, cacheKey, start, stop) // 按 score(created_at) 倒序
+	// 占位符 score=0，倒序时排在末尾，可能不在当前页内，需单独判断是否存在
+	placeholderCmd := pipe.ZScore(ctx, cacheKey, applyPendingPlaceholder)
 
 	// 概率续期：1% 概率续期避免热点 key 过期
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.ApplyPendingTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.ApplyPendingTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
-	if err != nil {
+	if err != nil && err != redis.Nil {
 		return nil, 0, err
 	}
 
 	total := totalCmd.Val()
-	applicantUUIDs := membersCmd.Val()
 
 	// 2. 缓存未命中（key 不存在）
 	if total == 0 {
 		return nil, 0, redis.Nil
 	}
 
-	// 3. 空值占位符检查
-	if total == 1 && len(applicantUUIDs) == 1 && applicantUUIDs[0] == "__EMPTY__" {
-		return []*model.ApplyRequest{}, 0, nil
-	}
-
-	// 过滤掉可能的空值占位符
-	filteredUUIDs := make([]string, 0, len(applicantUUIDs))
-	for _, uuid := range applicantUUIDs {
-		if uuid != "__EMPTY__" {
-			filteredUUIDs = append(filteredUUIDs, uuid)
-		}
+	// 3. 过滤占位符并修正总数（仅占位符时 realTotal=0）
+	hasPlaceholder := placeholderCmd.Err() == nil
+	filteredUUIDs, realTotal := filterPendingPlaceholder(membersCmd.Val(), total, hasPlaceholder)
+	if hasPlaceholder && realTotal > 0 {
+		// 占位符与真实成员共存（部分写入残留），尽力清理，不影响本次结果
+		r.removeStrayPendingPlaceholderAsync(ctx, cacheKey)
 	}
 
 	if len(filteredUUIDs) == 0 {
-		return []*model.ApplyRequest{}, total, nil
+		return []*model.ApplyRequest{}, realTotal, nil
 	}
 
-	// 4. 根据 applicantUUIDs 批量查 MySQL 补全完整字段
+	// 4. 根据 applicantUUIDs 批量查 MySQL 补全完整字段（过滤已到期的申请）
 	var applies []*model.ApplyRequest
 	err = r.db.WithContext(ctx).
+		Scopes(notExpired(time.Now())).
 		Where("apply_type = ? AND target_uuid = ? AND status = ? AND applicant_uuid IN ? AND deleted_at IS NULL",
 			0, targetUUID, 0, filteredUUIDs).
 		Order("created_at DESC").
@@ -170,36 +216,81 @@ func (r *applyRepositoryImpl) getPendingListFromCache(ctx context.Context, targe
 		return nil, 0, WrapDBError(err)
 	}
 
-	// 5. 如果有占位符，总数需要减 1
-	realTotal := total
-	if total > 0 {
-		// 检查是否包含占位符（通过原始列表）
-		for _, uuid := range applicantUUIDs {
-			if uuid == "__EMPTY__" {
-				realTotal--
-				break
-			}
+	// 5. 缓存中有但 MySQL 已不是待处理（已过期/已处理）的成员：本次不返回并扣减总数，异步移出缓存
+	if stale := missingApplicants(filteredUUIDs, applies); len(stale) > 0 {
+		realTotal -= int64(len(stale))
+		if realTotal < int64(len(applies)) {
+			realTotal = int64(len(applies))
 		}
+		r.removePendingMembersAsync(ctx, cacheKey, stale)
 	}
 
 	return applies, realTotal, nil
 }
 
+// missingApplicants 返回 members 中没有对应申请记录的申请人
+func missingApplicants(members []string, applies []*model.ApplyRequest) []string {
+	found := make(map[string]struct{}, len(applies))
+	for _, apply := range applies {
+		found[apply.ApplicantUuid] = struct{}{}
+	}
+	var missing []string
+	for _, member := range members {
+		if _, ok := found[member]; !ok {
+			missing = append(missing, member)
+		}
+	}
+	return missing
+}
+
+// removePendingMembersAsync 异步将申请人移出待处理缓存（失败静默忽略，由后续重建兜底）
+func (r *applyRepositoryImpl) removePendingMembersAsync(ctx context.Context, cacheKey string, applicantUUIDs []string) {
+	members := make([]interface{}, 0, len(applicantUUIDs))
+	for _, uuid := range applicantUUIDs {
+		members = append(members, uuid)
+	}
+	async.RunSafe(ctx, func(runCtx context.Context) {
+		if err := r.redisClient.ZRem(runCtx, cacheKey, members...).Err(); err != nil {
+			LogRedisError(runCtx, err)
+		}
+	}, 0)
+}
+
+// filterPendingPlaceholder 从当前页成员中剔除空值占位符，并按占位符是否存在于整个 ZSet 修正总数。
+// hasPlaceholder 需基于整个 ZSet 判断（而非当前页），否则占位符不在本页时总数会多算 1。
+func filterPendingPlaceholder(members []string, total int64, hasPlaceholder bool) ([]string, int64) {
+	filtered := make([]string, 0, len(members))
+	for _, member := range members {
+		if member == "" || member == applyPendingPlaceholder {
+			continue
+		}
+		filtered = append(filtered, member)
+	}
+
+	realTotal := total
+	if hasPlaceholder {
+		realTotal--
+	}
+	if realTotal < 0 {
+		realTotal = 0
+	}
+	return filtered, realTotal
+}
+
+// removeStrayPendingPlaceholderAsync 异步移除与真实成员共存的占位符（失败静默忽略）
+func (r *applyRepositoryImpl) removeStrayPendingPlaceholderAsync(ctx context.Context, cacheKey string) {
+	async.RunSafe(ctx, func(runCtx context.Context) {
+		if err := r.redisClient.ZRem(runCtx, cacheKey, applyPendingPlaceholder).Err(); err != nil {
+			LogRedisError(runCtx, err)
+		}
+	}, 0)
+}
+
 // getPendingListFromDB 从 MySQL 查询好友申请列表
 func (r *applyRepositoryImpl) getPendingListFromDB(ctx context.Context, targetUUID string, status, page, pageSize int) ([]*model.ApplyRequest, int64, error) {
 	offset := (page - 1) * pageSize
 
-	// 基础条件：仅好友申请 + 指定目标用户 + 未删除
-	query := r.db.WithContext(ctx).
-		Model(&model.ApplyRequest{}).
-		Where("apply_type = ? AND target_uuid = ? AND deleted_at IS NULL", 0, targetUUID)
-
-	// status >= 0 时按指定状态过滤；否则返回全部(0/1/2)状态
-	if status >= 0 {
-		query = query.Where("status = ?", status)
-	} else {
-		query = query.Where("status IN ?", []int{0, 1, 2})
-	}
+	query := r.applyListQuery(ctx, "target_uuid", targetUUID, status)
 
 	// 先查总数
 	var total int64
@@ -207,10 +298,10 @@ func (r *applyRepositoryImpl) getPendingListFromDB(ctx context.Context, targetUU
 		return nil, 0, WrapDBError(err)
 	}
 
-	// 再查列表，按创建时间倒序
+	// 再查列表，按创建时间倒序（加二级排序保证稳定性，与游标分页一致）
 	var applies []*model.ApplyRequest
 	if err := query.
-		Order("created_at DESC").
+		Order("created_at DESC, id DESC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&applies).
@@ -236,6 +327,7 @@ func (r *applyRepositoryImpl) rebuildPendingCacheAsync(ctx context.Context, targ
 		var applies []model.ApplyRequest
 		err := r.db.WithContext(runCtx).
 			Select("applicant_uuid", "created_at").
+			Scopes(notExpired(time.Now())).
 			Where("apply_type = ? AND target_uuid = ? AND status = ? AND deleted_at IS NULL", 0, targetUUID, 0).
 			Find(&applies).Error
 		if err != nil {
@@ -251,7 +343,7 @@ func (r *applyRepositoryImpl) rebuildPendingCacheAsync(ctx context.Context, targ
 			// 空值占位，防止缓存穿透
 			pipe.ZAdd(runCtx, cacheKey, redis.Z{
 				Score:  0,
-				Member: "__EMPTY__",
+				Member: applyPendingPlaceholder,
 			})
 			pipe.Expire(runCtx, cacheKey, rediskey.ApplyPendingEmptyTTL)
 		} else {
@@ -263,7 +355,7 @@ func (r *applyRepositoryImpl) rebuildPendingCacheAsync(ctx context.Context, targ
 				})
 			}
 			pipe.ZAdd(runCtx, cacheKey, zs...)
-			pipe.Expire(runCtx, cacheKey, getRandomExpireTime(rediskey.ApplyPendingTTL))
+			pipe.Expire(runCtx, cacheKey, cachejitter.ExpireTime(rediskey.ApplyPendingTTL))
 		}
 
 		if _, err := pipe.Exec(runCtx); err != nil {
@@ -284,17 +376,7 @@ func (r *applyRepositoryImpl) GetSentList(ctx context.Context, applicantUUID str
 
 	offset := (page - 1) * pageSize
 
-	// 基础条件：仅好友申请 + 指定申请人 + 未删除
-	query := r.db.WithContext(ctx).
-		Model(&model.ApplyRequest{}).
-		Where("apply_type = ? AND applicant_uuid = ? AND deleted_at IS NULL", 0, applicantUUID)
-
-	// status >= 0 时按指定状态过滤；否则返回全部(0/1/2)状态
-	if status >= 0 {
-		query = query.Where("status = ?", status)
-	} else {
-		query = query.Where("status IN ?", []int{0, 1, 2})
-	}
+	query := r.applyListQuery(ctx, "applicant_uuid", applicantUUID, status)
 
 	// 先查总数
 	var total int64
@@ -302,10 +384,10 @@ func (r *applyRepositoryImpl) GetSentList(ctx context.Context, applicantUUID str
 		return nil, 0, WrapDBError(err)
 	}
 
-	// 再查列表，按创建时间倒序
+	// 再查列表，按创建时间倒序（加二级排序保证稳定性，与游标分页一致）
 	var applies []*model.ApplyRequest
 	if err := query.
-		Order("created_at DESC").
+		Order("created_at DESC, id DESC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&applies).
@@ -316,10 +398,62 @@ func (r *applyRepositoryImpl) GetSentList(ctx context.Context, applicantUUID str
 	return applies, total, nil
 }
 
+// GetPendingListByCursor 游标分页获取收到的好友申请列表，返回是否还有下一页
+// 深翻页不走 Redis 待处理缓存（ZSet 只能按名次分页），直接按 created_at + id 查 MySQL
+func (r *applyRepositoryImpl) GetPendingListByCursor(ctx context.Context, targetUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error) {
+	return r.getApplyListByCursor(ctx, "target_uuid", targetUUID, status, after, limit)
+}
+
+// GetSentListByCursor 游标分页获取发出的好友申请列表，返回是否还有下一页
+func (r *applyRepositoryImpl) GetSentListByCursor(ctx context.Context, applicantUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error) {
+	return r.getApplyListByCursor(ctx, "applicant_uuid", applicantUUID, status, after, limit)
+}
+
+func (r *applyRepositoryImpl) getApplyListByCursor(ctx context.Context, userColumn, userUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// 多查一条用于判断是否还有下一页
+	var applies []*model.ApplyRequest
+	if err := r.applyListQuery(ctx, userColumn, userUUID, status).
+		Scopes(beforeCursor(after)).
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
+		Find(&applies).
+		Error; err != nil {
+		return nil, false, WrapDBError(err)
+	}
+
+	if len(applies) > limit {
+		return applies[:limit], true, nil
+	}
+	return applies, false, nil
+}
+
+// applyListQuery 好友申请列表基础条件：仅好友申请 + 指定用户（userColumn 为 target_uuid 或 applicant_uuid）+ 未删除。
+// status >= 0 时按指定状态过滤（待处理不含已到期）；否则返回全部(0/1/2/3)状态
+func (r *applyRepositoryImpl) applyListQuery(ctx context.Context, userColumn, userUUID string, status int) *gorm.DB {
+	query := r.db.WithContext(ctx).
+		Model(&model.ApplyRequest{}).
+		Where("apply_type = ? AND "+userColumn+" = ? AND deleted_at IS NULL", 0, userUUID)
+
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+		if status == 0 {
+			query = query.Scopes(notExpired(time.Now()))
+		}
+	} else {
+		query = query.Where("status IN ?", []int{0, 1, 2, applyStatusExpired})
+	}
+	return query
+}
+
 // UpdateStatus 更新申请状态
 func (r *applyRepositoryImpl) UpdateStatus(ctx context.Context, id int64, status int, remark string) error {
 	updates := map[string]interface{}{
-		"status": status,
+		"status":      status,
+		"pending_key": nil, // 离开待处理状态后释放唯一键
 	}
 	if remark != "" {
 		updates["handle_remark"] = remark
@@ -356,10 +490,14 @@ func (r *applyRepositoryImpl) AcceptApplyAndCreateRelation(ctx context.Context,
 	now := time.Now()
 	var alreadyProcessed bool
 
-	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	// 两行关系 Upsert 与申请状态更新在同一事务内，并发同意互相加好友时可能死锁，死锁时整体重试事务。
+	err := pkgmysql.RunInTxWithRetry(ctx, r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		alreadyProcessed = false
+
 		// 1. CAS 更新申请状态（WHERE status=0 作为守门员）
 		applyUpdates := map[string]interface{}{
-			"status": 1, // 同意
+			"status":      1, // 同意
+			"pending_key": nil,
 		}
 		if remark != "" {
 			applyUpdates["handle_remark"] = remark
@@ -467,7 +605,7 @@ func (r *applyRepositoryImpl) invalidateFriendCacheAsync(ctx context.Context, us
 			},
 		}
 
-		expireSeconds := int(getRandomExpireTime(24 * time.Hour).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(24 * time.Hour).Seconds())
 		upsertScript := redis.NewScript(luaUpsertFriendMetaIfExists)
 		insertScript := redis.NewScript(luaInsertFriendMetaIfExists)
 
@@ -494,6 +632,7 @@ func (r *applyRepositoryImpl) invalidateFriendCacheAsync(ctx context.Context, us
 }
 
 // MarkAsRead 标记申请已读（同步）
+// 仅 is_read=false 的记录会被更新，RowsAffected 即本次真正由未读变为已读的数量，据此扣减未读计数。
 func (r *applyRepositoryImpl) MarkAsRead(ctx context.Context, targetUUID string, ids []int64) (int64, error) {
 	if len(ids) == 0 || targetUUID == "" {
 		return 0, nil
@@ -503,7 +642,11 @@ func (r *applyRepositoryImpl) MarkAsRead(ctx context.Context, targetUUID string,
 		Where("id IN ? AND target_uuid = ? AND apply_type = ? AND is_read = ? AND deleted_at IS NULL",
 			ids, targetUUID, 0, false).
 		Update("is_read", true)
-	return result.RowsAffected, WrapDBError(result.Error)
+	if result.Error != nil {
+		return 0, WrapDBError(result.Error)
+	}
+	r.decrUnreadCount(ctx, targetUUID, result.RowsAffected)
+	return result.RowsAffected, nil
 }
 
 // MarkAllAsRead 标记当前用户所有好友申请已读（同步）
@@ -516,29 +659,45 @@ func (r *applyRepositoryImpl) MarkAllAsRead(ctx context.Context, targetUUID stri
 		Where("apply_type = ? AND target_uuid = ? AND is_read = ? AND deleted_at IS NULL",
 			0, targetUUID, false).
 		Update("is_read", true)
-	return result.RowsAffected, WrapDBError(result.Error)
+	if result.Error != nil {
+		return 0, WrapDBError(result.Error)
+	}
+	r.decrUnreadCount(ctx, targetUUID, result.RowsAffected)
+	return result.RowsAffected, nil
 }
 
 // MarkAsReadAsync 异步标记申请已读（不阻塞主请求）
-// 批量更新，仅更新 is_read=false 的记录避免无效写入
-func (r *applyRepositoryImpl) MarkAsReadAsync(ctx context.Context, ids []int64) {
-	if len(ids) == 0 {
+// 批量更新，仅更新 is_read=false 的记录避免无效写入；与 MarkAsRead 共用扣减逻辑，
+// 即使先于本次扣减执行了 ClearUnreadCount，计数也不会减为负数或被重新创建。
+func (r *applyRepositoryImpl) MarkAsReadAsync(ctx context.Context, targetUUID string, ids []int64) {
+	if len(ids) == 0 || targetUUID == "" {
 		return
 	}
 
 	// 使用 async.RunSafe 异步执行，自带 panic recover 和超时控制
 	async.RunSafe(ctx, func(runCtx context.Context) {
-		err := r.db.WithContext(runCtx).
-			Model(&model.ApplyRequest{}).
-			Where("id IN ? AND apply_type = ? AND is_read = ? AND deleted_at IS NULL", ids, 0, false).
-			Update("is_read", true).Error
-		if err != nil {
+		if _, err := r.MarkAsRead(runCtx, targetUUID, ids); err != nil {
 			// 异步更新失败只记录日志，不影响主流程
 			logger.Error(runCtx, "异步标记申请已读失败", logger.ErrorField("error", err))
 		}
 	}, 0) // timeout=0 使用默认 1 分钟超时
 }
 
+// decrUnreadCount 按实际已读行数扣减好友申请未读计数（尽力而为）。
+// Lua 保证原子扣减且下限为 0；key 不存在时不创建，由 Create 重新累加。
+func (r *applyRepositoryImpl) decrUnreadCount(ctx context.Context, targetUUID string, n int64) {
+	if n <= 0 {
+		return
+	}
+	err := redis.NewScript(luaDecrByFloorZeroIfExists).Run(ctx, r.redisClient,
+		[]string{rediskey.ApplyUnreadNotifyKey(targetUUID)},
+		n,
+	).Err()
+	if err != nil && err != redis.Nil {
+		LogRedisError(ctx, err)
+	}
+}
+
 // GetUnreadCount 获取未读申请数量
 func (r *applyRepositoryImpl) GetUnreadCount(ctx context.Context, targetUUID string) (int64, error) {
 	if targetUUID == "" {
@@ -587,9 +746,10 @@ func (r *applyRepositoryImpl) ClearUnreadCount(ctx context.Context, targetUUID s
 	return nil
 }
 
-// ExistsPendingRequest 检查是否存在待处理的申请
+// ExistsPendingRequest 检查是否存在未过期的待处理申请
 // 采用 Cache-Aside Pattern：优先查 Redis ZSet，未命中则回源 MySQL 并缓存
-// 使用 ZSet 存储目标用户的待处理申请，以申请时间戳为 score
+// 使用 ZSet 存储目标用户的待处理申请，以申请时间戳为 score；
+// 命中的成员申请时间早于有效期（可能已过期、尚未被扫描移除）时回源 MySQL 确认。
 func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applicantUUID, targetUUID string) (bool, error) {
 	cacheKey := rediskey.ApplyPendingKey(targetUUID)
 
@@ -599,8 +759,8 @@ func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applican
 	scoreCmd := pipe.ZScore(ctx, cacheKey, applicantUUID)
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.ApplyPendingTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.ApplyPendingTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -610,7 +770,11 @@ func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applican
 		if existsCmd.Val() > 0 {
 			// Cache hit: if member exists, it has a score.
 			if scoreCmd.Err() == nil {
-				return true, nil
+				if int64(scoreCmd.Val()) > time.Now().Add(-r.expireAfter).Unix() {
+					return true, nil
+				}
+				// 可能已过期，回源确认
+				return r.existsPendingInDB(ctx, applicantUUID, targetUUID)
 			}
 			if scoreCmd.Err() == redis.Nil {
 				return false, nil
@@ -622,6 +786,7 @@ func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applican
 	// ==================== 2. 缓存未命中，回源查询 MySQL ====================
 	var applies []model.ApplyRequest
 	err = r.db.WithContext(ctx).
+		Scopes(notExpired(time.Now())).
 		Where("apply_type = ? AND target_uuid = ? AND status = ? AND deleted_at IS NULL", 0, targetUUID, 0).
 		Find(&applies).Error
 	if err != nil {
@@ -635,7 +800,7 @@ func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applican
 		if len(applies) == 0 {
 			pipe.ZAdd(runCtx, cacheKey, redis.Z{
 				Score:  0,
-				Member: "__EMPTY__",
+				Member: applyPendingPlaceholder,
 			})
 			pipe.Expire(runCtx, cacheKey, rediskey.ApplyPendingEmptyTTL)
 		} else {
@@ -647,7 +812,7 @@ func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applican
 				})
 			}
 			pipe.ZAdd(runCtx, cacheKey, zs...)
-			pipe.Expire(runCtx, cacheKey, getRandomExpireTime(rediskey.ApplyPendingTTL))
+			pipe.Expire(runCtx, cacheKey, cachejitter.ExpireTime(rediskey.ApplyPendingTTL))
 		}
 		if _, err := pipe.Exec(runCtx); err != nil {
 			LogRedisError(runCtx, err)
@@ -663,7 +828,137 @@ func (r *applyRepositoryImpl) ExistsPendingRequest(ctx context.Context, applican
 	return false, nil
 }
 
-// GetByIDWithInfo 根据ID获取好友申请（仅申请记录）
-func (r *applyRepositoryImpl) GetByIDWithInfo(ctx context.Context, id int64) (*model.ApplyRequest, error) {
-	return r.GetByID(ctx, id)
+// existsPendingInDB 查询 MySQL 确认申请人对目标用户是否有未过期的待处理申请
+func (r *applyRepositoryImpl) existsPendingInDB(ctx context.Context, applicantUUID, targetUUID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.ApplyRequest{}).
+		Scopes(notExpired(time.Now())).
+		Where("apply_type = ? AND applicant_uuid = ? AND target_uuid = ? AND status = ? AND deleted_at IS NULL",
+			0, applicantUUID, targetUUID, 0).
+		Count(&count).Error
+	if err != nil {
+		return false, WrapDBError(err)
+	}
+	return count > 0, nil
+}
+
+// ExpireApply 将已到期的待处理申请置为过期（读取时惰性处理，CAS 幂等）
+// 状态更新成功后移出待处理缓存并扣减未读计数；申请未到期或已处理时不做任何修改。
+func (r *applyRepositoryImpl) ExpireApply(ctx context.Context, apply *model.ApplyRequest) error {
+	if apply == nil || apply.Status != 0 || !apply.IsExpired(time.Now()) {
+		return nil
+	}
+	_, err := r.expireApplies(ctx, []model.ApplyRequest{*apply})
+	return err
+}
+
+// ExpirePendingApplies 批量将已到期的待处理申请置为过期（后台扫描调用），返回本批实际过期的数量
+// 每批最多处理 limit 条，调用方在返回值等于 limit 时继续下一批。
+func (r *applyRepositoryImpl) ExpirePendingApplies(ctx context.Context, limit int) (int64, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	var applies []model.ApplyRequest
+	err := r.db.WithContext(ctx).
+		Select("id", "apply_type", "applicant_uuid", "target_uuid", "is_read").
+		Where("status = ? AND expired_at <= ? AND deleted_at IS NULL", 0, time.Now()).
+		Order("expired_at ASC").
+		Limit(limit).
+		Find(&applies).Error
+	if err != nil {
+		return 0, WrapDBError(err)
+	}
+	if len(applies) == 0 {
+		return 0, nil
+	}
+	return r.expireApplies(ctx, applies)
+}
+
+// expireApplies CAS 更新申请为过期状态（WHERE status=0 守门员，与同意/拒绝并发时只有一方生效），
+// 然后尽力而为地移出待处理缓存、扣减未读的好友申请计数。
+func (r *applyRepositoryImpl) expireApplies(ctx context.Context, applies []model.ApplyRequest) (int64, error) {
+	ids := make([]int64, 0, len(applies))
+	for _, apply := range applies {
+		ids = append(ids, apply.Id)
+	}
+	result := r.db.WithContext(ctx).
+		Model(&model.ApplyRequest{}).
+		Where("id IN ? AND status = ? AND expired_at <= ?", ids, 0, time.Now()).
+		Updates(map[string]interface{}{"status": applyStatusExpired, "pending_key": nil})
+	if result.Error != nil {
+		return 0, WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, nil
+	}
+
+	// 并发处理时部分记录可能未被本次更新，缓存多删只会触发回源，不影响正确性
+	unread := make(map[string]int64)
+	pipe := r.redisClient.Pipeline()
+	for _, apply := range applies {
+		if apply.ApplyType != 0 {
+			continue
+		}
+		pipe.ZRem(ctx, rediskey.ApplyPendingKey(apply.TargetUuid), apply.ApplicantUuid)
+		if !apply.IsRead {
+			unread[apply.TargetUuid]++
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		LogRedisError(ctx, err)
+	}
+	// 部分记录被并发处理时无法区分哪些已过期，跳过扣减（以下次清除红点为准）
+	if int(result.RowsAffected) == len(applies) {
+		for targetUUID, n := range unread {
+			r.decrUnreadCount(ctx, targetUUID, n)
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// applyWithApplicantRow GetByIDWithInfo 联表查询结果：申请记录 + 申请人公开资料（u_ 前缀，避免与 apply_request 列重名）
+type applyWithApplicantRow struct {
+	model.ApplyRequest
+	UUuid      string `gorm:"column:u_uuid"`
+	UNickname  string `gorm:"column:u_nickname"`
+	UAvatar    string `gorm:"column:u_avatar"`
+	UGender    int8   `gorm:"column:u_gender"`
+	USignature string `gorm:"column:u_signature"`
+}
+
+// applicant 组装申请人资料；LEFT JOIN 未命中（申请人已注销）时返回 nil
+func (row *applyWithApplicantRow) applicant() *model.UserInfo {
+	if row.UUuid == "" {
+		return nil
+	}
+	return &model.UserInfo{
+		Uuid:      row.UUuid,
+		Nickname:  row.UNickname,
+		Avatar:    row.UAvatar,
+		Gender:    row.UGender,
+		Signature: row.USignature,
+	}
+}
+
+// GetByIDWithInfo 根据ID获取好友申请及申请人公开资料
+// apply_request LEFT JOIN user_info 单次查询，只取公开字段（不含密码、手机号等）。
+func (r *applyRepositoryImpl) GetByIDWithInfo(ctx context.Context, id int64) (*model.ApplyRequest, *model.UserInfo, error) {
+	var row applyWithApplicantRow
+	result := r.db.WithContext(ctx).
+		Table("apply_request AS a").
+		Select("a.*, u.uuid AS u_uuid, u.nickname AS u_nickname, u.avatar AS u_avatar, u.gender AS u_gender, u.signature AS u_signature").
+		Joins("LEFT JOIN user_info AS u ON u.uuid = a.applicant_uuid AND u.deleted_at IS NULL").
+		Where("a.id = ? AND a.apply_type = ? AND a.deleted_at IS NULL", id, 0).
+		Limit(1).
+		Scan(&row)
+	if result.Error != nil {
+		return nil, nil, WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrRecordNotFound
+	}
+
+	apply := row.ApplyRequest
+	return &apply, row.applicant(), nil
 }
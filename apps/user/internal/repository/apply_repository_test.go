@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"testing"
+
+	"ChatServer/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterPendingPlaceholder(t *testing.T) {
+	t.Run("only_placeholder", func(t *testing.T) {
+		members, total := filterPendingPlaceholder([]string{applyPendingPlaceholder}, 1, true)
+		assert.Empty(t, members)
+		assert.Equal(t, int64(0), total)
+	})
+
+	t.Run("mixed_placeholder_in_page", func(t *testing.T) {
+		// 3 个真实成员 + 残留占位符（score=0 排在末尾，落在当前页）。
+		members, total := filterPendingPlaceholder([]string{"a3", "a2", "a1", applyPendingPlaceholder}, 4, true)
+		assert.Equal(t, []string{"a3", "a2", "a1"}, members)
+		assert.Equal(t, int64(3), total)
+	})
+
+	t.Run("mixed_placeholder_outside_page", func(t *testing.T) {
+		// 第 1 页不含占位符，但总数仍需扣除。
+		members, total := filterPendingPlaceholder([]string{"a5", "a4"}, 6, true)
+		assert.Equal(t, []string{"a5", "a4"}, members)
+		assert.Equal(t, int64(5), total)
+	})
+
+	t.Run("no_placeholder", func(t *testing.T) {
+		members, total := filterPendingPlaceholder([]string{"a2", "", "a1"}, 2, false)
+		assert.Equal(t, []string{"a2", "a1"}, members)
+		assert.Equal(t, int64(2), total)
+	})
+}
+
+func TestApplyWithApplicantRow(t *testing.T) {
+	t.Run("applicant_joined", func(t *testing.T) {
+		row := applyWithApplicantRow{
+			ApplyRequest: model.ApplyRequest{Id: 7, ApplicantUuid: "u2", TargetUuid: "u1"},
+			UUuid:        "u2",
+			UNickname:    "bob",
+			UAvatar:      "a.png",
+			UGender:      1,
+			USignature:   "hi",
+		}
+		applicant := row.applicant()
+		require.NotNil(t, applicant)
+		assert.Equal(t, model.UserInfo{Uuid: "u2", Nickname: "bob", Avatar: "a.png", Gender: 1, Signature: "hi"}, *applicant)
+	})
+
+	t.Run("applicant_deleted", func(t *testing.T) {
+		// LEFT JOIN 未命中：申请仍返回，申请人资料为 nil
+		row := applyWithApplicantRow{ApplyRequest: model.ApplyRequest{Id: 7, ApplicantUuid: "u2"}}
+		assert.Nil(t, row.applicant())
+	})
+}
+
+func TestMissingApplicants(t *testing.T) {
+	applies := []*model.ApplyRequest{{ApplicantUuid: "a3"}, {ApplicantUuid: "a1"}}
+
+	// a2 在缓存中但 MySQL 已不是待处理（已过期/已处理），需移出缓存
+	assert.Equal(t, []string{"a2"}, missingApplicants([]string{"a3", "a2", "a1"}, applies))
+	assert.Empty(t, missingApplicants([]string{"a3", "a1"}, applies))
+}
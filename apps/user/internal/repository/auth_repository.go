@@ -38,7 +38,7 @@ func (r *authRepositoryImpl) GetByEmail(ctx context.Context, email string) (*mod
 }
 
 // VerifyVerifyCode 校验验证码
-// type: 验证码类型 (1:注册 2:登录 3:重置密码 4:换绑邮箱)
+// type: 验证码类型 (1:注册 2:登录 3:重置密码 4:换绑邮箱 5:换绑手机)
 func (r *authRepositoryImpl) VerifyVerifyCode(ctx context.Context, email, verifyCode string, codeType int32) (bool, error) {
 	// 从Redis中获取验证码
 	// 格式：user:verify_code:{email}:{type}
@@ -51,7 +51,7 @@ func (r *authRepositoryImpl) VerifyVerifyCode(ctx context.Context, email, verify
 }
 
 // StoreVerifyCode 存储验证码到Redis（带过期时间）
-// type: 验证码类型 (1:注册 2:登录 3:重置密码 4:换绑邮箱)
+// type: 验证码类型 (1:注册 2:登录 3:重置密码 4:换绑邮箱 5:换绑手机)
 func (r *authRepositoryImpl) StoreVerifyCode(ctx context.Context, email, verifyCode string, codeType int32, expireDuration time.Duration) error {
 	// 格式：user:verify_code:{email}:{type}
 	verifyCodeKey := rediskey.VerifyCodeKey(email, codeType)
@@ -70,7 +70,7 @@ func (r *authRepositoryImpl) StoreVerifyCode(ctx context.Context, email, verifyC
 }
 
 // DeleteVerifyCode 删除验证码（消耗验证码）
-// type: 验证码类型 (1:注册 2:登录 3:重置密码 4:换绑邮箱)
+// type: 验证码类型 (1:注册 2:登录 3:重置密码 4:换绑邮箱 5:换绑手机)
 func (r *authRepositoryImpl) DeleteVerifyCode(ctx context.Context, email string, codeType int32) error {
 	// 格式：user:verify_code:{email}:{type}
 	verifyCodeKey := rediskey.VerifyCodeKey(email, codeType)
@@ -3,6 +3,7 @@ package repository
 import (
 	"ChatServer/consts/redisKey"
 	"ChatServer/pkg/async"
+	"ChatServer/pkg/cachejitter"
 	"ChatServer/model"
 	"context"
 	"errors"
@@ -25,54 +26,60 @@ func NewBlacklistRepository(db *gorm.DB, redisClient *redis.Client) IBlacklistRe
 }
 
 // AddBlacklist 拉黑用户
+// 拉黑即解除双方好友关系（同一事务内完成，失败时不会只删一半）：
+// - A -> B: 标记为拉黑（status=3），取消拉黑后不再恢复好友；
+// - B -> A: 见 blacklistPeerUpdates，好友关系软删除并推进 updated_at，SyncFriendList 据此下发 delete。
+// 好友关系被解除时同步失效双方的好友缓存。
 func (r *blacklistRepositoryImpl) AddBlacklist(ctx context.Context, userUUID, targetUUID string) error {
 	now := time.Now()
+	severed := false
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// A -> B: 标记为拉黑（status=1/3）
-		// - status=1: 原先为好友（含已删除好友）
-		// - status=3: 原先非好友
-		status := int8(3)
-		var existing model.UserRelation
+		var relations []model.UserRelation
 		if err := tx.Unscoped().
-			Select("status").
-			Where("user_uuid = ? AND peer_uuid = ?", userUUID, targetUUID).
-			First(&existing).Error; err != nil {
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return err
+			Select("user_uuid", "status", "deleted_at").
+			Where("(user_uuid = ? AND peer_uuid = ?) OR (user_uuid = ? AND peer_uuid = ?)",
+				userUUID, targetUUID, targetUUID, userUUID).
+			Find(&relations).Error; err != nil {
+			return err
+		}
+
+		for i := range relations {
+			relation := &relations[i]
+			if isActiveFriend(relation) {
+				severed = true
 			}
-		} else {
-			switch existing.Status {
-			case 0, 1, 2:
-				status = 1
-			case 3:
-				status = 3
-			default:
-				status = 3
+			if relation.UserUuid != targetUUID {
+				continue
+			}
+			updates := blacklistPeerUpdates(relation, now)
+			if updates == nil {
+				continue
+			}
+			if err := tx.Unscoped().
+				Model(&model.UserRelation{}).
+				Where("user_uuid = ? AND peer_uuid = ?", targetUUID, userUUID).
+				Updates(updates).Error; err != nil {
+				return err
 			}
 		}
 
 		relationAB := &model.UserRelation{
-			UserUuid:  userUUID,
-			PeerUuid:  targetUUID,
-			Status:    status,
-			CreatedAt: now,
-			UpdatedAt: now,
+			UserUuid:      userUUID,
+			PeerUuid:      targetUUID,
+			Status:        3,
+			CreatedAt:     now,
+			UpdatedAt:     now,
 			BlacklistedAt: &now,
 		}
-		if err := tx.Clauses(clause.OnConflict{
+		return tx.Clauses(clause.OnConflict{
 			Columns: []clause.Column{{Name: "user_uuid"}, {Name: "peer_uuid"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
-				"status":     status,
-				"deleted_at": nil,
+				"status":         3,
+				"deleted_at":     nil,
 				"blacklisted_at": now,
-				"updated_at": now,
+				"updated_at":     now,
 			}),
-		}).Create(relationAB).Error; err != nil {
-			return err
-		}
-
-		// B -> A: 不变（保留好友关系，由消息链路查询黑名单拦截）
-		return nil
+		}).Create(relationAB).Error
 	})
 	if err != nil {
 		return WrapDBError(err)
@@ -80,10 +87,41 @@ func (r *blacklistRepositoryImpl) AddBlacklist(ctx context.Context, userUUID, ta
 
 	// 异步更新黑名单缓存（仅更新当前用户侧）
 	r.updateBlacklistCacheAsync(ctx, userUUID, targetUUID, now.UnixMilli())
+	if severed {
+		r.removeFriendCacheAsync(ctx, userUUID, targetUUID)
+		r.removeFriendCacheAsync(ctx, targetUUID, userUUID)
+	}
 
 	return nil
 }
 
+// isActiveFriend 判断关系是否为有效好友（status=0 且未软删除）。
+func isActiveFriend(relation *model.UserRelation) bool {
+	return relation != nil && relation.Status == 0 && !relation.DeletedAt.Valid
+}
+
+// blacklistPeerUpdates 计算被拉黑方（B -> A）关系在拉黑时的变更，无需变更返回 nil：
+// - 有效好友（status=0）：软删除（status=2）；
+// - 对端已拉黑且原先为好友（status=1）：改为 status=3，对端取消拉黑时不再恢复好友；
+// - 其他（已删除、拉黑且原先非好友）：不变。
+func blacklistPeerUpdates(relation *model.UserRelation, now time.Time) map[string]interface{} {
+	switch {
+	case isActiveFriend(relation):
+		return map[string]interface{}{
+			"status":     2,
+			"deleted_at": gorm.DeletedAt{Time: now, Valid: true},
+			"updated_at": now,
+		}
+	case relation != nil && relation.Status == 1:
+		return map[string]interface{}{
+			"status":     3,
+			"updated_at": now,
+		}
+	default:
+		return nil
+	}
+}
+
 // RemoveBlacklist 取消拉黑
 func (r *blacklistRepositoryImpl) RemoveBlacklist(ctx context.Context, userUUID, targetUUID string) error {
 	if userUUID == "" || targetUUID == "" {
@@ -110,7 +148,7 @@ func (r *blacklistRepositoryImpl) RemoveBlacklist(ctx context.Context, userUUID,
 	}
 
 	if relation.Status == 1 {
-		// 原先为好友：恢复好友关系
+		// 历史数据（拉黑时未解除好友关系的 status=1）：恢复好友关系
 		updates["status"] = 0
 		updates["deleted_at"] = nil
 	} else {
@@ -158,8 +196,8 @@ func (r *blacklistRepositoryImpl) GetBlacklistList(ctx context.Context, userUUID
 	countCmd := pipe.ZCard(ctx, cacheKey)
 	rangeCmd := pipe.ZRevRangeWithScores(ctx, cacheKey, int64(offset), int64(offset+pageSize-1))
 	emptyScoreCmd := pipe.ZScore(ctx, cacheKey, "__EMPTY__")
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.BlacklistTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.BlacklistTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -241,7 +279,7 @@ func (r *blacklistRepositoryImpl) GetBlacklistList(ctx context.Context, userUUID
 			if len(members) > 0 {
 				pipe.ZAdd(runCtx, cacheKey, members...)
 			}
-			pipe.Expire(runCtx, cacheKey, getRandomExpireTime(rediskey.BlacklistTTL))
+			pipe.Expire(runCtx, cacheKey, cachejitter.ExpireTime(rediskey.BlacklistTTL))
 		}
 		if _, err := pipe.Exec(runCtx); err != nil && err != redis.Nil {
 			if isRedisWrongType(err) {
@@ -272,8 +310,8 @@ func (r *blacklistRepositoryImpl) IsBlocked(ctx context.Context, userUUID, targe
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
 	// 无论 Key 是否存在，Expire 都是安全的 (不存在则返回0)
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.BlacklistTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.BlacklistTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -340,7 +378,7 @@ func (r *blacklistRepositoryImpl) IsBlocked(ctx context.Context, userUUID, targe
 			blacklistedAt = *relation.BlacklistedAt
 		}
 		pipe.ZAdd(runCtx, cacheKey, redis.Z{Score: float64(blacklistedAt.UnixMilli()), Member: targetUUID})
-		pipe.Expire(runCtx, cacheKey, getRandomExpireTime(rediskey.BlacklistTTL))
+		pipe.Expire(runCtx, cacheKey, cachejitter.ExpireTime(rediskey.BlacklistTTL))
 		if _, err := pipe.Exec(runCtx); err != nil {
 			LogRedisError(runCtx, err)
 		}
@@ -364,7 +402,7 @@ func (r *blacklistRepositoryImpl) updateBlacklistCacheAsync(ctx context.Context,
 	cacheKey := rediskey.BlacklistRelationKey(userUUID)
 	async.RunSafe(ctx, func(runCtx context.Context) {
 		luaScript := redis.NewScript(luaAddBlacklistIfExists)
-		expireSeconds := int(getRandomExpireTime(rediskey.BlacklistTTL).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(rediskey.BlacklistTTL).Seconds())
 		_, err := luaScript.Run(runCtx, r.redisClient,
 			[]string{cacheKey},
 			blockedAt,
@@ -392,7 +430,7 @@ func (r *blacklistRepositoryImpl) removeBlacklistCacheAsync(ctx context.Context,
 	cacheKey := rediskey.BlacklistRelationKey(userUUID)
 	async.RunSafe(ctx, func(runCtx context.Context) {
 		luaScript := redis.NewScript(luaRemoveBlacklistIfExists)
-		expireSeconds := int(getRandomExpireTime(rediskey.BlacklistTTL).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(rediskey.BlacklistTTL).Seconds())
 		_, err := luaScript.Run(runCtx, r.redisClient,
 			[]string{cacheKey},
 			targetUUID,
@@ -420,7 +458,7 @@ func (r *blacklistRepositoryImpl) removeFriendCacheAsync(ctx context.Context, us
 	async.RunSafe(ctx, func(runCtx context.Context) {
 		luaScript := redis.NewScript(luaRemoveFriendMetaIfExists)
 		placeholderJSON := buildFriendMetaJSON("", "", "", 0)
-		expireSeconds := int(getRandomExpireTime(rediskey.BlacklistTTL).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(rediskey.FriendRelationTTL).Seconds())
 		_, err := luaScript.Run(runCtx, r.redisClient,
 			[]string{cacheKey},
 			friendUUID,
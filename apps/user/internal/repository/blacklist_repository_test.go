@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"ChatServer/model"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestBlacklistPeerUpdates(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	deletedAt := gorm.DeletedAt{Time: now.Add(-time.Hour), Valid: true}
+
+	t.Run("friend_removed", func(t *testing.T) {
+		friend := &model.UserRelation{Status: 0}
+		assert.True(t, isActiveFriend(friend))
+		assert.Equal(t, map[string]interface{}{
+			"status":     2,
+			"deleted_at": gorm.DeletedAt{Time: now, Valid: true},
+			"updated_at": now,
+		}, blacklistPeerUpdates(friend, now))
+	})
+
+	t.Run("peer_blocked_friend_not_restored", func(t *testing.T) {
+		blocked := &model.UserRelation{Status: 1}
+		assert.False(t, isActiveFriend(blocked))
+		assert.Equal(t, map[string]interface{}{
+			"status":     3,
+			"updated_at": now,
+		}, blacklistPeerUpdates(blocked, now))
+	})
+
+	t.Run("stranger_unchanged", func(t *testing.T) {
+		for _, relation := range []*model.UserRelation{
+			nil,
+			{Status: 2, DeletedAt: deletedAt},
+			{Status: 3},
+			{Status: 0, DeletedAt: deletedAt},
+		} {
+			assert.False(t, isActiveFriend(relation))
+			assert.Nil(t, blacklistPeerUpdates(relation, now))
+		}
+	})
+}
@@ -5,6 +5,9 @@ import (
 	"ChatServer/consts/redisKey"
 	"ChatServer/model"
 	pkgdeviceactive "ChatServer/pkg/deviceactive"
+	"ChatServer/pkg/logger"
+	pkgmysql "ChatServer/pkg/mysql"
+	"ChatServer/pkg/util"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
@@ -103,8 +106,10 @@ func (r *deviceRepositoryImpl) UpsertSession(ctx context.Context, session *model
 
 	// 直接执行 INSERT ... ON DUPLICATE KEY UPDATE
 	// 当唯一索引冲突时（user_uuid + device_id 已存在），执行 UPDATE
-	err := r.db.WithContext(ctx).
-		Exec(`
+	// 同一用户多设备并发登录时，唯一索引上的间隙锁可能导致死锁，按死锁重试。
+	err := pkgmysql.RetryOnDeadlock(ctx, func() error {
+		return WrapDBError(r.db.WithContext(ctx).
+			Exec(`
 			INSERT INTO device_session (
 				user_uuid, device_id, device_name, platform, 
 				app_version, ip, user_agent, status, created_at, updated_at
@@ -118,12 +123,12 @@ func (r *deviceRepositoryImpl) UpsertSession(ctx context.Context, session *model
 				status = ?,
 				updated_at = VALUES(updated_at)
 		`,
-			session.UserUuid, session.DeviceId, session.DeviceName, session.Platform,
-			session.AppVersion, session.IP, session.UserAgent, onlineStatus, now, now, onlineStatus,
-		).Error
-
+				session.UserUuid, session.DeviceId, session.DeviceName, session.Platform,
+				session.AppVersion, session.IP, session.UserAgent, onlineStatus, now, now, onlineStatus,
+			).Error)
+	})
 	if err != nil {
-		return WrapDBError(err)
+		return err
 	}
 
 	r.storeDeviceInfoCache(ctx, session, now)
@@ -437,6 +442,8 @@ func (r *deviceRepositoryImpl) StoreAccessToken(ctx context.Context, userUUID, d
 	key := r.accessTokenKey(userUUID, deviceID)
 	// 存储 MD5 哈希值以节省内存
 	value := md5Hash(accessToken)
+	// 覆盖前吊销旧 Token（刷新/同设备重新登录），避免其在有效期内通过 connect 的降级鉴权
+	r.revokeStoredAccessToken(ctx, key, value)
 	err := r.redisClient.Set(ctx, key, value, expireDuration).Err()
 	if err != nil {
 		// 发送到重试队列
@@ -497,6 +504,36 @@ func (r *deviceRepositoryImpl) GetRefreshToken(ctx context.Context, userUUID, de
 	return result, nil
 }
 
+// revokeStoredAccessToken 将 atKey 中当前存储的 Token 哈希写入吊销列表（TTL 为其剩余有效期）。
+// keepHash 与当前哈希相同时不吊销（重复存储同一 Token）。
+// 吊销为尽力而为：失败仅 log Warn，per-device 哈希校验仍是主校验路径。
+func (r *deviceRepositoryImpl) revokeStoredAccessToken(ctx context.Context, atKey, keepHash string) {
+	pipe := r.redisClient.Pipeline()
+	hashCmd := pipe.Get(ctx, atKey)
+	ttlCmd := pipe.TTL(ctx, atKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if err != redis.Nil {
+			logger.Warn(ctx, "读取旧 AccessToken 失败，跳过吊销",
+				logger.String("key", atKey),
+				logger.ErrorField("error", err),
+			)
+		}
+		return
+	}
+
+	oldHash := hashCmd.Val()
+	ttl := ttlCmd.Val()
+	if oldHash == "" || oldHash == keepHash || ttl <= 0 {
+		return
+	}
+	if err := r.redisClient.Set(ctx, rediskey.RevokedAccessTokenKey(oldHash), 1, ttl).Err(); err != nil {
+		logger.Warn(ctx, "写入 AccessToken 吊销列表失败",
+			logger.String("key", atKey),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
 // DeleteTokens 删除设备的所有 Token（用于踢出设备）
 func (r *deviceRepositoryImpl) DeleteTokens(ctx context.Context, userUUID, deviceID string) error {
 	if r.redisClient == nil {
@@ -505,6 +542,7 @@ func (r *deviceRepositoryImpl) DeleteTokens(ctx context.Context, userUUID, devic
 
 	atKey := r.accessTokenKey(userUUID, deviceID)
 	rtKey := r.refreshTokenKey(userUUID, deviceID)
+	r.revokeStoredAccessToken(ctx, atKey, "")
 
 	pipe := r.redisClient.Pipeline()
 	pipe.Del(ctx, atKey)
@@ -525,6 +563,64 @@ func (r *deviceRepositoryImpl) DeleteTokens(ctx context.Context, userUUID, devic
 	return nil
 }
 
+// GetTokenEpoch 获取设备当前令牌纪元
+// Key 不存在（从未踢出/改密，或已超过 TTL）时返回 0
+func (r *deviceRepositoryImpl) GetTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	epoch, err := r.redisClient.Get(ctx, rediskey.TokenEpochKey(userUUID, deviceID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, WrapRedisError(err)
+	}
+	return epoch, nil
+}
+
+// BumpTokenEpoch 递增设备令牌纪元并续期
+// 纪元是批量失效的唯一依据，失败时直接返回错误，不走异步重试（重试期间旧 Token 仍然有效）
+func (r *deviceRepositoryImpl) BumpTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	key := rediskey.TokenEpochKey(userUUID, deviceID)
+	pipe := r.redisClient.TxPipeline()
+	incrCmd := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, tokenStateTTL(rediskey.TokenEpochTTL))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, WrapRedisError(err)
+	}
+	return incrCmd.Val(), nil
+}
+
+// GetTokenVersion 获取用户当前令牌版本
+// Key 不存在（从未全局失效，或已超过 TTL）时返回 0
+func (r *deviceRepositoryImpl) GetTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	version, err := r.redisClient.Get(ctx, rediskey.TokenVersionKey(userUUID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, WrapRedisError(err)
+	}
+	return version, nil
+}
+
+// BumpTokenVersion 递增用户令牌版本并续期
+// 与 BumpTokenEpoch 相同，失败时直接返回错误，由调用方决定是否中断流程
+func (r *deviceRepositoryImpl) BumpTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	key := rediskey.TokenVersionKey(userUUID)
+	pipe := r.redisClient.TxPipeline()
+	incrCmd := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, tokenStateTTL(rediskey.TokenVersionTTL))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, WrapRedisError(err)
+	}
+	return incrCmd.Val(), nil
+}
+
+// tokenStateTTL 纪元/版本 Key 的 TTL 不短于当前配置的 Token 有效期，
+// 否则 Key 先于旧 Token 过期，版本回落为 0 后旧 Token 会重新通过校验
+func tokenStateTTL(base time.Duration) time.Duration {
+	return max(base, util.AccessTTL(), util.RefreshTTL())
+}
+
 // UpdateOnlineStatus 更新在线状态
 func (r *deviceRepositoryImpl) UpdateOnlineStatus(ctx context.Context, userUUID, deviceID string, status int8) error {
 	result := r.db.WithContext(ctx).
@@ -735,6 +831,37 @@ func (r *deviceRepositoryImpl) UpdateToken(ctx context.Context, userUUID, device
 }
 
 // DeleteByUserUUID 删除用户所有设备会话
+// 软删除 DB 会话记录，并清理所有设备的 Token 与设备缓存（Redis 失败进入重试队列）。
+// 幂等：会话已被软删除（如注销账号事务中已处理）时仍会清理 Redis。
 func (r *deviceRepositoryImpl) DeleteByUserUUID(ctx context.Context, userUUID string) error {
-	return nil // TODO: 删除用户所有设备会话
+	// 1. 查询该用户全部设备 ID（含已软删除的记录，确保 Token 能被清理）
+	var deviceIDs []string
+	err := r.db.WithContext(ctx).Unscoped().
+		Model(&model.DeviceSession{}).
+		Where("user_uuid = ?", userUUID).
+		Distinct().
+		Pluck("device_id", &deviceIDs).
+		Error
+	if err != nil {
+		return WrapDBError(err)
+	}
+
+	// 2. 软删除会话记录
+	err = r.db.WithContext(ctx).
+		Where("user_uuid = ?", userUUID).
+		Delete(&model.DeviceSession{}).
+		Error
+	if err != nil {
+		return WrapDBError(err)
+	}
+
+	// 3. 清理 Token 与设备缓存
+	keys := make([]string, 0, len(deviceIDs)*2+2)
+	for _, deviceID := range deviceIDs {
+		keys = append(keys, rediskey.AccessTokenKey(userUUID, deviceID), rediskey.RefreshTokenKey(userUUID, deviceID))
+	}
+	keys = append(keys, rediskey.DeviceInfoKey(userUUID), rediskey.DeviceActiveKey(userUUID))
+	delKeysWithRetry(ctx, r.redisClient, keys, "DeviceRepository.DeleteByUserUUID")
+
+	return nil
 }
@@ -3,10 +3,15 @@ package repository
 import (
 	"ChatServer/apps/user/mq"
 	"ChatServer/pkg/logger"
+	pkgmysql "ChatServer/pkg/mysql"
 	"context"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"net"
+	"syscall"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -20,6 +25,12 @@ var (
 	// ErrDuplicateKey 唯一键冲突
 	ErrDuplicateKey = errors.New("duplicate key")
 
+	// ErrDeadlock 事务死锁（MySQL 1213），整体重试事务即可恢复
+	ErrDeadlock = errors.New("deadlock")
+
+	// ErrConnRefused 数据库连接不可用（拒绝连接/连接失效），属于瞬时错误
+	ErrConnRefused = errors.New("database connection unavailable")
+
 	// ErrDatabase 数据库操作错误
 	ErrDatabase = errors.New("database error")
 
@@ -73,8 +84,44 @@ var (
 // ==================== 便捷函数 ====================
 
 // WrapDBError 包装数据库错误
+// 分类结果：
+//   - ErrRecordNotFound：记录不存在
+//   - ErrDuplicateKey：唯一键冲突（GORM ErrDuplicatedKey 或 MySQL 1062）
+//   - ErrDeadlock：死锁（MySQL 1213），可重试
+//   - ErrConnRefused：连接被拒绝/连接失效，瞬时错误
+//
+// 死锁与连接错误保留原始错误信息；其余未识别错误包装为 ErrDatabase（行为不变）。
 func WrapDBError(err error) error {
-	return wrapError(err, dbErrorRules, ErrDatabase)
+	if err == nil {
+		return nil
+	}
+	for source, target := range dbErrorRules {
+		if errors.Is(err, source) {
+			return target
+		}
+	}
+
+	if pkgmysql.IsDuplicateEntry(err) {
+		return ErrDuplicateKey
+	}
+	if pkgmysql.IsDeadlock(err) {
+		// 同时保留原始错误链，便于 pkgmysql.RetryOnDeadlock 识别
+		return fmt.Errorf("%w: %w", ErrDeadlock, err)
+	}
+	if isDBConnError(err) {
+		return fmt.Errorf("%w: %w", ErrConnRefused, err)
+	}
+	return fmt.Errorf("%w: %v", ErrDatabase, err)
+}
+
+// isDBConnError 判断是否为连接层错误（连接被拒绝、连接已失效）。
+func isDBConnError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
 }
 
 // WrapRedisError 包装 Redis 错误
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	pkgmysql "ChatServer/pkg/mysql"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestWrapDBErrorClassification(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "gorm not found", err: gorm.ErrRecordNotFound, want: ErrRecordNotFound},
+		{name: "gorm duplicated key", err: gorm.ErrDuplicatedKey, want: ErrDuplicateKey},
+		{name: "mysql 1062", err: &mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'uidx'"}, want: ErrDuplicateKey},
+		{name: "wrapped mysql 1213", err: fmt.Errorf("exec: %w", &mysqldriver.MySQLError{Number: 1213, Message: "Deadlock found"}), want: ErrDeadlock},
+		{name: "connection refused", err: dialErr, want: ErrConnRefused},
+		{name: "bad conn", err: driver.ErrBadConn, want: ErrConnRefused},
+		{name: "invalid conn", err: mysqldriver.ErrInvalidConn, want: ErrConnRefused},
+		{name: "other mysql error", err: &mysqldriver.MySQLError{Number: 1054, Message: "Unknown column"}, want: ErrDatabase},
+		{name: "unknown error", err: errors.New("boom"), want: ErrDatabase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapDBError(tt.err)
+			assert.ErrorIs(t, got, tt.want)
+		})
+	}
+	assert.NoError(t, WrapDBError(nil))
+	assert.True(t, pkgmysql.IsDeadlock(WrapDBError(&mysqldriver.MySQLError{Number: 1213})), "死锁包装后仍可被事务重试识别")
+	assert.Contains(t, WrapDBError(errors.New("boom")).Error(), "boom", "未识别错误保留原始信息")
+}
@@ -4,6 +4,9 @@ import (
 	"ChatServer/consts/redisKey"
 	"ChatServer/model"
 	"ChatServer/pkg/async"
+	"ChatServer/pkg/cachejitter"
+	"ChatServer/pkg/cursor"
+	pkgmysql "ChatServer/pkg/mysql"
 	"context"
 	"errors"
 	"time"
@@ -13,6 +16,12 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// 增量同步好友列表单页条数：未传时取默认值，超过上限时截断。
+const (
+	SyncFriendListDefaultLimit = 100
+	SyncFriendListMaxLimit     = 500
+)
+
 // friendRepositoryImpl 好友关系数据访问层实现
 type friendRepositoryImpl struct {
 	db          *gorm.DB
@@ -36,13 +45,7 @@ func (r *friendRepositoryImpl) GetFriendList(ctx context.Context, userUUID, grou
 
 	offset := (page - 1) * pageSize
 
-	// 基础条件：仅好友关系 + 指定用户 + 未删除
-	query := r.db.WithContext(ctx).
-		Model(&model.UserRelation{}).
-		Where("user_uuid = ? AND status = ? AND deleted_at IS NULL", userUUID, 0)
-	if groupTag != "" {
-		query = query.Where("group_tag = ?", groupTag)
-	}
+	query := r.friendListQuery(ctx, userUUID, groupTag)
 
 	var total int64
 	var version int64
@@ -72,6 +75,40 @@ func (r *friendRepositoryImpl) GetFriendList(ctx context.Context, userUUID, grou
 	return relations, total, version, nil
 }
 
+// GetFriendListByCursor 游标分页获取好友列表（排序与 GetFriendList 一致），返回是否还有下一页
+func (r *friendRepositoryImpl) GetFriendListByCursor(ctx context.Context, userUUID, groupTag string, after cursor.Cursor, limit int) ([]*model.UserRelation, bool, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// 多查一条用于判断是否还有下一页
+	var relations []*model.UserRelation
+	if err := r.friendListQuery(ctx, userUUID, groupTag).
+		Scopes(beforeCursor(after)).
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
+		Find(&relations).
+		Error; err != nil {
+		return nil, false, WrapDBError(err)
+	}
+
+	if len(relations) > limit {
+		return relations[:limit], true, nil
+	}
+	return relations, false, nil
+}
+
+// friendListQuery 好友列表基础条件：仅好友关系 + 指定用户 + 未删除（可选按分组过滤）
+func (r *friendRepositoryImpl) friendListQuery(ctx context.Context, userUUID, groupTag string) *gorm.DB {
+	query := r.db.WithContext(ctx).
+		Model(&model.UserRelation{}).
+		Where("user_uuid = ? AND status = ? AND deleted_at IS NULL", userUUID, 0)
+	if groupTag != "" {
+		query = query.Where("group_tag = ?", groupTag)
+	}
+	return query
+}
+
 // GetFriendRelation 获取好友关系
 func (r *friendRepositoryImpl) GetFriendRelation(ctx context.Context, userUUID, friendUUID string) (*model.UserRelation, error) {
 	return nil, nil // TODO: 实现获取好友关系
@@ -103,17 +140,19 @@ func (r *friendRepositoryImpl) CreateFriendRelation(ctx context.Context, userUUI
 		},
 	}
 
-	// 2. 批量 Upsert (Insert On Duplicate Key Update)
-	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
-		// 指定冲突列（必须是数据库的唯一索引列）
-		Columns: []clause.Column{{Name: "user_uuid"}, {Name: "peer_uuid"}},
-		// 冲突时执行更新操作
-		DoUpdates: clause.Assignments(map[string]interface{}{
-			"status":     0,   // 恢复正常状态
-			"deleted_at": nil, // 【关键】恢复软删除
-			"updated_at": now, // 更新时间
-		}),
-	}).Create(&relations).Error
+	// 2. 批量 Upsert (Insert On Duplicate Key Update)，双方并发互加时可能死锁，死锁时重试
+	err := pkgmysql.RetryOnDeadlock(ctx, func() error {
+		return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+			// 指定冲突列（必须是数据库的唯一索引列）
+			Columns: []clause.Column{{Name: "user_uuid"}, {Name: "peer_uuid"}},
+			// 冲突时执行更新操作
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"status":     0,   // 恢复正常状态
+				"deleted_at": nil, // 【关键】恢复软删除
+				"updated_at": now, // 更新时间
+			}),
+		}).Create(&relations).Error
+	})
 
 	if err != nil {
 		return WrapDBError(err)
@@ -218,8 +257,8 @@ func (r *friendRepositoryImpl) IsFriend(ctx context.Context, userUUID, friendUUI
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
 	// 无论 Key 是否存在，Expire 都是安全的 (不存在则返回0)
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.FriendRelationTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.FriendRelationTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -291,8 +330,8 @@ func (r *friendRepositoryImpl) checkFriendCache(ctx context.Context, userUUID, f
 	metaCmd := pipe.HGet(ctx, cacheKey, friendUUID)
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.FriendRelationTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.FriendRelationTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -338,8 +377,8 @@ func (r *friendRepositoryImpl) getFriendMetaCache(ctx context.Context, userUUID,
 	metaCmd := pipe.HGet(ctx, cacheKey, friendUUID)
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.FriendRelationTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.FriendRelationTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -388,8 +427,8 @@ func (r *friendRepositoryImpl) checkBlacklistCache(ctx context.Context, userUUID
 	memberCmd := pipe.SIsMember(ctx, cacheKey, peerUUID)
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.BlacklistTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.BlacklistTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -486,8 +525,11 @@ func (r *friendRepositoryImpl) GetRelationStatus(ctx context.Context, userUUID,
 
 // SyncFriendList 增量同步好友列表
 // 返回值: 变更列表, nextVersion(客户端下次用的时间戳), hasMore(是否还有更多), error
+// limit 在仓储层独立收敛到 [1, SyncFriendListMaxLimit]，避免绕过网关/服务层的请求一次拉取无界变更集；
+// 被截断时 hasMore=true 且 nextVersion 为本批最后一条的时间，客户端按 nextVersion 继续翻页即可追平。
 func (r *friendRepositoryImpl) SyncFriendList(ctx context.Context, userUUID string, version int64, limit int) ([]*model.UserRelation, int64, bool, error) {
     // 1. 准备查询
+    limit = ClampSyncFriendListLimit(limit)
     // 客户端传来的 version 是毫秒，转成 time.Time
     lastTime := time.UnixMilli(version)
     
@@ -509,42 +551,49 @@ func (r *friendRepositoryImpl) SyncFriendList(ctx context.Context, userUUID stri
     }
 
     // 3. 计算 hasMore 和 nextVersion
-    hasMore := false
-    var nextVersion int64
-
-    if len(relations) > limit {
-        hasMore = true
-        relations = relations[:limit] // 去掉多查的那一条
-        // 情况 A：还有更多数据，Cursor 必须是本批次最后一条的时间
-        nextVersion = relations[len(relations)-1].UpdatedAt.UnixMilli()
-    } else {
-        hasMore = false
-        // 情况 B：没有更多数据了（追平了）
-        // 这里的 nextVersion 可以是最后一条的时间，也可以是 ServerTime
-        // 推荐：取 ServerTime 并回退 5 秒（安全窗口），防止事务并发导致的数据丢失
-        safeTime := time.Now().Add(-5 * time.Second).UnixMilli()
-        
-        // 如果列表为空，直接用 safeTime；如果不为空，取 max(lastItem, safeTime)
-        if len(relations) > 0 {
-            lastItemTime := relations[len(relations)-1].UpdatedAt.UnixMilli()
-            if lastItemTime > safeTime {
-                nextVersion = lastItemTime
-            } else {
-                nextVersion = safeTime
-            }
-        } else {
-            // 如果本来就没数据，说明 version 已经很新了，保持原样或推进到 safeTime
-            if safeTime > version {
-                nextVersion = safeTime
-            } else {
-                nextVersion = version
-            }
-        }
-    }
-
+    relations, nextVersion, hasMore := pageSyncRelations(relations, limit, version, time.Now())
     return relations, nextVersion, hasMore, nil
 }
 
+// ClampSyncFriendListLimit 将增量同步单页条数收敛到 [1, SyncFriendListMaxLimit]，<=0 时取默认值。
+func ClampSyncFriendListLimit(limit int) int {
+	if limit <= 0 {
+		return SyncFriendListDefaultLimit
+	}
+	if limit > SyncFriendListMaxLimit {
+		return SyncFriendListMaxLimit
+	}
+	return limit
+}
+
+// pageSyncRelations 根据多查一条的结果计算本页数据、nextVersion 与 hasMore。
+// relations 须按 updated_at 升序，长度最多为 limit+1。
+func pageSyncRelations(relations []*model.UserRelation, limit int, version int64, now time.Time) ([]*model.UserRelation, int64, bool) {
+	if len(relations) > limit {
+		relations = relations[:limit] // 去掉多查的那一条
+		// 情况 A：还有更多数据，Cursor 必须是本批次最后一条的时间
+		return relations, relations[len(relations)-1].UpdatedAt.UnixMilli(), true
+	}
+
+	// 情况 B：没有更多数据了（追平了）
+	// 推荐：取 ServerTime 并回退 5 秒（安全窗口），防止事务并发导致的数据丢失
+	safeTime := now.Add(-5 * time.Second).UnixMilli()
+
+	// 如果列表为空，直接用 safeTime；如果不为空，取 max(lastItem, safeTime)
+	if len(relations) > 0 {
+		lastItemTime := relations[len(relations)-1].UpdatedAt.UnixMilli()
+		if lastItemTime > safeTime {
+			return relations, lastItemTime, false
+		}
+		return relations, safeTime, false
+	}
+	// 如果本来就没数据，说明 version 已经很新了，保持原样或推进到 safeTime
+	if safeTime > version {
+		return relations, safeTime, false
+	}
+	return relations, version, false
+}
+
 // BatchCheckIsFriend 批量检查是否为好友（使用Redis Hash优化）
 // 返回：map[peerUUID]isFriend
 func (r *friendRepositoryImpl) BatchCheckIsFriend(ctx context.Context, userUUID string, peerUUIDs []string) (map[string]bool, error) {
@@ -566,8 +615,8 @@ func (r *friendRepositoryImpl) BatchCheckIsFriend(ctx context.Context, userUUID
 
 	// 概率续期优化：1% 的概率在读取时顺便续期
 	// 无论 Key 是否存在，Expire 都是安全的 (不存在则返回0)
-	if getRandomBool(0.01) {
-		pipe.Expire(ctx, cacheKey, getRandomExpireTime(rediskey.FriendRelationTTL))
+	if cachejitter.Bool(0.01) {
+		pipe.Expire(ctx, cacheKey, cachejitter.ExpireTime(rediskey.FriendRelationTTL))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -641,6 +690,34 @@ func (r *friendRepositoryImpl) BatchCheckIsFriend(ctx context.Context, userUUID
 	return result, nil
 }
 
+// BatchCheckIsFriendOf 批量检查 peerUUID 是否在各 userUUIDs 的好友列表中。
+// 与 BatchCheckIsFriend 方向相反：好友缓存按 user_uuid 分 Key，反向批量判断无法合并为一次缓存读取，
+// 直接以 (user_uuid IN ?, peer_uuid) 单次查询 DB。
+func (r *friendRepositoryImpl) BatchCheckIsFriendOf(ctx context.Context, peerUUID string, userUUIDs []string) (map[string]bool, error) {
+	result := make(map[string]bool, len(userUUIDs))
+	if len(userUUIDs) == 0 {
+		return result, nil
+	}
+
+	var friendUUIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&model.UserRelation{}).
+		Where("user_uuid IN ? AND peer_uuid = ?", userUUIDs, peerUUID).
+		Where("status = ? AND deleted_at IS NULL", 0).
+		Pluck("user_uuid", &friendUUIDs).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+
+	for _, userUUID := range userUUIDs {
+		result[userUUID] = false
+	}
+	for _, userUUID := range friendUUIDs {
+		result[userUUID] = true
+	}
+	return result, nil
+}
+
 // invalidateFriendCacheAsync 异步更新双方的好友缓存
 // 在单个协程中同时处理 userUUID 和 friendUUID 的缓存更新
 func (r *friendRepositoryImpl) invalidateFriendCacheAsync(ctx context.Context, userUUID, friendUUID string) {
@@ -650,7 +727,7 @@ func (r *friendRepositoryImpl) invalidateFriendCacheAsync(ctx context.Context, u
 			{rediskey.FriendRelationKey(friendUUID), userUUID},
 		}
 		metaJSON := buildFriendMetaJSON("", "", "", time.Now().UnixMilli())
-		expireSeconds := int(getRandomExpireTime(rediskey.FriendRelationTTL).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(rediskey.FriendRelationTTL).Seconds())
 		luaScript := redis.NewScript(luaInsertFriendMetaIfExists)
 
 		for _, pair := range pairs {
@@ -679,7 +756,7 @@ func (r *friendRepositoryImpl) removeFriendCacheAsync(ctx context.Context, userU
 	async.RunSafe(ctx, func(runCtx context.Context) {
 		luaScript := redis.NewScript(luaRemoveFriendMetaIfExists)
 		placeholderJSON := buildFriendMetaJSON("", "", "", 0)
-		expireSeconds := int(getRandomExpireTime(rediskey.FriendRelationTTL).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(rediskey.FriendRelationTTL).Seconds())
 		_, err := luaScript.Run(runCtx, r.redisClient,
 			[]string{cacheKey},
 			friendUUID,
@@ -723,7 +800,7 @@ func (r *friendRepositoryImpl) rebuildFriendCacheAsync(ctx context.Context, user
 			if len(fields) > 0 {
 				pipe.HSet(runCtx, cacheKey, fields)
 			}
-			pipe.Expire(runCtx, cacheKey, getRandomExpireTime(rediskey.FriendRelationTTL))
+			pipe.Expire(runCtx, cacheKey, cachejitter.ExpireTime(rediskey.FriendRelationTTL))
 		}
 
 		if _, err := pipe.Exec(runCtx); err != nil && err != redis.Nil {
@@ -749,7 +826,7 @@ func (r *friendRepositoryImpl) updateFriendMetaCacheAsync(ctx context.Context, u
 			relation.Source,
 			relation.UpdatedAt.UnixMilli(),
 		)
-		expireSeconds := int(getRandomExpireTime(rediskey.FriendRelationTTL).Seconds())
+		expireSeconds := int(cachejitter.ExpireTime(rediskey.FriendRelationTTL).Seconds())
 		luaScript := redis.NewScript(luaUpsertFriendMetaIfExists)
 		_, err := luaScript.Run(runCtx, r.redisClient,
 			[]string{cacheKey},
@@ -802,7 +879,7 @@ func (r *friendRepositoryImpl) updateFriendRemarkCacheAsync(ctx context.Context,
 			meta.Remark = remark
 			meta.UpdatedAt = updatedAt
 			metaJSON := buildFriendMetaJSON(meta.Remark, meta.GroupTag, meta.Source, meta.UpdatedAt)
-			expireSeconds := int(getRandomExpireTime(rediskey.FriendRelationTTL).Seconds())
+			expireSeconds := int(cachejitter.ExpireTime(rediskey.FriendRelationTTL).Seconds())
 			luaScript := redis.NewScript(luaUpsertFriendMetaIfExists)
 			_, err = luaScript.Run(runCtx, r.redisClient,
 				[]string{cacheKey},
@@ -877,7 +954,7 @@ func (r *friendRepositoryImpl) updateFriendTagCacheAsync(ctx context.Context, us
 			meta.GroupTag = groupTag
 			meta.UpdatedAt = updatedAt
 			metaJSON := buildFriendMetaJSON(meta.Remark, meta.GroupTag, meta.Source, meta.UpdatedAt)
-			expireSeconds := int(getRandomExpireTime(rediskey.FriendRelationTTL).Seconds())
+			expireSeconds := int(cachejitter.ExpireTime(rediskey.FriendRelationTTL).Seconds())
 			luaScript := redis.NewScript(luaUpsertFriendMetaIfExists)
 			_, err = luaScript.Run(runCtx, r.redisClient,
 				[]string{cacheKey},
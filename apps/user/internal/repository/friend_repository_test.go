@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"ChatServer/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClampSyncFriendListLimit(t *testing.T) {
+	assert.Equal(t, SyncFriendListDefaultLimit, ClampSyncFriendListLimit(0))
+	assert.Equal(t, SyncFriendListDefaultLimit, ClampSyncFriendListLimit(-1))
+	assert.Equal(t, 1, ClampSyncFriendListLimit(1))
+	assert.Equal(t, SyncFriendListMaxLimit, ClampSyncFriendListLimit(SyncFriendListMaxLimit))
+	assert.Equal(t, SyncFriendListMaxLimit, ClampSyncFriendListLimit(100000))
+}
+
+// fakeSyncQuery 模拟 SyncFriendList 的查询：updated_at > version 升序取 limit+1 条。
+func fakeSyncQuery(all []*model.UserRelation, version int64, limit int) []*model.UserRelation {
+	result := make([]*model.UserRelation, 0, limit+1)
+	for _, relation := range all {
+		if relation.UpdatedAt.UnixMilli() > version {
+			result = append(result, relation)
+			if len(result) == limit+1 {
+				break
+			}
+		}
+	}
+	return result
+}
+
+func TestPageSyncRelations_OverMaxLimitClampedAndConverges(t *testing.T) {
+	now := time.Now()
+	base := now.Add(-time.Hour)
+	all := make([]*model.UserRelation, 1200)
+	for i := range all {
+		all[i] = &model.UserRelation{PeerUuid: "p", UpdatedAt: base.Add(time.Duration(i) * time.Millisecond)}
+	}
+
+	limit := ClampSyncFriendListLimit(100000)
+	var version int64
+	var pulled, pages int
+	for {
+		page, nextVersion, hasMore := pageSyncRelations(fakeSyncQuery(all, version, limit), limit, version, now)
+		require.LessOrEqual(t, len(page), SyncFriendListMaxLimit)
+		require.Greater(t, nextVersion, version, "游标必须前进")
+		pulled += len(page)
+		pages++
+		version = nextVersion
+		if !hasMore {
+			break
+		}
+		require.Less(t, pages, 10, "分页未收敛")
+	}
+
+	assert.Equal(t, len(all), pulled)
+	assert.Equal(t, 3, pages)
+	// 追平后 nextVersion 推进到安全窗口，不会低于最后一条变更。
+	assert.Equal(t, now.Add(-5*time.Second).UnixMilli(), version)
+}
+
+func TestPageSyncRelations_EmptyKeepsNewerVersion(t *testing.T) {
+	now := time.Now()
+	future := now.UnixMilli()
+
+	page, nextVersion, hasMore := pageSyncRelations(nil, 10, future, now)
+	assert.Empty(t, page)
+	assert.False(t, hasMore)
+	assert.Equal(t, future, nextVersion)
+}
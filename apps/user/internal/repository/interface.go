@@ -2,6 +2,7 @@ package repository
 
 import (
 	"ChatServer/model"
+	"ChatServer/pkg/cursor"
 	"context"
 	"time"
 )
@@ -88,6 +89,11 @@ type IUserRepository interface {
 	// Delete 软删除用户（注销账号）
 	Delete(ctx context.Context, userUUID string) error
 
+	// DeleteAccountCascade 注销账号级联软删除（单事务）：
+	// 用户、双向关系、待处理好友申请、设备会话；提交后尽力清理相关 Redis 缓存。
+	// 用户不存在或已注销时返回 ErrRecordNotFound。
+	DeleteAccountCascade(ctx context.Context, userUUID string) error
+
 	// ExistsByPhone 检查手机号是否已存在
 	ExistsByPhone(ctx context.Context, telephone string) (bool, error)
 
@@ -97,20 +103,15 @@ type IUserRepository interface {
 	// UpdatePassword 更新密码
 	UpdatePassword(ctx context.Context, userUUID, password string) error
 
-	// SaveQRCode 保存用户二维码
-	// 将二维码 token 与用户 UUID 的映射关系存储到 Redis
-	// 同时保存反向映射: user:qrcode:user:{userUUID} -> token
-	// 过期时间: 48小时
-	SaveQRCode(ctx context.Context, userUUID, token string) error
-
-	// GetUUIDByQRCodeToken 根据 token 获取用户 UUID
-	GetUUIDByQRCodeToken(ctx context.Context, token string) (string, error)
+	// SearchUser 按 mode 搜索用户（UUID/邮箱精确匹配、昵称模糊匹配或自动），按匹配程度排序并排除已将 searcherUUID 拉黑的用户
+	SearchUser(ctx context.Context, searcherUUID, keyword string, mode SearchMode, page, pageSize int) ([]*model.UserInfo, int64, error)
 
-	// GetQRCodeTokenByUserUUID 根据用户 UUID 获取二维码 token
-	GetQRCodeTokenByUserUUID(ctx context.Context, userUUID string) (string, time.Time, error)
+	// UpdatePresencePrivacy 更新在线状态隐私设置（同步维护 Redis 隐藏集合）
+	// scope 为 model.PresenceHideScope*，仅 hidden=true 时有意义
+	UpdatePresencePrivacy(ctx context.Context, userUUID string, hidden bool, scope int8) error
 
-	// SearchUser 搜索用户（按邮箱、昵称、UUID）
-	SearchUser(ctx context.Context, keyword string, page, pageSize int) ([]*model.UserInfo, int64, error)
+	// RebuildPresenceHiddenSet 以 MySQL 为准校准 Redis 隐藏集合，返回补入与移除的用户数
+	RebuildPresenceHiddenSet(ctx context.Context) (added, removed int, err error)
 }
 
 // ==================== 好友关系 Repository ====================
@@ -121,6 +122,9 @@ type IFriendRepository interface {
 	// GetFriendList 获取好友列表
 	GetFriendList(ctx context.Context, userUUID, groupTag string, page, pageSize int) ([]*model.UserRelation, int64, int64, error)
 
+	// GetFriendListByCursor 游标分页获取好友列表，返回是否还有下一页
+	GetFriendListByCursor(ctx context.Context, userUUID, groupTag string, after cursor.Cursor, limit int) ([]*model.UserRelation, bool, error)
+
 	// GetFriendRelation 获取好友关系
 	GetFriendRelation(ctx context.Context, userUUID, friendUUID string) (*model.UserRelation, error)
 
@@ -149,10 +153,14 @@ type IFriendRepository interface {
 	// 返回：map[peerUUID]isFriend
 	BatchCheckIsFriend(ctx context.Context, userUUID string, peerUUIDs []string) (map[string]bool, error)
 
+	// BatchCheckIsFriendOf 批量检查 peerUUID 是否在各 userUUIDs 的好友列表中（以各 userUUID 为准，单次查询 DB）
+	// 返回：map[userUUID]isFriend
+	BatchCheckIsFriendOf(ctx context.Context, peerUUID string, userUUIDs []string) (map[string]bool, error)
+
 	// GetRelationStatus 获取关系状态
 	GetRelationStatus(ctx context.Context, userUUID, peerUUID string) (*model.UserRelation, error)
 
-	// SyncFriendList 增量同步好友列表
+	// SyncFriendList 增量同步好友列表（limit 由仓储层收敛到 [1, SyncFriendListMaxLimit]）
 	SyncFriendList(ctx context.Context, userUUID string, version int64, limit int) ([]*model.UserRelation, int64, bool, error)
 }
 
@@ -163,7 +171,7 @@ type IApplyRepository interface {
 	// Create 创建好友申请
 	Create(ctx context.Context, apply *model.ApplyRequest) (*model.ApplyRequest, error)
 
-	// GetByID 根据ID获取好友申请
+	// GetByID 根据ID获取好友申请，不存在时返回 ErrRecordNotFound
 	GetByID(ctx context.Context, id int64) (*model.ApplyRequest, error)
 
 	// GetPendingList 获取待处理的好友申请列表
@@ -172,6 +180,12 @@ type IApplyRepository interface {
 	// GetSentList 获取发出的好友申请列表
 	GetSentList(ctx context.Context, applicantUUID string, status, page, pageSize int) ([]*model.ApplyRequest, int64, error)
 
+	// GetPendingListByCursor 游标分页获取收到的好友申请列表，返回是否还有下一页
+	GetPendingListByCursor(ctx context.Context, targetUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error)
+
+	// GetSentListByCursor 游标分页获取发出的好友申请列表，返回是否还有下一页
+	GetSentListByCursor(ctx context.Context, applicantUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error)
+
 	// UpdateStatus 更新申请状态
 	UpdateStatus(ctx context.Context, id int64, status int, remark string) error
 
@@ -179,14 +193,14 @@ type IApplyRepository interface {
 	// 返回值: alreadyProcessed=true 表示已被处理（幂等成功）
 	AcceptApplyAndCreateRelation(ctx context.Context, applyId int64, userUUID, friendUUID, remark string) (alreadyProcessed bool, err error)
 
-	// MarkAsRead 标记申请已读（同步）
+	// MarkAsRead 标记申请已读（同步），按实际置为已读的行数扣减未读计数，返回该行数
 	MarkAsRead(ctx context.Context, targetUUID string, ids []int64) (int64, error)
 
-	// MarkAllAsRead 标记当前用户所有申请已读（同步）
+	// MarkAllAsRead 标记当前用户所有申请已读（同步），按实际置为已读的行数扣减未读计数，返回该行数
 	MarkAllAsRead(ctx context.Context, targetUUID string) (int64, error)
 
-	// MarkAsReadAsync 异步标记申请已读（不阻塞主请求）
-	MarkAsReadAsync(ctx context.Context, ids []int64)
+	// MarkAsReadAsync 异步标记申请已读（不阻塞主请求），与 MarkAsRead 共用扣减逻辑
+	MarkAsReadAsync(ctx context.Context, targetUUID string, ids []int64)
 
 	// GetUnreadCount 获取未读申请数量
 	GetUnreadCount(ctx context.Context, targetUUID string) (int64, error)
@@ -194,11 +208,18 @@ type IApplyRepository interface {
 	// ClearUnreadCount 清除未读申请数量（红点清除）
 	ClearUnreadCount(ctx context.Context, targetUUID string) error
 
-	// ExistsPendingRequest 检查是否存在待处理的申请
+	// ExistsPendingRequest 检查是否存在未过期的待处理申请
 	ExistsPendingRequest(ctx context.Context, applicantUUID, targetUUID string) (bool, error)
 
-	// GetByIDWithInfo 根据ID获取好友申请（仅申请记录）
-	GetByIDWithInfo(ctx context.Context, id int64) (*model.ApplyRequest, error)
+	// ExpireApply 将已到期的待处理申请置为过期并移出待处理缓存（读取时惰性处理），未到期时为空操作
+	ExpireApply(ctx context.Context, apply *model.ApplyRequest) error
+
+	// ExpirePendingApplies 批量将已到期的待处理申请置为过期（后台扫描），返回本批过期数量
+	ExpirePendingApplies(ctx context.Context, limit int) (int64, error)
+
+	// GetByIDWithInfo 根据ID获取好友申请及申请人公开资料（单次联表查询）
+	// 申请不存在时返回 ErrRecordNotFound；申请人已注销时 applicant 为 nil
+	GetByIDWithInfo(ctx context.Context, id int64) (apply *model.ApplyRequest, applicant *model.UserInfo, err error)
 }
 
 // ==================== 黑名单 Repository ====================
@@ -295,4 +316,16 @@ type IDeviceRepository interface {
 
 	// DeleteTokens 删除设备的所有 Token（用于踢出设备）
 	DeleteTokens(ctx context.Context, userUUID, deviceID string) error
+
+	// GetTokenEpoch 获取设备当前令牌纪元（未设置时为 0）
+	GetTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error)
+
+	// BumpTokenEpoch 递增设备令牌纪元，使此前签发的 Token 全部失效
+	BumpTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error)
+
+	// GetTokenVersion 获取用户当前令牌版本（未设置时为 0）
+	GetTokenVersion(ctx context.Context, userUUID string) (int64, error)
+
+	// BumpTokenVersion 递增用户令牌版本，使该用户所有设备此前签发的 Token 全部失效
+	BumpTokenVersion(ctx context.Context, userUUID string) (int64, error)
 }
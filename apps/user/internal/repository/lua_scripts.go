@@ -16,6 +16,23 @@ if current == 1 then
 end
 
 return current
+`
+
+	// luaDecrByFloorZeroIfExists 计数器扣减（仅在 key 存在时扣减，结果下限为 0，保留原 TTL）
+	// KEYS[1]: 计数器 key
+	// ARGV[1]: 扣减数量
+	// 返回: 扣减后的值，key 不存在时返回 -1
+	luaDecrByFloorZeroIfExists = `
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+local remaining = (tonumber(current) or 0) - tonumber(ARGV[1])
+if remaining < 0 then
+	remaining = 0
+end
+redis.call('SET', KEYS[1], remaining, 'KEEPTTL')
+return remaining
 `
 
 	// luaAddPendingApplyIfExists 申请写入（仅在 key 存在时增量更新）
@@ -5,15 +5,16 @@ import (
 	"ChatServer/consts/redisKey"
 	"ChatServer/model"
 	"ChatServer/pkg/async"
+	"ChatServer/pkg/cachejitter"
 	"context"
 	"encoding/json"
 	"errors"
-	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // userRepositoryImpl 用户信息数据访问层实现
@@ -39,9 +40,10 @@ func (r *userRepositoryImpl) GetByUUID(ctx context.Context, uuid string) (*model
 			return nil, nil
 		}
 		var user model.UserInfo
-		if err := json.Unmarshal([]byte(cachedData), &user); err == nil {
+		if err := json.Unmarshal([]byte(cachedData), &user); err == nil && isCompleteUserInfoCache(&user, uuid) {
 			return &user, nil
 		}
+		// 反序列化失败或字段不完整（部分写入）：视为未命中，回源 MySQL 并覆盖缓存
 	}
 	if err != nil && err != redis.Nil {
 		LogRedisError(ctx, err) // 记录日志 降级处理
@@ -53,7 +55,7 @@ func (r *userRepositoryImpl) GetByUUID(ctx context.Context, uuid string) (*model
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// 存一份空到redis 5min过期
-			randomDuration := getRandomExpireTime(rediskey.UserInfoEmptyTTL)
+			randomDuration := cachejitter.ExpireTime(rediskey.UserInfoEmptyTTL)
 			async.RunSafe(ctx, func(runCtx context.Context) {
 				if err := r.redisClient.Set(runCtx, cacheKey, "{}", randomDuration).Err(); err != nil {
 					LogRedisError(runCtx, err)
@@ -73,10 +75,8 @@ func (r *userRepositoryImpl) GetByUUID(ctx context.Context, uuid string) (*model
 		return &user, nil
 	}
 
-	// 存入缓存，设置过期时间为 1 小时（+-5min缓冲）
-	// 随机时间防止缓存雪崩
-	randomDuration := time.Duration(rand.Intn(10)) * time.Minute
-	ttl := rediskey.UserInfoTTL - randomDuration
+	// 存入缓存，设置过期时间为 1 小时（±10% 随机抖动，防止缓存雪崩）
+	ttl := cachejitter.ExpireTime(rediskey.UserInfoTTL)
 	async.RunSafe(ctx, func(runCtx context.Context) {
 		if err := r.redisClient.Set(runCtx, cacheKey, userJSON, ttl).Err(); err != nil {
 			LogRedisError(runCtx, err)
@@ -86,6 +86,17 @@ func (r *userRepositoryImpl) GetByUUID(ctx context.Context, uuid string) (*model
 	return &user, nil
 }
 
+// isCompleteUserInfoCache 校验缓存中的用户信息是否完整。
+// 部分写入或旧版本结构可能缺失关键字段，此时应视为未命中回源，而不是返回残缺资料。
+// 判定依据为注册时必填且不会被清空的字段：uuid（须与查询一致）、昵称、手机号、创建时间。
+func isCompleteUserInfoCache(user *model.UserInfo, uuid string) bool {
+	return user != nil &&
+		user.Uuid == uuid &&
+		user.Nickname != "" &&
+		user.Telephone != "" &&
+		!user.CreatedAt.IsZero()
+}
+
 // GetByPhone 根据手机号查询用户信息
 func (r *userRepositoryImpl) GetByPhone(ctx context.Context, telephone string) (*model.UserInfo, error) {
 	return nil, nil // TODO: 实现查询用户信息
@@ -143,8 +154,8 @@ func (r *userRepositoryImpl) BatchGetByUUIDs(ctx context.Context, uuids []string
 			}
 
 			var user model.UserInfo
-			if err := json.Unmarshal([]byte(raw), &user); err != nil {
-				// 反序列化失败，需要回源
+			if err := json.Unmarshal([]byte(raw), &user); err != nil || !isCompleteUserInfoCache(&user, uuid) {
+				// 反序列化失败或字段不完整，需要回源
 				missUUIDs = append(missUUIDs, uuid)
 				continue
 			}
@@ -195,7 +206,7 @@ func (r *userRepositoryImpl) BatchGetByUUIDs(ctx context.Context, uuids []string
 					continue
 				}
 				cacheKey := rediskey.UserInfoKey(user.Uuid)
-				pipe.Set(runCtx, cacheKey, userJSON, getRandomExpireTime(rediskey.UserInfoTTL))
+				pipe.Set(runCtx, cacheKey, userJSON, cachejitter.ExpireTime(rediskey.UserInfoTTL))
 			}
 
 			// 对不存在的 UUID 写入空占位，避免缓存穿透
@@ -204,7 +215,7 @@ func (r *userRepositoryImpl) BatchGetByUUIDs(ctx context.Context, uuids []string
 					continue
 				}
 				cacheKey := rediskey.UserInfoKey(uuid)
-				pipe.Set(runCtx, cacheKey, "{}", getRandomExpireTime(rediskey.UserInfoEmptyTTL))
+				pipe.Set(runCtx, cacheKey, "{}", cachejitter.ExpireTime(rediskey.UserInfoEmptyTTL))
 			}
 
 			if _, err := pipe.Exec(runCtx); err != nil {
@@ -255,6 +266,147 @@ func (r *userRepositoryImpl) UpdateAvatar(ctx context.Context, userUUID, avatar
 	return nil
 }
 
+// UpdatePresencePrivacy 更新在线状态隐私设置
+// MySQL 为准；成功后同步维护 user:presence:hidden 集合供 connect 读取，失败进入重试队列。
+// 仅对所有人隐藏（hidden 且 scope=PresenceHideScopeEveryone）的用户进入集合，仅对非好友隐藏时好友仍收到推送。
+func (r *userRepositoryImpl) UpdatePresencePrivacy(ctx context.Context, userUUID string, hidden bool, scope int8) error {
+	var hidePresence int8
+	if hidden {
+		hidePresence = 1
+	}
+	result := r.db.WithContext(ctx).
+		Model(&model.UserInfo{}).
+		Where("uuid = ? AND deleted_at IS NULL", userUUID).
+		Updates(map[string]interface{}{
+			"hide_presence":       hidePresence,
+			"presence_hide_scope": scope,
+			"updated_at":          time.Now(),
+		})
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	cacheKey := rediskey.PresenceHiddenKey()
+	var task mq.RedisTask
+	var err error
+	if hidden && scope == model.PresenceHideScopeEveryone {
+		err = r.redisClient.SAdd(ctx, cacheKey, userUUID).Err()
+		task = mq.BuildSAddTask(cacheKey, userUUID)
+	} else {
+		err = r.redisClient.SRem(ctx, cacheKey, userUUID).Err()
+		task = mq.BuildSRemTask(cacheKey, userUUID)
+	}
+	if err != nil {
+		LogAndRetryRedisError(ctx, task.WithSource("UserRepository.UpdatePresencePrivacy"), err)
+	}
+
+	// 用户信息缓存中包含隐私设置，一并失效
+	infoKey := rediskey.UserInfoKey(userUUID)
+	if err := r.redisClient.Del(ctx, infoKey).Err(); err != nil {
+		delTask := mq.BuildDelTask(infoKey).
+			WithSource("UserRepository.UpdatePresencePrivacy")
+		LogAndRetryRedisError(ctx, delTask, err)
+	}
+
+	return nil
+}
+
+// presenceHiddenRebuildBatch 校准隐藏集合时单批读取/写入的用户数
+const presenceHiddenRebuildBatch = 1000
+
+// RebuildPresenceHiddenSet 以 MySQL 为准校准 user:presence:hidden 集合（集合无 TTL，Redis 数据丢失或
+// 重试队列积压时依赖此校准恢复），返回补入与移除的用户数。
+// 先按主键分批 SADD 所有对所有人隐藏的用户，再移除集合中已不满足条件的成员；移除前回查 MySQL，
+// 避免把校准期间刚设置隐藏的用户移出集合。直接修改原集合而非整体替换，不会覆盖并发的隐私设置。
+func (r *userRepositoryImpl) RebuildPresenceHiddenSet(ctx context.Context) (added, removed int, err error) {
+	cacheKey := rediskey.PresenceHiddenKey()
+	hidden := make(map[string]struct{})
+	var lastID int64
+	for {
+		var rows []struct {
+			ID   int64
+			Uuid string
+		}
+		err := r.db.WithContext(ctx).
+			Model(&model.UserInfo{}).
+			Select("id", "uuid").
+			Where("id > ? AND hide_presence = 1 AND presence_hide_scope = ? AND deleted_at IS NULL", lastID, model.PresenceHideScopeEveryone).
+			Order("id ASC").
+			Limit(presenceHiddenRebuildBatch).
+			Find(&rows).Error
+		if err != nil {
+			return added, removed, WrapDBError(err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		members := make([]interface{}, 0, len(rows))
+		for _, row := range rows {
+			hidden[row.Uuid] = struct{}{}
+			members = append(members, row.Uuid)
+		}
+		n, err := r.redisClient.SAdd(ctx, cacheKey, members...).Result()
+		if err != nil {
+			return added, removed, WrapRedisError(err)
+		}
+		added += int(n)
+		lastID = rows[len(rows)-1].ID
+		if len(rows) < presenceHiddenRebuildBatch {
+			break
+		}
+	}
+
+	members, err := r.redisClient.SMembers(ctx, cacheKey).Result()
+	if err != nil {
+		return added, removed, WrapRedisError(err)
+	}
+	stale := stalePresenceHiddenMembers(members, hidden)
+	for start := 0; start < len(stale); start += presenceHiddenRebuildBatch {
+		batch := stale[start:min(start+presenceHiddenRebuildBatch, len(stale))]
+		// 回查：校准期间刚设置为隐藏的用户保留在集合中
+		var stillHidden []string
+		err := r.db.WithContext(ctx).
+			Model(&model.UserInfo{}).
+			Where("uuid IN ? AND hide_presence = 1 AND presence_hide_scope = ? AND deleted_at IS NULL", batch, model.PresenceHideScopeEveryone).
+			Pluck("uuid", &stillHidden).Error
+		if err != nil {
+			return added, removed, WrapDBError(err)
+		}
+		keep := make(map[string]struct{}, len(stillHidden))
+		for _, uuid := range stillHidden {
+			keep[uuid] = struct{}{}
+		}
+		toRemove := stalePresenceHiddenMembers(batch, keep)
+		if len(toRemove) == 0 {
+			continue
+		}
+		args := make([]interface{}, 0, len(toRemove))
+		for _, uuid := range toRemove {
+			args = append(args, uuid)
+		}
+		n, err := r.redisClient.SRem(ctx, cacheKey, args...).Result()
+		if err != nil {
+			return added, removed, WrapRedisError(err)
+		}
+		removed += int(n)
+	}
+	return added, removed, nil
+}
+
+// stalePresenceHiddenMembers 返回 members 中不在 hidden 内的成员
+func stalePresenceHiddenMembers(members []string, hidden map[string]struct{}) []string {
+	var stale []string
+	for _, member := range members {
+		if _, ok := hidden[member]; !ok {
+			stale = append(stale, member)
+		}
+	}
+	return stale
+}
+
 // UpdateBasicInfo 更新基本信息
 func (r *userRepositoryImpl) UpdateBasicInfo(ctx context.Context, userUUID string, nickname, signature, birthday string, gender int8) error {
 	// 构造更新字段
@@ -325,7 +477,27 @@ func (r *userRepositoryImpl) UpdateEmail(ctx context.Context, userUUID, email st
 
 // UpdateTelephone 更新手机号
 func (r *userRepositoryImpl) UpdateTelephone(ctx context.Context, userUUID, telephone string) error {
-	return nil // TODO: 实现更新手机号
+	// 更新手机号到数据库（telephone 有唯一索引，并发换绑冲突时返回 ErrDuplicateKey）
+	err := r.db.WithContext(ctx).
+		Model(&model.UserInfo{}).
+		Where("uuid = ? AND deleted_at IS NULL", userUUID).
+		Update("telephone", telephone).
+		Error
+	if err != nil {
+		return WrapDBError(err)
+	}
+
+	// 更新成功后，删除Redis缓存
+	cacheKey := rediskey.UserInfoKey(userUUID)
+	err = r.redisClient.Del(ctx, cacheKey).Err()
+	if err != nil {
+		// 发送到重试队列
+		task := mq.BuildDelTask(cacheKey).
+			WithSource("UserRepository.UpdateTelephone")
+		LogAndRetryRedisError(ctx, task, err)
+	}
+
+	return nil
 }
 
 // Delete 软删除用户（注销账号）
@@ -353,9 +525,88 @@ func (r *userRepositoryImpl) Delete(ctx context.Context, userUUID string) error
 	return nil
 }
 
+// DeleteAccountCascade 注销账号级联软删除
+// MySQL 部分在同一事务内完成，任一步失败整体回滚：
+//  1. 软删除用户
+//  2. 软删除双向关系（好友/拉黑），并收集受影响的对端用户
+//  3. 软删除双向待处理的好友申请
+//  4. 软删除设备会话
+//
+// 事务提交后删除用户及对端的关系/申请缓存，失败的 Key 进入重试队列。
+func (r *userRepositoryImpl) DeleteAccountCascade(ctx context.Context, userUUID string) error {
+	var peerUUIDs, applyTargets []string
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 软删除用户
+		result := tx.Where("uuid = ?", userUUID).Delete(&model.UserInfo{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// 2. 收集对端用户后软删除双向关系
+		if err := tx.Model(&model.UserRelation{}).
+			Where("user_uuid = ?", userUUID).
+			Pluck("peer_uuid", &peerUUIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_uuid = ? OR peer_uuid = ?", userUUID, userUUID).
+			Delete(&model.UserRelation{}).Error; err != nil {
+			return err
+		}
+
+		// 3. 软删除待处理的好友申请（我发出的 + 发给我的）
+		if err := tx.Model(&model.ApplyRequest{}).
+			Where("apply_type = ? AND status = ? AND applicant_uuid = ?", 0, 0, userUUID).
+			Pluck("target_uuid", &applyTargets).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ApplyRequest{}).
+			Where("apply_type = ? AND status = ? AND (applicant_uuid = ? OR target_uuid = ?)", 0, 0, userUUID, userUUID).
+			Updates(map[string]interface{}{"pending_key": nil, "deleted_at": time.Now()}).Error; err != nil {
+			return err
+		}
+
+		// 4. 软删除设备会话（Token 等 Redis 状态由 DeviceRepository.DeleteByUserUUID 清理）
+		return tx.Where("user_uuid = ?", userUUID).Delete(&model.DeviceSession{}).Error
+	})
+	if err != nil {
+		return WrapDBError(err)
+	}
+
+	// 5. 尽力清理 Redis 缓存
+	keys := []string{
+		rediskey.UserInfoKey(userUUID),
+		rediskey.FriendRelationKey(userUUID),
+		rediskey.BlacklistRelationKey(userUUID),
+		rediskey.ApplyPendingKey(userUUID),
+		rediskey.ApplyUnreadNotifyKey(userUUID),
+	}
+	for _, peerUUID := range peerUUIDs {
+		keys = append(keys, rediskey.FriendRelationKey(peerUUID), rediskey.BlacklistRelationKey(peerUUID))
+	}
+	for _, targetUUID := range applyTargets {
+		keys = append(keys, rediskey.ApplyPendingKey(targetUUID))
+	}
+	delKeysWithRetry(ctx, r.redisClient, keys, "UserRepository.DeleteAccountCascade")
+
+	return nil
+}
+
 // ExistsByPhone 检查手机号是否已存在
 func (r *userRepositoryImpl) ExistsByPhone(ctx context.Context, telephone string) (bool, error) {
-	return false, nil // TODO: 实现检查手机号是否已存在
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.UserInfo{}).
+		Where("telephone = ? AND deleted_at IS NULL", telephone).
+		Count(&count).
+		Error
+	if err != nil {
+		return false, WrapDBError(err)
+	}
+	return count > 0, nil
 }
 
 // ExistsByEmail 检查邮箱是否已存在
@@ -397,78 +648,62 @@ func (r *userRepositoryImpl) UpdatePassword(ctx context.Context, userUUID, passw
 	return nil
 }
 
-// SaveQRCode 保存用户二维码
-// 将二维码 token 与用户 UUID 的映射关系存储到 Redis
-// 同时保存反向映射: user:qrcode:user:{userUUID} -> token
-// 过期时间: 48小时
-func (r *userRepositoryImpl) SaveQRCode(ctx context.Context, userUUID, token string) error {
-	// 1. 保存 token -> userUUID 映射
-	tokenKey := rediskey.QRCodeTokenKey(token)
-	err := r.redisClient.Set(ctx, tokenKey, userUUID, rediskey.QRCodeTTL).Err()
-	if err != nil {
-		return WrapRedisError(err)
-	}
-
-	// 2. 保存 userUUID -> token 反向映射
-	userKey := rediskey.QRCodeUserKey(userUUID)
-	err = r.redisClient.Set(ctx, userKey, token, rediskey.QRCodeTTL).Err()
-	if err != nil {
-		return WrapRedisError(err)
-	}
-
-	return nil
-}
+// SearchMode 用户搜索模式
+type SearchMode int
+
+const (
+	// SearchModeAuto 自动：含 @ 按邮箱精确匹配，否则 UUID 精确/前缀 + 昵称包含匹配
+	SearchModeAuto SearchMode = iota
+	// SearchModeUUID UUID 精确匹配（按 ID 添加好友，避免模糊误命中）
+	SearchModeUUID
+	// SearchModeEmail 邮箱精确匹配（走 uk_user_info_email 唯一索引）
+	SearchModeEmail
+	// SearchModeNickname 昵称模糊匹配（前缀匹配优先）
+	SearchModeNickname
+)
 
-// GetUUIDByQRCodeToken 根据 token 获取用户 UUID
-func (r *userRepositoryImpl) GetUUIDByQRCodeToken(ctx context.Context, token string) (string, error) {
-	tokenKey := rediskey.QRCodeTokenKey(token)
-	userUUID, err := r.redisClient.Get(ctx, tokenKey).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return "", ErrRedisNil
+// searchUserCondition 按搜索模式构建匹配条件与排序表达式。
+// 精确模式只使用等值条件以命中唯一索引，至多返回一行，无需按匹配程度排序。
+func searchUserCondition(mode SearchMode, keyword string) (string, []interface{}, *clause.Expr) {
+	escaped := escapeLike(keyword)
+	switch mode {
+	case SearchModeUUID:
+		return "uuid = ?", []interface{}{keyword}, nil
+	case SearchModeEmail:
+		return "email = ?", []interface{}{keyword}, nil
+	case SearchModeNickname:
+		return "nickname LIKE ?", []interface{}{"%" + escaped + "%"}, &clause.Expr{
+			SQL:  "CASE WHEN nickname LIKE ? THEN 0 ELSE 1 END",
+			Vars: []interface{}{escaped + "%"},
 		}
-		return "", WrapRedisError(err)
 	}
-	return userUUID, nil
-}
 
-// GetQRCodeTokenByUserUUID 根据用户 UUID 获取二维码 token和剩余时间
-func (r *userRepositoryImpl) GetQRCodeTokenByUserUUID(ctx context.Context, userUUID string) (string, time.Time, error) {
-	userKey := rediskey.QRCodeUserKey(userUUID)
-	pipe := r.redisClient.Pipeline()
-	pipe.Get(ctx, userKey)
-	pipe.TTL(ctx, userKey)
-	cmds, err := pipe.Exec(ctx)
-	if err != nil {
-		return "", time.Time{}, WrapRedisError(err)
+	// 自动模式：含 @ 视为邮箱（简单判断），全匹配
+	if strings.Contains(keyword, "@") {
+		return "email = ?", []interface{}{keyword}, nil
+	}
+	// 非邮箱格式：UUID 精确/前缀匹配，昵称包含匹配
+	return "(uuid = ? OR uuid LIKE ? OR nickname LIKE ?)", []interface{}{keyword, escaped + "%", "%" + escaped + "%"}, &clause.Expr{
+		SQL:  "CASE WHEN uuid = ? THEN 0 WHEN nickname LIKE ? THEN 1 ELSE 2 END",
+		Vars: []interface{}{keyword, escaped + "%"},
 	}
-	token := cmds[0].(*redis.StringCmd).Val()
-	expireTime := time.Now().Add(cmds[1].(*redis.DurationCmd).Val().Round(time.Second))
-	return token, expireTime, nil
 }
 
-// SearchUser 搜索用户（按邮箱、昵称、UUID）
-func (r *userRepositoryImpl) SearchUser(ctx context.Context, keyword string, page, pageSize int) ([]*model.UserInfo, int64, error) {
+// SearchUser 按 mode 搜索用户
+// 排序：自动模式下 UUID 精确匹配 > 昵称前缀匹配 > 昵称包含（及 UUID 前缀）匹配；昵称模式下前缀匹配优先；同档按注册时间倒序。
+// 已将 searcherUUID 拉黑的用户不出现在结果中（与 IsBlocked 口径一致：user_relation.status IN (1,3)）。
+func (r *userRepositoryImpl) SearchUser(ctx context.Context, searcherUUID, keyword string, mode SearchMode, page, pageSize int) ([]*model.UserInfo, int64, error) {
 	// 计算偏移量
 	offset := (page - 1) * pageSize
 
-	// 判断关键词是否为邮箱格式（简单判断：包含@符号）
-	isEmail := strings.Contains(keyword, "@")
-
-	// 构建查询条件
+	// 构建查询条件：排除已将搜索者拉黑的用户
+	cond, args, rank := searchUserCondition(mode, keyword)
 	query := r.db.WithContext(ctx).
 		Model(&model.UserInfo{}).
-		Where("deleted_at IS NULL")
-
-	if isEmail {
-		// 邮箱格式：全匹配
-		query = query.Where("email = ?", keyword)
-	} else {
-		// 非邮箱格式：模糊搜索（昵称、UUID）
-		query = query.Where("(nickname LIKE ? OR uuid LIKE ?)",
-			keyword+"%",
-			keyword+"%")
-	}
+		Where("deleted_at IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM user_relation AS ur WHERE ur.user_uuid = user_info.uuid AND ur.peer_uuid = ? AND ur.status IN ? AND ur.deleted_at IS NULL)",
+			searcherUUID, []int{1, 3}).
+		Where(cond, args...)
 
 	// 先查询总数
 	var total int64
@@ -477,6 +712,9 @@ func (r *userRepositoryImpl) SearchUser(ctx context.Context, keyword string, pag
 	}
 
 	// 查询用户列表
+	if rank != nil {
+		query = query.Order(*rank)
+	}
 	var users []*model.UserInfo
 	if err := query.
 		Order("created_at DESC").
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ChatServer/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCompleteUserInfoCache(t *testing.T) {
+	full := model.UserInfo{
+		Uuid:      "u1",
+		Nickname:  "alice",
+		Telephone: "13800138000",
+		CreatedAt: time.Unix(1700000000, 0),
+	}
+	raw, err := json.Marshal(full)
+	require.NoError(t, err)
+
+	var cached model.UserInfo
+	require.NoError(t, json.Unmarshal(raw, &cached))
+	assert.True(t, isCompleteUserInfoCache(&cached, "u1"))
+
+	cases := map[string]string{
+		"only_uuid":        `{"Uuid":"u1"}`,
+		"missing_nickname": `{"Uuid":"u1","Telephone":"13800138000","CreatedAt":"2023-11-14T22:13:20Z"}`,
+		"missing_phone":    `{"Uuid":"u1","Nickname":"alice","CreatedAt":"2023-11-14T22:13:20Z"}`,
+		"missing_created":  `{"Uuid":"u1","Nickname":"alice","Telephone":"13800138000"}`,
+		"uuid_mismatch":    `{"Uuid":"u2","Nickname":"alice","Telephone":"13800138000","CreatedAt":"2023-11-14T22:13:20Z"}`,
+	}
+	for name, partial := range cases {
+		t.Run(name, func(t *testing.T) {
+			var user model.UserInfo
+			require.NoError(t, json.Unmarshal([]byte(partial), &user))
+			assert.False(t, isCompleteUserInfoCache(&user, "u1"), "部分写入的缓存应视为未命中")
+		})
+	}
+
+	assert.False(t, isCompleteUserInfoCache(nil, "u1"))
+}
+
+func TestSearchUserCondition(t *testing.T) {
+	cond, args, rank := searchUserCondition(SearchModeUUID, "u1000000000000000001")
+	assert.Equal(t, "uuid = ?", cond)
+	assert.Equal(t, []interface{}{"u1000000000000000001"}, args)
+	assert.Nil(t, rank, "精确匹配至多一行，无需排序")
+
+	cond, args, rank = searchUserCondition(SearchModeEmail, "a@b.com")
+	assert.Equal(t, "email = ?", cond)
+	assert.Equal(t, []interface{}{"a@b.com"}, args)
+	assert.Nil(t, rank)
+
+	cond, args, rank = searchUserCondition(SearchModeNickname, "a_b")
+	assert.Equal(t, "nickname LIKE ?", cond)
+	assert.Equal(t, []interface{}{`%a\_b%`}, args)
+	require.NotNil(t, rank)
+	assert.Equal(t, []interface{}{`a\_b%`}, rank.Vars)
+
+	// 自动模式：含 @ 按邮箱精确匹配，否则 UUID 精确/前缀 + 昵称包含
+	cond, _, rank = searchUserCondition(SearchModeAuto, "a@b.com")
+	assert.Equal(t, "email = ?", cond)
+	assert.Nil(t, rank)
+	cond, args, rank = searchUserCondition(SearchModeAuto, "alice")
+	assert.Equal(t, "(uuid = ? OR uuid LIKE ? OR nickname LIKE ?)", cond)
+	assert.Equal(t, []interface{}{"alice", "alice%", "%alice%"}, args)
+	require.NotNil(t, rank)
+}
+
+func TestStalePresenceHiddenMembers(t *testing.T) {
+	hidden := map[string]struct{}{"u1": {}, "u3": {}}
+	assert.Equal(t, []string{"u2", "u4"}, stalePresenceHiddenMembers([]string{"u1", "u2", "u3", "u4"}, hidden))
+	assert.Empty(t, stalePresenceHiddenMembers([]string{"u1"}, hidden))
+	assert.Empty(t, stalePresenceHiddenMembers(nil, hidden))
+}
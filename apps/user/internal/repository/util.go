@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"ChatServer/apps/user/mq"
+	"ChatServer/pkg/cursor"
+	"context"
 	"encoding/json"
-	"math/rand"
 	"strings"
-	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
 type friendMeta struct {
@@ -43,20 +47,37 @@ func isRedisWrongType(err error) bool {
 	return strings.Contains(err.Error(), "WRONGTYPE")
 }
 
-// getRandomExpireTime 生成带随机抖动的过期时间
-// baseExpire: 基础过期时间
-// 返回: 基础过期时间 ± 10% 的随机时间
-func getRandomExpireTime(baseExpire time.Duration) time.Duration {
-	// 计算随机抖动范围（±10%）
-	jitterRange := float64(baseExpire) * 0.1
-	jitter := time.Duration(rand.Float64()*float64(jitterRange)*2 - float64(jitterRange))
+// delKeysWithRetry 批量删除 Redis Key（尽力而为）
+// 整体删除失败时按 Key 拆分投递到重试队列，source 用于排查来源。
+func delKeysWithRetry(ctx context.Context, redisClient *redis.Client, keys []string, source string) {
+	if len(keys) == 0 {
+		return
+	}
+	err := redisClient.Del(ctx, keys...).Err()
+	if err == nil {
+		return
+	}
+	for _, key := range keys {
+		task := mq.BuildDelTask(key).WithSource(source)
+		LogAndRetryRedisError(ctx, task, err)
+	}
+}
+
+// likeEscaper 转义 LIKE 通配符（MySQL 默认转义符为反斜杠），避免关键词中的 % / _ 被当作通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
 
-	return baseExpire + jitter
+// escapeLike 转义 LIKE 模式中的特殊字符
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
 
-// getRandomBool 生成随机布尔值
-// probability: 概率
-// 返回: 概率为probability的布尔值
-func getRandomBool(probability float64) bool {
-	return rand.Float64() < probability
+// beforeCursor 游标分页条件：按 created_at DESC, id DESC 排序时，取位于游标之后（更早）的记录；空游标不加条件
+func beforeCursor(c cursor.Cursor) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !c.IsRow() {
+			return db
+		}
+		createdAt := c.CreatedAt()
+		return db.Where("(created_at < ? OR (created_at = ? AND id < ?))", createdAt, createdAt, c.ID)
+	}
 }
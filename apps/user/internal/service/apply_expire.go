@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ChatServer/apps/user/internal/repository"
+	"ChatServer/pkg/logger"
+)
+
+// applyExpireMaxBatches 单次扫描最多处理的批数，避免积压过多时长时间占用数据库
+const applyExpireMaxBatches = 20
+
+// StartApplyExpireSweeper 启动好友申请过期扫描：每 interval 将已到期的待处理申请置为过期（status=3）
+// 并移出待处理缓存。interval<=0 时不启动（仅依赖读取时的惰性过期）。ctx 取消后停止。
+func StartApplyExpireSweeper(ctx context.Context, applyRepo repository.IApplyRepository, interval time.Duration, batchSize int) {
+	if interval <= 0 || applyRepo == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sweepExpiredApplies(ctx, applyRepo, batchSize)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// sweepExpiredApplies 分批处理到期申请，直到某批不足 batchSize 或达到单次批数上限，返回本次过期总数
+func sweepExpiredApplies(ctx context.Context, applyRepo repository.IApplyRepository, batchSize int) int64 {
+	var total int64
+	for i := 0; i < applyExpireMaxBatches && ctx.Err() == nil; i++ {
+		n, err := applyRepo.ExpirePendingApplies(ctx, batchSize)
+		if err != nil {
+			logger.Error(ctx, "好友申请过期扫描失败", logger.ErrorField("error", err))
+			break
+		}
+		total += n
+		if n < int64(batchSize) {
+			break
+		}
+	}
+	if total > 0 {
+		logger.Info(ctx, "好友申请过期扫描完成", logger.Int64("expired", total))
+	}
+	return total
+}
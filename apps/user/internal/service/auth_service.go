@@ -3,7 +3,6 @@ package service
 import (
 	"ChatServer/apps/user/internal/converter"
 	"ChatServer/apps/user/internal/repository"
-	"ChatServer/apps/user/internal/utils"
 	pb "ChatServer/apps/user/pb"
 	"ChatServer/consts"
 	"ChatServer/model"
@@ -64,6 +63,8 @@ func getRequiredDeviceID(ctx context.Context) (string, error) {
 type authServiceImpl struct {
 	authRepo   repository.IAuthRepository
 	deviceRepo repository.IDeviceRepository
+	// sendCodeEmail 验证码邮件发送函数，默认 util.SendVerifyCodeEmail，测试中替换
+	sendCodeEmail func(toEmail, code string, expireMinutes int) error
 }
 
 // NewAuthService 创建认证服务实例
@@ -72,11 +73,31 @@ func NewAuthService(
 	deviceRepo repository.IDeviceRepository,
 ) AuthService {
 	return &authServiceImpl{
-		authRepo:   authRepo,
-		deviceRepo: deviceRepo,
+		authRepo:      authRepo,
+		deviceRepo:    deviceRepo,
+		sendCodeEmail: util.SendVerifyCodeEmail,
 	}
 }
 
+// generateAccessToken 读取设备当前令牌纪元与用户令牌版本并签发 Access Token
+func (s *authServiceImpl) generateAccessToken(ctx context.Context, userUUID, deviceID string) (string, error) {
+	return signAccessToken(ctx, s.deviceRepo, userUUID, deviceID)
+}
+
+// signAccessToken 按设备当前令牌纪元与用户令牌版本签发 Access Token
+// 纪元/版本读取失败时不签发（否则签出的低纪元/低版本 Token 会被拒绝，或在回退时放过已失效 Token）
+func signAccessToken(ctx context.Context, deviceRepo repository.IDeviceRepository, userUUID, deviceID string) (string, error) {
+	epoch, err := deviceRepo.GetTokenEpoch(ctx, userUUID, deviceID)
+	if err != nil {
+		return "", err
+	}
+	version, err := deviceRepo.GetTokenVersion(ctx, userUUID)
+	if err != nil {
+		return "", err
+	}
+	return util.GenerateTokenWithVersion(userUUID, deviceID, epoch, version)
+}
+
 // Register 用户注册
 // 业务流程：
 //  1. 校验验证码
@@ -90,13 +111,13 @@ func NewAuthService(
 func (s *authServiceImpl) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
 	// 记录注册请求
 	logger.Info(ctx, "用户注册请求",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 		logger.String("nickname", req.Nickname),
 		logger.String("telephone", req.Telephone),
 	)
 
 	// 1. 校验验证码（type=1: 注册）
-	isValid, err := s.authRepo.VerifyVerifyCode(ctx, req.Email, req.VerifyCode, 1)
+	isValid, err := verifyCodeMatches(ctx, s.authRepo, req.Email, req.VerifyCode, 1)
 	if err != nil {
 		// 判断是 Redis Key 不存在还是其他错误
 		if errors.Is(err, repository.ErrRedisNil) {
@@ -180,7 +201,7 @@ func (s *authServiceImpl) Login(ctx context.Context, req *pb.LoginRequest) (*pb.
 
 	// 记录登录请求（账号脱敏）
 	logger.Info(ctx, "用户登录请求",
-		logger.String("account", utils.MaskEmail(req.Account)),
+		logger.String("account", util.MaskEmail(req.Account)),
 		logger.String("device_name", req.DeviceInfo.GetDeviceName()),
 		logger.String("platform", req.DeviceInfo.GetPlatform()),
 	)
@@ -222,7 +243,7 @@ func (s *authServiceImpl) Login(ctx context.Context, req *pb.LoginRequest) (*pb.
 	clientIP := util.GetClientIPFromContext(ctx)
 
 	// 6. 生成访问令牌
-	accessToken, err := util.GenerateToken(user.Uuid, deviceID)
+	accessToken, err := s.generateAccessToken(ctx, user.Uuid, deviceID)
 	if err != nil {
 		logger.Error(ctx, "生成访问令牌失败",
 			logger.ErrorField("error", err),
@@ -234,14 +255,14 @@ func (s *authServiceImpl) Login(ctx context.Context, req *pb.LoginRequest) (*pb.
 	refreshToken := util.GenIDString()
 
 	// 8. 写入 Redis（AccessToken 和 RefreshToken）
-	if err := s.deviceRepo.StoreAccessToken(ctx, user.Uuid, deviceID, accessToken, util.AccessExpire); err != nil {
+	if err := s.deviceRepo.StoreAccessToken(ctx, user.Uuid, deviceID, accessToken, util.AccessTTL()); err != nil {
 		logger.Error(ctx, "AccessToken 写入 Redis 失败",
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	if err := s.deviceRepo.StoreRefreshToken(ctx, user.Uuid, deviceID, refreshToken, util.RefreshExpire); err != nil {
+	if err := s.deviceRepo.StoreRefreshToken(ctx, user.Uuid, deviceID, refreshToken, util.RefreshTTL()); err != nil {
 		logger.Error(ctx, "RefreshToken 写入 Redis 失败",
 			logger.ErrorField("error", err),
 		)
@@ -281,7 +302,7 @@ func (s *authServiceImpl) Login(ctx context.Context, req *pb.LoginRequest) (*pb.
 
 	// 11. 登录成功
 	logger.Info(ctx, "用户登录成功",
-		logger.String("account", utils.MaskEmail(req.Account)),
+		logger.String("account", util.MaskEmail(req.Account)),
 		logger.String("platform", req.DeviceInfo.GetPlatform()),
 	)
 
@@ -289,7 +310,7 @@ func (s *authServiceImpl) Login(ctx context.Context, req *pb.LoginRequest) (*pb.
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		TokenType:    "Bearer",
-		ExpiresIn:    int64(util.AccessExpire.Seconds()),
+		ExpiresIn:    int64(util.AccessTTL().Seconds()),
 		UserInfo:     converter.ModelToProtoUserInfo(user),
 	}, nil
 }
@@ -317,7 +338,7 @@ func (s *authServiceImpl) LoginByCode(ctx context.Context, req *pb.LoginByCodeRe
 
 	// 记录验证码登录请求（邮箱脱敏）
 	logger.Info(ctx, "验证码登录请求",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 		logger.String("device_name", req.DeviceInfo.GetDeviceName()),
 		logger.String("platform", req.DeviceInfo.GetPlatform()),
 	)
@@ -349,7 +370,7 @@ func (s *authServiceImpl) LoginByCode(ctx context.Context, req *pb.LoginByCodeRe
 	}
 
 	// 4. 校验验证码（type=2: 登录）
-	isValid, err := s.authRepo.VerifyVerifyCode(ctx, req.Email, req.VerifyCode, 2)
+	isValid, err := verifyCodeMatches(ctx, s.authRepo, req.Email, req.VerifyCode, 2)
 	if err != nil {
 		// 判断是 Redis Key 不存在还是其他错误
 		if errors.Is(err, repository.ErrRedisNil) {
@@ -379,7 +400,7 @@ func (s *authServiceImpl) LoginByCode(ctx context.Context, req *pb.LoginByCodeRe
 	clientIP := util.GetClientIPFromContext(ctx)
 
 	// 7. 生成访问令牌
-	accessToken, err := util.GenerateToken(user.Uuid, deviceID)
+	accessToken, err := s.generateAccessToken(ctx, user.Uuid, deviceID)
 	if err != nil {
 		logger.Error(ctx, "生成访问令牌失败",
 			logger.ErrorField("error", err),
@@ -391,14 +412,14 @@ func (s *authServiceImpl) LoginByCode(ctx context.Context, req *pb.LoginByCodeRe
 	refreshToken := util.GenIDString()
 
 	// 9. 写入 Redis（AccessToken 和 RefreshToken）
-	if err := s.deviceRepo.StoreAccessToken(ctx, user.Uuid, deviceID, accessToken, util.AccessExpire); err != nil {
+	if err := s.deviceRepo.StoreAccessToken(ctx, user.Uuid, deviceID, accessToken, util.AccessTTL()); err != nil {
 		logger.Error(ctx, "AccessToken 写入 Redis 失败",
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	if err := s.deviceRepo.StoreRefreshToken(ctx, user.Uuid, deviceID, refreshToken, util.RefreshExpire); err != nil {
+	if err := s.deviceRepo.StoreRefreshToken(ctx, user.Uuid, deviceID, refreshToken, util.RefreshTTL()); err != nil {
 		logger.Error(ctx, "RefreshToken 写入 Redis 失败",
 			logger.ErrorField("error", err),
 		)
@@ -438,7 +459,7 @@ func (s *authServiceImpl) LoginByCode(ctx context.Context, req *pb.LoginByCodeRe
 
 	// 12. 登录成功，记录日志
 	logger.Info(ctx, "验证码登录成功",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 		logger.String("platform", req.DeviceInfo.GetPlatform()),
 	)
 
@@ -446,7 +467,7 @@ func (s *authServiceImpl) LoginByCode(ctx context.Context, req *pb.LoginByCodeRe
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		TokenType:    "Bearer",
-		ExpiresIn:    int64(util.AccessExpire.Seconds()),
+		ExpiresIn:    int64(util.AccessTTL().Seconds()),
 		UserInfo:     converter.ModelToProtoUserInfo(user),
 	}, nil
 }
@@ -479,8 +500,8 @@ func (s *authServiceImpl) SendVerifyCode(ctx context.Context, req *pb.SendVerify
 		return nil, status.Error(codes.ResourceExhausted, strconv.Itoa(consts.CodeSendTooFrequent))
 	}
 
-	// 3. 生成6位验证码
-	code, err := util.GenerateVerifyCode(6)
+	// 3. 按验证码类型的规范生成验证码（默认 6 位数字）
+	code, err := util.VerifyCodeSpecFor(req.Type).Generate()
 	if err != nil {
 		logger.Error(ctx, "生成验证码失败",
 			logger.ErrorField("error", err),
@@ -507,7 +528,7 @@ func (s *authServiceImpl) SendVerifyCode(ctx context.Context, req *pb.SendVerify
 	}
 
 	// 6. 发送验证码邮件
-	err = util.SendVerifyCodeEmail(req.Email, code, 2) // 2分钟有效期
+	err = s.sendCodeEmail(req.Email, code, 2) // 2分钟有效期
 	if err != nil {
 		logger.Error(ctx, "发送验证码邮件失败",
 			logger.ErrorField("error", err),
@@ -535,12 +556,12 @@ func (s *authServiceImpl) SendVerifyCode(ctx context.Context, req *pb.SendVerify
 func (s *authServiceImpl) VerifyCode(ctx context.Context, req *pb.VerifyCodeRequest) (*pb.VerifyCodeResponse, error) {
 	// 记录校验验证码请求（邮箱脱敏）
 	logger.Info(ctx, "校验验证码请求",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 		logger.Int("type", int(req.Type)),
 	)
 
-	// 1. 校验验证码（type参数：1:注册 2:登录 3:重置密码 4:换绑邮箱）
-	isValid, err := s.authRepo.VerifyVerifyCode(ctx, req.Email, req.VerifyCode, req.Type)
+	// 1. 校验验证码（type参数：1:注册 2:登录 3:重置密码 4:换绑邮箱 5:换绑手机 6:注销账号）
+	isValid, err := verifyCodeMatches(ctx, s.authRepo, req.Email, req.VerifyCode, req.Type)
 	if err != nil {
 		// 判断是 Redis Key 不存在还是其他错误
 		if errors.Is(err, repository.ErrRedisNil) {
@@ -554,7 +575,7 @@ func (s *authServiceImpl) VerifyCode(ctx context.Context, req *pb.VerifyCodeRequ
 
 	// 2. 返回验证结果
 	logger.Info(ctx, "验证码校验结果",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 		logger.Bool("valid", isValid),
 	)
 
@@ -619,7 +640,7 @@ func (s *authServiceImpl) RefreshToken(ctx context.Context, req *pb.RefreshToken
 	}
 
 	// 4. 生成新的 Access Token
-	newAccessToken, err := util.GenerateToken(userUUID, deviceID)
+	newAccessToken, err := s.generateAccessToken(ctx, userUUID, deviceID)
 	if err != nil {
 		logger.Error(ctx, "生成 Access Token 失败",
 			logger.ErrorField("error", err),
@@ -628,7 +649,7 @@ func (s *authServiceImpl) RefreshToken(ctx context.Context, req *pb.RefreshToken
 	}
 
 	// 5. 更新 Redis 中的 Access Token
-	if err := s.deviceRepo.StoreAccessToken(ctx, userUUID, deviceID, newAccessToken, util.AccessExpire); err != nil {
+	if err := s.deviceRepo.StoreAccessToken(ctx, userUUID, deviceID, newAccessToken, util.AccessTTL()); err != nil {
 		logger.Error(ctx, "更新 Access Token 失败",
 			logger.ErrorField("error", err),
 		)
@@ -652,7 +673,7 @@ func (s *authServiceImpl) RefreshToken(ctx context.Context, req *pb.RefreshToken
 	return &pb.RefreshTokenResponse{
 		AccessToken: newAccessToken,
 		TokenType:   "Bearer",
-		ExpiresIn:   int64(util.AccessExpire.Seconds()),
+		ExpiresIn:   int64(util.AccessTTL().Seconds()),
 	}, nil
 }
 
@@ -733,6 +754,7 @@ func (s *authServiceImpl) Logout(ctx context.Context, req *pb.LogoutRequest) err
 //  4. 生成新密码哈希
 //  5. 更新密码
 //  6. 删除验证码
+//  7. 递增用户令牌版本并删除所有设备的 Token（所有设备登录态失效）
 //
 // 错误码映射：
 //   - codes.NotFound: 用户不存在
@@ -742,7 +764,7 @@ func (s *authServiceImpl) Logout(ctx context.Context, req *pb.LogoutRequest) err
 func (s *authServiceImpl) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) error {
 	// 记录重置密码请求（邮箱脱敏）
 	logger.Info(ctx, "用户重置密码请求",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 	)
 
 	// 1. 根据邮箱查询用户
@@ -761,7 +783,7 @@ func (s *authServiceImpl) ResetPassword(ctx context.Context, req *pb.ResetPasswo
 	}
 
 	// 2. 校验验证码（type=3: 重置密码）
-	isValid, err := s.authRepo.VerifyVerifyCode(ctx, req.Email, req.VerifyCode, 3)
+	isValid, err := verifyCodeMatches(ctx, s.authRepo, req.Email, req.VerifyCode, 3)
 	if err != nil {
 		// 判断是 Redis Key 不存在还是其他错误
 		if errors.Is(err, repository.ErrRedisNil) {
@@ -810,9 +832,19 @@ func (s *authServiceImpl) ResetPassword(ctx context.Context, req *pb.ResetPasswo
 		// 删除失败不影响重置密码流程，只记录警告日志
 	}
 
-	// 7. 重置成功
+	// 7. 所有设备登录态失效：令牌版本使已签发的 Access Token 失效，删除 Token 使 Refresh Token 无法再换新
+	// 密码已重置成功，失败仅记录日志
+	if _, err := s.deviceRepo.BumpTokenVersion(ctx, user.Uuid); err != nil {
+		logger.Error(ctx, "递增用户令牌版本失败",
+			logger.String("user_uuid", user.Uuid),
+			logger.ErrorField("error", err),
+		)
+	}
+	revokeDeviceTokens(ctx, s.deviceRepo, user.Uuid, "")
+
+	// 8. 重置成功
 	logger.Info(ctx, "用户密码重置成功",
-		logger.String("email", utils.MaskEmail(req.Email)),
+		logger.String("email", util.MaskEmail(req.Email)),
 	)
 
 	return nil
@@ -113,6 +113,10 @@ type fakeAuthDeviceRepo struct {
 	touchDeviceInfoFn    func(ctx context.Context, userUUID string) error
 	deleteTokensFn       func(ctx context.Context, userUUID, deviceID string) error
 	updateOnlineStatusFn func(ctx context.Context, userUUID, deviceID string, status int8) error
+	getTokenEpochFn      func(ctx context.Context, userUUID, deviceID string) (int64, error)
+	getTokenVersionFn    func(ctx context.Context, userUUID string) (int64, error)
+	bumpTokenVersionFn   func(ctx context.Context, userUUID string) (int64, error)
+	getByUserUUIDFn      func(ctx context.Context, userUUID string) ([]*model.DeviceSession, error)
 }
 
 var _ repository.IDeviceRepository = (*fakeAuthDeviceRepo)(nil)
@@ -166,6 +170,38 @@ func (f *fakeAuthDeviceRepo) DeleteTokens(ctx context.Context, userUUID, deviceI
 	return f.deleteTokensFn(ctx, userUUID, deviceID)
 }
 
+func (f *fakeAuthDeviceRepo) GetTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	if f.getTokenEpochFn == nil {
+		return 0, nil
+	}
+	return f.getTokenEpochFn(ctx, userUUID, deviceID)
+}
+
+func (f *fakeAuthDeviceRepo) BumpTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeAuthDeviceRepo) GetTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	if f.getTokenVersionFn == nil {
+		return 0, nil
+	}
+	return f.getTokenVersionFn(ctx, userUUID)
+}
+
+func (f *fakeAuthDeviceRepo) BumpTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	if f.bumpTokenVersionFn == nil {
+		return 1, nil
+	}
+	return f.bumpTokenVersionFn(ctx, userUUID)
+}
+
+func (f *fakeAuthDeviceRepo) GetByUserUUID(ctx context.Context, userUUID string) ([]*model.DeviceSession, error) {
+	if f.getByUserUUIDFn == nil {
+		return nil, nil
+	}
+	return f.getByUserUUIDFn(ctx, userUUID)
+}
+
 func (f *fakeAuthDeviceRepo) UpdateOnlineStatus(ctx context.Context, userUUID, deviceID string, status int8) error {
 	if f.updateOnlineStatusFn == nil {
 		return nil
@@ -407,6 +443,124 @@ func TestUserAuthServiceLogin(t *testing.T) {
 		requireAuthStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 	})
 
+	t.Run("token_epoch_read_failed", func(t *testing.T) {
+		repo := &fakeAuthRepo{
+			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				u := *validUser
+				return &u, nil
+			},
+		}
+		deviceRepo := &fakeAuthDeviceRepo{
+			getTokenEpochFn: func(_ context.Context, _, _ string) (int64, error) {
+				return 0, errors.New("redis read error")
+			},
+		}
+		svc := NewAuthService(repo, deviceRepo)
+
+		ctx := context.WithValue(context.Background(), "device_id", "d1")
+		resp, err := svc.Login(ctx, &pb.LoginRequest{
+			Account:    "a@test.com",
+			Password:   "pass123",
+			DeviceInfo: &pb.DeviceInfo{DeviceName: "iphone"},
+		})
+		require.Nil(t, resp)
+		requireAuthStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+
+	t.Run("token_carries_device_epoch", func(t *testing.T) {
+		repo := &fakeAuthRepo{
+			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				u := *validUser
+				return &u, nil
+			},
+		}
+		deviceRepo := &fakeAuthDeviceRepo{
+			getTokenEpochFn: func(_ context.Context, userUUID, deviceID string) (int64, error) {
+				require.Equal(t, "u1", userUUID)
+				require.Equal(t, "d1", deviceID)
+				return 3, nil
+			},
+			getTokenVersionFn: func(_ context.Context, userUUID string) (int64, error) {
+				require.Equal(t, "u1", userUUID)
+				return 5, nil
+			},
+		}
+		svc := NewAuthService(repo, deviceRepo)
+
+		ctx := context.WithValue(context.Background(), "device_id", "d1")
+		resp, err := svc.Login(ctx, &pb.LoginRequest{
+			Account:    "a@test.com",
+			Password:   "pass123",
+			DeviceInfo: &pb.DeviceInfo{DeviceName: "iphone"},
+		})
+		require.NoError(t, err)
+		claims, err := util.ParseToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), claims.Epoch)
+		assert.Equal(t, int64(5), claims.TokenVersion)
+	})
+
+	t.Run("token_version_read_failed", func(t *testing.T) {
+		repo := &fakeAuthRepo{
+			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				u := *validUser
+				return &u, nil
+			},
+		}
+		deviceRepo := &fakeAuthDeviceRepo{
+			getTokenVersionFn: func(_ context.Context, _ string) (int64, error) {
+				return 0, errors.New("redis down")
+			},
+		}
+		svc := NewAuthService(repo, deviceRepo)
+
+		ctx := context.WithValue(context.Background(), "device_id", "d1")
+		_, err := svc.Login(ctx, &pb.LoginRequest{
+			Account:    "a@test.com",
+			Password:   "pass123",
+			DeviceInfo: &pb.DeviceInfo{DeviceName: "iphone"},
+		})
+		requireAuthStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+
+	t.Run("token_ttl_from_config", func(t *testing.T) {
+		util.SetTokenTTL(30*time.Minute, 24*time.Hour)
+		t.Cleanup(func() { util.SetTokenTTL(util.AccessExpire, util.RefreshExpire) })
+
+		repo := &fakeAuthRepo{
+			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				u := *validUser
+				return &u, nil
+			},
+		}
+		var accessTTL, refreshTTL time.Duration
+		deviceRepo := &fakeAuthDeviceRepo{
+			storeAccessTokenFn: func(_ context.Context, _, _, _ string, expire time.Duration) error {
+				accessTTL = expire
+				return nil
+			},
+			storeRefreshTokenFn: func(_ context.Context, _, _, _ string, expire time.Duration) error {
+				refreshTTL = expire
+				return nil
+			},
+		}
+		svc := NewAuthService(repo, deviceRepo)
+
+		ctx := context.WithValue(context.Background(), "device_id", "d1")
+		resp, err := svc.Login(ctx, &pb.LoginRequest{
+			Account:    "a@test.com",
+			Password:   "pass123",
+			DeviceInfo: &pb.DeviceInfo{DeviceName: "iphone"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1800), resp.ExpiresIn)
+		assert.Equal(t, 30*time.Minute, accessTTL)
+		assert.Equal(t, 24*time.Hour, refreshTTL)
+		claims, err := util.ParseToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(30*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+	})
+
 	t.Run("store_refresh_token_failed", func(t *testing.T) {
 		repo := &fakeAuthRepo{
 			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
@@ -743,6 +897,72 @@ func TestUserAuthServiceSendVerifyCode(t *testing.T) {
 	})
 }
 
+func TestUserAuthServiceVerifyCodeSpec(t *testing.T) {
+	initUserAuthTestLogger()
+	t.Cleanup(func() { util.SetVerifyCodeSpecs(util.DefaultVerifyCodeSpec, nil) })
+	util.SetVerifyCodeSpecs(util.DefaultVerifyCodeSpec, map[int32]util.VerifyCodeSpec{
+		3: util.NewVerifyCodeSpec(8, util.VerifyCodeAlphanumeric),
+	})
+
+	t.Run("send_generates_configured_spec", func(t *testing.T) {
+		stored := map[int32]string{}
+		repo := &fakeAuthRepo{
+			verifyVerifyCodeRateLimitFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil },
+			storeVerifyCodeFn: func(_ context.Context, _, verifyCode string, codeType int32, _ time.Duration) error {
+				stored[codeType] = verifyCode
+				return nil
+			},
+			incrementVerifyCodeCountFn: func(_ context.Context, _, _ string) error { return nil },
+		}
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+
+		// 邮件未配置会在存储后失败，这里只关心存储的验证码格式
+		_, _ = svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{Email: "a@test.com", Type: 3})
+		_, _ = svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{Email: "a@test.com", Type: 2})
+
+		require.Len(t, stored, 2)
+		assert.True(t, util.VerifyCodeSpecFor(3).Valid(stored[3]))
+		assert.Len(t, stored[3], 8)
+		assert.True(t, util.DefaultVerifyCodeSpec.Valid(stored[2]))
+	})
+
+	t.Run("malformed_code_rejected_without_lookup", func(t *testing.T) {
+		var lookups int
+		repo := &fakeAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				lookups++
+				return true, nil
+			},
+		}
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+
+		// type=2 使用默认 6 位数字：字母或长度不符直接判定错误
+		resp, err := svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{Email: "a@test.com", VerifyCode: "12AB56", Type: 2})
+		require.NoError(t, err)
+		assert.False(t, resp.Valid)
+		resp, err = svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{Email: "a@test.com", VerifyCode: "1234", Type: 2})
+		require.NoError(t, err)
+		assert.False(t, resp.Valid)
+		assert.Zero(t, lookups)
+	})
+
+	t.Run("alphanumeric_code_case_insensitive", func(t *testing.T) {
+		var got string
+		repo := &fakeAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, verifyCode string, _ int32) (bool, error) {
+				got = verifyCode
+				return true, nil
+			},
+		}
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+
+		resp, err := svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{Email: "a@test.com", VerifyCode: "ab23cd45", Type: 3})
+		require.NoError(t, err)
+		assert.True(t, resp.Valid)
+		assert.Equal(t, "AB23CD45", got)
+	})
+}
+
 func TestUserAuthServiceVerifyCode(t *testing.T) {
 	initUserAuthTestLogger()
 
@@ -1102,4 +1322,43 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 		require.NoError(t, err)
 		assert.True(t, deleteCalled)
 	})
+
+	t.Run("success_revokes_all_devices", func(t *testing.T) {
+		repo := &fakeAuthRepo{
+			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				return &model.UserInfo{Uuid: "u1", Password: oldHashed}, nil
+			},
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return true, nil
+			},
+			updatePasswordFn: func(_ context.Context, _, _ string) error {
+				return nil
+			},
+		}
+		var bumped []string
+		var deletedDevices []string
+		deviceRepo := &fakeAuthDeviceRepo{
+			bumpTokenVersionFn: func(_ context.Context, userUUID string) (int64, error) {
+				bumped = append(bumped, userUUID)
+				return 2, nil
+			},
+			getByUserUUIDFn: func(_ context.Context, _ string) ([]*model.DeviceSession, error) {
+				return []*model.DeviceSession{{DeviceId: "d1"}, {DeviceId: "d2"}}, nil
+			},
+			deleteTokensFn: func(_ context.Context, _, deviceID string) error {
+				deletedDevices = append(deletedDevices, deviceID)
+				return nil
+			},
+		}
+		svc := NewAuthService(repo, deviceRepo)
+
+		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
+			Email:       "a@test.com",
+			VerifyCode:  "123456",
+			NewPassword: "newpass123",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"u1"}, bumped)
+		assert.Equal(t, []string{"d1", "d2"}, deletedDevices)
+	})
 }
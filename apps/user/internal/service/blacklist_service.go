@@ -8,10 +8,6 @@ import (
 	"ChatServer/pkg/util"
 	"context"
 	"errors"
-	"strconv"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // blacklistServiceImpl 黑名单服务实现
@@ -32,17 +28,17 @@ func (s *blacklistServiceImpl) AddBlacklist(ctx context.Context, req *pb.AddBlac
 	currentUserUUID := util.GetUserUUIDFromContext(ctx)
 	if currentUserUUID == "" {
 		logger.Error(ctx, "获取用户UUID失败")
-		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return bizError(consts.CodeUnauthorized)
 	}
 
 	// 2. 参数校验
 	if req == nil || req.TargetUuid == "" {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return bizError(consts.CodeParamError)
 	}
 
 	// 3. 不能拉黑自己
 	if req.TargetUuid == currentUserUUID {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeCannotBlacklistSelf))
+		return bizError(consts.CodeCannotBlacklistSelf)
 	}
 
 	// 4. 判断是否已在黑名单中
@@ -53,10 +49,10 @@ func (s *blacklistServiceImpl) AddBlacklist(ctx context.Context, req *pb.AddBlac
 			logger.String("target_uuid", req.TargetUuid),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 	if isBlocked {
-		return status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeAlreadyInBlacklist))
+		return bizError(consts.CodeAlreadyInBlacklist)
 	}
 
 	// 5. 拉黑用户
@@ -66,7 +62,7 @@ func (s *blacklistServiceImpl) AddBlacklist(ctx context.Context, req *pb.AddBlac
 			logger.String("target_uuid", req.TargetUuid),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 
 	logger.Info(ctx, "拉黑用户成功",
@@ -83,12 +79,12 @@ func (s *blacklistServiceImpl) RemoveBlacklist(ctx context.Context, req *pb.Remo
 	currentUserUUID := util.GetUserUUIDFromContext(ctx)
 	if currentUserUUID == "" {
 		logger.Error(ctx, "获取用户UUID失败")
-		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return bizError(consts.CodeUnauthorized)
 	}
 
 	// 2. 参数校验
 	if req == nil || req.UserUuid == "" {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return bizError(consts.CodeParamError)
 	}
 
 	// 3. 判断是否已在黑名单中
@@ -99,23 +95,23 @@ func (s *blacklistServiceImpl) RemoveBlacklist(ctx context.Context, req *pb.Remo
 			logger.String("target_uuid", req.UserUuid),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 	if !isBlocked {
-		return status.Error(codes.NotFound, strconv.Itoa(consts.CodeNotInBlacklist))
+		return bizError(consts.CodeNotInBlacklist)
 	}
 
 	// 4. 取消拉黑
 	if err := s.blacklistRepo.RemoveBlacklist(ctx, currentUserUUID, req.UserUuid); err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
-			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeNotInBlacklist))
+			return bizError(consts.CodeNotInBlacklist)
 		}
 		logger.Error(ctx, "取消拉黑失败",
 			logger.String("user_uuid", currentUserUUID),
 			logger.String("target_uuid", req.UserUuid),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 
 	logger.Info(ctx, "取消拉黑成功",
@@ -132,7 +128,7 @@ func (s *blacklistServiceImpl) GetBlacklistList(ctx context.Context, req *pb.Get
 	currentUserUUID := util.GetUserUUIDFromContext(ctx)
 	if currentUserUUID == "" {
 		logger.Error(ctx, "获取用户UUID失败")
-		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return nil, bizError(consts.CodeUnauthorized)
 	}
 
 	// 2. 兜底分页参数
@@ -154,7 +150,7 @@ func (s *blacklistServiceImpl) GetBlacklistList(ctx context.Context, req *pb.Get
 			logger.Int32("page_size", pageSize),
 			logger.ErrorField("error", err),
 		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, bizError(consts.CodeInternalError)
 	}
 
 	if len(relations) == 0 {
@@ -198,7 +194,7 @@ func (s *blacklistServiceImpl) GetBlacklistList(ctx context.Context, req *pb.Get
 // CheckIsBlacklist 判断是否拉黑
 func (s *blacklistServiceImpl) CheckIsBlacklist(ctx context.Context, req *pb.CheckIsBlacklistRequest) (*pb.CheckIsBlacklistResponse, error) {
 	if req == nil || req.UserUuid == "" || req.TargetUuid == "" {
-		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return nil, bizError(consts.CodeParamError)
 	}
 
 	isBlocked, err := s.blacklistRepo.IsBlocked(ctx, req.UserUuid, req.TargetUuid)
@@ -208,7 +204,7 @@ func (s *blacklistServiceImpl) CheckIsBlacklist(ctx context.Context, req *pb.Che
 			logger.String("target_uuid", req.TargetUuid),
 			logger.ErrorField("error", err),
 		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, bizError(consts.CodeInternalError)
 	}
 
 	return &pb.CheckIsBlacklistResponse{
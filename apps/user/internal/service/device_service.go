@@ -14,19 +14,22 @@ import (
 	"strconv"
 	"time"
 
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // deviceServiceImpl 设备会话服务实现
 type deviceServiceImpl struct {
 	deviceRepo repository.IDeviceRepository
+	userRepo   repository.IUserRepository   // 读取在线状态隐私设置
+	friendRepo repository.IFriendRepository // 隐私范围为“仅非好友”时判断好友关系
 }
 
 // NewDeviceService 创建设备服务实例
-func NewDeviceService(deviceRepo repository.IDeviceRepository) DeviceService {
+func NewDeviceService(deviceRepo repository.IDeviceRepository, userRepo repository.IUserRepository, friendRepo repository.IFriendRepository) DeviceService {
 	return &deviceServiceImpl{
 		deviceRepo: deviceRepo,
+		userRepo:   userRepo,
+		friendRepo: friendRepo,
 	}
 }
 
@@ -35,7 +38,7 @@ func (s *deviceServiceImpl) GetDeviceList(ctx context.Context, req *pb.GetDevice
 	userUUID := util.GetUserUUIDFromContext(ctx)
 	if userUUID == "" {
 		logger.Warn(ctx, "获取设备列表失败：user_uuid 为空")
-		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return nil, bizError(consts.CodeUnauthorized)
 	}
 
 	deviceID := util.GetDeviceIDFromContext(ctx)
@@ -46,7 +49,7 @@ func (s *deviceServiceImpl) GetDeviceList(ctx context.Context, req *pb.GetDevice
 			logger.String("user_uuid", userUUID),
 			logger.ErrorField("error", err),
 		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, bizError(consts.CodeInternalError)
 	}
 	sessions := sessionsByUser[userUUID]
 
@@ -103,73 +106,193 @@ func (s *deviceServiceImpl) KickDevice(ctx context.Context, req *pb.KickDeviceRe
 	userUUID := util.GetUserUUIDFromContext(ctx)
 	if userUUID == "" {
 		logger.Warn(ctx, "踢出设备失败：user_uuid 为空")
-		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return bizError(consts.CodeUnauthorized)
 	}
 
 	if req == nil || req.DeviceId == "" {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return bizError(consts.CodeParamError)
 	}
 
 	currentDeviceID := util.GetDeviceIDFromContext(ctx)
 	if currentDeviceID != "" && currentDeviceID == req.DeviceId {
-		return status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeCannotKickCurrent))
+		return bizError(consts.CodeCannotKickCurrent)
 	}
 
-	session, err := s.deviceRepo.GetByDeviceID(ctx, userUUID, req.DeviceId)
+	return s.kickOne(ctx, userUUID, req.DeviceId)
+}
+
+// kickOne 踢出当前用户的单个设备（不含"当前设备"校验）。
+// 返回 gRPC status 错误，便于单个踢出与批量踢出复用同一套错误码。
+func (s *deviceServiceImpl) kickOne(ctx context.Context, userUUID, deviceID string) error {
+	session, err := s.deviceRepo.GetByDeviceID(ctx, userUUID, deviceID)
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
-			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+			return bizError(consts.CodeDeviceNotFound)
 		}
 		logger.Error(ctx, "踢出设备失败：查询设备会话失败",
 			logger.String("user_uuid", userUUID),
-			logger.String("device_id", req.DeviceId),
+			logger.String("device_id", deviceID),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 	if session == nil {
-		return status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+		return bizError(consts.CodeDeviceNotFound)
 	}
 
 	// 幂等语义：无论 token 是否已删除，都返回成功；仅 Redis 异常才报错。
-	if err := s.deviceRepo.DeleteTokens(ctx, userUUID, req.DeviceId); err != nil {
+	if err := s.deviceRepo.DeleteTokens(ctx, userUUID, deviceID); err != nil {
 		logger.Error(ctx, "踢出设备失败：删除设备 Token 失败",
 			logger.String("user_uuid", userUUID),
-			logger.String("device_id", req.DeviceId),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return bizError(consts.CodeInternalError)
+	}
+
+	// 递增设备令牌纪元：即使 connect 鉴权降级（Redis 哈希不可达）也能拒绝该设备此前签发的 Token。
+	if _, err := s.deviceRepo.BumpTokenEpoch(ctx, userUUID, deviceID); err != nil {
+		logger.Error(ctx, "踢出设备失败：递增设备令牌纪元失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 
 	// status 语义：0=在线, 1=离线, 2=注销, 3=被踢出。
 	// 踢设备时：在线/离线 -> 被踢出；注销/已被踢出保持原状态，按幂等成功。
 	if session.Status == model.DeviceStatusOnline || session.Status == model.DeviceStatusOffline {
-		if err := s.deviceRepo.UpdateOnlineStatus(ctx, userUUID, req.DeviceId, model.DeviceStatusKicked); err != nil {
+		if err := s.deviceRepo.UpdateOnlineStatus(ctx, userUUID, deviceID, model.DeviceStatusKicked); err != nil {
 			if errors.Is(err, repository.ErrRecordNotFound) {
-				return status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+				return bizError(consts.CodeDeviceNotFound)
 			}
 			logger.Error(ctx, "踢出设备失败：更新设备状态失败",
 				logger.String("user_uuid", userUUID),
-				logger.String("device_id", req.DeviceId),
+				logger.String("device_id", deviceID),
 				logger.ErrorField("error", err),
 			)
-			return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+			return bizError(consts.CodeInternalError)
 		}
 	}
 
 	logger.Info(ctx, "踢出设备成功",
 		logger.String("user_uuid", userUUID),
-		logger.String("device_id", req.DeviceId),
+		logger.String("device_id", deviceID),
 		logger.Int("before_status", int(session.Status)),
 	)
 
 	return nil
 }
 
+// 批量踢出设备的逐目标结果取值
+const (
+	kickResultKicked  = "kicked"
+	kickResultSkipped = "skipped"
+	kickResultFailed  = "failed"
+)
+
+// batchKickDevicesMaxSize 单次批量踢出的设备数上限（与 proto 校验一致）
+const batchKickDevicesMaxSize = 50
+
+// BatchKickDevices 批量踢出当前用户的设备
+// 业务流程：
+//  1. 校验参数并去重（保持原始顺序）
+//  2. 逐个目标独立踢出：当前设备跳过，单个失败只记录结果，不中断批量
+//  3. 记录审计事件（操作人、目标、成功/跳过/失败明细）
+//  4. 返回逐目标结果与汇总
+func (s *deviceServiceImpl) BatchKickDevices(ctx context.Context, req *pb.BatchKickDevicesRequest) (*pb.BatchKickDevicesResponse, error) {
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	if userUUID == "" {
+		logger.Warn(ctx, "批量踢出设备失败：user_uuid 为空")
+		return nil, bizError(consts.CodeUnauthorized)
+	}
+
+	if req == nil || len(req.DeviceIds) == 0 || len(req.DeviceIds) > batchKickDevicesMaxSize {
+		return nil, bizError(consts.CodeParamError)
+	}
+
+	targets := make([]string, 0, len(req.DeviceIds))
+	seen := make(map[string]struct{}, len(req.DeviceIds))
+	for _, deviceID := range req.DeviceIds {
+		if deviceID == "" {
+			return nil, bizError(consts.CodeParamError)
+		}
+		if _, ok := seen[deviceID]; ok {
+			continue
+		}
+		seen[deviceID] = struct{}{}
+		targets = append(targets, deviceID)
+	}
+
+	currentDeviceID := util.GetDeviceIDFromContext(ctx)
+	resp := &pb.BatchKickDevicesResponse{
+		Results: make([]*pb.KickDeviceResult, 0, len(targets)),
+	}
+	var kicked, skipped, failed []string
+	for _, deviceID := range targets {
+		item := &pb.KickDeviceResult{DeviceId: deviceID}
+		if currentDeviceID != "" && currentDeviceID == deviceID {
+			// 当前设备不允许踢出，跳过而非失败
+			item.Result = kickResultSkipped
+			item.Code = consts.CodeCannotKickCurrent
+			skipped = append(skipped, deviceID)
+		} else if err := s.kickOne(ctx, userUUID, deviceID); err != nil {
+			item.Result = kickResultFailed
+			item.Code = int32(kickErrorCode(err))
+			failed = append(failed, deviceID)
+		} else {
+			item.Result = kickResultKicked
+			kicked = append(kicked, deviceID)
+		}
+		resp.Results = append(resp.Results, item)
+	}
+	resp.KickedCount = int32(len(kicked))
+	resp.SkippedCount = int32(len(skipped))
+	resp.FailedCount = int32(len(failed))
+
+	// 审计事件：记录完整操作，便于追溯批量踢线
+	logger.Info(ctx, "审计：批量踢出设备",
+		logger.String("audit_event", "device.batch_kick"),
+		logger.String("operator_uuid", userUUID),
+		logger.String("operator_device_id", currentDeviceID),
+		logger.Any("targets", targets),
+		logger.Any("kicked", kicked),
+		logger.Any("skipped", skipped),
+		logger.Any("failed", failed),
+	)
+
+	return resp, nil
+}
+
+// kickErrorCode 从 kickOne 返回的 status 错误中解析业务错误码
+func kickErrorCode(err error) int {
+	if st, ok := status.FromError(err); ok {
+		if code, convErr := strconv.Atoi(st.Message()); convErr == nil {
+			return code
+		}
+	}
+	return consts.CodeInternalError
+}
+
 // GetOnlineStatus 获取用户在线状态
+// 目标用户对当前用户隐藏在线状态时返回离线且 LastSeenAt=0（与真实离线不可区分）。
 func (s *deviceServiceImpl) GetOnlineStatus(ctx context.Context, req *pb.GetOnlineStatusRequest) (*pb.GetOnlineStatusResponse, error) {
 	if req == nil || req.UserUuid == "" {
-		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return nil, bizError(consts.CodeParamError)
+	}
+
+	hidden, err := s.presenceHiddenUsers(ctx, util.GetUserUUIDFromContext(ctx), []string{req.UserUuid})
+	if err != nil {
+		return nil, err
+	}
+	if hidden[req.UserUuid] {
+		return &pb.GetOnlineStatusResponse{
+			Status: &pb.OnlineStatus{
+				UserUuid:        req.UserUuid,
+				OnlinePlatforms: []string{},
+			},
+		}, nil
 	}
 
 	sessionsByUser, err := s.deviceRepo.BatchGetOnlineStatus(ctx, []string{req.UserUuid})
@@ -178,7 +301,7 @@ func (s *deviceServiceImpl) GetOnlineStatus(ctx context.Context, req *pb.GetOnli
 			logger.String("user_uuid", req.UserUuid),
 			logger.ErrorField("error", err),
 		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, bizError(consts.CodeInternalError)
 	}
 	sessions := sessionsByUser[req.UserUuid]
 
@@ -262,18 +385,75 @@ func (s *deviceServiceImpl) GetOnlineStatus(ctx context.Context, req *pb.GetOnli
 	}, nil
 }
 
+// batchOnlineStatusChunkSize 单次批量查询在线状态的用户数上限（单次 RPC 上限与分片大小一致）。
+const batchOnlineStatusChunkSize = 100
+
 // BatchGetOnlineStatus 批量获取在线状态
 func (s *deviceServiceImpl) BatchGetOnlineStatus(ctx context.Context, req *pb.BatchGetOnlineStatusRequest) (*pb.BatchGetOnlineStatusResponse, error) {
-	if req == nil || len(req.UserUuids) == 0 || len(req.UserUuids) > 100 {
-		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	if req == nil || len(req.UserUuids) == 0 || len(req.UserUuids) > batchOnlineStatusChunkSize {
+		return nil, bizError(consts.CodeParamError)
+	}
+
+	unique, ok := dedupOnlineStatusUsers(req.UserUuids)
+	if !ok {
+		return nil, bizError(consts.CodeParamError)
+	}
+
+	statusByUser, err := s.queryVisibleOnlineStatus(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BatchGetOnlineStatusResponse{
+		Users: buildOnlineStatusItems(req.UserUuids, statusByUser),
+	}, nil
+}
+
+// BatchGetOnlineStatusChunked 批量获取在线状态（服务端内部调用，不限人数）。
+// 供大群成员在线状态等场景使用：去重后按 batchOnlineStatusChunkSize 分片查询仓储并合并，
+// 返回结果按请求顺序组装并保留重复项。
+// 内部调用没有查看者，不做在线状态隐私过滤，返回真实状态；结果需要展示给用户时由调用方按查看者自行过滤。
+func (s *deviceServiceImpl) BatchGetOnlineStatusChunked(ctx context.Context, userUUIDs []string) ([]*pb.OnlineStatusItem, error) {
+	if len(userUUIDs) == 0 {
+		return []*pb.OnlineStatusItem{}, nil
+	}
+
+	unique, ok := dedupOnlineStatusUsers(userUUIDs)
+	if !ok {
+		return nil, bizError(consts.CodeParamError)
+	}
+
+	statusByUser := make(map[string]onlineStatusResult, len(unique))
+	for start := 0; start < len(unique); start += batchOnlineStatusChunkSize {
+		end := start + batchOnlineStatusChunkSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		chunkStatus, err := s.queryOnlineStatus(ctx, unique[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for userUUID, result := range chunkStatus {
+			statusByUser[userUUID] = result
+		}
 	}
 
-	// 去重后查询，返回结果按请求顺序组装。
-	unique := make([]string, 0, len(req.UserUuids))
-	seen := make(map[string]struct{}, len(req.UserUuids))
-	for _, userUUID := range req.UserUuids {
+	return buildOnlineStatusItems(userUUIDs, statusByUser), nil
+}
+
+// onlineStatusResult 单个用户的在线状态计算结果。
+type onlineStatusResult struct {
+	isOnline    bool
+	lastSeenSec int64
+}
+
+// dedupOnlineStatusUsers 按首次出现顺序去重，存在空 UUID 时返回 false。
+func dedupOnlineStatusUsers(userUUIDs []string) ([]string, bool) {
+	unique := make([]string, 0, len(userUUIDs))
+	seen := make(map[string]struct{}, len(userUUIDs))
+	for _, userUUID := range userUUIDs {
 		if userUUID == "" {
-			return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+			return nil, false
 		}
 		if _, ok := seen[userUUID]; ok {
 			continue
@@ -281,14 +461,108 @@ func (s *deviceServiceImpl) BatchGetOnlineStatus(ctx context.Context, req *pb.Ba
 		seen[userUUID] = struct{}{}
 		unique = append(unique, userUUID)
 	}
+	return unique, true
+}
+
+// buildOnlineStatusItems 按请求顺序组装响应（保留重复项），未查到的用户按离线返回。
+func buildOnlineStatusItems(userUUIDs []string, statusByUser map[string]onlineStatusResult) []*pb.OnlineStatusItem {
+	users := make([]*pb.OnlineStatusItem, 0, len(userUUIDs))
+	for _, userUUID := range userUUIDs {
+		result := statusByUser[userUUID]
+		users = append(users, &pb.OnlineStatusItem{
+			UserUuid:   userUUID,
+			IsOnline:   result.isOnline,
+			LastSeenAt: result.lastSeenSec * 1000,
+		})
+	}
+	return users
+}
 
+// queryVisibleOnlineStatus 查询一批已去重用户的在线状态，并按隐私设置对当前用户隐藏（离线、LastSeenAt=0）。
+func (s *deviceServiceImpl) queryVisibleOnlineStatus(ctx context.Context, unique []string) (map[string]onlineStatusResult, error) {
+	hidden, err := s.presenceHiddenUsers(ctx, util.GetUserUUIDFromContext(ctx), unique)
+	if err != nil {
+		return nil, err
+	}
+	statusByUser, err := s.queryOnlineStatus(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	for userUUID := range hidden {
+		statusByUser[userUUID] = onlineStatusResult{}
+	}
+	return statusByUser, nil
+}
+
+// presenceHiddenUsers 返回 targets 中对 viewerUUID 隐藏在线状态的用户集合。
+// 规则：查询自己始终可见；hide_presence=1 且范围为所有人时对任何人隐藏；
+// 范围为仅非好友时，viewer 不在目标用户好友列表中（或 viewer 为空）则隐藏。
+// 读取隐私设置失败返回 CodeInternalError；好友关系查询失败按非好友处理（宁可隐藏）。
+func (s *deviceServiceImpl) presenceHiddenUsers(ctx context.Context, viewerUUID string, targets []string) (map[string]bool, error) {
+	if s.userRepo == nil {
+		return nil, nil
+	}
+	others := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if target != viewerUUID {
+			others = append(others, target)
+		}
+	}
+	if len(others) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.userRepo.BatchGetByUUIDs(ctx, others)
+	if err != nil {
+		logger.Error(ctx, "获取在线状态失败：读取隐私设置失败",
+			logger.Int("user_count", len(others)),
+			logger.ErrorField("error", err),
+		)
+		return nil, bizError(consts.CodeInternalError)
+	}
+
+	hidden := make(map[string]bool)
+	nonFriendScoped := make([]string, 0)
+	for _, user := range users {
+		if user == nil || user.HidePresence != 1 {
+			continue
+		}
+		if user.PresenceHideScope != model.PresenceHideScopeNonFriends || viewerUUID == "" || s.friendRepo == nil {
+			hidden[user.Uuid] = true
+			continue
+		}
+		nonFriendScoped = append(nonFriendScoped, user.Uuid)
+	}
+	if len(nonFriendScoped) == 0 {
+		return hidden, nil
+	}
+
+	// 仅对非好友隐藏的用户：一次批量查询 viewer 是否在其好友列表中
+	isFriendOf, err := s.friendRepo.BatchCheckIsFriendOf(ctx, viewerUUID, nonFriendScoped)
+	if err != nil {
+		logger.Warn(ctx, "获取在线状态：查询好友关系失败，按非好友隐藏",
+			logger.Int("user_count", len(nonFriendScoped)),
+			logger.ErrorField("error", err),
+		)
+	}
+	for _, userUUID := range nonFriendScoped {
+		if err != nil || !isFriendOf[userUUID] {
+			hidden[userUUID] = true
+		}
+	}
+	return hidden, nil
+}
+
+// queryOnlineStatus 查询一批已去重用户的在线状态。
+// 设备会话查询失败返回 CodeInternalError；活跃时间/最近活跃时间读取失败降级为离线/0。
+func (s *deviceServiceImpl) queryOnlineStatus(ctx context.Context, unique []string) (map[string]onlineStatusResult, error) {
 	sessionsByUser, err := s.deviceRepo.BatchGetOnlineStatus(ctx, unique)
 	if err != nil {
 		logger.Error(ctx, "批量获取在线状态失败：查询设备会话失败",
 			logger.Int("user_count", len(unique)),
 			logger.ErrorField("error", err),
 		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, bizError(consts.CodeInternalError)
 	}
 
 	nowSec := time.Now().Unix()
@@ -330,26 +604,11 @@ func (s *deviceServiceImpl) BatchGetOnlineStatus(ctx context.Context, req *pb.Ba
 		lastSeenByUser = map[string]int64{}
 	}
 
-	users := make([]*pb.OnlineStatusItem, 0, len(req.UserUuids))
-	for _, userUUID := range req.UserUuids {
-		sessions := sessionsByUser[userUUID]
-		lastSeenSec := lastSeenByUser[userUUID]
-		if len(sessions) == 0 {
-			users = append(users, &pb.OnlineStatusItem{
-				UserUuid:   userUUID,
-				IsOnline:   false,
-				LastSeenAt: lastSeenSec * 1000,
-			})
-			continue
-		}
-
+	statusByUser := make(map[string]onlineStatusResult, len(unique))
+	for _, userUUID := range unique {
+		result := onlineStatusResult{lastSeenSec: lastSeenByUser[userUUID]}
 		activeTimes := activeByUser[userUUID]
-		if activeTimes == nil {
-			activeTimes = map[string]int64{}
-		}
-
-		isOnline := false
-		for _, session := range sessions {
+		for _, session := range sessionsByUser[userUUID] {
 			if session == nil || session.DeviceId == "" {
 				continue
 			}
@@ -358,34 +617,26 @@ func (s *deviceServiceImpl) BatchGetOnlineStatus(ctx context.Context, req *pb.Ba
 				continue
 			}
 			if session.Status == model.DeviceStatusOnline && nowSec-seenSec <= windowSec {
-				isOnline = true
+				result.isOnline = true
 			}
 		}
-
-		users = append(users, &pb.OnlineStatusItem{
-			UserUuid:   userUUID,
-			IsOnline:   isOnline,
-			LastSeenAt: lastSeenSec * 1000,
-		})
+		statusByUser[userUUID] = result
 	}
-
-	return &pb.BatchGetOnlineStatusResponse{
-		Users: users,
-	}, nil
+	return statusByUser, nil
 }
 
 // UpdateDeviceActive 批量更新设备活跃时间（内部调用）。
 // 由 gateway/connect 在本地节流命中后调用，仅更新 Redis 活跃时间。
 func (s *deviceServiceImpl) UpdateDeviceActive(ctx context.Context, req *pb.UpdateDeviceActiveRequest) error {
 	if req == nil || len(req.Items) == 0 {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return bizError(consts.CodeParamError)
 	}
 
 	nowSec := time.Now().Unix()
 	repoItems := make([]repository.DeviceActiveItem, 0, len(req.Items))
 	for _, item := range req.Items {
 		if item == nil || item.UserUuid == "" || item.DeviceId == "" {
-			return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+			return bizError(consts.CodeParamError)
 		}
 		repoItems = append(repoItems, repository.DeviceActiveItem{
 			UserUUID: item.UserUuid,
@@ -398,7 +649,7 @@ func (s *deviceServiceImpl) UpdateDeviceActive(ctx context.Context, req *pb.Upda
 			logger.Int("item_count", len(repoItems)),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 
 	return nil
@@ -409,13 +660,13 @@ func (s *deviceServiceImpl) UpdateDeviceActive(ctx context.Context, req *pb.Upda
 // 幂等语义：设备不存在时视为成功（可能设备已被踢出或注销）。
 func (s *deviceServiceImpl) UpdateDeviceStatus(ctx context.Context, req *pb.UpdateDeviceStatusRequest) error {
 	if req == nil || req.UserUuid == "" || req.DeviceId == "" {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return bizError(consts.CodeParamError)
 	}
 
 	// 仅允许 0(在线) 和 1(离线) 两种状态。
 	targetStatus := int8(req.Status)
 	if targetStatus != model.DeviceStatusOnline && targetStatus != model.DeviceStatusOffline {
-		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		return bizError(consts.CodeParamError)
 	}
 
 	if err := s.deviceRepo.UpdateOnlineStatus(ctx, req.UserUuid, req.DeviceId, targetStatus); err != nil {
@@ -435,7 +686,7 @@ func (s *deviceServiceImpl) UpdateDeviceStatus(ctx context.Context, req *pb.Upda
 			logger.Int("status", int(targetStatus)),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return bizError(consts.CodeInternalError)
 	}
 
 	logger.Info(ctx, "UpdateDeviceStatus: 设备状态已更新",
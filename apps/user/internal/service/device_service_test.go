@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -75,6 +77,8 @@ type fakeDeviceRepository struct {
 	verifyAccessTokenFn    func(context.Context, string, string, string) (bool, error)
 	getRefreshTokenFn      func(context.Context, string, string) (string, error)
 	deleteTokensFn         func(context.Context, string, string) error
+	getTokenEpochFn        func(context.Context, string, string) (int64, error)
+	bumpTokenEpochFn       func(context.Context, string, string) (int64, error)
 }
 
 func (f *fakeDeviceRepository) Create(ctx context.Context, session *model.DeviceSession) error {
@@ -231,11 +235,33 @@ func (f *fakeDeviceRepository) DeleteTokens(ctx context.Context, userUUID, devic
 	return f.deleteTokensFn(ctx, userUUID, deviceID)
 }
 
+func (f *fakeDeviceRepository) GetTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	if f.getTokenEpochFn == nil {
+		return 0, nil
+	}
+	return f.getTokenEpochFn(ctx, userUUID, deviceID)
+}
+
+func (f *fakeDeviceRepository) BumpTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	if f.bumpTokenEpochFn == nil {
+		return 1, nil
+	}
+	return f.bumpTokenEpochFn(ctx, userUUID, deviceID)
+}
+
+func (f *fakeDeviceRepository) GetTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeDeviceRepository) BumpTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	return 1, nil
+}
+
 func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("unauthenticated", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		resp, err := svc.GetDeviceList(context.Background(), &pb.GetDeviceListRequest{})
 		require.Nil(t, resp)
 		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
@@ -247,7 +273,7 @@ func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 				assert.Equal(t, []string{"u1"}, userUUIDs)
 				return nil, errors.New("redis failed")
 			},
-		})
+		}, nil, nil)
 		resp, err := svc.GetDeviceList(withDeviceContext("u1", "d1"), &pb.GetDeviceListRequest{})
 		require.Nil(t, resp)
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
@@ -274,7 +300,7 @@ func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 					"d2": nowSec - 30,
 				}, nil
 			},
-		})
+		}, nil, nil)
 
 		resp, err := svc.GetDeviceList(withDeviceContext("u1", "d2"), &pb.GetDeviceListRequest{})
 		require.NoError(t, err)
@@ -302,7 +328,7 @@ func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 			getActiveTimestampsFn: func(_ context.Context, _ string, _ []string) (map[string]int64, error) {
 				return nil, errors.New("active redis down")
 			},
-		})
+		}, nil, nil)
 
 		resp, err := svc.GetDeviceList(withDeviceContext("u1", "d1"), &pb.GetDeviceListRequest{})
 		require.NoError(t, err)
@@ -317,13 +343,13 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("unauthenticated", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.KickDevice(context.Background(), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
 	})
 
 	t.Run("invalid_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 
 		err := svc.KickDevice(withDeviceContext("u1", "d2"), nil)
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
@@ -333,7 +359,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 	})
 
 	t.Run("cannot_kick_current_device", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.KickDevice(withDeviceContext("u1", "d1"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.FailedPrecondition, consts.CodeCannotKickCurrent)
 	})
@@ -343,7 +369,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return nil, repository.ErrRecordNotFound
 			},
-		})
+		}, nil, nil)
 		err := svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
 
@@ -351,7 +377,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return nil, errors.New("db failed")
 			},
-		})
+		}, nil, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 
@@ -359,7 +385,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return nil, nil
 			},
-		})
+		}, nil, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
 	})
@@ -374,10 +400,22 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			deleteTokensFn: func(_ context.Context, _, _ string) error {
 				return errors.New("redis failed")
 			},
-		})
+		}, nil, nil)
 		err := svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 
+		svc = NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
+				return baseSession, nil
+			},
+			deleteTokensFn: func(_ context.Context, _, _ string) error { return nil },
+			bumpTokenEpochFn: func(_ context.Context, _, _ string) (int64, error) {
+				return 0, errors.New("redis failed")
+			},
+		}, nil, nil)
+		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+
 		svc = NewDeviceService(&fakeDeviceRepository{
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return baseSession, nil
@@ -386,7 +424,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return repository.ErrRecordNotFound
 			},
-		})
+		}, nil, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
 
@@ -398,13 +436,13 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return errors.New("db failed")
 			},
-		})
+		}, nil, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 	})
 
 	t.Run("success_paths", func(t *testing.T) {
-		var updateCalls int
+		var updateCalls, bumpCalls int
 		svc := NewDeviceService(&fakeDeviceRepository{
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return &model.DeviceSession{UserUuid: "u1", DeviceId: "d1", Status: model.DeviceStatusOnline}, nil
@@ -414,6 +452,12 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 				assert.Equal(t, "d1", deviceID)
 				return nil
 			},
+			bumpTokenEpochFn: func(_ context.Context, userUUID, deviceID string) (int64, error) {
+				bumpCalls++
+				assert.Equal(t, "u1", userUUID)
+				assert.Equal(t, "d1", deviceID)
+				return 2, nil
+			},
 			updateOnlineStatusFn: func(_ context.Context, userUUID, deviceID string, status int8) error {
 				updateCalls++
 				assert.Equal(t, "u1", userUUID)
@@ -421,9 +465,10 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 				assert.Equal(t, model.DeviceStatusKicked, status)
 				return nil
 			},
-		})
+		}, nil, nil)
 		require.NoError(t, svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"}))
 		assert.Equal(t, 1, updateCalls)
+		assert.Equal(t, 1, bumpCalls)
 
 		updateCalls = 0
 		svc = NewDeviceService(&fakeDeviceRepository{
@@ -435,17 +480,91 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 				updateCalls++
 				return nil
 			},
-		})
+		}, nil, nil)
 		require.NoError(t, svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"}))
 		assert.Equal(t, 0, updateCalls)
 	})
 }
 
+func TestUserDeviceServiceBatchKickDevices(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	t.Run("unauthenticated_and_invalid", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
+		_, err := svc.BatchKickDevices(context.Background(), &pb.BatchKickDevicesRequest{DeviceIds: []string{"d1"}})
+		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+
+		_, err = svc.BatchKickDevices(withDeviceContext("u1", "d9"), &pb.BatchKickDevicesRequest{})
+		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+
+		_, err = svc.BatchKickDevices(withDeviceContext("u1", "d9"), &pb.BatchKickDevicesRequest{DeviceIds: []string{"d1", ""}})
+		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("partial_success_with_summary_and_audit", func(t *testing.T) {
+		core, logs := observer.New(zap.InfoLevel)
+		logger.ReplaceGlobal(zap.New(core))
+		defer logger.ReplaceGlobal(zap.NewNop())
+
+		var kicked []string
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: func(_ context.Context, _ string, deviceID string) (*model.DeviceSession, error) {
+				switch deviceID {
+				case "gone":
+					return nil, repository.ErrRecordNotFound
+				case "broken":
+					return nil, errors.New("db down")
+				default:
+					return &model.DeviceSession{UserUuid: "u1", DeviceId: deviceID, Status: model.DeviceStatusOnline}, nil
+				}
+			},
+			deleteTokensFn: func(_ context.Context, _, _ string) error { return nil },
+			updateOnlineStatusFn: func(_ context.Context, _, deviceID string, _ int8) error {
+				kicked = append(kicked, deviceID)
+				return nil
+			},
+		}, nil, nil)
+
+		resp, err := svc.BatchKickDevices(withDeviceContext("u1", "cur"), &pb.BatchKickDevicesRequest{
+			DeviceIds: []string{"d1", "gone", "cur", "broken", "d2", "d1"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"d1", "d2"}, kicked)
+		assert.Equal(t, int32(2), resp.KickedCount)
+		assert.Equal(t, int32(1), resp.SkippedCount)
+		assert.Equal(t, int32(2), resp.FailedCount)
+
+		require.Len(t, resp.Results, 5)
+		byDevice := make(map[string]*pb.KickDeviceResult, len(resp.Results))
+		for _, item := range resp.Results {
+			byDevice[item.DeviceId] = item
+		}
+		assert.Equal(t, "kicked", byDevice["d1"].Result)
+		assert.Equal(t, int32(0), byDevice["d1"].Code)
+		assert.Equal(t, "failed", byDevice["gone"].Result)
+		assert.Equal(t, int32(consts.CodeDeviceNotFound), byDevice["gone"].Code)
+		assert.Equal(t, "skipped", byDevice["cur"].Result)
+		assert.Equal(t, int32(consts.CodeCannotKickCurrent), byDevice["cur"].Code)
+		assert.Equal(t, "failed", byDevice["broken"].Result)
+		assert.Equal(t, int32(consts.CodeInternalError), byDevice["broken"].Code)
+
+		audits := logs.FilterField(zap.String("audit_event", "device.batch_kick")).All()
+		require.Len(t, audits, 1)
+		fields := audits[0].ContextMap()
+		assert.Equal(t, "u1", fields["operator_uuid"])
+		assert.Equal(t, "cur", fields["operator_device_id"])
+		assert.Equal(t, []interface{}{"d1", "gone", "cur", "broken", "d2"}, fields["targets"])
+		assert.Equal(t, []interface{}{"d1", "d2"}, fields["kicked"])
+		assert.Equal(t, []interface{}{"cur"}, fields["skipped"])
+		assert.Equal(t, []interface{}{"gone", "broken"}, fields["failed"])
+	})
+}
+
 func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("invalid_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 
 		resp, err := svc.GetOnlineStatus(context.Background(), nil)
 		require.Nil(t, resp)
@@ -462,7 +581,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 				assert.Equal(t, []string{"u1"}, userUUIDs)
 				return nil, errors.New("db failed")
 			},
-		})
+		}, nil, nil)
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.Nil(t, resp)
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
@@ -473,7 +592,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 			batchGetOnlineStatusFn: func(_ context.Context, _ []string) (map[string][]*model.DeviceSession, error) {
 				return map[string][]*model.DeviceSession{}, nil
 			},
-		})
+		}, nil, nil)
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -495,7 +614,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 			getActiveTimestampsFn: func(_ context.Context, _ string, _ []string) (map[string]int64, error) {
 				return nil, errors.New("redis failed")
 			},
-		})
+		}, nil, nil)
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -528,7 +647,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 				assert.Equal(t, []string{"u1"}, userUUIDs)
 				return map[string]int64{"u1": now - 10}, nil
 			},
-		})
+		}, nil, nil)
 
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.NoError(t, err)
@@ -544,7 +663,7 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("invalid_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 
 		resp, err := svc.BatchGetOnlineStatus(context.Background(), nil)
 		require.Nil(t, resp)
@@ -573,7 +692,7 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 				assert.Equal(t, []string{"u1", "u2"}, userUUIDs)
 				return nil, errors.New("db failed")
 			},
-		})
+		}, nil, nil)
 
 		resp, err := svc.BatchGetOnlineStatus(context.Background(), &pb.BatchGetOnlineStatusRequest{UserUuids: []string{"u1", "u2"}})
 		require.Nil(t, resp)
@@ -610,7 +729,7 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 					"u1": now - 10,
 				}, nil
 			},
-		})
+		}, nil, nil)
 
 		req := &pb.BatchGetOnlineStatusRequest{UserUuids: []string{"u1", "u1", "u2", "u3"}}
 		resp, err := svc.BatchGetOnlineStatus(context.Background(), req)
@@ -640,17 +759,112 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 	})
 }
 
+func TestUserDeviceServiceBatchGetOnlineStatusChunked(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	t.Run("empty_and_invalid", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
+
+		items, err := svc.BatchGetOnlineStatusChunked(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, items)
+
+		items, err = svc.BatchGetOnlineStatusChunked(context.Background(), []string{"u1", ""})
+		require.Nil(t, items)
+		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("over_100_chunks_dedup_and_order", func(t *testing.T) {
+		now := time.Now().Unix()
+		// 250 个不同用户，倒序排列并在末尾重复前 5 个，验证去重跨分片与请求顺序。
+		userUUIDs := make([]string, 0, 255)
+		for i := 249; i >= 0; i-- {
+			userUUIDs = append(userUUIDs, "u"+strconv.Itoa(i))
+		}
+		userUUIDs = append(userUUIDs, userUUIDs[:5]...)
+
+		var chunks [][]string
+		svc := NewDeviceService(&fakeDeviceRepository{
+			batchGetOnlineStatusFn: func(_ context.Context, chunk []string) (map[string][]*model.DeviceSession, error) {
+				chunks = append(chunks, append([]string(nil), chunk...))
+				result := make(map[string][]*model.DeviceSession)
+				for _, userUUID := range chunk {
+					result[userUUID] = []*model.DeviceSession{
+						{UserUuid: userUUID, DeviceId: "d-" + userUUID, Status: model.DeviceStatusOnline},
+					}
+				}
+				return result, nil
+			},
+			batchGetActiveTsFn: func(_ context.Context, userDeviceIDs map[string][]string) (map[string]map[string]int64, error) {
+				// 仅偶数编号用户在线。
+				result := make(map[string]map[string]int64)
+				for userUUID, deviceIDs := range userDeviceIDs {
+					n, _ := strconv.Atoi(userUUID[1:])
+					if n%2 == 0 {
+						result[userUUID] = map[string]int64{deviceIDs[0]: now}
+					}
+				}
+				return result, nil
+			},
+		}, nil, nil)
+
+		items, err := svc.BatchGetOnlineStatusChunked(context.Background(), userUUIDs)
+		require.NoError(t, err)
+		require.Len(t, items, len(userUUIDs))
+
+		require.Len(t, chunks, 3)
+		assert.Len(t, chunks[0], 100)
+		assert.Len(t, chunks[1], 100)
+		assert.Len(t, chunks[2], 50)
+		seen := make(map[string]struct{})
+		for _, chunk := range chunks {
+			for _, userUUID := range chunk {
+				_, dup := seen[userUUID]
+				assert.False(t, dup, "用户 %s 被重复查询", userUUID)
+				seen[userUUID] = struct{}{}
+			}
+		}
+
+		for i, item := range items {
+			assert.Equal(t, userUUIDs[i], item.UserUuid)
+			n, _ := strconv.Atoi(item.UserUuid[1:])
+			assert.Equal(t, n%2 == 0, item.IsOnline, item.UserUuid)
+		}
+	})
+
+	t.Run("chunk_repo_error", func(t *testing.T) {
+		calls := 0
+		svc := NewDeviceService(&fakeDeviceRepository{
+			batchGetOnlineStatusFn: func(_ context.Context, _ []string) (map[string][]*model.DeviceSession, error) {
+				calls++
+				if calls == 2 {
+					return nil, errors.New("db failed")
+				}
+				return map[string][]*model.DeviceSession{}, nil
+			},
+		}, nil, nil)
+
+		userUUIDs := make([]string, 150)
+		for i := range userUUIDs {
+			userUUIDs[i] = "u" + strconv.Itoa(i)
+		}
+		items, err := svc.BatchGetOnlineStatusChunked(context.Background(), userUUIDs)
+		require.Nil(t, items)
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
+
 func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("nil_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), nil)
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("empty_user_uuid", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "",
 			DeviceId: "d1",
@@ -660,7 +874,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	})
 
 	t.Run("empty_device_id", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "",
@@ -670,7 +884,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	})
 
 	t.Run("invalid_status_kicked", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -680,7 +894,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	})
 
 	t.Run("invalid_status_logged_out", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -702,7 +916,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 				captured.status = status
 				return nil
 			},
-		})
+		}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -721,7 +935,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 				capturedStatus = status
 				return nil
 			},
-		})
+		}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -736,7 +950,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return repository.ErrRecordNotFound
 			},
-		})
+		}, nil, nil)
 		// 设备不存在时应返回成功（幂等语义）
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
@@ -751,7 +965,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return errors.New("db write failed")
 			},
-		})
+		}, nil, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -765,19 +979,19 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("nil_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceActive(context.Background(), nil)
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("empty_items", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{Items: []*pb.UpdateDeviceActiveItem{}})
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("invalid_item", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{
 			Items: []*pb.UpdateDeviceActiveItem{
 				{UserUuid: "u1", DeviceId: "d1"},
@@ -796,7 +1010,7 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 				assert.Greater(t, ts, int64(0))
 				return errors.New("redis write failed")
 			},
-		})
+		}, nil, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{
 			Items: []*pb.UpdateDeviceActiveItem{
 				{UserUuid: "u1", DeviceId: "d1"},
@@ -819,7 +1033,7 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 				assert.True(t, got["u2:d3"])
 				return nil
 			},
-		})
+		}, nil, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{
 			Items: []*pb.UpdateDeviceActiveItem{
 				{UserUuid: "u1", DeviceId: "d1"},
@@ -830,3 +1044,142 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestUserDeviceServiceOnlineStatusPrivacy(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	now := time.Now().Unix()
+	// u1/u2/u3 均在线：u1 未隐藏，u2 对所有人隐藏，u3 仅对非好友隐藏（viewer 为 u3 的好友）
+	deviceRepo := &fakeDeviceRepository{
+		batchGetOnlineStatusFn: func(_ context.Context, userUUIDs []string) (map[string][]*model.DeviceSession, error) {
+			result := make(map[string][]*model.DeviceSession, len(userUUIDs))
+			for _, userUUID := range userUUIDs {
+				result[userUUID] = []*model.DeviceSession{
+					{UserUuid: userUUID, DeviceId: "d-" + userUUID, Platform: "ios", Status: model.DeviceStatusOnline},
+				}
+			}
+			return result, nil
+		},
+		getActiveTimestampsFn: func(_ context.Context, _ string, deviceIDs []string) (map[string]int64, error) {
+			return map[string]int64{deviceIDs[0]: now - 10}, nil
+		},
+		batchGetActiveTsFn: func(_ context.Context, userDeviceIDs map[string][]string) (map[string]map[string]int64, error) {
+			result := make(map[string]map[string]int64, len(userDeviceIDs))
+			for userUUID, deviceIDs := range userDeviceIDs {
+				result[userUUID] = map[string]int64{deviceIDs[0]: now - 10}
+			}
+			return result, nil
+		},
+		batchGetLastSeenTsFn: func(_ context.Context, userUUIDs []string) (map[string]int64, error) {
+			result := make(map[string]int64, len(userUUIDs))
+			for _, userUUID := range userUUIDs {
+				result[userUUID] = now - 10
+			}
+			return result, nil
+		},
+	}
+	userRepo := &fakeUserSvcRepo{
+		batchGetByUUIDsFn: func(_ context.Context, uuids []string) ([]*model.UserInfo, error) {
+			privacy := map[string]*model.UserInfo{
+				"u1": {Uuid: "u1"},
+				"u2": {Uuid: "u2", HidePresence: 1, PresenceHideScope: model.PresenceHideScopeEveryone},
+				"u3": {Uuid: "u3", HidePresence: 1, PresenceHideScope: model.PresenceHideScopeNonFriends},
+			}
+			users := make([]*model.UserInfo, 0, len(uuids))
+			for _, uuid := range uuids {
+				if user, ok := privacy[uuid]; ok {
+					users = append(users, user)
+				}
+			}
+			return users, nil
+		},
+	}
+	var friendOfCalls atomic.Int32
+	friendRepo := &fakeFriendRepoForService{
+		checkIsFriendFn: func(context.Context, string, string) (bool, error) {
+			return false, errors.New("unexpected per-user friend check")
+		},
+		batchIsFriendOfFn: func(_ context.Context, peerUUID string, userUUIDs []string) (map[string]bool, error) {
+			friendOfCalls.Add(1)
+			result := make(map[string]bool, len(userUUIDs))
+			for _, userUUID := range userUUIDs {
+				result[userUUID] = userUUID == "u3" && peerUUID == "friend"
+			}
+			return result, nil
+		},
+	}
+	svc := NewDeviceService(deviceRepo, userRepo, friendRepo)
+
+	t.Run("hidden_from_everyone", func(t *testing.T) {
+		resp, err := svc.GetOnlineStatus(userSvcCtx("friend"), &pb.GetOnlineStatusRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.False(t, resp.Status.IsOnline)
+		assert.Equal(t, int64(0), resp.Status.LastSeenAt)
+		assert.Empty(t, resp.Status.OnlinePlatforms)
+	})
+
+	t.Run("self_always_visible", func(t *testing.T) {
+		resp, err := svc.GetOnlineStatus(userSvcCtx("u2"), &pb.GetOnlineStatusRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.True(t, resp.Status.IsOnline)
+	})
+
+	t.Run("non_friend_scope", func(t *testing.T) {
+		resp, err := svc.GetOnlineStatus(userSvcCtx("friend"), &pb.GetOnlineStatusRequest{UserUuid: "u3"})
+		require.NoError(t, err)
+		assert.True(t, resp.Status.IsOnline, "friends still see presence")
+
+		resp, err = svc.GetOnlineStatus(userSvcCtx("stranger"), &pb.GetOnlineStatusRequest{UserUuid: "u3"})
+		require.NoError(t, err)
+		assert.False(t, resp.Status.IsOnline)
+		assert.Equal(t, int64(0), resp.Status.LastSeenAt)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		friendOfCalls.Store(0)
+		resp, err := svc.BatchGetOnlineStatus(userSvcCtx("stranger"), &pb.BatchGetOnlineStatusRequest{UserUuids: []string{"u1", "u2", "u3"}})
+		require.NoError(t, err)
+		require.Len(t, resp.Users, 3)
+		assert.True(t, resp.Users[0].IsOnline)
+		assert.Equal(t, (now-10)*1000, resp.Users[0].LastSeenAt)
+		for _, item := range resp.Users[1:] {
+			assert.False(t, item.IsOnline, item.UserUuid)
+			assert.Equal(t, int64(0), item.LastSeenAt, item.UserUuid)
+		}
+		assert.Equal(t, int32(1), friendOfCalls.Load(), "好友关系应批量查询一次")
+	})
+
+	t.Run("internal_chunked_unfiltered", func(t *testing.T) {
+		friendOfCalls.Store(0)
+		items, err := svc.BatchGetOnlineStatusChunked(context.Background(), []string{"u1", "u2", "u3"})
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		for _, item := range items {
+			assert.True(t, item.IsOnline, item.UserUuid)
+			assert.Equal(t, (now-10)*1000, item.LastSeenAt, item.UserUuid)
+		}
+		assert.Equal(t, int32(0), friendOfCalls.Load())
+	})
+
+	t.Run("friend_lookup_error_hides", func(t *testing.T) {
+		failing := NewDeviceService(deviceRepo, userRepo, &fakeFriendRepoForService{
+			batchIsFriendOfFn: func(context.Context, string, []string) (map[string]bool, error) {
+				return nil, errors.New("db down")
+			},
+		})
+		resp, err := failing.GetOnlineStatus(userSvcCtx("friend"), &pb.GetOnlineStatusRequest{UserUuid: "u3"})
+		require.NoError(t, err)
+		assert.False(t, resp.Status.IsOnline)
+	})
+
+	t.Run("privacy_lookup_error", func(t *testing.T) {
+		failing := NewDeviceService(deviceRepo, &fakeUserSvcRepo{
+			batchGetByUUIDsFn: func(context.Context, []string) ([]*model.UserInfo, error) {
+				return nil, errors.New("db down")
+			},
+		}, friendRepo)
+		resp, err := failing.GetOnlineStatus(userSvcCtx("stranger"), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
+		require.Nil(t, resp)
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
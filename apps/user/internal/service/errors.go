@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"ChatServer/consts"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// bizErrorDomain 业务错误详情（ErrorInfo.Domain）中的服务标识。
+const bizErrorDomain = "user"
+
+// bizGRPCCodes 业务错误码 → gRPC 状态码。
+// 统一在此维护映射，避免各处手写 status.Error 时 gRPC 码与业务码不一致。
+var bizGRPCCodes = map[int]codes.Code{
+	consts.CodeParamError:            codes.InvalidArgument,
+	consts.CodeUnauthorized:          codes.Unauthenticated,
+	consts.CodeInvalidToken:          codes.InvalidArgument,
+	consts.CodeInternalError:         codes.Internal,
+	consts.CodeServiceUnavailable:    codes.Unavailable,
+	consts.CodeUserNotFound:          codes.NotFound,
+	consts.CodeUserAlreadyExist:      codes.AlreadyExists,
+	consts.CodeUserDisabled:          codes.PermissionDenied,
+	consts.CodePasswordError:         codes.Unauthenticated,
+	consts.CodePasswordSameAsOld:     codes.FailedPrecondition,
+	consts.CodeVerifyCodeError:       codes.Unauthenticated,
+	consts.CodeVerifyCodeExpire:      codes.Unauthenticated,
+	consts.CodeSendTooFrequent:       codes.ResourceExhausted,
+	consts.CodeInvalidEmail:          codes.InvalidArgument,
+	consts.CodePhoneFormatError:      codes.InvalidArgument,
+	consts.CodeBirthdayFormatError:   codes.InvalidArgument,
+	consts.CodeEmailAlreadyExist:     codes.AlreadyExists,
+	consts.CodeEmailSameAsOld:        codes.FailedPrecondition,
+	consts.CodeTelephoneAlreadyExist: codes.AlreadyExists,
+	consts.CodeTelephoneSameAsOld:    codes.FailedPrecondition,
+	consts.CodeQRCodeExpired:         codes.NotFound,
+	consts.CodeQRCodeFormatError:     codes.InvalidArgument,
+	consts.CodeNoPermission:          codes.PermissionDenied,
+	consts.CodeAlreadyFriend:         codes.AlreadyExists,
+	consts.CodeFriendRequestSent:     codes.AlreadyExists,
+	consts.CodeCannotAddSelf:         codes.InvalidArgument,
+	consts.CodeNotFriend:             codes.NotFound,
+	consts.CodeApplyNotFoundOrHandle: codes.NotFound,
+	consts.CodeAlreadyInBlacklist:    codes.AlreadyExists,
+	consts.CodeNotInBlacklist:        codes.NotFound,
+	consts.CodeCannotBlacklistSelf:   codes.InvalidArgument,
+	consts.CodePeerBlacklistYou:      codes.FailedPrecondition,
+	consts.CodeYouBlacklistPeer:      codes.FailedPrecondition,
+	consts.CodeDeviceNotFound:        codes.NotFound,
+	consts.CodeCannotKickCurrent:     codes.FailedPrecondition,
+}
+
+// bizGRPCCode 返回业务错误码对应的 gRPC 状态码。
+// 未登记的业务码按区间兜底：3xxxx 服务端错误 → Internal，2xxxx 认证错误 → Unauthenticated，其余 → FailedPrecondition。
+func bizGRPCCode(code int) codes.Code {
+	if c, ok := bizGRPCCodes[code]; ok {
+		return c
+	}
+	switch {
+	case code >= 30000:
+		return codes.Internal
+	case code >= 20000:
+		return codes.Unauthenticated
+	default:
+		return codes.FailedPrecondition
+	}
+}
+
+// bizError 构造业务错误：status message 固定为业务码字符串（Gateway 据此解析），
+// 同时附带 ErrorInfo 详情（Reason=业务码，Metadata.message=业务码默认文案）。
+func bizError(code int) error {
+	return newBizStatus(code, consts.GetMessage(code)).Err()
+}
+
+// bizErrorf 同 bizError，附带格式化的补充说明（仅写入 ErrorInfo 详情，不影响 message 中的业务码）。
+func bizErrorf(code int, format string, args ...interface{}) error {
+	return newBizStatus(code, fmt.Sprintf(format, args...)).Err()
+}
+
+func newBizStatus(code int, detail string) *status.Status {
+	st := status.New(bizGRPCCode(code), strconv.Itoa(code))
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   strconv.Itoa(code),
+		Domain:   bizErrorDomain,
+		Metadata: map[string]string{"message": detail},
+	})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
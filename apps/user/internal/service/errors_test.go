@@ -0,0 +1,70 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"ChatServer/consts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// bizErrorInfo 取出业务错误附带的 ErrorInfo 详情。
+func bizErrorInfo(t *testing.T, st *status.Status) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatal("缺少 ErrorInfo 详情")
+	return nil
+}
+
+func TestBizErrorRoundTrip(t *testing.T) {
+	tests := []struct {
+		code     int
+		wantGRPC codes.Code
+	}{
+		{consts.CodeParamError, codes.InvalidArgument},
+		{consts.CodeUnauthorized, codes.Unauthenticated},
+		{consts.CodeDeviceNotFound, codes.NotFound},
+		{consts.CodeAlreadyInBlacklist, codes.AlreadyExists},
+		{consts.CodeCannotKickCurrent, codes.FailedPrecondition},
+		{consts.CodeInternalError, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			st, ok := status.FromError(bizError(tt.code))
+			require.True(t, ok)
+			assert.Equal(t, tt.wantGRPC, st.Code())
+
+			code, err := strconv.Atoi(st.Message())
+			require.NoError(t, err, "message 必须是业务码字符串")
+			assert.Equal(t, tt.code, code)
+
+			info := bizErrorInfo(t, st)
+			assert.Equal(t, strconv.Itoa(tt.code), info.Reason)
+			assert.Equal(t, bizErrorDomain, info.Domain)
+			assert.Equal(t, consts.GetMessage(tt.code), info.Metadata["message"])
+		})
+	}
+}
+
+func TestBizErrorfKeepsCodeInMessage(t *testing.T) {
+	st, ok := status.FromError(bizErrorf(consts.CodeParamError, "device_id 长度超过 %d", 64))
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, strconv.Itoa(consts.CodeParamError), st.Message())
+	assert.Equal(t, "device_id 长度超过 64", bizErrorInfo(t, st).Metadata["message"])
+}
+
+func TestBizGRPCCodeFallback(t *testing.T) {
+	assert.Equal(t, codes.Internal, bizGRPCCode(39999))
+	assert.Equal(t, codes.Unauthenticated, bizGRPCCode(29999))
+	assert.Equal(t, codes.FailedPrecondition, bizGRPCCode(19999))
+}
@@ -5,6 +5,7 @@ import (
 	pb "ChatServer/apps/user/pb"
 	"ChatServer/consts"
 	"ChatServer/model"
+	"ChatServer/pkg/cursor"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/util"
 	"context"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"time"
 
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -21,6 +23,9 @@ type friendServiceImpl struct {
 	friendRepo    repository.IFriendRepository
 	applyRepo     repository.IApplyRepository
 	blacklistRepo repository.IBlacklistRepository
+
+	// applyFlight 按 (申请人, 目标) 合并本实例内的并发好友申请，跨实例由 pending_key 唯一索引兜底
+	applyFlight singleflight.Group
 }
 
 // NewFriendService 创建好友服务实例
@@ -47,6 +52,9 @@ func NewFriendService(
 //  7. 创建好友申请记录
 //  8. 返回申请ID
 //
+// 并发控制：步骤 4~7 按 (申请人, 目标) 经 singleflight 合并执行，并发重复申请共享同一结果；
+// 多实例间的竞争由 apply_request.pending_key 唯一索引兜底，唯一键冲突同样返回“申请已发送”。
+//
 // 错误码映射：
 //   - codes.InvalidArgument: 不能添加自己为好友
 //   - codes.AlreadyExists: 已经是好友、申请已发送
@@ -87,6 +95,24 @@ func (s *friendServiceImpl) SendFriendApply(ctx context.Context, req *pb.SendFri
 		return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeAlreadyFriend))
 	}
 
+	flightKey := currentUserUUID + ":" + req.TargetUuid
+	v, err, _ := s.applyFlight.Do(flightKey, func() (interface{}, error) {
+		return s.createFriendApply(ctx, currentUserUUID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	createdApply := v.(*model.ApplyRequest)
+
+	// 8. 返回申请ID
+	return &pb.SendFriendApplyResponse{
+		ApplyId: createdApply.Id,
+	}, nil
+}
+
+// createFriendApply 检查待处理申请与拉黑状态后创建好友申请（SendFriendApply 步骤 4~7）。
+// 返回的 error 已是 gRPC status。
+func (s *friendServiceImpl) createFriendApply(ctx context.Context, currentUserUUID string, req *pb.SendFriendApplyRequest) (*model.ApplyRequest, error) {
 	// 4. 检查是否存在待处理的申请
 	exists, err := s.applyRepo.ExistsPendingRequest(ctx, currentUserUUID, req.TargetUuid)
 	if err != nil {
@@ -156,6 +182,14 @@ func (s *friendServiceImpl) SendFriendApply(ctx context.Context, req *pb.SendFri
 	}
 
 	createdApply, err := s.applyRepo.Create(ctx, apply)
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		// 并发申请（其他实例）已抢先写入待处理记录
+		logger.Info(ctx, "好友申请已发送（唯一键冲突）",
+			logger.String("user_uuid", currentUserUUID),
+			logger.String("target_uuid", req.TargetUuid),
+		)
+		return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeFriendRequestSent))
+	}
 	if err != nil {
 		logger.Error(ctx, "创建好友申请失败",
 			logger.String("user_uuid", currentUserUUID),
@@ -173,10 +207,7 @@ func (s *friendServiceImpl) SendFriendApply(ctx context.Context, req *pb.SendFri
 		logger.String("source", req.Source),
 	)
 
-	// 8. 返回申请ID
-	return &pb.SendFriendApplyResponse{
-		ApplyId: createdApply.Id,
-	}, nil
+	return createdApply, nil
 }
 
 // GetFriendApplyList 获取好友申请列表
@@ -198,14 +229,30 @@ func (s *friendServiceImpl) GetFriendApplyList(ctx context.Context, req *pb.GetF
 		pageSize = 20
 	}
 
-	// 查询申请列表（status<0 表示全部状态）
-	applies, total, err := s.applyRepo.GetPendingList(ctx, currentUserUUID, int(req.Status), int(page), int(pageSize))
+	after, err := decodeListCursor(ctx, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 查询申请列表（status<0 表示全部状态）：携带游标时走游标分页，否则走偏移分页
+	var (
+		applies []*model.ApplyRequest
+		total   int64
+		hasMore bool
+	)
+	if after.IsZero() {
+		applies, total, err = s.applyRepo.GetPendingList(ctx, currentUserUUID, int(req.Status), int(page), int(pageSize))
+		hasMore = offsetHasMore(page, pageSize, len(applies), total)
+	} else {
+		applies, hasMore, err = s.applyRepo.GetPendingListByCursor(ctx, currentUserUUID, int(req.Status), after, int(pageSize))
+	}
 	if err != nil {
 		logger.Error(ctx, "获取好友申请列表失败",
 			logger.String("user_uuid", currentUserUUID),
 			logger.Int32("status", req.Status),
 			logger.Int32("page", page),
 			logger.Int32("page_size", pageSize),
+			logger.String("cursor", req.Cursor),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
@@ -233,6 +280,7 @@ func (s *friendServiceImpl) GetFriendApplyList(ctx context.Context, req *pb.GetF
 
 	// 组装返回项（申请记录 + 申请人简要信息）
 	items := make([]*pb.FriendApplyItem, 0, len(applies))
+	now := time.Now()
 	unreadIDs := make([]int64, 0) // 收集未读申请的 ID
 
 	for _, apply := range applies {
@@ -256,7 +304,7 @@ func (s *friendServiceImpl) GetFriendApplyList(ctx context.Context, req *pb.GetF
 			ApplicantInfo: applicantInfo,
 			Reason:        apply.Reason,
 			Source:        apply.Source,
-			Status:        int32(apply.Status),
+			Status:        applyStatus(apply, now),
 			IsRead:        apply.IsRead,
 			CreatedAt:     apply.CreatedAt.UnixMilli(),
 		})
@@ -264,7 +312,7 @@ func (s *friendServiceImpl) GetFriendApplyList(ctx context.Context, req *pb.GetF
 
 	// 异步标记已读（不阻塞响应）
 	if len(unreadIDs) > 0 {
-		s.applyRepo.MarkAsReadAsync(ctx, unreadIDs)
+		s.applyRepo.MarkAsReadAsync(ctx, currentUserUUID, unreadIDs)
 	}
 
 	// 清除未读数量红点（尽力而为）
@@ -275,7 +323,7 @@ func (s *friendServiceImpl) GetFriendApplyList(ctx context.Context, req *pb.GetF
 		)
 	}
 
-	return &pb.GetFriendApplyListResponse{
+	resp := &pb.GetFriendApplyListResponse{
 		Items: items,
 		Pagination: &pb.PaginationInfo{
 			Page:       page,
@@ -283,7 +331,11 @@ func (s *friendServiceImpl) GetFriendApplyList(ctx context.Context, req *pb.GetF
 			Total:      total,
 			TotalPages: int32((total + int64(pageSize) - 1) / int64(pageSize)),
 		},
-	}, nil
+	}
+	if last := applies[len(applies)-1]; hasMore && last != nil {
+		resp.NextCursor = cursor.AfterRow(last.CreatedAt, last.Id).Encode()
+	}
+	return resp, nil
 }
 
 // GetSentApplyList 获取发出的申请列表
@@ -305,14 +357,30 @@ func (s *friendServiceImpl) GetSentApplyList(ctx context.Context, req *pb.GetSen
 		pageSize = 20
 	}
 
-	// 查询发出的申请列表（status<0 表示全部状态）
-	applies, total, err := s.applyRepo.GetSentList(ctx, currentUserUUID, int(req.Status), int(page), int(pageSize))
+	after, err := decodeListCursor(ctx, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 查询发出的申请列表（status<0 表示全部状态）：携带游标时走游标分页，否则走偏移分页
+	var (
+		applies []*model.ApplyRequest
+		total   int64
+		hasMore bool
+	)
+	if after.IsZero() {
+		applies, total, err = s.applyRepo.GetSentList(ctx, currentUserUUID, int(req.Status), int(page), int(pageSize))
+		hasMore = offsetHasMore(page, pageSize, len(applies), total)
+	} else {
+		applies, hasMore, err = s.applyRepo.GetSentListByCursor(ctx, currentUserUUID, int(req.Status), after, int(pageSize))
+	}
 	if err != nil {
 		logger.Error(ctx, "获取发出的申请列表失败",
 			logger.String("user_uuid", currentUserUUID),
 			logger.Int32("status", req.Status),
 			logger.Int32("page", page),
 			logger.Int32("page_size", pageSize),
+			logger.String("cursor", req.Cursor),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
@@ -333,6 +401,7 @@ func (s *friendServiceImpl) GetSentApplyList(ctx context.Context, req *pb.GetSen
 
 	// 组装返回项（申请记录 + 目标用户简要信息）
 	items := make([]*pb.SentApplyItem, 0, len(applies))
+	now := time.Now()
 	for _, apply := range applies {
 		if apply == nil {
 			continue
@@ -348,13 +417,13 @@ func (s *friendServiceImpl) GetSentApplyList(ctx context.Context, req *pb.GetSen
 			TargetInfo: targetInfo,
 			Reason:     apply.Reason,
 			Source:     apply.Source,
-			Status:     int32(apply.Status),
+			Status:     applyStatus(apply, now),
 			IsRead:     apply.IsRead,
 			CreatedAt:  apply.CreatedAt.UnixMilli(),
 		})
 	}
 
-	return &pb.GetSentApplyListResponse{
+	resp := &pb.GetSentApplyListResponse{
 		Items: items,
 		Pagination: &pb.PaginationInfo{
 			Page:       page,
@@ -362,7 +431,37 @@ func (s *friendServiceImpl) GetSentApplyList(ctx context.Context, req *pb.GetSen
 			Total:      total,
 			TotalPages: int32((total + int64(pageSize) - 1) / int64(pageSize)),
 		},
-	}, nil
+	}
+	if last := applies[len(applies)-1]; hasMore && last != nil {
+		resp.NextCursor = cursor.AfterRow(last.CreatedAt, last.Id).Encode()
+	}
+	return resp, nil
+}
+
+// decodeListCursor 解析列表请求携带的游标，空串表示偏移分页；格式非法返回 InvalidArgument
+func decodeListCursor(ctx context.Context, raw string) (cursor.Cursor, error) {
+	after, err := cursor.Decode(raw)
+	if err != nil || (!after.IsZero() && !after.IsRow()) {
+		logger.Warn(ctx, "列表游标非法", logger.String("cursor", raw))
+		return cursor.Cursor{}, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	return after, nil
+}
+
+// offsetHasMore 偏移分页是否还有下一页：已知总数时按总数判断，否则（非首页不计总数）按本页是否取满判断
+func offsetHasMore(page, pageSize int32, got int, total int64) bool {
+	if total > 0 {
+		return int64(page)*int64(pageSize) < total
+	}
+	return got >= int(pageSize)
+}
+
+// applyStatus 返回对外展示的申请状态：已到期但尚未被扫描置为过期的待处理申请展示为 3（已过期）
+func applyStatus(apply *model.ApplyRequest, now time.Time) int32 {
+	if apply.IsExpired(now) {
+		return 3
+	}
+	return int32(apply.Status)
 }
 
 // HandleFriendApply 处理好友申请
@@ -370,7 +469,8 @@ func (s *friendServiceImpl) GetSentApplyList(ctx context.Context, req *pb.GetSen
 //  1. 从context获取当前用户UUID
 //  2. 根据applyId获取申请详情
 //  3. 验证当前用户是否为申请的目标用户（有权限处理）
-//  4. 同意：调用 AcceptApplyAndCreateRelation（事务 + CAS幂等）
+//  4. 检查申请是否已过期（已到期的待处理申请惰性置为过期）
+//  5. 同意：调用 AcceptApplyAndCreateRelation（事务 + CAS幂等）
 //     拒绝：调用 UpdateStatus（CAS幂等）
 func (s *friendServiceImpl) HandleFriendApply(ctx context.Context, req *pb.HandleFriendApplyRequest) error {
 	// 1. 从context获取当前用户UUID（处理人）
@@ -382,12 +482,18 @@ func (s *friendServiceImpl) HandleFriendApply(ctx context.Context, req *pb.Handl
 
 	// 2. 根据applyId获取申请详情
 	apply, err := s.applyRepo.GetByID(ctx, req.ApplyId)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		logger.Warn(ctx, "好友申请不存在",
+			logger.Int64("apply_id", req.ApplyId),
+		)
+		return status.Error(codes.NotFound, strconv.Itoa(consts.CodeApplyNotFoundOrHandle))
+	}
 	if err != nil {
-		logger.Warn(ctx, "获取好友申请失败",
+		logger.Error(ctx, "获取好友申请失败",
 			logger.Int64("apply_id", req.ApplyId),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.NotFound, strconv.Itoa(consts.CodeApplyNotFoundOrHandle))
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 	if apply == nil {
 		logger.Warn(ctx, "好友申请不存在",
@@ -406,7 +512,22 @@ func (s *friendServiceImpl) HandleFriendApply(ctx context.Context, req *pb.Handl
 		return status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNoPermission))
 	}
 
-	// 4. 处理申请
+	// 4. 检查申请是否已过期
+	if apply.IsExpired(time.Now()) {
+		if err := s.applyRepo.ExpireApply(ctx, apply); err != nil {
+			// 置为过期失败不影响本次判定，由后台扫描兜底
+			logger.Warn(ctx, "好友申请置为过期失败",
+				logger.Int64("apply_id", req.ApplyId),
+				logger.ErrorField("error", err),
+			)
+		}
+		logger.Info(ctx, "好友申请已过期",
+			logger.Int64("apply_id", req.ApplyId),
+		)
+		return status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeApplyExpired))
+	}
+
+	// 5. 处理申请
 	if req.Action == 1 {
 		// 同意：事务性更新申请状态 + 创建好友关系
 		alreadyProcessed, err := s.applyRepo.AcceptApplyAndCreateRelation(ctx, req.ApplyId, currentUserUUID, apply.ApplicantUuid, req.Remark)
@@ -481,44 +602,57 @@ func (s *friendServiceImpl) GetUnreadApplyCount(ctx context.Context, req *pb.Get
 	}, nil
 }
 
-// MarkApplyAsRead 标记申请已读
-func (s *friendServiceImpl) MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) error {
+// MarkApplyAsRead 标记申请已读，返回剩余未读申请数量
+func (s *friendServiceImpl) MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) (*pb.MarkApplyAsReadResponse, error) {
 	// 1. 获取当前用户 UUID
 	currentUserUUID := util.GetUserUUIDFromContext(ctx)
 	if currentUserUUID == "" {
 		logger.Error(ctx, "获取用户UUID失败")
-		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
 	}
 
-	// 2. 标记已读（applyIds 为空则标记全部）
-	if len(req.ApplyIds) == 0 {
+	// 2. mark_all：单条 UPDATE 标记全部并清除未读数量红点（尽力而为）
+	if req.MarkAll {
 		if _, err := s.applyRepo.MarkAllAsRead(ctx, currentUserUUID); err != nil {
 			logger.Error(ctx, "标记全部申请已读失败",
 				logger.String("user_uuid", currentUserUUID),
 				logger.ErrorField("error", err),
 			)
-			return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+			return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 		}
-	} else {
-		if _, err := s.applyRepo.MarkAsRead(ctx, currentUserUUID, req.ApplyIds); err != nil {
-			logger.Error(ctx, "标记申请已读失败",
+		if err := s.applyRepo.ClearUnreadCount(ctx, currentUserUUID); err != nil {
+			logger.Warn(ctx, "清除好友申请未读数量失败",
 				logger.String("user_uuid", currentUserUUID),
-				logger.Int("count", len(req.ApplyIds)),
 				logger.ErrorField("error", err),
 			)
-			return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 		}
+		return &pb.MarkApplyAsReadResponse{UnreadCount: 0}, nil
 	}
 
-	// 3. 清除未读数量红点（尽力而为）
-	if err := s.applyRepo.ClearUnreadCount(ctx, currentUserUUID); err != nil {
-		logger.Warn(ctx, "清除好友申请未读数量失败",
+	// 3. 按 applyIds 标记（不能为空），仓储按实际已读行数扣减未读计数
+	if len(req.ApplyIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	if _, err := s.applyRepo.MarkAsRead(ctx, currentUserUUID, req.ApplyIds); err != nil {
+		logger.Error(ctx, "标记申请已读失败",
 			logger.String("user_uuid", currentUserUUID),
+			logger.Int("count", len(req.ApplyIds)),
 			logger.ErrorField("error", err),
 		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	return nil
+	// 4. 返回扣减后的未读数量（读取失败降级返回 0，与 GetUnreadApplyCount 一致）
+	count, err := s.applyRepo.GetUnreadCount(ctx, currentUserUUID)
+	if err != nil {
+		logger.Warn(ctx, "获取好友申请未读数量失败，降级返回 0",
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		count = 0
+	}
+
+	return &pb.MarkApplyAsReadResponse{UnreadCount: int32(count)}, nil
 }
 
 // GetFriendList 获取好友列表
@@ -540,14 +674,31 @@ func (s *friendServiceImpl) GetFriendList(ctx context.Context, req *pb.GetFriend
 		pageSize = 20
 	}
 
-	// 3. 获取好友关系列表
-	relations, total, version, err := s.friendRepo.GetFriendList(ctx, currentUserUUID, req.GroupTag, int(page), int(pageSize))
+	after, err := decodeListCursor(ctx, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 获取好友关系列表：携带游标时走游标分页（不计算 total/version），否则走偏移分页
+	var (
+		relations []*model.UserRelation
+		total     int64
+		version   int64
+		hasMore   bool
+	)
+	if after.IsZero() {
+		relations, total, version, err = s.friendRepo.GetFriendList(ctx, currentUserUUID, req.GroupTag, int(page), int(pageSize))
+		hasMore = offsetHasMore(page, pageSize, len(relations), total)
+	} else {
+		relations, hasMore, err = s.friendRepo.GetFriendListByCursor(ctx, currentUserUUID, req.GroupTag, after, int(pageSize))
+	}
 	if err != nil {
 		logger.Error(ctx, "获取好友列表失败",
 			logger.String("user_uuid", currentUserUUID),
 			logger.String("group_tag", req.GroupTag),
 			logger.Int32("page", page),
 			logger.Int32("page_size", pageSize),
+			logger.String("cursor", req.Cursor),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
@@ -584,7 +735,7 @@ func (s *friendServiceImpl) GetFriendList(ctx context.Context, req *pb.GetFriend
 		items = append(items, item)
 	}
 
-	return &pb.GetFriendListResponse{
+	resp := &pb.GetFriendListResponse{
 		Items: items,
 		Pagination: &pb.PaginationInfo{
 			Page:       page,
@@ -593,7 +744,11 @@ func (s *friendServiceImpl) GetFriendList(ctx context.Context, req *pb.GetFriend
 			TotalPages: int32((total + int64(pageSize) - 1) / int64(pageSize)),
 		},
 		Version: version,
-	}, nil
+	}
+	if last := relations[len(relations)-1]; hasMore && last != nil {
+		resp.NextCursor = cursor.AfterRow(last.CreatedAt, last.Id).Encode()
+	}
+	return resp, nil
 }
 
 // SyncFriendList 好友增量同步
@@ -608,13 +763,7 @@ func (s *friendServiceImpl) SyncFriendList(ctx context.Context, req *pb.SyncFrie
 	}
 
 	// 2. 兜底同步参数
-	limit := int(req.Limit)
-	if limit <= 0 {
-		limit = 100
-	}
-	if limit > 500 {
-		limit = 500
-	}
+	limit := repository.ClampSyncFriendListLimit(int(req.Limit))
 	version := req.Version
 	if version < 0 {
 		version = 0
@@ -667,17 +816,25 @@ func (s *friendServiceImpl) SyncFriendList(ctx context.Context, req *pb.SyncFrie
 		if relation.DeletedAt.Valid {
 			changeType = "delete"
 			changedAt = relation.DeletedAt.Time.UnixMilli()
+		} else if relation.Status != 0 {
+			// 已拉黑（status=1/3）不再是好友，按删除下发
+			changeType = "delete"
 		} else if relation.CreatedAt.After(versionTime) {
 			changeType = "add"
 		}
 
+		// 非删除变更携带调用方视角的备注/标签与关系版本：仅改备注/标签时 updated_at 推进，
+		// 以 update 事件下发，客户端无需额外拉取即可更新本地列表。
 		change := &pb.FriendChange{
 			Uuid:       relation.PeerUuid,
-			Remark:     relation.Remark,
-			GroupTag:   relation.GroupTag,
-			Source:     relation.Source,
 			ChangeType: changeType,
 			ChangedAt:  changedAt,
+			Version:    changedAt,
+		}
+		if changeType != "delete" {
+			change.Remark = relation.Remark
+			change.GroupTag = relation.GroupTag
+			change.Source = relation.Source
 		}
 
 		changes = append(changes, change)
@@ -864,7 +1021,31 @@ func (s *friendServiceImpl) BatchCheckIsFriend(ctx context.Context, req *pb.Batc
 	}, nil
 }
 
+// 关系（GetRelationStatus.relation），取值保持不变以兼容已有客户端
+const (
+	RelationNone      = "none"      // 无关系
+	RelationFriend    = "friend"    // 好友
+	RelationBlacklist = "blacklist" // 我已拉黑对方
+	RelationDeleted   = "deleted"   // 已删除好友
+)
+
+// 关系状态（GetRelationStatus.relation_state），综合黑名单与待处理申请，供资料页决定操作按钮
+const (
+	RelationStatusFriend            = "friend"              // 好友
+	RelationStatusPendingOutgoing   = "pending_outgoing"    // 我已向对方发出申请，待对方处理
+	RelationStatusPendingIncoming   = "pending_incoming"    // 对方已向我发出申请，待我处理
+	RelationStatusBlacklistedByMe   = "blacklisted_by_me"   // 我已拉黑对方
+	RelationStatusBlacklistedByPeer = "blacklisted_by_peer" // 对方已拉黑我
+	RelationStatusStranger          = "stranger"            // 陌生人（含已删除好友）
+)
+
 // GetRelationStatus 获取关系状态
+// relation 仅由我方关系记录决定（none/friend/blacklist/deleted，与旧版一致）；
+// relation_state 综合好友关系、黑名单与待处理申请，按以下优先级返回单一状态：
+//
+//	blacklisted_by_me > blacklisted_by_peer > friend > pending_outgoing > pending_incoming > stranger
+//
+// is_friend 仅取决于我方好友关系（status=0），与对方是否拉黑无关；is_blacklist 表示我已拉黑对方。
 func (s *friendServiceImpl) GetRelationStatus(ctx context.Context, req *pb.GetRelationStatusRequest) (*pb.GetRelationStatusResponse, error) {
 	if req == nil || req.UserUuid == "" || req.PeerUuid == "" {
 		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
@@ -880,34 +1061,67 @@ func (s *friendServiceImpl) GetRelationStatus(ctx context.Context, req *pb.GetRe
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	resp := &pb.GetRelationStatusResponse{
-		Relation:    "none",
-		IsFriend:    false,
-		IsBlacklist: false,
-		Remark:      "",
-		GroupTag:    "",
+	resp := &pb.GetRelationStatusResponse{Relation: RelationNone, RelationState: RelationStatusStranger}
+	switch {
+	case relation == nil:
+	case relation.DeletedAt.Valid || relation.Status == 2:
+		resp.Relation = RelationDeleted
+	case relation.Status == 0:
+		resp.Relation = RelationFriend
+		resp.IsFriend = true
+		resp.Remark = relation.Remark
+		resp.GroupTag = relation.GroupTag
+	case relation.Status == 1 || relation.Status == 3:
+		resp.Relation = RelationBlacklist
+		resp.IsBlacklist = true
 	}
 
-	if relation == nil {
+	if resp.IsBlacklist {
+		resp.RelationState = RelationStatusBlacklistedByMe
 		return resp, nil
 	}
 
-	if relation.DeletedAt.Valid || relation.Status == 2 {
-		resp.Relation = "deleted"
+	blockedByPeer, err := s.blacklistRepo.IsBlocked(ctx, req.PeerUuid, req.UserUuid)
+	if err != nil {
+		logger.Error(ctx, "检查对方拉黑状态失败",
+			logger.String("user_uuid", req.UserUuid),
+			logger.String("peer_uuid", req.PeerUuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	if blockedByPeer {
+		resp.RelationState = RelationStatusBlacklistedByPeer
 		return resp, nil
 	}
 
-	switch relation.Status {
-	case 0:
-		resp.Relation = "friend"
-		resp.IsFriend = true
-		resp.Remark = relation.Remark
-		resp.GroupTag = relation.GroupTag
-	case 1, 3:
-		resp.Relation = "blacklist"
-		resp.IsBlacklist = true
-	default:
-		resp.Relation = "none"
+	if resp.IsFriend {
+		resp.RelationState = RelationStatusFriend
+		return resp, nil
+	}
+
+	pendingChecks := []struct {
+		applicantUUID string
+		targetUUID    string
+		relation      string
+	}{
+		{req.UserUuid, req.PeerUuid, RelationStatusPendingOutgoing},
+		{req.PeerUuid, req.UserUuid, RelationStatusPendingIncoming},
+	}
+	for _, check := range pendingChecks {
+		pending, err := s.applyRepo.ExistsPendingRequest(ctx, check.applicantUUID, check.targetUUID)
+		if err != nil {
+			logger.Error(ctx, "检查待处理好友申请失败",
+				logger.String("applicant_uuid", check.applicantUUID),
+				logger.String("target_uuid", check.targetUUID),
+				logger.ErrorField("error", err),
+			)
+			return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		}
+		if pending {
+			resp.RelationState = check.relation
+			return resp, nil
+		}
 	}
 
 	return resp, nil
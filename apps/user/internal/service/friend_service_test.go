@@ -12,6 +12,7 @@ import (
 	pb "ChatServer/apps/user/pb"
 	"ChatServer/consts"
 	"ChatServer/model"
+	"ChatServer/pkg/cursor"
 	"ChatServer/pkg/logger"
 
 	"github.com/stretchr/testify/assert"
@@ -47,6 +48,7 @@ func requireFriendStatusCode(t *testing.T, err error, wantGRPC codes.Code, wantB
 
 type fakeFriendRepoForService struct {
 	getFriendListFn      func(context.Context, string, string, int, int) ([]*model.UserRelation, int64, int64, error)
+	getFriendCursorFn    func(context.Context, string, string, cursor.Cursor, int) ([]*model.UserRelation, bool, error)
 	getFriendRelationFn  func(context.Context, string, string) (*model.UserRelation, error)
 	createRelationFn     func(context.Context, string, string) error
 	deleteRelationFn     func(context.Context, string, string) error
@@ -56,6 +58,7 @@ type fakeFriendRepoForService struct {
 	isFriendFn           func(context.Context, string, string) (bool, error)
 	checkIsFriendFn      func(context.Context, string, string) (bool, error)
 	batchCheckIsFriendFn func(context.Context, string, []string) (map[string]bool, error)
+	batchIsFriendOfFn    func(context.Context, string, []string) (map[string]bool, error)
 	getRelationStatusFn  func(context.Context, string, string) (*model.UserRelation, error)
 	syncFriendListFn     func(context.Context, string, int64, int) ([]*model.UserRelation, int64, bool, error)
 }
@@ -67,6 +70,13 @@ func (f *fakeFriendRepoForService) GetFriendList(ctx context.Context, userUUID,
 	return f.getFriendListFn(ctx, userUUID, groupTag, page, pageSize)
 }
 
+func (f *fakeFriendRepoForService) GetFriendListByCursor(ctx context.Context, userUUID, groupTag string, after cursor.Cursor, limit int) ([]*model.UserRelation, bool, error) {
+	if f.getFriendCursorFn == nil {
+		return nil, false, errors.New("unexpected GetFriendListByCursor call")
+	}
+	return f.getFriendCursorFn(ctx, userUUID, groupTag, after, limit)
+}
+
 func (f *fakeFriendRepoForService) GetFriendRelation(ctx context.Context, userUUID, friendUUID string) (*model.UserRelation, error) {
 	if f.getFriendRelationFn == nil {
 		return nil, nil
@@ -130,6 +140,13 @@ func (f *fakeFriendRepoForService) BatchCheckIsFriend(ctx context.Context, userU
 	return f.batchCheckIsFriendFn(ctx, userUUID, peerUUIDs)
 }
 
+func (f *fakeFriendRepoForService) BatchCheckIsFriendOf(ctx context.Context, peerUUID string, userUUIDs []string) (map[string]bool, error) {
+	if f.batchIsFriendOfFn == nil {
+		return map[string]bool{}, nil
+	}
+	return f.batchIsFriendOfFn(ctx, peerUUID, userUUIDs)
+}
+
 func (f *fakeFriendRepoForService) GetRelationStatus(ctx context.Context, userUUID, peerUUID string) (*model.UserRelation, error) {
 	if f.getRelationStatusFn == nil {
 		return nil, nil
@@ -149,15 +166,19 @@ type fakeApplyRepoForService struct {
 	getByIDFn          func(context.Context, int64) (*model.ApplyRequest, error)
 	getPendingListFn   func(context.Context, string, int, int, int) ([]*model.ApplyRequest, int64, error)
 	getSentListFn      func(context.Context, string, int, int, int) ([]*model.ApplyRequest, int64, error)
+	getPendingCursorFn func(context.Context, string, int, cursor.Cursor, int) ([]*model.ApplyRequest, bool, error)
+	getSentCursorFn    func(context.Context, string, int, cursor.Cursor, int) ([]*model.ApplyRequest, bool, error)
 	updateStatusFn     func(context.Context, int64, int, string) error
 	acceptApplyFn      func(context.Context, int64, string, string, string) (bool, error)
 	markAsReadFn       func(context.Context, string, []int64) (int64, error)
 	markAllAsReadFn    func(context.Context, string) (int64, error)
-	markAsReadAsyncFn  func(context.Context, []int64)
+	markAsReadAsyncFn  func(context.Context, string, []int64)
 	getUnreadCountFn   func(context.Context, string) (int64, error)
 	clearUnreadCountFn func(context.Context, string) error
 	existsPendingReqFn func(context.Context, string, string) (bool, error)
-	getByIDWithInfoFn  func(context.Context, int64) (*model.ApplyRequest, error)
+	getByIDWithInfoFn  func(context.Context, int64) (*model.ApplyRequest, *model.UserInfo, error)
+	expireApplyFn      func(context.Context, *model.ApplyRequest) error
+	expirePendingFn    func(context.Context, int) (int64, error)
 }
 
 func (f *fakeApplyRepoForService) Create(ctx context.Context, apply *model.ApplyRequest) (*model.ApplyRequest, error) {
@@ -188,6 +209,20 @@ func (f *fakeApplyRepoForService) GetSentList(ctx context.Context, applicantUUID
 	return f.getSentListFn(ctx, applicantUUID, status, page, pageSize)
 }
 
+func (f *fakeApplyRepoForService) GetPendingListByCursor(ctx context.Context, targetUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error) {
+	if f.getPendingCursorFn == nil {
+		return nil, false, errors.New("unexpected GetPendingListByCursor call")
+	}
+	return f.getPendingCursorFn(ctx, targetUUID, status, after, limit)
+}
+
+func (f *fakeApplyRepoForService) GetSentListByCursor(ctx context.Context, applicantUUID string, status int, after cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error) {
+	if f.getSentCursorFn == nil {
+		return nil, false, errors.New("unexpected GetSentListByCursor call")
+	}
+	return f.getSentCursorFn(ctx, applicantUUID, status, after, limit)
+}
+
 func (f *fakeApplyRepoForService) UpdateStatus(ctx context.Context, id int64, status int, remark string) error {
 	if f.updateStatusFn == nil {
 		return nil
@@ -216,9 +251,9 @@ func (f *fakeApplyRepoForService) MarkAllAsRead(ctx context.Context, targetUUID
 	return f.markAllAsReadFn(ctx, targetUUID)
 }
 
-func (f *fakeApplyRepoForService) MarkAsReadAsync(ctx context.Context, ids []int64) {
+func (f *fakeApplyRepoForService) MarkAsReadAsync(ctx context.Context, targetUUID string, ids []int64) {
 	if f.markAsReadAsyncFn != nil {
-		f.markAsReadAsyncFn(ctx, ids)
+		f.markAsReadAsyncFn(ctx, targetUUID, ids)
 	}
 }
 
@@ -243,9 +278,23 @@ func (f *fakeApplyRepoForService) ExistsPendingRequest(ctx context.Context, appl
 	return f.existsPendingReqFn(ctx, applicantUUID, targetUUID)
 }
 
-func (f *fakeApplyRepoForService) GetByIDWithInfo(ctx context.Context, id int64) (*model.ApplyRequest, error) {
+func (f *fakeApplyRepoForService) ExpireApply(ctx context.Context, apply *model.ApplyRequest) error {
+	if f.expireApplyFn == nil {
+		return nil
+	}
+	return f.expireApplyFn(ctx, apply)
+}
+
+func (f *fakeApplyRepoForService) ExpirePendingApplies(ctx context.Context, limit int) (int64, error) {
+	if f.expirePendingFn == nil {
+		return 0, nil
+	}
+	return f.expirePendingFn(ctx, limit)
+}
+
+func (f *fakeApplyRepoForService) GetByIDWithInfo(ctx context.Context, id int64) (*model.ApplyRequest, *model.UserInfo, error) {
 	if f.getByIDWithInfoFn == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	return f.getByIDWithInfoFn(ctx, id)
 }
@@ -400,6 +449,28 @@ func TestUserFriendServiceSendFriendApply(t *testing.T) {
 		requireFriendStatusCode(t, err, codes.FailedPrecondition, consts.CodePeerBlacklistYou)
 	})
 
+	t.Run("target_blocked_by_applicant", func(t *testing.T) {
+		svc := NewFriendService(
+			&fakeFriendRepoForService{
+				isFriendFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil },
+			},
+			&fakeApplyRepoForService{
+				existsPendingReqFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil },
+			},
+			&fakeBlacklistRepoForService{
+				isBlockedFn: func(_ context.Context, userUUID, targetUUID string) (bool, error) {
+					if userUUID == "u1" && targetUUID == "u2" {
+						return true, nil
+					}
+					return false, nil
+				},
+			},
+		)
+		resp, err := svc.SendFriendApply(withFriendUserUUID("u1"), &pb.SendFriendApplyRequest{TargetUuid: "u2"})
+		require.Nil(t, resp)
+		requireFriendStatusCode(t, err, codes.FailedPrecondition, consts.CodeYouBlacklistPeer)
+	})
+
 	t.Run("blacklist_check_error", func(t *testing.T) {
 		svc := NewFriendService(
 			&fakeFriendRepoForService{isFriendFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil }},
@@ -414,6 +485,134 @@ func TestUserFriendServiceSendFriendApply(t *testing.T) {
 		require.Nil(t, resp)
 		requireFriendStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 	})
+
+	t.Run("duplicate_key_maps_to_request_sent", func(t *testing.T) {
+		svc := NewFriendService(
+			&fakeFriendRepoForService{isFriendFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil }},
+			&fakeApplyRepoForService{
+				existsPendingReqFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil },
+				createFn: func(_ context.Context, _ *model.ApplyRequest) (*model.ApplyRequest, error) {
+					return nil, repository.ErrDuplicateKey
+				},
+			},
+			&fakeBlacklistRepoForService{},
+		)
+		resp, err := svc.SendFriendApply(withFriendUserUUID("u1"), &pb.SendFriendApplyRequest{TargetUuid: "u2"})
+		require.Nil(t, resp)
+		requireFriendStatusCode(t, err, codes.AlreadyExists, consts.CodeFriendRequestSent)
+	})
+}
+
+// pendingApplyStore 模拟 apply_request 的 pending_key 唯一索引
+type pendingApplyStore struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   map[string]int64 // applicant:target -> apply id
+}
+
+func newPendingApplyStore() *pendingApplyStore {
+	return &pendingApplyStore{rows: make(map[string]int64)}
+}
+
+func (s *pendingApplyStore) exists(applicantUUID, targetUUID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.rows[applicantUUID+":"+targetUUID]
+	return ok
+}
+
+func (s *pendingApplyStore) create(apply *model.ApplyRequest) (*model.ApplyRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := apply.ApplicantUuid + ":" + apply.TargetUuid
+	if _, ok := s.rows[key]; ok {
+		return nil, repository.ErrDuplicateKey
+	}
+	s.nextID++
+	s.rows[key] = s.nextID
+	return &model.ApplyRequest{Id: s.nextID}, nil
+}
+
+func TestUserFriendServiceSendFriendApplyConcurrent(t *testing.T) {
+	initUserFriendTestLogger()
+
+	newSvc := func(store *pendingApplyStore, existsHook func()) FriendService {
+		return NewFriendService(
+			&fakeFriendRepoForService{isFriendFn: func(_ context.Context, _, _ string) (bool, error) { return false, nil }},
+			&fakeApplyRepoForService{
+				existsPendingReqFn: func(_ context.Context, applicantUUID, targetUUID string) (bool, error) {
+					exists := store.exists(applicantUUID, targetUUID)
+					if existsHook != nil {
+						existsHook()
+					}
+					return exists, nil
+				},
+				createFn: func(_ context.Context, apply *model.ApplyRequest) (*model.ApplyRequest, error) {
+					return store.create(apply)
+				},
+			},
+			&fakeBlacklistRepoForService{},
+		)
+	}
+
+	// requireOnePending 并发申请只产生一条待处理记录，其余请求成功（共享结果）或返回“申请已发送”
+	requireOnePending := func(t *testing.T, store *pendingApplyStore, results []error) {
+		assert.Len(t, store.rows, 1)
+		for _, err := range results {
+			if err != nil {
+				requireFriendStatusCode(t, err, codes.AlreadyExists, consts.CodeFriendRequestSent)
+			}
+		}
+	}
+
+	t.Run("same_instance", func(t *testing.T) {
+		store := newPendingApplyStore()
+		svc := newSvc(store, nil)
+
+		const n = 8
+		results := make([]error, n)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				_, results[i] = svc.SendFriendApply(withFriendUserUUID("u1"), &pb.SendFriendApplyRequest{TargetUuid: "u2"})
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		requireOnePending(t, store, results)
+	})
+
+	t.Run("across_instances_falls_back_to_unique_key", func(t *testing.T) {
+		// 两个实例各自通过“无待处理申请”检查后再创建，模拟 check-then-act 竞争
+		store := newPendingApplyStore()
+		var checked sync.WaitGroup
+		checked.Add(2)
+		barrier := func() {
+			checked.Done()
+			checked.Wait()
+		}
+		svcA := newSvc(store, barrier)
+		svcB := newSvc(store, barrier)
+
+		results := make([]error, 2)
+		var wg sync.WaitGroup
+		for i, svc := range []FriendService{svcA, svcB} {
+			wg.Add(1)
+			go func(i int, svc FriendService) {
+				defer wg.Done()
+				_, results[i] = svc.SendFriendApply(withFriendUserUUID("u1"), &pb.SendFriendApplyRequest{TargetUuid: "u2"})
+			}(i, svc)
+		}
+		wg.Wait()
+
+		requireOnePending(t, store, results)
+		assert.True(t, (results[0] == nil) != (results[1] == nil), "恰好一个请求创建成功")
+	})
 }
 
 func TestUserFriendServiceGetFriendApplyList(t *testing.T) {
@@ -453,7 +652,8 @@ func TestUserFriendServiceGetFriendApplyList(t *testing.T) {
 					{Id: 2, ApplicantUuid: "u3", Status: 1, IsRead: true, Reason: "ok", Source: "qrcode", CreatedAt: createdAt},
 				}, 22, nil
 			},
-			markAsReadAsyncFn: func(_ context.Context, ids []int64) {
+			markAsReadAsyncFn: func(_ context.Context, targetUUID string, ids []int64) {
+				assert.Equal(t, "u1", targetUUID)
 				asyncIDs = append(asyncIDs, ids...)
 			},
 			clearUnreadCountFn: func(_ context.Context, userUUID string) error {
@@ -496,6 +696,95 @@ func TestUserFriendServiceGetFriendApplyList(t *testing.T) {
 	})
 }
 
+func TestUserFriendServiceListCursorPagination(t *testing.T) {
+	initUserFriendTestLogger()
+	createdAt := time.Unix(1700000000, 123000000)
+
+	t.Run("offset_page_returns_next_cursor", func(t *testing.T) {
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			getPendingListFn: func(_ context.Context, _ string, _, _, _ int) ([]*model.ApplyRequest, int64, error) {
+				return []*model.ApplyRequest{
+					{Id: 9, ApplicantUuid: "u2", CreatedAt: createdAt.Add(time.Second)},
+					{Id: 7, ApplicantUuid: "u3", CreatedAt: createdAt},
+				}, 5, nil
+			},
+		}, &fakeBlacklistRepoForService{})
+
+		resp, err := svc.GetFriendApplyList(withFriendUserUUID("u1"), &pb.GetFriendApplyListRequest{Page: 1, PageSize: 2})
+		require.NoError(t, err)
+		got, err := cursor.Decode(resp.NextCursor)
+		require.NoError(t, err)
+		assert.Equal(t, cursor.AfterRow(createdAt, 7), got)
+	})
+
+	t.Run("offset_last_page_has_no_cursor", func(t *testing.T) {
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			getSentListFn: func(_ context.Context, _ string, _, _, _ int) ([]*model.ApplyRequest, int64, error) {
+				return []*model.ApplyRequest{{Id: 1, TargetUuid: "u2", CreatedAt: createdAt}}, 3, nil
+			},
+		}, &fakeBlacklistRepoForService{})
+
+		resp, err := svc.GetSentApplyList(withFriendUserUUID("u1"), &pb.GetSentApplyListRequest{Page: 2, PageSize: 2})
+		require.NoError(t, err)
+		assert.Empty(t, resp.NextCursor)
+	})
+
+	t.Run("apply_lists_follow_cursor", func(t *testing.T) {
+		after := cursor.AfterRow(createdAt, 7)
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			getPendingCursorFn: func(_ context.Context, userUUID string, status int, got cursor.Cursor, limit int) ([]*model.ApplyRequest, bool, error) {
+				assert.Equal(t, "u1", userUUID)
+				assert.Equal(t, -1, status)
+				assert.Equal(t, after, got)
+				assert.Equal(t, 2, limit)
+				return []*model.ApplyRequest{{Id: 6, ApplicantUuid: "u4", CreatedAt: createdAt}, {Id: 5, ApplicantUuid: "u5", CreatedAt: createdAt}}, true, nil
+			},
+			getSentCursorFn: func(_ context.Context, _ string, _ int, got cursor.Cursor, _ int) ([]*model.ApplyRequest, bool, error) {
+				assert.Equal(t, after, got)
+				return []*model.ApplyRequest{{Id: 4, TargetUuid: "u6", CreatedAt: createdAt}}, false, nil
+			},
+		}, &fakeBlacklistRepoForService{})
+
+		pendingResp, err := svc.GetFriendApplyList(withFriendUserUUID("u1"), &pb.GetFriendApplyListRequest{Status: -1, Page: 1, PageSize: 2, Cursor: after.Encode()})
+		require.NoError(t, err)
+		require.Len(t, pendingResp.Items, 2)
+		assert.Equal(t, cursor.AfterRow(createdAt, 5).Encode(), pendingResp.NextCursor)
+
+		sentResp, err := svc.GetSentApplyList(withFriendUserUUID("u1"), &pb.GetSentApplyListRequest{Status: -1, Page: 1, PageSize: 2, Cursor: after.Encode()})
+		require.NoError(t, err)
+		require.Len(t, sentResp.Items, 1)
+		assert.Empty(t, sentResp.NextCursor)
+	})
+
+	t.Run("friend_list_follows_cursor", func(t *testing.T) {
+		after := cursor.AfterRow(createdAt, 30)
+		svc := NewFriendService(&fakeFriendRepoForService{
+			getFriendCursorFn: func(_ context.Context, userUUID, groupTag string, got cursor.Cursor, limit int) ([]*model.UserRelation, bool, error) {
+				assert.Equal(t, "u1", userUUID)
+				assert.Equal(t, "work", groupTag)
+				assert.Equal(t, after, got)
+				assert.Equal(t, 20, limit)
+				return []*model.UserRelation{{Id: 29, PeerUuid: "u2", CreatedAt: createdAt}}, true, nil
+			},
+		}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{})
+
+		resp, err := svc.GetFriendList(withFriendUserUUID("u1"), &pb.GetFriendListRequest{GroupTag: "work", Cursor: after.Encode()})
+		require.NoError(t, err)
+		require.Len(t, resp.Items, 1)
+		assert.Equal(t, cursor.AfterRow(createdAt, 29).Encode(), resp.NextCursor)
+		assert.Zero(t, resp.Pagination.Total, "游标分页不计算总数")
+	})
+
+	t.Run("invalid_cursor", func(t *testing.T) {
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{})
+		for _, raw := range []string{"not-a-cursor", cursor.AfterSeq(10).Encode()} {
+			resp, err := svc.GetFriendList(withFriendUserUUID("u1"), &pb.GetFriendListRequest{Cursor: raw})
+			require.Nil(t, resp)
+			requireFriendStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+		}
+	})
+}
+
 func TestUserFriendServiceHandleFriendApply(t *testing.T) {
 	initUserFriendTestLogger()
 
@@ -515,6 +804,16 @@ func TestUserFriendServiceHandleFriendApply(t *testing.T) {
 		requireFriendStatusCode(t, err, codes.NotFound, consts.CodeApplyNotFoundOrHandle)
 	})
 
+	t.Run("apply_lookup_db_error", func(t *testing.T) {
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			getByIDFn: func(_ context.Context, _ int64) (*model.ApplyRequest, error) {
+				return nil, repository.ErrDatabase
+			},
+		}, &fakeBlacklistRepoForService{})
+		err := svc.HandleFriendApply(withFriendUserUUID("u1"), &pb.HandleFriendApplyRequest{ApplyId: 1, Action: 1})
+		requireFriendStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+
 	t.Run("apply_nil_without_error", func(t *testing.T) {
 		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
 			getByIDFn: func(_ context.Context, _ int64) (*model.ApplyRequest, error) {
@@ -589,6 +888,99 @@ func TestUserFriendServiceHandleFriendApply(t *testing.T) {
 		err = svc.HandleFriendApply(withFriendUserUUID("u1"), &pb.HandleFriendApplyRequest{ApplyId: 1, Action: 2})
 		requireFriendStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 	})
+
+	t.Run("expired_apply", func(t *testing.T) {
+		expiredAt := time.Now().Add(-time.Minute)
+		for _, action := range []int32{1, 2} {
+			var expired *model.ApplyRequest
+			svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+				getByIDFn: func(_ context.Context, _ int64) (*model.ApplyRequest, error) {
+					return &model.ApplyRequest{Id: 1, TargetUuid: "u1", ApplicantUuid: "u2", ExpiredAt: &expiredAt}, nil
+				},
+				expireApplyFn: func(_ context.Context, apply *model.ApplyRequest) error {
+					expired = apply
+					return errors.New("db down") // 惰性过期失败不影响判定
+				},
+				acceptApplyFn: func(_ context.Context, _ int64, _, _, _ string) (bool, error) {
+					t.Fatal("expired apply must not be accepted")
+					return false, nil
+				},
+				updateStatusFn: func(_ context.Context, _ int64, _ int, _ string) error {
+					t.Fatal("expired apply must not be rejected")
+					return nil
+				},
+			}, &fakeBlacklistRepoForService{})
+			err := svc.HandleFriendApply(withFriendUserUUID("u1"), &pb.HandleFriendApplyRequest{ApplyId: 1, Action: action})
+			requireFriendStatusCode(t, err, codes.FailedPrecondition, consts.CodeApplyExpired)
+			require.NotNil(t, expired)
+			assert.Equal(t, int64(1), expired.Id)
+		}
+
+		// 已被扫描置为过期（status=3）
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			getByIDFn: func(_ context.Context, _ int64) (*model.ApplyRequest, error) {
+				return &model.ApplyRequest{Id: 1, TargetUuid: "u1", ApplicantUuid: "u2", Status: 3}, nil
+			},
+		}, &fakeBlacklistRepoForService{})
+		err := svc.HandleFriendApply(withFriendUserUUID("u1"), &pb.HandleFriendApplyRequest{ApplyId: 1, Action: 1})
+		requireFriendStatusCode(t, err, codes.FailedPrecondition, consts.CodeApplyExpired)
+	})
+
+	t.Run("not_yet_expired_apply_handled", func(t *testing.T) {
+		expiredAt := time.Now().Add(time.Hour)
+		var accepted bool
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			getByIDFn: func(_ context.Context, _ int64) (*model.ApplyRequest, error) {
+				return &model.ApplyRequest{Id: 1, TargetUuid: "u1", ApplicantUuid: "u2", ExpiredAt: &expiredAt}, nil
+			},
+			acceptApplyFn: func(_ context.Context, _ int64, _, _, _ string) (bool, error) {
+				accepted = true
+				return false, nil
+			},
+		}, &fakeBlacklistRepoForService{})
+		err := svc.HandleFriendApply(withFriendUserUUID("u1"), &pb.HandleFriendApplyRequest{ApplyId: 1, Action: 1})
+		require.NoError(t, err)
+		assert.True(t, accepted)
+	})
+}
+
+func TestSweepExpiredApplies(t *testing.T) {
+	initUserFriendTestLogger()
+
+	t.Run("loops_until_short_batch", func(t *testing.T) {
+		batches := []int64{2, 2, 1}
+		var calls int
+		repo := &fakeApplyRepoForService{
+			expirePendingFn: func(_ context.Context, limit int) (int64, error) {
+				assert.Equal(t, 2, limit)
+				n := batches[calls]
+				calls++
+				return n, nil
+			},
+		}
+		assert.Equal(t, int64(5), sweepExpiredApplies(context.Background(), repo, 2))
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("stops_on_error_and_batch_cap", func(t *testing.T) {
+		var calls int
+		repo := &fakeApplyRepoForService{
+			expirePendingFn: func(_ context.Context, _ int) (int64, error) {
+				calls++
+				return 0, errors.New("db down")
+			},
+		}
+		assert.Zero(t, sweepExpiredApplies(context.Background(), repo, 10))
+		assert.Equal(t, 1, calls)
+
+		calls = 0
+		repo.expirePendingFn = func(_ context.Context, limit int) (int64, error) {
+			calls++
+			return int64(limit), nil
+		}
+		assert.Equal(t, int64(applyExpireMaxBatches*10), sweepExpiredApplies(context.Background(), repo, 10))
+		assert.Equal(t, applyExpireMaxBatches, calls)
+	})
 }
 
 func TestUserFriendServiceUnreadMarkAndListSync(t *testing.T) {
@@ -609,6 +1001,7 @@ func TestUserFriendServiceUnreadMarkAndListSync(t *testing.T) {
 	t.Run("mark_apply_as_read_paths", func(t *testing.T) {
 		var markAllCalled bool
 		var markSomeCalled bool
+		var clearCalls int
 		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
 			markAllAsReadFn: func(_ context.Context, userUUID string) (int64, error) {
 				markAllCalled = true
@@ -622,14 +1015,89 @@ func TestUserFriendServiceUnreadMarkAndListSync(t *testing.T) {
 				return int64(len(ids)), nil
 			},
 			clearUnreadCountFn: func(_ context.Context, _ string) error {
+				clearCalls++
 				return errors.New("ignore")
 			},
+			getUnreadCountFn: func(_ context.Context, userUUID string) (int64, error) {
+				assert.Equal(t, "u1", userUUID)
+				return 3, nil // 仓储已按实际已读行数扣减
+			},
 		}, &fakeBlacklistRepoForService{})
 
-		require.NoError(t, svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{}))
-		require.NoError(t, svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{ApplyIds: []int64{1, 2}}))
-		assert.True(t, markAllCalled)
+		// 非 markAll 且未传 ID：参数错误，不触达仓储。
+		resp, err := svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{})
+		require.Nil(t, resp)
+		requireFriendStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+		assert.False(t, markAllCalled)
+		assert.Zero(t, clearCalls)
+
+		// 按 ID 标记：不再整体清除红点，返回扣减后的剩余未读数。
+		resp, err = svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{ApplyIds: []int64{1, 2}})
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), resp.UnreadCount)
 		assert.True(t, markSomeCalled)
+		assert.False(t, markAllCalled)
+		assert.Zero(t, clearCalls)
+	})
+
+	t.Run("mark_apply_as_read_unread_count_degrades", func(t *testing.T) {
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			markAsReadFn: func(_ context.Context, _ string, ids []int64) (int64, error) {
+				return int64(len(ids)), nil
+			},
+			getUnreadCountFn: func(_ context.Context, _ string) (int64, error) {
+				return 0, errors.New("redis down")
+			},
+		}, &fakeBlacklistRepoForService{})
+
+		resp, err := svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{ApplyIds: []int64{1}})
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), resp.UnreadCount)
+	})
+
+	t.Run("mark_all_applies_read", func(t *testing.T) {
+		var markAllCalled, markSomeCalled bool
+		var clearedUser string
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			markAllAsReadFn: func(_ context.Context, userUUID string) (int64, error) {
+				markAllCalled = true
+				assert.Equal(t, "u1", userUUID)
+				return 10, nil
+			},
+			markAsReadFn: func(_ context.Context, _ string, _ []int64) (int64, error) {
+				markSomeCalled = true
+				return 0, nil
+			},
+			clearUnreadCountFn: func(_ context.Context, userUUID string) error {
+				clearedUser = userUUID
+				return nil
+			},
+		}, &fakeBlacklistRepoForService{})
+
+		// markAll 时忽略 apply_ids，单次 UPDATE 标记全部并清零未读计数。
+		resp, err := svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{MarkAll: true, ApplyIds: []int64{3}})
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), resp.UnreadCount)
+		assert.True(t, markAllCalled)
+		assert.False(t, markSomeCalled)
+		assert.Equal(t, "u1", clearedUser)
+	})
+
+	t.Run("mark_all_applies_read_db_error", func(t *testing.T) {
+		var clearCalled bool
+		svc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			markAllAsReadFn: func(_ context.Context, _ string) (int64, error) {
+				return 0, errors.New("db down")
+			},
+			clearUnreadCountFn: func(_ context.Context, _ string) error {
+				clearCalled = true
+				return nil
+			},
+		}, &fakeBlacklistRepoForService{})
+
+		_, err := svc.MarkApplyAsRead(withFriendUserUUID("u1"), &pb.MarkApplyAsReadRequest{MarkAll: true})
+		requireFriendStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+		assert.False(t, clearCalled)
 	})
 
 	t.Run("get_friend_list_and_sync_friend_list", func(t *testing.T) {
@@ -673,6 +1141,70 @@ func TestUserFriendServiceUnreadMarkAndListSync(t *testing.T) {
 	})
 }
 
+func TestUserFriendServiceSyncFriendListRemarkChange(t *testing.T) {
+	initUserFriendTestLogger()
+
+	friendSince := time.Unix(1700000000, 0)
+	remarkedAt := friendSince.Add(time.Hour)
+	deletedAt := gorm.DeletedAt{Time: remarkedAt.Add(time.Minute), Valid: true}
+	version := friendSince.Add(time.Minute).UnixMilli()
+
+	svc := NewFriendService(&fakeFriendRepoForService{
+		syncFriendListFn: func(_ context.Context, _ string, gotVersion int64, _ int) ([]*model.UserRelation, int64, bool, error) {
+			assert.Equal(t, version, gotVersion)
+			return []*model.UserRelation{
+				// 客户端上次同步后仅修改了备注：应作为 update 下发新备注。
+				{PeerUuid: "u2", Remark: "新备注", GroupTag: "同事", Source: "search", CreatedAt: friendSince, UpdatedAt: remarkedAt},
+				{PeerUuid: "u3", Remark: "旧备注", GroupTag: "同学", CreatedAt: friendSince, UpdatedAt: deletedAt.Time, DeletedAt: deletedAt},
+			}, remarkedAt.Add(time.Hour).UnixMilli(), false, nil
+		},
+	}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{})
+
+	resp, err := svc.SyncFriendList(withFriendUserUUID("u1"), &pb.SyncFriendListRequest{Version: version})
+	require.NoError(t, err)
+	require.Len(t, resp.Changes, 2)
+
+	update := resp.Changes[0]
+	assert.Equal(t, "update", update.ChangeType)
+	assert.Equal(t, "新备注", update.Remark)
+	assert.Equal(t, "同事", update.GroupTag)
+	assert.Equal(t, remarkedAt.UnixMilli(), update.Version)
+
+	deleted := resp.Changes[1]
+	assert.Equal(t, "delete", deleted.ChangeType)
+	assert.Empty(t, deleted.Remark)
+	assert.Empty(t, deleted.GroupTag)
+	assert.Equal(t, deletedAt.Time.UnixMilli(), deleted.Version)
+}
+
+func TestUserFriendServiceSyncFriendListBlacklisted(t *testing.T) {
+	initUserFriendTestLogger()
+
+	friendSince := time.Unix(1700000000, 0)
+	blockedAt := friendSince.Add(time.Hour)
+	version := friendSince.Add(time.Minute).UnixMilli()
+
+	svc := NewFriendService(&fakeFriendRepoForService{
+		syncFriendListFn: func(_ context.Context, _ string, _ int64, _ int) ([]*model.UserRelation, int64, bool, error) {
+			return []*model.UserRelation{
+				// 拉黑好友：好友关系已解除，应作为 delete 下发。
+				{PeerUuid: "u2", Status: 3, Remark: "备注", CreatedAt: friendSince, UpdatedAt: blockedAt, BlacklistedAt: &blockedAt},
+				// 拉黑陌生人：新建的拉黑关系不能作为 add 下发。
+				{PeerUuid: "u3", Status: 3, CreatedAt: blockedAt, UpdatedAt: blockedAt, BlacklistedAt: &blockedAt},
+			}, blockedAt.UnixMilli(), false, nil
+		},
+	}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{})
+
+	resp, err := svc.SyncFriendList(withFriendUserUUID("u1"), &pb.SyncFriendListRequest{Version: version})
+	require.NoError(t, err)
+	require.Len(t, resp.Changes, 2)
+	for _, change := range resp.Changes {
+		assert.Equal(t, "delete", change.ChangeType, change.Uuid)
+		assert.Empty(t, change.Remark)
+		assert.Equal(t, blockedAt.UnixMilli(), change.Version)
+	}
+}
+
 func TestUserFriendServiceMutationsAndRelations(t *testing.T) {
 	initUserFriendTestLogger()
 
@@ -720,45 +1252,77 @@ func TestUserFriendServiceMutationsAndRelations(t *testing.T) {
 
 	t.Run("relation_status_branches", func(t *testing.T) {
 		now := time.Unix(1700000000, 0)
+		relations := map[string]*model.UserRelation{
+			"friend":         {Status: 0, Remark: "r", GroupTag: "g"},
+			"black_friend":   {Status: 1},
+			"black_stranger": {Status: 3},
+			"deleted":        {Status: 2, DeletedAt: gorm.DeletedAt{Valid: true, Time: now}},
+			"friend_blocker": {Status: 0},
+		}
 		svc := NewFriendService(&fakeFriendRepoForService{
-			getRelationStatusFn: func(_ context.Context, userUUID, peerUUID string) (*model.UserRelation, error) {
-				switch peerUUID {
-				case "nil":
-					return nil, nil
-				case "friend":
-					return &model.UserRelation{Status: 0, Remark: "r", GroupTag: "g"}, nil
-				case "black":
-					return &model.UserRelation{Status: 1}, nil
-				case "deleted":
-					return &model.UserRelation{Status: 2, DeletedAt: gorm.DeletedAt{Valid: true, Time: now}}, nil
-				default:
-					return nil, errors.New("db failed")
-				}
+			getRelationStatusFn: func(_ context.Context, _, peerUUID string) (*model.UserRelation, error) {
+				return relations[peerUUID], nil
 			},
-		}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{})
+		}, &fakeApplyRepoForService{
+			existsPendingReqFn: func(_ context.Context, applicantUUID, targetUUID string) (bool, error) {
+				return (applicantUUID == "u1" && targetUUID == "outgoing") ||
+					(applicantUUID == "incoming" && targetUUID == "u1"), nil
+			},
+		}, &fakeBlacklistRepoForService{
+			isBlockedFn: func(_ context.Context, userUUID, targetUUID string) (bool, error) {
+				return targetUUID == "u1" && (userUUID == "blocker" || userUUID == "friend_blocker"), nil
+			},
+		})
 
-		nilResp, nilErr := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: "nil"})
-		require.NoError(t, nilErr)
-		assert.Equal(t, "none", nilResp.Relation)
+		cases := []struct {
+			peer        string
+			relation    string
+			state       string
+			isFriend    bool
+			isBlacklist bool
+		}{
+			{"nobody", "none", RelationStatusStranger, false, false},
+			{"deleted", "deleted", RelationStatusStranger, false, false},
+			{"friend", "friend", RelationStatusFriend, true, false},
+			{"outgoing", "none", RelationStatusPendingOutgoing, false, false},
+			{"incoming", "none", RelationStatusPendingIncoming, false, false},
+			{"black_friend", "blacklist", RelationStatusBlacklistedByMe, false, true},
+			{"black_stranger", "blacklist", RelationStatusBlacklistedByMe, false, true},
+			{"blocker", "none", RelationStatusBlacklistedByPeer, false, false},
+			{"friend_blocker", "friend", RelationStatusBlacklistedByPeer, true, false},
+		}
+		for _, tc := range cases {
+			resp, err := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: tc.peer})
+			require.NoError(t, err, tc.peer)
+			assert.Equal(t, tc.relation, resp.Relation, tc.peer)
+			assert.Equal(t, tc.state, resp.RelationState, tc.peer)
+			assert.Equal(t, tc.isFriend, resp.IsFriend, tc.peer)
+			assert.Equal(t, tc.isBlacklist, resp.IsBlacklist, tc.peer)
+		}
 
 		friendResp, friendErr := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: "friend"})
 		require.NoError(t, friendErr)
-		assert.Equal(t, "friend", friendResp.Relation)
-		assert.True(t, friendResp.IsFriend)
 		assert.Equal(t, "r", friendResp.Remark)
+		assert.Equal(t, "g", friendResp.GroupTag)
+	})
 
-		blackResp, blackErr := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: "black"})
-		require.NoError(t, blackErr)
-		assert.Equal(t, "blacklist", blackResp.Relation)
-		assert.True(t, blackResp.IsBlacklist)
-
-		deletedResp, deletedErr := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: "deleted"})
-		require.NoError(t, deletedErr)
-		assert.Equal(t, "deleted", deletedResp.Relation)
+	t.Run("relation_status_dependency_errors", func(t *testing.T) {
+		dbErr := errors.New("db failed")
+		repoErrSvc := NewFriendService(&fakeFriendRepoForService{
+			getRelationStatusFn: func(context.Context, string, string) (*model.UserRelation, error) { return nil, dbErr },
+		}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{})
+		blacklistErrSvc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{}, &fakeBlacklistRepoForService{
+			isBlockedFn: func(context.Context, string, string) (bool, error) { return false, dbErr },
+		})
+		applyErrSvc := NewFriendService(&fakeFriendRepoForService{}, &fakeApplyRepoForService{
+			existsPendingReqFn: func(context.Context, string, string) (bool, error) { return false, dbErr },
+		}, &fakeBlacklistRepoForService{})
 
-		errResp, err := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: "err"})
-		require.Nil(t, errResp)
-		requireFriendStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+		for _, svc := range []FriendService{repoErrSvc, blacklistErrSvc, applyErrSvc} {
+			resp, err := svc.GetRelationStatus(context.Background(), &pb.GetRelationStatusRequest{UserUuid: "u1", PeerUuid: "u2"})
+			require.Nil(t, resp)
+			requireFriendStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+		}
 	})
 
 	t.Run("relation_status_invalid_params", func(t *testing.T) {
@@ -56,7 +56,7 @@ type IUserService interface {
 	UploadAvatar(ctx context.Context, req *pb.UploadAvatarRequest) (*pb.UploadAvatarResponse, error)
 
 	// ChangePassword 修改密码
-	ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) error
+	ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error)
 
 	// ChangeEmail 绑定/换绑邮箱
 	ChangeEmail(ctx context.Context, req *pb.ChangeEmailRequest) (*pb.ChangeEmailResponse, error)
@@ -75,6 +75,12 @@ type IUserService interface {
 
 	// BatchGetProfile 批量获取用户信息
 	BatchGetProfile(ctx context.Context, req *pb.BatchGetProfileRequest) (*pb.BatchGetProfileResponse, error)
+
+	// SetPresenceVisibility 设置是否向好友隐藏在线状态
+	SetPresenceVisibility(ctx context.Context, req *pb.SetPresenceVisibilityRequest) (*pb.SetPresenceVisibilityResponse, error)
+
+	// UpdatePrivacy 更新隐私设置（在线状态与最近活跃时间的可见范围）
+	UpdatePrivacy(ctx context.Context, req *pb.UpdatePrivacyRequest) (*pb.UpdatePrivacyResponse, error)
 }
 
 // ==================== 好友服务接口 ====================
@@ -98,7 +104,7 @@ type IFriendService interface {
 	GetUnreadApplyCount(ctx context.Context, req *pb.GetUnreadApplyCountRequest) (*pb.GetUnreadApplyCountResponse, error)
 
 	// MarkApplyAsRead 标记申请已读
-	MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) error
+	MarkApplyAsRead(ctx context.Context, req *pb.MarkApplyAsReadRequest) (*pb.MarkApplyAsReadResponse, error)
 
 	// GetFriendList 获取好友列表
 	GetFriendList(ctx context.Context, req *pb.GetFriendListRequest) (*pb.GetFriendListResponse, error)
@@ -156,12 +162,16 @@ type IDeviceService interface {
 
 	// KickDevice 踢出设备
 	KickDevice(ctx context.Context, req *pb.KickDeviceRequest) error
+	// BatchKickDevices 批量踢出设备（逐目标独立执行，返回逐目标结果与汇总）
+	BatchKickDevices(ctx context.Context, req *pb.BatchKickDevicesRequest) (*pb.BatchKickDevicesResponse, error)
 
 	// GetOnlineStatus 获取用户在线状态
 	GetOnlineStatus(ctx context.Context, req *pb.GetOnlineStatusRequest) (*pb.GetOnlineStatusResponse, error)
 
 	// BatchGetOnlineStatus 批量获取在线状态
 	BatchGetOnlineStatus(ctx context.Context, req *pb.BatchGetOnlineStatusRequest) (*pb.BatchGetOnlineStatusResponse, error)
+	// BatchGetOnlineStatusChunked 批量获取在线状态（内部调用，不限人数，按 100 分片查询后合并；不做隐私过滤）
+	BatchGetOnlineStatusChunked(ctx context.Context, userUUIDs []string) ([]*pb.OnlineStatusItem, error)
 
 	// UpdateDeviceActive 批量更新设备活跃时间（内部调用）
 	// 由 gateway/connect 在本地节流命中后调用，仅更新 Redis 活跃时间。
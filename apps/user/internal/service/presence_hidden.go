@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"ChatServer/apps/user/internal/repository"
+	"ChatServer/pkg/logger"
+)
+
+// StartPresenceHiddenRebuilder 启动隐藏在线状态集合校准：启动时立即校准一次，之后每 interval 校准一次，
+// 以 MySQL hide_presence 为准恢复 Redis 中丢失或未同步的 user:presence:hidden 成员。
+// interval<=0 时仅在启动时校准一次。ctx 取消后停止。
+func StartPresenceHiddenRebuilder(ctx context.Context, userRepo repository.IUserRepository, interval time.Duration) {
+	if userRepo == nil {
+		return
+	}
+	go func() {
+		rebuildPresenceHidden(ctx, userRepo)
+		if interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rebuildPresenceHidden(ctx, userRepo)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// rebuildPresenceHidden 执行一次校准，失败仅 log（下一周期重试）
+func rebuildPresenceHidden(ctx context.Context, userRepo repository.IUserRepository) {
+	added, removed, err := userRepo.RebuildPresenceHiddenSet(ctx)
+	if err != nil {
+		logger.Error(ctx, "隐藏在线状态集合校准失败", logger.ErrorField("error", err))
+		return
+	}
+	if added > 0 || removed > 0 {
+		logger.Info(ctx, "隐藏在线状态集合校准完成",
+			logger.Int("added", added),
+			logger.Int("removed", removed),
+		)
+	}
+}
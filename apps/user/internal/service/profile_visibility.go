@@ -0,0 +1,119 @@
+package service
+
+import (
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+	"context"
+)
+
+// ProfileRelation 查看者与被查看用户之间的关系层级（决定资料可见性）
+type ProfileRelation string
+
+const (
+	// ProfileRelationStranger 陌生人（含已删除好友、拉黑关系）
+	ProfileRelationStranger ProfileRelation = "stranger"
+	// ProfileRelationPending 任意一方存在待处理的好友申请
+	ProfileRelationPending ProfileRelation = "pending"
+	// ProfileRelationFriend 好友（查看自己的资料也按好友处理）
+	ProfileRelationFriend ProfileRelation = "friend"
+)
+
+// FieldVisibility 单个字段的可见级别
+type FieldVisibility int
+
+const (
+	// FieldMasked 脱敏展示
+	FieldMasked FieldVisibility = iota
+	// FieldVisible 原样展示
+	FieldVisible
+)
+
+// ProfileVisibility 资料字段可见性策略
+type ProfileVisibility struct {
+	Email     FieldVisibility
+	Telephone FieldVisibility
+}
+
+// profileVisibilityPolicy 关系层级 -> 字段可见性
+//   - stranger: 邮箱、手机号均脱敏
+//   - pending:  邮箱可见（便于确认申请对象身份），手机号脱敏
+//   - friend:   全部可见
+var profileVisibilityPolicy = map[ProfileRelation]ProfileVisibility{
+	ProfileRelationStranger: {Email: FieldMasked, Telephone: FieldMasked},
+	ProfileRelationPending:  {Email: FieldVisible, Telephone: FieldMasked},
+	ProfileRelationFriend:   {Email: FieldVisible, Telephone: FieldVisible},
+}
+
+// VisibilityFor 返回关系层级对应的可见性策略（未知关系按陌生人处理）
+func VisibilityFor(relation ProfileRelation) ProfileVisibility {
+	if v, ok := profileVisibilityPolicy[relation]; ok {
+		return v
+	}
+	return profileVisibilityPolicy[ProfileRelationStranger]
+}
+
+// ApplyProfileVisibility 按关系层级对用户信息做脱敏（原地修改）
+func ApplyProfileVisibility(info *pb.UserInfo, relation ProfileRelation) {
+	if info == nil {
+		return
+	}
+	visibility := VisibilityFor(relation)
+	if visibility.Email == FieldMasked && info.Email != "" {
+		info.Email = util.MaskEmail(info.Email)
+	}
+	if visibility.Telephone == FieldMasked && info.Telephone != "" {
+		info.Telephone = util.MaskTelephone(info.Telephone)
+	}
+}
+
+// resolveProfileRelation 计算查看者与目标用户的关系层级
+// 查询失败时降级为陌生人（宁可多脱敏，不可泄露）。
+func resolveProfileRelation(
+	ctx context.Context,
+	friendRepo repository.IFriendRepository,
+	applyRepo repository.IApplyRepository,
+	viewerUUID, targetUUID string,
+) ProfileRelation {
+	if viewerUUID == "" {
+		return ProfileRelationStranger
+	}
+	if viewerUUID == targetUUID {
+		return ProfileRelationFriend
+	}
+
+	if friendRepo != nil {
+		relation, err := friendRepo.GetRelationStatus(ctx, viewerUUID, targetUUID)
+		if err != nil {
+			logger.Warn(ctx, "查询关系状态失败，按陌生人处理",
+				logger.String("user_uuid", viewerUUID),
+				logger.String("peer_uuid", targetUUID),
+				logger.ErrorField("error", err),
+			)
+			return ProfileRelationStranger
+		}
+		if relation != nil && !relation.DeletedAt.Valid && relation.Status == 0 {
+			return ProfileRelationFriend
+		}
+	}
+
+	if applyRepo != nil {
+		for _, pair := range [][2]string{{viewerUUID, targetUUID}, {targetUUID, viewerUUID}} {
+			pending, err := applyRepo.ExistsPendingRequest(ctx, pair[0], pair[1])
+			if err != nil {
+				logger.Warn(ctx, "查询待处理好友申请失败，按陌生人处理",
+					logger.String("applicant_uuid", pair[0]),
+					logger.String("target_uuid", pair[1]),
+					logger.ErrorField("error", err),
+				)
+				return ProfileRelationStranger
+			}
+			if pending {
+				return ProfileRelationPending
+			}
+		}
+	}
+
+	return ProfileRelationStranger
+}
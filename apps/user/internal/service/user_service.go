@@ -5,16 +5,18 @@ import (
 	"ChatServer/apps/user/internal/repository"
 	"ChatServer/apps/user/internal/utils"
 	pb "ChatServer/apps/user/pb"
+	"ChatServer/config"
 	"ChatServer/consts"
-	"ChatServer/pkg/async"
+	"ChatServer/model"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/util"
 	"context"
 	"errors"
-	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
@@ -23,17 +25,61 @@ import (
 
 // userServiceImpl 用户信息服务实现
 type userServiceImpl struct {
-	userRepo   repository.IUserRepository
-	authRepo   repository.IAuthRepository
-	deviceRepo repository.IDeviceRepository
+	userRepo    repository.IUserRepository
+	authRepo    repository.IAuthRepository
+	deviceRepo  repository.IDeviceRepository
+	friendRepo  repository.IFriendRepository
+	applyRepo   repository.IApplyRepository
+	qrSigner    *utils.QRCodeSigner
+	deleteGrace time.Duration           // 注销宽限期（超过后才允许物理清理）
+	searchCfg   config.UserSearchConfig // 用户搜索配置（关键词最小长度、默认/最大每页条数）
 }
 
+// qrCodeURLPrefix 用户二维码 URL 前缀
+const qrCodeURLPrefix = "https://www.LCchat.top/q/"
+
+// 换绑联系方式使用的验证码类型（与 SendVerifyCode 的 type 取值一致）
+const (
+	verifyCodeTypeChangeEmail     int32 = 4 // 换绑邮箱
+	verifyCodeTypeChangeTelephone int32 = 5 // 换绑手机（发送到当前绑定邮箱）
+	verifyCodeTypeDeleteAccount   int32 = 6 // 注销账号（发送到当前绑定邮箱）
+)
+
+// defaultAccountDeleteGrace 注销宽限期默认值
+const defaultAccountDeleteGrace = 30 * 24 * time.Hour
+
+// defaultUserSearchConfig 未传入搜索配置时使用的默认值
+var defaultUserSearchConfig = config.UserSearchConfig{MinKeywordLen: 2, DefaultPageSize: 20, MaxPageSize: 100}
+
+// telephonePattern 大陆手机号格式
+var telephonePattern = regexp.MustCompile(`^1[3-9]\d{9}$`)
+
 // NewUserService 创建用户信息服务实例
-func NewUserService(userRepo repository.IUserRepository, authRepo repository.IAuthRepository, deviceRepo repository.IDeviceRepository) UserService {
+func NewUserService(
+	userRepo repository.IUserRepository,
+	authRepo repository.IAuthRepository,
+	deviceRepo repository.IDeviceRepository,
+	friendRepo repository.IFriendRepository,
+	applyRepo repository.IApplyRepository,
+	qrSigner *utils.QRCodeSigner,
+	deleteGrace time.Duration,
+	searchCfg config.UserSearchConfig,
+) UserService {
+	if deleteGrace <= 0 {
+		deleteGrace = defaultAccountDeleteGrace
+	}
+	if searchCfg == (config.UserSearchConfig{}) {
+		searchCfg = defaultUserSearchConfig
+	}
 	return &userServiceImpl{
-		userRepo:   userRepo,
-		authRepo:   authRepo,
-		deviceRepo: deviceRepo,
+		userRepo:    userRepo,
+		authRepo:    authRepo,
+		deviceRepo:  deviceRepo,
+		friendRepo:  friendRepo,
+		applyRepo:   applyRepo,
+		qrSigner:    qrSigner,
+		deleteGrace: deleteGrace,
+		searchCfg:   searchCfg,
 	}
 }
 
@@ -71,9 +117,10 @@ func (s *userServiceImpl) GetProfile(ctx context.Context, req *pb.GetProfileRequ
 		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
 	}
 
-	// 3. 转换为Protobuf格式并返回
+	// 3. 转换为Protobuf格式并返回（隐私设置仅本人可见）
 	return &pb.GetProfileResponse{
 		UserInfo: converter.ModelToProtoUserInfo(userInfo),
+		Privacy:  converter.ModelToProtoPrivacySettings(userInfo),
 	}, nil
 }
 
@@ -81,9 +128,9 @@ func (s *userServiceImpl) GetProfile(ctx context.Context, req *pb.GetProfileRequ
 // 业务流程：
 //  1. 从context中获取当前用户UUID
 //  2. 查询目标用户信息
-//  3. 判断是否为好友关系
-//  4. 非好友时脱敏邮箱和手机号
-//  5. 返回用户信息
+//  3. 计算关系层级（stranger/pending/friend）
+//  4. 按关系可见性策略脱敏邮箱和手机号（唯一脱敏点，Gateway 不再处理）
+//  5. 返回用户信息与关系层级
 //
 // 错误码映射：
 //   - codes.NotFound: 用户不存在
@@ -106,20 +153,30 @@ func (s *userServiceImpl) GetOtherProfile(ctx context.Context, req *pb.GetOtherP
 		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
 	}
 
-	// 2. 返回用户信息（脱敏由Gateway层负责）
+	// 2. 计算关系层级并按策略脱敏
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	relation := resolveProfileRelation(ctx, s.friendRepo, s.applyRepo, currentUserUUID, req.UserUuid)
+	userInfo := converter.ModelToProtoUserInfo(targetUserInfo)
+	ApplyProfileVisibility(userInfo, relation)
+
+	// 3. 返回用户信息
 	return &pb.GetOtherProfileResponse{
-		UserInfo: converter.ModelToProtoUserInfo(targetUserInfo),
+		UserInfo: userInfo,
+		Relation: string(relation),
+		IsFriend: relation == ProfileRelationFriend && currentUserUUID != req.UserUuid,
 	}, nil
 }
 
 // SearchUser 搜索用户
 // 业务流程：
-//  1. 从context中获取当前用户UUID（用于鉴权）
-//  2. 调用userRepo搜索用户（按邮箱、昵称、UUID）
-//  3. 组装响应（不返回 email）
+//  1. 从context中获取当前用户UUID（用于鉴权），规整关键词（去除首尾空白、连续空白合并为一个空格）并校验最小长度，
+//     page<=0 按第 1 页，page_size<=0 取默认值、超过上限时截断
+//  2. 按 search_type 调用userRepo搜索用户（UUID/邮箱精确匹配、昵称模糊匹配，未指定时自动判断），
+//     结果按匹配程度排序，排除已拉黑当前用户的用户
+//  3. 组装响应（不返回 email，邮箱精确搜索同样不回显邮箱）
 //
 // 错误码映射：
-//   - codes.InvalidArgument: 关键词太短
+//   - codes.InvalidArgument: 关键词太短或搜索模式未定义
 //   - codes.Internal: 系统内部错误
 func (s *userServiceImpl) SearchUser(ctx context.Context, req *pb.SearchUserRequest) (*pb.SearchUserResponse, error) {
 	// 1. 从context中获取当前用户UUID
@@ -129,13 +186,24 @@ func (s *userServiceImpl) SearchUser(ctx context.Context, req *pb.SearchUserRequ
 		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
 	}
 
+	keyword := normalizeSearchKeyword(req.Keyword)
+	if utf8.RuneCountInString(keyword) < s.searchCfg.MinKeywordLen {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	mode, ok := searchUserModes[req.SearchType]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	page, pageSize := s.searchUserPage(req.Page, req.PageSize)
+
 	// 2. 调用搜索用户
-	users, total, err := s.userRepo.SearchUser(ctx, req.Keyword, int(req.Page), int(req.PageSize))
+	users, total, err := s.userRepo.SearchUser(ctx, currentUserUUID, keyword, mode, int(page), int(pageSize))
 	if err != nil {
 		logger.Error(ctx, "搜索用户失败",
-			logger.String("keyword", req.Keyword),
-			logger.Int("page", int(req.Page)),
-			logger.Int("page_size", int(req.PageSize)),
+			logger.String("keyword", keyword),
+			logger.String("search_type", req.SearchType.String()),
+			logger.Int("page", int(page)),
+			logger.Int("page_size", int(pageSize)),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
@@ -146,10 +214,10 @@ func (s *userServiceImpl) SearchUser(ctx context.Context, req *pb.SearchUserRequ
 		return &pb.SearchUserResponse{
 			Items: []*pb.SimpleUserItem{},
 			Pagination: &pb.PaginationInfo{
-				Page:       req.Page,
-				PageSize:   req.PageSize,
+				Page:       page,
+				PageSize:   pageSize,
 				Total:      total,
-				TotalPages: int32((total + int64(req.PageSize) - 1) / int64(req.PageSize)),
+				TotalPages: totalPages(total, pageSize),
 			},
 		}, nil
 	}
@@ -166,30 +234,63 @@ func (s *userServiceImpl) SearchUser(ctx context.Context, req *pb.SearchUserRequ
 		}
 	}
 
-	// 4. 计算总页数
-	totalPages := int32((total + int64(req.PageSize) - 1) / int64(req.PageSize))
-
 	logger.Info(ctx, "搜索用户成功",
-		logger.String("keyword", req.Keyword),
+		logger.String("keyword", keyword),
+		logger.String("search_type", req.SearchType.String()),
 		logger.String("user_uuid", currentUserUUID),
-		logger.Int("page", int(req.Page)),
-		logger.Int("page_size", int(req.PageSize)),
+		logger.Int("page", int(page)),
+		logger.Int("page_size", int(pageSize)),
 		logger.Int64("total", total),
 		logger.Int("found", len(users)),
 	)
 
-	// 5. 返回搜索结果
+	// 4. 返回搜索结果
 	return &pb.SearchUserResponse{
 		Items: items,
 		Pagination: &pb.PaginationInfo{
-			Page:       req.Page,
-			PageSize:   req.PageSize,
+			Page:       page,
+			PageSize:   pageSize,
 			Total:      total,
-			TotalPages: totalPages,
+			TotalPages: totalPages(total, pageSize),
 		},
 	}, nil
 }
 
+// searchUserModes 搜索模式到仓储查询模式的映射，不在表中的取值视为参数错误。
+var searchUserModes = map[pb.SearchUserType]repository.SearchMode{
+	pb.SearchUserType_SEARCH_USER_TYPE_UNSPECIFIED: repository.SearchModeAuto,
+	pb.SearchUserType_SEARCH_USER_TYPE_UUID:        repository.SearchModeUUID,
+	pb.SearchUserType_SEARCH_USER_TYPE_EMAIL:       repository.SearchModeEmail,
+	pb.SearchUserType_SEARCH_USER_TYPE_NICKNAME:    repository.SearchModeNickname,
+}
+
+// normalizeSearchKeyword 规整搜索关键词：去除首尾空白，内部连续空白（含全角空格、制表符）合并为一个空格。
+func normalizeSearchKeyword(keyword string) string {
+	return strings.Join(strings.Fields(keyword), " ")
+}
+
+// searchUserPage 规整分页参数：page<=0 按第 1 页；pageSize<=0 取默认值，超过上限时截断。
+func (s *userServiceImpl) searchUserPage(page, pageSize int32) (int32, int32) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = int32(s.searchCfg.DefaultPageSize)
+	}
+	if maxSize := int32(s.searchCfg.MaxPageSize); maxSize > 0 && pageSize > maxSize {
+		pageSize = maxSize
+	}
+	return page, pageSize
+}
+
+// totalPages 计算总页数（向上取整），pageSize<=0 时返回 0，避免除零。
+func totalPages(total int64, pageSize int32) int32 {
+	if pageSize <= 0 || total <= 0 {
+		return 0
+	}
+	return int32((total + int64(pageSize) - 1) / int64(pageSize))
+}
+
 // UpdateProfile 更新基本信息
 // 业务流程：
 //  1. 从context中获取用户UUID
@@ -330,19 +431,20 @@ func (s *userServiceImpl) UploadAvatar(ctx context.Context, req *pb.UploadAvatar
 //  4. 验证新密码不能与旧密码相同
 //  5. 生成新密码哈希
 //  6. 更新密码
-//  7. 踢出其他所有设备的登录态
+//  7. 递增用户令牌版本（此前签发的 Access Token 全部失效），踢出其他所有设备的登录态
+//  8. 为当前设备签发新版本 Access Token
 //
 // 错误码映射：
 //   - codes.NotFound: 用户不存在
 //   - codes.Unauthenticated: 旧密码错误
 //   - codes.FailedPrecondition: 新密码不能与旧密码相同
 //   - codes.Internal: 系统内部错误
-func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) error {
+func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
 	// 1. 从context中获取用户UUID
 	userUUID := util.GetUserUUIDFromContext(ctx)
 	if userUUID == "" {
 		logger.Error(ctx, "获取用户UUID失败")
-		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
 	}
 
 	// 2. 查询用户信息
@@ -352,14 +454,14 @@ func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePass
 			logger.String("user_uuid", userUUID),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
 	if userInfo == nil {
 		logger.Warn(ctx, "用户不存在",
 			logger.String("user_uuid", userUUID),
 		)
-		return status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
 	}
 
 	// 3. 校验旧密码是否正确
@@ -368,7 +470,7 @@ func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePass
 		logger.Warn(ctx, "旧密码错误",
 			logger.String("user_uuid", userUUID),
 		)
-		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodePasswordError))
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodePasswordError))
 	}
 
 	// 4. 校验新密码是否与旧密码相同
@@ -378,7 +480,7 @@ func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePass
 		logger.Warn(ctx, "新密码不能与旧密码相同",
 			logger.String("user_uuid", userUUID),
 		)
-		return status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodePasswordSameAsOld))
+		return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodePasswordSameAsOld))
 	}
 
 	// 5. 生成新密码哈希
@@ -388,7 +490,7 @@ func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePass
 			logger.String("user_uuid", userUUID),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
 	// 6. 更新密码
@@ -398,31 +500,93 @@ func (s *userServiceImpl) ChangePassword(ctx context.Context, req *pb.ChangePass
 			logger.String("user_uuid", userUUID),
 			logger.ErrorField("error", err),
 		)
-		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	// 7. 踢出其他所有设备的登录态（删除所有设备的token）
-	// 注意：当前设备保持登录态，其他设备被踢出
-	// 这里需要在repository中实现踢出其他设备的方法，暂时跳过
-	// TODO: 实现踢出其他设备登录态
+	// 7. 递增用户令牌版本，使 Gateway/connect 拒绝所有旧 Access Token；其他设备删除 Token 并递增纪元
+	// 密码已修改成功，失败仅记录日志
+	if _, err := s.deviceRepo.BumpTokenVersion(ctx, userUUID); err != nil {
+		logger.Error(ctx, "递增用户令牌版本失败",
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+	}
+	deviceID := util.GetDeviceIDFromContext(ctx)
+	revokeDeviceTokens(ctx, s.deviceRepo, userUUID, deviceID)
 
 	logger.Info(ctx, "密码修改成功",
 		logger.String("user_uuid", userUUID),
 	)
 
-	return nil
+	// 8. 当前设备保持登录态：按新版本重新签发 Access Token
+	// 签发失败不回滚密码，返回空 Token，客户端按重新登录处理
+	resp := &pb.ChangePasswordResponse{}
+	if deviceID == "" {
+		return resp, nil
+	}
+	accessToken, err := signAccessToken(ctx, s.deviceRepo, userUUID, deviceID)
+	if err == nil {
+		err = s.deviceRepo.StoreAccessToken(ctx, userUUID, deviceID, accessToken, util.AccessTTL())
+	}
+	if err != nil {
+		logger.Error(ctx, "修改密码后签发 Access Token 失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return resp, nil
+	}
+	resp.AccessToken = accessToken
+	resp.TokenType = "Bearer"
+	resp.ExpiresIn = int64(util.AccessTTL().Seconds())
+	return resp, nil
+}
+
+// revokeDeviceTokens 使除 currentDeviceID 外的所有设备登录态失效（currentDeviceID 为空时为全部设备）
+// 递增令牌纪元保证即使 connect 鉴权降级，也会拒绝这些设备此前签发的 Token
+func revokeDeviceTokens(ctx context.Context, deviceRepo repository.IDeviceRepository, userUUID, currentDeviceID string) {
+	sessions, err := deviceRepo.GetByUserUUID(ctx, userUUID)
+	if err != nil {
+		logger.Warn(ctx, "查询设备会话失败，跳过踢出其他设备",
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+
+	for _, session := range sessions {
+		if session == nil || session.DeviceId == currentDeviceID {
+			continue
+		}
+		if err := deviceRepo.DeleteTokens(ctx, userUUID, session.DeviceId); err != nil {
+			logger.Warn(ctx, "删除设备 Token 失败",
+				logger.String("user_uuid", userUUID),
+				logger.String("device_id", session.DeviceId),
+				logger.ErrorField("error", err),
+			)
+		}
+		if _, err := deviceRepo.BumpTokenEpoch(ctx, userUUID, session.DeviceId); err != nil {
+			logger.Warn(ctx, "递增设备令牌纪元失败",
+				logger.String("user_uuid", userUUID),
+				logger.String("device_id", session.DeviceId),
+				logger.ErrorField("error", err),
+			)
+		}
+	}
 }
 
 // ChangeEmail 绑定/换绑邮箱
 // 业务流程：
 //  1. 从context中获取用户UUID
-//  2. 检查新邮箱是否已被使用
-//  3. 校验验证码是否正确
-//  4. 更新邮箱
-//  5. 删除验证码
+//  2. 查询用户当前信息，拒绝换绑为当前已绑定的邮箱
+//  3. 检查新邮箱是否已被使用
+//  4. 校验新邮箱的验证码是否正确
+//  5. 更新邮箱（仓储层同步删除用户信息缓存）
+//  6. 删除验证码
 //
 // 错误码映射：
 //   - codes.NotFound: 用户不存在
+//   - codes.FailedPrecondition: 新邮箱与当前绑定邮箱相同
 //   - codes.AlreadyExists: 邮箱已被使用
 //   - codes.Unauthenticated: 验证码错误或已过期
 //   - codes.Internal: 系统内部错误
@@ -437,80 +601,64 @@ func (s *userServiceImpl) ChangeEmail(ctx context.Context, req *pb.ChangeEmailRe
 	// 记录换绑邮箱请求（新旧邮箱脱敏）
 	logger.Info(ctx, "用户换绑邮箱请求",
 		logger.String("user_uuid", userUUID),
-		logger.String("new_email", utils.MaskEmail(req.NewEmail)),
+		logger.String("new_email", util.MaskEmail(req.NewEmail)),
 	)
 
-	// 2. 检查新邮箱是否已被使用
-	exists, err := s.userRepo.ExistsByEmail(ctx, req.NewEmail)
+	// 2. 查询用户当前信息
+	userInfo, err := s.getUserForContactChange(ctx, userUUID)
 	if err != nil {
-		logger.Error(ctx, "检查邮箱是否存在失败",
-			logger.String("email", utils.MaskEmail(req.NewEmail)),
-			logger.ErrorField("error", err),
-		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		return nil, err
 	}
-	if exists {
-		logger.Warn(ctx, "邮箱已被使用",
-			logger.String("email", utils.MaskEmail(req.NewEmail)),
+	if strings.EqualFold(userInfo.Email, req.NewEmail) {
+		logger.Warn(ctx, "新邮箱与当前绑定邮箱相同",
+			logger.String("user_uuid", userUUID),
 		)
-		return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeEmailAlreadyExist))
+		return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeEmailSameAsOld))
 	}
 
-	// 3. 校验验证码（type=4: 换绑邮箱）
-	isValid, err := s.authRepo.VerifyVerifyCode(ctx, req.NewEmail, req.VerifyCode, 4)
+	// 3. 检查新邮箱是否已被使用
+	exists, err := s.userRepo.ExistsByEmail(ctx, req.NewEmail)
 	if err != nil {
-		// 判断是 Redis Key 不存在还是其他错误
-		if errors.Is(err, repository.ErrRedisNil) {
-			logger.Warn(ctx, "验证码已过期",
-				logger.String("email", utils.MaskEmail(req.NewEmail)),
-			)
-			return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeVerifyCodeExpire))
-		}
-		logger.Error(ctx, "校验验证码失败",
-			logger.String("email", utils.MaskEmail(req.NewEmail)),
+		logger.Error(ctx, "检查邮箱是否存在失败",
+			logger.String("email", util.MaskEmail(req.NewEmail)),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
-	if !isValid {
-		logger.Warn(ctx, "验证码错误",
-			logger.String("email", utils.MaskEmail(req.NewEmail)),
+	if exists {
+		logger.Warn(ctx, "邮箱已被使用",
+			logger.String("email", util.MaskEmail(req.NewEmail)),
 		)
-		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeVerifyCodeError))
+		return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeEmailAlreadyExist))
 	}
 
-	// 4. 查询用户当前信息，获取旧邮箱用于日志记录
-	userInfo, err := s.userRepo.GetByUUID(ctx, userUUID)
-	if err != nil {
-		logger.Error(ctx, "查询用户信息失败",
-			logger.String("user_uuid", userUUID),
-			logger.ErrorField("error", err),
-		)
-		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
-	}
-	if userInfo == nil {
-		logger.Warn(ctx, "用户不存在",
-			logger.String("user_uuid", userUUID),
-		)
-		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+	// 4. 校验验证码（type=4: 换绑邮箱）
+	if err := s.checkContactVerifyCode(ctx, req.NewEmail, req.VerifyCode, verifyCodeTypeChangeEmail); err != nil {
+		return nil, err
 	}
 
 	// 5. 更新邮箱
 	err = s.userRepo.UpdateEmail(ctx, userUUID, req.NewEmail)
 	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			logger.Warn(ctx, "邮箱已被使用（并发换绑冲突）",
+				logger.String("email", util.MaskEmail(req.NewEmail)),
+			)
+			return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeEmailAlreadyExist))
+		}
 		logger.Error(ctx, "更新邮箱失败",
 			logger.String("user_uuid", userUUID),
-			logger.String("old_email", utils.MaskEmail(userInfo.Email)),
-			logger.String("new_email", utils.MaskEmail(req.NewEmail)),
+			logger.String("old_email", util.MaskEmail(userInfo.Email)),
+			logger.String("new_email", util.MaskEmail(req.NewEmail)),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
 	// 6. 删除验证码（type=4: 换绑邮箱）
-	if err := s.authRepo.DeleteVerifyCode(ctx, req.NewEmail, 4); err != nil {
+	if err := s.authRepo.DeleteVerifyCode(ctx, req.NewEmail, verifyCodeTypeChangeEmail); err != nil {
 		logger.Warn(ctx, "删除验证码失败",
-			logger.String("email", utils.MaskEmail(req.NewEmail)),
+			logger.String("email", util.MaskEmail(req.NewEmail)),
 			logger.ErrorField("error", err),
 		)
 		// 删除失败不影响换绑邮箱流程，只记录警告日志
@@ -519,8 +667,8 @@ func (s *userServiceImpl) ChangeEmail(ctx context.Context, req *pb.ChangeEmailRe
 	// 7. 换绑成功
 	logger.Info(ctx, "邮箱更换成功",
 		logger.String("user_uuid", userUUID),
-		logger.String("old_email", utils.MaskEmail(userInfo.Email)),
-		logger.String("new_email", utils.MaskEmail(req.NewEmail)),
+		logger.String("old_email", util.MaskEmail(userInfo.Email)),
+		logger.String("new_email", util.MaskEmail(req.NewEmail)),
 	)
 
 	return &pb.ChangeEmailResponse{
@@ -529,23 +677,22 @@ func (s *userServiceImpl) ChangeEmail(ctx context.Context, req *pb.ChangeEmailRe
 }
 
 // ChangeTelephone 绑定/换绑手机
-func (s *userServiceImpl) ChangeTelephone(ctx context.Context, req *pb.ChangeTelephoneRequest) (*pb.ChangeTelephoneResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "绑定/换绑手机功能暂未实现")
-}
-
-// GetQRCode 获取用户二维码
 // 业务流程：
 //  1. 从context中获取用户UUID
-//  2. 使用雪花算法生成唯一的二维码 token
-//  3. 在 Redis 中保存 token -> userUUID 和 userUUID -> token 的映射关系（48小时过期）
-//  4. 构造二维码 URL，格式为: https://LCchat.top/api/v1/auth/user/parse-qrcode/{token}
-//  5. 计算过期时间（当前时间 + 48小时）
-//  6. 返回二维码 URL 和过期时间
+//  2. 查询用户当前信息，拒绝换绑为当前已绑定的手机号
+//  3. 检查新手机号是否已被使用
+//  4. 校验发送到当前绑定邮箱的验证码（type=5；短信通道未接入，以邮箱确认账号本人操作）
+//  5. 更新手机号（仓储层同步删除用户信息缓存）
+//  6. 删除验证码
 //
 // 错误码映射：
-//   - codes.Unauthenticated: 未认证
+//   - codes.InvalidArgument: 手机号格式错误
+//   - codes.NotFound: 用户不存在
+//   - codes.FailedPrecondition: 新手机号与当前绑定手机号相同
+//   - codes.AlreadyExists: 手机号已被使用
+//   - codes.Unauthenticated: 验证码错误或已过期
 //   - codes.Internal: 系统内部错误
-func (s *userServiceImpl) GetQRCode(ctx context.Context, req *pb.GetQRCodeRequest) (*pb.GetQRCodeResponse, error) {
+func (s *userServiceImpl) ChangeTelephone(ctx context.Context, req *pb.ChangeTelephoneRequest) (*pb.ChangeTelephoneResponse, error) {
 	// 1. 从context中获取用户UUID
 	userUUID := util.GetUserUUIDFromContext(ctx)
 	if userUUID == "" {
@@ -553,56 +700,174 @@ func (s *userServiceImpl) GetQRCode(ctx context.Context, req *pb.GetQRCodeReques
 		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
 	}
 
-	// 2. 如果已有二维码 token，则直接返回
-	token, expireTime, err := s.userRepo.GetQRCodeTokenByUserUUID(ctx, userUUID)
-	if err == nil {
-		logger.Info(ctx, "用户已有二维码 token",
+	if !telephonePattern.MatchString(req.NewTelephone) {
+		logger.Warn(ctx, "手机号格式错误",
 			logger.String("user_uuid", userUUID),
-			logger.String("qrcode_url", fmt.Sprintf("https://www.LCchat.top/q/%s", token)),
 		)
-		return &pb.GetQRCodeResponse{
-			Qrcode:   fmt.Sprintf("https://www.LCchat.top/q/%s", token),
-			ExpireAt: expireTime.Format(time.RFC3339),
-		}, nil
-	} else if errors.Is(err, repository.ErrRedisNil) {
-	} else {
-		logger.Error(ctx, "获取用户二维码 token失败",
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodePhoneFormatError))
+	}
+
+	// 记录换绑手机请求（手机号脱敏）
+	logger.Info(ctx, "用户换绑手机请求",
+		logger.String("user_uuid", userUUID),
+		logger.String("new_telephone", util.MaskTelephone(req.NewTelephone)),
+	)
+
+	// 2. 查询用户当前信息
+	userInfo, err := s.getUserForContactChange(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.Telephone == req.NewTelephone {
+		logger.Warn(ctx, "新手机号与当前绑定手机号相同",
 			logger.String("user_uuid", userUUID),
+		)
+		return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeTelephoneSameAsOld))
+	}
+
+	// 3. 检查新手机号是否已被使用
+	exists, err := s.userRepo.ExistsByPhone(ctx, req.NewTelephone)
+	if err != nil {
+		logger.Error(ctx, "检查手机号是否存在失败",
+			logger.String("telephone", util.MaskTelephone(req.NewTelephone)),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
+	if exists {
+		logger.Warn(ctx, "手机号已被使用",
+			logger.String("telephone", util.MaskTelephone(req.NewTelephone)),
+		)
+		return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeTelephoneAlreadyExist))
+	}
 
-	// 3. 使用雪花算法生成唯一的二维码 token
-	token = util.GenIDString()
+	// 4. 校验验证码（type=5: 换绑手机，按当前绑定邮箱存储）
+	if err := s.checkContactVerifyCode(ctx, userInfo.Email, req.VerifyCode, verifyCodeTypeChangeTelephone); err != nil {
+		return nil, err
+	}
 
-	// 3. 在 Redis 中保存 token -> userUUID 和 userUUID -> token 的映射关系
-	err = s.userRepo.SaveQRCode(ctx, userUUID, token)
+	// 5. 更新手机号
+	err = s.userRepo.UpdateTelephone(ctx, userUUID, req.NewTelephone)
 	if err != nil {
-		logger.Error(ctx, "保存二维码到Redis失败",
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			logger.Warn(ctx, "手机号已被使用（并发换绑冲突）",
+				logger.String("telephone", util.MaskTelephone(req.NewTelephone)),
+			)
+			return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeTelephoneAlreadyExist))
+		}
+		logger.Error(ctx, "更新手机号失败",
 			logger.String("user_uuid", userUUID),
-			logger.String("token", token),
+			logger.String("old_telephone", util.MaskTelephone(userInfo.Telephone)),
+			logger.String("new_telephone", util.MaskTelephone(req.NewTelephone)),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	// 4. 构造二维码 URL
-	qrcodeURL := fmt.Sprintf("https://www.LCchat.top/q/%s", token)
+	// 6. 删除验证码（type=5: 换绑手机）
+	if err := s.authRepo.DeleteVerifyCode(ctx, userInfo.Email, verifyCodeTypeChangeTelephone); err != nil {
+		logger.Warn(ctx, "删除验证码失败",
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+		// 删除失败不影响换绑手机流程，只记录警告日志
+	}
+
+	// 7. 换绑成功
+	logger.Info(ctx, "手机号更换成功",
+		logger.String("user_uuid", userUUID),
+		logger.String("old_telephone", util.MaskTelephone(userInfo.Telephone)),
+		logger.String("new_telephone", util.MaskTelephone(req.NewTelephone)),
+	)
 
-	// 5. 计算过期时间（当前时间 + 48小时）
-	expireAt := time.Now().Add(48 * time.Hour).Format(time.RFC3339)
+	return &pb.ChangeTelephoneResponse{
+		Telephone: req.NewTelephone,
+	}, nil
+}
+
+// getUserForContactChange 查询换绑联系方式的用户，不存在时返回 NotFound
+func (s *userServiceImpl) getUserForContactChange(ctx context.Context, userUUID string) (*model.UserInfo, error) {
+	userInfo, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		logger.Error(ctx, "查询用户信息失败",
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	if userInfo == nil {
+		logger.Warn(ctx, "用户不存在",
+			logger.String("user_uuid", userUUID),
+		)
+		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+	}
+	return userInfo, nil
+}
+
+// checkContactVerifyCode 校验换绑联系方式的验证码（target 为新邮箱或新手机号）
+func (s *userServiceImpl) checkContactVerifyCode(ctx context.Context, target, verifyCode string, codeType int32) error {
+	isValid, err := verifyCodeMatches(ctx, s.authRepo, target, verifyCode, codeType)
+	if err != nil {
+		// 判断是 Redis Key 不存在还是其他错误
+		if errors.Is(err, repository.ErrRedisNil) {
+			logger.Warn(ctx, "验证码已过期",
+				logger.Int("type", int(codeType)),
+			)
+			return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeVerifyCodeExpire))
+		}
+		logger.Error(ctx, "校验验证码失败",
+			logger.Int("type", int(codeType)),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	if !isValid {
+		logger.Warn(ctx, "验证码错误",
+			logger.Int("type", int(codeType)),
+		)
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeVerifyCodeError))
+	}
+	return nil
+}
+
+// GetQRCode 获取用户二维码
+// 业务流程：
+//  1. 从context中获取用户UUID
+//  2. 签发 HMAC 签名 token（userUUID + 签发时间），无需服务端存储
+//  3. 构造二维码 URL，格式为: https://www.LCchat.top/q/{token}
+//  4. 返回二维码 URL 和过期时间
+//
+// 错误码映射：
+//   - codes.Unauthenticated: 未认证
+//   - codes.Internal: 系统内部错误
+func (s *userServiceImpl) GetQRCode(ctx context.Context, req *pb.GetQRCodeRequest) (*pb.GetQRCodeResponse, error) {
+	// 1. 从context中获取用户UUID
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	if userUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	if s.qrSigner == nil {
+		logger.Error(ctx, "二维码签名器未初始化")
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	// 2. 签发二维码 token
+	token, expireAt := s.qrSigner.Sign(userUUID)
+
+	// 3. 构造二维码 URL
+	qrcodeURL := qrCodeURLPrefix + token
 
 	logger.Info(ctx, "生成用户二维码成功",
 		logger.String("user_uuid", userUUID),
-		logger.String("token", token),
-		logger.String("qrcode_url", qrcodeURL),
+		logger.String("expire_at", expireAt.Format(time.RFC3339)),
 	)
 
-	// 6. 返回二维码 URL 和过期时间
+	// 4. 返回二维码 URL 和过期时间
 	return &pb.GetQRCodeResponse{
 		Qrcode:   qrcodeURL,
-		ExpireAt: expireAt,
+		ExpireAt: expireAt.Format(time.RFC3339),
 	}, nil
 }
 
@@ -610,14 +875,15 @@ func (s *userServiceImpl) GetQRCode(ctx context.Context, req *pb.GetQRCodeReques
 // 业务流程：
 //  1. 从context中获取用户UUID
 //  2. 查询用户信息
-//  3. 验证密码是否正确
-//  4. 软删除用户（设置 deleted_at 时间戳）
-//  5. 删除用户的所有设备会话（登出所有设备）
-//  6. 返回注销时间和恢复截止时间
+//  3. 二次确认：校验密码，或校验发送到绑定邮箱的验证码（type=6）
+//  4. 单事务级联软删除：用户、双向关系、待处理好友申请、设备会话
+//  5. 清理所有设备的 Token 与设备缓存（登出所有设备，Redis 失败进入重试队列），并递增用户令牌版本
+//  6. 返回注销时间和恢复截止时间（注销时间 + 宽限期）
 //
 // 错误码映射：
+//   - codes.InvalidArgument: 未提供密码或验证码
 //   - codes.NotFound: 用户不存在
-//   - codes.Unauthenticated: 密码错误
+//   - codes.Unauthenticated: 密码错误、验证码错误或已过期
 //   - codes.Internal: 系统内部错误
 func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
 	// 1. 从context中获取用户UUID
@@ -627,6 +893,13 @@ func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccou
 		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
 	}
 
+	if req.Password == "" && req.VerifyCode == "" {
+		logger.Warn(ctx, "注销账号缺少二次确认",
+			logger.String("user_uuid", userUUID),
+		)
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
 	// 2. 查询用户信息
 	userInfo, err := s.userRepo.GetByUUID(ctx, userUUID)
 	if err != nil {
@@ -644,18 +917,28 @@ func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccou
 		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
 	}
 
-	// 3. 校验密码是否正确
-	err = bcrypt.CompareHashAndPassword([]byte(userInfo.Password), []byte(req.Password))
-	if err != nil {
-		logger.Warn(ctx, "密码错误",
-			logger.String("user_uuid", userUUID),
-		)
-		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodePasswordError))
+	// 3. 二次确认（优先校验密码）
+	if req.Password != "" {
+		err = bcrypt.CompareHashAndPassword([]byte(userInfo.Password), []byte(req.Password))
+		if err != nil {
+			logger.Warn(ctx, "密码错误",
+				logger.String("user_uuid", userUUID),
+			)
+			return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodePasswordError))
+		}
+	} else if err := s.checkContactVerifyCode(ctx, userInfo.Email, req.VerifyCode, verifyCodeTypeDeleteAccount); err != nil {
+		return nil, err
 	}
 
-	// 4. 软删除用户（设置 deleted_at 时间戳）
-	err = s.userRepo.Delete(ctx, userUUID)
+	// 4. 级联软删除（MySQL 事务）
+	err = s.userRepo.DeleteAccountCascade(ctx, userUUID)
 	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			logger.Warn(ctx, "注销账号失败：用户不存在或已注销",
+				logger.String("user_uuid", userUUID),
+			)
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+		}
 		logger.Error(ctx, "注销账号失败",
 			logger.String("user_uuid", userUUID),
 			logger.String("reason", req.Reason),
@@ -664,23 +947,33 @@ func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccou
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	// 5. 异步清理用户所有设备的 Redis 会话（不阻塞返回）
-	async.RunSafe(ctx, func(asyncCtx context.Context) {
-		if err := s.deviceRepo.DeleteByUserUUID(asyncCtx, userUUID); err != nil {
-			logger.Warn(asyncCtx, "清理用户 Redis 会话失败",
+	// 5. 清理所有设备 Token（同步执行，确保注销后旧 Token 立即失效；Redis 失败已进入重试队列）
+	if err := s.deviceRepo.DeleteByUserUUID(ctx, userUUID); err != nil {
+		logger.Warn(ctx, "清理用户设备会话失败",
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+	}
+	// 递增用户令牌版本：即使 Token 清理失败，已签发的 Access Token 也会被 Gateway/connect 拒绝
+	if _, err := s.deviceRepo.BumpTokenVersion(ctx, userUUID); err != nil {
+		logger.Error(ctx, "递增用户令牌版本失败",
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+	}
+
+	if req.Password == "" {
+		if err := s.authRepo.DeleteVerifyCode(ctx, userInfo.Email, verifyCodeTypeDeleteAccount); err != nil {
+			logger.Warn(ctx, "删除验证码失败",
 				logger.String("user_uuid", userUUID),
 				logger.ErrorField("error", err),
 			)
-		} else {
-			logger.Info(asyncCtx, "用户 Redis 会话清理完成",
-				logger.String("user_uuid", userUUID),
-			)
 		}
-	}, 5*time.Second)
+	}
 
-	// 6. 计算恢复截止时间（30天后）
+	// 6. 计算恢复截止时间
 	deleteAt := time.Now()
-	recoverDeadline := deleteAt.Add(30 * 24 * time.Hour)
+	recoverDeadline := deleteAt.Add(s.deleteGrace)
 
 	logger.Info(ctx, "账号注销成功",
 		logger.String("user_uuid", userUUID),
@@ -696,15 +989,14 @@ func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccou
 	}, nil
 }
 
-// BatchGetProfile 批量获取用户信息
 // BatchGetProfile 批量获取用户信息
 // 业务流程：
-//  1. 验证请求参数（UUID列表不为空，最多100个）
+//  1. 验证请求参数（最多 consts.BatchGetProfileMaxSize 个，去重，拒绝空/非法 UUID）
 //  2. 批量查询用户信息
 //  3. 转换为SimpleUserInfo格式并返回
 //
 // 错误码映射：
-//   - codes.InvalidArgument: 参数错误
+//   - codes.InvalidArgument: 参数错误（超限或包含非法 UUID）
 //   - codes.Internal: 系统内部错误
 func (s *userServiceImpl) BatchGetProfile(ctx context.Context, req *pb.BatchGetProfileRequest) (*pb.BatchGetProfileResponse, error) {
 	// 1. 验证请求参数
@@ -715,18 +1007,28 @@ func (s *userServiceImpl) BatchGetProfile(ctx context.Context, req *pb.BatchGetP
 		}, nil
 	}
 
-	if len(req.UserUuids) > 100 {
+	// 先按原始长度拦截，避免对超大列表做去重等额外开销
+	if len(req.UserUuids) > consts.BatchGetProfileMaxSize {
 		logger.Warn(ctx, "批量获取用户信息超过最大限制",
 			logger.Int("count", len(req.UserUuids)),
+			logger.Int("max", consts.BatchGetProfileMaxSize),
+		)
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	userUUIDs, ok := normalizeUserUUIDs(req.UserUuids)
+	if !ok {
+		logger.Warn(ctx, "批量获取用户信息包含非法UUID",
+			logger.Int("count", len(req.UserUuids)),
 		)
 		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
 	}
 
 	// 2. 批量查询用户信息
-	users, err := s.userRepo.BatchGetByUUIDs(ctx, req.UserUuids)
+	users, err := s.userRepo.BatchGetByUUIDs(ctx, userUUIDs)
 	if err != nil {
 		logger.Error(ctx, "批量查询用户信息失败",
-			logger.Int("count", len(req.UserUuids)),
+			logger.Int("count", len(userUUIDs)),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
@@ -745,7 +1047,7 @@ func (s *userServiceImpl) BatchGetProfile(ctx context.Context, req *pb.BatchGetP
 	}
 
 	logger.Info(ctx, "批量获取用户信息成功",
-		logger.Int("requested", len(req.UserUuids)),
+		logger.Int("requested", len(userUUIDs)),
 		logger.Int("found", len(simpleUsers)),
 	)
 
@@ -754,47 +1056,179 @@ func (s *userServiceImpl) BatchGetProfile(ctx context.Context, req *pb.BatchGetP
 	}, nil
 }
 
+// normalizeUserUUIDs 去重并校验 UUID 列表（保持原始顺序）
+// 任一 UUID 为空、超长或包含非法字符时返回 false。
+func normalizeUserUUIDs(uuids []string) ([]string, bool) {
+	result := make([]string, 0, len(uuids))
+	seen := make(map[string]struct{}, len(uuids))
+	for _, raw := range uuids {
+		uuid := strings.TrimSpace(raw)
+		if !isValidUserUUID(uuid) {
+			return nil, false
+		}
+		if _, exists := seen[uuid]; exists {
+			continue
+		}
+		seen[uuid] = struct{}{}
+		result = append(result, uuid)
+	}
+	return result, true
+}
+
+// isValidUserUUID 校验用户 UUID：非空、不超过 consts.UserUUIDMaxLen，且仅包含字母、数字、下划线和短横线
+func isValidUserUUID(uuid string) bool {
+	if uuid == "" || len(uuid) > consts.UserUUIDMaxLen {
+		return false
+	}
+	for _, r := range uuid {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // ParseQRCode 解析二维码
 // 业务流程：
-//  1. 验证 token 是否为空
-//  2. 从 Redis 中根据 token 获取用户 UUID
-//  3. 验证用户是否存在
-//  4. 返回用户 UUID
+//  1. 验证 token 是否为空（兼容传入完整二维码 URL）
+//  2. 校验 token 签名与有效期
+//  3. 查询二维码所属用户信息
+//  4. 计算当前用户与其关系状态
+//  5. 返回用户信息与关系状态
 //
 // 错误码映射：
-//   - codes.InvalidArgument: 二维码格式错误（token 为空）
+//   - codes.InvalidArgument: 二维码格式错误（为空、格式不合法或签名被篡改）
 //   - codes.NotFound: 二维码已过期或用户不存在
 //   - codes.Internal: 系统内部错误
 func (s *userServiceImpl) ParseQRCode(ctx context.Context, req *pb.ParseQRCodeRequest) (*pb.ParseQRCodeResponse, error) {
 	// 1. 验证 token 是否为空
-	if req.Token == "" {
+	token := strings.TrimPrefix(strings.TrimSpace(req.Token), qrCodeURLPrefix)
+	if token == "" {
 		logger.Warn(ctx, "二维码 token 为空")
 		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeQRCodeFormatError))
 	}
 
-	// 2. 从 Redis 中根据 token 获取用户 UUID
-	userUUID, err := s.userRepo.GetUUIDByQRCodeToken(ctx, req.Token)
+	if s.qrSigner == nil {
+		logger.Error(ctx, "二维码签名器未初始化")
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	// 2. 校验签名与有效期
+	userUUID, err := s.qrSigner.Verify(token)
 	if err != nil {
-		if errors.Is(err, repository.ErrRedisNil) {
-			// Redis 中不存在该 token，说明二维码已过期或无效
+		if errors.Is(err, utils.ErrQRCodeExpired) {
 			logger.Warn(ctx, "二维码已过期",
-				logger.String("token", req.Token),
+				logger.String("token", token),
 			)
 			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeQRCodeExpired))
 		}
-		logger.Error(ctx, "从 Redis 获取二维码 token 失败",
-			logger.String("token", req.Token),
+		logger.Warn(ctx, "二维码格式错误或签名无效",
+			logger.String("token", token),
+		)
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeQRCodeFormatError))
+	}
+
+	// 3. 查询二维码所属用户信息
+	userInfo, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		logger.Error(ctx, "查询用户信息失败",
+			logger.String("target_user_uuid", userUUID),
 			logger.ErrorField("error", err),
 		)
 		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
+	if userInfo == nil {
+		logger.Warn(ctx, "二维码所属用户不存在",
+			logger.String("target_user_uuid", userUUID),
+		)
+		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+	}
+
+	// 4. 计算关系状态（未登录时按陌生人处理）
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	relation := resolveProfileRelation(ctx, s.friendRepo, s.applyRepo, currentUserUUID, userUUID)
 
 	logger.Info(ctx, "解析二维码成功",
 		logger.String("user_uuid", userUUID),
+		logger.String("relation", string(relation)),
 	)
 
-	// 4. 返回用户 UUID
+	// 5. 返回用户信息与关系状态
 	return &pb.ParseQRCodeResponse{
 		UserUuid: userUUID,
+		UserInfo: converter.ModelToProtoSimpleUserInfo(userInfo),
+		Relation: string(relation),
+		IsFriend: relation == ProfileRelationFriend && currentUserUUID != userUUID,
 	}, nil
 }
+
+// SetPresenceVisibility 设置是否向好友隐藏在线状态
+// 隐藏后 connect 不再向好友推送该用户的上下线事件（已推送的状态由客户端按离线处理），
+// 在线状态查询对所有人返回离线；等价于 UpdatePrivacy(hide_presence, scope=所有人)。
+func (s *userServiceImpl) SetPresenceVisibility(ctx context.Context, req *pb.SetPresenceVisibilityRequest) (*pb.SetPresenceVisibilityResponse, error) {
+	if err := s.updatePresencePrivacy(ctx, req.Hidden, model.PresenceHideScopeEveryone); err != nil {
+		return nil, err
+	}
+	return &pb.SetPresenceVisibilityResponse{Hidden: req.Hidden}, nil
+}
+
+// UpdatePrivacy 更新隐私设置
+// hide_presence=true 时按 presence_hide_scope 隐藏在线状态与最近活跃时间：
+//   - 0（所有人）：在线状态查询对所有人返回离线，且不向好友推送上下线事件；
+//   - 1（仅非好友）：好友仍可见并收到推送，非好友查询返回离线。
+func (s *userServiceImpl) UpdatePrivacy(ctx context.Context, req *pb.UpdatePrivacyRequest) (*pb.UpdatePrivacyResponse, error) {
+	privacy := req.GetPrivacy()
+	if privacy == nil {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	scope := int8(privacy.PresenceHideScope)
+	if scope != model.PresenceHideScopeEveryone && scope != model.PresenceHideScopeNonFriends {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	if err := s.updatePresencePrivacy(ctx, privacy.HidePresence, scope); err != nil {
+		return nil, err
+	}
+	return &pb.UpdatePrivacyResponse{
+		Privacy: &pb.PrivacySettings{
+			HidePresence:      privacy.HidePresence,
+			PresenceHideScope: privacy.PresenceHideScope,
+		},
+	}, nil
+}
+
+// updatePresencePrivacy 更新当前用户的在线状态隐私设置（MySQL + Redis 隐藏集合）。
+func (s *userServiceImpl) updatePresencePrivacy(ctx context.Context, hidden bool, scope int8) error {
+	// 1. 从context中获取用户UUID
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	if userUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 更新隐私设置
+	if err := s.userRepo.UpdatePresencePrivacy(ctx, userUUID, hidden, scope); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			logger.Warn(ctx, "更新在线状态隐私设置失败：用户不存在",
+				logger.String("user_uuid", userUUID),
+			)
+			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeUserNotFound))
+		}
+		logger.Error(ctx, "更新在线状态隐私设置失败",
+			logger.String("user_uuid", userUUID),
+			logger.Bool("hidden", hidden),
+			logger.Int("scope", int(scope)),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "更新在线状态隐私设置成功",
+		logger.String("user_uuid", userUUID),
+		logger.Bool("hidden", hidden),
+		logger.Int("scope", int(scope)),
+	)
+	return nil
+}
@@ -4,15 +4,19 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"ChatServer/apps/user/internal/repository"
+	"ChatServer/apps/user/internal/utils"
 	pb "ChatServer/apps/user/pb"
+	"ChatServer/config"
 	"ChatServer/consts"
 	"ChatServer/model"
 	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +28,9 @@ import (
 
 var userSvcLoggerOnce sync.Once
 
+// testUserSearchCfg 测试使用的用户搜索配置（与默认值一致）
+var testUserSearchCfg = config.UserSearchConfig{MinKeywordLen: 2, DefaultPageSize: 20, MaxPageSize: 100}
+
 func initUserSvcTestLogger() {
 	userSvcLoggerOnce.Do(func() {
 		logger.ReplaceGlobal(zap.NewNop())
@@ -33,18 +40,20 @@ func initUserSvcTestLogger() {
 type fakeUserSvcRepo struct {
 	repository.IUserRepository
 
-	getByUUIDFn              func(context.Context, string) (*model.UserInfo, error)
-	searchUserFn             func(context.Context, string, int, int) ([]*model.UserInfo, int64, error)
-	updateBasicInfoFn        func(context.Context, string, string, string, string, int8) error
-	updateAvatarFn           func(context.Context, string, string) error
-	updatePasswordFn         func(context.Context, string, string) error
-	existsByEmailFn          func(context.Context, string) (bool, error)
-	updateEmailFn            func(context.Context, string, string) error
-	getQRCodeByUserUUIDFn    func(context.Context, string) (string, time.Time, error)
-	saveQRCodeFn             func(context.Context, string, string) error
-	getUUIDByQRCodeTokenFn   func(context.Context, string) (string, error)
-	deleteFn                 func(context.Context, string) error
-	batchGetByUUIDsFn        func(context.Context, []string) ([]*model.UserInfo, error)
+	getByUUIDFn       func(context.Context, string) (*model.UserInfo, error)
+	searchUserFn      func(context.Context, string, string, repository.SearchMode, int, int) ([]*model.UserInfo, int64, error)
+	updateBasicInfoFn func(context.Context, string, string, string, string, int8) error
+	updateAvatarFn    func(context.Context, string, string) error
+	updatePasswordFn  func(context.Context, string, string) error
+	existsByEmailFn   func(context.Context, string) (bool, error)
+	updateEmailFn     func(context.Context, string, string) error
+	existsByPhoneFn   func(context.Context, string) (bool, error)
+	updateTelFn       func(context.Context, string, string) error
+	deleteFn          func(context.Context, string) error
+	deleteCascadeFn   func(context.Context, string) error
+	batchGetByUUIDsFn func(context.Context, []string) ([]*model.UserInfo, error)
+	updatePresenceFn  func(context.Context, string, bool, int8) error
+	rebuildHiddenFn   func(context.Context) (int, int, error)
 }
 
 func (f *fakeUserSvcRepo) GetByUUID(ctx context.Context, uuid string) (*model.UserInfo, error) {
@@ -54,11 +63,11 @@ func (f *fakeUserSvcRepo) GetByUUID(ctx context.Context, uuid string) (*model.Us
 	return f.getByUUIDFn(ctx, uuid)
 }
 
-func (f *fakeUserSvcRepo) SearchUser(ctx context.Context, keyword string, page, pageSize int) ([]*model.UserInfo, int64, error) {
+func (f *fakeUserSvcRepo) SearchUser(ctx context.Context, searcherUUID, keyword string, mode repository.SearchMode, page, pageSize int) ([]*model.UserInfo, int64, error) {
 	if f.searchUserFn == nil {
 		return nil, 0, errors.New("unexpected SearchUser call")
 	}
-	return f.searchUserFn(ctx, keyword, page, pageSize)
+	return f.searchUserFn(ctx, searcherUUID, keyword, mode, page, pageSize)
 }
 
 func (f *fakeUserSvcRepo) UpdateBasicInfo(ctx context.Context, userUUID, nickname, signature, birthday string, gender int8) error {
@@ -96,25 +105,18 @@ func (f *fakeUserSvcRepo) UpdateEmail(ctx context.Context, userUUID, email strin
 	return f.updateEmailFn(ctx, userUUID, email)
 }
 
-func (f *fakeUserSvcRepo) GetQRCodeTokenByUserUUID(ctx context.Context, userUUID string) (string, time.Time, error) {
-	if f.getQRCodeByUserUUIDFn == nil {
-		return "", time.Time{}, repository.ErrRedisNil
+func (f *fakeUserSvcRepo) ExistsByPhone(ctx context.Context, telephone string) (bool, error) {
+	if f.existsByPhoneFn == nil {
+		return false, nil
 	}
-	return f.getQRCodeByUserUUIDFn(ctx, userUUID)
+	return f.existsByPhoneFn(ctx, telephone)
 }
 
-func (f *fakeUserSvcRepo) SaveQRCode(ctx context.Context, userUUID, token string) error {
-	if f.saveQRCodeFn == nil {
+func (f *fakeUserSvcRepo) UpdateTelephone(ctx context.Context, userUUID, telephone string) error {
+	if f.updateTelFn == nil {
 		return nil
 	}
-	return f.saveQRCodeFn(ctx, userUUID, token)
-}
-
-func (f *fakeUserSvcRepo) GetUUIDByQRCodeToken(ctx context.Context, token string) (string, error) {
-	if f.getUUIDByQRCodeTokenFn == nil {
-		return "", repository.ErrRedisNil
-	}
-	return f.getUUIDByQRCodeTokenFn(ctx, token)
+	return f.updateTelFn(ctx, userUUID, telephone)
 }
 
 func (f *fakeUserSvcRepo) Delete(ctx context.Context, userUUID string) error {
@@ -124,6 +126,13 @@ func (f *fakeUserSvcRepo) Delete(ctx context.Context, userUUID string) error {
 	return f.deleteFn(ctx, userUUID)
 }
 
+func (f *fakeUserSvcRepo) DeleteAccountCascade(ctx context.Context, userUUID string) error {
+	if f.deleteCascadeFn == nil {
+		return errors.New("unexpected DeleteAccountCascade call")
+	}
+	return f.deleteCascadeFn(ctx, userUUID)
+}
+
 func (f *fakeUserSvcRepo) BatchGetByUUIDs(ctx context.Context, uuids []string) ([]*model.UserInfo, error) {
 	if f.batchGetByUUIDsFn == nil {
 		return nil, errors.New("unexpected BatchGetByUUIDs call")
@@ -131,6 +140,20 @@ func (f *fakeUserSvcRepo) BatchGetByUUIDs(ctx context.Context, uuids []string) (
 	return f.batchGetByUUIDsFn(ctx, uuids)
 }
 
+func (f *fakeUserSvcRepo) UpdatePresencePrivacy(ctx context.Context, userUUID string, hidden bool, scope int8) error {
+	if f.updatePresenceFn == nil {
+		return errors.New("unexpected UpdatePresencePrivacy call")
+	}
+	return f.updatePresenceFn(ctx, userUUID, hidden, scope)
+}
+
+func (f *fakeUserSvcRepo) RebuildPresenceHiddenSet(ctx context.Context) (int, int, error) {
+	if f.rebuildHiddenFn == nil {
+		return 0, 0, errors.New("unexpected RebuildPresenceHiddenSet call")
+	}
+	return f.rebuildHiddenFn(ctx)
+}
+
 type fakeUserSvcAuthRepo struct {
 	repository.IAuthRepository
 
@@ -155,6 +178,12 @@ func (f *fakeUserSvcAuthRepo) DeleteVerifyCode(ctx context.Context, email string
 type fakeUserSvcDeviceRepo struct {
 	repository.IDeviceRepository
 	deleteByUserUUIDFn func(context.Context, string) error
+	getByUserUUIDFn    func(context.Context, string) ([]*model.DeviceSession, error)
+	deleteTokensFn     func(context.Context, string, string) error
+	bumpTokenEpochFn   func(context.Context, string, string) (int64, error)
+	bumpTokenVersionFn func(context.Context, string) (int64, error)
+	tokenVersion       int64
+	storedAccessTokens map[string]string
 }
 
 func (f *fakeUserSvcDeviceRepo) DeleteByUserUUID(ctx context.Context, userUUID string) error {
@@ -164,6 +193,51 @@ func (f *fakeUserSvcDeviceRepo) DeleteByUserUUID(ctx context.Context, userUUID s
 	return f.deleteByUserUUIDFn(ctx, userUUID)
 }
 
+func (f *fakeUserSvcDeviceRepo) GetByUserUUID(ctx context.Context, userUUID string) ([]*model.DeviceSession, error) {
+	if f.getByUserUUIDFn == nil {
+		return nil, nil
+	}
+	return f.getByUserUUIDFn(ctx, userUUID)
+}
+
+func (f *fakeUserSvcDeviceRepo) DeleteTokens(ctx context.Context, userUUID, deviceID string) error {
+	if f.deleteTokensFn == nil {
+		return nil
+	}
+	return f.deleteTokensFn(ctx, userUUID, deviceID)
+}
+
+func (f *fakeUserSvcDeviceRepo) BumpTokenEpoch(ctx context.Context, userUUID, deviceID string) (int64, error) {
+	if f.bumpTokenEpochFn == nil {
+		return 1, nil
+	}
+	return f.bumpTokenEpochFn(ctx, userUUID, deviceID)
+}
+
+func (f *fakeUserSvcDeviceRepo) BumpTokenVersion(ctx context.Context, userUUID string) (int64, error) {
+	if f.bumpTokenVersionFn == nil {
+		f.tokenVersion++
+		return f.tokenVersion, nil
+	}
+	return f.bumpTokenVersionFn(ctx, userUUID)
+}
+
+func (f *fakeUserSvcDeviceRepo) GetTokenVersion(_ context.Context, _ string) (int64, error) {
+	return f.tokenVersion, nil
+}
+
+func (f *fakeUserSvcDeviceRepo) GetTokenEpoch(_ context.Context, _, _ string) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeUserSvcDeviceRepo) StoreAccessToken(_ context.Context, _, deviceID, token string, _ time.Duration) error {
+	if f.storedAccessTokens == nil {
+		f.storedAccessTokens = map[string]string{}
+	}
+	f.storedAccessTokens[deviceID] = token
+	return nil
+}
+
 func userSvcCtx(uuid string) context.Context {
 	return context.WithValue(context.Background(), "user_uuid", uuid)
 }
@@ -190,7 +264,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 	initUserSvcTestLogger()
 
 	t.Run("get_profile_missing_user_uuid", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.GetProfile(context.Background(), &pb.GetProfileRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
@@ -202,7 +276,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 				require.Equal(t, "u1", uuid)
 				return &model.UserInfo{Uuid: "u1", Nickname: "n1"}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.GetProfile(userSvcCtx("u1"), &pb.GetProfileRequest{})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -211,52 +285,178 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 	})
 
 	t.Run("search_user_missing_user_uuid", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.SearchUser(context.Background(), &pb.SearchUserRequest{Keyword: "a", Page: 1, PageSize: 20})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
 	})
 
+	t.Run("search_user_keyword_too_short", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: " a ", Page: 1, PageSize: 20})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
 	t.Run("search_user_repo_error", func(t *testing.T) {
 		svc := NewUserService(&fakeUserSvcRepo{
-			searchUserFn: func(_ context.Context, _ string, _, _ int) ([]*model.UserInfo, int64, error) {
+			searchUserFn: func(_ context.Context, _, _ string, _ repository.SearchMode, _, _ int) ([]*model.UserInfo, int64, error) {
 				return nil, 0, errors.New("db error")
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "a", Page: 1, PageSize: 20})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "al", Page: 1, PageSize: 20})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Internal, consts.CodeInternalError)
 	})
 
 	t.Run("search_user_success", func(t *testing.T) {
 		svc := NewUserService(&fakeUserSvcRepo{
-			searchUserFn: func(_ context.Context, keyword string, page, pageSize int) ([]*model.UserInfo, int64, error) {
+			searchUserFn: func(_ context.Context, searcherUUID, keyword string, mode repository.SearchMode, page, pageSize int) ([]*model.UserInfo, int64, error) {
+				require.Equal(t, "u1", searcherUUID)
 				require.Equal(t, "alice", keyword)
+				require.Equal(t, repository.SearchModeAuto, mode)
 				require.Equal(t, 1, page)
 				require.Equal(t, 20, pageSize)
 				return []*model.UserInfo{{Uuid: "u2", Nickname: "n2"}}, 1, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "alice", Page: 1, PageSize: 20})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: " alice ", Page: 1, PageSize: 20})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
 		require.Len(t, resp.Items, 1)
 		assert.Equal(t, "u2", resp.Items[0].Uuid)
 	})
+
+	t.Run("search_user_keyword_normalized", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			searchUserFn: func(_ context.Context, _, keyword string, _ repository.SearchMode, _, _ int) ([]*model.UserInfo, int64, error) {
+				require.Equal(t, "alice smith", keyword)
+				return nil, 0, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		_, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "  alice \t\u3000 smith ", Page: 1, PageSize: 20})
+		require.NoError(t, err)
+	})
+
+	t.Run("search_user_configurable_min_keyword_len", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0,
+			config.UserSearchConfig{MinKeywordLen: 3, DefaultPageSize: 20, MaxPageSize: 100})
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "al", Page: 1, PageSize: 20})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("search_user_default_and_capped_page_size", func(t *testing.T) {
+		var gotPage, gotPageSize int
+		svc := NewUserService(&fakeUserSvcRepo{
+			searchUserFn: func(_ context.Context, _, _ string, _ repository.SearchMode, page, pageSize int) ([]*model.UserInfo, int64, error) {
+				gotPage, gotPageSize = page, pageSize
+				return nil, 45, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "al"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, gotPage)
+		assert.Equal(t, 20, gotPageSize)
+		assert.Equal(t, int32(20), resp.Pagination.PageSize)
+		assert.Equal(t, int32(3), resp.Pagination.TotalPages)
+
+		resp, err = svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "al", Page: 2, PageSize: 1000})
+		require.NoError(t, err)
+		assert.Equal(t, 100, gotPageSize)
+		assert.Equal(t, int32(100), resp.Pagination.PageSize)
+		assert.Equal(t, int32(1), resp.Pagination.TotalPages)
+	})
+
+	t.Run("search_user_modes", func(t *testing.T) {
+		// 仓储按模式返回对应行：精确模式至多一行，昵称模式可多行
+		rows := map[repository.SearchMode][]*model.UserInfo{
+			repository.SearchModeUUID:     {{Uuid: "u2000000000000000001", Nickname: "n2", Email: "bob@example.com"}},
+			repository.SearchModeEmail:    {{Uuid: "u3", Nickname: "n3", Email: "carol@example.com"}},
+			repository.SearchModeNickname: {{Uuid: "u4", Nickname: "alice"}, {Uuid: "u5", Nickname: "malice"}},
+		}
+		svc := NewUserService(&fakeUserSvcRepo{
+			searchUserFn: func(_ context.Context, _, _ string, mode repository.SearchMode, _, _ int) ([]*model.UserInfo, int64, error) {
+				return rows[mode], int64(len(rows[mode])), nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		cases := []struct {
+			searchType pb.SearchUserType
+			keyword    string
+			wantUUIDs  []string
+		}{
+			{pb.SearchUserType_SEARCH_USER_TYPE_UUID, "u2000000000000000001", []string{"u2000000000000000001"}},
+			{pb.SearchUserType_SEARCH_USER_TYPE_EMAIL, "carol@example.com", []string{"u3"}},
+			{pb.SearchUserType_SEARCH_USER_TYPE_NICKNAME, "alice", []string{"u4", "u5"}},
+		}
+		for _, tc := range cases {
+			resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: tc.keyword, SearchType: tc.searchType})
+			require.NoError(t, err, tc.searchType.String())
+			uuids := make([]string, 0, len(resp.Items))
+			for _, item := range resp.Items {
+				uuids = append(uuids, item.Uuid)
+			}
+			assert.Equal(t, tc.wantUUIDs, uuids, tc.searchType.String())
+		}
+	})
+
+	t.Run("search_user_exact_email_not_echoed", func(t *testing.T) {
+		// 邮箱精确搜索命中非好友时，结果中不出现邮箱（明文与脱敏均不回显）
+		svc := NewUserService(&fakeUserSvcRepo{
+			searchUserFn: func(_ context.Context, _, keyword string, mode repository.SearchMode, _, _ int) ([]*model.UserInfo, int64, error) {
+				require.Equal(t, repository.SearchModeEmail, mode)
+				return []*model.UserInfo{{Uuid: "u3", Nickname: "n3", Email: keyword, Telephone: "13800000000"}}, 1, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "carol@example.com", SearchType: pb.SearchUserType_SEARCH_USER_TYPE_EMAIL})
+		require.NoError(t, err)
+		require.Len(t, resp.Items, 1)
+		assert.False(t, resp.Items[0].IsFriend)
+		assert.NotContains(t, resp.Items[0].String(), "carol")
+		assert.NotContains(t, resp.Items[0].String(), "example.com")
+	})
+
+	t.Run("search_user_undefined_type", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "alice", SearchType: pb.SearchUserType(99)})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+}
+
+func TestTotalPages(t *testing.T) {
+	cases := []struct {
+		total    int64
+		pageSize int32
+		want     int32
+	}{
+		{total: 0, pageSize: 20, want: 0},
+		{total: 1, pageSize: 20, want: 1},
+		{total: 20, pageSize: 20, want: 1},
+		{total: 21, pageSize: 20, want: 2},
+		{total: 40, pageSize: 20, want: 2},
+		{total: 10, pageSize: 0, want: 0},
+		{total: 10, pageSize: -1, want: 0},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, totalPages(tc.total, tc.pageSize), "total=%d pageSize=%d", tc.total, tc.pageSize)
+	}
 }
 
 func TestUserServiceUpdateAndAvatar(t *testing.T) {
 	initUserSvcTestLogger()
 
 	t.Run("update_profile_empty_request", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.UpdateProfile(userSvcCtx("u1"), &pb.UpdateProfileRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("update_profile_birthday_format_error", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.UpdateProfile(userSvcCtx("u1"), &pb.UpdateProfileRequest{Birthday: "2026/02/06"})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeBirthdayFormatError)
@@ -272,7 +472,7 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Nickname: "new-nick"}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.UpdateProfile(userSvcCtx("u1"), &pb.UpdateProfileRequest{Nickname: "new-nick"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -280,7 +480,7 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 	})
 
 	t.Run("upload_avatar_empty_url", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.UploadAvatar(userSvcCtx("u1"), &pb.UploadAvatarRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
@@ -293,7 +493,7 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 				require.Equal(t, "https://cdn/a.png", avatar)
 				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.UploadAvatar(userSvcCtx("u1"), &pb.UploadAvatarRequest{AvatarUrl: "https://cdn/a.png"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -310,8 +510,8 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: oldHash}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "newpass123"})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		_, err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "newpass123"})
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodePasswordError)
 	})
 
@@ -320,13 +520,27 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: oldHash}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "oldpass123", NewPassword: "oldpass123"})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		_, err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "oldpass123", NewPassword: "oldpass123"})
 		requireUserSvcStatus(t, err, codes.FailedPrecondition, consts.CodePasswordSameAsOld)
 	})
 
 	t.Run("change_password_success", func(t *testing.T) {
 		updated := false
+		var revoked []string
+		deviceRepo := &fakeUserSvcDeviceRepo{
+			getByUserUUIDFn: func(_ context.Context, _ string) ([]*model.DeviceSession, error) {
+				return []*model.DeviceSession{{DeviceId: "d1"}, {DeviceId: "d2"}, {DeviceId: "d3"}}, nil
+			},
+			deleteTokensFn: func(_ context.Context, _ string, deviceID string) error {
+				revoked = append(revoked, "tokens:"+deviceID)
+				return nil
+			},
+			bumpTokenEpochFn: func(_ context.Context, _ string, deviceID string) (int64, error) {
+				revoked = append(revoked, "epoch:"+deviceID)
+				return 1, nil
+			},
+		}
 		svc := NewUserService(&fakeUserSvcRepo{
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: oldHash}, nil
@@ -337,18 +551,41 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 				require.NotEmpty(t, password)
 				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "oldpass123", NewPassword: "newpass123"})
+		}, &fakeUserSvcAuthRepo{}, deviceRepo, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		ctx := context.WithValue(userSvcCtx("u1"), util.ContextKeyDeviceID, "d1")
+		preChangeToken, err := util.GenerateTokenWithVersion("u1", "d1", 0, deviceRepo.tokenVersion)
+		require.NoError(t, err)
+
+		resp, err := svc.ChangePassword(ctx, &pb.ChangePasswordRequest{OldPassword: "oldpass123", NewPassword: "newpass123"})
 		require.NoError(t, err)
 		assert.True(t, updated)
+		// 当前设备保持登录态，其他设备 Token 删除且纪元递增
+		assert.Equal(t, []string{"tokens:d2", "epoch:d2", "tokens:d3", "epoch:d3"}, revoked)
+
+		// 令牌版本递增：修改前签发的 Token 失效，当前设备拿到新版本 Token
+		assert.Equal(t, int64(1), deviceRepo.tokenVersion)
+		oldClaims, err := util.ParseToken(preChangeToken)
+		require.NoError(t, err)
+		assert.ErrorIs(t, util.CheckTokenVersion(oldClaims, deviceRepo.tokenVersion), util.ErrTokenVersionStale)
+
+		require.NotEmpty(t, resp.AccessToken)
+		assert.Equal(t, "Bearer", resp.TokenType)
+		assert.Equal(t, resp.AccessToken, deviceRepo.storedAccessTokens["d1"])
+		newClaims, err := util.ParseToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.NoError(t, util.CheckTokenVersion(newClaims, deviceRepo.tokenVersion))
+		assert.Equal(t, "d1", newClaims.DeviceID)
 	})
 
 	t.Run("change_email_already_exists", func(t *testing.T) {
 		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				return &model.UserInfo{Uuid: "u1", Email: "old@test.com"}, nil
+			},
 			existsByEmailFn: func(_ context.Context, _ string) (bool, error) {
 				return true, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.AlreadyExists, consts.CodeEmailAlreadyExist)
@@ -356,6 +593,9 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 
 	t.Run("change_email_verify_code_expired", func(t *testing.T) {
 		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				return &model.UserInfo{Uuid: "u1", Email: "old@test.com"}, nil
+			},
 			existsByEmailFn: func(_ context.Context, _ string) (bool, error) {
 				return false, nil
 			},
@@ -363,7 +603,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
 				return false, repository.ErrRedisNil
 			},
-		}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeVerifyCodeExpire)
@@ -390,7 +630,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			deleteVerifyCodeFn: func(_ context.Context, _ string, _ int32) error {
 				return errors.New("delete code failed")
 			},
-		}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -398,61 +638,282 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 	})
 }
 
-func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
+func TestUserServiceChangeContact(t *testing.T) {
 	initUserSvcTestLogger()
 
-	t.Run("get_qrcode_existing_token", func(t *testing.T) {
-		expireAt := time.Now().Add(12 * time.Hour)
+	current := func(_ context.Context, _ string) (*model.UserInfo, error) {
+		return &model.UserInfo{Uuid: "u1", Email: "old@test.com", Telephone: "13800138000"}, nil
+	}
+
+	t.Run("change_email_same_as_old", func(t *testing.T) {
 		svc := NewUserService(&fakeUserSvcRepo{
-			getQRCodeByUserUUIDFn: func(_ context.Context, _ string) (string, time.Time, error) {
-				return "tk1", expireAt, nil
+			getByUUIDFn: current,
+			existsByEmailFn: func(_ context.Context, _ string) (bool, error) {
+				t.Fatal("相同邮箱不应再检查唯一性")
+				return false, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		resp, err := svc.GetQRCode(userSvcCtx("u1"), &pb.GetQRCodeRequest{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "OLD@test.com", VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.FailedPrecondition, consts.CodeEmailSameAsOld)
+	})
+
+	t.Run("change_email_user_not_found", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				return nil, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.NotFound, consts.CodeUserNotFound)
+	})
+
+	t.Run("change_email_duplicate_on_update", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: current,
+			updateEmailFn: func(_ context.Context, _, _ string) error {
+				return repository.ErrDuplicateKey
+			},
+		}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return true, nil
+			},
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.AlreadyExists, consts.CodeEmailAlreadyExist)
+	})
+
+	t.Run("change_telephone_invalid_format", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "12345678901", VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodePhoneFormatError)
+	})
+
+	t.Run("change_telephone_same_as_old", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{getByUUIDFn: current}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "13800138000", VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.FailedPrecondition, consts.CodeTelephoneSameAsOld)
+	})
+
+	t.Run("change_telephone_already_exists", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: current,
+			existsByPhoneFn: func(_ context.Context, telephone string) (bool, error) {
+				require.Equal(t, "13900139000", telephone)
+				return true, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "13900139000", VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.AlreadyExists, consts.CodeTelephoneAlreadyExist)
+	})
+
+	t.Run("change_telephone_verify_code_error", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{getByUUIDFn: current}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return false, nil
+			},
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "13900139000", VerifyCode: "000000"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeVerifyCodeError)
+	})
+
+	t.Run("change_telephone_success", func(t *testing.T) {
+		var updated, deleted bool
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: current,
+			updateTelFn: func(_ context.Context, userUUID, telephone string) error {
+				require.Equal(t, "u1", userUUID)
+				require.Equal(t, "13900139000", telephone)
+				updated = true
+				return nil
+			},
+		}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, target, _ string, codeType int32) (bool, error) {
+				require.Equal(t, "old@test.com", target)
+				require.Equal(t, int32(5), codeType)
+				return true, nil
+			},
+			deleteVerifyCodeFn: func(_ context.Context, target string, codeType int32) error {
+				require.Equal(t, "old@test.com", target)
+				require.Equal(t, int32(5), codeType)
+				deleted = true
+				return nil
+			},
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "13900139000", VerifyCode: "123456"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
-		assert.Equal(t, "https://www.LCchat.top/q/tk1", resp.Qrcode)
+		assert.Equal(t, "13900139000", resp.Telephone)
+		assert.True(t, updated)
+		assert.True(t, deleted)
 	})
 
-	t.Run("get_qrcode_save_error", func(t *testing.T) {
+	t.Run("change_telephone_with_sent_code", func(t *testing.T) {
+		codeRepo := &memVerifyCodeRepo{codes: map[string]string{}}
+		authSvc := NewAuthService(codeRepo, &fakeAuthDeviceRepo{}).(*authServiceImpl)
+		var mailedTo, mailedCode string
+		authSvc.sendCodeEmail = func(toEmail, code string, _ int) error {
+			mailedTo, mailedCode = toEmail, code
+			return nil
+		}
+
+		var updated string
 		svc := NewUserService(&fakeUserSvcRepo{
-			getQRCodeByUserUUIDFn: func(_ context.Context, _ string) (string, time.Time, error) {
-				return "", time.Time{}, repository.ErrRedisNil
-			},
-			saveQRCodeFn: func(_ context.Context, _, _ string) error {
-				return errors.New("save failed")
+			getByUUIDFn: current,
+			updateTelFn: func(_ context.Context, _, telephone string) error {
+				updated = telephone
+				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, codeRepo, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		_, err := authSvc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{Email: "old@test.com", Type: verifyCodeTypeChangeTelephone})
+		require.NoError(t, err)
+		require.Equal(t, "old@test.com", mailedTo)
+
+		resp, err := svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "13900139000", VerifyCode: mailedCode})
+		require.NoError(t, err)
+		assert.Equal(t, "13900139000", resp.Telephone)
+		assert.Equal(t, "13900139000", updated)
+
+		// 验证码已消耗，不能再次使用
+		_, err = svc.ChangeTelephone(userSvcCtx("u1"), &pb.ChangeTelephoneRequest{NewTelephone: "13700137000", VerifyCode: mailedCode})
+		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeVerifyCodeExpire)
+	})
+}
+
+// memVerifyCodeRepo 内存验证码存储，串联 SendVerifyCode 与换绑校验
+type memVerifyCodeRepo struct {
+	repository.IAuthRepository
+	codes map[string]string
+}
+
+func memVerifyCodeKey(target string, codeType int32) string {
+	return target + ":" + strconv.Itoa(int(codeType))
+}
+
+func (m *memVerifyCodeRepo) VerifyVerifyCodeRateLimit(_ context.Context, _, _ string) (bool, error) {
+	return false, nil
+}
+
+func (m *memVerifyCodeRepo) IncrementVerifyCodeCount(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (m *memVerifyCodeRepo) StoreVerifyCode(_ context.Context, target, verifyCode string, codeType int32, _ time.Duration) error {
+	m.codes[memVerifyCodeKey(target, codeType)] = verifyCode
+	return nil
+}
+
+func (m *memVerifyCodeRepo) VerifyVerifyCode(_ context.Context, target, verifyCode string, codeType int32) (bool, error) {
+	stored, ok := m.codes[memVerifyCodeKey(target, codeType)]
+	if !ok {
+		return false, repository.ErrRedisNil
+	}
+	return stored == verifyCode, nil
+}
+
+func (m *memVerifyCodeRepo) DeleteVerifyCode(_ context.Context, target string, codeType int32) error {
+	delete(m.codes, memVerifyCodeKey(target, codeType))
+	return nil
+}
+
+func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
+	initUserSvcTestLogger()
+
+	t.Run("get_qrcode_signed_token", func(t *testing.T) {
+		signer := utils.NewQRCodeSigner("test-secret", 48*time.Hour)
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, signer, 0, testUserSearchCfg)
 		resp, err := svc.GetQRCode(userSvcCtx("u1"), &pb.GetQRCodeRequest{})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		require.True(t, strings.HasPrefix(resp.Qrcode, qrCodeURLPrefix))
+
+		userUUID, verifyErr := signer.Verify(strings.TrimPrefix(resp.Qrcode, qrCodeURLPrefix))
+		require.NoError(t, verifyErr)
+		assert.Equal(t, "u1", userUUID)
+
+		expireAt, parseErr := time.Parse(time.RFC3339, resp.ExpireAt)
+		require.NoError(t, parseErr)
+		assert.WithinDuration(t, time.Now().Add(48*time.Hour), expireAt, 5*time.Second)
+	})
+
+	t.Run("get_qrcode_missing_user_uuid", func(t *testing.T) {
+		signer := utils.NewQRCodeSigner("test-secret", time.Hour)
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, signer, 0, testUserSearchCfg)
+		resp, err := svc.GetQRCode(context.Background(), &pb.GetQRCodeRequest{})
 		require.Nil(t, resp)
-		requireUserSvcStatus(t, err, codes.Internal, consts.CodeInternalError)
+		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
 	})
 
-	t.Run("parse_qrcode_empty_or_expired_or_success", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+	t.Run("parse_qrcode_empty_tampered_expired", func(t *testing.T) {
+		signer := utils.NewQRCodeSigner("test-secret", time.Hour)
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, signer, 0, testUserSearchCfg)
+
 		resp1, err1 := svc.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{})
 		require.Nil(t, resp1)
 		requireUserSvcStatus(t, err1, codes.InvalidArgument, consts.CodeQRCodeFormatError)
 
-		svcExpired := NewUserService(&fakeUserSvcRepo{
-			getUUIDByQRCodeTokenFn: func(_ context.Context, _ string) (string, error) {
-				return "", repository.ErrRedisNil
-			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		resp2, err2 := svcExpired.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: "tk1"})
+		token, _ := signer.Sign("u1")
+		tampered := strings.Replace(token, token[:4], "AAAA", 1)
+		resp2, err2 := svc.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: tampered})
 		require.Nil(t, resp2)
-		requireUserSvcStatus(t, err2, codes.NotFound, consts.CodeQRCodeExpired)
+		requireUserSvcStatus(t, err2, codes.InvalidArgument, consts.CodeQRCodeFormatError)
 
-		svcOK := NewUserService(&fakeUserSvcRepo{
-			getUUIDByQRCodeTokenFn: func(_ context.Context, token string) (string, error) {
-				require.Equal(t, "tk1", token)
-				return "u1", nil
+		foreign, _ := utils.NewQRCodeSigner("other-secret", time.Hour).Sign("u1")
+		resp3, err3 := svc.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: foreign})
+		require.Nil(t, resp3)
+		requireUserSvcStatus(t, err3, codes.InvalidArgument, consts.CodeQRCodeFormatError)
+
+		expired, _ := signer.SignAt("u1", time.Now().Add(-2*time.Hour))
+		resp4, err4 := svc.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: expired})
+		require.Nil(t, resp4)
+		requireUserSvcStatus(t, err4, codes.NotFound, consts.CodeQRCodeExpired)
+	})
+
+	t.Run("parse_qrcode_user_not_found", func(t *testing.T) {
+		signer := utils.NewQRCodeSigner("test-secret", time.Hour)
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				return nil, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, signer, 0, testUserSearchCfg)
+
+		token, _ := signer.Sign("u2")
+		resp, err := svc.ParseQRCode(userSvcCtx("u1"), &pb.ParseQRCodeRequest{Token: token})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.NotFound, consts.CodeUserNotFound)
+	})
+
+	t.Run("parse_qrcode_success_with_relation", func(t *testing.T) {
+		signer := utils.NewQRCodeSigner("test-secret", time.Hour)
+		friendRepo := &fakeFriendRepoForService{
+			getRelationStatusFn: func(_ context.Context, userUUID, peerUUID string) (*model.UserRelation, error) {
+				require.Equal(t, "u1", userUUID)
+				require.Equal(t, "u2", peerUUID)
+				return &model.UserRelation{UserUuid: userUUID, PeerUuid: peerUUID, Status: 0}, nil
+			},
+		}
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: func(_ context.Context, uuid string) (*model.UserInfo, error) {
+				return &model.UserInfo{Uuid: uuid, Nickname: "alice", Email: "alice@example.com"}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
-		resp3, err3 := svcOK.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: "tk1"})
-		require.NoError(t, err3)
-		require.NotNil(t, resp3)
-		assert.Equal(t, "u1", resp3.UserUuid)
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, friendRepo, &fakeApplyRepoForService{}, signer, 0, testUserSearchCfg)
+
+		token, _ := signer.Sign("u2")
+		resp, err := svc.ParseQRCode(userSvcCtx("u1"), &pb.ParseQRCodeRequest{Token: qrCodeURLPrefix + token})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, "u2", resp.UserUuid)
+		require.NotNil(t, resp.UserInfo)
+		assert.Equal(t, "alice", resp.UserInfo.Nickname)
+		assert.Equal(t, string(ProfileRelationFriend), resp.Relation)
+		assert.True(t, resp.IsFriend)
 	})
 
 	t.Run("delete_account_password_wrong_and_success", func(t *testing.T) {
@@ -461,7 +922,7 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: hash}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		respWrong, errWrong := svcWrong.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{Password: "wrong"})
 		require.Nil(t, respWrong)
 		requireUserSvcStatus(t, errWrong, codes.Unauthenticated, consts.CodePasswordError)
@@ -470,11 +931,11 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: hash}, nil
 			},
-			deleteFn: func(_ context.Context, userUUID string) error {
+			deleteCascadeFn: func(_ context.Context, userUUID string) error {
 				require.Equal(t, "u1", userUUID)
 				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 		respOK, errOK := svcOK.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{Password: "pass123456"})
 		require.NoError(t, errOK)
 		require.NotNil(t, respOK)
@@ -486,7 +947,7 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			batchGetByUUIDsFn: func(_ context.Context, _ []string) ([]*model.UserInfo, error) {
 				return []*model.UserInfo{{Uuid: "u1", Nickname: "n1"}}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
 
 		respEmpty, errEmpty := svc.BatchGetProfile(context.Background(), &pb.BatchGetProfileRequest{UserUuids: []string{}})
 		require.NoError(t, errEmpty)
@@ -508,3 +969,378 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 		assert.Equal(t, "u1", respOK.Users[0].Uuid)
 	})
 }
+
+func TestUserServiceBatchGetProfileInput(t *testing.T) {
+	initUserSvcTestLogger()
+
+	t.Run("over_cap_rejected_without_repo_call", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			batchGetByUUIDsFn: func(_ context.Context, _ []string) ([]*model.UserInfo, error) {
+				t.Fatal("超限请求不应访问仓储")
+				return nil, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		// 即使全部重复，原始数量超限也直接拒绝
+		uuids := make([]string, consts.BatchGetProfileMaxSize+1)
+		for i := range uuids {
+			uuids[i] = "u1"
+		}
+		resp, err := svc.BatchGetProfile(context.Background(), &pb.BatchGetProfileRequest{UserUuids: uuids})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("dedup_preserves_order", func(t *testing.T) {
+		var got []string
+		svc := NewUserService(&fakeUserSvcRepo{
+			batchGetByUUIDsFn: func(_ context.Context, uuids []string) ([]*model.UserInfo, error) {
+				got = uuids
+				return []*model.UserInfo{{Uuid: "u2"}, {Uuid: "u1"}}, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		uuids := make([]string, 0, consts.BatchGetProfileMaxSize)
+		for i := 0; i < consts.BatchGetProfileMaxSize/2; i++ {
+			uuids = append(uuids, "u2", " u1 ")
+		}
+		resp, err := svc.BatchGetProfile(context.Background(), &pb.BatchGetProfileRequest{UserUuids: uuids})
+		require.NoError(t, err)
+		require.Len(t, resp.Users, 2)
+		assert.Equal(t, []string{"u2", "u1"}, got)
+	})
+
+	t.Run("invalid_uuid_rejected", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			batchGetByUUIDsFn: func(_ context.Context, _ []string) ([]*model.UserInfo, error) {
+				t.Fatal("非法请求不应访问仓储")
+				return nil, nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		cases := map[string][]string{
+			"empty":       {"u1", ""},
+			"blank":       {"   "},
+			"too_long":    {strings.Repeat("1", consts.UserUUIDMaxLen+1)},
+			"bad_charset": {"u1;drop"},
+		}
+		for name, uuids := range cases {
+			t.Run(name, func(t *testing.T) {
+				resp, err := svc.BatchGetProfile(context.Background(), &pb.BatchGetProfileRequest{UserUuids: uuids})
+				require.Nil(t, resp)
+				requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+			})
+		}
+	})
+}
+
+func TestUserServiceGetOtherProfileVisibility(t *testing.T) {
+	initUserSvcTestLogger()
+
+	target := &model.UserInfo{
+		Uuid:      "u2",
+		Nickname:  "alice",
+		Email:     "alice@example.com",
+		Telephone: "13800138000",
+	}
+	userRepo := &fakeUserSvcRepo{
+		getByUUIDFn: func(_ context.Context, uuid string) (*model.UserInfo, error) {
+			require.Equal(t, "u2", uuid)
+			copied := *target
+			return &copied, nil
+		},
+	}
+
+	t.Run("stranger_masks_email_and_telephone", func(t *testing.T) {
+		svc := NewUserService(userRepo, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		resp, err := svc.GetOtherProfile(userSvcCtx("u1"), &pb.GetOtherProfileRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.Equal(t, string(ProfileRelationStranger), resp.Relation)
+		assert.False(t, resp.IsFriend)
+		assert.Equal(t, "a***@example.com", resp.UserInfo.Email)
+		assert.Equal(t, "138****8000", resp.UserInfo.Telephone)
+		assert.Equal(t, "alice", resp.UserInfo.Nickname)
+	})
+
+	t.Run("pending_shows_email_masks_telephone", func(t *testing.T) {
+		applyRepo := &fakeApplyRepoForService{
+			existsPendingReqFn: func(_ context.Context, applicant, targetUUID string) (bool, error) {
+				// 对方向当前用户发起了申请
+				return applicant == "u2" && targetUUID == "u1", nil
+			},
+		}
+		svc := NewUserService(userRepo, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, applyRepo, nil, 0, testUserSearchCfg)
+
+		resp, err := svc.GetOtherProfile(userSvcCtx("u1"), &pb.GetOtherProfileRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.Equal(t, string(ProfileRelationPending), resp.Relation)
+		assert.False(t, resp.IsFriend)
+		assert.Equal(t, "alice@example.com", resp.UserInfo.Email)
+		assert.Equal(t, "138****8000", resp.UserInfo.Telephone)
+	})
+
+	t.Run("friend_shows_all_fields", func(t *testing.T) {
+		friendRepo := &fakeFriendRepoForService{
+			getRelationStatusFn: func(_ context.Context, userUUID, peerUUID string) (*model.UserRelation, error) {
+				require.Equal(t, "u1", userUUID)
+				require.Equal(t, "u2", peerUUID)
+				return &model.UserRelation{UserUuid: userUUID, PeerUuid: peerUUID, Status: 0}, nil
+			},
+		}
+		svc := NewUserService(userRepo, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, friendRepo, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		resp, err := svc.GetOtherProfile(userSvcCtx("u1"), &pb.GetOtherProfileRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.Equal(t, string(ProfileRelationFriend), resp.Relation)
+		assert.True(t, resp.IsFriend)
+		assert.Equal(t, "alice@example.com", resp.UserInfo.Email)
+		assert.Equal(t, "13800138000", resp.UserInfo.Telephone)
+	})
+
+	t.Run("blacklist_treated_as_stranger", func(t *testing.T) {
+		friendRepo := &fakeFriendRepoForService{
+			getRelationStatusFn: func(_ context.Context, userUUID, peerUUID string) (*model.UserRelation, error) {
+				return &model.UserRelation{UserUuid: userUUID, PeerUuid: peerUUID, Status: 1}, nil
+			},
+		}
+		svc := NewUserService(userRepo, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, friendRepo, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		resp, err := svc.GetOtherProfile(userSvcCtx("u1"), &pb.GetOtherProfileRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.Equal(t, string(ProfileRelationStranger), resp.Relation)
+		assert.Equal(t, "a***@example.com", resp.UserInfo.Email)
+	})
+
+	t.Run("relation_lookup_error_downgrades_to_stranger", func(t *testing.T) {
+		friendRepo := &fakeFriendRepoForService{
+			getRelationStatusFn: func(_ context.Context, _, _ string) (*model.UserRelation, error) {
+				return nil, errors.New("redis down")
+			},
+		}
+		svc := NewUserService(userRepo, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, friendRepo, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+
+		resp, err := svc.GetOtherProfile(userSvcCtx("u1"), &pb.GetOtherProfileRequest{UserUuid: "u2"})
+		require.NoError(t, err)
+		assert.Equal(t, string(ProfileRelationStranger), resp.Relation)
+		assert.Equal(t, "138****8000", resp.UserInfo.Telephone)
+	})
+}
+
+func TestUserServiceDeleteAccount(t *testing.T) {
+	user := func(_ context.Context, _ string) (*model.UserInfo, error) {
+		return &model.UserInfo{Uuid: "u1", Email: "a@test.com", Password: "hash"}, nil
+	}
+
+	t.Run("no_confirmation", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{getByUUIDFn: user}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{Reason: "bye"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("verify_code_wrong", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{getByUUIDFn: user}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return false, nil
+			},
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeVerifyCodeError)
+	})
+
+	t.Run("verify_code_success_cascade_and_grace", func(t *testing.T) {
+		var (
+			cascaded      bool
+			devicesKicked bool
+			versionBumped bool
+			deletedCode   int32
+		)
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: user,
+			deleteCascadeFn: func(_ context.Context, userUUID string) error {
+				assert.Equal(t, "u1", userUUID)
+				cascaded = true
+				return nil
+			},
+		}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, email, code string, codeType int32) (bool, error) {
+				assert.Equal(t, "a@test.com", email)
+				assert.Equal(t, "123456", code)
+				assert.Equal(t, verifyCodeTypeDeleteAccount, codeType)
+				return true, nil
+			},
+			deleteVerifyCodeFn: func(_ context.Context, _ string, codeType int32) error {
+				deletedCode = codeType
+				return nil
+			},
+		}, &fakeUserSvcDeviceRepo{
+			deleteByUserUUIDFn: func(_ context.Context, userUUID string) error {
+				assert.Equal(t, "u1", userUUID)
+				devicesKicked = true
+				return nil
+			},
+			bumpTokenVersionFn: func(_ context.Context, userUUID string) (int64, error) {
+				assert.Equal(t, "u1", userUUID)
+				versionBumped = true
+				return 1, nil
+			},
+		}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 7*24*time.Hour, testUserSearchCfg)
+
+		resp, err := svc.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{VerifyCode: "123456"})
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.True(t, cascaded)
+		assert.True(t, devicesKicked)
+		assert.True(t, versionBumped, "注销后该用户已签发的 Access Token 全局失效")
+		assert.Equal(t, verifyCodeTypeDeleteAccount, deletedCode)
+
+		deleteAt, err := time.Parse(time.RFC3339, resp.DeleteAt)
+		require.NoError(t, err)
+		deadline, err := time.Parse(time.RFC3339, resp.RecoverDeadline)
+		require.NoError(t, err)
+		assert.Equal(t, 7*24*time.Hour, deadline.Sub(deleteAt))
+	})
+
+	t.Run("cascade_not_found", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: user,
+			deleteCascadeFn: func(_ context.Context, _ string) error {
+				return repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return true, nil
+			},
+		}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.NotFound, consts.CodeUserNotFound)
+	})
+
+	t.Run("cascade_error_skips_device_cleanup", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			getByUUIDFn: user,
+			deleteCascadeFn: func(_ context.Context, _ string) error {
+				return errors.New("tx failed")
+			},
+		}, &fakeUserSvcAuthRepo{
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return true, nil
+			},
+		}, &fakeUserSvcDeviceRepo{
+			deleteByUserUUIDFn: func(_ context.Context, _ string) error {
+				t.Fatal("device cleanup should not run when cascade fails")
+				return nil
+			},
+		}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{VerifyCode: "123456"})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
+
+func TestUserServiceSetPresenceVisibility(t *testing.T) {
+	initUserSvcTestLogger()
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SetPresenceVisibility(context.Background(), &pb.SetPresenceVisibilityRequest{Hidden: true})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("user_not_found", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			updatePresenceFn: func(context.Context, string, bool, int8) error { return repository.ErrRecordNotFound },
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SetPresenceVisibility(userSvcCtx("u1"), &pb.SetPresenceVisibilityRequest{Hidden: true})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.NotFound, consts.CodeUserNotFound)
+	})
+
+	t.Run("repo_error", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			updatePresenceFn: func(context.Context, string, bool, int8) error { return errors.New("db down") },
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SetPresenceVisibility(userSvcCtx("u1"), &pb.SetPresenceVisibilityRequest{})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.Internal, consts.CodeInternalError)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			updatePresenceFn: func(_ context.Context, userUUID string, hidden bool, scope int8) error {
+				require.Equal(t, "u1", userUUID)
+				require.True(t, hidden)
+				require.Equal(t, model.PresenceHideScopeEveryone, scope)
+				return nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.SetPresenceVisibility(userSvcCtx("u1"), &pb.SetPresenceVisibilityRequest{Hidden: true})
+		require.NoError(t, err)
+		assert.True(t, resp.Hidden)
+	})
+}
+
+func TestUserServiceUpdatePrivacy(t *testing.T) {
+	initUserSvcTestLogger()
+
+	t.Run("invalid_scope", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.UpdatePrivacy(userSvcCtx("u1"), &pb.UpdatePrivacyRequest{
+			Privacy: &pb.PrivacySettings{HidePresence: true, PresenceHideScope: 2},
+		})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("missing_privacy", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.UpdatePrivacy(userSvcCtx("u1"), &pb.UpdatePrivacyRequest{})
+		require.Nil(t, resp)
+		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("hide_from_non_friends", func(t *testing.T) {
+		svc := NewUserService(&fakeUserSvcRepo{
+			updatePresenceFn: func(_ context.Context, userUUID string, hidden bool, scope int8) error {
+				require.Equal(t, "u1", userUUID)
+				require.True(t, hidden)
+				require.Equal(t, model.PresenceHideScopeNonFriends, scope)
+				return nil
+			},
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, &fakeFriendRepoForService{}, &fakeApplyRepoForService{}, nil, 0, testUserSearchCfg)
+		resp, err := svc.UpdatePrivacy(userSvcCtx("u1"), &pb.UpdatePrivacyRequest{
+			Privacy: &pb.PrivacySettings{HidePresence: true, PresenceHideScope: 1},
+		})
+		require.NoError(t, err)
+		assert.True(t, resp.Privacy.HidePresence)
+		assert.Equal(t, int32(1), resp.Privacy.PresenceHideScope)
+	})
+}
+
+func TestStartPresenceHiddenRebuilder(t *testing.T) {
+	initUserSvcTestLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := make(chan struct{}, 8)
+	repo := &fakeUserSvcRepo{
+		rebuildHiddenFn: func(context.Context) (int, int, error) {
+			calls <- struct{}{}
+			return 1, 0, nil
+		},
+	}
+	StartPresenceHiddenRebuilder(ctx, repo, 10*time.Millisecond)
+
+	// 启动时立即校准一次，之后按周期校准
+	for i := 0; i < 2; i++ {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatalf("第 %d 次校准未执行", i+1)
+		}
+	}
+}
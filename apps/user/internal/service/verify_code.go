@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"ChatServer/apps/user/internal/repository"
+	"ChatServer/pkg/util"
+)
+
+// verifyCodeMatches 按验证码类型的格式规范（长度/字符集）校验输入后，再比对 Redis 中的验证码。
+// 格式不符直接视为验证码错误，不访问 Redis；字母数字验证码不区分大小写。
+func verifyCodeMatches(ctx context.Context, authRepo repository.IAuthRepository, target, verifyCode string, codeType int32) (bool, error) {
+	spec := util.VerifyCodeSpecFor(codeType)
+	verifyCode = spec.Normalize(verifyCode)
+	if !spec.Valid(verifyCode) {
+		return false, nil
+	}
+	return authRepo.VerifyVerifyCode(ctx, target, verifyCode, codeType)
+}
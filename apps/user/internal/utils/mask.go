@@ -1,30 +1,6 @@
 package utils
 
-import "strings"
-
-// MaskPhone 手机号脱敏
-// 示例：13812345678 -> 138****5678
-func MaskPhone(phone string) string {
-	if len(phone) != 11 {
-		return "***"
-	}
-	return phone[:3] + "****" + phone[7:]
-}
-
-// MaskEmail 邮箱脱敏
-// 示例：test@example.com -> t***@example.com
-func MaskEmail(email string) string {
-	parts := strings.Split(email, "@")
-	if len(parts) != 2 || len(parts[0]) == 0 {
-		return "***"
-	}
-	
-	if len(parts[0]) == 1 {
-		return "*@" + parts[1]
-	}
-	
-	return parts[0][:1] + "***@" + parts[1]
-}
+// 手机号/邮箱脱敏统一使用 pkg/util.MaskTelephone / pkg/util.MaskEmail（与 gateway 共用实现）。
 
 // MaskName 姓名脱敏
 // 示例：张三 -> 张*，张三丰 -> 张**
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrQRCodeMalformed 二维码 token 格式错误或签名不匹配（被篡改）
+	ErrQRCodeMalformed = errors.New("qrcode token malformed")
+	// ErrQRCodeExpired 二维码 token 已过期
+	ErrQRCodeExpired = errors.New("qrcode token expired")
+)
+
+// qrCodeClockSkew 允许的签发时间超前量（多实例间时钟偏差）
+const qrCodeClockSkew = time.Minute
+
+// QRCodeSigner 用户二维码 token 签名器
+// token 格式：base64url(userUUID:issuedAtUnix) + "." + base64url(HMAC-SHA256(payload))
+// token 自包含用户与签发时间，服务端无需存储即可校验真伪与有效期。
+type QRCodeSigner struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewQRCodeSigner 创建二维码签名器
+func NewQRCodeSigner(secret string, ttl time.Duration) *QRCodeSigner {
+	return &QRCodeSigner{
+		secret: []byte(secret),
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Sign 为用户签发二维码 token，返回 token 与过期时间
+func (s *QRCodeSigner) Sign(userUUID string) (string, time.Time) {
+	return s.SignAt(userUUID, s.now())
+}
+
+// SignAt 以指定签发时间签发二维码 token（精度为秒）
+func (s *QRCodeSigner) SignAt(userUUID string, issuedAt time.Time) (string, time.Time) {
+	payload := userUUID + ":" + strconv.FormatInt(issuedAt.Unix(), 10)
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.mac(payload))
+	return token, time.Unix(issuedAt.Unix(), 0).Add(s.ttl)
+}
+
+// Verify 校验 token 签名与有效期，返回 token 所属用户 UUID
+//   - ErrQRCodeMalformed: 格式错误或签名不匹配
+//   - ErrQRCodeExpired: 签名有效但已过期
+func (s *QRCodeSigner) Verify(token string) (string, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrQRCodeMalformed
+	}
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrQRCodeMalformed
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", ErrQRCodeMalformed
+	}
+	payload := string(payloadBytes)
+	if !hmac.Equal(sig, s.mac(payload)) {
+		return "", ErrQRCodeMalformed
+	}
+
+	idx := strings.LastIndex(payload, ":")
+	if idx <= 0 {
+		return "", ErrQRCodeMalformed
+	}
+	userUUID := payload[:idx]
+	issuedUnix, err := strconv.ParseInt(payload[idx+1:], 10, 64)
+	if err != nil {
+		return "", ErrQRCodeMalformed
+	}
+
+	issuedAt := time.Unix(issuedUnix, 0)
+	now := s.now()
+	if issuedAt.After(now.Add(qrCodeClockSkew)) {
+		return "", ErrQRCodeMalformed
+	}
+	if !now.Before(issuedAt.Add(s.ttl)) {
+		return "", ErrQRCodeExpired
+	}
+	return userUUID, nil
+}
+
+func (s *QRCodeSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,58 @@
+package mq
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// ==================== 重试退避策略 ====================
+
+// RetryBackoff 重试任务的指数退避配置
+// 第 n 次重试的基准延迟为 Base * 2^(n-1)，不超过 Max；
+// 实际延迟在 [基准/2, 基准] 内随机（等值抖动），避免大量任务在同一时刻集中重试。
+// Base<=0 表示不退避（立即重试）。
+type RetryBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// backoffJitter 返回 [0, n) 的随机数（测试中可替换为固定值）
+var backoffJitter = func(n int64) int64 { return rand.Int64N(n) }
+
+// Delay 计算第 attempt 次重试（从 1 开始）的等待时间
+func (b RetryBackoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 || attempt <= 0 {
+		return 0
+	}
+	limit := b.Max
+	if limit < b.Base {
+		limit = b.Base
+	}
+
+	delay := b.Base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	half := delay / 2
+	return half + time.Duration(backoffJitter(int64(delay-half)+1))
+}
+
+// retryWait 返回距 notBefore 还需等待的时间，最长 maxWait（防止异常时间戳让任务长期滞留）
+// 已到期或未设置时返回 0。
+func retryWait(notBefore time.Time, maxWait time.Duration) time.Duration {
+	if notBefore.IsZero() {
+		return 0
+	}
+	wait := time.Until(notBefore)
+	if wait <= 0 {
+		return 0
+	}
+	if maxWait > 0 && wait > maxWait {
+		wait = maxWait
+	}
+	return wait
+}
@@ -0,0 +1,133 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withJitter 将抖动固定为 fn 的返回值，测试结束后恢复
+func withJitter(t *testing.T, fn func(n int64) int64) {
+	t.Helper()
+	orig := backoffJitter
+	backoffJitter = fn
+	t.Cleanup(func() { backoffJitter = orig })
+}
+
+func TestRetryBackoffDelay_ExponentialAndCapped(t *testing.T) {
+	// 抖动取最大值：实际延迟 = 基准延迟
+	withJitter(t, func(n int64) int64 { return n - 1 })
+	b := RetryBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, b.Delay(2))
+	assert.Equal(t, 400*time.Millisecond, b.Delay(3))
+	assert.Equal(t, 800*time.Millisecond, b.Delay(4))
+	assert.Equal(t, time.Second, b.Delay(5))
+	assert.Equal(t, time.Second, b.Delay(64))
+}
+
+func TestRetryBackoffDelay_JitterRange(t *testing.T) {
+	withJitter(t, func(n int64) int64 { return 0 })
+	b := RetryBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+	assert.Equal(t, 100*time.Millisecond, b.Delay(2)) // 基准 200ms 的下界
+
+	backoffJitter = func(n int64) int64 { return n / 2 }
+	d := b.Delay(3)
+	assert.GreaterOrEqual(t, d, 200*time.Millisecond)
+	assert.LessOrEqual(t, d, 400*time.Millisecond)
+}
+
+func TestRetryBackoffDelay_Disabled(t *testing.T) {
+	assert.Zero(t, RetryBackoff{}.Delay(3))
+	assert.Zero(t, RetryBackoff{Base: time.Second, Max: time.Minute}.Delay(0))
+}
+
+func TestRedisRetryConsumer_RequeueSetsNextRetryAt(t *testing.T) {
+	withJitter(t, func(n int64) int64 { return n - 1 })
+	retry := &fakeTaskPublisher{}
+	c := &RedisRetryConsumer{producer: retry, dlqProducer: &fakeTaskPublisher{}, logger: nopKafkaLogger{}}
+	c.SetRetryBackoff(RetryBackoff{Base: time.Second, Max: 10 * time.Second})
+
+	before := time.Now()
+	require.Error(t, c.processMessage(context.Background(), unknownTask(1, 3)))
+	require.Len(t, retry.sent, 1)
+
+	var task RedisTask
+	require.NoError(t, json.Unmarshal(retry.sent[0], &task))
+	assert.Equal(t, 2, task.RetryCount)
+	// 退避只看 Attempt：生产端首次投递的任务 RetryCount=1 但尚未经过消费者退避，第 1 次退避为 1s
+	assert.Equal(t, 1, task.Attempt)
+	assert.WithinDuration(t, before.Add(time.Second), task.NextRetryAt, 500*time.Millisecond)
+
+	// 再次失败：第 2 次退避 1s * 2^1 = 2s
+	retry.sent = nil
+	before = time.Now()
+	task.NextRetryAt = time.Time{}
+	data, _ := json.Marshal(task)
+	require.Error(t, c.processMessage(context.Background(), data))
+	require.Len(t, retry.sent, 1)
+	require.NoError(t, json.Unmarshal(retry.sent[0], &task))
+	assert.Equal(t, 3, task.RetryCount)
+	assert.Equal(t, 2, task.Attempt)
+	assert.WithinDuration(t, before.Add(2*time.Second), task.NextRetryAt, 500*time.Millisecond)
+}
+
+func TestRetryWait(t *testing.T) {
+	assert.Zero(t, retryWait(time.Time{}, time.Second))
+	assert.Zero(t, retryWait(time.Now().Add(-time.Second), time.Second))
+	assert.Equal(t, 20*time.Millisecond, retryWait(time.Now().Add(time.Hour), 20*time.Millisecond), "wait should be capped by maxWait")
+
+	wait := retryWait(time.Now().Add(time.Second), time.Minute)
+	assert.Greater(t, wait, 900*time.Millisecond)
+	assert.LessOrEqual(t, wait, time.Second)
+}
+
+// dueTask 构造一个 NextRetryAt 在 wait 之后的任务（未知命令类型，执行必然失败）。
+func dueTask(wait time.Duration) []byte {
+	var task RedisTask
+	_ = json.Unmarshal(unknownTask(1, 3), &task)
+	task.NextRetryAt = time.Now().Add(wait)
+	data, _ := json.Marshal(task)
+	return data
+}
+
+func TestRedisRetryConsumer_NotDueTaskIsDeferredWithoutBlocking(t *testing.T) {
+	retry := &fakeTaskPublisher{}
+	dlq := &fakeTaskPublisher{}
+	c := &RedisRetryConsumer{producer: retry, dlqProducer: dlq, logger: nopKafkaLogger{}}
+	c.SetRetryBackoff(RetryBackoff{Base: time.Second, Max: 10 * time.Second})
+
+	message := dueTask(100 * time.Millisecond)
+	start := time.Now()
+	require.NoError(t, c.processMessage(context.Background(), message))
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "未到期任务不应阻塞消费循环")
+	assert.Zero(t, retry.sentCount(), "未到期前不重新入队")
+
+	// 到期后原消息重新入队，不执行、不增加重试次数
+	require.Eventually(t, func() bool { return retry.sentCount() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, message, retry.sent[0])
+	assert.Empty(t, dlq.sent)
+}
+
+func TestRedisRetryConsumer_FlushDeferredOnClose(t *testing.T) {
+	retry := &fakeTaskPublisher{}
+	c := &RedisRetryConsumer{producer: retry, logger: nopKafkaLogger{}}
+	c.SetRetryBackoff(RetryBackoff{Base: time.Second, Max: time.Hour})
+
+	message := dueTask(time.Hour)
+	require.NoError(t, c.processMessage(context.Background(), message))
+	assert.Zero(t, retry.sentCount())
+
+	c.flushDeferred()
+	require.Equal(t, 1, retry.sentCount())
+	assert.Equal(t, message, retry.sent[0])
+
+	// 关闭后新的未到期任务立即写回
+	require.NoError(t, c.processMessage(context.Background(), dueTask(time.Hour)))
+	assert.Equal(t, 2, retry.sentCount())
+}
@@ -0,0 +1,48 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ==================== 死信队列定义 ====================
+
+// DeadLetterTask 死信队列消息体
+// 当 RedisTask 重试次数耗尽时，由消费者投递到死信 topic，
+// 保留原始任务（命令/参数/来源）与最后一次失败原因，便于人工排查与重放。
+type DeadLetterTask struct {
+	Task      RedisTask `json:"task"`             // 原始任务（含命令、参数、来源等）
+	Source    string    `json:"source,omitempty"` // 操作来源（冗余自 Task.Source，便于检索）
+	LastError string    `json:"last_error"`       // 最后一次执行失败的错误信息
+	Errors    []string  `json:"errors,omitempty"` // 完整错误历史：首次失败原因 + 每次重试的失败原因
+	Attempts  int       `json:"attempts"`         // 总执行次数（首次执行 + 重试次数）
+	DeadAt    time.Time `json:"dead_at"`          // 进入死信队列的时间
+}
+
+// BuildDeadLetterTask 根据重试耗尽的任务构造死信消息
+func BuildDeadLetterTask(task RedisTask, lastErr error) DeadLetterTask {
+	dead := DeadLetterTask{
+		Task:     task,
+		Source:   task.Source,
+		Attempts: task.RetryCount + 1,
+		DeadAt:   time.Now(),
+	}
+	if lastErr != nil {
+		dead.LastError = lastErr.Error()
+	}
+	if task.OriginalErr != "" {
+		dead.Errors = append(dead.Errors, task.OriginalErr)
+	}
+	dead.Errors = append(dead.Errors, task.ErrorHistory...)
+	return dead
+}
+
+// DecodeDeadLetterTask 解析死信队列消息
+func DecodeDeadLetterTask(data []byte) (DeadLetterTask, error) {
+	var dead DeadLetterTask
+	if err := json.Unmarshal(data, &dead); err != nil {
+		return DeadLetterTask{}, fmt.Errorf("解析死信任务失败: %w", err)
+	}
+	return dead, nil
+}
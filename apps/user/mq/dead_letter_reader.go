@@ -0,0 +1,45 @@
+package mq
+
+import (
+	"ChatServer/pkg/kafka"
+	"context"
+)
+
+// ==================== 死信队列读取器 ====================
+
+// DeadLetterReader 死信队列只读读取器（供运维排查使用）
+// 不提交 offset，不会消费或删除死信消息。
+type DeadLetterReader struct {
+	inspector *kafka.Inspector
+}
+
+// NewDeadLetterReader 创建死信队列读取器
+func NewDeadLetterReader(brokers []string, topic, groupID string) *DeadLetterReader {
+	return &DeadLetterReader{
+		inspector: kafka.NewInspector(brokers, topic, groupID),
+	}
+}
+
+// List 读取至多 limit 条死信任务
+// source 非空时只返回该来源的任务（limit 按读取条数计算，而非过滤后的条数）。
+// 无法解析的消息会被跳过并计入 skipped。
+func (r *DeadLetterReader) List(ctx context.Context, limit int, source string) (tasks []DeadLetterTask, skipped int, err error) {
+	_, err = r.inspector.Read(ctx, limit, func(ctx context.Context, message []byte) error {
+		dead, decodeErr := DecodeDeadLetterTask(message)
+		if decodeErr != nil {
+			skipped++
+			return nil
+		}
+		if source != "" && dead.Source != source {
+			return nil
+		}
+		tasks = append(tasks, dead)
+		return nil
+	})
+	return tasks, skipped, err
+}
+
+// Close 关闭读取器
+func (r *DeadLetterReader) Close() error {
+	return r.inspector.Close()
+}
@@ -44,5 +44,9 @@ func SendRedisTask(ctx context.Context, task RedisTask) error {
 		return err
 	}
 
-	return producer.Send(ctx, data)
+	if err := producer.Send(ctx, data); err != nil {
+		return err
+	}
+	kafka.RecordRetryTask(kafka.RetryStagePublished, task.Source, string(task.Type))
+	return nil
 }
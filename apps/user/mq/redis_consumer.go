@@ -5,27 +5,44 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 // ==================== Redis 重试消费者 ====================
 
+// TaskPublisher 任务投递接口（*kafka.Producer 已实现）
+// 抽象出来便于在测试中替换为内存实现。
+type TaskPublisher interface {
+	Send(ctx context.Context, data []byte) error
+}
+
 // RedisRetryConsumer Redis 重试队列消费者
 type RedisRetryConsumer struct {
 	consumer    *kafka.Consumer
 	redisClient *redis.Client
-	producer    *kafka.Producer
+	producer    TaskPublisher // 重试队列 Producer（未耗尽的任务重新入队）
+	dlqProducer TaskPublisher // 死信队列 Producer（可为 nil，此时仅记录日志）
+	backoff     RetryBackoff  // 重试退避策略（零值表示立即重试）
 	logger      kafka.Logger
+
+	// deferred 未到退避时间、等待到期后重新入队的任务（timer -> 原始消息）
+	deferMu  sync.Mutex
+	deferred map[*time.Timer][]byte
+	closed   bool
 }
 
 // NewRedisRetryConsumer 创建 Redis 重试队列消费者
+// dlqProducer 可为 nil：重试耗尽的任务将仅记录错误日志。
 func NewRedisRetryConsumer(
 	brokers []string,
 	topic string,
 	groupID string,
 	redisClient *redis.Client,
-	producer *kafka.Producer,
+	producer TaskPublisher,
+	dlqProducer TaskPublisher,
 	logger kafka.Logger,
 ) *RedisRetryConsumer {
 	consumer := kafka.NewConsumer(brokers, topic, groupID)
@@ -33,10 +50,17 @@ func NewRedisRetryConsumer(
 		consumer:    consumer,
 		redisClient: redisClient,
 		producer:    producer,
+		dlqProducer: dlqProducer,
 		logger:      logger,
 	}
 }
 
+// SetRetryBackoff 设置重试退避策略
+// 应在 Start 之前调用。
+func (c *RedisRetryConsumer) SetRetryBackoff(backoff RetryBackoff) {
+	c.backoff = backoff
+}
+
 // Start 启动消费者（阻塞式运行）
 func (c *RedisRetryConsumer) Start(ctx context.Context) error {
 	c.logger.Info(ctx, "Redis 重试队列消费者启动", nil)
@@ -47,7 +71,9 @@ func (c *RedisRetryConsumer) Start(ctx context.Context) error {
 }
 
 // Close 关闭消费者
+// 尚未到期的延迟任务立即写回重试队列（由下次消费继续等待），需在重试队列 Producer 关闭之前调用。
 func (c *RedisRetryConsumer) Close() error {
+	c.flushDeferred()
 	return c.consumer.Close()
 }
 
@@ -59,6 +85,14 @@ func (c *RedisRetryConsumer) processMessage(ctx context.Context, message []byte)
 		return fmt.Errorf("解析 Redis 任务失败: %w", err)
 	}
 
+	// 未到退避时间：不在消费循环中等待（会阻塞整个分区），到期后将原消息重新入队，当前 offset 正常提交
+	if wait := retryWait(task.NextRetryAt, c.backoff.Max); wait > 0 {
+		c.deferRetry(task, message, wait)
+		return nil
+	}
+
+	kafka.RecordRetryTask(kafka.RetryStageConsumed, task.Source, string(task.Type))
+
 	c.logger.Info(ctx, "处理 Redis 重试任务", map[string]interface{}{
 		"type":        task.Type,
 		"retry_count": task.RetryCount,
@@ -68,9 +102,17 @@ func (c *RedisRetryConsumer) processMessage(ctx context.Context, message []byte)
 	// 执行 Redis 操作
 	err := c.executeRedisTask(ctx, task)
 	if err != nil {
+		task.ErrorHistory = appendErrorHistory(task.ErrorHistory, err)
+
 		// 如果还没达到最大重试次数，重新发送到 Kafka
 		if task.RetryCount < task.MaxRetries {
 			task.RetryCount++
+			task.Attempt++
+			delay := c.backoff.Delay(task.Attempt)
+			task.NextRetryAt = time.Time{}
+			if delay > 0 {
+				task.NextRetryAt = time.Now().Add(delay)
+			}
 			taskJSON, _ := json.Marshal(task)
 			if retryErr := c.producer.Send(ctx, taskJSON); retryErr != nil {
 				c.logger.Error(ctx, "重新发送 Redis 任务到 Kafka 失败", map[string]interface{}{
@@ -78,23 +120,22 @@ func (c *RedisRetryConsumer) processMessage(ctx context.Context, message []byte)
 					"retry_count": task.RetryCount,
 				})
 			} else {
+				kafka.RecordRetryTask(kafka.RetryStageRetried, task.Source, string(task.Type))
 				c.logger.Info(ctx, "Redis 任务重新发送到队列", map[string]interface{}{
 					"retry_count": task.RetryCount,
 					"max_retries": task.MaxRetries,
+					"delay":       delay.String(),
 				})
 			}
 		} else {
-			// 达到最大重试次数，记录错误并放弃
-			c.logger.Error(ctx, "Redis 任务达到最大重试次数，放弃处理", map[string]interface{}{
-				"error":       err.Error(),
-				"retry_count": task.RetryCount,
-				"max_retries": task.MaxRetries,
-				"task":        task,
-			})
+			// 达到最大重试次数，投递到死信队列
+			kafka.ObserveRetryRecovery(kafka.RetryStageDeadLettered, task.Source, string(task.Type), task.Timestamp)
+			c.sendToDeadLetter(ctx, task, err)
 		}
 		return err
 	}
 
+	kafka.ObserveRetryRecovery(kafka.RetryStageSucceeded, task.Source, string(task.Type), task.Timestamp)
 	c.logger.Info(ctx, "Redis 重试任务执行成功", map[string]interface{}{
 		"type":        task.Type,
 		"retry_count": task.RetryCount,
@@ -102,6 +143,116 @@ func (c *RedisRetryConsumer) processMessage(ctx context.Context, message []byte)
 	return nil
 }
 
+// deferRetry 在 wait 后将原消息（携带 next_retry_at）重新投递到重试队列。
+// 消费者已关闭时立即投递。
+func (c *RedisRetryConsumer) deferRetry(task RedisTask, message []byte, wait time.Duration) {
+	c.deferMu.Lock()
+	defer c.deferMu.Unlock()
+	if c.closed {
+		c.requeue(task, message)
+		return
+	}
+	if c.deferred == nil {
+		c.deferred = make(map[*time.Timer][]byte)
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		c.deferMu.Lock()
+		_, pending := c.deferred[timer]
+		delete(c.deferred, timer)
+		c.deferMu.Unlock()
+		if pending {
+			c.requeue(task, message)
+		}
+	})
+	c.deferred[timer] = message
+}
+
+// flushDeferred 停止所有延迟计时器，并将未到期的任务立即写回重试队列。
+func (c *RedisRetryConsumer) flushDeferred() {
+	c.deferMu.Lock()
+	c.closed = true
+	pending := make([][]byte, 0, len(c.deferred))
+	for timer, message := range c.deferred {
+		// Stop 失败说明计时器已触发，由其回调负责重新入队
+		if timer.Stop() {
+			delete(c.deferred, timer)
+			pending = append(pending, message)
+		}
+	}
+	c.deferMu.Unlock()
+
+	for _, message := range pending {
+		var task RedisTask
+		_ = json.Unmarshal(message, &task)
+		c.requeue(task, message)
+	}
+}
+
+// requeue 将原消息重新投递到重试队列，失败时记录完整任务以便从日志恢复。
+func (c *RedisRetryConsumer) requeue(task RedisTask, message []byte) {
+	ctx := context.Background()
+	if err := c.producer.Send(ctx, message); err != nil {
+		c.logger.Error(ctx, "延迟 Redis 任务重新入队失败", map[string]interface{}{
+			"error":       err.Error(),
+			"retry_count": task.RetryCount,
+			"source":      task.Source,
+			"task":        task,
+		})
+	}
+}
+
+// deadLetterTopic 返回死信队列 topic（用于指标标签），死信 Producer 不提供 topic 时返回空串
+func (c *RedisRetryConsumer) deadLetterTopic() string {
+	if p, ok := c.dlqProducer.(interface{ Topic() string }); ok {
+		return p.Topic()
+	}
+	return ""
+}
+
+// sendToDeadLetter 将重试耗尽的任务投递到死信队列
+// 投递失败（或未配置死信队列）时记录完整任务到错误日志，保证至少可从日志中恢复。
+func (c *RedisRetryConsumer) sendToDeadLetter(ctx context.Context, task RedisTask, lastErr error) {
+	dead := BuildDeadLetterTask(task, lastErr)
+	fields := map[string]interface{}{
+		"error":       dead.LastError,
+		"retry_count": task.RetryCount,
+		"max_retries": task.MaxRetries,
+		"source":      task.Source,
+		"task":        task,
+	}
+
+	if c.dlqProducer == nil {
+		c.logger.Error(ctx, "Redis 任务达到最大重试次数，未配置死信队列，放弃处理", fields)
+		return
+	}
+
+	deadJSON, marshalErr := json.Marshal(dead)
+	if marshalErr != nil {
+		kafka.RecordDeadLetter(c.deadLetterTopic(), kafka.DeadLetterReasonRetriesExhausted, marshalErr)
+		fields["dlq_error"] = marshalErr.Error()
+		c.logger.Error(ctx, "序列化死信任务失败，放弃处理", fields)
+		return
+	}
+
+	sendErr := c.dlqProducer.Send(ctx, deadJSON)
+	kafka.RecordDeadLetter(c.deadLetterTopic(), kafka.DeadLetterReasonRetriesExhausted, sendErr)
+	if sendErr != nil {
+		fields["dlq_error"] = sendErr.Error()
+		c.logger.Error(ctx, "Redis 任务投递死信队列失败，放弃处理", fields)
+		return
+	}
+
+	c.logger.Error(ctx, "Redis 任务达到最大重试次数，已投递死信队列", map[string]interface{}{
+		"error":    dead.LastError,
+		"type":     task.Type,
+		"source":   task.Source,
+		"attempts": dead.Attempts,
+		"trace_id": task.TraceID,
+	})
+}
+
 // executeRedisTask 执行 Redis 任务
 func (c *RedisRetryConsumer) executeRedisTask(ctx context.Context, task RedisTask) error {
 	switch task.Type {
@@ -0,0 +1,150 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"ChatServer/pkg/kafka"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTaskPublisher struct {
+	mu   sync.Mutex
+	sent [][]byte
+	err  error
+}
+
+func (f *fakeTaskPublisher) Send(ctx context.Context, data []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, data)
+	return nil
+}
+
+func (f *fakeTaskPublisher) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+type nopKafkaLogger struct{}
+
+func (nopKafkaLogger) Info(ctx context.Context, msg string, fields map[string]interface{})  {}
+func (nopKafkaLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {}
+
+// dlqCounterValue 读取死信投递计数（fakeTaskPublisher 不提供 topic，标签为 unknown）
+func dlqCounterValue(t *testing.T, result string) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, kafka.GetDeadLetterTotal().WithLabelValues("unknown", kafka.DeadLetterReasonRetriesExhausted, result).Write(m))
+	return m.GetCounter().GetValue()
+}
+
+// unknownTask 构造一个无法执行的任务（未知命令类型），无需依赖真实 Redis。
+func unknownTask(retryCount, maxRetries int) []byte {
+	task := RedisTask{
+		Type:        CommandType("unknown"),
+		Command:     "set",
+		Args:        []interface{}{"k", "v"},
+		TraceID:     "trace-1",
+		Source:      "user.ChangeEmail",
+		RetryCount:  retryCount,
+		MaxRetries:  maxRetries,
+		OriginalErr: "dial tcp: connection refused",
+	}
+	data, _ := json.Marshal(task)
+	return data
+}
+
+func TestRedisRetryConsumer_ExhaustedTaskGoesToDeadLetter(t *testing.T) {
+	retry := &fakeTaskPublisher{}
+	dlq := &fakeTaskPublisher{}
+	c := &RedisRetryConsumer{producer: retry, dlqProducer: dlq, logger: nopKafkaLogger{}}
+
+	before := dlqCounterValue(t, "ok")
+	err := c.processMessage(context.Background(), unknownTask(3, 3))
+	require.Error(t, err)
+
+	assert.Empty(t, retry.sent)
+	require.Len(t, dlq.sent, 1)
+
+	var dead DeadLetterTask
+	require.NoError(t, json.Unmarshal(dlq.sent[0], &dead))
+	assert.Equal(t, "user.ChangeEmail", dead.Source)
+	assert.Equal(t, 4, dead.Attempts)
+	assert.Equal(t, err.Error(), dead.LastError)
+	assert.Equal(t, "set", dead.Task.Command)
+	assert.Equal(t, "trace-1", dead.Task.TraceID)
+	assert.Equal(t, []string{"dial tcp: connection refused", err.Error()}, dead.Errors)
+	assert.False(t, dead.DeadAt.IsZero())
+
+	assert.Equal(t, before+1, dlqCounterValue(t, "ok"))
+}
+
+func TestRedisRetryConsumer_RetryableTaskIsRequeued(t *testing.T) {
+	retry := &fakeTaskPublisher{}
+	dlq := &fakeTaskPublisher{}
+	c := &RedisRetryConsumer{producer: retry, dlqProducer: dlq, logger: nopKafkaLogger{}}
+
+	err := c.processMessage(context.Background(), unknownTask(1, 3))
+	require.Error(t, err)
+
+	assert.Empty(t, dlq.sent)
+	require.Len(t, retry.sent, 1)
+
+	var task RedisTask
+	require.NoError(t, json.Unmarshal(retry.sent[0], &task))
+	assert.Equal(t, 2, task.RetryCount)
+	assert.Equal(t, []string{err.Error()}, task.ErrorHistory)
+}
+
+func TestAppendErrorHistoryKeepsLatest(t *testing.T) {
+	var history []string
+	for i := 0; i < maxErrorHistory+3; i++ {
+		history = appendErrorHistory(history, errors.New(string(rune('a'+i))))
+	}
+	require.Len(t, history, maxErrorHistory)
+	assert.Equal(t, "d", history[0])
+	assert.Equal(t, string(rune('a'+maxErrorHistory+2)), history[len(history)-1])
+	assert.Equal(t, history, appendErrorHistory(history, nil))
+}
+
+func TestDecodeDeadLetterTask(t *testing.T) {
+	task := RedisTask{Type: CmdSimple, Command: "del", Source: "DeviceRepository.DeleteTokens", OriginalErr: "timeout"}
+	task.ErrorHistory = []string{"retry 1 failed"}
+	data, err := json.Marshal(BuildDeadLetterTask(task, errors.New("retry 1 failed")))
+	require.NoError(t, err)
+
+	dead, err := DecodeDeadLetterTask(data)
+	require.NoError(t, err)
+	assert.Equal(t, "DeviceRepository.DeleteTokens", dead.Source)
+	assert.Equal(t, []string{"timeout", "retry 1 failed"}, dead.Errors)
+
+	_, err = DecodeDeadLetterTask([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestRedisRetryConsumer_DeadLetterPublishFailureIsCounted(t *testing.T) {
+	dlq := &fakeTaskPublisher{err: errors.New("broker down")}
+	c := &RedisRetryConsumer{producer: &fakeTaskPublisher{}, dlqProducer: dlq, logger: nopKafkaLogger{}}
+
+	before := dlqCounterValue(t, "error")
+	require.Error(t, c.processMessage(context.Background(), unknownTask(3, 3)))
+	assert.Equal(t, before+1, dlqCounterValue(t, "error"))
+}
+
+func TestRedisRetryConsumer_NilDeadLetterProducer(t *testing.T) {
+	c := &RedisRetryConsumer{producer: &fakeTaskPublisher{}, logger: nopKafkaLogger{}}
+	assert.NotPanics(t, func() {
+		_ = c.processMessage(context.Background(), unknownTask(3, 3))
+	})
+}
@@ -41,6 +41,29 @@ type RedisTask struct {
 	MaxRetries  int       `json:"max_retries"`      // 最大重试次数
 	OriginalErr string    `json:"original_err"`     // 原始错误信息
 	Source      string    `json:"source,omitempty"` // 操作来源（repo/service）
+
+	// Attempt 消费者重试的退避序号，仅用于计算下一次退避延迟，不参与重试次数判断
+	Attempt int `json:"attempt,omitempty"`
+	// NextRetryAt 最早可执行时间（由消费者按 Attempt 指数退避计算），零值表示立即执行
+	NextRetryAt time.Time `json:"next_retry_at,omitempty"`
+
+	// ErrorHistory 消费者重试执行的失败记录（按时间顺序，最多保留 maxErrorHistory 条）
+	ErrorHistory []string `json:"error_history,omitempty"`
+}
+
+// maxErrorHistory 单个任务最多保留的重试失败记录数（避免消息体无限增长）
+const maxErrorHistory = 10
+
+// appendErrorHistory 追加一次失败记录，超出上限时丢弃最早的记录
+func appendErrorHistory(history []string, err error) []string {
+	if err == nil {
+		return history
+	}
+	history = append(history, err.Error())
+	if len(history) > maxErrorHistory {
+		history = history[len(history)-maxErrorHistory:]
+	}
+	return history
 }
 
 type RedisCmd struct {
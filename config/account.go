@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+// AccountDeleteConfig 账号注销配置。
+// 注销后账号处于软删除状态，宽限期内可恢复，超过宽限期后才允许物理清理。
+type AccountDeleteConfig struct {
+	// GracePeriod 注销宽限期。
+	GracePeriod time.Duration `json:"gracePeriod" yaml:"gracePeriod"`
+}
+
+// DefaultAccountDeleteConfig 返回默认配置（可通过环境变量覆盖）。
+// - USER_ACCOUNT_DELETE_GRACE_DAYS: 注销宽限期天数（默认 30）
+func DefaultAccountDeleteConfig() AccountDeleteConfig {
+	grace := time.Duration(getenvInt("USER_ACCOUNT_DELETE_GRACE_DAYS", 30)) * 24 * time.Hour
+	if grace <= 0 {
+		grace = 30 * 24 * time.Hour
+	}
+	return AccountDeleteConfig{GracePeriod: grace}
+}
@@ -0,0 +1,49 @@
+package config
+
+import "time"
+
+// GRPCBreakerConfig gRPC 客户端熔断配置（Gateway→User、Connect→User 使用）。
+type GRPCBreakerConfig struct {
+	// MaxRequests 半开状态下允许通过的探测请求数。
+	MaxRequests int `json:"maxRequests" yaml:"maxRequests"`
+	// Interval 关闭状态下清零统计的周期。
+	Interval time.Duration `json:"interval" yaml:"interval"`
+	// Timeout 熔断打开后多久进入半开状态。
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// MinRequests 统计周期内至少多少请求才判断失败率。
+	MinRequests int `json:"minRequests" yaml:"minRequests"`
+	// FailurePercent 失败率阈值（百分比），达到即熔断。
+	FailurePercent int `json:"failurePercent" yaml:"failurePercent"`
+}
+
+// DefaultGRPCBreakerConfig 返回默认配置（可通过环境变量覆盖）。
+// - GRPC_BREAKER_MAX_REQUESTS: 半开探测请求数（默认 3）
+// - GRPC_BREAKER_INTERVAL_SECONDS: 统计清零周期秒数（默认 15）
+// - GRPC_BREAKER_TIMEOUT_SECONDS: 打开状态持续秒数（默认 45）
+// - GRPC_BREAKER_MIN_REQUESTS: 判断失败率的最小请求数（默认 5）
+// - GRPC_BREAKER_FAILURE_PERCENT: 失败率阈值百分比（默认 50）
+func DefaultGRPCBreakerConfig() GRPCBreakerConfig {
+	cfg := GRPCBreakerConfig{
+		MaxRequests:    getenvInt("GRPC_BREAKER_MAX_REQUESTS", 3),
+		Interval:       time.Duration(getenvInt("GRPC_BREAKER_INTERVAL_SECONDS", 15)) * time.Second,
+		Timeout:        time.Duration(getenvInt("GRPC_BREAKER_TIMEOUT_SECONDS", 45)) * time.Second,
+		MinRequests:    getenvInt("GRPC_BREAKER_MIN_REQUESTS", 5),
+		FailurePercent: getenvInt("GRPC_BREAKER_FAILURE_PERCENT", 50),
+	}
+	if cfg.MaxRequests <= 0 {
+		cfg.MaxRequests = 3
+	}
+	if cfg.Interval < 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 45 * time.Second
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 5
+	}
+	if cfg.FailurePercent <= 0 || cfg.FailurePercent > 100 {
+		cfg.FailurePercent = 50
+	}
+	return cfg
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// ConnectBroadcastConfig 批量推送（BroadcastToUsers）扇出配置（Connect 使用）。
+type ConnectBroadcastConfig struct {
+	// Concurrency 并发推送的 worker 数量上限。
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+}
+
+// DefaultConnectBroadcastConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_BROADCAST_CONCURRENCY: 批量推送并发 worker 数（默认 32）
+func DefaultConnectBroadcastConfig() ConnectBroadcastConfig {
+	cfg := ConnectBroadcastConfig{
+		Concurrency: getenvInt("CONNECT_BROADCAST_CONCURRENCY", 32),
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 32
+	}
+	return cfg
+}
+
+// ConnectRedeliveryConfig 需要回执（ack_required）下行帧的重投配置（Connect 使用）。
+type ConnectRedeliveryConfig struct {
+	// Interval 未确认帧的重投间隔，<=0 表示关闭重投（ack_required 退化为普通推送）。
+	Interval time.Duration `json:"interval" yaml:"interval"`
+	// MaxAttempts 单帧最多发送次数（含首次发送）。
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+	// MaxPending 单连接最多保留的未确认帧数，超出时淘汰最早的帧。
+	MaxPending int `json:"max_pending" yaml:"max_pending"`
+}
+
+// DefaultConnectRedeliveryConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_REDELIVERY_INTERVAL_MS: 重投间隔毫秒数（默认 5000，0 表示关闭）
+// - CONNECT_REDELIVERY_MAX_ATTEMPTS: 单帧最多发送次数（默认 3）
+// - CONNECT_REDELIVERY_MAX_PENDING: 单连接未确认帧上限（默认 256）
+func DefaultConnectRedeliveryConfig() ConnectRedeliveryConfig {
+	cfg := ConnectRedeliveryConfig{
+		Interval:    time.Duration(getenvInt("CONNECT_REDELIVERY_INTERVAL_MS", 5000)) * time.Millisecond,
+		MaxAttempts: getenvInt("CONNECT_REDELIVERY_MAX_ATTEMPTS", 3),
+		MaxPending:  getenvInt("CONNECT_REDELIVERY_MAX_PENDING", 256),
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 256
+	}
+	return cfg
+}
+
+// ConnectPresenceConfig 用户在线状态变更事件配置（Connect 使用）。
+type ConnectPresenceConfig struct {
+	// Enabled 是否投递在线状态变更事件到 Kafka（topic 见 KafkaConfig.PresenceTopic）。
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// DebounceWindow 防抖窗口：窗口内的快速上下线抖动合并为一次事件。
+	DebounceWindow time.Duration `json:"debounce_window" yaml:"debounce_window"`
+	// FanoutEnabled 是否消费在线状态事件并向本节点在线的好友推送 type=presence 帧（需 Redis）。
+	FanoutEnabled bool `json:"fanout_enabled" yaml:"fanout_enabled"`
+	// MaxSubscriptions 单连接通过 subscribe_presence 最多订阅的用户数。
+	MaxSubscriptions int `json:"max_subscriptions" yaml:"max_subscriptions"`
+	// IdleThreshold 活跃阈值：连接超过该时间未收到上行帧（业务消息/心跳）视为空闲，
+	// 用户全部连接空闲时在线状态为 idle。实际阈值不小于连接的空闲超时（心跳间隔 * 2）。
+	IdleThreshold time.Duration `json:"idle_threshold" yaml:"idle_threshold"`
+}
+
+// DefaultConnectPresenceConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_PRESENCE_ENABLED: 是否投递在线状态事件（默认 true）
+// - CONNECT_PRESENCE_DEBOUNCE_MS: 防抖窗口毫秒数（默认 3000）
+// - CONNECT_PRESENCE_FANOUT_ENABLED: 是否向好友推送在线状态（默认 true）
+// - CONNECT_PRESENCE_MAX_SUBSCRIPTIONS: 单连接最多订阅的用户数（默认 200）
+// - CONNECT_PRESENCE_IDLE_MS: 活跃阈值毫秒数（默认 120000）
+func DefaultConnectPresenceConfig() ConnectPresenceConfig {
+	cfg := ConnectPresenceConfig{
+		Enabled:          getenvBool("CONNECT_PRESENCE_ENABLED", true),
+		DebounceWindow:   time.Duration(getenvInt("CONNECT_PRESENCE_DEBOUNCE_MS", 3000)) * time.Millisecond,
+		FanoutEnabled:    getenvBool("CONNECT_PRESENCE_FANOUT_ENABLED", true),
+		MaxSubscriptions: getenvInt("CONNECT_PRESENCE_MAX_SUBSCRIPTIONS", 200),
+		IdleThreshold:    time.Duration(getenvInt("CONNECT_PRESENCE_IDLE_MS", 120000)) * time.Millisecond,
+	}
+	if cfg.DebounceWindow <= 0 {
+		cfg.DebounceWindow = 3 * time.Second
+	}
+	if cfg.MaxSubscriptions <= 0 {
+		cfg.MaxSubscriptions = 200
+	}
+	if cfg.IdleThreshold <= 0 {
+		cfg.IdleThreshold = 2 * time.Minute
+	}
+	return cfg
+}
+
+// ConnectDrainConfig 滚动发布排空配置（Connect 使用）。
+type ConnectDrainConfig struct {
+	// NodeID 本节点 ID，用于连接归属登记；为空时不登记（断开连接总是上报离线）。
+	NodeID string `json:"node_id" yaml:"node_id"`
+	// Grace 排空宽限期：停机时等待客户端重连到其他节点的时间，到期后对未被接管的连接上报离线。
+	Grace time.Duration `json:"grace" yaml:"grace"`
+	// CloseGrace 关闭宽限期：下发 server_shutdown 与关闭帧后等待写出与关闭握手的最长时间，到期后强制断开。
+	CloseGrace time.Duration `json:"close_grace" yaml:"close_grace"`
+	// RetryAfter server_shutdown 帧中建议客户端的首次重连延迟。
+	RetryAfter time.Duration `json:"retry_after" yaml:"retry_after"`
+}
+
+// DefaultConnectDrainConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_NODE_ID: 本节点 ID（默认主机名）
+// - CONNECT_DRAIN_GRACE_MS: 排空宽限期毫秒数（默认 5000，需小于停机超时 15s）
+// - CONNECT_CLOSE_GRACE_MS: 关闭宽限期毫秒数（默认 1000，与排空宽限期之和需小于停机超时 15s）
+// - CONNECT_SHUTDOWN_RETRY_AFTER_MS: 建议的首次重连延迟毫秒数（默认 1000）
+func DefaultConnectDrainConfig() ConnectDrainConfig {
+	hostname, _ := os.Hostname()
+	cfg := ConnectDrainConfig{
+		NodeID:     getenvString("CONNECT_NODE_ID", hostname),
+		Grace:      time.Duration(getenvInt("CONNECT_DRAIN_GRACE_MS", 5000)) * time.Millisecond,
+		CloseGrace: time.Duration(getenvInt("CONNECT_CLOSE_GRACE_MS", 1000)) * time.Millisecond,
+		RetryAfter: time.Duration(getenvInt("CONNECT_SHUTDOWN_RETRY_AFTER_MS", 1000)) * time.Millisecond,
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = 5 * time.Second
+	}
+	if cfg.CloseGrace <= 0 {
+		cfg.CloseGrace = time.Second
+	}
+	if cfg.RetryAfter < 0 {
+		cfg.RetryAfter = 0
+	}
+	return cfg
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+// FriendApplyConfig 好友申请过期配置。
+// 待处理申请超过有效期后视为已过期：不再出现在待处理列表中、不能再被处理，申请人可重新发起申请。
+type FriendApplyConfig struct {
+	// ExpireAfter 申请有效期（创建时写入 expired_at = created_at + ExpireAfter）。
+	ExpireAfter time.Duration `json:"expireAfter" yaml:"expireAfter"`
+	// SweepInterval 后台扫描间隔：将已到期的待处理申请置为已过期（status=3）并移出待处理缓存；<=0 表示不启动，仅在读取时惰性处理。
+	SweepInterval time.Duration `json:"sweepInterval" yaml:"sweepInterval"`
+	// SweepBatchSize 单批处理的申请数，扫描时循环处理直到没有到期申请。
+	SweepBatchSize int `json:"sweepBatchSize" yaml:"sweepBatchSize"`
+}
+
+// DefaultFriendApplyConfig 返回默认配置（可通过环境变量覆盖）。
+// - USER_APPLY_EXPIRE_DAYS: 好友申请有效期天数（默认 7）
+// - USER_APPLY_SWEEP_INTERVAL_SEC: 过期扫描间隔秒数（默认 300，0 表示关闭）
+// - USER_APPLY_SWEEP_BATCH_SIZE: 单批处理数（默认 500）
+func DefaultFriendApplyConfig() FriendApplyConfig {
+	cfg := FriendApplyConfig{
+		ExpireAfter:    time.Duration(getenvInt("USER_APPLY_EXPIRE_DAYS", 7)) * 24 * time.Hour,
+		SweepInterval:  time.Duration(getenvInt("USER_APPLY_SWEEP_INTERVAL_SEC", 300)) * time.Second,
+		SweepBatchSize: getenvInt("USER_APPLY_SWEEP_BATCH_SIZE", 500),
+	}
+	if cfg.ExpireAfter <= 0 {
+		cfg.ExpireAfter = 7 * 24 * time.Hour
+	}
+	if cfg.SweepBatchSize <= 0 {
+		cfg.SweepBatchSize = 500
+	}
+	return cfg
+}
@@ -0,0 +1,81 @@
+package config
+
+import "time"
+
+// GatewayResponseConfig 网关 HTTP 响应格式配置。
+type GatewayResponseConfig struct {
+	// ProtobufEnabled 是否允许客户端通过 Accept: application/x-protobuf 协商 protobuf 响应。
+	// 仅对支持 protobuf 的列表类接口生效，其余接口及错误响应始终返回 JSON。
+	ProtobufEnabled bool `json:"protobufEnabled" yaml:"protobufEnabled"`
+}
+
+// DefaultGatewayResponseConfig 返回默认配置（可通过环境变量覆盖）。
+// - GATEWAY_PROTOBUF_RESPONSE_ENABLED: 是否允许协商 protobuf 响应（默认 true）
+func DefaultGatewayResponseConfig() GatewayResponseConfig {
+	return GatewayResponseConfig{
+		ProtobufEnabled: getenvBool("GATEWAY_PROTOBUF_RESPONSE_ENABLED", true),
+	}
+}
+
+// GatewayServiceModeConfig 网关降级模式探测配置。
+type GatewayServiceModeConfig struct {
+	// HeaderEnabled 降级时是否在响应中添加 X-Service-Mode: degraded。
+	HeaderEnabled bool `json:"headerEnabled" yaml:"headerEnabled"`
+	// RedisProbeInterval Redis 探活间隔，探活失败即进入降级模式，恢复后自动退出。
+	RedisProbeInterval time.Duration `json:"redisProbeInterval" yaml:"redisProbeInterval"`
+}
+
+// DefaultGatewayServiceModeConfig 返回默认配置（可通过环境变量覆盖）。
+// - GATEWAY_SERVICE_MODE_HEADER_ENABLED: 降级时是否返回 X-Service-Mode 响应头（默认 true）
+// - GATEWAY_REDIS_PROBE_INTERVAL_MS: Redis 探活间隔（默认 5000）
+func DefaultGatewayServiceModeConfig() GatewayServiceModeConfig {
+	return GatewayServiceModeConfig{
+		HeaderEnabled:      getenvBool("GATEWAY_SERVICE_MODE_HEADER_ENABLED", true),
+		RedisProbeInterval: time.Duration(getenvInt("GATEWAY_REDIS_PROBE_INTERVAL_MS", 5000)) * time.Millisecond,
+	}
+}
+
+// GatewayReadinessConfig 网关 /readyz 就绪检查配置。
+type GatewayReadinessConfig struct {
+	// Timeout 单次就绪检查超时（所有依赖并发检查），避免探针本身挂起。
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// RedisRequired Redis 不可用时是否判定为未就绪。
+	// 默认 false：网关在 Redis 故障时可降级运行，仅在响应中报告 Redis 状态。
+	RedisRequired bool `json:"redisRequired" yaml:"redisRequired"`
+}
+
+// DefaultGatewayReadinessConfig 返回默认配置（可通过环境变量覆盖）。
+// - GATEWAY_READINESS_TIMEOUT_MS: 就绪检查超时（默认 1000）
+// - GATEWAY_READINESS_REDIS_REQUIRED: Redis 不可用时是否返回 503（默认 false）
+func DefaultGatewayReadinessConfig() GatewayReadinessConfig {
+	cfg := GatewayReadinessConfig{
+		Timeout:       time.Duration(getenvInt("GATEWAY_READINESS_TIMEOUT_MS", 1000)) * time.Millisecond,
+		RedisRequired: getenvBool("GATEWAY_READINESS_REDIS_REQUIRED", false),
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	return cfg
+}
+
+// GatewayAuditConfig 认证类接口审计日志配置。
+type GatewayAuditConfig struct {
+	// Enabled 是否记录审计日志（登录、注册、重置/修改密码、登出），默认关闭。
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// OutputPaths 审计日志输出路径，与业务日志分开，便于单独投递到 SIEM（文件无滚动，由外部系统切割）。
+	OutputPaths []string `json:"outputPaths" yaml:"outputPaths"`
+}
+
+// DefaultGatewayAuditConfig 返回默认配置（可通过环境变量覆盖）。
+// - GATEWAY_AUDIT_ENABLED: 是否记录审计日志（默认 false）
+// - GATEWAY_AUDIT_OUTPUT: 输出路径，逗号分隔，支持 stdout/stderr（默认 audit.log）
+func DefaultGatewayAuditConfig() GatewayAuditConfig {
+	cfg := GatewayAuditConfig{
+		Enabled:     getenvBool("GATEWAY_AUDIT_ENABLED", false),
+		OutputPaths: splitCSV(getenvString("GATEWAY_AUDIT_OUTPUT", "audit.log")),
+	}
+	if len(cfg.OutputPaths) == 0 {
+		cfg.OutputPaths = []string{"audit.log"}
+	}
+	return cfg
+}
@@ -0,0 +1,44 @@
+package config
+
+import "time"
+
+// ConnectHeartbeatConfig WebSocket 心跳间隔协商配置（Connect 使用）。
+// 客户端可在握手时通过 heartbeat_interval 申请心跳间隔，服务端将其限制在 [Min, Max] 内。
+type ConnectHeartbeatConfig struct {
+	// MinInterval 允许客户端申请的最小心跳间隔。
+	MinInterval time.Duration `json:"minInterval" yaml:"minInterval"`
+	// MaxInterval 允许客户端申请的最大心跳间隔。
+	MaxInterval time.Duration `json:"maxInterval" yaml:"maxInterval"`
+	// DefaultInterval 客户端未申请或参数非法时使用的心跳间隔。
+	DefaultInterval time.Duration `json:"defaultInterval" yaml:"defaultInterval"`
+}
+
+// DefaultConnectHeartbeatConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_WS_HEARTBEAT_MIN_SECONDS: 最小心跳间隔秒数（默认 10）
+// - CONNECT_WS_HEARTBEAT_MAX_SECONDS: 最大心跳间隔秒数（默认 300，即 5 分钟）
+// - CONNECT_WS_HEARTBEAT_DEFAULT_SECONDS: 默认心跳间隔秒数（默认 30）
+func DefaultConnectHeartbeatConfig() ConnectHeartbeatConfig {
+	cfg := ConnectHeartbeatConfig{
+		MinInterval:     time.Duration(getenvInt("CONNECT_WS_HEARTBEAT_MIN_SECONDS", 10)) * time.Second,
+		MaxInterval:     time.Duration(getenvInt("CONNECT_WS_HEARTBEAT_MAX_SECONDS", 300)) * time.Second,
+		DefaultInterval: time.Duration(getenvInt("CONNECT_WS_HEARTBEAT_DEFAULT_SECONDS", 30)) * time.Second,
+	}
+	return normalizeConnectHeartbeatConfig(cfg)
+}
+
+func normalizeConnectHeartbeatConfig(cfg ConnectHeartbeatConfig) ConnectHeartbeatConfig {
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = 10 * time.Second
+	}
+	if cfg.MaxInterval < cfg.MinInterval {
+		cfg.MaxInterval = cfg.MinInterval
+	}
+	// 默认值必须落在 [Min, Max] 区间内。
+	if cfg.DefaultInterval < cfg.MinInterval {
+		cfg.DefaultInterval = cfg.MinInterval
+	}
+	if cfg.DefaultInterval > cfg.MaxInterval {
+		cfg.DefaultInterval = cfg.MaxInterval
+	}
+	return cfg
+}
@@ -0,0 +1,30 @@
+package config
+
+import "time"
+
+// JWTConfig 令牌有效期配置（user 服务签发 Token 时使用）。
+type JWTConfig struct {
+	// AccessTTL Access Token 有效期。
+	AccessTTL time.Duration `json:"accessTTL" yaml:"accessTTL"`
+	// RefreshTTL Refresh Token 有效期（不应短于 AccessTTL）。
+	RefreshTTL time.Duration `json:"refreshTTL" yaml:"refreshTTL"`
+}
+
+// DefaultJWTConfig 返回默认配置（可通过环境变量覆盖）。
+// - JWT_ACCESS_TTL_SECONDS: Access Token 有效期秒数（默认 7200）
+// - JWT_REFRESH_TTL_SECONDS: Refresh Token 有效期秒数（默认 604800，即 7 天）
+// 非法值回退默认值；RefreshTTL 小于 AccessTTL 时取 AccessTTL。
+func DefaultJWTConfig() JWTConfig {
+	access := time.Duration(getenvInt("JWT_ACCESS_TTL_SECONDS", 7200)) * time.Second
+	if access <= 0 {
+		access = 2 * time.Hour
+	}
+	refresh := time.Duration(getenvInt("JWT_REFRESH_TTL_SECONDS", 604800)) * time.Second
+	if refresh <= 0 {
+		refresh = 7 * 24 * time.Hour
+	}
+	if refresh < access {
+		refresh = access
+	}
+	return JWTConfig{AccessTTL: access, RefreshTTL: refresh}
+}
@@ -14,6 +14,15 @@ type KafkaConfig struct {
 
 	// Redis 重试队列配置
 	RedisRetryTopic string `json:"redisRetryTopic" yaml:"redisRetryTopic"` // Redis 重试队列 topic
+	// RedisRetryDLQTopic 死信队列 topic：重试耗尽的任务投递至此，供人工排查与重放
+	RedisRetryDLQTopic string `json:"redisRetryDlqTopic" yaml:"redisRetryDlqTopic"`
+	// RedisRetryBackoffBase 重试退避基准延迟：第 n 次重试延迟 base*2^(n-1)（带抖动），<=0 表示立即重试
+	RedisRetryBackoffBase time.Duration `json:"redisRetryBackoffBase" yaml:"redisRetryBackoffBase"`
+	// RedisRetryBackoffMax 重试退避延迟上限
+	RedisRetryBackoffMax time.Duration `json:"redisRetryBackoffMax" yaml:"redisRetryBackoffMax"`
+
+	// PresenceTopic 用户在线状态变更事件 topic（connect 投递，下游向好友推送在线状态）
+	PresenceTopic string `json:"presenceTopic" yaml:"presenceTopic"`
 }
 
 // KafkaProducerConfig Kafka 生产者配置
@@ -45,8 +54,13 @@ func DefaultKafkaConfig() KafkaConfig {
 	}
 
 	return KafkaConfig{
-		Brokers:         brokers,
-		RedisRetryTopic: getenvString("KAFKA_RETRY_TOPIC", "redis-retry-queue"),
+		Brokers:            brokers,
+		RedisRetryTopic:    getenvString("KAFKA_RETRY_TOPIC", "redis-retry-queue"),
+		RedisRetryDLQTopic: getenvString("KAFKA_RETRY_DLQ_TOPIC", "redis-retry-dlq"),
+		PresenceTopic:      getenvString("KAFKA_PRESENCE_TOPIC", "user-presence"),
+
+		RedisRetryBackoffBase: time.Duration(getenvInt("KAFKA_RETRY_BACKOFF_BASE_MS", 200)) * time.Millisecond,
+		RedisRetryBackoffMax:  time.Duration(getenvInt("KAFKA_RETRY_BACKOFF_MAX_MS", 30000)) * time.Millisecond,
 
 		ProducerConfig: KafkaProducerConfig{
 			BatchSize:    100,
@@ -1,19 +1,46 @@
 package config
 
+import "time"
+
 // LoggerConfig 定义 zap 日志初始化所需的最小参数集。
 // - 默认写入 stdout/stderr，方便容器中用 docker logs 采集。
 // - 如需直接写文件，可在 OutputPaths/ErrorOutputPaths 配置路径（无滚动，由外部系统切割）。
 type LoggerConfig struct {
-	Level            string   `json:"level" yaml:"level"`                       // 日志级别: debug|info|warn|error
-	Encoding         string   `json:"encoding" yaml:"encoding"`                 // 编码格式: json 或 console
-	Development      bool     `json:"development" yaml:"development"`           // 开发模式: 输出更详细的堆栈/检查
-	EnableColor      bool     `json:"enableColor" yaml:"enableColor"`           // console 模式时是否彩色等级
-	OutputPaths      []string `json:"outputPaths" yaml:"outputPaths"`           // 普通日志输出，默认 stdout
-	ErrorOutputPaths []string `json:"errorOutputPaths" yaml:"errorOutputPaths"` // 错误日志输出，默认 stderr
+	Level            string               `json:"level" yaml:"level"`                       // 日志级别: debug|info|warn|error
+	Encoding         string               `json:"encoding" yaml:"encoding"`                 // 编码格式: json 或 console
+	Development      bool                 `json:"development" yaml:"development"`           // 开发模式: 输出更详细的堆栈/检查
+	EnableColor      bool                 `json:"enableColor" yaml:"enableColor"`           // console 模式时是否彩色等级
+	OutputPaths      []string             `json:"outputPaths" yaml:"outputPaths"`           // 普通日志输出，默认 stdout
+	ErrorOutputPaths []string             `json:"errorOutputPaths" yaml:"errorOutputPaths"` // 错误日志输出，默认 stderr
+	Sampling         LoggerSamplingConfig `json:"sampling" yaml:"sampling"`                 // 日志采样，防止降级期间热点日志刷屏
+}
+
+// LoggerSamplingConfig 日志采样配置（zap sampler）：每个 Tick 内同级别同内容的日志先输出 Initial 条，
+// 之后每 Thereafter 条输出 1 条。Error 及以上级别不采样。
+type LoggerSamplingConfig struct {
+	Initial    int           `json:"initial" yaml:"initial"`       // 每个周期内完整输出的条数，<=0 表示关闭采样
+	Thereafter int           `json:"thereafter" yaml:"thereafter"` // 超出 Initial 后每多少条输出 1 条
+	Tick       time.Duration `json:"tick" yaml:"tick"`             // 采样周期
+}
+
+// Enabled 是否开启采样。
+func (c LoggerSamplingConfig) Enabled() bool {
+	return c.Initial > 0
 }
 
 // DefaultLoggerConfig 返回开箱即用的配置：json 编码 + stdout/stderr。
+// 采样参数可通过环境变量覆盖：
+// - LOG_SAMPLING_INITIAL: 每秒同内容日志完整输出条数（默认 100，0 表示关闭采样）
+// - LOG_SAMPLING_THEREAFTER: 超出后每多少条输出 1 条（默认 100）
 func DefaultLoggerConfig() LoggerConfig {
+	sampling := LoggerSamplingConfig{
+		Initial:    getenvInt("LOG_SAMPLING_INITIAL", 100),
+		Thereafter: getenvInt("LOG_SAMPLING_THEREAFTER", 100),
+		Tick:       time.Second,
+	}
+	if sampling.Thereafter <= 0 {
+		sampling.Thereafter = 100
+	}
 	return LoggerConfig{
 		Level:            "info",
 		Encoding:         "json",
@@ -21,5 +48,6 @@ func DefaultLoggerConfig() LoggerConfig {
 		EnableColor:      false,
 		OutputPaths:      []string{"stdout"},
 		ErrorOutputPaths: []string{"stderr"},
+		Sampling:         sampling,
 	}
 }
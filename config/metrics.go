@@ -0,0 +1,19 @@
+package config
+
+// MetricsConfig 指标公共标签配置（所有服务的 /metrics 指标统一携带 service / version 标签）。
+type MetricsConfig struct {
+	// Version 部署版本；为空时回退到 ldflags 注入值或 VCS 修订号（见 pkg/metrics）。
+	Version string `json:"version" yaml:"version"`
+	// AdminToken 监控端口管理接口（/admin/loglevel）的访问口令；为空时不挂载管理接口。
+	AdminToken string `json:"adminToken" yaml:"adminToken"`
+}
+
+// DefaultMetricsConfig 返回默认配置（可通过环境变量覆盖）。
+// - APP_VERSION: 部署版本（如镜像 tag），便于将指标波动与发布关联
+// - METRICS_ADMIN_TOKEN: 管理接口访问口令（默认空，不启用）
+func DefaultMetricsConfig() MetricsConfig {
+	return MetricsConfig{
+		Version:    getenvString("APP_VERSION", ""),
+		AdminToken: getenvString("METRICS_ADMIN_TOKEN", ""),
+	}
+}
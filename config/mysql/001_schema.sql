@@ -23,6 +23,8 @@ CREATE TABLE IF NOT EXISTS `user_info` (
   `deleted_at` DATETIME(3) DEFAULT NULL COMMENT '删除时间',
   `is_admin` TINYINT NOT NULL DEFAULT 0 COMMENT '是否是管理员,0.不是 1.是',
   `status` TINYINT NOT NULL DEFAULT 0 COMMENT '状态,0.正常 1.禁用',
+  `hide_presence` TINYINT NOT NULL DEFAULT 0 COMMENT '是否隐藏在线状态,0.否 1.是',
+  `presence_hide_scope` TINYINT NOT NULL DEFAULT 0 COMMENT '在线状态隐藏范围,0.所有人 1.仅非好友',
   PRIMARY KEY (`id`),
   UNIQUE KEY `uk_user_info_uuid` (`uuid`),
   UNIQUE KEY `uk_user_info_telephone` (`telephone`),
@@ -105,10 +107,12 @@ CREATE TABLE IF NOT EXISTS `apply_request` (
   `handle_user_uuid` CHAR(20) DEFAULT NULL COMMENT '处理人uuid',
   `handle_remark` VARCHAR(255) DEFAULT NULL COMMENT '处理备注',
   `expired_at` DATETIME(3) DEFAULT NULL COMMENT '过期时间',
+  `pending_key` VARCHAR(48) DEFAULT NULL COMMENT '待处理唯一键(仅status=0时非空)',
   `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
   `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
   `deleted_at` DATETIME(3) DEFAULT NULL COMMENT '删除时间',
   PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_apply_pending_key` (`pending_key`),
   KEY `idx_applicant_target` (`applicant_uuid`, `target_uuid`),
   KEY `idx_apply_pending_list` (`apply_type`, `target_uuid`, `status`, `deleted_at`, `created_at`, `id`),
   KEY `idx_apply_sent_list` (`apply_type`, `applicant_uuid`, `status`, `deleted_at`, `created_at`, `id`),
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// PresencePrivacyConfig 在线状态隐私配置（User 使用）。
+type PresencePrivacyConfig struct {
+	// HiddenRebuildInterval 以 MySQL hide_presence 为准校准 Redis 隐藏集合（user:presence:hidden）的周期；
+	// 启动时总会校准一次，<=0 表示仅在启动时校准。
+	HiddenRebuildInterval time.Duration `json:"hiddenRebuildInterval" yaml:"hiddenRebuildInterval"`
+}
+
+// DefaultPresencePrivacyConfig 返回默认配置（可通过环境变量覆盖）。
+// 环境变量：
+// - USER_PRESENCE_HIDDEN_REBUILD_INTERVAL_SEC（默认 600）
+func DefaultPresencePrivacyConfig() PresencePrivacyConfig {
+	return PresencePrivacyConfig{
+		HiddenRebuildInterval: time.Duration(getenvInt("USER_PRESENCE_HIDDEN_REBUILD_INTERVAL_SEC", 600)) * time.Second,
+	}
+}
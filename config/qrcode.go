@@ -0,0 +1,40 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrQRCodeSecretUnset 未配置二维码签名密钥。
+var ErrQRCodeSecretUnset = errors.New("USER_QRCODE_SECRET is not set")
+
+// QRCodeConfig 用户二维码（加好友）签名配置。
+// 二维码内容为 HMAC 签名的自包含 token，无需服务端存储。
+type QRCodeConfig struct {
+	// Secret HMAC 签名密钥（必须通过环境变量配置，无默认值）。
+	Secret string `json:"secret" yaml:"secret"`
+	// TTL 二维码有效期。
+	TTL time.Duration `json:"ttl" yaml:"ttl"`
+}
+
+// DefaultQRCodeConfig 返回默认配置（可通过环境变量覆盖）。
+// - USER_QRCODE_SECRET: 签名密钥（必填，未设置时 Validate 返回错误）
+// - USER_QRCODE_TTL_HOURS: 有效期小时数（默认 48）
+func DefaultQRCodeConfig() QRCodeConfig {
+	ttl := time.Duration(getenvInt("USER_QRCODE_TTL_HOURS", 48)) * time.Hour
+	if ttl <= 0 {
+		ttl = 48 * time.Hour
+	}
+	return QRCodeConfig{
+		Secret: getenvString("USER_QRCODE_SECRET", ""),
+		TTL:    ttl,
+	}
+}
+
+// Validate 校验签名密钥已配置；使用公开的默认密钥会让任何人都能伪造二维码，启动时直接失败。
+func (c QRCodeConfig) Validate() error {
+	if c.Secret == "" {
+		return ErrQRCodeSecretUnset
+	}
+	return nil
+}
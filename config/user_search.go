@@ -0,0 +1,33 @@
+package config
+
+// UserSearchConfig 用户搜索（SearchUser）配置。
+type UserSearchConfig struct {
+	// MinKeywordLen 关键词最小长度（按字符计，规整空白后）。
+	MinKeywordLen int `json:"minKeywordLen" yaml:"minKeywordLen"`
+	// DefaultPageSize page_size 未指定（<=0）时使用的每页条数。
+	DefaultPageSize int `json:"defaultPageSize" yaml:"defaultPageSize"`
+	// MaxPageSize 每页条数上限，超出时截断。
+	MaxPageSize int `json:"maxPageSize" yaml:"maxPageSize"`
+}
+
+// DefaultUserSearchConfig 返回默认配置（可通过环境变量覆盖）。
+// - USER_SEARCH_MIN_KEYWORD_LEN: 关键词最小长度（默认 2）
+// - USER_SEARCH_DEFAULT_PAGE_SIZE: 默认每页条数（默认 20）
+// - USER_SEARCH_MAX_PAGE_SIZE: 每页条数上限（默认 100）
+func DefaultUserSearchConfig() UserSearchConfig {
+	cfg := UserSearchConfig{
+		MinKeywordLen:   getenvInt("USER_SEARCH_MIN_KEYWORD_LEN", 2),
+		DefaultPageSize: getenvInt("USER_SEARCH_DEFAULT_PAGE_SIZE", 20),
+		MaxPageSize:     getenvInt("USER_SEARCH_MAX_PAGE_SIZE", 100),
+	}
+	if cfg.MinKeywordLen <= 0 {
+		cfg.MinKeywordLen = 2
+	}
+	if cfg.MaxPageSize <= 0 {
+		cfg.MaxPageSize = 100
+	}
+	if cfg.DefaultPageSize <= 0 || cfg.DefaultPageSize > cfg.MaxPageSize {
+		cfg.DefaultPageSize = min(20, cfg.MaxPageSize)
+	}
+	return cfg
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"ChatServer/pkg/util"
+)
+
+// VerifyCodeConfig 验证码格式配置（长度与字符集，可按验证码类型覆盖）。
+type VerifyCodeConfig struct {
+	// Default 未单独配置的验证码类型使用的规范。
+	Default util.VerifyCodeSpec `json:"default" yaml:"default"`
+	// ByType 按验证码类型（1:注册 2:登录 3:重置密码 4:换绑邮箱 6:注销账号）覆盖的规范。
+	ByType map[int32]util.VerifyCodeSpec `json:"byType" yaml:"byType"`
+}
+
+// DefaultVerifyCodeConfig 返回默认配置（可通过环境变量覆盖）。
+// - USER_VERIFY_CODE_LENGTH: 默认长度（默认 6，范围 4~12）
+// - USER_VERIFY_CODE_CHARSET: 默认字符集 numeric / alphanumeric（默认 numeric）
+// - USER_VERIFY_CODE_TYPE_SPECS: 按类型覆盖，格式 type=length:charset，逗号分隔，如 "3=8:alphanumeric,6=6:numeric"
+func DefaultVerifyCodeConfig() VerifyCodeConfig {
+	def := util.NewVerifyCodeSpec(
+		getenvInt("USER_VERIFY_CODE_LENGTH", util.DefaultVerifyCodeSpec.Length),
+		util.VerifyCodeCharset(getenvString("USER_VERIFY_CODE_CHARSET", string(util.DefaultVerifyCodeSpec.Charset))),
+	)
+	return VerifyCodeConfig{
+		Default: def,
+		ByType:  parseVerifyCodeTypeSpecs(getenvString("USER_VERIFY_CODE_TYPE_SPECS", "")),
+	}
+}
+
+// parseVerifyCodeTypeSpecs 解析 type=length:charset 列表，格式错误的条目忽略。
+func parseVerifyCodeTypeSpecs(value string) map[int32]util.VerifyCodeSpec {
+	specs := make(map[int32]util.VerifyCodeSpec)
+	for _, item := range splitCSV(value) {
+		typePart, specPart, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		codeType, err := strconv.ParseInt(strings.TrimSpace(typePart), 10, 32)
+		if err != nil {
+			continue
+		}
+		lengthPart, charsetPart, _ := strings.Cut(specPart, ":")
+		length, err := strconv.Atoi(strings.TrimSpace(lengthPart))
+		if err != nil {
+			continue
+		}
+		specs[int32(codeType)] = util.NewVerifyCodeSpec(length, util.VerifyCodeCharset(strings.TrimSpace(charsetPart)))
+	}
+	return specs
+}
@@ -95,6 +95,10 @@ const (
 	CodeEmailNotFound = 11026 // 邮箱不存在
 	// 账号已注销
 	CodeAccountDeleted = 11029 // 账号已注销
+	// 新邮箱与当前绑定邮箱相同
+	CodeEmailSameAsOld = 11030 // 新邮箱与当前绑定邮箱相同
+	// 新手机号与当前绑定手机号相同
+	CodeTelephoneSameAsOld = 11031 // 新手机号与当前绑定手机号相同
 )
 
 // 好友模块错误 (12xxx)
@@ -222,6 +226,10 @@ const (
 	CodeConnectMessageFormatError = 17003 // WebSocket 上行消息格式错误
 	// WebSocket 上行消息类型不支持
 	CodeConnectMessageTypeNotSupport = 17004 // WebSocket 上行消息类型不支持
+	// 不是会话参与者（如 typing 帧的 conv_id 不包含发送者）
+	CodeConnectNotConvMember = 17005 // 不是会话参与者
+	// 单连接在线状态订阅数超过上限
+	CodeConnectPresenceSubscribeTooMany = 17006 // 在线状态订阅数超过上限
 )
 
 // 服务端错误 (3xxxx)
@@ -282,6 +290,8 @@ var CodeMessage = map[int]string{
 	CodeReasonTooLong:         "理由过长",
 	CodeEmailNotFound:         "邮箱不存在",
 	CodeAccountDeleted:        "账号已注销",
+	CodeEmailSameAsOld:        "新邮箱与当前绑定邮箱相同",
+	CodeTelephoneSameAsOld:    "新手机号与当前绑定手机号相同",
 
 	// 好友模块
 	CodeAlreadyFriend:         "已经是好友",
@@ -342,10 +352,12 @@ var CodeMessage = map[int]string{
 	CodeCannotBlacklistSelf: "不能拉黑自己",
 
 	// Connect 模块
-	CodeConnectTokenRequired:         "缺少 token",
-	CodeConnectDeviceIDRequired:      "缺少 device_id",
-	CodeConnectMessageFormatError:    "消息格式错误",
-	CodeConnectMessageTypeNotSupport: "消息类型不支持",
+	CodeConnectTokenRequired:            "缺少 token",
+	CodeConnectDeviceIDRequired:         "缺少 device_id",
+	CodeConnectMessageFormatError:       "消息格式错误",
+	CodeConnectMessageTypeNotSupport:    "消息类型不支持",
+	CodeConnectNotConvMember:            "不是会话参与者",
+	CodeConnectPresenceSubscribeTooMany: "在线状态订阅数超过上限",
 
 	// 服务端错误
 	CodeInternalError:      "服务器内部错误",
@@ -368,4 +380,10 @@ func IsNonServerError(code int) bool {
 
 const (
 	VerifyCodeExpireMinutes = 10
+
+	// BatchGetProfileMaxSize BatchGetProfile 单次请求允许的最大 UUID 数量。
+	// user 服务据此拒绝超限请求，Gateway 批量拉取时按该值分片，二者必须保持一致。
+	BatchGetProfileMaxSize = 100
+	// UserUUIDMaxLen 用户 UUID 最大长度（与 user_info.uuid CHAR(20) 对齐）。
+	UserUUIDMaxLen = 20
 )
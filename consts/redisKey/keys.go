@@ -42,8 +42,16 @@ const (
 	// ApplyUnreadNotifyTTL 好友申请未读计数 TTL
 	ApplyUnreadNotifyTTL = 7 * 24 * time.Hour
 
-	// QRCodeTTL 用户二维码缓存 TTL
-	QRCodeTTL = 48 * time.Hour
+	// ConvReadPositionTTL 会话已读位置 TTL（每次上报续期）
+	ConvReadPositionTTL = 30 * 24 * time.Hour
+
+	// TokenEpochTTL 设备令牌纪元 TTL（不短于 RefreshToken 有效期，递增时续期）
+	TokenEpochTTL = 7 * 24 * time.Hour
+	// TokenVersionTTL 用户令牌版本 TTL（同 TokenEpochTTL，递增时续期）
+	TokenVersionTTL = 7 * 24 * time.Hour
+
+	// ConnectConnOwnerTTL 连接归属登记 TTL（连接建立时写入；兜底节点异常退出未清理的情况）
+	ConnectConnOwnerTTL = 24 * time.Hour
 )
 
 // ==================== Key 构造函数 ====================
@@ -78,6 +86,24 @@ func RefreshTokenKey(userUUID, deviceID string) string {
 	return fmt.Sprintf("auth:rt:%s:%s", userUUID, deviceID)
 }
 
+// RevokedAccessTokenKey 生成已吊销 AccessToken Key: auth:revoked:{token_md5}
+// 刷新/重新登录/踢出时写入旧 Token 的哈希，TTL 为旧 Token 剩余有效期。
+func RevokedAccessTokenKey(tokenHash string) string {
+	return fmt.Sprintf("auth:revoked:%s", tokenHash)
+}
+
+// TokenEpochKey 生成设备令牌纪元 Key: auth:epoch:{user_uuid}:{device_id}
+// 踢出/改密时 INCR，纪元低于当前值的 Token 统一失效。
+func TokenEpochKey(userUUID, deviceID string) string {
+	return fmt.Sprintf("auth:epoch:%s:%s", userUUID, deviceID)
+}
+
+// TokenVersionKey 生成用户令牌版本 Key: auth:token_version:{user_uuid}
+// 重置密码/注销等安全事件时 INCR，版本低于当前值的 Token（该用户所有设备）统一失效。
+func TokenVersionKey(userUUID string) string {
+	return fmt.Sprintf("auth:token_version:%s", userUUID)
+}
+
 // DeviceInfoKey 生成设备信息缓存 Key: user:devices:{user_uuid}
 func DeviceInfoKey(userUUID string) string {
 	return fmt.Sprintf("user:devices:%s", userUUID)
@@ -93,16 +119,6 @@ func UserInfoKey(uuid string) string {
 	return fmt.Sprintf("user:info:%s", uuid)
 }
 
-// QRCodeTokenKey 生成二维码 token Key: user:qrcode:token:{token}
-func QRCodeTokenKey(token string) string {
-	return fmt.Sprintf("user:qrcode:token:%s", token)
-}
-
-// QRCodeUserKey 生成二维码 user Key: user:qrcode:user:{user_uuid}
-func QRCodeUserKey(userUUID string) string {
-	return fmt.Sprintf("user:qrcode:user:%s", userUUID)
-}
-
 // FriendRelationKey 生成好友关系 Key: user:relation:friend:{user_uuid}
 func FriendRelationKey(userUUID string) string {
 	return fmt.Sprintf("user:relation:friend:%s", userUUID)
@@ -113,6 +129,13 @@ func BlacklistRelationKey(userUUID string) string {
 	return fmt.Sprintf("user:relation:blacklist:%s", userUUID)
 }
 
+// PresenceHiddenKey 生成隐藏在线状态用户集合 Key: user:presence:hidden（Set: user_uuid，无 TTL）
+// 由 user 服务在用户修改隐私设置时维护（MySQL user_info.hide_presence=1 且 presence_hide_scope=0 的用户），
+// 并在启动时及按 USER_PRESENCE_HIDDEN_REBUILD_INTERVAL_SEC 周期性以 MySQL 为准校准；connect 推送在线状态前读取；仅对非好友隐藏的用户不在集合中，好友仍收到推送。
+func PresenceHiddenKey() string {
+	return "user:presence:hidden"
+}
+
 // ApplyPendingKey 生成好友申请待处理 Key: user:apply:pending:{target_uuid}
 func ApplyPendingKey(targetUUID string) string {
 	return fmt.Sprintf("user:apply:pending:%s", targetUUID)
@@ -123,6 +146,33 @@ func ApplyUnreadNotifyKey(targetUUID string) string {
 	return fmt.Sprintf("user:notify:friend_apply:unread:%s", targetUUID)
 }
 
+// ==================== 消息 Key 构造函数 ====================
+
+// ConvReadPositionKey 生成会话已读位置 Key: msg:read:{user_uuid}（Hash: conv_id -> read_seq）
+func ConvReadPositionKey(userUUID string) string {
+	return fmt.Sprintf("msg:read:%s", userUUID)
+}
+
+// ==================== Group Key 构造函数 ====================
+
+// GroupMembersKey 生成群成员 Key: group:members:{group_uuid}（Set: user_uuid）
+// 由群组服务在成员变更时维护，connect 仅读取（如 typing 帧扇出）。
+func GroupMembersKey(groupUUID string) string {
+	return fmt.Sprintf("group:members:%s", groupUUID)
+}
+
+// ==================== Connect Key 构造函数 ====================
+
+// ConnectConnOwnerKey 生成连接归属 Key: connect:conn:{user_uuid}:{device_id}（值为持有连接的节点 ID）
+func ConnectConnOwnerKey(userUUID, deviceID string) string {
+	return fmt.Sprintf("connect:conn:%s:%s", userUUID, deviceID)
+}
+
+// ConnectPresenceKey 生成跨节点在线状态 Key: connect:presence:{user_uuid}（Hash: node_id -> state:unix_ms）
+func ConnectPresenceKey(userUUID string) string {
+	return fmt.Sprintf("connect:presence:%s", userUUID)
+}
+
 // ==================== Gateway Key 构造函数 ====================
 
 // GatewayIPBlacklistKey 网关 IP 黑名单 Key: gateway:blacklist:ips
@@ -130,9 +180,10 @@ func GatewayIPBlacklistKey() string {
 	return "gateway:blacklist:ips"
 }
 
-// GatewayUserRateLimitKey 网关用户限流 Key: gateway:rate:limit:user:{user_uuid}
-func GatewayUserRateLimitKey(userUUID string) string {
-	return fmt.Sprintf("gateway:rate:limit:user:%s", userUUID)
+// GatewayUserRateLimitKey 网关用户限流 Key: gateway:rate:limit:user:{limiter}:{user_uuid}
+// limiter 为限流器名称，不同 rate/burst 的限流器各自维护令牌桶，互不覆盖。
+func GatewayUserRateLimitKey(limiter, userUUID string) string {
+	return fmt.Sprintf("gateway:rate:limit:user:%s:%s", limiter, userUUID)
 }
 
 // GatewayIPRateLimitKey 网关 IP 限流 Key: rate:limit:ip:{ip}
@@ -14,13 +14,14 @@ type ApplyRequest struct {
 	ApplyType      int8           `gorm:"column:apply_type;not null;comment:0好友 1加群"`
 	ApplicantUuid  string         `gorm:"column:applicant_uuid;type:char(20);not null;index:idx_applicant_target;comment:申请人uuid"`
 	TargetUuid     string         `gorm:"column:target_uuid;type:char(20);not null;index:idx_applicant_target;comment:好友申请为目标用户uuid;加群为群uuid"`
-	Status         int8           `gorm:"column:status;not null;default:0;comment:0待处理 1通过 2拒绝 3过期"`
+	Status         int8           `gorm:"column:status;not null;default:0;index:idx_status_expired,priority:1;comment:0待处理 1通过 2拒绝 3过期"`
 	IsRead         bool           `gorm:"column:is_read;not null;default:false;comment:申请是否已读"`
 	Reason         string         `gorm:"column:reason;type:varchar(255);comment:申请附言"`
 	Source         string         `gorm:"column:source;type:varchar(32);comment:申请来源"`
 	HandleUserUuid string         `gorm:"column:handle_user_uuid;type:char(20);comment:处理人uuid(好友为目标用户;群为管理员/群主)"`
 	HandleRemark   string         `gorm:"column:handle_remark;type:varchar(255);comment:处理备注"`
-	ExpiredAt      *time.Time     `gorm:"column:expired_at;comment:过期时间"`
+	ExpiredAt      *time.Time     `gorm:"column:expired_at;index:idx_status_expired,priority:2;comment:过期时间(创建时写入,待处理申请到期后置为过期)"`
+	PendingKey     *string        `gorm:"column:pending_key;type:varchar(48);uniqueIndex:uk_apply_pending_key;comment:待处理唯一键(仅status=0时非空)"`
 	CreatedAt      time.Time      `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt      time.Time      `gorm:"column:updated_at;autoUpdateTime"`
 	DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index"`
@@ -28,6 +29,15 @@ type ApplyRequest struct {
 
 func (ApplyRequest) TableName() string { return "apply_request" }
 
+// IsExpired 判断申请在 now 时是否已过期：已置为过期状态，或仍待处理但已超过 expired_at。
+// expired_at 为空（引入过期机制前的历史数据）的待处理申请不过期。
+func (a *ApplyRequest) IsExpired(now time.Time) bool {
+	if a.Status == 3 {
+		return true
+	}
+	return a.Status == 0 && a.ExpiredAt != nil && !a.ExpiredAt.After(now)
+}
+
 
 //如果一个人多次申请
 //应该 找到之前那条 Status=0 的旧记录，更新它的 UpdatedAt 时间，并把 IsRead 重置为 0。
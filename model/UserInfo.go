@@ -22,8 +22,16 @@ type UserInfo struct {
 	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;comment:删除时间"`
 	IsAdmin   int8           `gorm:"column:is_admin;not null;comment:是否是管理员,0.不是 1.是"`
 	Status    int8           `gorm:"column:status;not null;comment:状态,0.正常 1.禁用"`
+	HidePresence int8        `gorm:"column:hide_presence;not null;default:0;comment:是否隐藏在线状态,0.否 1.是"`
+	PresenceHideScope int8   `gorm:"column:presence_hide_scope;not null;default:0;comment:在线状态隐藏范围,0.所有人 1.仅非好友"`
 }
 
+// 在线状态隐藏范围（hide_presence=1 时生效）
+const (
+	PresenceHideScopeEveryone   int8 = 0 // 对所有人隐藏（含好友，且不推送 presence 帧）
+	PresenceHideScopeNonFriends int8 = 1 // 仅对非好友隐藏
+)
+
 func (UserInfo) TableName() string {
 	return "user_info"
 }
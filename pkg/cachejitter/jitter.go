@@ -0,0 +1,73 @@
+package cachejitter
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultRatio 默认 TTL 抖动比例（±10%），用于打散同批写入缓存的过期时间，避免缓存雪崩。
+const DefaultRatio = 0.1
+
+// Jitter 基于可指定种子的随机源生成缓存 TTL 抖动与概率判断。
+// 并发安全；测试中可通过 New(seed) 固定随机序列。
+type Jitter struct {
+	mu    sync.Mutex
+	rng   *rand.Rand
+	ratio float64
+}
+
+// New 创建使用 seed 作为随机种子、抖动比例为 DefaultRatio 的 Jitter。
+func New(seed int64) *Jitter {
+	return NewWithRatio(seed, DefaultRatio)
+}
+
+// NewWithRatio 创建指定抖动比例的 Jitter，ratio 取值范围 [0, 1]，越界时截断。
+func NewWithRatio(seed int64, ratio float64) *Jitter {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+	return &Jitter{
+		rng:   rand.New(rand.NewSource(seed)),
+		ratio: ratio,
+	}
+}
+
+// float64 返回 [0, 1) 的随机数（rand.Rand 本身非并发安全，需加锁）。
+func (j *Jitter) float64() float64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.rng.Float64()
+}
+
+// ExpireTime 生成带随机抖动的过期时间
+// 返回: [base*(1-ratio), base*(1+ratio)) 内的随机时间；base <= 0 时原样返回
+func (j *Jitter) ExpireTime(base time.Duration) time.Duration {
+	if base <= 0 {
+		return base
+	}
+	jitterRange := float64(base) * j.ratio
+	jitter := time.Duration(j.float64()*jitterRange*2 - jitterRange)
+	return base + jitter
+}
+
+// Bool 以 probability 的概率返回 true（probability <= 0 恒为 false，>= 1 恒为 true）。
+func (j *Jitter) Bool(probability float64) bool {
+	return j.float64() < probability
+}
+
+// defaultJitter 进程级默认实例，以启动时间作为种子。
+var defaultJitter = New(time.Now().UnixNano())
+
+// ExpireTime 使用默认实例生成带 ±10% 抖动的过期时间。
+func ExpireTime(base time.Duration) time.Duration {
+	return defaultJitter.ExpireTime(base)
+}
+
+// Bool 使用默认实例以 probability 的概率返回 true（用于概率续期等场景）。
+func Bool(probability float64) bool {
+	return defaultJitter.Bool(probability)
+}
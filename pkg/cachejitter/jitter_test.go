@@ -0,0 +1,69 @@
+package cachejitter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpireTimeStaysWithinWindow(t *testing.T) {
+	j := New(42)
+	base := 24 * time.Hour
+	lower := base - base/10
+	upper := base + base/10
+
+	var minSeen, maxSeen time.Duration = upper, lower
+	for i := 0; i < 10000; i++ {
+		ttl := j.ExpireTime(base)
+		assert.GreaterOrEqual(t, ttl, lower)
+		assert.Less(t, ttl, upper)
+		minSeen = min(minSeen, ttl)
+		maxSeen = max(maxSeen, ttl)
+	}
+	// 抖动应覆盖大部分窗口，而不是集中在某一侧
+	assert.Less(t, minSeen, base-base/20)
+	assert.Greater(t, maxSeen, base+base/20)
+}
+
+func TestExpireTimeNonPositiveBase(t *testing.T) {
+	j := New(1)
+	assert.Equal(t, time.Duration(0), j.ExpireTime(0))
+	assert.Equal(t, -time.Second, j.ExpireTime(-time.Second))
+}
+
+func TestNewWithRatioClamps(t *testing.T) {
+	assert.Equal(t, time.Minute, NewWithRatio(1, -0.5).ExpireTime(time.Minute))
+	ttl := NewWithRatio(1, 5).ExpireTime(time.Minute)
+	assert.GreaterOrEqual(t, ttl, time.Duration(0))
+	assert.Less(t, ttl, 2*time.Minute)
+}
+
+func TestBoolTrendsTowardProbability(t *testing.T) {
+	const n = 20000
+	for _, p := range []float64{0.05, 0.3, 0.5, 0.9} {
+		j := New(7)
+		hits := 0
+		for i := 0; i < n; i++ {
+			if j.Bool(p) {
+				hits++
+			}
+		}
+		assert.InDelta(t, p, float64(hits)/n, 0.02, "p=%v", p)
+	}
+}
+
+func TestBoolBounds(t *testing.T) {
+	j := New(3)
+	for i := 0; i < 1000; i++ {
+		assert.False(t, j.Bool(0))
+		assert.True(t, j.Bool(1))
+	}
+}
+
+func TestSameSeedSameSequence(t *testing.T) {
+	a, b := New(99), New(99)
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, a.ExpireTime(time.Hour), b.ExpireTime(time.Hour))
+	}
+}
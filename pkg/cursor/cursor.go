@@ -0,0 +1,78 @@
+// Package cursor 列表接口的游标分页（keyset pagination）。
+//
+// 偏移分页（page/pageSize）在深翻页时需要扫描并丢弃 offset 行，游标分页改为记录上一页最后一条的排序键，
+// 下一页从该位置之后继续查询，代价与页码无关。游标对客户端不透明（base64url 编码），
+// 服务端可在不破坏兼容的前提下调整内部字段：
+//   - 按创建时间倒序的列表（好友、好友申请）使用 created_at + id 作为锚点；
+//   - 按序号递增的列表（消息拉取）使用 seq 作为锚点。
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalid 游标格式非法（无法解码或缺少锚点字段）。
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor 分页锚点：上一页最后一条记录的排序键。零值表示从第一页开始。
+type Cursor struct {
+	Time int64 `json:"t,omitempty"` // created_at（Unix 纳秒，保证与数据库中的时间精确比较）
+	ID   int64 `json:"i,omitempty"` // 自增 id，created_at 相同时的二级排序键
+	Seq  int64 `json:"s,omitempty"` // 消息序号
+}
+
+// AfterRow 以记录的 created_at + id 构造游标。
+func AfterRow(createdAt time.Time, id int64) Cursor {
+	return Cursor{Time: createdAt.UnixNano(), ID: id}
+}
+
+// AfterSeq 以消息序号构造游标。
+func AfterSeq(seq int64) Cursor {
+	return Cursor{Seq: seq}
+}
+
+// IsZero 是否为空游标（第一页）。
+func (c Cursor) IsZero() bool {
+	return c == Cursor{}
+}
+
+// CreatedAt 返回游标中的 created_at。
+func (c Cursor) CreatedAt() time.Time {
+	return time.Unix(0, c.Time)
+}
+
+// IsRow 是否为 created_at + id 锚点。
+func (c Cursor) IsRow() bool {
+	return c.Time > 0 && c.ID > 0
+}
+
+// Encode 编码为对客户端不透明的字符串，空游标编码为空串。
+func (c Cursor) Encode() string {
+	if c.IsZero() {
+		return ""
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode 解码游标字符串，空串返回空游标；格式非法或锚点字段不完整时返回 ErrInvalid。
+func Decode(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Cursor{}, ErrInvalid
+	}
+	if !c.IsRow() && c.Seq <= 0 {
+		return Cursor{}, ErrInvalid
+	}
+	return c, nil
+}
@@ -0,0 +1,53 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 678000000, time.UTC)
+
+	t.Run("row", func(t *testing.T) {
+		c := AfterRow(createdAt, 42)
+		got, err := Decode(c.Encode())
+		require.NoError(t, err)
+		assert.Equal(t, c, got)
+		assert.True(t, got.CreatedAt().Equal(createdAt))
+		assert.Equal(t, int64(42), got.ID)
+	})
+
+	t.Run("seq", func(t *testing.T) {
+		got, err := Decode(AfterSeq(1001).Encode())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1001), got.Seq)
+		assert.False(t, got.IsRow())
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		assert.Equal(t, "", Cursor{}.Encode())
+		got, err := Decode("")
+		require.NoError(t, err)
+		assert.True(t, got.IsZero())
+	})
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for name, s := range map[string]string{
+		"not_base64":     "!!!",
+		"not_json":       base64.RawURLEncoding.EncodeToString([]byte("abc")),
+		"missing_id":     base64.RawURLEncoding.EncodeToString([]byte(`{"t":1}`)),
+		"negative_seq":   base64.RawURLEncoding.EncodeToString([]byte(`{"s":-1}`)),
+		"empty_object":   base64.RawURLEncoding.EncodeToString([]byte(`{}`)),
+		"std_base64_pad": base64.StdEncoding.EncodeToString([]byte(`{"s":1}`)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Decode(s)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
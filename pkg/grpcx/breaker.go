@@ -0,0 +1,123 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerConfig gRPC 客户端熔断配置。
+type BreakerConfig struct {
+	// MaxRequests 半开状态下允许通过的探测请求数。
+	MaxRequests uint32
+	// Interval 关闭状态下清零统计的周期。
+	Interval time.Duration
+	// Timeout 打开状态持续多久后进入半开状态。
+	Timeout time.Duration
+	// MinRequests 统计周期内达到该请求数才判断失败率。
+	MinRequests uint32
+	// FailureRatio 失败率阈值（0~1），达到即熔断。
+	FailureRatio float64
+}
+
+// DefaultBreakerConfig 返回默认熔断配置。
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		MaxRequests:  3,
+		Interval:     15 * time.Second,
+		Timeout:      45 * time.Second,
+		MinRequests:  5,
+		FailureRatio: 0.5,
+	}
+}
+
+// NewCircuitBreaker 创建熔断器。
+// 只有下游不可用类错误（见 isBreakerFailure）计入失败，业务错误（参数错误、未找到等）不会触发熔断。
+func NewCircuitBreaker(name string, cfg BreakerConfig) *gobreaker.CircuitBreaker {
+	def := DefaultBreakerConfig()
+	if cfg.MaxRequests == 0 {
+		cfg.MaxRequests = def.MaxRequests
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.MinRequests == 0 {
+		cfg.MinRequests = def.MinRequests
+	}
+	if cfg.FailureRatio <= 0 || cfg.FailureRatio > 1 {
+		cfg.FailureRatio = def.FailureRatio
+	}
+
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: cfg.MaxRequests,
+		Interval:    cfg.Interval,
+		Timeout:     cfg.Timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			if counts.Requests < cfg.MinRequests {
+				return false
+			}
+			return float64(counts.TotalFailures)/float64(counts.Requests) >= cfg.FailureRatio
+		},
+		IsSuccessful: func(err error) bool {
+			return !isBreakerFailure(err)
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			logger.Info(context.Background(), "熔断器状态变化",
+				logger.String("name", name),
+				logger.String("from", from.String()),
+				logger.String("to", to.String()),
+			)
+		},
+	})
+}
+
+// CircuitBreakerUnaryClientInterceptor gRPC 客户端熔断拦截器。
+// 熔断打开（或半开探测名额已满）时不发起调用，直接返回 Unavailable + CodeServiceUnavailable。
+// grpc 内置重试（service config retryPolicy）发生在拦截器之下，
+// 熔断打开时整次调用被拒绝，不会再进入重试。
+func CircuitBreakerUnaryClientInterceptor(cb *gobreaker.CircuitBreaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, err := cb.Execute(func() (interface{}, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		})
+		return BreakerError(err)
+	}
+}
+
+// BreakerError 将熔断器拒绝错误（打开状态 / 半开探测名额已满）转换为 Unavailable + CodeServiceUnavailable，
+// 其他错误原样返回。
+func BreakerError(err error) error {
+	if IsBreakerRejected(err) {
+		return status.Error(codes.Unavailable, strconv.Itoa(consts.CodeServiceUnavailable))
+	}
+	return err
+}
+
+// IsBreakerRejected 判断错误是否为熔断器直接拒绝（打开状态 / 半开探测名额已满），此时未发起下游调用。
+func IsBreakerRejected(err error) bool {
+	return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)
+}
+
+// isBreakerFailure 判断错误是否表示下游不可用（含下游内部错误）。
+// 业务错误与调用方主动取消（Canceled）不计入失败，避免客户端断开或参数错误触发熔断。
+func isBreakerFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Unknown, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,83 @@
+package grpcx
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestBreaker() *gobreaker.CircuitBreaker {
+	logger.ReplaceGlobal(zap.NewNop())
+	return NewCircuitBreaker("test", BreakerConfig{
+		MaxRequests:  1,
+		Interval:     time.Minute,
+		Timeout:      50 * time.Millisecond,
+		MinRequests:  3,
+		FailureRatio: 0.5,
+	})
+}
+
+// callThrough 通过熔断拦截器发起一次调用，invoker 返回 rpcErr。
+func callThrough(cb *gobreaker.CircuitBreaker, rpcErr error) (invoked bool, err error) {
+	err = CircuitBreakerUnaryClientInterceptor(cb)(context.Background(), "/svc/Method", nil, nil, nil,
+		func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			invoked = true
+			return rpcErr
+		})
+	return invoked, err
+}
+
+func TestCircuitBreaker_OpensOnUnavailableAndFastFails(t *testing.T) {
+	cb := newTestBreaker()
+	down := status.Error(codes.Unavailable, "connection refused")
+	for i := 0; i < 3; i++ {
+		_, err := callThrough(cb, down)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	require.Equal(t, gobreaker.StateOpen, cb.State())
+
+	invoked, err := callThrough(cb, nil)
+	assert.False(t, invoked, "熔断打开时不应发起调用")
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Equal(t, strconv.Itoa(consts.CodeServiceUnavailable), st.Message())
+}
+
+func TestCircuitBreaker_BusinessErrorsDoNotTrip(t *testing.T) {
+	cb := newTestBreaker()
+	for i := 0; i < 10; i++ {
+		_, err := callThrough(cb, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError)))
+		require.Error(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		_, _ = callThrough(cb, context.Canceled)
+	}
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+}
+
+func TestCircuitBreaker_HalfOpenProbeCloses(t *testing.T) {
+	cb := newTestBreaker()
+	for i := 0; i < 3; i++ {
+		_, _ = callThrough(cb, status.Error(codes.DeadlineExceeded, "timeout"))
+	}
+	require.Equal(t, gobreaker.StateOpen, cb.State())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, gobreaker.StateHalfOpen, cb.State())
+	invoked, err := callThrough(cb, nil)
+	assert.True(t, invoked)
+	assert.NoError(t, err)
+	assert.Equal(t, gobreaker.StateClosed, cb.State())
+}
@@ -4,39 +4,83 @@ import (
 	"ChatServer/pkg/ctxmeta"
 	"context"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
 // MetadataUnaryInterceptor 将 gRPC incoming metadata 注入到 context 中，
 // 使下游业务代码可通过 ctxmeta 包统一读取 trace_id / user_uuid / device_id / client_ip。
+// 上游未携带 trace_id 时生成新的 trace_id，保证服务端日志始终可关联。
 func MetadataUnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			if traceID := firstValue(md.Get(ctxmeta.MetadataTraceID)); traceID != "" {
-				ctx = ctxmeta.WithTraceID(ctx, traceID)
-			}
-			if userUUID := firstValue(md.Get(ctxmeta.MetadataUserUUID)); userUUID != "" {
-				ctx = ctxmeta.WithUserUUID(ctx, userUUID)
-			}
-			if deviceID := firstValue(md.Get(ctxmeta.MetadataDeviceID)); deviceID != "" {
-				ctx = ctxmeta.WithDeviceID(ctx, deviceID)
-			}
-			clientIP := firstValue(md.Get(ctxmeta.MetadataXRealIP))
-			if clientIP == "" {
-				clientIP = firstValue(md.Get(ctxmeta.MetadataXForwardedFor))
-			}
-			if clientIP == "" {
-				clientIP = firstValue(md.Get(ctxmeta.MetadataClientIP))
-			}
-			if clientIP != "" {
-				ctx = ctxmeta.WithClientIP(ctx, clientIP)
-			}
+		ctx = withIncomingMetadata(ctx)
+		if ctxmeta.TraceID(ctx) == "" {
+			ctx = ctxmeta.WithTraceID(ctx, uuid.New().String())
 		}
 		return handler(ctx, req)
 	}
 }
 
+// MetadataUnaryClientInterceptor 将 context 中的 trace_id / user_uuid / device_id / client_ip 写入 outgoing metadata，
+// 与服务端 MetadataUnaryInterceptor 配对使用，实现跨服务链路透传。
+// context 中没有 trace_id 时（如后台任务发起的调用）生成新的 trace_id。
+func MetadataUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		} else {
+			md = md.Copy()
+		}
+
+		traceID := ctxmeta.TraceID(ctx)
+		if traceID == "" {
+			traceID = uuid.New().String()
+		}
+		md.Set(ctxmeta.MetadataTraceID, traceID)
+		if userUUID := ctxmeta.UserUUID(ctx); userUUID != "" {
+			md.Set(ctxmeta.MetadataUserUUID, userUUID)
+		}
+		if deviceID := ctxmeta.DeviceID(ctx); deviceID != "" {
+			md.Set(ctxmeta.MetadataDeviceID, deviceID)
+		}
+		if clientIP := ctxmeta.ClientIP(ctx); clientIP != "" {
+			md.Set(ctxmeta.MetadataXRealIP, clientIP)
+			md.Set(ctxmeta.MetadataClientIP, clientIP)
+		}
+
+		ctx = metadata.NewOutgoingContext(ctx, md)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// withIncomingMetadata 从 incoming metadata 读取链路字段写入 context。
+func withIncomingMetadata(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if traceID := firstValue(md.Get(ctxmeta.MetadataTraceID)); traceID != "" {
+			ctx = ctxmeta.WithTraceID(ctx, traceID)
+		}
+		if userUUID := firstValue(md.Get(ctxmeta.MetadataUserUUID)); userUUID != "" {
+			ctx = ctxmeta.WithUserUUID(ctx, userUUID)
+		}
+		if deviceID := firstValue(md.Get(ctxmeta.MetadataDeviceID)); deviceID != "" {
+			ctx = ctxmeta.WithDeviceID(ctx, deviceID)
+		}
+		clientIP := firstValue(md.Get(ctxmeta.MetadataXRealIP))
+		if clientIP == "" {
+			clientIP = firstValue(md.Get(ctxmeta.MetadataXForwardedFor))
+		}
+		if clientIP == "" {
+			clientIP = firstValue(md.Get(ctxmeta.MetadataClientIP))
+		}
+		if clientIP != "" {
+			ctx = ctxmeta.WithClientIP(ctx, clientIP)
+		}
+	}
+	return ctx
+}
+
 func firstValue(values []string) string {
 	if len(values) == 0 {
 		return ""
@@ -0,0 +1,73 @@
+package grpcx
+
+import (
+	"context"
+	"testing"
+
+	"ChatServer/pkg/ctxmeta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// invokeClient 执行客户端拦截器并返回写入的 outgoing metadata。
+func invokeClient(t *testing.T, ctx context.Context) metadata.MD {
+	t.Helper()
+	var captured metadata.MD
+	err := MetadataUnaryClientInterceptor()(ctx, "/svc/Method", nil, nil, nil,
+		func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			captured, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	require.NoError(t, err)
+	return captured
+}
+
+// invokeServer 以给定 incoming metadata 执行服务端拦截器并返回 handler 收到的 context。
+func invokeServer(t *testing.T, md metadata.MD) context.Context {
+	t.Helper()
+	var got context.Context
+	_, err := MetadataUnaryInterceptor()(metadata.NewIncomingContext(context.Background(), md), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/svc/Method"},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			got = ctx
+			return nil, nil
+		})
+	require.NoError(t, err)
+	return got
+}
+
+func TestMetadataInterceptors_PropagateTraceID(t *testing.T) {
+	ctx := ctxmeta.WithTraceID(context.Background(), "trace-1")
+	ctx = ctxmeta.WithUserUUID(ctx, "1001")
+	ctx = ctxmeta.WithDeviceID(ctx, "d1")
+	ctx = ctxmeta.WithClientIP(ctx, "10.0.0.1")
+
+	md := invokeClient(t, ctx)
+	assert.Equal(t, []string{"trace-1"}, md.Get(ctxmeta.MetadataTraceID))
+
+	serverCtx := invokeServer(t, md)
+	assert.Equal(t, "trace-1", ctxmeta.TraceID(serverCtx))
+	assert.Equal(t, "1001", ctxmeta.UserUUID(serverCtx))
+	assert.Equal(t, "d1", ctxmeta.DeviceID(serverCtx))
+	assert.Equal(t, "10.0.0.1", ctxmeta.ClientIP(serverCtx))
+}
+
+func TestMetadataUnaryClientInterceptor_GeneratesTraceID(t *testing.T) {
+	md := invokeClient(t, context.Background())
+	assert.NotEmpty(t, md.Get(ctxmeta.MetadataTraceID))
+}
+
+func TestMetadataUnaryClientInterceptor_KeepsExistingOutgoingMetadata(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-custom", "v")
+	md := invokeClient(t, ctxmeta.WithTraceID(ctx, "trace-2"))
+	assert.Equal(t, []string{"v"}, md.Get("x-custom"))
+	assert.Equal(t, []string{"trace-2"}, md.Get(ctxmeta.MetadataTraceID))
+}
+
+func TestMetadataUnaryInterceptor_GeneratesTraceIDWhenAbsent(t *testing.T) {
+	serverCtx := invokeServer(t, metadata.MD{})
+	assert.NotEmpty(t, ctxmeta.TraceID(serverCtx))
+}
@@ -5,8 +5,9 @@ import (
 	"net/http"
 	"time"
 
+	"ChatServer/pkg/metrics"
+
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
 )
@@ -110,13 +111,13 @@ func (m *Metrics) UnaryInterceptor() grpc.UnaryServerInterceptor {
 }
 
 // Handler 返回 Prometheus HTTP handler，用于暴露 /metrics 端点。
-// 使用独立 Registry 的 handler，仅包含本服务的指标。
+// 使用独立 Registry 的 handler，仅包含本服务的指标；指标携带 service / version 公共标签。
 func (m *Metrics) Handler() http.Handler {
-	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return metrics.HandlerFor(m.registry)
 }
 
 // DefaultHandler 返回使用默认全局 Registry 的 handler，
-// 兼容已有的 Prometheus 集成（包含 Go runtime 等自带指标）。
+// 兼容已有的 Prometheus 集成（包含 Go runtime 等自带指标）；指标携带 service / version 公共标签。
 func DefaultHandler() http.Handler {
-	return promhttp.Handler()
+	return metrics.Handler()
 }
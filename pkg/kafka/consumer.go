@@ -2,6 +2,9 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"io"
+	"sync"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -53,3 +56,113 @@ func (c *Consumer) Start(ctx context.Context, handler MessageHandler) error {
 func (c *Consumer) Close() error {
 	return c.reader.Close()
 }
+
+// ==================== BroadcastConsumer 定义 ====================
+
+// BroadcastConsumer 不加入消费组的广播消费者：直接读取 topic 的全部分区，从启动时的最新 offset 开始，不提交 offset。
+// 适用于每个实例都需要收到全量事件、且只关心启动之后事件的场景（如节点本地推送），
+// 不会像按实例命名的消费组那样在每次发布后遗留无人使用的消费组。
+// 分区列表在 Start 时确定，运行期间新增的分区需重启后才会读取。
+type BroadcastConsumer struct {
+	brokers []string
+	topic   string
+
+	mu      sync.Mutex
+	readers []*kafka.Reader
+	closed  bool
+}
+
+// NewBroadcastConsumer 创建广播消费者
+func NewBroadcastConsumer(brokers []string, topic string) *BroadcastConsumer {
+	return &BroadcastConsumer{brokers: brokers, topic: topic}
+}
+
+// Start 查询分区并为每个分区启动读取协程（阻塞式运行，直到 ctx 取消）
+func (c *BroadcastConsumer) Start(ctx context.Context, handler MessageHandler) error {
+	partitions, err := c.lookupPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   c.brokers,
+			Topic:     c.topic,
+			Partition: p.ID,
+		})
+		if err := reader.SetOffset(kafka.LastOffset); err != nil {
+			_ = reader.Close()
+			continue
+		}
+		if !c.track(reader) {
+			_ = reader.Close()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					msg, err := reader.ReadMessage(ctx)
+					if err != nil {
+						// ctx 取消或 reader 已关闭时退出，其余错误继续读取
+						if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) {
+							return
+						}
+						continue
+					}
+					_ = handler(ctx, msg.Value)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// lookupPartitions 依次尝试各 broker 查询 topic 的分区列表
+func (c *BroadcastConsumer) lookupPartitions(ctx context.Context) ([]kafka.Partition, error) {
+	dialer := &kafka.Dialer{}
+	var lastErr error
+	for _, broker := range c.brokers {
+		partitions, err := dialer.LookupPartitions(ctx, "tcp", broker, c.topic)
+		if err == nil {
+			return partitions, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("kafka: no brokers configured")
+	}
+	return nil, lastErr
+}
+
+// track 登记分区 reader 以便 Close 统一关闭；已关闭时返回 false
+func (c *BroadcastConsumer) track(reader *kafka.Reader) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.readers = append(c.readers, reader)
+	return true
+}
+
+// Close 关闭全部分区 reader
+func (c *BroadcastConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var errs []error
+	for _, reader := range c.readers {
+		if err := reader.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.readers = nil
+	return errors.Join(errs...)
+}
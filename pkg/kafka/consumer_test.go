@@ -0,0 +1,21 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastConsumer_StartWithoutBrokersFails(t *testing.T) {
+	c := NewBroadcastConsumer(nil, "user-presence")
+	require.Error(t, c.Start(context.Background(), func(context.Context, []byte) error { return nil }))
+	assert.NoError(t, c.Close())
+}
+
+func TestBroadcastConsumer_ClosedRejectsNewReaders(t *testing.T) {
+	c := NewBroadcastConsumer([]string{"127.0.0.1:9092"}, "user-presence")
+	require.NoError(t, c.Close())
+	assert.False(t, c.track(nil), "关闭后不再登记分区 reader")
+}
@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ==================== Inspector 定义 ====================
+
+// Inspector Kafka 只读检查器
+// 使用独立的消费者组从最早位置读取，且从不提交 offset，
+// 因此每次检查都能看到 topic 中的全部消息，也不会影响线上消费者。
+// 主要用于死信队列等需要人工排查的场景。
+type Inspector struct {
+	reader *kafka.Reader
+}
+
+// NewInspector 创建 Kafka 只读检查器
+func NewInspector(brokers []string, topic, groupID string) *Inspector {
+	return &Inspector{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     brokers,
+			Topic:       topic,
+			GroupID:     groupID,
+			StartOffset: kafka.FirstOffset,
+		}),
+	}
+}
+
+// Read 读取至多 limit 条消息并交给 handler 处理（不提交 offset）
+// ctx 超时或取消视为已读到末尾，返回已读取的条数；handler 返回错误时立即中止。
+func (i *Inspector) Read(ctx context.Context, limit int, handler MessageHandler) (int, error) {
+	read := 0
+	for limit <= 0 || read < limit {
+		msg, err := i.reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return read, nil
+			}
+			return read, err
+		}
+		if err := handler(ctx, msg.Value); err != nil {
+			return read, err
+		}
+		read++
+	}
+	return read, nil
+}
+
+// Close 关闭检查器
+func (i *Inspector) Close() error {
+	return i.reader.Close()
+}
@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus 指标定义（注册到默认 Registry，由引入该包的服务 /metrics 暴露）
+
+// RetryStage 重试任务在管道中所处的阶段
+type RetryStage string
+
+const (
+	RetryStagePublished    RetryStage = "published"     // 业务侧首次投递到重试队列
+	RetryStageConsumed     RetryStage = "consumed"      // 消费者取到任务
+	RetryStageRetried      RetryStage = "retried"       // 执行失败，重新投递等待下次重试
+	RetryStageSucceeded    RetryStage = "succeeded"     // 重试执行成功
+	RetryStageDeadLettered RetryStage = "dead_lettered" // 重试次数耗尽，进入死信
+)
+
+// unknownSource 任务未设置来源时使用的标签值
+const unknownSource = "unknown"
+
+// DeadLetterReasonRetriesExhausted 死信原因：重试次数耗尽
+const DeadLetterReasonRetriesExhausted = "retries_exhausted"
+
+// retryTasksTotal 计数器：记录重试管道各阶段的任务数
+// 标签：
+//   - stage: 阶段 (published, consumed, retried, succeeded, dead_lettered)
+//   - source: 任务来源（WithSource 的值，如 DeviceRepository.StoreAccessToken）
+//   - type: 命令类型 (simple, pipeline, lua)
+var retryTasksTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kafka_retry_tasks_total",
+		Help: "Total number of Redis retry tasks by pipeline stage",
+	},
+	[]string{"stage", "source", "type"},
+)
+
+// retryTaskRecoveryDuration 直方图：记录任务从首次失败到最终结果（成功/死信）的耗时
+// 标签：
+//   - source: 任务来源
+//   - type: 命令类型
+//   - outcome: 最终结果 (succeeded, dead_lettered)
+var retryTaskRecoveryDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "kafka_retry_task_recovery_seconds",
+		Help:    "Time from the original Redis failure to the final retry outcome in seconds",
+		Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	},
+	[]string{"source", "type", "outcome"},
+)
+
+// deadLetterTotal 计数器：记录投递到死信 topic 的消息数（所有死信投递统一计入该指标）
+// 标签：
+//   - topic: 死信 topic
+//   - reason: 进入死信的原因（如 retries_exhausted）
+//   - result: 投递结果 (ok, error)
+var deadLetterTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kafka_dead_letter_total",
+		Help: "Total number of messages published to dead-letter topics",
+	},
+	[]string{"topic", "reason", "result"},
+)
+
+// RecordDeadLetter 记录一次死信投递，err 非 nil 表示投递失败
+func RecordDeadLetter(topic, reason string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	deadLetterTotal.WithLabelValues(sourceLabel(topic), reason, result).Inc()
+}
+
+// GetDeadLetterTotal 获取死信投递计数指标
+func GetDeadLetterTotal() *prometheus.CounterVec {
+	return deadLetterTotal
+}
+
+// RecordRetryTask 记录一次重试任务阶段变化
+func RecordRetryTask(stage RetryStage, source, cmdType string) {
+	retryTasksTotal.WithLabelValues(string(stage), sourceLabel(source), cmdType).Inc()
+}
+
+// ObserveRetryRecovery 记录任务最终结果及其自首次失败以来的耗时
+// since 为零值时只计数不观测耗时。
+func ObserveRetryRecovery(outcome RetryStage, source, cmdType string, since time.Time) {
+	RecordRetryTask(outcome, source, cmdType)
+	if since.IsZero() {
+		return
+	}
+	retryTaskRecoveryDuration.WithLabelValues(sourceLabel(source), cmdType, string(outcome)).
+		Observe(time.Since(since).Seconds())
+}
+
+func sourceLabel(source string) string {
+	if source == "" {
+		return unknownSource
+	}
+	return source
+}
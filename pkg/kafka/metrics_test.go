@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recoverySampleCount(t *testing.T, source, cmdType string, outcome RetryStage) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	observer := retryTaskRecoveryDuration.WithLabelValues(source, cmdType, string(outcome))
+	require.NoError(t, observer.(prometheus.Metric).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRecordRetryTask_LabelsBySourceAndType(t *testing.T) {
+	counter := retryTasksTotal.WithLabelValues(string(RetryStageRetried), "DeviceRepository.StoreAccessToken", "simple")
+	before := testutil.ToFloat64(counter)
+
+	RecordRetryTask(RetryStageRetried, "DeviceRepository.StoreAccessToken", "simple")
+	RecordRetryTask(RetryStageRetried, "DeviceRepository.StoreAccessToken", "simple")
+
+	assert.Equal(t, before+2, testutil.ToFloat64(counter))
+}
+
+func TestRecordRetryTask_EmptySourceUsesUnknown(t *testing.T) {
+	counter := retryTasksTotal.WithLabelValues(string(RetryStagePublished), unknownSource, "lua")
+	before := testutil.ToFloat64(counter)
+
+	RecordRetryTask(RetryStagePublished, "", "lua")
+
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestObserveRetryRecovery(t *testing.T) {
+	counter := retryTasksTotal.WithLabelValues(string(RetryStageSucceeded), "AuthRepository.StoreVerifyCode", "simple")
+	before := testutil.ToFloat64(counter)
+	samplesBefore := recoverySampleCount(t, "AuthRepository.StoreVerifyCode", "simple", RetryStageSucceeded)
+
+	ObserveRetryRecovery(RetryStageSucceeded, "AuthRepository.StoreVerifyCode", "simple", time.Now().Add(-time.Second))
+	// 无起始时间：只计数，不观测耗时
+	ObserveRetryRecovery(RetryStageSucceeded, "AuthRepository.StoreVerifyCode", "simple", time.Time{})
+
+	assert.Equal(t, before+2, testutil.ToFloat64(counter))
+	assert.Equal(t, samplesBefore+1, recoverySampleCount(t, "AuthRepository.StoreVerifyCode", "simple", RetryStageSucceeded))
+}
+
+func TestRecordDeadLetter(t *testing.T) {
+	ok := deadLetterTotal.WithLabelValues("redis-retry-dlq", DeadLetterReasonRetriesExhausted, "ok")
+	failed := deadLetterTotal.WithLabelValues("unknown", DeadLetterReasonRetriesExhausted, "error")
+	beforeOK, beforeFailed := testutil.ToFloat64(ok), testutil.ToFloat64(failed)
+
+	RecordDeadLetter("redis-retry-dlq", DeadLetterReasonRetriesExhausted, nil)
+	RecordDeadLetter("", DeadLetterReasonRetriesExhausted, assert.AnError)
+
+	assert.Equal(t, beforeOK+1, testutil.ToFloat64(ok))
+	assert.Equal(t, beforeFailed+1, testutil.ToFloat64(failed), "未知 topic 记为 unknown")
+}
@@ -25,6 +25,11 @@ func NewProducer(brokers []string, topic string) *Producer {
 	}
 }
 
+// Topic 返回生产者写入的 topic
+func (p *Producer) Topic() string {
+	return p.writer.Topic
+}
+
 // Send 发送消息到 Kafka
 func (p *Producer) Send(ctx context.Context, data []byte) error {
 	return p.writer.WriteMessages(ctx, kafka.Message{
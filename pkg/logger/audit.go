@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// BuildAudit 构建独立的审计日志 logger：固定 JSON 编码、Info 级别、不采样，
+// 不受运行期日志级别（见 Level）影响，与业务日志分开输出，便于单独投递到 SIEM。
+// outputPaths 为空时输出到 stdout；路径语义与 LoggerConfig.OutputPaths 一致（文件无滚动）。
+func BuildAudit(outputPaths []string) *zap.Logger {
+	encoderCfg := newEncoderConfig()
+	encoderCfg.CallerKey = zapcore.OmitKey
+	encoderCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(encoderCfg),
+		buildSyncer(outputPaths, zapcore.AddSync(os.Stdout)),
+		zapcore.InfoLevel,
+	)
+	return zap.New(core).Named("audit")
+}
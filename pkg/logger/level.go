@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// atomicLevel 进程级日志级别，Build 创建的所有 logger 共享，运行期修改立即生效。
+var atomicLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+// Level 返回进程级日志级别。
+func Level() zap.AtomicLevel {
+	return atomicLevel
+}
+
+// LevelHandler 返回运行期查看/修改日志级别的 HTTP 处理器（挂载到内部监控端口的 /admin/loglevel）：
+//   - GET：返回 {"level":"info"}；
+//   - PUT：请求体 {"level":"debug"}，修改后返回新级别。
+//
+// 请求需携带 Authorization: Bearer <token>（或 X-Admin-Token: <token>），不匹配返回 401；
+// token 为空时拒绝所有请求，避免未配置口令时接口被匿名调用。
+func LevelHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !adminTokenValid(r, token) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+
+		before := atomicLevel.Level()
+		atomicLevel.ServeHTTP(w, r)
+		if after := atomicLevel.Level(); after != before {
+			Warn(r.Context(), "日志级别已变更",
+				String("from", before.String()),
+				String("to", after.String()),
+				String("remote_addr", r.RemoteAddr),
+			)
+		}
+	})
+}
+
+// adminTokenValid 以常量时间比较请求携带的管理口令。
+func adminTokenValid(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got := r.Header.Get("X-Admin-Token")
+	if auth := r.Header.Get("Authorization"); got == "" && strings.HasPrefix(auth, "Bearer ") {
+		got = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
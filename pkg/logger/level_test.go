@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ChatServer/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func serveLevel(h http.Handler, method, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("X-Admin-Token", token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLevelHandler(t *testing.T) {
+	ReplaceGlobal(zap.NewNop())
+	cfg := config.DefaultLoggerConfig()
+	cfg.OutputPaths = []string{"stderr"}
+	built, err := Build(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { atomicLevel.SetLevel(zapcore.InfoLevel) })
+	h := LevelHandler("secret")
+
+	t.Run("rejects_missing_or_wrong_token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serveLevel(h, http.MethodGet, "", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serveLevel(h, http.MethodPut, "wrong", `{"level":"debug"}`).Code)
+		assert.Equal(t, zapcore.InfoLevel, Level().Level())
+	})
+
+	t.Run("empty_token_rejects_all", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serveLevel(LevelHandler(""), http.MethodGet, "", "").Code)
+	})
+
+	t.Run("get_and_put_affect_existing_logger", func(t *testing.T) {
+		rec := serveLevel(h, http.MethodGet, "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+		assert.False(t, built.Core().Enabled(zapcore.DebugLevel))
+
+		rec = serveLevel(h, http.MethodPut, "secret", `{"level":"debug"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+		assert.True(t, built.Core().Enabled(zapcore.DebugLevel), "已创建的 logger 立即生效")
+	})
+
+	t.Run("invalid_level", func(t *testing.T) {
+		rec := serveLevel(h, http.MethodPut, "secret", `{"level":"verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -32,25 +32,18 @@ func ReplaceGlobal(l *zap.Logger) {
 // - 默认输出 stdout/stderr（容器场景方便 docker logs）。
 // - 可通过 OutputPaths/ErrorOutputPaths 写入文件（无滚动，滚动由外部系统负责）。
 // - 自动根据 Level 解析日志级别，配置错误时回退到 info。
+// - 级别写入进程级 AtomicLevel（见 Level），运行期修改对已创建的 logger 立即生效。
+// - 开启采样时 Error 以下级别按 Sampling 限流，Error 及以上级别始终完整输出（见 buildCore）。
 func Build(cfg config.LoggerConfig) (*zap.Logger, error) {
-	level := zap.NewAtomicLevel()
-	if err := level.UnmarshalText([]byte(strings.ToLower(cfg.Level))); err != nil {
+	var parsed zapcore.Level
+	if err := parsed.UnmarshalText([]byte(strings.ToLower(cfg.Level))); err != nil {
 		// 回退到 info，避免配置错误导致崩溃
-		level = zap.NewAtomicLevelAt(zap.InfoLevel)
+		parsed = zapcore.InfoLevel
 	}
+	level := atomicLevel
+	level.SetLevel(parsed)
 
-	encoderCfg := zapcore.EncoderConfig{
-		TimeKey:        "ts",                                          // 时间戳
-		LevelKey:       "level",                                       // 日志级别
-		NameKey:        "logger",                                      // 日志名称
-		CallerKey:      "caller",                                      // 调用者
-		MessageKey:     "msg",                                         // 消息
-		StacktraceKey:  "stack",                                       // 堆栈
-		LineEnding:     zapcore.DefaultLineEnding,                     // 行结束符
-		EncodeTime:     zapcore.TimeEncoderOfLayout(time.RFC3339Nano), // 统一时间格式
-		EncodeDuration: zapcore.MillisDurationEncoder,                 // 耗时以毫秒输出
-		EncodeCaller:   zapcore.ShortCallerEncoder,                    // 文件:行 短路径
-	}
+	encoderCfg := newEncoderConfig()
 	// 根据 Encoding 配置选择编码器
 	var encoder zapcore.Encoder
 	if strings.ToLower(cfg.Encoding) == "console" {
@@ -68,7 +61,7 @@ func Build(cfg config.LoggerConfig) (*zap.Logger, error) {
 	outSync := buildSyncer(cfg.OutputPaths, zapcore.AddSync(os.Stdout))      // 普通日志输出
 	errSync := buildSyncer(cfg.ErrorOutputPaths, zapcore.AddSync(os.Stderr)) // 错误日志输出
 
-	core := zapcore.NewCore(encoder, outSync, level)
+	core := buildCore(encoder, outSync, level, cfg.Sampling)
 	opts := []zap.Option{
 		zap.ErrorOutput(errSync),
 		zap.AddCaller(),
@@ -81,6 +74,47 @@ func Build(cfg config.LoggerConfig) (*zap.Logger, error) {
 	return zap.New(core, opts...), nil
 }
 
+// newEncoderConfig 返回统一的编码配置（字段名、时间格式、耗时单位），业务日志与审计日志共用。
+func newEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "ts",                                          // 时间戳
+		LevelKey:       "level",                                       // 日志级别
+		NameKey:        "logger",                                      // 日志名称
+		CallerKey:      "caller",                                      // 调用者
+		MessageKey:     "msg",                                         // 消息
+		StacktraceKey:  "stack",                                       // 堆栈
+		LineEnding:     zapcore.DefaultLineEnding,                     // 行结束符
+		EncodeTime:     zapcore.TimeEncoderOfLayout(time.RFC3339Nano), // 统一时间格式
+		EncodeDuration: zapcore.MillisDurationEncoder,                 // 耗时以毫秒输出
+		EncodeCaller:   zapcore.ShortCallerEncoder,                    // 文件:行 短路径
+	}
+}
+
+// buildCore 构建日志 Core：未开启采样时直接返回；开启时拆为“Error 以下采样 + Error 及以上不采样”两路。
+// 同级别同内容的日志每周期先输出 Initial 条，之后每 Thereafter 条输出 1 条，降级期间日志量有上限且不丢失首次出现的日志。
+// 两路共用同一个 AtomicLevel，运行期调整级别仍然生效。
+func buildCore(encoder zapcore.Encoder, out zapcore.WriteSyncer, level zap.AtomicLevel, sampling config.LoggerSamplingConfig) zapcore.Core {
+	if !sampling.Enabled() {
+		return zapcore.NewCore(encoder, out, level)
+	}
+	tick := sampling.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	thereafter := max(sampling.Thereafter, 1)
+
+	belowError := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l < zapcore.ErrorLevel && level.Enabled(l)
+	})
+	errorAndAbove := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= zapcore.ErrorLevel && level.Enabled(l)
+	})
+	return zapcore.NewTee(
+		zapcore.NewSamplerWithOptions(zapcore.NewCore(encoder, out, belowError), tick, sampling.Initial, thereafter),
+		zapcore.NewCore(encoder.Clone(), out, errorAndAbove),
+	)
+}
+
 // buildSyncer 根据配置构建 WriteSyncer：
 // - 支持 stdout/stderr 关键字。
 // - 支持直接写文件（无滚动），打开失败则回退到 fallback。
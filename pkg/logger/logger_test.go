@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatServer/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild_SamplingBypassesErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := config.DefaultLoggerConfig()
+	cfg.OutputPaths = []string{path}
+	cfg.Sampling = config.LoggerSamplingConfig{Initial: 2, Thereafter: 100, Tick: time.Minute}
+	l, err := Build(cfg)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		l.Warn("Redis 限流检查超时，降级放行")
+		l.Error("数据库写入失败")
+	}
+	require.NoError(t, l.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	out := string(data)
+	assert.Equal(t, 2, strings.Count(out, "Redis 限流检查超时"), "Warn 只保留每周期前 Initial 条")
+	assert.Equal(t, 10, strings.Count(out, "数据库写入失败"), "Error 不采样")
+}
+
+func TestBuild_SamplingDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := config.DefaultLoggerConfig()
+	cfg.OutputPaths = []string{path}
+	cfg.Sampling = config.LoggerSamplingConfig{}
+	l, err := Build(cfg)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		l.Warn("Redis 限流检查超时，降级放行")
+	}
+	require.NoError(t, l.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 10, strings.Count(string(data), "Redis 限流检查超时"))
+}
@@ -0,0 +1,144 @@
+// Package metrics 为各服务暴露的 Prometheus 指标统一注入 service / version 常量标签。
+//
+// 现有指标大多通过 promauto 在包初始化时注册到默认 Registry，此时服务名与版本尚未确定，
+// 因此标签不在注册时写入，而是在 /metrics 抓取（Gather）时附加到每个指标上：
+// 服务启动时调用 Init，暴露 /metrics 时使用 Handler / Gatherer 包装。
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// LabelService 服务名标签（gateway / user / connect / msg）。
+	LabelService = "service"
+	// LabelVersion 构建版本标签。
+	LabelVersion = "version"
+)
+
+// Version 构建版本，可通过 -ldflags "-X ChatServer/pkg/metrics.Version=v1.2.3" 注入。
+// 未注入且未配置 APP_VERSION 时回退到 VCS 修订号。
+var Version string
+
+var (
+	mu     sync.RWMutex
+	labels prometheus.Labels
+)
+
+// Init 设置当前进程的 service / version 标签，应在暴露 /metrics 之前调用一次。
+// version 为空时依次回退到 Version、构建信息中的 vcs.revision、"unknown"。
+func Init(service, version string) {
+	if version == "" {
+		version = buildVersion()
+	}
+	mu.Lock()
+	labels = prometheus.Labels{LabelService: service, LabelVersion: version}
+	mu.Unlock()
+}
+
+// ConstLabels 返回当前的常量标签副本，未调用 Init 时返回空。
+func ConstLabels() prometheus.Labels {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make(prometheus.Labels, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+// TextLabels 返回文本格式的常量标签（如 service="connect",version="v1"），按标签名排序，
+// 供手写 Prometheus 文本格式的 /metrics 使用；未调用 Init 时返回空串。
+func TextLabels() string {
+	constLabels := ConstLabels()
+	names := make([]string, 0, len(constLabels))
+	for name := range constLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, constLabels[name]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Handler 返回暴露默认 Registry 的 /metrics handler，所有指标带 service / version 标签。
+func Handler() http.Handler {
+	return HandlerFor(prometheus.DefaultGatherer)
+}
+
+// HandlerFor 返回暴露指定 Gatherer 的 /metrics handler，所有指标带 service / version 标签。
+func HandlerFor(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(Gatherer(g), promhttp.HandlerOpts{})
+}
+
+// Gatherer 包装 g：每次 Gather 时为所有指标附加当前的常量标签。
+func Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return WithLabels(g, ConstLabels()).Gather()
+	})
+}
+
+// WithLabels 包装 g：为所有指标附加固定标签。
+// 指标自身已有同名标签时返回错误（与 prometheus.WrapRegistererWith 的冲突处理一致），
+// 避免静默覆盖业务标签。
+func WithLabels(g prometheus.Gatherer, constLabels prometheus.Labels) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		if err != nil || len(constLabels) == 0 {
+			return families, err
+		}
+		pairs := make([]*dto.LabelPair, 0, len(constLabels))
+		for name, value := range constLabels {
+			pairs = append(pairs, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+		}
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				for _, existing := range metric.GetLabel() {
+					if _, dup := constLabels[existing.GetName()]; dup {
+						return nil, fmt.Errorf("metric %s already has label %q", family.GetName(), existing.GetName())
+					}
+				}
+				metric.Label = append(metric.Label, pairs...)
+				sort.Slice(metric.Label, func(i, j int) bool {
+					return metric.Label[i].GetName() < metric.Label[j].GetName()
+				})
+			}
+		}
+		// 经 Gatherers 再做一次一致性校验与排序
+		return prometheus.Gatherers{staticGatherer(families)}.Gather()
+	})
+}
+
+type staticGatherer []*dto.MetricFamily
+
+func (s staticGatherer) Gather() ([]*dto.MetricFamily, error) { return s, nil }
+
+// buildVersion 从 ldflags 注入值或构建信息中解析版本。
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && setting.Value != "" {
+				if len(setting.Value) > 12 {
+					return setting.Value[:12]
+				}
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetLabels(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		labels = nil
+		mu.Unlock()
+	})
+}
+
+func TestGatherer_InjectsConstLabels(t *testing.T) {
+	resetLabels(t)
+	Init("gateway", "v1.2.3")
+
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sample_requests_total",
+		Help: "sample",
+	}, []string{"method"})
+	reg.MustRegister(requests)
+	requests.WithLabelValues("GET").Inc()
+
+	families, err := Gatherer(reg).Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Len(t, families[0].GetMetric(), 1)
+
+	got := map[string]string{}
+	for _, pair := range families[0].GetMetric()[0].GetLabel() {
+		got[pair.GetName()] = pair.GetValue()
+	}
+	assert.Equal(t, map[string]string{"method": "GET", "service": "gateway", "version": "v1.2.3"}, got)
+
+	rec := httptest.NewRecorder()
+	HandlerFor(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	assert.Contains(t, string(body), `sample_requests_total{method="GET",service="gateway",version="v1.2.3"} 1`)
+}
+
+func TestWithLabels_RejectsCollision(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	calls := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sample_calls_total",
+		Help: "sample",
+	}, []string{"service"})
+	reg.MustRegister(calls)
+	calls.WithLabelValues("user.AuthService").Inc()
+
+	_, err := WithLabels(reg, prometheus.Labels{LabelService: "gateway"}).Gather()
+	assert.Error(t, err)
+}
+
+func TestInit_VersionFallback(t *testing.T) {
+	resetLabels(t)
+	assert.Empty(t, TextLabels(), "未初始化时不输出标签")
+
+	original := Version
+	t.Cleanup(func() { Version = original })
+	Version = "ldflags-version"
+
+	Init("connect", "")
+	assert.Equal(t, `service="connect",version="ldflags-version"`, TextLabels())
+
+	Init("connect", "env-version")
+	assert.Equal(t, "env-version", ConstLabels()[LabelVersion])
+}
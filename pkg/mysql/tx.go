@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ChatServer/pkg/logger"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQL 服务端错误码
+const (
+	errDuplicateEntry = 1062 // ER_DUP_ENTRY
+	errLockDeadlock   = 1213 // ER_LOCK_DEADLOCK
+)
+
+// 死锁重试参数：死锁在对方事务提交/回滚后即可消除，短暂线性退避后整体重试。
+const (
+	deadlockMaxAttempts = 3
+	deadlockBackoff     = 20 * time.Millisecond
+)
+
+// TxRunner 可开启事务的数据库句柄（*gorm.DB 实现该接口）。
+type TxRunner interface {
+	Transaction(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error
+}
+
+// IsDuplicateEntry 判断是否为唯一键冲突（MySQL 1062）。
+func IsDuplicateEntry(err error) bool {
+	return mysqlErrorNumber(err) == errDuplicateEntry
+}
+
+// IsDeadlock 判断是否为事务死锁（MySQL 1213），此类错误整体重试事务即可恢复。
+func IsDeadlock(err error) bool {
+	return mysqlErrorNumber(err) == errLockDeadlock
+}
+
+func mysqlErrorNumber(err error) uint16 {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number
+	}
+	return 0
+}
+
+// RetryOnDeadlock 执行 fn，遇到死锁时按线性退避重试（最多 3 次），非死锁错误立即返回。
+// fn 必须可整体重放（如单条语句或完整事务）。
+func RetryOnDeadlock(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= deadlockMaxAttempts; attempt++ {
+		err = fn()
+		if !IsDeadlock(err) || attempt == deadlockMaxAttempts {
+			return err
+		}
+		logger.Warn(ctx, "数据库死锁，准备重试",
+			logger.Int("attempt", attempt),
+			logger.ErrorField("error", err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * deadlockBackoff):
+		}
+	}
+	return err
+}
+
+// RunInTxWithRetry 在 GORM 事务中执行 fn，死锁时回滚并重试整个事务。
+// db 应已绑定请求 context（如 r.db.WithContext(ctx)）；ctx 用于重试退避期间的取消。
+// fn 可能被执行多次，不应在其中产生事务外副作用（缓存更新、消息投递等放到提交之后）。
+func RunInTxWithRetry(ctx context.Context, db TxRunner, fn func(tx *gorm.DB) error) error {
+	return RetryOnDeadlock(ctx, func() error {
+		return db.Transaction(fn)
+	})
+}
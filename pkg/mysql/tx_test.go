@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"ChatServer/pkg/logger"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeTx 前 failures 次事务返回 err，之后执行 fn。
+type fakeTx struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *fakeTx) Transaction(fc func(tx *gorm.DB) error, _ ...*sql.TxOptions) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return fc(nil)
+}
+
+func deadlockErr() error {
+	return fmt.Errorf("exec: %w", &mysqldriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+}
+
+func TestRunInTxWithRetry(t *testing.T) {
+	logger.ReplaceGlobal(zap.NewNop())
+
+	t.Run("succeeds after deadlocks", func(t *testing.T) {
+		tx := &fakeTx{failures: 2, err: deadlockErr()}
+		fnCalls := 0
+		err := RunInTxWithRetry(context.Background(), tx, func(*gorm.DB) error {
+			fnCalls++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, tx.calls)
+		assert.Equal(t, 1, fnCalls)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		tx := &fakeTx{failures: 10, err: deadlockErr()}
+		err := RunInTxWithRetry(context.Background(), tx, func(*gorm.DB) error { return nil })
+		assert.True(t, IsDeadlock(err))
+		assert.Equal(t, deadlockMaxAttempts, tx.calls)
+	})
+
+	t.Run("non-deadlock error not retried", func(t *testing.T) {
+		dup := &mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry"}
+		tx := &fakeTx{failures: 10, err: dup}
+		err := RunInTxWithRetry(context.Background(), tx, func(*gorm.DB) error { return nil })
+		assert.True(t, IsDuplicateEntry(err))
+		assert.Equal(t, 1, tx.calls)
+	})
+
+	t.Run("fn error returned as is", func(t *testing.T) {
+		tx := &fakeTx{}
+		boom := errors.New("boom")
+		err := RunInTxWithRetry(context.Background(), tx, func(*gorm.DB) error { return boom })
+		assert.ErrorIs(t, err, boom)
+		assert.Equal(t, 1, tx.calls)
+	})
+
+	t.Run("canceled context stops retrying", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		tx := &fakeTx{failures: 10, err: deadlockErr()}
+		err := RunInTxWithRetry(ctx, tx, func(*gorm.DB) error { return nil })
+		assert.True(t, IsDeadlock(err))
+		assert.Equal(t, 1, tx.calls)
+	})
+}
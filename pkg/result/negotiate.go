@@ -0,0 +1,73 @@
+package result
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// MIMEProtobuf protobuf 响应的 Content-Type / Accept 值。
+	MIMEProtobuf = binding.MIMEPROTOBUF
+
+	// HeaderBizCode protobuf 响应中携带业务状态码的响应头（body 仅为 data 消息本身）。
+	HeaderBizCode = "X-Biz-Code"
+	// HeaderTraceID protobuf 响应中携带 trace_id 的响应头。
+	HeaderTraceID = "X-Trace-Id"
+)
+
+// ProtoConvertible 支持 protobuf 响应的 data（通常为列表类接口的响应 DTO）。
+// 客户端 Accept: application/x-protobuf 时，Result 输出 ToProto() 的编码结果。
+type ProtoConvertible interface {
+	ToProto() proto.Message
+}
+
+var protobufEnabled atomic.Bool
+
+func init() {
+	protobufEnabled.Store(true)
+}
+
+// SetProtobufEnabled 设置是否允许按 Accept 协商返回 protobuf（关闭后总是返回 JSON）。
+func SetProtobufEnabled(enabled bool) {
+	protobufEnabled.Store(enabled)
+}
+
+// wantsProtobuf 判断客户端是否要求 protobuf 响应（按 Accept 中的顺序匹配，未声明或为 */* 时返回 JSON）。
+func wantsProtobuf(c *gin.Context) bool {
+	if c.Request == nil {
+		return false
+	}
+	return c.NegotiateFormat(binding.MIMEJSON, MIMEProtobuf) == MIMEProtobuf
+}
+
+// writeProtobuf 按 Accept 协商输出 protobuf 响应，返回是否已写出。
+// 仅当 data 实现 ProtoConvertible 时生效；其余情况（含错误响应）仍由调用方输出 JSON。
+// code/trace_id 通过响应头返回，body 为 data 消息本身，客户端可直接用对应的 pb 类型解码。
+func writeProtobuf(c *gin.Context, httpStatus, code int, traceID string, data interface{}) bool {
+	convertible, ok := data.(ProtoConvertible)
+	if !ok || !protobufEnabled.Load() {
+		return false
+	}
+	// 同一 URL 的响应格式取决于 Accept，避免中间缓存混用 JSON 与 protobuf。
+	c.Header("Vary", "Accept")
+	if !wantsProtobuf(c) {
+		return false
+	}
+	msg := convertible.ToProto()
+	if msg == nil {
+		return false
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return false
+	}
+
+	c.Header(HeaderBizCode, strconv.Itoa(code))
+	c.Header(HeaderTraceID, traceID)
+	c.Data(httpStatus, MIMEProtobuf, body)
+	return true
+}
@@ -59,6 +59,11 @@ func Result(c *gin.Context, data interface{}, message string, code int) {
 	// 将业务状态码存储到 context 中供监控中间件使用
 	c.Set("business_code", code)
 
+	// 客户端 Accept: application/x-protobuf 且 data 支持时返回 protobuf
+	if writeProtobuf(c, httpStatus, code, traceId, data) {
+		return
+	}
+
 	resp := GetResponse()
 	defer PutResponse(resp)
 	resp.Code = code
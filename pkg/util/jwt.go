@@ -2,6 +2,7 @@ package util
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,29 +12,87 @@ import (
 const (
 	// TODO: 生产环境应从配置文件或环境变量读取
 	JWTSecret     = "your-secret-key-change-in-production" // JWT 签名密钥
-	AccessExpire  = 2 * time.Hour                          // Access Token 过期时间
-	RefreshExpire = 7 * 24 * time.Hour                     // Refresh Token 过期时间
+	AccessExpire  = 2 * time.Hour                          // Access Token 默认过期时间
+	RefreshExpire = 7 * 24 * time.Hour                     // Refresh Token 默认过期时间
 )
 
+var (
+	tokenTTLMu sync.RWMutex
+	accessTTL  = AccessExpire
+	refreshTTL = RefreshExpire
+)
+
+// SetTokenTTL 设置 Access/Refresh Token 有效期，<=0 的参数保持默认值
+// 应在服务启动阶段调用（签发 Token 之前）
+func SetTokenTTL(access, refresh time.Duration) {
+	if access <= 0 {
+		access = AccessExpire
+	}
+	if refresh <= 0 {
+		refresh = RefreshExpire
+	}
+	tokenTTLMu.Lock()
+	defer tokenTTLMu.Unlock()
+	accessTTL = access
+	refreshTTL = refresh
+}
+
+// AccessTTL 返回当前 Access Token 有效期
+func AccessTTL() time.Duration {
+	tokenTTLMu.RLock()
+	defer tokenTTLMu.RUnlock()
+	return accessTTL
+}
+
+// RefreshTTL 返回当前 Refresh Token 有效期
+func RefreshTTL() time.Duration {
+	tokenTTLMu.RLock()
+	defer tokenTTLMu.RUnlock()
+	return refreshTTL
+}
+
+// ErrTokenEpochStale 表示 Token 签发时的设备纪元低于设备当前纪元（已被踢出/改密等批量失效）
+var ErrTokenEpochStale = errors.New("token epoch is stale")
+
+// ErrTokenVersionStale 表示 Token 签发时的用户令牌版本低于用户当前版本（重置密码/注销等全局失效）
+var ErrTokenVersionStale = errors.New("token version is stale")
+
 // CustomClaims 自定义 JWT Claims
 type CustomClaims struct {
-	UserUUID string `json:"user_uuid"` // 用户唯一标识
-	DeviceID string `json:"device_id"` // 设备 ID（用于多端登录管理）
+	UserUUID string `json:"user_uuid"`       // 用户唯一标识
+	DeviceID string `json:"device_id"`       // 设备 ID（用于多端登录管理）
+	Epoch    int64  `json:"epoch,omitempty"` // 签发时的设备纪元（踢出/改密时递增，旧纪元 Token 统一失效）
+	// TokenVersion 签发时的用户令牌版本（重置密码/注销等安全事件时递增，该用户所有设备的旧 Token 统一失效）
+	TokenVersion int64 `json:"token_version,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成 Access Token
+// GenerateToken 生成 Access Token（设备纪元为 0）
 // userUUID: 用户唯一标识
 // deviceID: 设备唯一标识
 // 返回: token 字符串和可能的错误
 func GenerateToken(userUUID, deviceID string) (string, error) {
+	return GenerateTokenWithEpoch(userUUID, deviceID, 0)
+}
+
+// GenerateTokenWithEpoch 生成携带设备纪元的 Access Token（用户令牌版本为 0）
+// epoch: 签发时设备的当前纪元（auth:epoch:{user_uuid}:{device_id}）
+func GenerateTokenWithEpoch(userUUID, deviceID string, epoch int64) (string, error) {
+	return GenerateTokenWithVersion(userUUID, deviceID, epoch, 0)
+}
+
+// GenerateTokenWithVersion 生成携带设备纪元与用户令牌版本的 Access Token
+// tokenVersion: 签发时用户的当前令牌版本（auth:token_version:{user_uuid}）
+func GenerateTokenWithVersion(userUUID, deviceID string, epoch, tokenVersion int64) (string, error) {
 	// 设置过期时间
 	now := time.Now()
 	claims := CustomClaims{
-		UserUUID: userUUID,
-		DeviceID: deviceID,
+		UserUUID:     userUUID,
+		DeviceID:     deviceID,
+		Epoch:        epoch,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(AccessExpire)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTTL())),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "ChatServer-Gateway", // 签发者
@@ -60,7 +119,7 @@ func GenerateRefreshToken(userUUID, deviceID string) (string, error) {
 		UserUUID: userUUID,
 		DeviceID: deviceID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshExpire)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTTL())),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "ChatServer-Gateway",
@@ -101,6 +160,24 @@ func ParseToken(tokenString string) (*CustomClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// CheckTokenEpoch 校验 Token 的设备纪元不低于设备当前纪元
+// 返回 ErrTokenEpochStale 表示 Token 已被批量失效
+func CheckTokenEpoch(claims *CustomClaims, currentEpoch int64) error {
+	if claims.Epoch < currentEpoch {
+		return ErrTokenEpochStale
+	}
+	return nil
+}
+
+// CheckTokenVersion 校验 Token 的用户令牌版本不低于用户当前版本
+// 返回 ErrTokenVersionStale 表示 Token 已被全局失效
+func CheckTokenVersion(claims *CustomClaims, currentVersion int64) error {
+	if claims.TokenVersion < currentVersion {
+		return ErrTokenVersionStale
+	}
+	return nil
+}
+
 // RefreshAccessToken 使用 Refresh Token 刷新 Access Token
 // refreshToken: refresh token 字符串
 // 返回: 新的 access token 和可能的错误
@@ -111,7 +188,7 @@ func RefreshAccessToken(refreshToken string) (string, error) {
 		return "", err
 	}
 
-	// 生成新的 access token
-	return GenerateToken(claims.UserUUID, claims.DeviceID)
+	// 生成新的 access token（沿用原设备纪元与用户令牌版本）
+	return GenerateTokenWithVersion(claims.UserUUID, claims.DeviceID, claims.Epoch, claims.TokenVersion)
 }
 
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenVersion(t *testing.T) {
+	staleToken, err := GenerateTokenWithVersion("u1", "d1", 0, 1)
+	require.NoError(t, err)
+	claims, err := ParseToken(staleToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.TokenVersion)
+
+	// 重置密码后版本递增：递增前签发的 Token 被拒绝，版本相同或更高的放行。
+	assert.ErrorIs(t, CheckTokenVersion(claims, 2), ErrTokenVersionStale)
+	assert.NoError(t, CheckTokenVersion(claims, 1))
+	assert.NoError(t, CheckTokenVersion(claims, 0))
+
+	legacyToken, err := GenerateTokenWithEpoch("u1", "d1", 3)
+	require.NoError(t, err)
+	legacy, err := ParseToken(legacyToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), legacy.TokenVersion, "未携带版本的 Token 视为版本 0")
+	assert.ErrorIs(t, CheckTokenVersion(legacy, 1), ErrTokenVersionStale)
+}
+
+func TestSetTokenTTL(t *testing.T) {
+	t.Cleanup(func() { SetTokenTTL(AccessExpire, RefreshExpire) })
+
+	SetTokenTTL(10*time.Minute, time.Hour)
+	assert.Equal(t, 10*time.Minute, AccessTTL())
+	assert.Equal(t, time.Hour, RefreshTTL())
+
+	token, err := GenerateToken("u1", "d1")
+	require.NoError(t, err)
+	claims, err := ParseToken(token)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+
+	refresh, err := GenerateRefreshToken("u1", "d1")
+	require.NoError(t, err)
+	claims, err = ParseToken(refresh)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, 5*time.Second)
+
+	SetTokenTTL(0, -time.Second)
+	assert.Equal(t, AccessExpire, AccessTTL(), "非正值保持默认")
+	assert.Equal(t, RefreshExpire, RefreshTTL())
+}
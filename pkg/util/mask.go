@@ -0,0 +1,53 @@
+package util
+
+import "strings"
+
+// 脱敏工具：gateway 与 user 服务共用同一实现，保证日志与接口返回的脱敏结果一致。
+// 约定：空输入返回空字符串；格式异常或过短的输入整体替换为 "***"，宁可多脱敏，不可泄露。
+
+// maskedPlaceholder 无法按规则部分展示时的整体替换值
+const maskedPlaceholder = "***"
+
+// MaskTelephone 手机号脱敏
+// 示例：13812345678 -> 138****5678，+8613812345678 -> +86****5678
+// 不足 11 位时无法区分号段与尾号，整体替换为 "***"。
+func MaskTelephone(telephone string) string {
+	if telephone == "" {
+		return ""
+	}
+	if len(telephone) < 11 {
+		return maskedPlaceholder
+	}
+	return telephone[:3] + "****" + telephone[len(telephone)-4:]
+}
+
+// MaskEmail 邮箱脱敏（仅展示用户名首字符，域名保留）
+// 示例：test@example.com -> t***@example.com，a@b.com -> *@b.com
+func MaskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return maskedPlaceholder
+	}
+
+	runes := []rune(local)
+	if len(runes) == 1 {
+		return "*@" + domain
+	}
+	return string(runes[0]) + "***@" + domain
+}
+
+// MaskUUID UUID 脱敏（保留首尾各 4 位，便于日志关联）
+// 示例：550e8400-e29b-41d4-a716-446655440000 -> 550e****0000
+// 不足 12 位时首尾展示会泄露过多信息，整体替换为 "***"。
+func MaskUUID(uuid string) string {
+	if uuid == "" {
+		return ""
+	}
+	if len(uuid) < 12 {
+		return maskedPlaceholder
+	}
+	return uuid[:4] + "****" + uuid[len(uuid)-4:]
+}
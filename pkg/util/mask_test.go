@@ -0,0 +1,67 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskTelephone(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"very_short", "12", "***"},
+		{"ten_digits", "1381234567", "***"},
+		{"normal", "13812345678", "138****5678"},
+		{"with_country_code", "+8613812345678", "+86****5678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MaskTelephone(tt.input))
+		})
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"single_char_local", "a@b.com", "*@b.com"},
+		{"normal", "test@example.com", "t***@example.com"},
+		{"unicode_local", "张三@example.com", "张***@example.com"},
+		{"missing_at", "example.com", "***"},
+		{"empty_local", "@example.com", "***"},
+		{"empty_domain", "test@", "***"},
+		{"multiple_at", "a@b@c.com", "***"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MaskEmail(tt.input))
+		})
+	}
+}
+
+func TestMaskUUID(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"very_short", "abc", "***"},
+		{"eleven_chars", "0123456789a", "***"},
+		{"normal", "550e8400-e29b-41d4-a716-446655440000", "550e****0000"},
+		{"compact", "u0123456789abcdef", "u012****cdef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MaskUUID(tt.input))
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
+	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/gomail.v2"
@@ -37,28 +39,126 @@ func GetEmailConfig() EmailConfig {
 	return defaultEmailConfig
 }
 
-// GenerateVerifyCode 生成指定位数的数字验证码
-// length: 验证码长度，推荐 6 位
-func GenerateVerifyCode(length int) (string, error) {
-	if length <= 0 {
-		length = 6 // 默认 6 位
+// VerifyCodeCharset 验证码字符集
+type VerifyCodeCharset string
+
+const (
+	// VerifyCodeNumeric 纯数字
+	VerifyCodeNumeric VerifyCodeCharset = "numeric"
+	// VerifyCodeAlphanumeric 数字 + 大写字母（去除易混淆的 0/O/1/I），校验时不区分大小写
+	VerifyCodeAlphanumeric VerifyCodeCharset = "alphanumeric"
+)
+
+const (
+	// MinVerifyCodeLength 验证码最短长度
+	MinVerifyCodeLength = 4
+	// MaxVerifyCodeLength 验证码最长长度（与接口层校验规则保持一致）
+	MaxVerifyCodeLength = 12
+
+	verifyCodeDigits       = "0123456789"
+	verifyCodeAlphanumeric = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// VerifyCodeSpec 验证码格式规范：长度 + 字符集
+type VerifyCodeSpec struct {
+	Length  int
+	Charset VerifyCodeCharset
+}
+
+// DefaultVerifyCodeSpec 默认 6 位数字验证码
+var DefaultVerifyCodeSpec = VerifyCodeSpec{Length: 6, Charset: VerifyCodeNumeric}
+
+// NewVerifyCodeSpec 创建验证码规范；长度限制在 [MinVerifyCodeLength, MaxVerifyCodeLength]，未知字符集按纯数字处理
+func NewVerifyCodeSpec(length int, charset VerifyCodeCharset) VerifyCodeSpec {
+	length = min(max(length, MinVerifyCodeLength), MaxVerifyCodeLength)
+	if charset != VerifyCodeAlphanumeric {
+		charset = VerifyCodeNumeric
 	}
+	return VerifyCodeSpec{Length: length, Charset: charset}
+}
 
-	const digits = "0123456789"
-	code := make([]byte, length)
+// alphabet 返回字符集对应的字母表
+func (spec VerifyCodeSpec) alphabet() string {
+	if spec.Charset == VerifyCodeAlphanumeric {
+		return verifyCodeAlphanumeric
+	}
+	return verifyCodeDigits
+}
 
-	for i := 0; i < length; i++ {
-		// 使用加密安全的随机数生成器
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
+// Normalize 规范化用户输入：去除首尾空白，字母数字验证码统一转大写
+func (spec VerifyCodeSpec) Normalize(code string) string {
+	code = strings.TrimSpace(code)
+	if spec.Charset == VerifyCodeAlphanumeric {
+		code = strings.ToUpper(code)
+	}
+	return code
+}
+
+// Valid 判断（已规范化的）验证码是否符合长度与字符集
+func (spec VerifyCodeSpec) Valid(code string) bool {
+	if len(code) != spec.Length {
+		return false
+	}
+	alphabet := spec.alphabet()
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(alphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate 按规范生成验证码（加密安全随机数）
+func (spec VerifyCodeSpec) Generate() (string, error) {
+	alphabet := spec.alphabet()
+	code := make([]byte, spec.Length)
+	for i := range code {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
 		if err != nil {
 			return "", fmt.Errorf("生成验证码失败: %w", err)
 		}
-		code[i] = digits[num.Int64()]
+		code[i] = alphabet[num.Int64()]
 	}
-
 	return string(code), nil
 }
 
+var (
+	verifyCodeSpecMu     sync.RWMutex
+	verifyCodeSpecDef    = DefaultVerifyCodeSpec
+	verifyCodeSpecByType = map[int32]VerifyCodeSpec{}
+)
+
+// SetVerifyCodeSpecs 设置默认验证码规范及按验证码类型的覆盖规范
+func SetVerifyCodeSpecs(def VerifyCodeSpec, byType map[int32]VerifyCodeSpec) {
+	copied := make(map[int32]VerifyCodeSpec, len(byType))
+	for codeType, spec := range byType {
+		copied[codeType] = spec
+	}
+	verifyCodeSpecMu.Lock()
+	defer verifyCodeSpecMu.Unlock()
+	verifyCodeSpecDef = def
+	verifyCodeSpecByType = copied
+}
+
+// VerifyCodeSpecFor 返回验证码类型对应的规范（未单独配置时使用默认规范）
+func VerifyCodeSpecFor(codeType int32) VerifyCodeSpec {
+	verifyCodeSpecMu.RLock()
+	defer verifyCodeSpecMu.RUnlock()
+	if spec, ok := verifyCodeSpecByType[codeType]; ok {
+		return spec
+	}
+	return verifyCodeSpecDef
+}
+
+// GenerateVerifyCode 生成指定位数的数字验证码
+// length: 验证码长度，推荐 6 位
+func GenerateVerifyCode(length int) (string, error) {
+	if length <= 0 {
+		length = 6 // 默认 6 位
+	}
+	return VerifyCodeSpec{Length: length, Charset: VerifyCodeNumeric}.Generate()
+}
+
 // SendVerifyCodeEmail 发送验证码邮件
 // toEmail: 收件人邮箱
 // code: 验证码
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyCodeSpecGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     VerifyCodeSpec
+		alphabet string
+	}{
+		{"numeric_6", NewVerifyCodeSpec(6, VerifyCodeNumeric), verifyCodeDigits},
+		{"numeric_4", NewVerifyCodeSpec(4, VerifyCodeNumeric), verifyCodeDigits},
+		{"alphanumeric_8", NewVerifyCodeSpec(8, VerifyCodeAlphanumeric), verifyCodeAlphanumeric},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				code, err := tt.spec.Generate()
+				require.NoError(t, err)
+				assert.Len(t, code, tt.spec.Length)
+				for _, c := range code {
+					assert.True(t, strings.ContainsRune(tt.alphabet, c), "unexpected char %q in %s", c, code)
+				}
+				assert.True(t, tt.spec.Valid(code))
+			}
+		})
+	}
+}
+
+func TestNewVerifyCodeSpec(t *testing.T) {
+	assert.Equal(t, VerifyCodeSpec{Length: MinVerifyCodeLength, Charset: VerifyCodeNumeric}, NewVerifyCodeSpec(1, "unknown"))
+	assert.Equal(t, VerifyCodeSpec{Length: MaxVerifyCodeLength, Charset: VerifyCodeAlphanumeric}, NewVerifyCodeSpec(64, VerifyCodeAlphanumeric))
+}
+
+func TestVerifyCodeSpecValid(t *testing.T) {
+	numeric := NewVerifyCodeSpec(6, VerifyCodeNumeric)
+	assert.True(t, numeric.Valid("012345"))
+	assert.False(t, numeric.Valid("01234"), "长度不足")
+	assert.False(t, numeric.Valid("0123456"), "长度超出")
+	assert.False(t, numeric.Valid("01234A"), "数字验证码不允许字母")
+
+	alnum := NewVerifyCodeSpec(6, VerifyCodeAlphanumeric)
+	assert.True(t, alnum.Valid("AB23CD"))
+	assert.False(t, alnum.Valid("ab23cd"), "未规范化的小写输入")
+	assert.True(t, alnum.Valid(alnum.Normalize(" ab23cd ")))
+	assert.False(t, alnum.Valid("AB0OCD"), "排除易混淆字符 0/O")
+
+	// 数字验证码不做大小写转换
+	assert.Equal(t, "12345a", numeric.Normalize(" 12345a "))
+}
+
+func TestVerifyCodeSpecFor(t *testing.T) {
+	t.Cleanup(func() { SetVerifyCodeSpecs(DefaultVerifyCodeSpec, nil) })
+
+	alnum8 := NewVerifyCodeSpec(8, VerifyCodeAlphanumeric)
+	SetVerifyCodeSpecs(NewVerifyCodeSpec(4, VerifyCodeNumeric), map[int32]VerifyCodeSpec{3: alnum8})
+
+	assert.Equal(t, alnum8, VerifyCodeSpecFor(3))
+	assert.Equal(t, VerifyCodeSpec{Length: 4, Charset: VerifyCodeNumeric}, VerifyCodeSpecFor(1))
+}